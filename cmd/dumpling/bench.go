@@ -0,0 +1,107 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/pingcap/dumpling/v4/export"
+	"github.com/spf13/pflag"
+)
+
+// byteCounter counts the bytes written to w.
+type byteCounter struct {
+	w io.Writer
+	n uint64
+}
+
+func (c *byteCounter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	c.n += uint64(n)
+	return n, err
+}
+
+// runBench serves `dumpling bench`, which writes the rows of a synthetic table
+// to measure the throughput of the serialization without a database.
+func runBench(args []string) int {
+	flags := pflag.NewFlagSet("dumpling bench", pflag.ContinueOnError)
+	var (
+		fileType    = flags.String("filetype", "sql", "The type of the output, sql, csv or tsv")
+		rows        = flags.Uint64("rows", 1000000, "The number of the rows")
+		rowWidth    = flags.Int("row-width", 256, "The approximate `bytes` of a row")
+		columnTypes = flags.String("column-types", "BIGINT,VARCHAR,DATETIME,DECIMAL,TEXT,BLOB", "The comma separated `types` of the columns")
+		output      = flags.String("output", "", "Write the output to this `file`, it's discarded if empty")
+		seed        = flags.Int64("seed", 0, "The seed of the random values")
+	)
+	if err := flags.Parse(args); err != nil {
+		if err == pflag.ErrHelp {
+			return 0
+		}
+		return exitCodeConfig
+	}
+
+	ir, err := export.NewSyntheticTableIR(export.SyntheticTable{
+		Database:    "bench",
+		Table:       "t",
+		ColumnTypes: strings.Split(*columnTypes, ","),
+		RowWidth:    *rowWidth,
+		Rows:        *rows,
+		Seed:        *seed,
+	})
+	if err != nil {
+		fmt.Printf("invalid config: %s\n", err.Error())
+		return exitCodeConfig
+	}
+	var w io.Writer = ioutil.Discard
+	if *output != "" {
+		f, err := os.Create(*output)
+		if err != nil {
+			fmt.Printf("create output failed: %s\n", err.Error())
+			return exitCodeWrite
+		}
+		defer f.Close()
+		w = f
+	}
+	counter := &byteCounter{w: w}
+
+	ctx := context.Background()
+	start := time.Now()
+	switch strings.ToLower(*fileType) {
+	case "sql":
+		err = export.WriteInsert(ctx, ir, counter, export.UnspecifiedSize, nil)
+	case "csv":
+		err = export.WriteInsertInCsv(ctx, ir, counter, false, "\\N", nil)
+	case "tsv":
+		err = export.WriteInsertInTsv(ctx, ir, counter, false, nil)
+	default:
+		fmt.Printf("invalid config: unsupported filetype %s\n", *fileType)
+		return exitCodeConfig
+	}
+	elapsed := time.Since(start)
+	if err != nil {
+		fmt.Printf("bench failed: %s\n", err.Error())
+		return exitCodeWrite
+	}
+	seconds := elapsed.Seconds()
+	fmt.Printf("wrote %d rows, %d bytes in %s: %.0f rows/s, %.1f MiB/s\n",
+		*rows, counter.n, elapsed.Round(time.Millisecond),
+		float64(*rows)/seconds, float64(counter.n)/seconds/(1<<20))
+	return 0
+}
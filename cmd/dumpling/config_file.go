@@ -0,0 +1,311 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	"github.com/pingcap/tidb-tools/pkg/filter"
+	router "github.com/pingcap/tidb-tools/pkg/table-router"
+	"github.com/spf13/pflag"
+	"gopkg.in/yaml.v2"
+
+	"github.com/pingcap/dumpling/v4/export"
+)
+
+// configFile is the file given by --config. Its top-level keys are the names
+// of the flags, except the sections below which have no flags.
+type configFile struct {
+	Tables      []tableConfig       `toml:"table" yaml:"table"`
+	Filter      *filterConfig       `toml:"filter" yaml:"filter"`
+	Routes      []*router.TableRule `toml:"routes" yaml:"routes"`
+	Rewrites    []rewriteConfig     `toml:"rewrite" yaml:"rewrite"`
+	ForeignKeys []foreignKeyConfig  `toml:"foreign-key" yaml:"foreign-key"`
+
+	// cliFilter is the filter flag given in command line, which overrides [filter]
+	cliFilter string
+}
+
+// cliFilterFlags are the flags selecting the databases and tables, [filter] is
+// ignored if any of them is given in command line.
+var cliFilterFlags = []string{"database"}
+
+var configFileSections = map[string]struct{}{
+	"table":       {},
+	"filter":      {},
+	"routes":      {},
+	"rewrite":     {},
+	"foreign-key": {},
+}
+
+type tableConfig struct {
+	Database    string `toml:"db-name" yaml:"db-name"`
+	Table       string `toml:"tbl-name" yaml:"tbl-name"`
+	Where       string `toml:"where" yaml:"where"`
+	Rows        uint64 `toml:"rows" yaml:"rows"`
+	Output      string `toml:"output" yaml:"output"`
+	ChunkColumn string `toml:"chunk-column" yaml:"chunk-column"`
+	RowFilter   string `toml:"row-filter" yaml:"row-filter"`
+}
+
+type rewriteConfig struct {
+	Database     string            `toml:"db-name" yaml:"db-name"`
+	Table        string            `toml:"tbl-name" yaml:"tbl-name"`
+	Column       string            `toml:"column" yaml:"column"`
+	Regexp       string            `toml:"regexp,omitempty" yaml:"regexp,omitempty"`
+	Replace      string            `toml:"replace,omitempty" yaml:"replace,omitempty"`
+	Value        *string           `toml:"value,omitempty" yaml:"value,omitempty"`
+	Lookup       map[string]string `toml:"lookup,omitempty" yaml:"lookup,omitempty"`
+	Pseudonymize bool              `toml:"pseudonymize,omitempty" yaml:"pseudonymize,omitempty"`
+	Length       int               `toml:"length,omitempty" yaml:"length,omitempty"`
+}
+
+type foreignKeyConfig struct {
+	Database           string   `toml:"db-name" yaml:"db-name"`
+	Table              string   `toml:"tbl-name" yaml:"tbl-name"`
+	Columns            []string `toml:"columns" yaml:"columns"`
+	ReferencedDatabase string   `toml:"referenced-db-name,omitempty" yaml:"referenced-db-name,omitempty"`
+	ReferencedTable    string   `toml:"referenced-tbl-name" yaml:"referenced-tbl-name"`
+	ReferencedColumns  []string `toml:"referenced-columns" yaml:"referenced-columns"`
+}
+
+type filterConfig struct {
+	CaseSensitive bool `toml:"case-sensitive" yaml:"case-sensitive"`
+	filter.Rules  `yaml:",inline"`
+}
+
+// loadConfigFile sets the flags not given in command line from the TOML or
+// YAML file of path, and returns the file for its sections.
+func loadConfigFile(flags *pflag.FlagSet, path string) (*configFile, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var unmarshal func([]byte, interface{}) error
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".toml":
+		unmarshal = toml.Unmarshal
+	case ".yaml", ".yml":
+		unmarshal = yaml.Unmarshal
+	default:
+		return nil, fmt.Errorf("unsupported config file %s, expect .toml, .yaml or .yml", path)
+	}
+
+	file := &configFile{}
+	for _, name := range cliFilterFlags {
+		if flag := flags.Lookup(name); flag != nil && flag.Changed {
+			file.cliFilter = name
+			break
+		}
+	}
+	values := map[string]interface{}{}
+	if err = unmarshal(data, &values); err != nil {
+		return nil, fmt.Errorf("parse config file %s: %v", path, err)
+	}
+	for name, value := range values {
+		if _, ok := configFileSections[name]; ok {
+			continue
+		}
+		flag := flags.Lookup(name)
+		if flag == nil || flag.Name == "config" {
+			return nil, fmt.Errorf("unknown option %s in config file %s", name, path)
+		}
+		if flag.Changed {
+			continue
+		}
+		str, ok := configValueString(value)
+		if !ok {
+			return nil, fmt.Errorf("invalid value of option %s in config file %s: %v", name, path, value)
+		}
+		if err = flags.Set(name, str); err != nil {
+			return nil, fmt.Errorf("invalid value of option %s in config file %s: %v", name, path, err)
+		}
+	}
+
+	if err = unmarshal(data, file); err != nil {
+		return nil, fmt.Errorf("parse config file %s: %v", path, err)
+	}
+	return file, nil
+}
+
+// configValueString returns the value of a flag in the config file as it's
+// given in command line. The arrays of the slice flags are joined by commas.
+func configValueString(value interface{}) (string, bool) {
+	switch v := value.(type) {
+	case string, bool, int, int64, uint64, float64:
+		return fmt.Sprint(v), true
+	case []interface{}:
+		items := make([]string, 0, len(v))
+		for _, item := range v {
+			if _, nested := item.([]interface{}); nested {
+				return "", false
+			}
+			str, ok := configValueString(item)
+			if !ok {
+				return "", false
+			}
+			items = append(items, str)
+		}
+		return strings.Join(items, ","), true
+	default:
+		return "", false
+	}
+}
+
+// apply sets the options of conf from the sections of file, it does nothing
+// if file is nil.
+func (file *configFile) apply(conf *export.Config) {
+	if file == nil {
+		return
+	}
+	for _, table := range file.Tables {
+		conf.TableConfigs = append(conf.TableConfigs, export.TableConfig{
+			Database:    table.Database,
+			Table:       table.Table,
+			Where:       table.Where,
+			Rows:        table.Rows,
+			Output:      table.Output,
+			ChunkColumn: table.ChunkColumn,
+			RowFilter:   table.RowFilter,
+		})
+	}
+	for _, rewrite := range file.Rewrites {
+		conf.ColumnRewrites = append(conf.ColumnRewrites, export.ColumnRewrite{
+			Database:     rewrite.Database,
+			Table:        rewrite.Table,
+			Column:       rewrite.Column,
+			Regexp:       rewrite.Regexp,
+			Replace:      rewrite.Replace,
+			Value:        rewrite.Value,
+			Lookup:       rewrite.Lookup,
+			Pseudonymize: rewrite.Pseudonymize,
+			Length:       rewrite.Length,
+		})
+	}
+	for _, fk := range file.ForeignKeys {
+		conf.ForeignKeys = append(conf.ForeignKeys, export.ForeignKey{
+			Database:           fk.Database,
+			Table:              fk.Table,
+			Columns:            fk.Columns,
+			ReferencedDatabase: fk.ReferencedDatabase,
+			ReferencedTable:    fk.ReferencedTable,
+			ReferencedColumns:  fk.ReferencedColumns,
+		})
+	}
+	conf.RouteRules = file.Routes
+	if file.Filter != nil && file.cliFilter != "" {
+		fmt.Fprintf(os.Stderr, "[filter] of the config file is ignored since --%s is given in command line\n", file.cliFilter)
+	} else if file.Filter != nil {
+		rules := file.Filter.Rules
+		conf.BlackWhiteList = export.BWListConf{
+			Mode: export.MySQLReplicationMode,
+			Rules: &export.MySQLReplicationConf{
+				Rules:         &rules,
+				CaseSensitive: file.Filter.CaseSensitive,
+			},
+		}
+	}
+}
+
+// printedFlagsExcluded are the flags not printed in the effective config,
+// which don't affect the dump.
+var printedFlagsExcluded = map[string]struct{}{
+	"config":       {},
+	"print-config": {},
+	"version":      {},
+}
+
+// printedSecrets are the flags redacted in the effective config. The URL of
+// a webhook is a credential itself.
+var printedSecrets = []string{"password", "pseudonymize-salt", "status-token", "notify-url"}
+
+// printedDSNs are the flags of the DSNs whose passwords are redacted in the
+// effective config.
+var printedDSNs = map[string]struct{}{
+	"shards":        {},
+	"read-replicas": {},
+	"target-dsn":    {},
+}
+
+// printEffectiveConfig writes the flags and the sections of file as a TOML
+// config file to w, which can be given to --config to reproduce the dump.
+// The empty values are left out, and the passwords are redacted.
+func printEffectiveConfig(w io.Writer, flags *pflag.FlagSet, file *configFile) error {
+	values := map[string]interface{}{}
+	var err error
+	flags.VisitAll(func(flag *pflag.Flag) {
+		if _, ok := printedFlagsExcluded[flag.Name]; ok || flag.Hidden || err != nil {
+			return
+		}
+		value := flag.Value.String()
+		if value == "" || value == "[]" {
+			// the empty values can't be set to some of the flags, e.g. params
+			return
+		}
+		if _, ok := printedDSNs[flag.Name]; ok {
+			dsns := strings.Split(strings.TrimSuffix(strings.TrimPrefix(value, "["), "]"), ",")
+			for i, dsn := range dsns {
+				dsns[i] = export.RedactSourceAddr(dsn)
+			}
+			values[flag.Name] = strings.Join(dsns, ",")
+			return
+		}
+		switch flag.Value.Type() {
+		case "bool":
+			values[flag.Name], err = strconv.ParseBool(value)
+		case "int":
+			values[flag.Name], err = strconv.ParseInt(value, 10, 64)
+		case "uint64":
+			values[flag.Name], err = strconv.ParseUint(value, 10, 64)
+		case "stringSlice", "stringToString":
+			// printed like `[a,b]`, but set like `a,b`
+			values[flag.Name] = strings.TrimSuffix(strings.TrimPrefix(value, "["), "]")
+		default:
+			values[flag.Name] = value
+		}
+	})
+	if err != nil {
+		return err
+	}
+	for _, secret := range printedSecrets {
+		if _, ok := values[secret]; ok {
+			values[secret] = "******"
+		}
+	}
+	if file != nil {
+		if len(file.Tables) > 0 {
+			values["table"] = file.Tables
+		}
+		if file.Filter != nil && file.cliFilter == "" {
+			values["filter"] = file.Filter
+		}
+		if len(file.Routes) > 0 {
+			values["routes"] = file.Routes
+		}
+		if len(file.Rewrites) > 0 {
+			values["rewrite"] = file.Rewrites
+		}
+		if len(file.ForeignKeys) > 0 {
+			values["foreign-key"] = file.ForeignKeys
+		}
+	}
+	return toml.NewEncoder(w).Encode(values)
+}
@@ -0,0 +1,91 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+
+	"github.com/pingcap/dumpling/v4/export"
+	"github.com/spf13/pflag"
+)
+
+// runDiff serves `dumpling diff`, which compares the rows of two dumps, or
+// of a dump and a server, by the checksums of their chunks and prints the
+// differing key ranges.
+func runDiff(args []string) int {
+	flags := pflag.NewFlagSet("dumpling diff", pflag.ContinueOnError)
+	var (
+		source = flags.String("source", "", "The directory of a dump with --checksum, or the DSN of the source server, e.g. 'root:@tcp(127.0.0.1:4000)/'")
+		target = flags.String("target", "", "The directory of a dump with --checksum, or the DSN of the target server")
+		output = flags.StringP("output", "o", "", "Write the difference to this `file` instead of stdout")
+	)
+	if err := flags.Parse(args); err != nil {
+		if err == pflag.ErrHelp {
+			return 0
+		}
+		return exitCodeConfig
+	}
+	if *source == "" || *target == "" {
+		fmt.Println("invalid config: both --source and --target are required")
+		return exitCodeConfig
+	}
+	isDir := func(location string) bool {
+		info, err := os.Stat(location)
+		return err == nil && info.IsDir()
+	}
+	if !isDir(*source) && !isDir(*target) {
+		fmt.Println("invalid config: at least one of --source and --target should be the directory of a dump")
+		return exitCodeConfig
+	}
+
+	var sourceChecksums, targetChecksums *export.DumpChecksums
+	read := func(location string, dumped *export.DumpChecksums) (*export.DumpChecksums, error) {
+		if isDir(location) {
+			return export.ReadChecksums(location)
+		}
+		// the server is checksummed by the same chunks as the dump
+		return export.FetchChecksums(context.Background(), export.DefaultConfig(), location, dumped)
+	}
+	var err error
+	if isDir(*source) {
+		if sourceChecksums, err = read(*source, nil); err == nil {
+			targetChecksums, err = read(*target, sourceChecksums)
+		}
+	} else {
+		if targetChecksums, err = read(*target, nil); err == nil {
+			sourceChecksums, err = read(*source, targetChecksums)
+		}
+	}
+	if err != nil {
+		fmt.Printf("read the checksums failed: %s\n", err.Error())
+		return exitCode(err)
+	}
+
+	diff := export.DiffChecksums(sourceChecksums, targetChecksums)
+	if *output != "" {
+		if err = ioutil.WriteFile(*output, []byte(diff.String()), 0644); err != nil {
+			fmt.Printf("write the difference failed: %s\n", err.Error())
+			return exitCodeWrite
+		}
+	} else {
+		fmt.Print(diff.String())
+	}
+	if !diff.Empty() {
+		return exitCodeDataDiff
+	}
+	return 0
+}
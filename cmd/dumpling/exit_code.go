@@ -0,0 +1,84 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"syscall"
+
+	"github.com/go-sql-driver/mysql"
+
+	"github.com/pingcap/dumpling/v4/export"
+)
+
+// The exit codes of dumpling, they are listed in the user guide.
+const (
+	exitCodeUnknown      = 1
+	exitCodeConfig       = 2
+	exitCodeConnection   = 3
+	exitCodeAccessDenied = 4
+	exitCodeLockTimeout  = 5
+	exitCodeConsistency  = 6
+	exitCodeSchema       = 7
+	exitCodeWrite        = 8
+	exitCodeDiskFull     = 9
+	// the dump is stopped before finished, the output is incomplete
+	exitCodeStopped = 10
+	// the schemas compared by `dumpling schema-diff` differ
+	exitCodeSchemaDiff = 11
+	// the rows compared by `dumpling diff` differ
+	exitCodeDataDiff = 12
+)
+
+var accessDeniedErrorNumbers = map[uint16]struct{}{
+	1044: {}, // ER_DBACCESS_DENIED_ERROR
+	1045: {}, // ER_ACCESS_DENIED_ERROR
+	1142: {}, // ER_TABLEACCESS_DENIED_ERROR
+	1227: {}, // ER_SPECIFIC_ACCESS_DENIED_ERROR
+}
+
+const lockWaitTimeoutErrorNumber = 1205 // ER_LOCK_WAIT_TIMEOUT
+
+func exitCode(err error) int {
+	if cause := export.RootCause(err); cause == export.ErrDumpStopped || cause == context.Canceled {
+		return exitCodeStopped
+	}
+	switch cause := export.RootCause(err).(type) {
+	case *mysql.MySQLError:
+		if _, ok := accessDeniedErrorNumbers[cause.Number]; ok {
+			return exitCodeAccessDenied
+		}
+		if cause.Number == lockWaitTimeoutErrorNumber {
+			return exitCodeLockTimeout
+		}
+	case syscall.Errno:
+		if cause == syscall.ENOSPC {
+			return exitCodeDiskFull
+		}
+	}
+	switch export.ErrorKindOf(err) {
+	case export.ErrorKindConfig:
+		return exitCodeConfig
+	case export.ErrorKindConnection:
+		return exitCodeConnection
+	case export.ErrorKindConsistency:
+		return exitCodeConsistency
+	case export.ErrorKindSchema:
+		return exitCodeSchema
+	case export.ErrorKindWrite:
+		return exitCodeWrite
+	default:
+		return exitCodeUnknown
+	}
+}
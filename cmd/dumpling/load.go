@@ -0,0 +1,77 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/pingcap/dumpling/v4/export"
+	"github.com/pingcap/dumpling/v4/log"
+	"github.com/spf13/pflag"
+)
+
+// runLoad serves `dumpling load`, which replays the SQL files of a dump into
+// a MySQL or TiDB server, the schemas first and then the data.
+func runLoad(args []string) int {
+	flags := pflag.NewFlagSet("dumpling load", pflag.ContinueOnError)
+	var (
+		dir             = flags.StringP("dir", "d", "", "The directory of the dump to load")
+		target          = flags.String("target", "", "The DSN of the target server, e.g. 'root:@tcp(127.0.0.1:4000)/'")
+		threads         = flags.IntP("threads", "t", 4, "The number of the files loaded at the same time")
+		escapeBackslash = flags.Bool("escape-backslash", true, "Whether the dump is dumped with --escape-backslash")
+		logLevel        = flags.String("loglevel", "info", "Log level: {debug|info|warn|error|dpanic|panic|fatal}")
+	)
+	if err := flags.Parse(args); err != nil {
+		if err == pflag.ErrHelp {
+			return 0
+		}
+		return exitCodeConfig
+	}
+	if *dir == "" || *target == "" {
+		fmt.Println("invalid config: both --dir and --target are required")
+		return exitCodeConfig
+	}
+	if err := log.InitAppLogger(&log.Config{Level: *logLevel}); err != nil {
+		fmt.Printf("invalid config: %s\n", err.Error())
+		return exitCodeConfig
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	sigCh := make(chan os.Signal, 1)
+	signal.Notify(sigCh, syscall.SIGINT, syscall.SIGTERM)
+	defer signal.Stop(sigCh)
+	go func() {
+		if sig, ok := <-sigCh; ok {
+			fmt.Fprintf(os.Stderr, "got signal %s, canceling load\n", sig)
+			cancel()
+		}
+	}()
+
+	err := export.Load(ctx, &export.LoadConfig{
+		Dir:             *dir,
+		TargetDSN:       *target,
+		Threads:         *threads,
+		EscapeBackslash: *escapeBackslash,
+	})
+	if err != nil {
+		fmt.Printf("load failed: %s\n", err.Error())
+		return exitCode(err)
+	}
+	return 0
+}
@@ -14,10 +14,13 @@
 package main
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	_ "net/http/pprof"
 	"os"
+	"os/signal"
+	"syscall"
 	"time"
 
 	"github.com/pingcap/dumpling/v4/cli"
@@ -42,6 +45,7 @@ var (
 	snapshot      string
 	noViews       bool
 	statusAddr    string
+	statusToken   string
 	rows          uint64
 	where         string
 	fileType      string
@@ -50,8 +54,116 @@ var (
 	noData        bool
 	csvNullValue  string
 	sql           string
+	sortByPk      bool
 
-	escapeBackslash bool
+	disableForeignKeyChecks bool
+	disableUniqueChecks     bool
+	noAutocommit            bool
+	orderByForeignKey       bool
+	disableKeys             bool
+	transactionRows         uint64
+	mysqldumpCompatible     bool
+	compact                 bool
+	showProgress            bool
+	stateFile               string
+	throttleBytesPerSec     uint64
+	throttleRowsPerSec      uint64
+	maxThreadsRunning       uint64
+	maxMemory               uint64
+	syncFiles               bool
+	checkFreeSpace          bool
+	minFree                 uint64
+	configPath              string
+	printConfig             bool
+	logFileMaxSize          int
+	logFileMaxDays          int
+	logFileMaxBackups       int
+	quiet                   bool
+	verbose                 bool
+	captureWarnings         bool
+	strictWarnings          bool
+	longQueryGuard          uint64
+	killLongQueries         bool
+	maxLockTime             uint64
+	downgradeOnMaxLockTime  bool
+	stopReplicaSQLThread    bool
+	maxReplicaLag           uint64
+	replicaLagWait          uint64
+	sourceDialect           string
+	postgresDatabase        string
+	targetDialect           string
+	bigQuerySchema          bool
+	hiveLocation            string
+	targetDSN               string
+	loadDataScripts         bool
+	kafkaBrokers            string
+	kafkaTopic              string
+	kafkaFormat             string
+	kafkaKey                string
+	kafkaSchemaRegistry     string
+	incrementalColumn       string
+	incrementalStateFile    string
+	appendDump              bool
+	retention               string
+	daemon                  bool
+	daemonConcurrency       int
+	daemonAllowSql          bool
+	schedule                string
+	scheduleOverlap         string
+	terminationGrace        uint64
+	beforeConsistencySQL    string
+	afterConsistencySQL     string
+	afterDumpCommand        string
+	hookFailurePolicy       string
+	notifyURL               string
+	tracingEndpoint         string
+	cpuProfile              string
+	memProfile              string
+	traceFile               string
+	fetchRows               uint64
+	chunkTimeout            uint64
+	serverOutfileDir        string
+	filesPerChunk           int
+	writerBufferSize        uint64
+	writerQueueDepth        int
+	maxConnections          int
+	connIdleTimeout         uint64
+	keepaliveInterval       uint64
+	netTimeout              uint64
+	waitTimeout             uint64
+	sessionParams           map[string]string
+	shards                  []string
+	readReplicas            []string
+	emptyTables             string
+	dumpSystemSchemas       bool
+	onlyObjects             []string
+	verifyComments          bool
+	tidbReplicas            bool
+	resourceGroup           string
+	lowPriority             bool
+	tidbReplicaRead         string
+	staleRead               uint64
+	explicitCollations      bool
+	coerceUTF8MB4           bool
+	noSequences             bool
+	rowFilter               string
+	pseudonymizeSalt        string
+	subset                  bool
+	checksum                bool
+	restorePlan             bool
+	dedupSchemas            bool
+	binlogStop              string
+	mysqlbinlogPath         string
+	replicationMeta         string
+	replicationAPI          string
+	replicationTask         string
+	replicationSource       string
+	replicationSinkURI      string
+
+	escapeBackslash  bool
+	quoteBigIntegers bool
+	zeroDates        string
+	canonicalJSON    bool
 )
 
 var defaultOutputDir = timestampDirName()
@@ -61,8 +173,24 @@ func timestampDirName() string {
 }
 
 func main() {
+	if len(os.Args) > 1 && os.Args[1] == "bench" {
+		os.Exit(runBench(os.Args[2:]))
+	}
+	if len(os.Args) > 1 && os.Args[1] == "schema-diff" {
+		os.Exit(runSchemaDiff(os.Args[2:]))
+	}
+	if len(os.Args) > 1 && os.Args[1] == "diff" {
+		os.Exit(runDiff(os.Args[2:]))
+	}
+	if len(os.Args) > 1 && os.Args[1] == "load" {
+		os.Exit(runLoad(os.Args[2:]))
+	}
+	if len(os.Args) > 1 && os.Args[1] == "preview" {
+		previewMode = true
+		os.Args = append(os.Args[:1], os.Args[2:]...)
+	}
 	pflag.Usage = func() {
-		fmt.Fprint(os.Stderr, "Dumpling is a CLI tool that helps you dump MySQL/TiDB data\n\nUsage:\n  dumpling [flags]\n\nFlags:\n")
+		fmt.Fprint(os.Stderr, "Dumpling is a CLI tool that helps you dump MySQL/TiDB data\n\nUsage:\n  dumpling [flags]\n  dumpling bench [flags]\n  dumpling schema-diff [flags]\n  dumpling diff [flags]\n  dumpling load [flags]\n  dumpling preview --table db.table [--rows n] [flags]\n\nFlags:\n")
 		pflag.PrintDefaults()
 	}
 	pflag.ErrHelp = errors.New("")
@@ -77,25 +205,154 @@ func main() {
 	pflag.Uint64VarP(&statementSize, "statement-size", "S", export.UnspecifiedSize, "Attempted size of INSERT statement in bytes")
 	pflag.StringVarP(&outputDir, "output", "o", defaultOutputDir, "Output directory")
 	pflag.StringVar(&logLevel, "loglevel", "info", "Log level: {debug|info|warn|error|dpanic|panic|fatal}")
-	pflag.StringVarP(&logFile, "logfile", "L", "", "Log file `path`, leave empty to write to console")
-	pflag.StringVar(&logFormat, "logfmt", "text", "Log `format`: {text|json}")
+	pflag.StringVarP(&logFile, "logfile", "L", "", "Log file `path`, leave empty to write to stderr")
+	pflag.StringVar(&logFormat, "logfmt", "text", "Log `format`: {text|console|json}")
 	pflag.StringVar(&consistency, "consistency", "auto", "Consistency level during dumping: {auto|none|flush|lock|snapshot}")
 	pflag.StringVar(&snapshot, "snapshot", "", "Snapshot position. Valid only when consistency=snapshot")
 	pflag.BoolVarP(&noViews, "no-views", "W", true, "Do not dump views")
-	pflag.StringVar(&statusAddr, "status-addr", ":8281", "dumpling API server and pprof addr")
-	pflag.Uint64VarP(&rows, "rows", "r", export.UnspecifiedSize, "Split table into chunks of this many rows, default unlimited")
+	pflag.StringVar(&statusAddr, "status-addr", "127.0.0.1:8281", "dumpling API server and pprof addr, e.g. :8281 to listen on all the interfaces")
+	pflag.StringVar(&statusToken, "status-token", "", "The bearer `token` required by the API on the status-addr except for /healthz and /readyz")
+	if previewMode {
+		pflag.StringVar(&previewTable, "table", "", "The table to preview, like db.table")
+		pflag.Uint64VarP(&rows, "rows", "r", defaultPreviewRows, "The number of the rows to preview")
+	} else {
+		pflag.Uint64VarP(&rows, "rows", "r", export.UnspecifiedSize, "Split table into chunks of this many rows, default unlimited")
+	}
 	pflag.StringVar(&where, "where", "", "Dump only selected records")
 	pflag.BoolVar(&escapeBackslash, "escape-backslash", true, "use backslash to escape quotation marks")
-	pflag.StringVar(&fileType, "filetype", "sql", "The type of export file (sql/csv)")
+	pflag.BoolVar(&quoteBigIntegers, "quote-big-integers", false, "Quote the integers out of the range of BIGINT, e.g. BIGINT UNSIGNED near 2^64, in the SQL and CSV files")
+	pflag.StringVar(&zeroDates, "zero-dates", export.ZeroDatesKeep, "How the zero dates and the other invalid values of DATE, DATETIME and TIMESTAMP are written (keep/null/error)")
+	pflag.BoolVar(&canonicalJSON, "canonical-json", false, "Write the values of the JSON columns with the sorted keys and without the insignificant whitespace, failing the dump at the malformed ones")
+	pflag.StringVar(&fileType, "filetype", "sql", "The type of export file (sql/csv/tsv/sqlite/kafka)")
 	pflag.BoolVar(&noHeader, "no-header", false, "whether not to dump CSV table header")
 	pflag.BoolVarP(&noSchemas, "no-schemas", "m", false, "Do not dump table schemas with the data")
 	pflag.BoolVarP(&noData, "no-data", "d", false, "Do not dump table data")
 	pflag.StringVar(&csvNullValue, "csv-null-value", "\\N", "The null value used when export to csv")
 	pflag.StringVarP(&sql, "sql", "s", "", "Dump data with given sql")
+	pflag.BoolVar(&sortByPk, "order-by-primary", true, "Sort dump results by primary key, or by all columns for small tables without primary key")
+	pflag.BoolVar(&disableForeignKeyChecks, "disable-foreign-key-checks", false, "Wrap data files with SET FOREIGN_KEY_CHECKS=0 to speed up restore")
+	pflag.BoolVar(&disableUniqueChecks, "disable-unique-checks", false, "Wrap data files with SET UNIQUE_CHECKS=0 to speed up restore")
+	pflag.BoolVar(&noAutocommit, "no-autocommit", false, "Wrap data files with SET AUTOCOMMIT=0 and COMMIT")
+	pflag.BoolVar(&orderByForeignKey, "order-by-foreign-key", false, "Record the restore order of tables sorted by foreign key dependencies in metadata")
+	pflag.BoolVar(&disableKeys, "disable-keys", false, "Wrap table data with ALTER TABLE ... DISABLE KEYS and ENABLE KEYS")
+	pflag.Uint64Var(&transactionRows, "transaction-rows", export.UnspecifiedSize, "Wrap every this many rows of INSERT statements with BEGIN and COMMIT, default unlimited")
+
+	pflag.BoolVar(&mysqldumpCompatible, "mysqldump-compatible", false, "Write mysqldump compatible header and footer in data files")
+	pflag.BoolVar(&compact, "compact", false, "Do not write the comments and the default session settings in data files, the settings asked by the other flags are kept")
+	pflag.BoolVar(&showProgress, "progress", false, "Show a progress bar with throughput and ETA on stderr if it's a terminal")
+	pflag.StringVar(&stateFile, "state-file", "", "Periodically write the dump state in JSON to this `path`")
+	pflag.Uint64Var(&throttleBytesPerSec, "throttle-bytes-per-sec", export.UnspecifiedSize, "Limit the bytes written per second, default unlimited")
+	pflag.Uint64Var(&throttleRowsPerSec, "throttle-rows-per-sec", export.UnspecifiedSize, "Limit the rows read per second, default unlimited")
+	pflag.Uint64Var(&maxThreadsRunning, "max-threads-running", export.UnspecifiedSize, "Pause dumping new tables and chunks while the running threads of source exceed this value, default unlimited")
+	pflag.Uint64Var(&maxMemory, "max-memory", export.UnspecifiedSize, "The maximum bytes buffered in memory waiting to be written, reading is blocked when exceeded, default unlimited")
+	pflag.BoolVar(&syncFiles, "sync-files", false, "Fsync each output file and the output directory before the file is reported complete")
+	pflag.BoolVar(&checkFreeSpace, "check-free-space", true, "Check the free space of the output directory is enough for the estimated output size before dumping")
+	pflag.Uint64Var(&minFree, "min-free", export.UnspecifiedSize, "The bytes that should remain free in the output directory after dumping, checked with --check-free-space")
+	pflag.StringVar(&configPath, "config", "", "Load the options from this TOML or YAML `path`, the options given in command line take precedence")
+	pflag.BoolVar(&printConfig, "print-config", false, "Validate the options and print the effective config in TOML without dumping")
+	pflag.IntVar(&logFileMaxSize, "logfile-max-size", 0, "Rotate the log file when it exceeds this many MiB, default 300")
+	pflag.IntVar(&logFileMaxDays, "logfile-max-days", 0, "Remove the rotated log files older than this many days, default never")
+	pflag.IntVar(&logFileMaxBackups, "logfile-max-backups", 0, "Keep at most this many rotated log files, default unlimited")
+	pflag.BoolVarP(&quiet, "quiet", "q", false, "Only log errors and don't show the progress bar or print the summary, overrides --loglevel")
+	pflag.BoolVarP(&verbose, "verbose", "v", false, "Log debug messages including every chunk, overrides --loglevel")
+	pflag.BoolVar(&captureWarnings, "capture-warnings", false, "Record the SHOW WARNINGS of every chunk into the warnings file of the output directory")
+	pflag.BoolVar(&strictWarnings, "strict-warnings", false, "Fail the dump if any chunk gets warnings, implies --capture-warnings")
+	pflag.Uint64Var(&longQueryGuard, "long-query-guard", export.UnspecifiedSize, "Wait for the queries running longer than this many seconds to finish before FTWRL, default disabled")
+	pflag.BoolVar(&killLongQueries, "kill-long-queries", false, "Kill the queries running longer than --long-query-guard (60 if not set) before FTWRL instead of waiting for them")
+	pflag.Uint64Var(&maxLockTime, "max-lock-time", export.UnspecifiedSize, "Release the locks of --consistency flush or lock and abort the dump if they are held longer than this many seconds, default unlimited")
+	pflag.BoolVar(&downgradeOnMaxLockTime, "downgrade-on-max-lock-time", false, "Continue dumping without consistency instead of aborting when --max-lock-time is exceeded")
+	pflag.BoolVar(&stopReplicaSQLThread, "stop-replica-sql-thread", false, "Stop the SQL thread of the replica during the dump, and record the stopped position in metadata")
+	pflag.Uint64Var(&maxReplicaLag, "max-replica-lag", export.UnspecifiedSize, "Abort if the replica is behind its source more than this many seconds before dumping, default unchecked")
+	pflag.Uint64Var(&replicaLagWait, "replica-lag-wait", 0, "Wait at most this many seconds for the replica to catch up with --max-replica-lag before aborting")
+	pflag.StringVar(&sourceDialect, "source-dialect", export.DialectMySQL, "The dialect of the source database. {mysql, postgres}, mysql is for MySQL, MariaDB and TiDB")
+	pflag.StringVar(&postgresDatabase, "postgres-database", "postgres", "The database of PostgreSQL to dump with --source-dialect postgres, its schemas are dumped as databases")
+	pflag.StringVar(&targetDialect, "target-dialect", "", "The dialect of the dump to load into. {mysql, tidb, postgres, sqlite, clickhouse}, defaults to the dialect of the source")
+	pflag.BoolVar(&bigQuerySchema, "bigquery-schema", false, "Write the BigQuery schema JSON of each table as <db>.<table>-schema.json, only with --filetype csv")
+	pflag.StringVar(&hiveLocation, "hive-location", "", "Write the Hive DDL of each table as <db>.<table>-schema-hive.sql located in <hive-location>/<db>.<table>, where the CSV files of the table are written, only with --filetype csv")
+	pflag.StringVar(&targetDSN, "target-dsn", "", "Restore the schemas and data into the MySQL or TiDB of the DSN, e.g. 'root:@tcp(127.0.0.1:4000)/', instead of writing the SQL files")
+	pflag.BoolVar(&loadDataScripts, "load-data-scripts", false, "Write the LOAD DATA LOCAL INFILE statements of the CSV files of each table as <db>.<table>-load.sql, only with --filetype csv")
+	pflag.StringVar(&kafkaBrokers, "kafka-brokers", "", "The comma separated addresses of the Kafka brokers to publish the rows to, only with --filetype kafka")
+	pflag.StringVar(&kafkaTopic, "kafka-topic", "{db}.{table}", "The Kafka topic of each table, where {db} and {table} are replaced")
+	pflag.StringVar(&kafkaFormat, "kafka-format", export.KafkaFormatJSON, "The format of the Kafka messages (json/avro)")
+	pflag.StringVar(&kafkaKey, "kafka-key", export.KafkaKeyPK, "The key of the Kafka messages, the primary key columns (pk) or none (none)")
+	pflag.StringVar(&kafkaSchemaRegistry, "kafka-schema-registry", "", "The URL of the Confluent Schema Registry where the Avro schemas are registered, required by --kafka-format avro")
+	pflag.StringVar(&incrementalColumn, "incremental-column", "", "Dump only the rows whose `column` is greater than the high watermark of the last run, e.g. updated_at, and write them as numbered delta files")
+	pflag.StringVar(&incrementalStateFile, "incremental-state", "", "The `path` of the state file recording the high watermarks of the tables with --incremental-column (default: <output>/incremental-state.json)")
+	pflag.BoolVar(&appendDump, "append", false, "Dump into the existing output directory, skipping the tables completed in its manifest.json, which is updated after every table")
+	pflag.StringVar(&retention, "retention", "", "After a successful dump, keep only the newest `N` finished dumps next to the output directory including this one, or the ones finished within an age if N is e.g. 7d or 36h")
+	pflag.BoolVar(&daemon, "daemon", false, "Run as a service dumping the jobs submitted to the REST API on the status-addr, the flags are the defaults of the jobs")
+	pflag.IntVar(&daemonConcurrency, "daemon-concurrency", 1, "The max number of the jobs run at the same time with --daemon")
+	pflag.BoolVar(&daemonAllowSql, "daemon-allow-sql", false, "Let the jobs submitted to the REST API of --daemon override --sql and --where, which are run with the credentials of the source")
+	pflag.StringVar(&schedule, "schedule", "", "Dump on the cron `expression` with --daemon, e.g. \"0 2 * * *\" or @daily, into the timestamped directories in the output directory")
+	pflag.StringVar(&scheduleOverlap, "schedule-overlap", "skip", "Skip the scheduled dump if the previous one is still going, or queue it to run after the previous one, one of skip and queue")
+	pflag.Uint64Var(&terminationGrace, "termination-grace", 0, "On SIGINT or SIGTERM, stop after the in-flight chunks are written, and cancel the dump if they aren't in this many seconds, default canceling immediately")
+	pflag.StringVar(&beforeConsistencySQL, "before-consistency-sql", "", "The `statements` separated by semicolons to run on the source before the consistency is ensured")
+	pflag.StringVar(&afterConsistencySQL, "after-consistency-sql", "", "The `statements` separated by semicolons to run on the source after the consistency is ensured, before the tables are dumped")
+	pflag.StringVar(&afterDumpCommand, "after-dump-command", "", "The shell `command` to run after the dump finishes, with the environment variables DUMPLING_STATUS, DUMPLING_ERROR and DUMPLING_OUTPUT_DIR")
+	pflag.StringVar(&hookFailurePolicy, "hook-failure-policy", export.HookFailureAbort, "Fail the dump if a hook fails, or log the failure and continue, one of abort and warn")
+	pflag.StringVar(&notifyURL, "notify-url", "", "POST a JSON summary of the dump to this webhook `url` when the dump finishes or fails, e.g. a Slack incoming webhook")
+	pflag.StringVar(&tracingEndpoint, "tracing-endpoint", "", "Export the OpenTelemetry spans of the dump stages to this OTLP HTTP `url`, e.g. http://localhost:4318")
+	pflag.StringVar(&cpuProfile, "profile", "", "Write the CPU profile of the dump to this `file`")
+	pflag.StringVar(&memProfile, "mem-profile", "", "Write the heap profile to this `file` when the dump finishes")
+	pflag.StringVar(&traceFile, "trace", "", "Write the execution trace of the Go runtime to this `file`")
+	pflag.Uint64Var(&fetchRows, "fetch-rows", export.UnspecifiedSize, "Fetch the tables by pages of this many rows with LIMIT, for the servers or proxies buffering the entire result sets, default unlimited")
+	pflag.Uint64Var(&chunkTimeout, "chunk-timeout", export.UnspecifiedSize, "Abort the query of a chunk not read and written in this many seconds, by MAX_EXECUTION_TIME of MySQL and TiDB or max_statement_time of MariaDB and a client deadline; the chunks timing out before any rows are read are split into halves and retried, default unlimited")
+	pflag.StringVar(&serverOutfileDir, "server-outfile-dir", "", "Let the server write the CSV files into this directory on its host by SELECT ... INTO OUTFILE, which should be the output directory shared with dumpling")
+	pflag.IntVar(&filesPerChunk, "files-per-chunk", 1, "Deal the rows of each chunk round-robin to this many files written concurrently")
+	pflag.Uint64Var(&writerBufferSize, "writer-buffer-size", export.UnspecifiedSize, "The size in bytes of the buffers the rows are serialized into before being written, default the statement size or 1 MiB")
+	pflag.IntVar(&writerQueueDepth, "writer-queue-depth", 8, "The number of the filled buffers waiting to be written for each file")
+	pflag.IntVar(&maxConnections, "max-connections", 0, "The max connections to the source, 0 for unlimited")
+	pflag.Uint64Var(&connIdleTimeout, "conn-idle-timeout", export.UnspecifiedSize, "Close the connections to the source idle longer than this many seconds, for the proxies closing the idle connections (default never)")
+	pflag.Uint64Var(&keepaliveInterval, "keepalive-interval", export.UnspecifiedSize, "Ping the idle connections to the source every this many seconds, so that they are not closed for being idle (default 60 with flush and lock consistency, otherwise disabled)")
+	pflag.Uint64Var(&netTimeout, "net-timeout", 3600, "The net_read_timeout and net_write_timeout in seconds of the sessions to MySQL, so that a chunk written slowly isn't aborted, 0 to keep the server's")
+	pflag.Uint64Var(&waitTimeout, "wait-timeout", 28800, "The wait_timeout in seconds of the sessions to MySQL, so that the connections holding the locks aren't closed for being idle, 0 to keep the server's")
+	pflag.StringToStringVar(&sessionParams, "params", nil, "The session variables set on every connection to the source, like 'sql_mode=,max_execution_time=0'")
+	pflag.StringSliceVar(&shards, "shards", nil, "The comma separated sources like 'user:password@host:port' dumped into one output, the omitted parts are taken from --user, --password and --port")
+	pflag.StringSliceVar(&readReplicas, "read-replicas", nil, "The comma separated replicas like 'user:password@host:port' which the chunks are read from in turns with the source")
+	pflag.StringVar(&emptyTables, "empty-tables", export.EmptyTablesNone, "The data file of the tables without any rows (none/empty/header), header writes the special comments of sql or the column names of csv and tsv")
+	pflag.BoolVar(&dumpSystemSchemas, "dump-system-schemas", false, "Dump the system schemas like mysql and sys, the in-memory ones like INFORMATION_SCHEMA are always skipped")
+	pflag.StringSliceVar(&onlyObjects, "only-objects", nil, "Only dump the comma separated objects (views/routines/triggers) of the filtered databases, without the tables and their data")
+	pflag.BoolVar(&verifyComments, "verify-comments", false, "Check the comments of the tables, columns, indexes and partitions in the DDL round-trip to INFORMATION_SCHEMA, and write them with the escapes understood by all the MySQL compatible parsers")
+	pflag.BoolVar(&tidbReplicas, "tidb-replicas", false, "Write the placement policies of TiDB into placement-policies.sql, and the TiFlash replicas of the tables as ALTER TABLE statements into <db>-schema-tiflash.sql")
+	pflag.StringVar(&resourceGroup, "resource-group", "", "Bind the sessions of the dump to this resource group of TiDB 7.1 and later, so that the dump is limited by its quota")
+	pflag.BoolVar(&lowPriority, "low-priority", false, "Read the tables of TiDB with SELECT LOW_PRIORITY, so that the requests of the other workloads are scheduled first")
+	pflag.StringVar(&tidbReplicaRead, "tidb-replica-read", "", "Set tidb_replica_read of the sessions to TiDB 4.0 and later, one of leader, follower, leader-and-follower and closest-replicas")
+	pflag.Uint64Var(&staleRead, "stale-read", export.UnspecifiedSize, "Read the tables of TiDB 5.1 and later as of this many seconds before the snapshot by AS OF TIMESTAMP, which may be served by the nearest replicas, default disabled")
+	pflag.BoolVar(&explicitCollations, "explicit-collations", false, "Write the collations of the databases, tables and columns in the DDL, even if they're the defaults of the source")
+	pflag.BoolVar(&coerceUTF8MB4, "coerce-utf8mb4", false, "Replace the character sets in the DDL with utf8mb4 and the collations with the utf8mb4 ones supported by TiDB, implies --explicit-collations")
+	pflag.BoolVar(&noSequences, "no-sequences", false, "Do not dump sequences, nor list them to warn the ones which aren't dumped")
+	pflag.StringVar(&rowFilter, "row-filter", "", "The expression evaluated against the decoded rows of every table, the rows it isn't true for are dropped, see the user guide for the syntax")
+	pflag.StringVar(&pseudonymizeSalt, "pseudonymize-salt", "", "The key of the pseudonyms of the column rewrites with pseudonymize, a random one is generated for the dump if it's empty")
+	pflag.BoolVar(&subset, "subset", false, "Dump only the rows referencing the rows selected by the where of the tables, following the foreign keys")
+	pflag.BoolVar(&checksum, "checksum", false, "Record the checksums of the chunks in checksums.json, which are compared by 'dumpling diff'")
+	pflag.BoolVar(&restorePlan, "restore-plan", false, "Write the files in the batches they can be restored in, in parallel within a batch, into restore-plan.json")
+	pflag.BoolVar(&dedupSchemas, "dedup-schemas", false, "Write a single schema file for the tables identical except the names, and map the others to it in schema-dedup.json")
+	pflag.StringVar(&binlogStop, "binlog-stop", "", "Tail the binlog from the position of the dump to this position, like 'mysql-bin.000003:1024' or 'current', into the {file}-binlog.sql files by mysqlbinlog")
+	pflag.StringVar(&mysqlbinlogPath, "mysqlbinlog", "mysqlbinlog", "The path of mysqlbinlog used by --binlog-stop")
+	pflag.StringVar(&replicationMeta, "replication-meta", "", "Write the position of the dump for the incremental replication: {dm|ticdc}, into dm-meta.yaml or ticdc-changefeed.json")
+	pflag.StringVar(&replicationAPI, "replication-api", "", "The OpenAPI address of the DM master or the TiCDC server to register the position of the dump by, e.g. 'http://127.0.0.1:8261'")
+	pflag.StringVar(&replicationTask, "replication-task", "", "The DM task or the TiCDC changefeed ID of --replication-meta")
+	pflag.StringVar(&replicationSource, "replication-source", "", "The source of the DM task the position is set to")
+	pflag.StringVar(&replicationSinkURI, "replication-sink-uri", "", "The sink URI of the TiCDC changefeed created by --replication-api")
+	registerMysqldumpFlags()
 
 	printVersion := pflag.BoolP("version", "V", false, "Print Dumpling version")
 
 	pflag.Parse()
+	var file *configFile
+	if configPath != "" {
+		var err error
+		file, err = loadConfigFile(pflag.CommandLine, configPath)
+		if err != nil {
+			fmt.Printf("load config file failed: %s\n", err.Error())
+			os.Exit(exitCodeConfig)
+		}
+	}
+	applyMysqldumpFlags()
+	if err := applyVerbosityFlags(); err != nil {
+		fmt.Printf("invalid config: %s\n", err.Error())
+		os.Exit(exitCodeConfig)
+	}
 
 	println(cli.LongVersion())
 
@@ -114,13 +371,22 @@ func main() {
 	conf.StatementSize = statementSize
 	conf.OutputDirPath = outputDir
 	conf.Consistency = consistency
+	conf.Snapshot = snapshot
 	conf.NoViews = noViews
 	conf.StatusAddr = statusAddr
+	conf.StatusToken = statusToken
 	conf.Rows = rows
 	conf.Where = where
 	conf.EscapeBackslash = escapeBackslash
+	conf.QuoteBigIntegers = quoteBigIntegers
+	conf.ZeroDates = zeroDates
+	conf.CanonicalJSON = canonicalJSON
+	conf.DaemonAllowSql = daemonAllowSql
 	conf.LogLevel = logLevel
 	conf.LogFile = logFile
+	conf.LogFileMaxSize = logFileMaxSize
+	conf.LogFileMaxDays = logFileMaxDays
+	conf.LogFileMaxBackups = logFileMaxBackups
 	conf.LogFormat = logFormat
 	conf.FileType = fileType
 	conf.NoHeader = noHeader
@@ -128,10 +394,172 @@ func main() {
 	conf.NoData = noData
 	conf.CsvNullValue = csvNullValue
 	conf.Sql = sql
+	conf.SortByPk = sortByPk
+	conf.DisableForeignKeyChecks = disableForeignKeyChecks
+	conf.DisableUniqueChecks = disableUniqueChecks
+	conf.NoAutocommit = noAutocommit
+	conf.OrderByForeignKey = orderByForeignKey
+	conf.DisableKeys = disableKeys
+	conf.TransactionRows = transactionRows
+	conf.MysqldumpCompatible = mysqldumpCompatible
+	conf.Compact = compact
+	conf.ShowProgress = showProgress && !quiet && isTerminal(os.Stderr)
+	conf.StateFile = stateFile
+	conf.ThrottleBytesPerSec = throttleBytesPerSec
+	conf.ThrottleRowsPerSec = throttleRowsPerSec
+	conf.MaxThreadsRunning = maxThreadsRunning
+	conf.MaxMemory = maxMemory
+	conf.SyncFiles = syncFiles
+	conf.CheckFreeSpace = checkFreeSpace
+	conf.MinFree = minFree
+	conf.CaptureWarnings = captureWarnings
+	conf.StrictWarnings = strictWarnings
+	conf.LongQueryGuard = longQueryGuard
+	conf.KillLongQueries = killLongQueries
+	conf.MaxLockTime = maxLockTime
+	conf.DowngradeOnMaxLockTime = downgradeOnMaxLockTime
+	conf.StopReplicaSQLThread = stopReplicaSQLThread
+	conf.MaxReplicaLag = maxReplicaLag
+	conf.ReplicaLagWait = replicaLagWait
+	conf.SourceDialect = sourceDialect
+	conf.PostgresDatabase = postgresDatabase
+	conf.TargetDialect = targetDialect
+	conf.BigQuerySchema = bigQuerySchema
+	conf.HiveLocation = hiveLocation
+	conf.TargetDSN = targetDSN
+	conf.LoadDataScripts = loadDataScripts
+	conf.KafkaBrokers = kafkaBrokers
+	conf.KafkaTopic = kafkaTopic
+	conf.KafkaFormat = kafkaFormat
+	conf.KafkaKey = kafkaKey
+	conf.KafkaSchemaRegistry = kafkaSchemaRegistry
+	conf.IncrementalColumn = incrementalColumn
+	conf.IncrementalStateFile = incrementalStateFile
+	conf.Append = appendDump
+	conf.Retention = retention
+	conf.BeforeConsistencySQL = beforeConsistencySQL
+	conf.AfterConsistencySQL = afterConsistencySQL
+	conf.AfterDumpCommand = afterDumpCommand
+	conf.HookFailurePolicy = hookFailurePolicy
+	conf.NotifyURL = notifyURL
+	conf.TracingEndpoint = tracingEndpoint
+	conf.FetchRows = fetchRows
+	conf.ChunkTimeout = chunkTimeout
+	conf.ServerOutfileDir = serverOutfileDir
+	conf.FilesPerChunk = filesPerChunk
+	conf.WriterBufferSize = writerBufferSize
+	conf.WriterQueueDepth = writerQueueDepth
+	conf.MaxConnections = maxConnections
+	conf.ConnIdleTimeout = connIdleTimeout
+	conf.KeepaliveInterval = keepaliveInterval
+	conf.NetTimeout = netTimeout
+	conf.WaitTimeout = waitTimeout
+	conf.SessionParams = sessionParams
+	conf.Shards = shards
+	conf.ReadReplicas = readReplicas
+	conf.EmptyTables = emptyTables
+	conf.DumpSystemSchemas = dumpSystemSchemas
+	conf.OnlyObjects = onlyObjects
+	conf.VerifyComments = verifyComments
+	conf.TiDBReplicas = tidbReplicas
+	conf.ResourceGroup = resourceGroup
+	conf.LowPriority = lowPriority
+	conf.TiDBReplicaRead = tidbReplicaRead
+	conf.StaleRead = staleRead
+	conf.ExplicitCollations = explicitCollations
+	conf.CoerceUTF8MB4 = coerceUTF8MB4
+	conf.NoSequences = noSequences
+	conf.RowFilter = rowFilter
+	conf.PseudonymizeSalt = pseudonymizeSalt
+	conf.Subset = subset
+	conf.Checksums = checksum
+	conf.RestorePlan = restorePlan
+	conf.DedupSchemas = dedupSchemas
+	conf.BinlogStop = binlogStop
+	conf.MysqlbinlogPath = mysqlbinlogPath
+	conf.ReplicationMeta = replicationMeta
+	conf.ReplicationAPI = replicationAPI
+	conf.ReplicationTask = replicationTask
+	conf.ReplicationSource = replicationSource
+	conf.ReplicationSinkURI = replicationSinkURI
+	file.apply(conf)
+
+	if previewMode {
+		os.Exit(runPreview(conf, previewTable, rows))
+	}
+
+	if printConfig {
+		if err := conf.Validate(); err != nil {
+			fmt.Printf("invalid config: %s\n", err.Error())
+			os.Exit(exitCodeConfig)
+		}
+		if err := printEffectiveConfig(os.Stdout, pflag.CommandLine, file); err != nil {
+			fmt.Printf("print config failed: %s\n", err.Error())
+			os.Exit(exitCodeUnknown)
+		}
+		return
+	}
 
-	err := export.Dump(conf)
+	if schedule != "" && !daemon {
+		fmt.Println("invalid config: schedule is only supported with daemon")
+		os.Exit(exitCodeConfig)
+	}
+	var d *export.Daemon
+	if daemon {
+		d = export.NewDaemon(conf, daemonConcurrency)
+		if schedule != "" {
+			if _, err := d.AddSchedule(export.ScheduleRequest{Schedule: schedule, Overlap: scheduleOverlap}); err != nil {
+				fmt.Printf("invalid config: %s\n", err.Error())
+				os.Exit(exitCodeConfig)
+			}
+		}
+	} else {
+		conf.Controller = export.NewJobController()
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	sigCh := make(chan os.Signal, 1)
+	signal.Notify(sigCh, syscall.SIGINT, syscall.SIGTERM)
+	go func() {
+		sig := <-sigCh
+		if terminationGrace != 0 {
+			// finish the in-flight chunks, so the written files are complete
+			fmt.Fprintf(os.Stderr, "got signal %s, stopping dump in %d seconds\n", sig, terminationGrace)
+			if d != nil {
+				d.Drain()
+			} else {
+				conf.Controller.Stop()
+			}
+			select {
+			case sig = <-sigCh:
+				fmt.Fprintf(os.Stderr, "got signal %s again, canceling dump\n", sig)
+			case <-time.After(time.Duration(terminationGrace) * time.Second):
+				fmt.Fprintf(os.Stderr, "termination grace period exceeded, canceling dump\n")
+			}
+		} else {
+			fmt.Fprintf(os.Stderr, "got signal %s, stopping dump\n", sig)
+		}
+		cancel()
+	}()
+
+	dumpGoroutinesOnSIGQUIT()
+	stopProfiling, err := startProfiling()
+	if err != nil {
+		fmt.Printf("start profiling failed: %s\n", err.Error())
+		os.Exit(exitCodeUnknown)
+	}
+	if d != nil {
+		err = d.Run(ctx)
+	} else {
+		err = export.Dump(ctx, conf)
+		if summary := conf.Progress.Summary(); !quiet && len(summary.Tables) > 0 {
+			fmt.Print(summary)
+		}
+	}
+	stopProfiling()
 	if err != nil {
 		fmt.Printf("dump failed: %s\n", err.Error())
-		os.Exit(1)
+		os.Exit(exitCode(err))
 	}
 }
@@ -0,0 +1,78 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/spf13/pflag"
+)
+
+// mysqldumpFlagAliases maps the mysqldump options to their dumpling equivalents.
+var mysqldumpFlagAliases = map[string]string{
+	"no-create-info": "no-schemas",
+}
+
+// mysqldumpConsistencyFlags maps the mysqldump locking options to dumpling consistency levels.
+var mysqldumpConsistencyFlags = map[string]string{
+	"lock-all-tables":    "flush",
+	"lock-tables":        "lock",
+	"single-transaction": "snapshot",
+	"skip-lock-tables":   "none",
+}
+
+// mysqldumpIgnoredFlags are the mysqldump options accepted for compatibility,
+// which are either always enabled or meaningless in dumpling. The line
+// "Dump completed on" always has the date, as by dump-date.
+var mysqldumpIgnoredFlags = []string{
+	"opt", "quick", "extended-insert", "hex-blob", "add-locks", "add-drop-table",
+	"create-options", "set-charset", "tz-utc", "routines", "triggers", "events",
+	"dump-date",
+}
+
+var mysqldumpConsistency = map[string]*bool{}
+var mysqldumpIgnored = map[string]*bool{}
+
+func registerMysqldumpFlags() {
+	pflag.CommandLine.SetNormalizeFunc(func(f *pflag.FlagSet, name string) pflag.NormalizedName {
+		if alias, ok := mysqldumpFlagAliases[name]; ok {
+			name = alias
+		}
+		return pflag.NormalizedName(name)
+	})
+	for name := range mysqldumpConsistencyFlags {
+		mysqldumpConsistency[name] = pflag.Bool(name, false, "mysqldump compatible option")
+		_ = pflag.CommandLine.MarkHidden(name)
+	}
+	for _, name := range mysqldumpIgnoredFlags {
+		mysqldumpIgnored[name] = pflag.Bool(name, false, "mysqldump compatible option, ignored")
+		_ = pflag.CommandLine.MarkHidden(name)
+	}
+}
+
+// applyMysqldumpFlags translates the mysqldump options given by user,
+// it must be called after the flags are parsed.
+func applyMysqldumpFlags() {
+	for name, level := range mysqldumpConsistencyFlags {
+		if *mysqldumpConsistency[name] {
+			consistency = level
+		}
+	}
+	for name, set := range mysqldumpIgnored {
+		if *set {
+			fmt.Fprintf(os.Stderr, "mysqldump option --%s is ignored\n", name)
+		}
+	}
+}
@@ -0,0 +1,52 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/pingcap/dumpling/v4/export"
+)
+
+// defaultPreviewRows is the number of the rows previewed without --rows.
+const defaultPreviewRows = 20
+
+// previewMode is set by `dumpling preview`, which takes the same flags as a
+// dump besides --table, and --rows is the number of the rows to preview.
+var (
+	previewMode  bool
+	previewTable string
+)
+
+// runPreview serves `dumpling preview`, which prints the first rows of a
+// table as they would be dumped by conf, after the filters, the rewrites and
+// the escaping.
+func runPreview(conf *export.Config, table string, rows uint64) int {
+	i := strings.Index(table, ".")
+	if i <= 0 || i == len(table)-1 {
+		fmt.Printf("invalid config: --table should be like db.table, got %s\n", table)
+		return exitCodeConfig
+	}
+	// the preview is a single chunk
+	conf.Rows = export.UnspecifiedSize
+	err := export.Preview(context.Background(), conf, table[:i], table[i+1:], rows, os.Stdout)
+	if err != nil {
+		fmt.Printf("preview failed: %s\n", err.Error())
+		return exitCode(err)
+	}
+	return 0
+}
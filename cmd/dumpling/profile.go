@@ -0,0 +1,109 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/signal"
+	"runtime"
+	"runtime/pprof"
+	"runtime/trace"
+	"syscall"
+)
+
+// startProfiling starts the CPU profile and the execution trace of --profile
+// and --trace. The returned function stops them and writes the heap profile
+// of --mem-profile, it must be called before exiting.
+func startProfiling() (func(), error) {
+	var stops []func()
+	stop := func() {
+		for i := len(stops) - 1; i >= 0; i-- {
+			stops[i]()
+		}
+	}
+	if cpuProfile != "" {
+		f, err := os.Create(cpuProfile)
+		if err != nil {
+			return nil, err
+		}
+		if err = pprof.StartCPUProfile(f); err != nil {
+			f.Close()
+			return nil, err
+		}
+		stops = append(stops, func() {
+			pprof.StopCPUProfile()
+			closeProfile(f)
+		})
+	}
+	if traceFile != "" {
+		f, err := os.Create(traceFile)
+		if err != nil {
+			stop()
+			return nil, err
+		}
+		if err = trace.Start(f); err != nil {
+			f.Close()
+			stop()
+			return nil, err
+		}
+		stops = append(stops, func() {
+			trace.Stop()
+			closeProfile(f)
+		})
+	}
+	if memProfile != "" {
+		stops = append(stops, func() {
+			if err := writeMemProfile(memProfile); err != nil {
+				fmt.Fprintf(os.Stderr, "write memory profile failed: %s\n", err)
+			}
+		})
+	}
+	return stop, nil
+}
+
+func writeMemProfile(path string) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	// get the up-to-date statistics of the allocations
+	runtime.GC()
+	if err = pprof.WriteHeapProfile(f); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+func closeProfile(f *os.File) {
+	if err := f.Close(); err != nil {
+		fmt.Fprintf(os.Stderr, "close profile %s failed: %s\n", f.Name(), err)
+	}
+}
+
+// dumpGoroutinesOnSIGQUIT prints the stacks of all goroutines to stderr on
+// SIGQUIT, instead of exiting as the Go runtime does by default.
+func dumpGoroutinesOnSIGQUIT() {
+	sigCh := make(chan os.Signal, 1)
+	signal.Notify(sigCh, syscall.SIGQUIT)
+	go func() {
+		for range sigCh {
+			fmt.Fprintln(os.Stderr, "got signal SIGQUIT, dumping goroutines")
+			if err := pprof.Lookup("goroutine").WriteTo(os.Stderr, 2); err != nil {
+				fmt.Fprintf(os.Stderr, "dump goroutines failed: %s\n", err)
+			}
+		}
+	}()
+}
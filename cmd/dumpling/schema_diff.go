@@ -0,0 +1,84 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+
+	"github.com/pingcap/dumpling/v4/export"
+	"github.com/spf13/pflag"
+)
+
+// runSchemaDiff serves `dumpling schema-diff`, which compares the schemas of
+// two servers, or of a server and a previous dump, and prints their
+// structural difference.
+func runSchemaDiff(args []string) int {
+	flags := pflag.NewFlagSet("dumpling schema-diff", pflag.ContinueOnError)
+	var (
+		source        = flags.String("source", "", "The DSN of the source server, e.g. 'root:@tcp(127.0.0.1:4000)/', or the directory of a previous dump")
+		target        = flags.String("target", "", "The DSN of the target server, or the directory of a previous dump")
+		database      = flags.StringP("database", "B", "", "The comma separated databases to compare, default all")
+		noViews       = flags.Bool("no-views", false, "Do not compare views")
+		sourceDialect = flags.String("source-dialect", export.DialectMySQL, "The dialect of the servers. {mysql, postgres}")
+		output        = flags.StringP("output", "o", "", "Write the difference to this `file` instead of stdout")
+	)
+	if err := flags.Parse(args); err != nil {
+		if err == pflag.ErrHelp {
+			return 0
+		}
+		return exitCodeConfig
+	}
+	if *source == "" || *target == "" {
+		fmt.Println("invalid config: both --source and --target are required")
+		return exitCodeConfig
+	}
+
+	fetch := func(location string) (export.SchemaSet, error) {
+		conf := export.DefaultConfig()
+		conf.Database = *database
+		conf.NoViews = *noViews
+		conf.SourceDialect = *sourceDialect
+		if info, err := os.Stat(location); err == nil && info.IsDir() {
+			return export.ReadSchemas(conf, location)
+		}
+		return export.FetchSchemas(context.Background(), conf, location)
+	}
+	sourceSchemas, err := fetch(*source)
+	if err != nil {
+		fmt.Printf("read the schemas of the source failed: %s\n", err.Error())
+		return exitCode(err)
+	}
+	targetSchemas, err := fetch(*target)
+	if err != nil {
+		fmt.Printf("read the schemas of the target failed: %s\n", err.Error())
+		return exitCode(err)
+	}
+
+	diff := export.DiffSchemas(sourceSchemas, targetSchemas)
+	if *output != "" {
+		if err = ioutil.WriteFile(*output, []byte(diff.String()), 0644); err != nil {
+			fmt.Printf("write the difference failed: %s\n", err.Error())
+			return exitCodeWrite
+		}
+	} else {
+		fmt.Print(diff.String())
+	}
+	if !diff.Empty() {
+		return exitCodeSchemaDiff
+	}
+	return 0
+}
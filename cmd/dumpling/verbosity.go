@@ -0,0 +1,43 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"errors"
+	"os"
+)
+
+// applyVerbosityFlags translates --quiet and --verbose to the log level,
+// it must be called after the flags are parsed.
+func applyVerbosityFlags() error {
+	switch {
+	case quiet && verbose:
+		return errors.New("--quiet and --verbose can't be both set")
+	case quiet:
+		logLevel = "error"
+	case verbose:
+		logLevel = "debug"
+	}
+	return nil
+}
+
+// isTerminal reports whether f is a terminal, the progress bar is only
+// rendered on terminals so that it doesn't pollute redirected output.
+func isTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	if err != nil {
+		return false
+	}
+	return info.Mode()&os.ModeCharDevice != 0
+}
@@ -0,0 +1 @@
+CREATE DATABASE `golden` /*!40100 DEFAULT CHARACTER SET utf8mb4 COLLATE utf8mb4_bin */;
@@ -0,0 +1,9 @@
+CREATE TABLE `t` (
+  `id` int NOT NULL,
+  `name` varchar(32) NOT NULL,
+  `price` decimal(10,2) NOT NULL,
+  `created` datetime NOT NULL,
+  `data` blob,
+  `note` text,
+  PRIMARY KEY (`id`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_bin;
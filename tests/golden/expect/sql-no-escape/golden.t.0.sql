@@ -0,0 +1,6 @@
+/*!40101 SET NAMES binary*/;
+INSERT INTO `t` VALUES
+(1,'apple',1.50,'2020-01-01 00:00:00',x'0102',NULL),
+(2,'O''Brien',-0.25,'2020-02-29 12:34:56',x'','line1
+line2'),
+(3,'back\slash',100.00,'1999-12-31 23:59:59',NULL,'tab	here, "quoted"');
@@ -0,0 +1,4 @@
+/*!40101 SET NAMES binary*/;
+INSERT INTO `t` VALUES
+(1,'apple',1.50,'2020-01-01 00:00:00',x'0102',NULL),
+(2,'O\'Brien',-0.25,'2020-02-29 12:34:56',x'','line1\nline2');
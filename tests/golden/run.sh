@@ -0,0 +1,22 @@
+#!/bin/sh
+
+# The golden file tests dump the fixture with every case of golden/cases, and
+# compare the data files with golden/expect/<case>. After changing the output
+# deliberately, run them with DUMPLING_UPDATE_GOLDEN=1 to update the expect.
+
+set -eu
+
+run_sql "DROP DATABASE IF EXISTS golden"
+run_sql_file "$DUMPLING_BASE_NAME/data/golden-schema-create.sql"
+export DUMPLING_TEST_DATABASE="golden"
+run_sql_file "$DUMPLING_BASE_NAME/data/golden.t-schema.sql"
+run_sql_file "$DUMPLING_BASE_NAME/data/golden.empty-schema.sql"
+run_sql_file "$DUMPLING_BASE_NAME/data/golden.t.sql"
+
+output_dir="$DUMPLING_OUTPUT_DIR"
+grep -v '^#' "$DUMPLING_BASE_NAME/cases" | while read -r name flags; do
+    echo "****************** Running golden case $name..."
+    # the flags are split by the shell deliberately
+    DUMPLING_OUTPUT_DIR="$output_dir/$name" run_dumpling $flags < /dev/null
+    check_golden "$DUMPLING_BASE_NAME/expect/$name" "$output_dir/$name"
+done
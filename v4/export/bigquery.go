@@ -0,0 +1,86 @@
+package export
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+)
+
+// bigQueryField is a column in the schema JSON of `bq load --schema`.
+type bigQueryField struct {
+	Name string `json:"name"`
+	Type string `json:"type"`
+	Mode string `json:"mode"`
+}
+
+// bigQueryType returns the type of BigQuery which loads the CSV value of col.
+// The binary values are written as raw bytes in CSV, so they are loaded as
+// STRING instead of the base64 encoded BYTES.
+func bigQueryType(col ColumnInfo) string {
+	switch col.DataType {
+	case "tinyint", "smallint", "mediumint", "int", "year":
+		return "INTEGER"
+	case "bigint":
+		if col.Unsigned {
+			return "NUMERIC"
+		}
+		return "INTEGER"
+	case "decimal":
+		if col.Precision > 0 && col.Precision-col.Scale <= 29 && col.Scale <= 9 {
+			return "NUMERIC"
+		}
+		return "BIGNUMERIC"
+	case "float", "double":
+		return "FLOAT"
+	case "bool":
+		return "BOOLEAN"
+	case "date":
+		return "DATE"
+	case "time":
+		return "TIME"
+	case "datetime":
+		return "DATETIME"
+	case "timestamp":
+		return "TIMESTAMP"
+	case "json":
+		return "JSON"
+	default:
+		return "STRING"
+	}
+}
+
+func buildBigQuerySchema(columns []ColumnInfo) ([]byte, error) {
+	fields := make([]bigQueryField, 0, len(columns))
+	for _, col := range columns {
+		mode := "REQUIRED"
+		if col.Nullable {
+			mode = "NULLABLE"
+		}
+		fields = append(fields, bigQueryField{Name: col.Name, Type: bigQueryType(col), Mode: mode})
+	}
+	schema, err := json.MarshalIndent(fields, "", "  ")
+	return append(schema, '\n'), withStack(err)
+}
+
+// writeBigQuerySchema writes the schema JSON of a table as `<db>.<table>-schema.json`.
+func writeBigQuerySchema(ctx context.Context, conf *Config, db *sql.DB, database, table string) error {
+	columns, err := conf.dialect().ListColumns(db, database, table)
+	if err != nil {
+		return withKind(ErrorKindSchema, err)
+	}
+	schema, err := buildBigQuerySchema(columns)
+	if err != nil {
+		return err
+	}
+	fileName := fmt.Sprintf("%s.%s-schema.json", database, table)
+	fileWriter, err := conf.ExternalStorage.Create(ctx, fileName)
+	if err != nil {
+		return err
+	}
+	if err = closeFile(fileWriter, writeBytes(fileWriter, schema)); err != nil {
+		return err
+	}
+	conf.hooks().OnFileClosed(conf.outputPath(ctx, fileName))
+	return nil
+}
@@ -0,0 +1,71 @@
+package export
+
+import (
+	"context"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	. "github.com/pingcap/check"
+)
+
+var _ = Suite(&testBigQuerySuite{})
+
+type testBigQuerySuite struct{}
+
+func (s *testBigQuerySuite) TestBigQueryType(c *C) {
+	for _, t := range []struct {
+		col      ColumnInfo
+		expected string
+	}{
+		{ColumnInfo{DataType: "int"}, "INTEGER"},
+		{ColumnInfo{DataType: "bigint", Unsigned: true}, "NUMERIC"},
+		{ColumnInfo{DataType: "decimal", Precision: 38, Scale: 9}, "NUMERIC"},
+		{ColumnInfo{DataType: "decimal", Precision: 65, Scale: 30}, "BIGNUMERIC"},
+		{ColumnInfo{DataType: "decimal"}, "BIGNUMERIC"},
+		{ColumnInfo{DataType: "double"}, "FLOAT"},
+		{ColumnInfo{DataType: "datetime"}, "DATETIME"},
+		{ColumnInfo{DataType: "timestamp"}, "TIMESTAMP"},
+		{ColumnInfo{DataType: "blob"}, "STRING"},
+		{ColumnInfo{DataType: "enum"}, "STRING"},
+	} {
+		c.Assert(bigQueryType(t.col), Equals, t.expected, Commentf("%s", t.col.DataType))
+	}
+}
+
+func (s *testBigQuerySuite) TestWriteBigQuerySchema(c *C) {
+	db, mock, err := sqlmock.New()
+	c.Assert(err, IsNil)
+	defer db.Close()
+
+	conf := DefaultConfig()
+	storage := newMemStorage()
+	conf.ExternalStorage = storage
+	columns := []string{"COLUMN_NAME", "DATA_TYPE", "COLUMN_TYPE", "IS_NULLABLE", "CHARACTER_MAXIMUM_LENGTH", "NUMERIC_PRECISION", "NUMERIC_SCALE"}
+	mock.ExpectQuery("SELECT COLUMN_NAME, DATA_TYPE").WithArgs("test", "t").WillReturnRows(
+		sqlmock.NewRows(columns).
+			AddRow("id", "int", "int(11)", "NO", nil, 10, 0).
+			AddRow("name", "varchar", "varchar(16)", "YES", 16, nil, nil))
+
+	c.Assert(writeBigQuerySchema(context.Background(), conf, db, "test", "t"), IsNil)
+	c.Assert(storage.files["test.t-schema.json"], Equals, `[
+  {
+    "name": "id",
+    "type": "INTEGER",
+    "mode": "REQUIRED"
+  },
+  {
+    "name": "name",
+    "type": "STRING",
+    "mode": "NULLABLE"
+  }
+]
+`)
+	c.Assert(mock.ExpectationsWereMet(), IsNil)
+}
+
+func (s *testBigQuerySuite) TestValidate(c *C) {
+	conf := DefaultConfig()
+	conf.BigQuerySchema = true
+	c.Assert(conf.Validate(), ErrorMatches, "bigquery-schema is only supported with filetype csv")
+	conf.FileType = "csv"
+	c.Assert(conf.Validate(), IsNil)
+}
@@ -0,0 +1,272 @@
+package export
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"fmt"
+	"os"
+	"os/exec"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pingcap/errors"
+	"go.uber.org/zap"
+
+	"github.com/pingcap/dumpling/v4/log"
+)
+
+// BinlogStopCurrent stops tailing the binlog at the position when the data
+// are dumped.
+const BinlogStopCurrent = "current"
+
+const defaultBinlogPollInterval = time.Second
+
+// binlogPosition is a position in the binlog files of MySQL.
+type binlogPosition struct {
+	file string
+	pos  uint64
+}
+
+// parseBinlogPosition parses a position given by `file:pos`.
+func parseBinlogPosition(s string) (binlogPosition, error) {
+	i := strings.LastIndex(s, ":")
+	if i <= 0 {
+		return binlogPosition{}, errors.Errorf("invalid binlog position %s, it should be like mysql-bin.000001:4", s)
+	}
+	pos, err := strconv.ParseUint(s[i+1:], 10, 64)
+	if err != nil {
+		return binlogPosition{}, errors.Errorf("invalid binlog position %s, it should be like mysql-bin.000001:4", s)
+	}
+	return binlogPosition{file: s[:i], pos: pos}, nil
+}
+
+func (p binlogPosition) String() string {
+	return fmt.Sprintf("%s:%d", p.file, p.pos)
+}
+
+// before returns whether p is before o, the files are ordered by their
+// sequence numbers.
+func (p binlogPosition) before(o binlogPosition) bool {
+	if p.file != o.file {
+		return binlogFileSeq(p.file) < binlogFileSeq(o.file)
+	}
+	return p.pos < o.pos
+}
+
+// binlogFileSeq returns the sequence number of a binlog file like
+// `mysql-bin.000012`.
+func binlogFileSeq(file string) uint64 {
+	seq, _ := strconv.ParseUint(strings.TrimPrefix(path.Ext(file), "."), 10, 64)
+	return seq
+}
+
+// binlogTailConflicts returns the invalid BinlogStop and the options
+// conflicting with it.
+func binlogTailConflicts(conf *Config) []string {
+	if conf.BinlogStop == "" {
+		return nil
+	}
+	var conflicts []string
+	if conf.BinlogStop != BinlogStopCurrent {
+		if _, err := parseBinlogPosition(conf.BinlogStop); err != nil {
+			conflicts = append(conflicts, err.Error())
+		}
+	}
+	if strings.ToLower(conf.FileType) != "sql" {
+		conflicts = append(conflicts, "binlog-stop is only supported with filetype sql")
+	}
+	if conf.Consistency == "none" {
+		conflicts = append(conflicts, "binlog-stop needs the consistency to start from the position of the dump")
+	}
+	// the changes of the rows not dumped can't be replayed
+	filtered := conf.Where != "" || conf.RowFilter != "" || conf.Subset
+	for _, tc := range conf.TableConfigs {
+		filtered = filtered || tc.Where != "" || tc.RowFilter != ""
+	}
+	if conf.Sql != "" || filtered {
+		conflicts = append(conflicts, "binlog-stop is not supported with sql, where, row filters or subset")
+	}
+	if len(conf.ColumnRewrites) > 0 || len(conf.RouteRules) > 0 {
+		conflicts = append(conflicts, "binlog-stop is not supported with column rewrites or route rules")
+	}
+	// mysqlbinlog only filters the events by one database, the others and the
+	// tables filtered out would be changed by the events replayed
+	if conf.Database == "" || strings.Contains(conf.Database, ",") || conf.BlackWhiteList.Mode != NopMode {
+		conflicts = append(conflicts, "binlog-stop needs a single database without the table filters")
+	}
+	if conf.TargetDSN != "" || len(conf.Shards) > 0 {
+		conflicts = append(conflicts, "binlog-stop is not supported with target-dsn or shards")
+	}
+	return conflicts
+}
+
+// currentBinlogPosition returns the position of SHOW MASTER STATUS.
+func currentBinlogPosition(db *sql.DB) (binlogPosition, error) {
+	const query = "SHOW MASTER STATUS"
+	rows, err := db.Query(query)
+	if err != nil {
+		return binlogPosition{}, withStack(errors.WithMessage(err, query))
+	}
+	defer rows.Close()
+	columns, err := rows.Columns()
+	if err != nil {
+		return binlogPosition{}, withStack(errors.WithMessage(err, query))
+	}
+	if !rows.Next() {
+		if err = rows.Err(); err != nil {
+			return binlogPosition{}, withStack(errors.WithMessage(err, query))
+		}
+		return binlogPosition{}, errors.New("the binlog of the source isn't enabled")
+	}
+	// Executed_Gtid_Set is only in MySQL 5.6 and later
+	values := make([]string, len(columns))
+	addr := make([]interface{}, len(columns))
+	for i := range values {
+		addr[i] = &values[i]
+	}
+	if err = rows.Scan(addr...); err != nil {
+		return binlogPosition{}, withStack(errors.WithMessage(err, query))
+	}
+	return parseBinlogPosition(values[fileFieldIndex] + ":" + values[posFieldIndex])
+}
+
+// listBinlogFiles returns the binlog files from the file of start to the file
+// of stop.
+func listBinlogFiles(db *sql.DB, start, stop binlogPosition) ([]string, error) {
+	const query = "SHOW BINARY LOGS"
+	rows, err := db.Query(query)
+	if err != nil {
+		return nil, withStack(errors.WithMessage(err, query))
+	}
+	defer rows.Close()
+	columns, err := rows.Columns()
+	if err != nil {
+		return nil, withStack(err)
+	}
+	var files []string
+	for rows.Next() {
+		// Log_name, File_size and Encrypted since MySQL 8.0.14
+		values := make([]sql.RawBytes, len(columns))
+		addr := make([]interface{}, len(columns))
+		for i := range values {
+			addr[i] = &values[i]
+		}
+		if err = rows.Scan(addr...); err != nil {
+			return nil, withStack(errors.WithMessage(err, query))
+		}
+		file := binlogPosition{file: string(values[0])}
+		if !file.before(binlogPosition{file: start.file}) && !stop.before(file) {
+			files = append(files, file.file)
+		}
+	}
+	if err = rows.Err(); err != nil {
+		return nil, withStack(errors.WithMessage(err, query))
+	}
+	if len(files) == 0 || files[0] != start.file {
+		return nil, withKind(ErrorKindConsistency, errors.Errorf("the binlog file %s of the dump is purged", start.file))
+	}
+	return files, nil
+}
+
+// tailBinlog writes the changes in the binlog from start, the position of the
+// dump, to conf.BinlogStop by mysqlbinlog, into a `{file}-binlog.sql` of every
+// binlog file. It waits for the source to reach the stop position, and
+// returns the stop position.
+func tailBinlog(ctx context.Context, conf *Config, db *sql.DB, start binlogPosition, pollInterval time.Duration) (binlogPosition, error) {
+	stop, err := currentBinlogPosition(db)
+	if err != nil {
+		return binlogPosition{}, withKind(ErrorKindConsistency, err)
+	}
+	if conf.BinlogStop != BinlogStopCurrent {
+		target, _ := parseBinlogPosition(conf.BinlogStop)
+		if target.before(start) {
+			return binlogPosition{}, withKind(ErrorKindConfig, errors.Errorf("the binlog stop position %s is before the position of the dump %s", target, start))
+		}
+		for stop.before(target) {
+			log.Info("wait for the binlog to reach the stop position", zap.Stringer("position", stop), zap.Stringer("stop", target))
+			select {
+			case <-ctx.Done():
+				return binlogPosition{}, ctx.Err()
+			case <-time.After(pollInterval):
+			}
+			if stop, err = currentBinlogPosition(db); err != nil {
+				return binlogPosition{}, withKind(ErrorKindConsistency, err)
+			}
+		}
+		stop = target
+	}
+	if !start.before(stop) {
+		log.Info("no binlog to tail", zap.Stringer("position", start))
+		return stop, nil
+	}
+	files, err := listBinlogFiles(db, start, stop)
+	if err != nil {
+		return binlogPosition{}, err
+	}
+	for i, file := range files {
+		args := []string{
+			"--read-from-remote-server",
+			"--host=" + conf.Host,
+			"--port=" + strconv.Itoa(conf.Port),
+			"--user=" + conf.User,
+			// the GTIDs of the source can't be executed on the target
+			"--skip-gtids",
+		}
+		if i == 0 {
+			args = append(args, fmt.Sprintf("--start-position=%d", start.pos))
+		}
+		if i == len(files)-1 {
+			args = append(args, fmt.Sprintf("--stop-position=%d", stop.pos))
+		}
+		args = append(args, "--database="+conf.Database)
+		if err = runMysqlbinlog(ctx, conf, append(args, file), file+"-binlog.sql"); err != nil {
+			return binlogPosition{}, err
+		}
+	}
+	log.Info("tail binlog successfully", zap.Stringer("start", start), zap.Stringer("stop", stop))
+	return stop, nil
+}
+
+// runMysqlbinlog writes the output of mysqlbinlog with args into fileName.
+func runMysqlbinlog(ctx context.Context, conf *Config, args []string, fileName string) error {
+	fileWriter, err := conf.ExternalStorage.Create(ctx, fileName)
+	if err != nil {
+		return err
+	}
+	cmd := exec.CommandContext(ctx, conf.MysqlbinlogPath, args...)
+	// the password isn't shown in the processes
+	cmd.Env = append(os.Environ(), "MYSQL_PWD="+conf.Password)
+	cmd.Stdout = fileWriter
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	log.Info("tail binlog", zap.String("command", conf.MysqlbinlogPath), zap.Strings("args", args))
+	if err = cmd.Run(); err != nil {
+		err = withKind(ErrorKindWrite, errors.Annotatef(err, "run %s: %s", conf.MysqlbinlogPath, bytes.TrimSpace(stderr.Bytes())))
+	}
+	if err = closeFile(fileWriter, err); err != nil {
+		return err
+	}
+	conf.hooks().OnFileClosed(conf.outputPath(ctx, fileName))
+	return nil
+}
+
+// binlogTailStart returns the position of the dump recorded in m, which the
+// binlog is tailed from.
+func binlogTailStart(conf *Config, m *globalMetadata) (binlogPosition, error) {
+	switch conf.ServerInfo.ServerType {
+	case ServerTypeMySQL, ServerTypeMariaDB:
+	default:
+		return binlogPosition{}, withKind(ErrorKindConfig, errors.Errorf("binlog-stop is only supported with the MySQL and MariaDB sources, got %s", conf.ServerInfo.ServerType))
+	}
+	if m.logFile == "" {
+		return binlogPosition{}, withKind(ErrorKindConsistency, errors.New("the binlog position of the dump is unknown"))
+	}
+	start, err := parseBinlogPosition(m.logFile + ":" + m.pos)
+	if err != nil {
+		return binlogPosition{}, withKind(ErrorKindConsistency, err)
+	}
+	return start, nil
+}
@@ -0,0 +1,155 @@
+package export
+
+import (
+	"context"
+	"io/ioutil"
+	"path/filepath"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	. "github.com/pingcap/check"
+	"github.com/pingcap/tidb-tools/pkg/filter"
+)
+
+var _ = Suite(&testBinlogTailSuite{})
+
+type testBinlogTailSuite struct{}
+
+func (s *testBinlogTailSuite) TestParseBinlogPosition(c *C) {
+	pos, err := parseBinlogPosition("mysql-bin.000012:1024")
+	c.Assert(err, IsNil)
+	c.Assert(pos, Equals, binlogPosition{file: "mysql-bin.000012", pos: 1024})
+	c.Assert(pos.String(), Equals, "mysql-bin.000012:1024")
+
+	for _, s := range []string{"mysql-bin.000012", ":4", "mysql-bin.000012:x"} {
+		_, err = parseBinlogPosition(s)
+		c.Assert(err, ErrorMatches, "invalid binlog position .*", Commentf("%s", s))
+	}
+
+	c.Assert(pos.before(binlogPosition{file: "mysql-bin.000012", pos: 2048}), IsTrue)
+	c.Assert(pos.before(binlogPosition{file: "mysql-bin.000100", pos: 4}), IsTrue)
+	c.Assert(pos.before(binlogPosition{file: "mysql-bin.000011", pos: 4096}), IsFalse)
+	c.Assert(pos.before(pos), IsFalse)
+}
+
+func (s *testBinlogTailSuite) TestValidate(c *C) {
+	conf := DefaultConfig()
+	conf.BinlogStop = BinlogStopCurrent
+	c.Assert(conf.Validate(), ErrorMatches, "binlog-stop needs a single database without the table filters")
+	conf.Database = "shop,crm"
+	c.Assert(conf.Validate(), ErrorMatches, "binlog-stop needs a single database without the table filters")
+	conf.Database = "shop"
+	conf.BlackWhiteList = BWListConf{Mode: MySQLReplicationMode, Rules: &MySQLReplicationConf{Rules: &filter.Rules{DoDBs: []string{"shop"}}}}
+	c.Assert(conf.Validate(), ErrorMatches, "binlog-stop needs a single database without the table filters")
+	conf.BlackWhiteList = BWListConf{}
+	c.Assert(conf.Validate(), IsNil)
+	conf.BinlogStop = "mysql-bin.000003:1024"
+	c.Assert(conf.Validate(), IsNil)
+
+	conf.BinlogStop = "mysql-bin.000003"
+	c.Assert(conf.Validate(), ErrorMatches, "(?s).*invalid binlog position mysql-bin.000003.*")
+	conf.BinlogStop = BinlogStopCurrent
+	conf.FileType = "csv"
+	c.Assert(conf.Validate(), ErrorMatches, "(?s).*binlog-stop is only supported with filetype sql.*")
+	conf.FileType = "sql"
+	conf.Where = "id < 100"
+	c.Assert(conf.Validate(), ErrorMatches, "(?s).*binlog-stop is not supported with sql, where, row filters or subset.*")
+	conf.Where = ""
+	conf.Consistency = "none"
+	c.Assert(conf.Validate(), ErrorMatches, "(?s).*binlog-stop needs the consistency.*")
+}
+
+// writeFakeMysqlbinlog writes a script printing its arguments like the
+// statements of mysqlbinlog.
+func writeFakeMysqlbinlog(c *C) string {
+	script := filepath.Join(c.MkDir(), "mysqlbinlog")
+	content := "#!/bin/sh\necho \"# at 4\"\necho \"SELECT '$*' /* $MYSQL_PWD */;\"\n"
+	c.Assert(ioutil.WriteFile(script, []byte(content), 0755), IsNil)
+	return script
+}
+
+func (s *testBinlogTailSuite) TestTailBinlog(c *C) {
+	db, mock, err := sqlmock.New()
+	c.Assert(err, IsNil)
+	defer db.Close()
+
+	storage := newMemStorage()
+	conf := DefaultConfig()
+	conf.Host = "127.0.0.1"
+	conf.Port = 3306
+	conf.User = "root"
+	conf.Password = "secret"
+	conf.Database = "shop"
+	conf.BinlogStop = "mysql-bin.000003:1024"
+	conf.MysqlbinlogPath = writeFakeMysqlbinlog(c)
+	conf.ExternalStorage = storage
+
+	masterStatus := []string{"File", "Position", "Binlog_Do_DB", "Binlog_Ignore_DB", "Executed_Gtid_Set"}
+	mock.ExpectQuery("SHOW MASTER STATUS").WillReturnRows(
+		sqlmock.NewRows(masterStatus).AddRow("mysql-bin.000002", "4", "", "", ""))
+	mock.ExpectQuery("SHOW MASTER STATUS").WillReturnRows(
+		sqlmock.NewRows(masterStatus).AddRow("mysql-bin.000003", "2048", "", "", ""))
+	mock.ExpectQuery("SHOW BINARY LOGS").WillReturnRows(
+		sqlmock.NewRows([]string{"Log_name", "File_size"}).
+			AddRow("mysql-bin.000001", 1000).
+			AddRow("mysql-bin.000002", 1000).
+			AddRow("mysql-bin.000003", 2048).
+			AddRow("mysql-bin.000004", 4))
+
+	start := binlogPosition{file: "mysql-bin.000002", pos: 120}
+	stop, err := tailBinlog(context.Background(), conf, db, start, 0)
+	c.Assert(err, IsNil)
+	c.Assert(stop, Equals, binlogPosition{file: "mysql-bin.000003", pos: 1024})
+	c.Assert(mock.ExpectationsWereMet(), IsNil)
+	c.Assert(storage.files, DeepEquals, map[string]string{
+		"mysql-bin.000002-binlog.sql": "# at 4\nSELECT '--read-from-remote-server --host=127.0.0.1 --port=3306 --user=root --skip-gtids --start-position=120 --database=shop mysql-bin.000002' /* secret */;\n",
+		"mysql-bin.000003-binlog.sql": "# at 4\nSELECT '--read-from-remote-server --host=127.0.0.1 --port=3306 --user=root --skip-gtids --stop-position=1024 --database=shop mysql-bin.000003' /* secret */;\n",
+	})
+
+	m := newGlobalMetadata(storage)
+	m.recordStartTime(time.Now())
+	m.recordBinlogTail(stop)
+	c.Assert(m.String(), Matches, "(?s).*BINLOG TAIL:\n\t\tLog: mysql-bin.000003\n\t\tPos: 1024\n.*")
+
+	// the binlog file of the dump is purged
+	mock.ExpectQuery("SHOW MASTER STATUS").WillReturnRows(
+		sqlmock.NewRows(masterStatus).AddRow("mysql-bin.000003", "2048", "", "", ""))
+	mock.ExpectQuery("SHOW BINARY LOGS").WillReturnRows(
+		sqlmock.NewRows([]string{"Log_name", "File_size"}).AddRow("mysql-bin.000003", 2048))
+	_, err = tailBinlog(context.Background(), conf, db, start, 0)
+	c.Assert(err, ErrorMatches, "the binlog file mysql-bin.000002 of the dump is purged")
+	c.Assert(ErrorKindOf(err), Equals, ErrorKindConsistency)
+
+	// the stop position is before the dump
+	conf.BinlogStop = "mysql-bin.000001:4"
+	mock.ExpectQuery("SHOW MASTER STATUS").WillReturnRows(
+		sqlmock.NewRows(masterStatus).AddRow("mysql-bin.000003", "2048", "", "", ""))
+	_, err = tailBinlog(context.Background(), conf, db, start, 0)
+	c.Assert(err, ErrorMatches, "the binlog stop position mysql-bin.000001:4 is before the position of the dump mysql-bin.000002:120")
+	c.Assert(ErrorKindOf(err), Equals, ErrorKindConfig)
+	c.Assert(mock.ExpectationsWereMet(), IsNil)
+}
+
+func (s *testBinlogTailSuite) TestLoadBinlog(c *C) {
+	dir := writeDumpFiles(c, map[string]string{
+		"shop-schema-create.sql":      "CREATE DATABASE `shop`;\n",
+		"mysql-bin.000002-binlog.sql": "# at 4\n#201014 10:00:00 server id 1\nDELIMITER /*!*/;\n# at 120\nBEGIN\n/*!*/;\n# End of log file\nDELIMITER ;\n",
+	})
+	plan, err := loadPlan(dir)
+	c.Assert(err, IsNil)
+	c.Assert(plan.Batches, DeepEquals, []RestoreBatch{
+		{Name: "databases", Parallel: true, Files: []string{"shop-schema-create.sql"}},
+		{Name: "binlog", Parallel: false, Files: []string{"mysql-bin.000002-binlog.sql"}},
+	})
+
+	db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+	c.Assert(err, IsNil)
+	defer db.Close()
+	result := sqlmock.NewResult(0, 0)
+	mock.ExpectExec("SET SESSION FOREIGN_KEY_CHECKS = 0").WillReturnResult(result)
+	mock.ExpectExec("CREATE DATABASE `shop`").WillReturnResult(result)
+	mock.ExpectExec("SET SESSION FOREIGN_KEY_CHECKS = 0").WillReturnResult(result)
+	mock.ExpectExec("# at 120\nBEGIN\n").WillReturnResult(result)
+	c.Assert(load(context.Background(), &LoadConfig{Dir: dir, Threads: 2}, db, plan), IsNil)
+	c.Assert(mock.ExpectationsWereMet(), IsNil)
+}
@@ -1,6 +1,8 @@
 package export
 
 import (
+	"strings"
+
 	"github.com/pingcap/dumpling/v4/log"
 	"github.com/pingcap/tidb-tools/pkg/filter"
 	"go.uber.org/zap"
@@ -34,7 +36,7 @@ type MySQLReplicationBWList struct {
 }
 
 func (bw *MySQLReplicationBWList) Apply(schema, table string) bool {
-	return bw.Match(&filter.Table{schema, table})
+	return bw.Match(&filter.Table{Schema: schema, Name: table})
 }
 
 type NopeBWList struct{}
@@ -60,18 +62,49 @@ func NewBWList(conf BWListConf) (BWList, error) {
 	return &NopeBWList{}, nil
 }
 
+// filterDirtySchemaTables removes the system schemas like mysql and sys unless
+// DumpSystemSchemas is set, or they're given by Database of a server other
+// than TiDB. The in-memory schemas like INFORMATION_SCHEMA are always removed,
+// since they only show the state of the server.
 func filterDirtySchemaTables(conf *Config) {
-	switch conf.ServerInfo.ServerType {
-	case ServerTypeTiDB:
-		if conf.Sql == "" {
-			for dbName := range conf.Tables {
-				if filter.IsSystemSchema(dbName) {
-					log.Warn("unsupported dump schema in TiDB now", zap.String("schema", dbName))
-					delete(conf.Tables, dbName)
-				}
-			}
+	if conf.Sql != "" {
+		return
+	}
+	for dbName := range conf.Tables {
+		if !filter.IsSystemSchema(dbName) {
+			continue
+		}
+		explicit := conf.ServerInfo.ServerType != ServerTypeTiDB && isExplicitDatabase(conf, dbName)
+		if !isMemorySchema(dbName) && (conf.DumpSystemSchemas || explicit) {
+			continue
+		}
+		log.Warn("skip the system schema", zap.String("schema", dbName),
+			zap.Bool("dump-system-schemas", conf.DumpSystemSchemas))
+		delete(conf.Tables, dbName)
+	}
+}
+
+// isExplicitDatabase returns whether dbName is given by Database.
+func isExplicitDatabase(conf *Config, dbName string) bool {
+	if conf.Database == "" {
+		return false
+	}
+	for _, db := range strings.Split(conf.Database, ",") {
+		if db == dbName {
+			return true
 		}
 	}
+	return false
+}
+
+// isMemorySchema returns whether the system schema is in memory, whose tables
+// are generated by the server.
+func isMemorySchema(dbName string) bool {
+	switch strings.ToUpper(dbName) {
+	case filter.InformationSchemaName, filter.PerformanceSchemaName, filter.MetricSchemaName, filter.InspectionSchemaName:
+		return true
+	}
+	return false
 }
 
 func filterTables(conf *Config) error {
@@ -117,3 +117,39 @@ func (s *testBWListSuite) TestFilterDatabaseWithNoTable(c *C) {
 	c.Assert(conf.Tables, HasLen, 1)
 	c.Assert(conf.Tables, DeepEquals, expectedDBTables)
 }
+
+func (s *testBWListSuite) TestFilterSystemSchemas(c *C) {
+	newTables := func() DatabaseTables {
+		return NewDatabaseTables().
+			AppendTables("mysql", "time_zone", "proc").
+			AppendTables("sys", "sys_config").
+			AppendTables("INFORMATION_SCHEMA", "TABLES").
+			AppendTables("performance_schema", "threads").
+			AppendTables("test", "t")
+	}
+	conf := &Config{ServerInfo: ServerInfo{ServerType: ServerTypeMySQL}, Tables: newTables()}
+	c.Assert(filterTables(conf), IsNil)
+	c.Assert(conf.Tables, HasLen, 1)
+	c.Assert(conf.Tables["test"], HasLen, 1)
+
+	// the in-memory schemas are always skipped
+	conf.Tables = newTables()
+	conf.DumpSystemSchemas = true
+	c.Assert(filterTables(conf), IsNil)
+	c.Assert(conf.Tables, HasLen, 3)
+	c.Assert(conf.Tables["mysql"], HasLen, 2)
+	c.Assert(conf.Tables["sys"], HasLen, 1)
+
+	// the system schemas given by the database are dumped, except by TiDB
+	conf.Tables = newTables()
+	conf.DumpSystemSchemas = false
+	conf.Database = "mysql,INFORMATION_SCHEMA,test"
+	c.Assert(filterTables(conf), IsNil)
+	c.Assert(conf.Tables, HasLen, 2)
+	c.Assert(conf.Tables["mysql"], HasLen, 2)
+	conf.Tables = newTables()
+	conf.ServerInfo.ServerType = ServerTypeTiDB
+	c.Assert(filterTables(conf), IsNil)
+	c.Assert(conf.Tables, HasLen, 1)
+	c.Assert(conf.Tables["test"], HasLen, 1)
+}
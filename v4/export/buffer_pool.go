@@ -0,0 +1,94 @@
+package export
+
+import (
+	"bytes"
+	"sync"
+)
+
+const (
+	minBufferSize = 64 * 1024
+	maxBufferSize = 64 * 1024 * 1024
+)
+
+// defaultBufferPool is used by WriteInsert and WriteInsertInCsv if no BufferPool is given.
+var defaultBufferPool = NewBufferPool(UnspecifiedSize, UnspecifiedSize)
+
+// BufferPool reuses the serialization buffers across all the tables and chunks
+// of a dump, and limits the bytes waiting to be written by MemoryBudget.
+// All the methods are safe to be called concurrently.
+type BufferPool struct {
+	pool   sync.Pool
+	size   int
+	budget *MemoryBudget
+	// depth is the number of the filled buffers in flight in a writerPipe,
+	// it's writerPipeDepth if not set.
+	depth int
+	// failure aborts the dump at the first write error of the pipes if it's set.
+	failure *writeFailure
+}
+
+// NewBufferPool creates a BufferPool. The buffers are sized to hold an INSERT
+// statement of statementSize bytes, or 1 MiB if it's UnspecifiedSize.
+// maxMemory is the limit of the bytes waiting to be written, see MemoryBudget.
+func NewBufferPool(statementSize, maxMemory uint64) *BufferPool {
+	size := lengthLimit
+	if statementSize != UnspecifiedSize {
+		switch {
+		case statementSize < minBufferSize:
+			size = minBufferSize
+		case statementSize > maxBufferSize:
+			size = maxBufferSize
+		default:
+			size = int(statementSize)
+		}
+	}
+	return &BufferPool{
+		pool: sync.Pool{New: func() interface{} {
+			return &bytes.Buffer{}
+		}},
+		size:   size,
+		budget: NewMemoryBudget(maxMemory),
+	}
+}
+
+// newConfigBufferPool creates the BufferPool of the writers of conf. The
+// buffers are sized by WriterBufferSize, or StatementSize if it's not set.
+func newConfigBufferPool(conf *Config) *BufferPool {
+	size := conf.StatementSize
+	if conf.WriterBufferSize != UnspecifiedSize {
+		size = conf.WriterBufferSize
+	}
+	p := NewBufferPool(size, conf.MaxMemory)
+	p.depth = conf.WriterQueueDepth
+	p.failure = conf.writeFailure
+	return p
+}
+
+// Size returns the size of the buffers, a buffer is considered full when its length reaches it.
+func (p *BufferPool) Size() int {
+	return p.size
+}
+
+func (p *BufferPool) pipeDepth() int {
+	if p.depth <= 0 {
+		return writerPipeDepth
+	}
+	return p.depth
+}
+
+func (p *BufferPool) get() *bytes.Buffer {
+	bf := p.pool.Get().(*bytes.Buffer)
+	if bfCap := bf.Cap(); bfCap < p.size {
+		bf.Grow(p.size - bfCap)
+	}
+	return bf
+}
+
+func (p *BufferPool) put(bf *bytes.Buffer) {
+	// don't keep the buffers grown by huge rows, they would pin the memory
+	if bf.Cap() > 2*p.size {
+		return
+	}
+	bf.Reset()
+	p.pool.Put(bf)
+}
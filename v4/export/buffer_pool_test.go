@@ -0,0 +1,62 @@
+package export
+
+import (
+	"bytes"
+	"context"
+	"database/sql/driver"
+
+	. "github.com/pingcap/check"
+)
+
+var _ = Suite(&testBufferPoolSuite{})
+
+type testBufferPoolSuite struct{}
+
+func (s *testBufferPoolSuite) TestBufferSize(c *C) {
+	c.Assert(NewBufferPool(UnspecifiedSize, UnspecifiedSize).Size(), Equals, lengthLimit)
+	c.Assert(NewBufferPool(1024, UnspecifiedSize).Size(), Equals, minBufferSize)
+	c.Assert(NewBufferPool(4*1024*1024, UnspecifiedSize).Size(), Equals, 4*1024*1024)
+	c.Assert(NewBufferPool(1<<40, UnspecifiedSize).Size(), Equals, maxBufferSize)
+
+	p := NewBufferPool(UnspecifiedSize, UnspecifiedSize)
+	c.Assert(p.budget, IsNil)
+	bf := p.get()
+	c.Assert(bf.Cap() >= lengthLimit, IsTrue)
+	bf.WriteString("leftover")
+	p.put(bf)
+	c.Assert(p.get().Len(), Equals, 0)
+}
+
+func (s *testBufferPoolSuite) TestConfigBufferPool(c *C) {
+	conf := DefaultConfig()
+	p := newConfigBufferPool(conf)
+	c.Assert(p.Size(), Equals, lengthLimit)
+	c.Assert(p.pipeDepth(), Equals, writerPipeDepth)
+
+	// the writer buffer size overrides the statement size
+	conf.StatementSize = 4 * 1024 * 1024
+	c.Assert(newConfigBufferPool(conf).Size(), Equals, 4*1024*1024)
+	conf.WriterBufferSize = 256 * 1024
+	conf.WriterQueueDepth = 32
+	p = newConfigBufferPool(conf)
+	c.Assert(p.Size(), Equals, 256*1024)
+	c.Assert(p.pipeDepth(), Equals, 32)
+	c.Assert(cap(newWriterPipe(&bytes.Buffer{}, p).input), Equals, 32)
+
+	conf.WriterQueueDepth = 0
+	c.Assert(conf.Validate(), ErrorMatches, "writer-queue-depth should be positive, got 0")
+}
+
+func (s *testBufferPoolSuite) TestWriteInsertWithSmallBuffers(c *C) {
+	value := string(bytes.Repeat([]byte("a"), 1024))
+	data := make([][]driver.Value, 0, 200)
+	for i := 0; i < 200; i++ {
+		data = append(data, []driver.Value{value})
+	}
+	// 200 KiB of rows go through 64 KiB buffers
+	buffers := NewBufferPool(minBufferSize, UnspecifiedSize)
+	bf := &bytes.Buffer{}
+	err := WriteInsert(context.Background(), newMockTableIR("test", "t", data, nil, []string{"VARCHAR"}), bf, UnspecifiedSize, buffers)
+	c.Assert(err, IsNil)
+	c.Assert(bytes.Count(bf.Bytes(), []byte("('"+value+"')")), Equals, 200)
+}
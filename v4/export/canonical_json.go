@@ -0,0 +1,78 @@
+package export
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+
+	"github.com/pingcap/errors"
+)
+
+// jsonTypes are the types of the JSON columns of MySQL and PostgreSQL.
+var jsonTypes = map[string]bool{
+	"JSON":  true,
+	"JSONB": true,
+}
+
+// canonicalJSONConflicts returns the options conflicting with CanonicalJSON.
+func canonicalJSONConflicts(conf *Config) []string {
+	// the files of the server are written without reading the rows
+	if conf.CanonicalJSON && conf.ServerOutfileDir != "" {
+		return []string{"canonical-json is not supported with server-outfile-dir"}
+	}
+	return nil
+}
+
+// canonicalJSON returns value serialized again with the keys of the objects
+// sorted and without the insignificant whitespace, the numbers are kept as
+// they're written. It fails if value isn't a JSON document.
+func canonicalJSON(value []byte) ([]byte, error) {
+	dec := json.NewDecoder(bytes.NewReader(value))
+	dec.UseNumber()
+	var v interface{}
+	if err := dec.Decode(&v); err != nil {
+		return nil, errors.Trace(err)
+	}
+	if _, err := dec.Token(); err != io.EOF {
+		return nil, errors.New("invalid data after the document")
+	}
+	var bf bytes.Buffer
+	enc := json.NewEncoder(&bf)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(v); err != nil {
+		return nil, errors.Trace(err)
+	}
+	// Encode ends the document with a line feed
+	return bytes.TrimSuffix(bf.Bytes(), []byte{'\n'}), nil
+}
+
+// withCanonicalJSON returns ir with the values of its JSON columns in the
+// canonical form if CanonicalJSON is set, or ir itself otherwise.
+func withCanonicalJSON(conf *Config, ir TableDataIR) TableDataIR {
+	if !conf.CanonicalJSON {
+		return ir
+	}
+	var rewriters []columnRewriter
+	columns := ir.ColumnNames()
+	for i, colType := range ir.ColumnTypes() {
+		if !jsonTypes[colType] {
+			continue
+		}
+		if rewriters == nil {
+			rewriters = make([]columnRewriter, len(columns))
+		}
+		column := columns[i]
+		rewriters[i] = func(value []byte) ([]byte, error) {
+			canonical, err := canonicalJSON(value)
+			if err != nil {
+				return nil, errors.Errorf("the column %s of %s has the malformed JSON %q: %s",
+					column, qualifiedTableName(ir.DatabaseName(), ir.TableName()), value, err)
+			}
+			return canonical, nil
+		}
+	}
+	if rewriters == nil {
+		return ir
+	}
+	return &rewrittenTableData{TableDataIR: ir, rewriters: rewriters}
+}
@@ -0,0 +1,74 @@
+package export
+
+import (
+	"bytes"
+	"context"
+	"database/sql/driver"
+
+	. "github.com/pingcap/check"
+)
+
+var _ = Suite(&testCanonicalJSONSuite{})
+
+type testCanonicalJSONSuite struct{}
+
+func (s *testCanonicalJSONSuite) TestCanonicalJSON(c *C) {
+	for _, t := range []struct {
+		value     string
+		canonical string
+	}{
+		{`{"b": [1, 2.50, {"d": null, "c": "<x>"}], "a": "é"}`, `{"a":"é","b":[1,2.50,{"c":"<x>","d":null}]}`},
+		{` "ab" `, `"ab"`},
+		{`18446744073709551615`, `18446744073709551615`},
+		{`[]`, `[]`},
+	} {
+		canonical, err := canonicalJSON([]byte(t.value))
+		c.Assert(err, IsNil, Commentf("value %s", t.value))
+		c.Assert(string(canonical), Equals, t.canonical, Commentf("value %s", t.value))
+	}
+
+	_, err := canonicalJSON([]byte(`{"a":`))
+	c.Assert(err, ErrorMatches, "unexpected EOF")
+	_, err = canonicalJSON([]byte(`{} []`))
+	c.Assert(err, ErrorMatches, "invalid data after the document")
+	_, err = canonicalJSON([]byte(``))
+	c.Assert(err, NotNil)
+}
+
+func jsonTableIR(doc string) TableDataIR {
+	data := [][]driver.Value{
+		{"1", `{"b": 1, "a": [true, false]}`, `{"b": 1, "a": 2}`},
+		{"2", nil, "{}"},
+		{"3", doc, "{}"},
+	}
+	tableIR := newMockTableIR("test", "t", data, nil, []string{"INT", "JSON", "TEXT"})
+	tableIR.(*mockTableIR).colNames = []string{"id", "doc", "note"}
+	return tableIR
+}
+
+func (s *testCanonicalJSONSuite) TestWithCanonicalJSON(c *C) {
+	conf := DefaultConfig()
+	ir := jsonTableIR("[]")
+	c.Assert(withCanonicalJSON(conf, ir), Equals, ir)
+
+	conf.CanonicalJSON = true
+	bf := &bytes.Buffer{}
+	c.Assert(WriteInsert(context.Background(), withCanonicalJSON(conf, jsonTableIR(`[1, {"y": 2, "x": 1}]`)), bf, UnspecifiedSize, nil), IsNil)
+	// only the JSON columns are canonicalized
+	c.Assert(bf.String(), Equals, "INSERT INTO `t` VALUES\n"+
+		`(1,'{"a":[true,false],"b":1}','{"b": 1, "a": 2}'),`+"\n"+
+		"(2,NULL,'{}'),\n"+
+		`(3,'[1,{"x":1,"y":2}]','{}');`+"\n")
+
+	bf.Reset()
+	err := WriteInsert(context.Background(), withCanonicalJSON(conf, jsonTableIR(`{"a": 1,}`)), bf, UnspecifiedSize, nil)
+	c.Assert(err, ErrorMatches, "the column doc of `test`.`t` has the malformed JSON \"{\\\\\"a\\\\\": 1,}\": invalid character '}' looking for beginning of object key string")
+}
+
+func (s *testCanonicalJSONSuite) TestValidate(c *C) {
+	conf := DefaultConfig()
+	conf.CanonicalJSON = true
+	c.Assert(conf.Validate(), IsNil)
+	conf.ServerOutfileDir = "/tmp/outfile"
+	c.Assert(conf.Validate(), ErrorMatches, "(?s).*canonical-json is not supported with server-outfile-dir.*")
+}
@@ -0,0 +1,184 @@
+package export
+
+import (
+	"database/sql"
+
+	"github.com/coreos/go-semver/semver"
+	"github.com/pingcap/errors"
+	"go.uber.org/zap"
+
+	"github.com/pingcap/dumpling/v4/log"
+)
+
+// ServerCapabilities are the features of the server which select the paths
+// of dumping, they're derived from the type and version of the server.
+type ServerCapabilities struct {
+	// Snapshot is reading the historical data by tidb_snapshot.
+	Snapshot bool
+	// TiDBRowID is the hidden _tidb_rowid column of the tables without
+	// integer primary key.
+	TiDBRowID bool
+	// FlushTablesWithReadLock is locking all the tables by FTWRL.
+	FlushTablesWithReadLock bool
+	// LockTables is LOCK TABLES ... READ.
+	LockTables bool
+	// ShowWarnings is SHOW WARNINGS after reading the rows.
+	ShowWarnings bool
+	// ThreadsRunning is the Threads_running global status.
+	ThreadsRunning bool
+	// ReplicaStatus is SHOW SLAVE STATUS and STOP/START SLAVE SQL_THREAD.
+	ReplicaStatus bool
+	// MasterStatusGTID is the Executed_Gtid_Set column of SHOW MASTER STATUS,
+	// which is added in MySQL 5.6. MariaDB has @@global.gtid_binlog_pos instead.
+	MasterStatusGTID bool
+	// Sequences is CREATE SEQUENCE, which is added in MariaDB 10.3 and TiDB 4.0.
+	Sequences bool
+	// TiFlashReplica is the TiFlash replicas of the tables of TiDB 4.0, which
+	// are listed in INFORMATION_SCHEMA.TIFLASH_REPLICA.
+	TiFlashReplica bool
+	// PlacementPolicies is SHOW CREATE PLACEMENT POLICY of TiDB 5.3.
+	PlacementPolicies bool
+	// MaxExecutionTime is the MAX_EXECUTION_TIME hint of the SELECT statements,
+	// which is added in MySQL 5.7.8 and TiDB 3.0.
+	MaxExecutionTime bool
+	// MaxStatementTime is SET STATEMENT max_statement_time=N FOR in MariaDB 10.1.
+	MaxStatementTime bool
+	// LowPriority is SELECT LOW_PRIORITY of TiDB, whose coprocessor requests
+	// are scheduled after the others.
+	LowPriority bool
+	// ResourceGroups is SET RESOURCE GROUP of the resource control of TiDB 7.1.
+	ResourceGroups bool
+	// ReplicaRead is the tidb_replica_read reading from the followers of TiDB 4.0.
+	ReplicaRead bool
+	// StaleRead is SELECT ... AS OF TIMESTAMP of TiDB 5.1.
+	StaleRead bool
+}
+
+// Capabilities returns the capabilities of the server. The version is
+// assumed to be the latest if it's unknown.
+func (info ServerInfo) Capabilities() ServerCapabilities {
+	atLeast := func(version string) bool {
+		return info.ServerVersion == nil || !info.ServerVersion.LessThan(*semver.New(version))
+	}
+	switch info.ServerType {
+	case ServerTypeMySQL:
+		return ServerCapabilities{
+			FlushTablesWithReadLock: true,
+			LockTables:              true,
+			ShowWarnings:            true,
+			ThreadsRunning:          true,
+			ReplicaStatus:           true,
+			MasterStatusGTID:        atLeast("5.6.0"),
+			MaxExecutionTime:        atLeast("5.7.8"),
+		}
+	case ServerTypeMariaDB:
+		return ServerCapabilities{
+			FlushTablesWithReadLock: true,
+			LockTables:              true,
+			ShowWarnings:            true,
+			ThreadsRunning:          true,
+			ReplicaStatus:           true,
+			Sequences:               atLeast("10.3.0"),
+			MaxStatementTime:        atLeast("10.1.2"),
+		}
+	case ServerTypeTiDB:
+		return ServerCapabilities{
+			Snapshot:          true,
+			TiDBRowID:         true,
+			LockTables:        true,
+			ShowWarnings:      true,
+			MasterStatusGTID:  true,
+			Sequences:         atLeast("4.0.0"),
+			TiFlashReplica:    atLeast("4.0.0"),
+			PlacementPolicies: atLeast("5.3.0"),
+			MaxExecutionTime:  atLeast("3.0.0"),
+			LowPriority:       true,
+			ResourceGroups:    atLeast("7.1.0"),
+			ReplicaRead:       atLeast("4.0.0"),
+			StaleRead:         atLeast("5.1.0"),
+		}
+	default:
+		// PostgreSQL is dumped without all the features above
+		return ServerCapabilities{}
+	}
+}
+
+// validateServer checks the options against the capabilities of the server,
+// it's called after ServerInfo is detected so that the unsupported options
+// fail before dumping. The options which can be skipped only log warnings.
+func (conf *Config) validateServer() error {
+	caps := conf.ServerInfo.Capabilities()
+	serverType := conf.ServerInfo.ServerType
+	resolveAutoConsistency(conf)
+
+	if conf.Snapshot != "" && !caps.Snapshot {
+		return errors.Errorf("snapshot is only supported by TiDB, got %s", serverType)
+	}
+	if conf.Consistency == "snapshot" && !caps.Snapshot {
+		return errors.Errorf("snapshot consistency is not supported by %s", serverType)
+	}
+	if conf.Consistency == "flush" && !caps.FlushTablesWithReadLock {
+		return errors.Errorf("flush consistency is not supported by %s", serverType)
+	}
+	if conf.Consistency == "lock" && !caps.LockTables {
+		return errors.Errorf("lock consistency is not supported by %s", serverType)
+	}
+	if conf.CaptureWarnings && !caps.ShowWarnings {
+		return errors.Errorf("capturing warnings is not supported by %s", serverType)
+	}
+	if conf.TiDBReplicas && !caps.TiFlashReplica {
+		return errors.Errorf("dumping the replicas is only supported by TiDB 4.0 and later, got %s", serverType)
+	}
+	if conf.StopReplicaSQLThread && !caps.ReplicaStatus {
+		return errors.Errorf("stopping replica SQL thread is only supported by MySQL and MariaDB, got %s", serverType)
+	}
+	if conf.ResourceGroup != "" && !caps.ResourceGroups {
+		return errors.Errorf("resource-group is only supported by TiDB 7.1 and later, got %s", serverType)
+	}
+	if conf.LowPriority && !caps.LowPriority {
+		return errors.Errorf("low-priority is only supported by TiDB, got %s", serverType)
+	}
+	if conf.TiDBReplicaRead != "" && !caps.ReplicaRead {
+		return errors.Errorf("tidb-replica-read is only supported by TiDB 4.0 and later, got %s", serverType)
+	}
+	if conf.StaleRead != UnspecifiedSize && !caps.StaleRead {
+		return errors.Errorf("stale-read is only supported by TiDB 5.1 and later, got %s", serverType)
+	}
+
+	if conf.MaxReplicaLag != UnspecifiedSize && !caps.ReplicaStatus {
+		log.Warn("replica lag is not checked since it's not supported by the server",
+			zap.String("server", serverType.String()))
+		conf.MaxReplicaLag = UnspecifiedSize
+	}
+	if conf.LongQueryGuard != UnspecifiedSize && conf.Consistency != "flush" {
+		log.Warn("long query guard is ignored since it only works with flush consistency",
+			zap.String("consistency", conf.Consistency))
+	}
+	if conf.MaxLockTime != UnspecifiedSize && !holdsLocks(conf.Consistency) {
+		log.Warn("max lock time is ignored since the consistency doesn't lock tables",
+			zap.String("consistency", conf.Consistency))
+	}
+	if serverType == ServerTypeMySQL && !caps.MasterStatusGTID {
+		log.Warn("GTID set isn't recorded in metadata since it's not supported by the server",
+			zap.Stringer("version", conf.ServerInfo.ServerVersion))
+	}
+	return nil
+}
+
+// warnSkippedSequences logs the sequences of the databases when they're not
+// dumped to the output, so that they don't go missing silently.
+func warnSkippedSequences(conf *Config, db *sql.DB, databases []string) {
+	if !conf.ServerInfo.Capabilities().Sequences {
+		return
+	}
+	for _, dbName := range databases {
+		sequences, err := ListAllSequences(db, dbName)
+		if err != nil {
+			log.Warn("list sequences failed", zap.String("database", dbName), zap.Error(err))
+			continue
+		}
+		if len(sequences) > 0 {
+			log.Warn("sequences are not dumped", zap.String("database", dbName), zap.Strings("sequences", sequences))
+		}
+	}
+}
@@ -0,0 +1,62 @@
+package export
+
+import (
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/coreos/go-semver/semver"
+	. "github.com/pingcap/check"
+)
+
+var _ = Suite(&testCapabilitiesSuite{})
+
+type testCapabilitiesSuite struct{}
+
+func (s *testCapabilitiesSuite) TestCapabilities(c *C) {
+	cases := []struct {
+		serverType ServerType
+		version    string
+		expect     ServerCapabilities
+	}{
+		{ServerTypeMySQL, "5.5.62", ServerCapabilities{FlushTablesWithReadLock: true, LockTables: true, ShowWarnings: true, ThreadsRunning: true, ReplicaStatus: true}},
+		{ServerTypeMySQL, "8.0.18", ServerCapabilities{FlushTablesWithReadLock: true, LockTables: true, ShowWarnings: true, ThreadsRunning: true, ReplicaStatus: true, MasterStatusGTID: true, MaxExecutionTime: true}},
+		{ServerTypeMariaDB, "10.2.30", ServerCapabilities{FlushTablesWithReadLock: true, LockTables: true, ShowWarnings: true, ThreadsRunning: true, ReplicaStatus: true, MaxStatementTime: true}},
+		{ServerTypeMariaDB, "10.4.10", ServerCapabilities{FlushTablesWithReadLock: true, LockTables: true, ShowWarnings: true, ThreadsRunning: true, ReplicaStatus: true, Sequences: true, MaxStatementTime: true}},
+		{ServerTypeTiDB, "3.0.12", ServerCapabilities{Snapshot: true, TiDBRowID: true, LockTables: true, ShowWarnings: true, MasterStatusGTID: true, MaxExecutionTime: true, LowPriority: true}},
+		{ServerTypeTiDB, "4.0.0-beta.2", ServerCapabilities{Snapshot: true, TiDBRowID: true, LockTables: true, ShowWarnings: true, MasterStatusGTID: true, MaxExecutionTime: true, LowPriority: true}},
+		{ServerTypeTiDB, "4.0.0", ServerCapabilities{Snapshot: true, TiDBRowID: true, LockTables: true, ShowWarnings: true, MasterStatusGTID: true, Sequences: true, TiFlashReplica: true, MaxExecutionTime: true, LowPriority: true, ReplicaRead: true}},
+		{ServerTypeTiDB, "5.3.0", ServerCapabilities{Snapshot: true, TiDBRowID: true, LockTables: true, ShowWarnings: true, MasterStatusGTID: true, Sequences: true, TiFlashReplica: true, PlacementPolicies: true, MaxExecutionTime: true, LowPriority: true, ReplicaRead: true, StaleRead: true}},
+		{ServerTypeTiDB, "7.1.0", ServerCapabilities{Snapshot: true, TiDBRowID: true, LockTables: true, ShowWarnings: true, MasterStatusGTID: true, Sequences: true, TiFlashReplica: true, PlacementPolicies: true, MaxExecutionTime: true, LowPriority: true, ResourceGroups: true, ReplicaRead: true, StaleRead: true}},
+		{ServerTypePostgreSQL, "12.4.0", ServerCapabilities{}},
+		{ServerTypeUnknown, "8.0.18", ServerCapabilities{}},
+	}
+	for _, t := range cases {
+		info := ServerInfo{ServerType: t.serverType, ServerVersion: semver.New(t.version)}
+		c.Assert(info.Capabilities(), Equals, t.expect, Commentf("%s %s", t.serverType, t.version))
+	}
+
+	// the unknown version is assumed to be the latest
+	info := ServerInfo{ServerType: ServerTypeMySQL}
+	c.Assert(info.Capabilities().MasterStatusGTID, IsTrue)
+}
+
+func (s *testCapabilitiesSuite) TestParseTiDBServerInfoWithoutVersion(c *C) {
+	info := ParseServerInfo("5.7.25-TiDB-None")
+	c.Assert(info.ServerType, Equals, ServerType(ServerTypeTiDB))
+	c.Assert(info.ServerVersion, IsNil)
+}
+
+func (s *testCapabilitiesSuite) TestMySQL55MasterStatus(c *C) {
+	db, mock, err := sqlmock.New()
+	c.Assert(err, IsNil)
+	defer db.Close()
+
+	mock.ExpectQuery("SHOW MASTER STATUS").WillReturnRows(
+		sqlmock.NewRows([]string{"File", "Position", "Binlog_Do_DB", "Binlog_Ignore_DB"}).
+			AddRow("mysql-bin.000003", "1234", "", ""))
+	m := newGlobalMetadata(newMemStorage())
+	info := ServerInfo{ServerType: ServerTypeMySQL, ServerVersion: semver.New("5.5.62")}
+	c.Assert(m.getGlobalMetaData(db, info), IsNil)
+	c.Assert(m.logFile, Equals, "mysql-bin.000003")
+	c.Assert(m.pos, Equals, "1234")
+	c.Assert(m.gtidSet, Equals, "")
+	c.Assert(mock.ExpectationsWereMet(), IsNil)
+}
@@ -0,0 +1,409 @@
+package export
+
+import (
+	"context"
+	"database/sql"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"hash/fnv"
+	"io/ioutil"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/pingcap/errors"
+	"go.uber.org/zap"
+
+	"github.com/pingcap/dumpling/v4/log"
+)
+
+// checksumsFile is the name of the checksums of the chunks in the output directory.
+const checksumsFile = "checksums.json"
+
+// DumpChecksums are the checksums of the chunks of the tables of a dump,
+// keyed by the quoted names of the tables like `db`.`table`.
+type DumpChecksums struct {
+	Tables map[string]*TableChecksums `json:"tables"`
+}
+
+// TableChecksums are the checksums of the chunks of a table, and how its
+// rows are selected, so that they can be checksummed again on a server.
+type TableChecksums struct {
+	Database string `json:"database"`
+	Table    string `json:"table"`
+	// Fields are the selected fields of the table.
+	Fields string `json:"fields"`
+	// Where is the where of the table, e.g. given by TableConfigs or Subset.
+	Where  string          `json:"where,omitempty"`
+	Chunks []ChunkChecksum `json:"chunks"`
+}
+
+// ChunkChecksum is the checksum of the rows of a chunk, which is the sum of
+// the hashes of the rows, so it doesn't depend on the order of the rows, and
+// the checksums of the chunks add up to the checksum of the table.
+type ChunkChecksum struct {
+	Chunk int `json:"chunk"`
+	// Range is the key range of the chunk, it's empty for the whole table.
+	Range    string `json:"range,omitempty"`
+	Rows     uint64 `json:"rows"`
+	Checksum uint64 `json:"checksum"`
+}
+
+// rowsChecksum accumulates the checksum of rows.
+type rowsChecksum struct {
+	rows uint64
+	sum  uint64
+}
+
+// add adds the FNV-1a hash of the columns of row, where NULL is different
+// from the empty value.
+func (c *rowsChecksum) add(row []sql.RawBytes) {
+	h := fnv.New64a()
+	var length [binary.MaxVarintLen64]byte
+	for _, col := range row {
+		if col == nil {
+			h.Write([]byte{0})
+			continue
+		}
+		h.Write([]byte{1})
+		h.Write(length[:binary.PutUvarint(length[:], uint64(len(col)))])
+		h.Write(col)
+	}
+	c.rows++
+	c.sum += h.Sum64()
+}
+
+// checksumRecorder collects the checksums of the dumped chunks, it does
+// nothing if it's nil.
+type checksumRecorder struct {
+	mu        sync.Mutex
+	checksums DumpChecksums
+}
+
+func newChecksumRecorder() *checksumRecorder {
+	return &checksumRecorder{checksums: DumpChecksums{Tables: map[string]*TableChecksums{}}}
+}
+
+// withChecksum returns ir which checksums the rows read from it, if the
+// checksums are recorded.
+func withChecksum(conf *Config, ir TableDataIR) (*rowsChecksum, TableDataIR) {
+	if conf.checksums == nil || ir.TableName() == "" {
+		return nil, ir
+	}
+	sum := &rowsChecksum{}
+	return sum, &checksummedTableData{TableDataIR: ir, sum: sum}
+}
+
+// record records the checksum of the chunk ir after it's written.
+func (r *checksumRecorder) record(conf *Config, ir TableDataIR, sum *rowsChecksum) {
+	if r == nil || sum == nil {
+		return
+	}
+	chunk := ChunkChecksum{Chunk: ir.ChunkIndex(), Rows: sum.rows, Checksum: sum.sum}
+	fields := "*"
+	if td, ok := ir.(*tableData); ok {
+		chunk.Range = td.chunkRange
+		if td.selectedField != "" {
+			fields = td.selectedField
+		}
+	}
+	name := qualifiedTableName(ir.DatabaseName(), ir.TableName())
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	table, ok := r.checksums.Tables[name]
+	if !ok {
+		table = &TableChecksums{
+			Database: ir.DatabaseName(),
+			Table:    ir.TableName(),
+			Fields:   fields,
+			Where:    conf.Where,
+		}
+		r.checksums.Tables[name] = table
+	}
+	table.Chunks = append(table.Chunks, chunk)
+}
+
+// recordEmpty records the checksum of the table found no rows to split into
+// chunks, as a chunk of the whole table without rows.
+func (r *checksumRecorder) recordEmpty(conf *Config, db *sql.DB, dbName, tableName string) error {
+	if r == nil {
+		return nil
+	}
+	selectedField, err := conf.dialect().SelectField(db, dbName, tableName)
+	if err != nil {
+		return err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.checksums.Tables[qualifiedTableName(dbName, tableName)] = &TableChecksums{
+		Database: dbName,
+		Table:    tableName,
+		Fields:   selectedField,
+		Where:    conf.Where,
+		Chunks:   []ChunkChecksum{{}},
+	}
+	return nil
+}
+
+// writeChecksums writes the recorded checksums into checksumsFile of storage.
+func (r *checksumRecorder) writeChecksums(storage ExternalStorage) error {
+	if r == nil {
+		return nil
+	}
+	r.mu.Lock()
+	for _, table := range r.checksums.Tables {
+		sort.Slice(table.Chunks, func(i, j int) bool {
+			return table.Chunks[i].Chunk < table.Chunks[j].Chunk
+		})
+	}
+	content, err := json.MarshalIndent(&r.checksums, "", "  ")
+	r.mu.Unlock()
+	if err != nil {
+		return withStack(err)
+	}
+	// write the checksums even if the dump is canceled
+	fileWriter, err := storage.Create(context.Background(), checksumsFile)
+	if err != nil {
+		return err
+	}
+	return closeFile(fileWriter, write(fileWriter, string(content)))
+}
+
+type checksummedTableData struct {
+	TableDataIR
+	sum *rowsChecksum
+}
+
+func (td *checksummedTableData) Rows() SQLRowIter {
+	n := td.ColumnCount()
+	return &checksummedRowIter{
+		SQLRowIter: td.TableDataIR.Rows(),
+		sum:        td.sum,
+		row:        make(rawRow, n),
+		args:       make([]interface{}, n),
+	}
+}
+
+type checksummedRowIter struct {
+	SQLRowIter
+	sum  *rowsChecksum
+	row  rawRow
+	args []interface{}
+}
+
+func (iter *checksummedRowIter) Decode(row RowReceiver) error {
+	if err := iter.SQLRowIter.Decode(iter.row); err != nil {
+		return err
+	}
+	iter.sum.add(iter.row)
+	return iter.row.copyTo(row, iter.args)
+}
+
+func (iter *checksummedRowIter) NextSQLRowIter() SQLRowIter {
+	return &checksummedRowIter{
+		SQLRowIter: iter.SQLRowIter.NextSQLRowIter(),
+		sum:        iter.sum,
+		row:        iter.row,
+		args:       iter.args,
+	}
+}
+
+// ReadChecksums reads the checksums of the dump in dir, which is dumped with
+// Checksums.
+func ReadChecksums(dir string) (*DumpChecksums, error) {
+	path := filepath.Join(dir, checksumsFile)
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, withStack(withKind(ErrorKindConfig, err))
+	}
+	checksums := &DumpChecksums{}
+	if err = json.Unmarshal(content, checksums); err != nil {
+		return nil, withStack(withKind(ErrorKindConfig, errors.WithMessage(err, path)))
+	}
+	if checksums.Tables == nil {
+		checksums.Tables = map[string]*TableChecksums{}
+	}
+	return checksums, nil
+}
+
+// FetchChecksums checksums the same chunks as the tables of dumped on the
+// server of dsn, the tables not found on the server are skipped.
+func FetchChecksums(ctx context.Context, conf *Config, dsn string, dumped *DumpChecksums) (*DumpChecksums, error) {
+	pool, _, err := openConnPool(conf, dsn)
+	if err != nil {
+		return nil, withStack(withKind(ErrorKindConnection, err))
+	}
+	defer pool.Close()
+
+	checksums := &DumpChecksums{Tables: map[string]*TableChecksums{}}
+	existing := map[string]map[string]bool{}
+	for name, table := range dumped.Tables {
+		if _, ok := existing[table.Database]; !ok {
+			tables, err := ListAllTables(pool, table.Database)
+			if err != nil {
+				return nil, withKind(ErrorKindSchema, err)
+			}
+			existing[table.Database] = map[string]bool{}
+			for _, tableName := range tables {
+				existing[table.Database][tableName] = true
+			}
+		}
+		if !existing[table.Database][table.Table] {
+			continue
+		}
+		fetched := *table
+		fetched.Chunks = nil
+		for _, chunk := range table.Chunks {
+			if err = ctx.Err(); err != nil {
+				return nil, err
+			}
+			sum, err := checksumChunk(pool, table, chunk.Range)
+			if err != nil {
+				return nil, err
+			}
+			fetched.Chunks = append(fetched.Chunks, ChunkChecksum{Chunk: chunk.Chunk, Range: chunk.Range, Rows: sum.rows, Checksum: sum.sum})
+		}
+		checksums.Tables[name] = &fetched
+	}
+	log.Info("fetch checksums finished", zap.Int("tables", len(checksums.Tables)))
+	return checksums, nil
+}
+
+// checksumChunk reads the rows of the key range of table and returns their
+// checksum.
+func checksumChunk(db *sql.DB, table *TableChecksums, keyRange string) (*rowsChecksum, error) {
+	var conditions []string
+	if table.Where != "" {
+		conditions = append(conditions, "("+table.Where+")")
+	}
+	if keyRange != "" {
+		conditions = append(conditions, keyRange)
+	}
+	query := fmt.Sprintf("SELECT %s FROM %s", table.Fields, qualifiedTableName(table.Database, table.Table))
+	if len(conditions) > 0 {
+		query += " WHERE " + strings.Join(conditions, " AND ")
+	}
+	rows, err := db.Query(query)
+	if err != nil {
+		return nil, withStack(errors.WithMessage(err, query))
+	}
+	defer rows.Close()
+	columns, err := rows.Columns()
+	if err != nil {
+		return nil, withStack(errors.WithMessage(err, query))
+	}
+	row := make(rawRow, len(columns))
+	args := make([]interface{}, len(columns))
+	row.BindAddress(args)
+	sum := &rowsChecksum{}
+	for rows.Next() {
+		if err = rows.Scan(args...); err != nil {
+			return nil, withStack(errors.WithMessage(err, query))
+		}
+		sum.add(row)
+	}
+	return sum, withStack(errors.WithMessage(rows.Err(), query))
+}
+
+// ChecksumDiff is the difference of the rows of the tables of a source and
+// a target, whose names are sorted.
+type ChecksumDiff struct {
+	// OnlyInSource are the tables only in the source.
+	OnlyInSource []string
+	// OnlyInTarget are the tables only in the target.
+	OnlyInTarget []string
+	// Changed are the tables whose rows are different.
+	Changed []TableChecksumDiff
+}
+
+// TableChecksumDiff is the differing chunks of a table. They're compared
+// chunk by chunk if the source and the target have the same chunks, or as a
+// whole otherwise, whose Range is empty.
+type TableChecksumDiff struct {
+	Name   string
+	Chunks [][2]ChunkChecksum
+}
+
+// DiffChecksums returns the difference of the target checksums from the source.
+func DiffChecksums(source, target *DumpChecksums) ChecksumDiff {
+	var diff ChecksumDiff
+	for name, sourceTable := range source.Tables {
+		targetTable, ok := target.Tables[name]
+		if !ok {
+			diff.OnlyInSource = append(diff.OnlyInSource, name)
+			continue
+		}
+		if chunks := diffChunks(sourceTable.Chunks, targetTable.Chunks); len(chunks) > 0 {
+			diff.Changed = append(diff.Changed, TableChecksumDiff{Name: name, Chunks: chunks})
+		}
+	}
+	for name := range target.Tables {
+		if _, ok := source.Tables[name]; !ok {
+			diff.OnlyInTarget = append(diff.OnlyInTarget, name)
+		}
+	}
+	sort.Strings(diff.OnlyInSource)
+	sort.Strings(diff.OnlyInTarget)
+	sort.Slice(diff.Changed, func(i, j int) bool {
+		return diff.Changed[i].Name < diff.Changed[j].Name
+	})
+	return diff
+}
+
+func diffChunks(source, target []ChunkChecksum) [][2]ChunkChecksum {
+	sameRanges := len(source) == len(target)
+	for i := 0; sameRanges && i < len(source); i++ {
+		sameRanges = source[i].Range == target[i].Range
+	}
+	if !sameRanges {
+		source, target = []ChunkChecksum{sumChunks(source)}, []ChunkChecksum{sumChunks(target)}
+	}
+	var chunks [][2]ChunkChecksum
+	for i := range source {
+		if source[i].Rows != target[i].Rows || source[i].Checksum != target[i].Checksum {
+			chunks = append(chunks, [2]ChunkChecksum{source[i], target[i]})
+		}
+	}
+	return chunks
+}
+
+// sumChunks returns the checksum of the whole table of chunks.
+func sumChunks(chunks []ChunkChecksum) ChunkChecksum {
+	var sum ChunkChecksum
+	for _, chunk := range chunks {
+		sum.Rows += chunk.Rows
+		sum.Checksum += chunk.Checksum
+	}
+	return sum
+}
+
+// Empty returns whether the source and the target have the same rows.
+func (d ChecksumDiff) Empty() bool {
+	return len(d.OnlyInSource) == 0 && len(d.OnlyInTarget) == 0 && len(d.Changed) == 0
+}
+
+// String formats the difference like SchemaDiff, the changed tables are
+// followed by their differing key ranges.
+func (d ChecksumDiff) String() string {
+	var b strings.Builder
+	for _, name := range d.OnlyInSource {
+		fmt.Fprintf(&b, "- %s\n", name)
+	}
+	for _, name := range d.OnlyInTarget {
+		fmt.Fprintf(&b, "+ %s\n", name)
+	}
+	for _, table := range d.Changed {
+		fmt.Fprintf(&b, "~ %s\n", table.Name)
+		for _, chunks := range table.Chunks {
+			keyRange := chunks[0].Range
+			if keyRange == "" {
+				keyRange = "all rows"
+			}
+			fmt.Fprintf(&b, "  ~ %s: %d rows %016x => %d rows %016x\n", keyRange,
+				chunks[0].Rows, chunks[0].Checksum, chunks[1].Rows, chunks[1].Checksum)
+		}
+	}
+	return b.String()
+}
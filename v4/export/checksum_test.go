@@ -0,0 +1,133 @@
+package export
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"io/ioutil"
+	"path/filepath"
+	"regexp"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	. "github.com/pingcap/check"
+)
+
+var _ = Suite(&testChecksumSuite{})
+
+type testChecksumSuite struct{}
+
+func checksumOf(rows ...[]sql.RawBytes) ChunkChecksum {
+	sum := &rowsChecksum{}
+	for _, row := range rows {
+		sum.add(row)
+	}
+	return ChunkChecksum{Rows: sum.rows, Checksum: sum.sum}
+}
+
+func (s *testChecksumSuite) TestRowsChecksum(c *C) {
+	a := []sql.RawBytes{sql.RawBytes("1"), sql.RawBytes("alice")}
+	b := []sql.RawBytes{sql.RawBytes("2"), nil}
+	c.Assert(checksumOf(a, b), Equals, checksumOf(b, a))
+	c.Assert(checksumOf(a, b).Rows, Equals, uint64(2))
+	// NULL isn't the empty value, and the columns aren't concatenated
+	c.Assert(checksumOf(b), Not(Equals), checksumOf([]sql.RawBytes{sql.RawBytes("2"), {}}))
+	c.Assert(checksumOf([]sql.RawBytes{sql.RawBytes("ab"), sql.RawBytes("c")}), Not(Equals),
+		checksumOf([]sql.RawBytes{sql.RawBytes("a"), sql.RawBytes("bc")}))
+}
+
+func (s *testChecksumSuite) TestRecordChecksums(c *C) {
+	conf := DefaultConfig()
+	conf.checksums = newChecksumRecorder()
+	conf.Where = "id > 0"
+	ir := &mockTableIR{
+		dbName:        "test",
+		tblName:       "t",
+		data:          [][]driver.Value{{"1", "alice"}, {"2", nil}},
+		selectedField: "*",
+		colTypes:      []string{"INT", "VARCHAR"},
+		colNames:      []string{"id", "name"},
+	}
+	sum, data := withChecksum(conf, ir)
+	bf := &bytes.Buffer{}
+	c.Assert(WriteInsert(context.Background(), data, bf, UnspecifiedSize, nil), IsNil)
+	c.Assert(bf.String(), Equals, "INSERT INTO `t` VALUES\n(1,'alice'),\n(2,NULL);\n")
+	conf.checksums.record(conf, ir, sum)
+
+	storage := newMemStorage()
+	c.Assert(conf.checksums.writeChecksums(storage), IsNil)
+	dir := c.MkDir()
+	c.Assert(ioutil.WriteFile(filepath.Join(dir, checksumsFile), []byte(storage.files[checksumsFile]), 0644), IsNil)
+	checksums, err := ReadChecksums(dir)
+	c.Assert(err, IsNil)
+	expected := checksumOf([]sql.RawBytes{sql.RawBytes("1"), sql.RawBytes("alice")}, []sql.RawBytes{sql.RawBytes("2"), nil})
+	c.Assert(checksums.Tables, HasLen, 1)
+	c.Assert(*checksums.Tables["`test`.`t`"], DeepEquals,
+		TableChecksums{Database: "test", Table: "t", Fields: "*", Where: "id > 0", Chunks: []ChunkChecksum{expected}})
+
+	// the checksums aren't recorded by default
+	sum, data = withChecksum(DefaultConfig(), ir)
+	c.Assert(sum, IsNil)
+	c.Assert(data, Equals, TableDataIR(ir))
+}
+
+func (s *testChecksumSuite) TestChecksumChunk(c *C) {
+	db, mock, err := sqlmock.New()
+	c.Assert(err, IsNil)
+	defer db.Close()
+
+	table := &TableChecksums{Database: "test", Table: "t", Fields: "`id`,`name`", Where: "id > 0"}
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT `id`,`name` FROM `test`.`t` WHERE (id > 0) AND (`id` >= 1 AND `id` < 3)")).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name"}).AddRow("2", nil).AddRow("1", "alice"))
+	sum, err := checksumChunk(db, table, "(`id` >= 1 AND `id` < 3)")
+	c.Assert(err, IsNil)
+	c.Assert(mock.ExpectationsWereMet(), IsNil)
+	c.Assert(ChunkChecksum{Rows: sum.rows, Checksum: sum.sum}, Equals,
+		checksumOf([]sql.RawBytes{sql.RawBytes("1"), sql.RawBytes("alice")}, []sql.RawBytes{sql.RawBytes("2"), nil}))
+}
+
+func (s *testChecksumSuite) TestDiffChecksums(c *C) {
+	chunks := func(sums ...uint64) []ChunkChecksum {
+		ranges := []string{"(`id` >= 1 AND `id` < 11)", "(`id` >= 11 AND `id` < 21)"}
+		var chunks []ChunkChecksum
+		for i, sum := range sums {
+			chunks = append(chunks, ChunkChecksum{Chunk: i + 1, Range: ranges[i], Rows: 10, Checksum: sum})
+		}
+		return chunks
+	}
+	source := &DumpChecksums{Tables: map[string]*TableChecksums{
+		"`test`.`same`":    {Chunks: chunks(1, 2)},
+		"`test`.`changed`": {Chunks: chunks(1, 2)},
+		"`test`.`resplit`": {Chunks: chunks(1, 2)},
+		"`test`.`dropped`": {Chunks: []ChunkChecksum{{}}},
+	}}
+	target := &DumpChecksums{Tables: map[string]*TableChecksums{
+		"`test`.`same`":    {Chunks: chunks(1, 2)},
+		"`test`.`changed`": {Chunks: chunks(1, 3)},
+		// the chunks of different ranges are compared as a whole
+		"`test`.`resplit`": {Chunks: []ChunkChecksum{{Rows: 19, Checksum: 3}}},
+		"`test`.`created`": {Chunks: []ChunkChecksum{{}}},
+	}}
+	diff := DiffChecksums(source, target)
+	c.Assert(diff.Empty(), IsFalse)
+	c.Assert(diff.String(), Equals, "- `test`.`dropped`\n"+
+		"+ `test`.`created`\n"+
+		"~ `test`.`changed`\n"+
+		"  ~ (`id` >= 11 AND `id` < 21): 10 rows 0000000000000002 => 10 rows 0000000000000003\n"+
+		"~ `test`.`resplit`\n"+
+		"  ~ all rows: 20 rows 0000000000000003 => 19 rows 0000000000000003\n")
+
+	target.Tables["`test`.`resplit`"].Chunks[0].Rows = 20
+	delete(source.Tables, "`test`.`dropped`")
+	delete(target.Tables, "`test`.`created`")
+	target.Tables["`test`.`changed`"] = source.Tables["`test`.`changed`"]
+	c.Assert(DiffChecksums(source, target).Empty(), IsTrue)
+}
+
+func (s *testChecksumSuite) TestValidate(c *C) {
+	conf := DefaultConfig()
+	conf.Checksums = true
+	c.Assert(conf.Validate(), IsNil)
+	conf.ServerOutfileDir = "/var/lib/mysql-files"
+	c.Assert(conf.Validate(), ErrorMatches, "(?s).*checksums are not supported with server-outfile-dir.*")
+}
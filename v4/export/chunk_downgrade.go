@@ -0,0 +1,101 @@
+package export
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"sort"
+	"sync"
+
+	"go.uber.org/zap"
+
+	"github.com/pingcap/dumpling/v4/log"
+)
+
+// chunkSplitAttempts is how many times a table is tried to be split into
+// chunks, before it's dumped by a full scan instead.
+const chunkSplitAttempts = 2
+
+// chunkSplitError is the error of splitting a table into chunks before any of
+// its chunks is dumped, so the table can still be dumped by a full scan.
+type chunkSplitError struct {
+	err error
+}
+
+func (e *chunkSplitError) Error() string {
+	return e.err.Error()
+}
+
+func (e *chunkSplitError) Unwrap() error {
+	return e.err
+}
+
+func (e *chunkSplitError) Cause() error {
+	return e.err
+}
+
+// dumpTableChunks dumps the table by chunks like concurrentDumpTable. If the
+// table fails to be split chunkSplitAttempts times, e.g. its integer key
+// isn't what the splitting assumes, it returns false for the table to be
+// dumped by a full scan, and the downgrade is recorded.
+func dumpTableChunks(ctx context.Context, writer Writer, conf *Config, db *sql.DB, dbName, tableName string) (bool, error) {
+	var splitErr *chunkSplitError
+	for attempt := 1; attempt <= chunkSplitAttempts; attempt++ {
+		finished, err := concurrentDumpTable(ctx, writer, conf, db, dbName, tableName)
+		var ok bool
+		if splitErr, ok = err.(*chunkSplitError); !ok {
+			return finished, err
+		}
+		if ctx.Err() != nil {
+			return true, ctx.Err()
+		}
+		log.Warn("split table into chunks failed",
+			zap.String("database", dbName), zap.String("table", tableName),
+			zap.Int("attempt", attempt), zap.Error(splitErr.err))
+	}
+	log.Warn("dump table by a full scan since it can't be split into chunks",
+		zap.String("database", dbName), zap.String("table", tableName))
+	conf.downgrades.record(dbName, tableName, splitErr.err)
+	return false, nil
+}
+
+// chunkDowngrades records the tables dumped by a full scan since they can't be
+// split into chunks, it does nothing if it's nil.
+type chunkDowngrades struct {
+	mu     sync.Mutex
+	tables map[string]string
+}
+
+func newChunkDowngrades() *chunkDowngrades {
+	return &chunkDowngrades{tables: map[string]string{}}
+}
+
+func (d *chunkDowngrades) record(dbName, tableName string, err error) {
+	if d == nil {
+		return
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	// the root cause is recorded without the stack
+	d.tables[fmt.Sprintf("`%s`.`%s`", dbName, tableName)] = RootCause(err).Error()
+}
+
+// String returns the downgraded tables sorted by their names, with the errors
+// of splitting them.
+func (d *chunkDowngrades) String() string {
+	if d == nil {
+		return ""
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	names := make([]string, 0, len(d.tables))
+	for name := range d.tables {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	str := ""
+	for _, name := range names {
+		str += "\t\t" + name + ": " + d.tables[name] + "\n"
+	}
+	return str
+}
@@ -0,0 +1,54 @@
+package export
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	. "github.com/pingcap/check"
+)
+
+var _ = Suite(&testChunkDowngradeSuite{})
+
+type testChunkDowngradeSuite struct{}
+
+func (s *testChunkDowngradeSuite) TestDowngrade(c *C) {
+	db, mock, err := sqlmock.New()
+	c.Assert(err, IsNil)
+	defer db.Close()
+	conf := DefaultConfig()
+	conf.Rows = 100
+	conf.downgrades = newChunkDowngrades()
+
+	for i := 0; i < chunkSplitAttempts; i++ {
+		mock.ExpectPrepare("SELECT column_name FROM information_schema.columns").
+			WillReturnError(errors.New("bad connection"))
+	}
+	finished, err := dumpTableChunks(context.Background(), nil, conf, db, "test", "t")
+	c.Assert(err, IsNil)
+	// the table is dumped by a full scan then
+	c.Assert(finished, IsFalse)
+	c.Assert(conf.downgrades.String(), Equals, "\t\t`test`.`t`: bad connection\n")
+	c.Assert(mock.ExpectationsWereMet(), IsNil)
+}
+
+func (s *testChunkDowngradeSuite) TestMetadata(c *C) {
+	m := newGlobalMetadata(newMemStorage())
+	m.recordStartTime(time.Date(2020, 5, 1, 10, 0, 0, 0, time.UTC))
+	downgrades := newChunkDowngrades()
+	m.recordChunkDowngrades(downgrades)
+	c.Assert(m.String(), Equals, "Started dump at: 2020-05-01 10:00:00\nSHOW MASTER STATUS:\n")
+
+	downgrades.record("db", "users", errors.New("invalid syntax"))
+	downgrades.record("db", "orders", errors.New("out of range"))
+	c.Assert(m.String(), Equals, "Started dump at: 2020-05-01 10:00:00\n"+
+		"SHOW MASTER STATUS:\n"+
+		"CHUNKING DOWNGRADED:\n"+
+		"\t\t`db`.`orders`: out of range\n"+
+		"\t\t`db`.`users`: invalid syntax\n")
+
+	var nilDowngrades *chunkDowngrades
+	nilDowngrades.record("db", "t", errors.New("ignored"))
+	c.Assert(nilDowngrades.String(), Equals, "")
+}
@@ -0,0 +1,50 @@
+package export
+
+import (
+	"database/sql"
+)
+
+const (
+	// oversizedChunkRatio is how many times the rows of a chunk are estimated
+	// more than conf.Rows, for the chunk to be split before it's dumped.
+	oversizedChunkRatio = 4
+	// maxOversizedChunkParts is the most parts an oversized chunk is split into.
+	maxOversizedChunkParts = 64
+)
+
+// estimateChunkRows returns the rows of the query of a chunk estimated by
+// EXPLAIN, or 0 if they can't be estimated.
+func estimateChunkRows(db *sql.DB, query string) uint64 {
+	estRows := detectEstimateRows(db, "EXPLAIN "+query, []string{"rows", "estRows", "count"})
+	if estRows > 0 {
+		return uint64(estRows)
+	}
+	return 0
+}
+
+// splitOversized returns the parts of about rows rows of the chunk, if it's
+// estimated to have more than oversizedChunkRatio times of rows, e.g. the
+// values of the split field are skewed into its range. It returns nil if the
+// chunk isn't oversized or can't be split.
+func (b chunkBounds) splitOversized(estimated, rows uint64) []chunkBounds {
+	if rows == 0 || estimated/rows < oversizedChunkRatio || b.hi-b.lo <= 1 {
+		return nil
+	}
+	n := (estimated + rows - 1) / rows
+	if n > maxOversizedChunkParts {
+		n = maxOversizedChunkParts
+	}
+	if n > b.hi-b.lo {
+		n = b.hi - b.lo
+	}
+	step := (b.hi - b.lo + n - 1) / n
+	parts := make([]chunkBounds, 0, n)
+	for lo := b.lo; lo < b.hi; lo += step {
+		hi := lo + step
+		if hi > b.hi {
+			hi = b.hi
+		}
+		parts = append(parts, chunkBounds{lo: lo, hi: hi})
+	}
+	return parts
+}
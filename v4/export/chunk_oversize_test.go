@@ -0,0 +1,73 @@
+package export
+
+import (
+	"context"
+	"regexp"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/coreos/go-semver/semver"
+	. "github.com/pingcap/check"
+)
+
+var _ = Suite(&testChunkOversizeSuite{})
+
+type testChunkOversizeSuite struct{}
+
+func (s *testChunkOversizeSuite) TestSplitOversized(c *C) {
+	bounds := chunkBounds{lo: 1, hi: 51, planned: true}
+	c.Assert(bounds.splitOversized(399, 100), IsNil)
+	c.Assert(bounds.splitOversized(0, 100), IsNil)
+	c.Assert(bounds.splitOversized(400, 100), DeepEquals, []chunkBounds{{lo: 1, hi: 14}, {lo: 14, hi: 27}, {lo: 27, hi: 40}, {lo: 40, hi: 51}})
+
+	// the parts are limited by the range and maxOversizedChunkParts
+	c.Assert(chunkBounds{lo: 1, hi: 3}.splitOversized(1000, 10), DeepEquals, []chunkBounds{{lo: 1, hi: 2}, {lo: 2, hi: 3}})
+	c.Assert(chunkBounds{lo: 1, hi: 2}.splitOversized(1000, 10), IsNil)
+	c.Assert(chunkBounds{lo: 0, hi: 1 << 20}.splitOversized(1<<30, 10), HasLen, maxOversizedChunkParts)
+}
+
+func (s *testChunkOversizeSuite) TestSplitTableWithOversizedChunk(c *C) {
+	db, mock, err := sqlmock.New()
+	c.Assert(err, IsNil)
+	defer db.Close()
+
+	conf := DefaultConfig()
+	conf.ServerInfo = ServerInfo{ServerType: ServerTypeMySQL, ServerVersion: semver.New("8.0.18")}
+	conf.ChunkColumn = "id"
+	conf.Rows = 50
+	conf.SortByPk = false
+
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT MIN(`id`),MAX(`id`) FROM `test`.`t`")).
+		WillReturnRows(sqlmock.NewRows([]string{"MIN", "MAX"}).AddRow("1", "100"))
+	mock.ExpectQuery("EXPLAIN SELECT `id` FROM `test`.`t`").
+		WillReturnRows(sqlmock.NewRows([]string{"rows"}).AddRow("100"))
+	mock.ExpectQuery("SELECT COLUMN_NAME,EXTRA FROM INFORMATION_SCHEMA.COLUMNS").
+		WillReturnRows(sqlmock.NewRows([]string{"COLUMN_NAME", "EXTRA"}).AddRow("id", ""))
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM `test`.`t` LIMIT 1")).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}))
+	// most of the rows are in the first chunk of the plan
+	explain := []string{"id", "select_type", "table", "type", "rows"}
+	mock.ExpectQuery(regexp.QuoteMeta("EXPLAIN SELECT * FROM `test`.`t` WHERE (`id` >= 1 AND `id` < 51)")).
+		WillReturnRows(sqlmock.NewRows(explain).AddRow("1", "SIMPLE", "t", "range", "200"))
+	mock.ExpectQuery(regexp.QuoteMeta("EXPLAIN SELECT * FROM `test`.`t` WHERE (`id` >= 1 AND `id` < 14)")).
+		WillReturnRows(sqlmock.NewRows(explain).AddRow("1", "SIMPLE", "t", "range", "50"))
+	parts := []string{"(`id` >= 1 AND `id` < 14)", "(`id` >= 14 AND `id` < 27)", "(`id` >= 27 AND `id` < 40)", "(`id` >= 40 AND `id` < 51)"}
+	for _, where := range parts {
+		mock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM `test`.`t` WHERE " + where)).WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow("1"))
+	}
+	mock.ExpectQuery(regexp.QuoteMeta("EXPLAIN SELECT * FROM `test`.`t` WHERE (`id` >= 51 AND `id` < 101)")).
+		WillReturnRows(sqlmock.NewRows(explain).AddRow("1", "SIMPLE", "t", "range", "10"))
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM `test`.`t` WHERE (`id` >= 51 AND `id` < 101)")).WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow("60"))
+
+	tableDataIRCh := make(chan TableDataIR, 8)
+	errCh := make(chan error, 1)
+	splitTableDataIntoChunks(context.Background(), tableDataIRCh, errCh, make(chan struct{}), "test", "t", db, conf)
+	var chunks []string
+	for ir := range tableDataIRCh {
+		td := ir.(*tableData)
+		c.Assert(td.Rows().Close(), IsNil)
+		chunks = append(chunks, td.chunkRange)
+		c.Assert(td.chunkIndex, Equals, len(chunks))
+	}
+	c.Assert(chunks, DeepEquals, append(parts, "(`id` >= 51 AND `id` < 101)"))
+	c.Assert(mock.ExpectationsWereMet(), IsNil)
+}
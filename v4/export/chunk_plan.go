@@ -0,0 +1,111 @@
+package export
+
+import (
+	"database/sql"
+	"strings"
+
+	"go.uber.org/zap"
+
+	"github.com/pingcap/dumpling/v4/log"
+)
+
+// checkChunkPlan EXPLAINs the query of a chunk of the table, and warns if the
+// chunks are read by full scans, which reads the whole table once per chunk.
+// The integer columns leading the indexes are suggested for chunk-column.
+func checkChunkPlan(db *sql.DB, serverType ServerType, dbName, tableName, field, query string) {
+	fullScan, err := explainFullScan(db, serverType, query)
+	if err != nil {
+		log.Warn("explain the query of chunk failed", zap.String("query", query), zap.Error(err))
+		return
+	}
+	if !fullScan {
+		return
+	}
+	columns, err := listIndexedIntColumns(db, dbName, tableName)
+	if err != nil {
+		log.Warn("list the indexed columns failed",
+			zap.String("database", dbName), zap.String("table", tableName), zap.Error(err))
+	}
+	var suggested []string
+	for _, column := range columns {
+		if column != field {
+			suggested = append(suggested, column)
+		}
+	}
+	log.Warn("the chunks of the table are read by full scans, which reads the whole table once per chunk;"+
+		" set chunk-column of the table in [[table]] to an indexed integer column,"+
+		" or set its rows larger than its row count to dump it in one chunk",
+		zap.String("database", dbName), zap.String("table", tableName),
+		zap.String("chunk column", field), zap.Strings("suggested columns", suggested),
+		zap.String("query", query))
+}
+
+// explainFullScan returns whether the plan of query scans the whole table or
+// index, by the access type `ALL` or `index` of MySQL and MariaDB, or the
+// full scan operators of TiDB.
+func explainFullScan(db *sql.DB, serverType ServerType, query string) (bool, error) {
+	rows, err := db.Query("EXPLAIN " + query)
+	if err != nil {
+		return false, withStack(err)
+	}
+	defer rows.Close()
+	columns, err := rows.Columns()
+	if err != nil {
+		return false, withStack(err)
+	}
+	values := make([]sql.NullString, len(columns))
+	args := make([]interface{}, len(columns))
+	for i := range values {
+		args[i] = &values[i]
+	}
+	fullScan := false
+	for rows.Next() {
+		if err = rows.Scan(args...); err != nil {
+			return false, withStack(err)
+		}
+		for i, column := range columns {
+			value := values[i].String
+			switch strings.ToLower(column) {
+			case "type":
+				// MySQL and MariaDB
+				if serverType != ServerTypeTiDB && (value == "ALL" || value == "index") {
+					fullScan = true
+				}
+			case "id":
+				// TiDB 4.0 and later, the operators are indented like `└─TableFullScan_5`
+				if serverType == ServerTypeTiDB && (strings.Contains(value, "TableFullScan") || strings.Contains(value, "IndexFullScan")) {
+					fullScan = true
+				}
+			case "operator info":
+				// TiDB 3.0
+				if serverType == ServerTypeTiDB && strings.Contains(value, "range:[-inf,+inf]") {
+					fullScan = true
+				}
+			}
+		}
+	}
+	return fullScan, withStack(rows.Err())
+}
+
+// listIndexedIntColumns returns the integer columns leading the indexes of the
+// table, which the chunk queries can read by ranges.
+func listIndexedIntColumns(db *sql.DB, dbName, tableName string) ([]string, error) {
+	query := "SELECT DISTINCT s.COLUMN_NAME FROM INFORMATION_SCHEMA.STATISTICS s " +
+		"JOIN INFORMATION_SCHEMA.COLUMNS c ON s.TABLE_SCHEMA = c.TABLE_SCHEMA AND s.TABLE_NAME = c.TABLE_NAME AND s.COLUMN_NAME = c.COLUMN_NAME " +
+		"WHERE s.TABLE_SCHEMA = ? AND s.TABLE_NAME = ? AND s.SEQ_IN_INDEX = 1 " +
+		"AND c.DATA_TYPE IN ('tinyint', 'smallint', 'mediumint', 'int', 'bigint') ORDER BY s.COLUMN_NAME"
+	rows, err := db.Query(query, dbName, tableName)
+	if err != nil {
+		return nil, withStack(err)
+	}
+	defer rows.Close()
+	var columns []string
+	for rows.Next() {
+		var column string
+		if err = rows.Scan(&column); err != nil {
+			return nil, withStack(err)
+		}
+		columns = append(columns, column)
+	}
+	return columns, withStack(rows.Err())
+}
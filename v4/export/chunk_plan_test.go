@@ -0,0 +1,98 @@
+package export
+
+import (
+	"regexp"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	. "github.com/pingcap/check"
+)
+
+var _ = Suite(&testChunkPlanSuite{})
+
+type testChunkPlanSuite struct{}
+
+func (s *testChunkPlanSuite) TestExplainMySQL(c *C) {
+	db, mock, err := sqlmock.New()
+	c.Assert(err, IsNil)
+	defer db.Close()
+	query := "SELECT * FROM `test`.`t` WHERE (`id` >= 1 AND `id` < 100)"
+	columns := []string{"id", "select_type", "table", "partitions", "type", "possible_keys", "key", "key_len", "ref", "rows", "filtered", "Extra"}
+
+	mock.ExpectQuery(regexp.QuoteMeta("EXPLAIN " + query)).WillReturnRows(sqlmock.NewRows(columns).
+		AddRow(1, "SIMPLE", "t", nil, "range", "PRIMARY", "PRIMARY", "8", nil, 99, 100, "Using where"))
+	fullScan, err := explainFullScan(db, ServerTypeMySQL, query)
+	c.Assert(err, IsNil)
+	c.Assert(fullScan, IsFalse)
+
+	for _, accessType := range []string{"ALL", "index"} {
+		mock.ExpectQuery(regexp.QuoteMeta("EXPLAIN " + query)).WillReturnRows(sqlmock.NewRows(columns).
+			AddRow(1, "SIMPLE", "t", nil, accessType, nil, nil, nil, nil, 100000, 11.11, "Using where"))
+		fullScan, err = explainFullScan(db, ServerTypeMySQL, query)
+		c.Assert(err, IsNil)
+		c.Assert(fullScan, IsTrue)
+	}
+	c.Assert(mock.ExpectationsWereMet(), IsNil)
+}
+
+func (s *testChunkPlanSuite) TestExplainTiDB(c *C) {
+	db, mock, err := sqlmock.New()
+	c.Assert(err, IsNil)
+	defer db.Close()
+	query := "SELECT * FROM `test`.`t` WHERE (`id` >= 1 AND `id` < 100)"
+	columns := []string{"id", "estRows", "task", "access object", "operator info"}
+
+	mock.ExpectQuery(regexp.QuoteMeta("EXPLAIN " + query)).WillReturnRows(sqlmock.NewRows(columns).
+		AddRow("TableReader_6", "99.00", "root", "", "data:TableRangeScan_5").
+		AddRow("└─TableRangeScan_5", "99.00", "cop[tikv]", "table:t", "range:[1,100), keep order:false"))
+	fullScan, err := explainFullScan(db, ServerTypeTiDB, query)
+	c.Assert(err, IsNil)
+	c.Assert(fullScan, IsFalse)
+
+	mock.ExpectQuery(regexp.QuoteMeta("EXPLAIN " + query)).WillReturnRows(sqlmock.NewRows(columns).
+		AddRow("TableReader_7", "99.00", "root", "", "data:Selection_6").
+		AddRow("└─Selection_6", "99.00", "cop[tikv]", "", "ge(test.t.id, 1), lt(test.t.id, 100)").
+		AddRow("  └─TableFullScan_5", "10000.00", "cop[tikv]", "table:t", "keep order:false"))
+	fullScan, err = explainFullScan(db, ServerTypeTiDB, query)
+	c.Assert(err, IsNil)
+	c.Assert(fullScan, IsTrue)
+
+	// TiDB 3.0
+	mock.ExpectQuery(regexp.QuoteMeta("EXPLAIN " + query)).WillReturnRows(sqlmock.NewRows([]string{"id", "count", "task", "operator info"}).
+		AddRow("TableReader_7", "99.00", "root", "data:Selection_6").
+		AddRow("└─TableScan_5", "10000.00", "cop[tikv]", "table:t, range:[-inf,+inf], keep order:false"))
+	fullScan, err = explainFullScan(db, ServerTypeTiDB, query)
+	c.Assert(err, IsNil)
+	c.Assert(fullScan, IsTrue)
+	c.Assert(mock.ExpectationsWereMet(), IsNil)
+}
+
+func (s *testChunkPlanSuite) TestCheckChunkPlan(c *C) {
+	db, mock, err := sqlmock.New()
+	c.Assert(err, IsNil)
+	defer db.Close()
+	query := "SELECT * FROM `test`.`t` WHERE (`id` >= 1 AND `id` < 100)"
+
+	mock.ExpectQuery(regexp.QuoteMeta("EXPLAIN " + query)).WillReturnRows(sqlmock.NewRows([]string{"type"}).AddRow("ALL"))
+	mock.ExpectQuery("SELECT DISTINCT s.COLUMN_NAME FROM INFORMATION_SCHEMA.STATISTICS").WithArgs("test", "t").
+		WillReturnRows(sqlmock.NewRows([]string{"COLUMN_NAME"}).AddRow("id").AddRow("user_id"))
+	checkChunkPlan(db, ServerTypeMySQL, "test", "t", "id", query)
+	c.Assert(mock.ExpectationsWereMet(), IsNil)
+
+	mock.ExpectQuery("SELECT DISTINCT s.COLUMN_NAME FROM INFORMATION_SCHEMA.STATISTICS").WithArgs("test", "t").
+		WillReturnRows(sqlmock.NewRows([]string{"COLUMN_NAME"}).AddRow("id").AddRow("user_id"))
+	columns, err := listIndexedIntColumns(db, "test", "t")
+	c.Assert(err, IsNil)
+	c.Assert(columns, DeepEquals, []string{"id", "user_id"})
+}
+
+func (s *testChunkPlanSuite) TestChunkColumn(c *C) {
+	conf := DefaultConfig()
+	conf.TableConfigs = []TableConfig{{Database: "test", Table: "t", ChunkColumn: "user_id"}}
+	tableConf := conf.forTable("test", "t")
+	c.Assert(tableConf.ChunkColumn, Equals, "user_id")
+	// the chunk column is used without checking the keys
+	field, err := pickupPossibleField("test", "t", nil, tableConf)
+	c.Assert(err, IsNil)
+	c.Assert(field, Equals, "user_id")
+	c.Assert(conf.forTable("test", "t2").ChunkColumn, Equals, "")
+}
@@ -0,0 +1,98 @@
+package export
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/pingcap/errors"
+)
+
+const (
+	// chunkTimeoutGrace is how much longer the client waits for a chunk than
+	// the server, so that the server aborts the query with its own error.
+	chunkTimeoutGrace = 5 * time.Second
+	// maxChunkResplits is how many times a chunk timing out is split into
+	// halves before the dump fails.
+	maxChunkResplits = 4
+
+	errQueryTimeout     = 3024 // ER_QUERY_TIMEOUT of MySQL and TiDB
+	errStatementTimeout = 1969 // ER_STATEMENT_TIMEOUT of MariaDB
+)
+
+// chunkContext returns the context of a chunk derived from ctx of the dump,
+// which is canceled after ChunkTimeout too if it's set. The deadline covers the whole chunk rather than
+// only its query: the rows are read with the context until the chunk is
+// released, so the time of writing them counts too. It's the same as what the
+// server limits, since MAX_EXECUTION_TIME and max_statement_time keep running
+// while the rows are sent.
+func (conf *Config) chunkContext(ctx context.Context) (context.Context, context.CancelFunc) {
+	if conf.ChunkTimeout == UnspecifiedSize {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, time.Duration(conf.ChunkTimeout)*time.Second+chunkTimeoutGrace)
+}
+
+// limitExecutionTime returns the SELECT query aborted by the server after
+// ChunkTimeout, by the MAX_EXECUTION_TIME hint of MySQL and TiDB or the
+// max_statement_time of MariaDB. The query is returned as it is if the
+// server can't limit it.
+func (conf *Config) limitExecutionTime(query string) string {
+	const selectKeyword = "SELECT "
+	if conf.ChunkTimeout == UnspecifiedSize || len(query) < len(selectKeyword) ||
+		!strings.EqualFold(query[:len(selectKeyword)], selectKeyword) {
+		return query
+	}
+	capabilities := conf.ServerInfo.Capabilities()
+	switch {
+	case capabilities.MaxExecutionTime:
+		return fmt.Sprintf("%s/*+ MAX_EXECUTION_TIME(%d) */ %s", query[:len(selectKeyword)], conf.ChunkTimeout*1000, query[len(selectKeyword):])
+	case capabilities.MaxStatementTime:
+		return fmt.Sprintf("SET STATEMENT max_statement_time=%d FOR %s", conf.ChunkTimeout, query)
+	default:
+		return query
+	}
+}
+
+// isQueryTimeout returns whether err is a query aborted by the server or
+// the client for exceeding ChunkTimeout.
+func isQueryTimeout(err error) bool {
+	cause := RootCause(err)
+	if cause == context.DeadlineExceeded {
+		return true
+	}
+	mysqlErr, ok := cause.(*mysql.MySQLError)
+	return ok && (mysqlErr.Number == errQueryTimeout || mysqlErr.Number == errStatementTimeout)
+}
+
+// chunkTimeoutError explains the error of a chunk aborted for exceeding
+// ChunkTimeout, and returns other errors as they are.
+func chunkTimeoutError(conf *Config, ir TableDataIR, err error) error {
+	if err == nil || conf.ChunkTimeout == UnspecifiedSize || !isQueryTimeout(err) {
+		return err
+	}
+	return errors.Annotatef(err, "chunk %d of `%s`.`%s` isn't read in the chunk-timeout %ds",
+		ir.ChunkIndex(), ir.DatabaseName(), ir.TableName(), conf.ChunkTimeout)
+}
+
+// chunkBounds is the range [lo, hi) of the split field of a chunk, resplits
+// is how many times it's split from a chunk timing out. planned is whether
+// it's a chunk of the plan by the min and max of the field, rather than a
+// part of one.
+type chunkBounds struct {
+	lo, hi   uint64
+	resplits int
+	planned  bool
+}
+
+// resplit returns the halves of the chunk timing out, or false if it can't be
+// split anymore.
+func (b chunkBounds) resplit() (chunkBounds, chunkBounds, bool) {
+	if b.resplits >= maxChunkResplits || b.hi-b.lo <= 1 {
+		return chunkBounds{}, chunkBounds{}, false
+	}
+	mid := b.lo + (b.hi-b.lo)/2
+	return chunkBounds{lo: b.lo, hi: mid, resplits: b.resplits + 1}, chunkBounds{lo: mid, hi: b.hi, resplits: b.resplits + 1}, true
+}
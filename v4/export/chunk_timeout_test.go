@@ -0,0 +1,123 @@
+package export
+
+import (
+	"context"
+	"errors"
+	"regexp"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/coreos/go-semver/semver"
+	"github.com/go-sql-driver/mysql"
+	. "github.com/pingcap/check"
+)
+
+var _ = Suite(&testChunkTimeoutSuite{})
+
+type testChunkTimeoutSuite struct{}
+
+func (s *testChunkTimeoutSuite) TestLimitExecutionTime(c *C) {
+	conf := DefaultConfig()
+	query := "SELECT * FROM `test`.`t` WHERE (`id` >= 1 AND `id` < 51)"
+	c.Assert(conf.limitExecutionTime(query), Equals, query)
+
+	conf.ChunkTimeout = 30
+	conf.ServerInfo = ServerInfo{ServerType: ServerTypeMySQL, ServerVersion: semver.New("8.0.18")}
+	c.Assert(conf.limitExecutionTime(query), Equals, "SELECT /*+ MAX_EXECUTION_TIME(30000) */ * FROM `test`.`t` WHERE (`id` >= 1 AND `id` < 51)")
+	c.Assert(conf.limitExecutionTime("select a from t"), Equals, "select /*+ MAX_EXECUTION_TIME(30000) */ a from t")
+	c.Assert(conf.limitExecutionTime("WITH c AS (SELECT 1) SELECT * FROM c"), Equals, "WITH c AS (SELECT 1) SELECT * FROM c")
+	conf.ServerInfo = ServerInfo{ServerType: ServerTypeTiDB, ServerVersion: semver.New("4.0.0")}
+	c.Assert(conf.limitExecutionTime(query), Equals, "SELECT /*+ MAX_EXECUTION_TIME(30000) */ * FROM `test`.`t` WHERE (`id` >= 1 AND `id` < 51)")
+	conf.ServerInfo = ServerInfo{ServerType: ServerTypeMariaDB, ServerVersion: semver.New("10.4.10")}
+	c.Assert(conf.limitExecutionTime(query), Equals, "SET STATEMENT max_statement_time=30 FOR SELECT * FROM `test`.`t` WHERE (`id` >= 1 AND `id` < 51)")
+	// the time is only limited by the client then
+	conf.ServerInfo = ServerInfo{ServerType: ServerTypeMySQL, ServerVersion: semver.New("5.6.40")}
+	c.Assert(conf.limitExecutionTime(query), Equals, query)
+}
+
+func (s *testChunkTimeoutSuite) TestChunkContext(c *C) {
+	conf := DefaultConfig()
+	for _, timeout := range []uint64{UnspecifiedSize, 30} {
+		conf.ChunkTimeout = timeout
+		// the chunks are aborted with the dump
+		ctx, cancel := context.WithCancel(context.Background())
+		chunkCtx, chunkCancel := conf.chunkContext(ctx)
+		_, hasDeadline := chunkCtx.Deadline()
+		c.Assert(hasDeadline, Equals, timeout != UnspecifiedSize)
+		cancel()
+		<-chunkCtx.Done()
+		c.Assert(chunkCtx.Err(), Equals, context.Canceled)
+		chunkCancel()
+	}
+}
+
+func (s *testChunkTimeoutSuite) TestIsQueryTimeout(c *C) {
+	c.Assert(isQueryTimeout(&mysql.MySQLError{Number: errQueryTimeout}), IsTrue)
+	c.Assert(isQueryTimeout(withStack(&mysql.MySQLError{Number: errStatementTimeout})), IsTrue)
+	c.Assert(isQueryTimeout(context.DeadlineExceeded), IsTrue)
+	c.Assert(isQueryTimeout(&mysql.MySQLError{Number: 1146}), IsFalse)
+	c.Assert(isQueryTimeout(errors.New("bad connection")), IsFalse)
+
+	conf := DefaultConfig()
+	ir := &tableData{database: "test", table: "t", chunkIndex: 3}
+	err := context.DeadlineExceeded
+	c.Assert(chunkTimeoutError(conf, ir, err), Equals, err)
+	conf.ChunkTimeout = 30
+	c.Assert(chunkTimeoutError(conf, ir, err), ErrorMatches, "chunk 3 of `test`.`t` isn't read in the chunk-timeout 30s: context deadline exceeded")
+	c.Assert(chunkTimeoutError(conf, ir, nil), IsNil)
+}
+
+func (s *testChunkTimeoutSuite) TestResplitChunkTimingOut(c *C) {
+	db, mock, err := sqlmock.New()
+	c.Assert(err, IsNil)
+	defer db.Close()
+
+	conf := DefaultConfig()
+	conf.ServerInfo = ServerInfo{ServerType: ServerTypeMySQL, ServerVersion: semver.New("8.0.18")}
+	conf.ChunkColumn = "id"
+	conf.Rows = 50
+	conf.SortByPk = false
+	conf.ChunkTimeout = 10
+
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT MIN(`id`),MAX(`id`) FROM `test`.`t`")).
+		WillReturnRows(sqlmock.NewRows([]string{"MIN", "MAX"}).AddRow("1", "100"))
+	mock.ExpectQuery("EXPLAIN SELECT `id` FROM `test`.`t`").
+		WillReturnRows(sqlmock.NewRows([]string{"rows"}).AddRow("100"))
+	mock.ExpectQuery("SELECT COLUMN_NAME,EXTRA FROM INFORMATION_SCHEMA.COLUMNS").
+		WillReturnRows(sqlmock.NewRows([]string{"COLUMN_NAME", "EXTRA"}).AddRow("id", ""))
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM `test`.`t` LIMIT 1")).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}))
+	mock.ExpectQuery("EXPLAIN SELECT").WillReturnRows(sqlmock.NewRows([]string{"rows"}).AddRow("50"))
+	mock.ExpectQuery("EXPLAIN SELECT").WillReturnError(errors.New("explain denied"))
+	hint := "SELECT /*+ MAX_EXECUTION_TIME(10000) */ * FROM `test`.`t` WHERE "
+	mock.ExpectQuery(regexp.QuoteMeta(hint + "(`id` >= 1 AND `id` < 51)")).
+		WillReturnError(&mysql.MySQLError{Number: errQueryTimeout, Message: "Query execution was interrupted, maximum statement execution time exceeded"})
+	for _, where := range []string{"(`id` >= 1 AND `id` < 26)", "(`id` >= 26 AND `id` < 51)"} {
+		mock.ExpectQuery(regexp.QuoteMeta(hint + where)).WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow("1"))
+	}
+	// the halves aren't estimated, only the chunks of the plan are
+	mock.ExpectQuery("EXPLAIN SELECT").WillReturnRows(sqlmock.NewRows([]string{"rows"}).AddRow("50"))
+	mock.ExpectQuery(regexp.QuoteMeta(hint + "(`id` >= 51 AND `id` < 101)")).WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow("1"))
+
+	tableDataIRCh := make(chan TableDataIR, 4)
+	errCh := make(chan error, 1)
+	splitTableDataIntoChunks(context.Background(), tableDataIRCh, errCh, make(chan struct{}), "test", "t", db, conf)
+	var chunks []string
+	for ir := range tableDataIRCh {
+		td := ir.(*tableData)
+		c.Assert(td.Rows().Close(), IsNil)
+		chunks = append(chunks, td.chunkRange)
+		c.Assert(td.chunkIndex, Equals, len(chunks))
+	}
+	c.Assert(chunks, DeepEquals, []string{"(`id` >= 1 AND `id` < 26)", "(`id` >= 26 AND `id` < 51)", "(`id` >= 51 AND `id` < 101)"})
+	c.Assert(mock.ExpectationsWereMet(), IsNil)
+
+	// the chunks can't be split forever
+	bounds := chunkBounds{lo: 1, hi: 3}
+	first, second, ok := bounds.resplit()
+	c.Assert(ok, IsTrue)
+	c.Assert([]chunkBounds{first, second}, DeepEquals, []chunkBounds{{lo: 1, hi: 2, resplits: 1}, {lo: 2, hi: 3, resplits: 1}})
+	_, _, ok = first.resplit()
+	c.Assert(ok, IsFalse)
+	_, _, ok = chunkBounds{lo: 1, hi: 100, resplits: maxChunkResplits}.resplit()
+	c.Assert(ok, IsFalse)
+}
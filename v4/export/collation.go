@@ -0,0 +1,215 @@
+package export
+
+import (
+	"database/sql"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/pingcap/errors"
+)
+
+var (
+	databaseCharsetClause = regexp.MustCompile(`DEFAULT CHARACTER SET (\w+)(?: COLLATE (\w+))?`)
+	tableCharsetOption    = regexp.MustCompile(`DEFAULT CHARSET=(\w+)(?: COLLATE=(\w+))?`)
+	columnCharsetClause   = regexp.MustCompile(` CHARACTER SET \w+`)
+	charsetName           = regexp.MustCompile(`(CHARACTER SET |CHARSET=)(\w+)`)
+	collationName         = regexp.MustCompile(`(COLLATE[ =])(\w+)`)
+)
+
+// utf8mb4Collations are the utf8mb4 collations supported by TiDB.
+var utf8mb4Collations = map[string]bool{
+	"utf8mb4_bin":        true,
+	"utf8mb4_general_ci": true,
+	"utf8mb4_unicode_ci": true,
+	"utf8mb4_0900_ai_ci": true,
+	"utf8mb4_0900_bin":   true,
+}
+
+// collationCharset returns the character set of a collation, which is the
+// prefix of its name.
+func collationCharset(collation string) string {
+	if i := strings.IndexByte(collation, '_'); i > 0 {
+		return collation[:i]
+	}
+	return collation
+}
+
+// explicitDatabaseCollation returns the CREATE DATABASE statement with both
+// the default character set and collation of the database, so that it's
+// restored with the same collation even if the target has another default
+// collation of the character set.
+func explicitDatabaseCollation(conf *Config, db *sql.DB, dbName, createSQL string) (string, error) {
+	var collation string
+	row := db.QueryRow("SELECT DEFAULT_COLLATION_NAME FROM INFORMATION_SCHEMA.SCHEMATA WHERE SCHEMA_NAME = ?", dbName)
+	if err := row.Scan(&collation); err != nil {
+		return "", withStack(errors.WithMessage(err, "query the collation of database "+dbName))
+	}
+	clause := fmt.Sprintf("DEFAULT CHARACTER SET %s COLLATE %s", collationCharset(collation), collation)
+	if databaseCharsetClause.MatchString(createSQL) {
+		createSQL = replaceOutsideQuotes(createSQL, databaseCharsetClause, func([]string) string { return clause })
+	} else {
+		createSQL = fmt.Sprintf("%s /*!40100 %s */", createSQL, clause)
+	}
+	if conf.CoerceUTF8MB4 {
+		createSQL = coerceUTF8MB4(createSQL)
+	}
+	return createSQL, nil
+}
+
+// explicitTableCollations returns the CREATE TABLE statement of SHOW CREATE
+// TABLE with the collation of the table in its options, and the collations
+// of the columns which differ from the table but are omitted since they're
+// the default collations of their character sets.
+func explicitTableCollations(conf *Config, db *sql.DB, dbName, tableName, createSQL string) (string, error) {
+	var tableCollation sql.NullString
+	row := db.QueryRow("SELECT TABLE_COLLATION FROM INFORMATION_SCHEMA.TABLES WHERE TABLE_SCHEMA = ? AND TABLE_NAME = ?", dbName, tableName)
+	if err := row.Scan(&tableCollation); err != nil {
+		return "", withStack(errors.WithMessage(err, "query the collation of table "+qualifiedTableName(dbName, tableName)))
+	}
+	columnCollations, err := listColumnCollations(db, dbName, tableName)
+	if err != nil {
+		return "", err
+	}
+
+	lines := strings.Split(createSQL, "\n")
+	for i, line := range lines {
+		switch {
+		case strings.HasPrefix(line, ")") && tableCollation.String != "":
+			collation := tableCollation.String
+			lines[i] = replaceOutsideQuotes(line, tableCharsetOption, func([]string) string {
+				return fmt.Sprintf("DEFAULT CHARSET=%s COLLATE=%s", collationCharset(collation), collation)
+			})
+		case i > 0 && strings.HasPrefix(strings.TrimSpace(line), "`"):
+			name := columnDefinition.FindString(strings.TrimSpace(line))
+			collation, ok := columnCollations[name]
+			if !ok || collation == tableCollation.String || strings.Contains(line, " COLLATE ") {
+				continue
+			}
+			lines[i] = withColumnCollation(line, name, collation)
+		}
+	}
+	createSQL = strings.Join(lines, "\n")
+	if conf.CoerceUTF8MB4 {
+		createSQL = coerceUTF8MB4(createSQL)
+	}
+	return createSQL, nil
+}
+
+// listColumnCollations returns the collations of the string columns of the
+// table, keyed by their quoted names.
+func listColumnCollations(db *sql.DB, dbName, tableName string) (map[string]string, error) {
+	query := "SELECT COLUMN_NAME, COLLATION_NAME FROM INFORMATION_SCHEMA.COLUMNS WHERE TABLE_SCHEMA = ? AND TABLE_NAME = ? AND COLLATION_NAME IS NOT NULL"
+	rows, err := db.Query(query, dbName, tableName)
+	if err != nil {
+		return nil, withStack(errors.WithMessage(err, query))
+	}
+	defer rows.Close()
+	collations := map[string]string{}
+	for rows.Next() {
+		var name, collation string
+		if err = rows.Scan(&name, &collation); err != nil {
+			return nil, withStack(errors.WithMessage(err, query))
+		}
+		collations[wrapBackTicks(strings.Replace(name, "`", "``", -1))] = collation
+	}
+	if err = rows.Err(); err != nil {
+		return nil, withStack(errors.WithMessage(err, query))
+	}
+	return collations, nil
+}
+
+// withColumnCollation adds the collation to a column definition, after its
+// character set if it's shown, or after its type.
+func withColumnCollation(line, name, collation string) string {
+	if loc := columnCharsetClause.FindStringIndex(line); loc != nil {
+		return line[:loc[1]] + " COLLATE " + collation + line[loc[1]:]
+	}
+	// the type ends at the first space outside the parentheses and quotes
+	start := strings.Index(line, name) + len(name) + 1
+	if start > len(line) {
+		return line
+	}
+	end, depth := start, 0
+	for end < len(line) {
+		switch c := line[end]; {
+		case c == '\'' || c == '"':
+			end = skipQuoted(line, end)
+			continue
+		case c == '(':
+			depth++
+		case c == ')':
+			depth--
+		case (c == ' ' || c == ',') && depth == 0:
+			return fmt.Sprintf("%s CHARACTER SET %s COLLATE %s%s", line[:end], collationCharset(collation), collation, line[end:])
+		}
+		end++
+	}
+	return fmt.Sprintf("%s CHARACTER SET %s COLLATE %s", line, collationCharset(collation), collation)
+}
+
+// coerceUTF8MB4 replaces the character sets of the statement except binary
+// with utf8mb4, and their collations with the utf8mb4 collations supported
+// by TiDB in the same kind.
+func coerceUTF8MB4(createSQL string) string {
+	createSQL = replaceOutsideQuotes(createSQL, charsetName, func(m []string) string {
+		if strings.EqualFold(m[2], "binary") {
+			return m[0]
+		}
+		return m[1] + "utf8mb4"
+	})
+	return replaceOutsideQuotes(createSQL, collationName, func(m []string) string {
+		return m[1] + utf8mb4Collation(m[2])
+	})
+}
+
+// utf8mb4Collation returns the utf8mb4 collation supported by TiDB which
+// sorts in the same kind as collation.
+func utf8mb4Collation(collation string) string {
+	collation = strings.ToLower(collation)
+	if collation == "binary" || utf8mb4Collations[collation] {
+		return collation
+	}
+	for _, prefix := range []string{"utf8_", "utf8mb3_"} {
+		if strings.HasPrefix(collation, prefix) {
+			if c := "utf8mb4_" + strings.TrimPrefix(collation, prefix); utf8mb4Collations[c] {
+				return c
+			}
+		}
+	}
+	switch {
+	case strings.HasSuffix(collation, "_bin"):
+		return "utf8mb4_bin"
+	case strings.Contains(collation, "_unicode"):
+		return "utf8mb4_unicode_ci"
+	case strings.HasSuffix(collation, "_ci"):
+		return "utf8mb4_general_ci"
+	default:
+		return "utf8mb4_bin"
+	}
+}
+
+// replaceOutsideQuotes replaces the matches of re in s which aren't in the
+// quoted strings or identifiers by repl of the submatches.
+func replaceOutsideQuotes(s string, re *regexp.Regexp, repl func(m []string) string) string {
+	var b strings.Builder
+	last := 0
+	flush := func(end int) {
+		b.WriteString(re.ReplaceAllStringFunc(s[last:end], func(match string) string {
+			return repl(re.FindStringSubmatch(match))
+		}))
+	}
+	for i := 0; i < len(s); {
+		switch s[i] {
+		case '\'', '"', '`':
+			flush(i)
+			end := skipQuoted(s, i)
+			b.WriteString(s[i:end])
+			i, last = end, end
+		default:
+			i++
+		}
+	}
+	flush(len(s))
+	return b.String()
+}
@@ -0,0 +1,141 @@
+package export
+
+import (
+	"github.com/DATA-DOG/go-sqlmock"
+	. "github.com/pingcap/check"
+)
+
+var _ = Suite(&testCollationSuite{})
+
+type testCollationSuite struct{}
+
+func (s *testCollationSuite) TestExplicitDatabaseCollation(c *C) {
+	db, mock, err := sqlmock.New()
+	c.Assert(err, IsNil)
+	defer db.Close()
+
+	conf := DefaultConfig()
+	cases := []struct {
+		createSQL string
+		collation string
+		expected  string
+	}{
+		{
+			"CREATE DATABASE `test` /*!40100 DEFAULT CHARACTER SET utf8mb4 */",
+			"utf8mb4_bin",
+			"CREATE DATABASE `test` /*!40100 DEFAULT CHARACTER SET utf8mb4 COLLATE utf8mb4_bin */",
+		},
+		{
+			"CREATE DATABASE `test` /*!40100 DEFAULT CHARACTER SET utf8mb4 COLLATE utf8mb4_0900_ai_ci */ /*!80016 DEFAULT ENCRYPTION='N' */",
+			"utf8mb4_0900_ai_ci",
+			"CREATE DATABASE `test` /*!40100 DEFAULT CHARACTER SET utf8mb4 COLLATE utf8mb4_0900_ai_ci */ /*!80016 DEFAULT ENCRYPTION='N' */",
+		},
+		{
+			"CREATE DATABASE `test`",
+			"latin1_swedish_ci",
+			"CREATE DATABASE `test` /*!40100 DEFAULT CHARACTER SET latin1 COLLATE latin1_swedish_ci */",
+		},
+	}
+	for _, t := range cases {
+		mock.ExpectQuery("SELECT DEFAULT_COLLATION_NAME FROM INFORMATION_SCHEMA.SCHEMATA").WithArgs("test").
+			WillReturnRows(sqlmock.NewRows([]string{"DEFAULT_COLLATION_NAME"}).AddRow(t.collation))
+		createSQL, err := explicitDatabaseCollation(conf, db, "test", t.createSQL)
+		c.Assert(err, IsNil)
+		c.Assert(createSQL, Equals, t.expected)
+	}
+
+	conf.CoerceUTF8MB4 = true
+	mock.ExpectQuery("SELECT DEFAULT_COLLATION_NAME FROM INFORMATION_SCHEMA.SCHEMATA").WithArgs("test").
+		WillReturnRows(sqlmock.NewRows([]string{"DEFAULT_COLLATION_NAME"}).AddRow("latin1_swedish_ci"))
+	createSQL, err := explicitDatabaseCollation(conf, db, "test", "CREATE DATABASE `test` /*!40100 DEFAULT CHARACTER SET latin1 */")
+	c.Assert(err, IsNil)
+	c.Assert(createSQL, Equals, "CREATE DATABASE `test` /*!40100 DEFAULT CHARACTER SET utf8mb4 COLLATE utf8mb4_general_ci */")
+	c.Assert(mock.ExpectationsWereMet(), IsNil)
+}
+
+func (s *testCollationSuite) TestExplicitTableCollations(c *C) {
+	db, mock, err := sqlmock.New()
+	c.Assert(err, IsNil)
+	defer db.Close()
+
+	createSQL := "CREATE TABLE `t` (\n" +
+		"  `id` int(11) NOT NULL,\n" +
+		"  `name` varchar(20) DEFAULT NULL,\n" +
+		"  `code` char(4) CHARACTER SET latin1 DEFAULT NULL,\n" +
+		"  `tag` enum('a b','c') CHARACTER SET utf8 COLLATE utf8_bin DEFAULT NULL,\n" +
+		"  `note` varchar(20) DEFAULT 'CHARSET=latin1',\n" +
+		"  PRIMARY KEY (`id`)\n" +
+		") ENGINE=InnoDB DEFAULT CHARSET=utf8mb4"
+	expectCollations := func() {
+		mock.ExpectQuery("SELECT TABLE_COLLATION FROM INFORMATION_SCHEMA.TABLES").WithArgs("test", "t").
+			WillReturnRows(sqlmock.NewRows([]string{"TABLE_COLLATION"}).AddRow("utf8mb4_general_ci"))
+		mock.ExpectQuery("SELECT COLUMN_NAME, COLLATION_NAME FROM INFORMATION_SCHEMA.COLUMNS").WithArgs("test", "t").
+			WillReturnRows(sqlmock.NewRows([]string{"COLUMN_NAME", "COLLATION_NAME"}).
+				AddRow("name", "utf8mb4_general_ci").
+				AddRow("code", "latin1_swedish_ci").
+				AddRow("tag", "utf8_bin").
+				AddRow("note", "utf8mb4_general_ci"))
+	}
+
+	conf := DefaultConfig()
+	expectCollations()
+	explicit, err := explicitTableCollations(conf, db, "test", "t", createSQL)
+	c.Assert(err, IsNil)
+	c.Assert(explicit, Equals, "CREATE TABLE `t` (\n"+
+		"  `id` int(11) NOT NULL,\n"+
+		"  `name` varchar(20) DEFAULT NULL,\n"+
+		"  `code` char(4) CHARACTER SET latin1 COLLATE latin1_swedish_ci DEFAULT NULL,\n"+
+		"  `tag` enum('a b','c') CHARACTER SET utf8 COLLATE utf8_bin DEFAULT NULL,\n"+
+		"  `note` varchar(20) DEFAULT 'CHARSET=latin1',\n"+
+		"  PRIMARY KEY (`id`)\n"+
+		") ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_general_ci")
+
+	conf.CoerceUTF8MB4 = true
+	expectCollations()
+	coerced, err := explicitTableCollations(conf, db, "test", "t", createSQL)
+	c.Assert(err, IsNil)
+	c.Assert(coerced, Equals, "CREATE TABLE `t` (\n"+
+		"  `id` int(11) NOT NULL,\n"+
+		"  `name` varchar(20) DEFAULT NULL,\n"+
+		"  `code` char(4) CHARACTER SET utf8mb4 COLLATE utf8mb4_general_ci DEFAULT NULL,\n"+
+		"  `tag` enum('a b','c') CHARACTER SET utf8mb4 COLLATE utf8mb4_bin DEFAULT NULL,\n"+
+		"  `note` varchar(20) DEFAULT 'CHARSET=latin1',\n"+
+		"  PRIMARY KEY (`id`)\n"+
+		") ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_general_ci")
+	c.Assert(mock.ExpectationsWereMet(), IsNil)
+}
+
+func (s *testCollationSuite) TestWithColumnCollation(c *C) {
+	c.Assert(withColumnCollation("  `a` enum('x y','z'),", "`a`", "latin1_bin"), Equals,
+		"  `a` enum('x y','z') CHARACTER SET latin1 COLLATE latin1_bin,")
+	c.Assert(withColumnCollation("  `a` text", "`a`", "utf8mb4_bin"), Equals,
+		"  `a` text CHARACTER SET utf8mb4 COLLATE utf8mb4_bin")
+}
+
+func (s *testCollationSuite) TestUTF8MB4Collation(c *C) {
+	cases := map[string]string{
+		"utf8mb4_bin":        "utf8mb4_bin",
+		"utf8mb4_0900_ai_ci": "utf8mb4_0900_ai_ci",
+		"utf8_general_ci":    "utf8mb4_general_ci",
+		"utf8mb3_unicode_ci": "utf8mb4_unicode_ci",
+		"latin1_bin":         "utf8mb4_bin",
+		"latin1_swedish_ci":  "utf8mb4_general_ci",
+		"gbk_chinese_ci":     "utf8mb4_general_ci",
+		"utf8mb4_sv_0900_as": "utf8mb4_bin",
+		"ucs2_unicode_ci":    "utf8mb4_unicode_ci",
+		"binary":             "binary",
+	}
+	for collation, expected := range cases {
+		c.Assert(utf8mb4Collation(collation), Equals, expected, Commentf("collation %s", collation))
+	}
+	c.Assert(coerceUTF8MB4("  `b` varbinary(10) CHARACTER SET binary COLLATE binary"), Equals,
+		"  `b` varbinary(10) CHARACTER SET binary COLLATE binary")
+}
+
+func (s *testCollationSuite) TestValidate(c *C) {
+	conf := DefaultConfig()
+	conf.CoerceUTF8MB4 = true
+	c.Assert(conf.Validate(), IsNil)
+	conf.TargetDialect = TargetPostgres
+	c.Assert(conf.Validate(), ErrorMatches, ".*explicit-collations and coerce-utf8mb4 are not supported when the DDL is translated.*")
+}
@@ -0,0 +1,283 @@
+package export
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"math/big"
+	"regexp"
+	"strings"
+
+	"github.com/pingcap/errors"
+)
+
+// ColumnRewrite rewrites the non-NULL values of a column of a table in the
+// output, exactly one of Regexp, Value, Lookup and Pseudonymize is set.
+type ColumnRewrite struct {
+	Database string
+	Table    string
+	Column   string
+	// Regexp is replaced by Replace in the values, where $1 and ${name} are
+	// expanded to the submatches.
+	Regexp  string
+	Replace string
+	// Value overrides the values if it's not nil.
+	Value *string
+	// Lookup maps the values, the values not in it are kept.
+	Lookup map[string]string
+	// Pseudonymize replaces the values by their HMAC-SHA256 keyed by the
+	// PseudonymizeSalt of the dump, so the same values are replaced by the
+	// same pseudonyms in all the tables, and the tables are still joinable.
+	Pseudonymize bool
+	// Length limits the hex digits of the pseudonyms of the string columns,
+	// and the decimal digits of the numeric columns, 0 means no limit.
+	Length int
+}
+
+// pseudonymLimits are the exclusive upper bounds of the pseudonyms of the
+// numeric columns which can be pseudonymized. The integers narrower than INT
+// aren't, since their pseudonyms would collide for a few hundred values, and
+// neither are the decimals and floats, whose ranges depend on their precisions.
+var pseudonymLimits = map[string]uint64{
+	"INT":     1 << 31,
+	"INTEGER": 1 << 31,
+	"INT4":    1 << 31,
+	"OID":     1 << 31,
+	"BIGINT":  1 << 63,
+	"INT8":    1 << 63,
+}
+
+func (rw *ColumnRewrite) kinds() int {
+	kinds := 0
+	if rw.Regexp != "" {
+		kinds++
+	}
+	if rw.Value != nil {
+		kinds++
+	}
+	if rw.Lookup != nil {
+		kinds++
+	}
+	if rw.Pseudonymize {
+		kinds++
+	}
+	return kinds
+}
+
+// pseudonymizes returns whether any column rewrite pseudonymizes the values.
+func (conf *Config) pseudonymizes() bool {
+	for _, rw := range conf.ColumnRewrites {
+		if rw.Pseudonymize {
+			return true
+		}
+	}
+	return false
+}
+
+// columnRewritesConflicts returns the invalid column rewrites of conf and
+// the options conflicting with them.
+func columnRewritesConflicts(conf *Config) []string {
+	if len(conf.ColumnRewrites) == 0 {
+		return nil
+	}
+	var conflicts []string
+	for _, rw := range conf.ColumnRewrites {
+		name := fmt.Sprintf("%s.%s", qualifiedTableName(rw.Database, rw.Table), wrapBackTicks(rw.Column))
+		if rw.Database == "" || rw.Table == "" || rw.Column == "" {
+			conflicts = append(conflicts, "the database, table and column of the column rewrite should be set, got "+name)
+			continue
+		}
+		if rw.kinds() != 1 {
+			conflicts = append(conflicts, "exactly one of regexp, value, lookup and pseudonymize of the column rewrite should be set for "+name)
+			continue
+		}
+		if rw.Length < 0 || rw.Length > 0 && !rw.Pseudonymize || rw.Length > sha256.Size*2 {
+			conflicts = append(conflicts, fmt.Sprintf("length of the column rewrite of %s should be between 0 and %d with pseudonymize, got %d",
+				name, sha256.Size*2, rw.Length))
+		}
+		if rw.Regexp != "" {
+			if _, err := regexp.Compile(rw.Regexp); err != nil {
+				conflicts = append(conflicts, fmt.Sprintf("invalid regexp of the column rewrite of %s: %v", name, err))
+			}
+		}
+	}
+	if conf.ServerOutfileDir != "" {
+		conflicts = append(conflicts, "column rewrites are not supported with server-outfile-dir")
+	}
+	return conflicts
+}
+
+// columnRewriter returns the rewritten value of a non-NULL value.
+type columnRewriter func(value []byte) ([]byte, error)
+
+// newColumnRewriter returns the rewriter of rw for the column of colType.
+// The values of the numeric columns should still be numbers after rewriting,
+// since they're written without quotes.
+func newColumnRewriter(conf *Config, rw *ColumnRewrite, colType string) (columnRewriter, error) {
+	var rewrite columnRewriter
+	switch {
+	case rw.Pseudonymize:
+		if _, ok := pseudonymLimits[colType]; isNumberType(colType) && !ok {
+			return nil, errors.Errorf("the %s column %s of %s can't be pseudonymized, only the string columns and the integers of INT and BIGINT can",
+				colType, rw.Column, qualifiedTableName(rw.Database, rw.Table))
+		}
+		return newPseudonymizer([]byte(conf.PseudonymizeSalt), rw.Length, colType), nil
+	case rw.Regexp != "":
+		re, err := regexp.Compile(rw.Regexp)
+		if err != nil {
+			return nil, errors.Trace(err)
+		}
+		replace := []byte(rw.Replace)
+		rewrite = func(value []byte) ([]byte, error) {
+			return re.ReplaceAll(value, replace), nil
+		}
+	case rw.Value != nil:
+		value := []byte(*rw.Value)
+		rewrite = func([]byte) ([]byte, error) {
+			return value, nil
+		}
+	default:
+		lookup := rw.Lookup
+		rewrite = func(value []byte) ([]byte, error) {
+			if mapped, ok := lookup[string(value)]; ok {
+				return []byte(mapped), nil
+			}
+			return value, nil
+		}
+	}
+	if !isNumberType(colType) {
+		return rewrite, nil
+	}
+	return func(value []byte) ([]byte, error) {
+		rewritten, err := rewrite(value)
+		if err != nil {
+			return nil, err
+		}
+		if _, ok := new(big.Rat).SetString(string(rewritten)); !ok {
+			return nil, errors.Errorf("the rewritten value %q of the %s column isn't a number", rewritten, colType)
+		}
+		return rewritten, nil
+	}, nil
+}
+
+// newPseudonymizer returns the rewriter replacing the values by their
+// HMAC-SHA256 keyed by salt, in hex for the string columns, or as a
+// non-negative integer within the range of the integer columns of
+// pseudonymLimits.
+func newPseudonymizer(salt []byte, length int, colType string) columnRewriter {
+	sum := func(value []byte) []byte {
+		mac := hmac.New(sha256.New, salt)
+		mac.Write(value)
+		return mac.Sum(nil)
+	}
+	if !isNumberType(colType) {
+		return func(value []byte) ([]byte, error) {
+			pseudonym := []byte(hex.EncodeToString(sum(value)))
+			if length > 0 {
+				pseudonym = pseudonym[:length]
+			}
+			return pseudonym, nil
+		}
+	}
+	limit := new(big.Int).SetUint64(pseudonymLimits[colType])
+	if length > 0 {
+		if digits := new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(length)), nil); digits.Cmp(limit) < 0 {
+			limit = digits
+		}
+	}
+	return func(value []byte) ([]byte, error) {
+		n := new(big.Int).SetBytes(sum(value))
+		return []byte(n.Mod(n, limit).String()), nil
+	}
+}
+
+// rewrittenTableData rewrites the values of the columns of a TableDataIR
+// which have rewriters.
+type rewrittenTableData struct {
+	TableDataIR
+	rewriters []columnRewriter
+}
+
+// withColumnRewrites returns ir with the values rewritten by the column
+// rewrites of its table, or ir itself if there isn't any.
+func withColumnRewrites(conf *Config, ir TableDataIR) (TableDataIR, error) {
+	var rewriters []columnRewriter
+	columns, colTypes := ir.ColumnNames(), ir.ColumnTypes()
+	for i := range conf.ColumnRewrites {
+		rw := &conf.ColumnRewrites[i]
+		if rw.Database != ir.DatabaseName() || rw.Table != ir.TableName() {
+			continue
+		}
+		index := -1
+		for j, column := range columns {
+			if strings.EqualFold(column, rw.Column) {
+				index = j
+				break
+			}
+		}
+		if index < 0 {
+			return nil, withKind(ErrorKindConfig, errors.Errorf("the column %s of the column rewrite isn't found in %s",
+				rw.Column, qualifiedTableName(rw.Database, rw.Table)))
+		}
+		if rewriters == nil {
+			rewriters = make([]columnRewriter, len(columns))
+		}
+		if rewriters[index] != nil {
+			return nil, withKind(ErrorKindConfig, errors.Errorf("the column %s of %s is rewritten more than once",
+				rw.Column, qualifiedTableName(rw.Database, rw.Table)))
+		}
+		rewriter, err := newColumnRewriter(conf, rw, colTypes[index])
+		if err != nil {
+			return nil, withKind(ErrorKindConfig, err)
+		}
+		rewriters[index] = rewriter
+	}
+	if rewriters == nil {
+		return ir, nil
+	}
+	return &rewrittenTableData{TableDataIR: ir, rewriters: rewriters}, nil
+}
+
+func (td *rewrittenTableData) Rows() SQLRowIter {
+	n := td.ColumnCount()
+	return &rewrittenRowIter{
+		SQLRowIter: td.TableDataIR.Rows(),
+		rewriters:  td.rewriters,
+		row:        make(rawRow, n),
+		args:       make([]interface{}, n),
+	}
+}
+
+type rewrittenRowIter struct {
+	SQLRowIter
+	rewriters []columnRewriter
+	row       rawRow
+	args      []interface{}
+}
+
+func (iter *rewrittenRowIter) Decode(row RowReceiver) error {
+	if err := iter.SQLRowIter.Decode(iter.row); err != nil {
+		return err
+	}
+	for i, rewrite := range iter.rewriters {
+		if rewrite == nil || iter.row[i] == nil {
+			continue
+		}
+		rewritten, err := rewrite(iter.row[i])
+		if err != nil {
+			return err
+		}
+		iter.row[i] = rewritten
+	}
+	return iter.row.copyTo(row, iter.args)
+}
+
+func (iter *rewrittenRowIter) NextSQLRowIter() SQLRowIter {
+	return &rewrittenRowIter{
+		SQLRowIter: iter.SQLRowIter.NextSQLRowIter(),
+		rewriters:  iter.rewriters,
+		row:        iter.row,
+		args:       iter.args,
+	}
+}
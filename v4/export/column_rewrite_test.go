@@ -0,0 +1,176 @@
+package export
+
+import (
+	"bytes"
+	"context"
+	"database/sql/driver"
+	"fmt"
+	"strconv"
+
+	. "github.com/pingcap/check"
+)
+
+var _ = Suite(&testColumnRewriteSuite{})
+
+type testColumnRewriteSuite struct{}
+
+func newRewriteTableIR() *mockTableIR {
+	return &mockTableIR{
+		dbName:  "app",
+		tblName: "users",
+		data: [][]driver.Value{
+			{"1", "alice@mail.com", "https://cdn.prod.example.com/a.png", "us-east-1", "100"},
+			{"2", nil, "https://other.example.com/b.png", "eu-west-1", nil},
+		},
+		selectedField: "*",
+		colTypes:      []string{"INT", "VARCHAR", "TEXT", "VARCHAR", "BIGINT"},
+		colNames:      []string{"id", "email", "avatar", "region", "score"},
+	}
+}
+
+func (s *testColumnRewriteSuite) TestWriteRewrittenRows(c *C) {
+	fixed := "0"
+	conf := DefaultConfig()
+	conf.ColumnRewrites = []ColumnRewrite{
+		{Database: "app", Table: "users", Column: "Email", Regexp: "^[^@]+@", Replace: "user@"},
+		{Database: "app", Table: "users", Column: "avatar", Regexp: `^https://cdn\.prod\.(example\.com)/`, Replace: "https://cdn.staging.$1/"},
+		{Database: "app", Table: "users", Column: "region", Lookup: map[string]string{"us-east-1": "staging-east"}},
+		{Database: "app", Table: "users", Column: "score", Value: &fixed},
+		{Database: "app", Table: "orders", Column: "missing", Value: &fixed},
+	}
+	ir, err := withColumnRewrites(conf, newRewriteTableIR())
+	c.Assert(err, IsNil)
+	bf := &bytes.Buffer{}
+	c.Assert(WriteInsert(context.Background(), ir, bf, UnspecifiedSize, nil), IsNil)
+	c.Assert(bf.String(), Equals, "INSERT INTO `users` VALUES\n"+
+		"(1,'user@mail.com','https://cdn.staging.example.com/a.png','staging-east',0),\n"+
+		"(2,NULL,'https://other.example.com/b.png','eu-west-1',NULL);\n")
+
+	// the tables without rewrites are kept
+	conf.ColumnRewrites = conf.ColumnRewrites[4:]
+	other := newRewriteTableIR()
+	ir, err = withColumnRewrites(conf, other)
+	c.Assert(err, IsNil)
+	c.Assert(ir, Equals, TableDataIR(other))
+}
+
+func (s *testColumnRewriteSuite) TestRewriteErrors(c *C) {
+	conf := DefaultConfig()
+	conf.ColumnRewrites = []ColumnRewrite{{Database: "app", Table: "users", Column: "age", Lookup: map[string]string{}}}
+	_, err := withColumnRewrites(conf, newRewriteTableIR())
+	c.Assert(err, ErrorMatches, "the column age of the column rewrite isn't found in `app`.`users`")
+
+	conf.ColumnRewrites = []ColumnRewrite{
+		{Database: "app", Table: "users", Column: "email", Lookup: map[string]string{}},
+		{Database: "app", Table: "users", Column: "email", Regexp: "a", Replace: "b"},
+	}
+	_, err = withColumnRewrites(conf, newRewriteTableIR())
+	c.Assert(err, ErrorMatches, "the column email of `app`.`users` is rewritten more than once")
+
+	// the numeric columns are written without quotes
+	conf.ColumnRewrites = []ColumnRewrite{{Database: "app", Table: "users", Column: "score", Regexp: "^", Replace: "x"}}
+	ir, err := withColumnRewrites(conf, newRewriteTableIR())
+	c.Assert(err, IsNil)
+	err = WriteInsert(context.Background(), ir, &bytes.Buffer{}, UnspecifiedSize, nil)
+	c.Assert(err, ErrorMatches, `the rewritten value "x100" of the BIGINT column isn't a number`)
+}
+
+func (s *testColumnRewriteSuite) TestValidate(c *C) {
+	value := "x"
+	conf := DefaultConfig()
+	conf.ColumnRewrites = []ColumnRewrite{{Database: "app", Table: "users", Column: "email", Value: &value}}
+	c.Assert(conf.Validate(), IsNil)
+
+	conf.ColumnRewrites = []ColumnRewrite{{Database: "app", Column: "email", Value: &value}}
+	c.Assert(conf.Validate(), ErrorMatches, "(?s).*the database, table and column of the column rewrite should be set, got `app`.``.`email`.*")
+	conf.ColumnRewrites = []ColumnRewrite{{Database: "app", Table: "users", Column: "email", Value: &value, Regexp: "a"}}
+	c.Assert(conf.Validate(), ErrorMatches, "(?s).*exactly one of regexp, value, lookup and pseudonymize of the column rewrite should be set for `app`.`users`.`email`.*")
+	conf.ColumnRewrites = []ColumnRewrite{{Database: "app", Table: "users", Column: "email", Regexp: "("}}
+	c.Assert(conf.Validate(), ErrorMatches, "(?s).*invalid regexp of the column rewrite of `app`.`users`.`email`: .*")
+	conf.ColumnRewrites = []ColumnRewrite{{Database: "app", Table: "users", Column: "email", Value: &value, Length: 8}}
+	c.Assert(conf.Validate(), ErrorMatches, "(?s).*length of the column rewrite of `app`.`users`.`email` should be between 0 and 64 with pseudonymize, got 8.*")
+	conf.ColumnRewrites = []ColumnRewrite{{Database: "app", Table: "users", Column: "email", Pseudonymize: true, Length: 65}}
+	c.Assert(conf.Validate(), ErrorMatches, "(?s).*should be between 0 and 64 with pseudonymize, got 65.*")
+	conf.ColumnRewrites[0].Length = 64
+	c.Assert(conf.Validate(), IsNil)
+	c.Assert(conf.pseudonymizes(), IsTrue)
+}
+
+func (s *testColumnRewriteSuite) TestPseudonymize(c *C) {
+	salt := []byte("sandbox")
+	hexPseudonym, err := newPseudonymizer(salt, 0, "VARCHAR")([]byte("alice"))
+	c.Assert(err, IsNil)
+	c.Assert(string(hexPseudonym), Matches, "[0-9a-f]{64}")
+	for _, colType := range []string{"CHAR", "BLOB", "TEXT"} {
+		pseudonym, err := newPseudonymizer(salt, 0, colType)([]byte("alice"))
+		c.Assert(err, IsNil)
+		c.Assert(pseudonym, DeepEquals, hexPseudonym)
+	}
+	short, err := newPseudonymizer(salt, 12, "VARCHAR")([]byte("alice"))
+	c.Assert(err, IsNil)
+	c.Assert(short, DeepEquals, hexPseudonym[:12])
+	other, err := newPseudonymizer([]byte("other"), 0, "VARCHAR")([]byte("alice"))
+	c.Assert(err, IsNil)
+	c.Assert(other, Not(DeepEquals), hexPseudonym)
+
+	// the numeric pseudonyms are within the range of the type
+	seen := map[string]bool{}
+	for i := 0; i < 100; i++ {
+		value := []byte(fmt.Sprint(i))
+		for colType, limit := range map[string]uint64{"INT": 1 << 31, "INT4": 1 << 31, "BIGINT": 1 << 63} {
+			pseudonym, err := newPseudonymizer(salt, 0, colType)(value)
+			c.Assert(err, IsNil)
+			n, err := strconv.ParseUint(string(pseudonym), 10, 64)
+			c.Assert(err, IsNil)
+			c.Assert(n < limit, IsTrue, Commentf("%s of %s", pseudonym, colType))
+		}
+		pseudonym, err := newPseudonymizer(salt, 4, "BIGINT")(value)
+		c.Assert(err, IsNil)
+		c.Assert(len(pseudonym) <= 4, IsTrue)
+		seen[string(pseudonym)] = true
+	}
+	c.Assert(len(seen) > 90, IsTrue)
+
+	// the narrow integers would collide, and the decimals and floats may overflow
+	conf := DefaultConfig()
+	for _, colType := range []string{"TINYINT", "SMALLINT", "MEDIUMINT", "BOOL", "DECIMAL", "FLOAT", "DOUBLE"} {
+		_, err := newColumnRewriter(conf, &ColumnRewrite{Database: "app", Table: "users", Column: "id", Pseudonymize: true}, colType)
+		c.Assert(err, ErrorMatches, "the "+colType+" column id of `app`.`users` can't be pseudonymized, .*")
+	}
+}
+
+func (s *testColumnRewriteSuite) TestJoinablePseudonyms(c *C) {
+	conf := DefaultConfig()
+	conf.PseudonymizeSalt = "sandbox"
+	conf.ColumnRewrites = []ColumnRewrite{
+		{Database: "app", Table: "users", Column: "id", Pseudonymize: true},
+		{Database: "app", Table: "orders", Column: "user_id", Pseudonymize: true},
+	}
+	users := &mockTableIR{
+		dbName: "app", tblName: "users", selectedField: "*",
+		data:     [][]driver.Value{{"1", "alice"}, {"2", "bob"}},
+		colTypes: []string{"INT", "VARCHAR"}, colNames: []string{"id", "name"},
+	}
+	orders := &mockTableIR{
+		dbName: "app", tblName: "orders", selectedField: "*",
+		data:     [][]driver.Value{{"10", "2"}, {"11", "1"}, {"12", nil}},
+		colTypes: []string{"INT", "INT"}, colNames: []string{"id", "user_id"},
+	}
+	pseudonyms := func(ir TableDataIR, column int) []string {
+		ir, err := withColumnRewrites(conf, ir)
+		c.Assert(err, IsNil)
+		var values []string
+		iter := ir.Rows()
+		row := make(rawRow, ir.ColumnCount())
+		for ; iter.HasNext(); iter.Next() {
+			c.Assert(iter.Decode(row), IsNil)
+			values = append(values, string(row[column]))
+		}
+		c.Assert(iter.Close(), IsNil)
+		return values
+	}
+	userIDs := pseudonyms(users, 0)
+	c.Assert(userIDs[0], Not(Equals), "1")
+	c.Assert(pseudonyms(orders, 1), DeepEquals, []string{userIDs[1], userIDs[0], ""})
+	c.Assert(pseudonyms(orders, 0), DeepEquals, []string{"10", "11", "12"})
+}
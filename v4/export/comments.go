@@ -0,0 +1,297 @@
+package export
+
+import (
+	"database/sql"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/pingcap/errors"
+)
+
+// ddlComment is a COMMENT clause of a CREATE TABLE statement.
+type ddlComment struct {
+	// object is "table", or like "column `id`", "index `idx`" and "partition `p0`"
+	object string
+	// start and end are the offsets of the quoted string of the comment
+	start, end int
+	text       string
+}
+
+// verifyComments checks the COMMENT clauses of the table, column, index and
+// partition definitions in the CREATE TABLE statement of SHOW CREATE TABLE
+// are the same as INFORMATION_SCHEMA after unescaped, so that they're
+// restored as they are. It returns the statement with the comments escaped
+// in the same way, which only uses the escapes \\, \n, \r, \0, \Z and the
+// doubled quotes understood by all the MySQL compatible parsers.
+func verifyComments(db *sql.DB, dbName, tableName, createSQL string) (string, error) {
+	expected, err := listComments(db, dbName, tableName)
+	if err != nil {
+		return "", err
+	}
+	comments := findComments(createSQL)
+	parsed := make(map[string]string, len(comments))
+	for _, comment := range comments {
+		parsed[comment.object] = comment.text
+	}
+
+	objects := make([]string, 0, len(expected)+len(parsed))
+	for object := range expected {
+		objects = append(objects, object)
+	}
+	for object := range parsed {
+		if _, ok := expected[object]; !ok {
+			objects = append(objects, object)
+		}
+	}
+	sort.Strings(objects)
+	var mismatches []string
+	for _, object := range objects {
+		if parsed[object] != expected[object] {
+			mismatches = append(mismatches, fmt.Sprintf("the comment of %s is %q in the DDL but %q in INFORMATION_SCHEMA",
+				object, parsed[object], expected[object]))
+		}
+	}
+	if len(mismatches) > 0 {
+		return "", errors.Errorf("the comments of %s don't round-trip: %s",
+			qualifiedTableName(dbName, tableName), strings.Join(mismatches, "; "))
+	}
+
+	var b strings.Builder
+	last := 0
+	for _, comment := range comments {
+		b.WriteString(createSQL[last:comment.start])
+		b.WriteString(quoteComment(comment.text))
+		last = comment.end
+	}
+	b.WriteString(createSQL[last:])
+	return b.String(), nil
+}
+
+// listComments returns the non-empty comments of the table and its columns,
+// indexes and partitions in INFORMATION_SCHEMA, keyed like ddlComment.object.
+func listComments(db *sql.DB, dbName, tableName string) (map[string]string, error) {
+	comments := map[string]string{}
+	queries := []struct {
+		query  string
+		object func(names []sql.NullString) string
+	}{
+		{
+			"SELECT TABLE_COMMENT FROM INFORMATION_SCHEMA.TABLES WHERE TABLE_SCHEMA = ? AND TABLE_NAME = ?",
+			func([]sql.NullString) string { return "table" },
+		},
+		{
+			"SELECT COLUMN_NAME, COLUMN_COMMENT FROM INFORMATION_SCHEMA.COLUMNS WHERE TABLE_SCHEMA = ? AND TABLE_NAME = ?",
+			func(names []sql.NullString) string { return "column " + wrapBackTicks(names[0].String) },
+		},
+		{
+			"SELECT DISTINCT INDEX_NAME, INDEX_COMMENT FROM INFORMATION_SCHEMA.STATISTICS WHERE TABLE_SCHEMA = ? AND TABLE_NAME = ?",
+			func(names []sql.NullString) string { return "index " + wrapBackTicks(names[0].String) },
+		},
+		{
+			"SELECT PARTITION_NAME, SUBPARTITION_NAME, PARTITION_COMMENT FROM INFORMATION_SCHEMA.PARTITIONS " +
+				"WHERE TABLE_SCHEMA = ? AND TABLE_NAME = ? AND PARTITION_NAME IS NOT NULL",
+			func(names []sql.NullString) string {
+				// the comments of the subpartitions are shown on themselves
+				if names[1].Valid {
+					return "partition " + wrapBackTicks(names[1].String)
+				}
+				return "partition " + wrapBackTicks(names[0].String)
+			},
+		},
+	}
+	for _, q := range queries {
+		rows, err := db.Query(q.query, dbName, tableName)
+		if err != nil {
+			return nil, withStack(errors.WithMessage(err, q.query))
+		}
+		columns, err := rows.Columns()
+		if err != nil {
+			rows.Close()
+			return nil, withStack(err)
+		}
+		values := make([]sql.NullString, len(columns))
+		args := make([]interface{}, len(columns))
+		for i := range values {
+			args[i] = &values[i]
+		}
+		for rows.Next() {
+			if err = rows.Scan(args...); err != nil {
+				rows.Close()
+				return nil, withStack(errors.WithMessage(err, q.query))
+			}
+			if comment := values[len(values)-1].String; comment != "" {
+				comments[q.object(values)] = comment
+			}
+		}
+		rows.Close()
+		if err = rows.Err(); err != nil {
+			return nil, withStack(errors.WithMessage(err, q.query))
+		}
+	}
+	return comments, nil
+}
+
+// findComments returns the COMMENT clauses of a CREATE TABLE statement of
+// SHOW CREATE TABLE, which has a line for each column, index and constraint
+// followed by the table options and partitions.
+func findComments(createSQL string) []ddlComment {
+	var comments []ddlComment
+	object := ""
+	for i := 0; i < len(createSQL); {
+		if i == 0 || createSQL[i-1] == '\n' {
+			object = lineObject(strings.TrimSpace(lineAt(createSQL, i)), object)
+		}
+		switch c := createSQL[i]; {
+		case c == '\'' || c == '"' || c == '`':
+			i = skipQuoted(createSQL, i)
+		case isWordByte(c):
+			start := i
+			for i < len(createSQL) && isWordByte(createSQL[i]) {
+				i++
+			}
+			switch strings.ToUpper(createSQL[start:i]) {
+			case "COMMENT":
+				j := i
+				for j < len(createSQL) && (createSQL[j] == ' ' || createSQL[j] == '=') {
+					j++
+				}
+				if j < len(createSQL) && createSQL[j] == '\'' {
+					end := skipQuoted(createSQL, j)
+					comments = append(comments, ddlComment{
+						object: object,
+						start:  j,
+						end:    end,
+						text:   unescapeSQLString(createSQL[j+1 : end-1]),
+					})
+					i = end
+				}
+			case "PARTITION", "SUBPARTITION":
+				if object == "table" || strings.HasPrefix(object, "partition ") {
+					j := i
+					for j < len(createSQL) && createSQL[j] == ' ' {
+						j++
+					}
+					end := j
+					if end < len(createSQL) && createSQL[end] == '`' {
+						end = skipQuoted(createSQL, end)
+					} else {
+						for end < len(createSQL) && isWordByte(createSQL[end]) {
+							end++
+						}
+					}
+					if name := createSQL[j:end]; name != "" && !strings.EqualFold(name, "BY") {
+						object = "partition " + wrapBackTicks(strings.Trim(name, "`"))
+					}
+				}
+			}
+		default:
+			i++
+		}
+	}
+	return comments
+}
+
+// lineObject returns the object defined by a line of SHOW CREATE TABLE, or
+// the object of the previous line if it's not a definition.
+func lineObject(line, previous string) string {
+	def := strings.TrimSuffix(line, ",")
+	if m := indexDefinition.FindStringSubmatch(def); m != nil {
+		return "index " + m[1]
+	}
+	if strings.HasPrefix(def, "PRIMARY KEY") {
+		return "index `PRIMARY`"
+	}
+	if m := constraintDefinition.FindStringSubmatch(def); m != nil {
+		return "constraint " + m[1]
+	}
+	if m := columnDefinition.FindString(def); m != "" {
+		return "column " + m
+	}
+	if strings.HasPrefix(def, ")") && !strings.HasPrefix(previous, "partition ") {
+		return "table"
+	}
+	return previous
+}
+
+func lineAt(s string, i int) string {
+	if end := strings.IndexByte(s[i:], '\n'); end >= 0 {
+		return s[i : i+end]
+	}
+	return s[i:]
+}
+
+func isWordByte(c byte) bool {
+	return c == '_' || c >= '0' && c <= '9' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z'
+}
+
+// skipQuoted returns the offset after the string or identifier quoted at i,
+// whose quote is escaped by doubling it or by a backslash except in
+// identifiers.
+func skipQuoted(s string, i int) int {
+	quote := s[i]
+	for j := i + 1; j < len(s); j++ {
+		switch {
+		case s[j] == '\\' && quote != '`':
+			j++
+		case s[j] == quote:
+			if j+1 < len(s) && s[j+1] == quote {
+				j++
+				continue
+			}
+			return j + 1
+		}
+	}
+	return len(s)
+}
+
+// unescapeSQLString unescapes the content of a quoted string of MySQL.
+func unescapeSQLString(s string) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case c == '\'' && i+1 < len(s) && s[i+1] == '\'':
+			b.WriteByte('\'')
+			i++
+		case c == '\\' && i+1 < len(s):
+			i++
+			switch s[i] {
+			case '0':
+				b.WriteByte(0)
+			case 'b':
+				b.WriteByte('\b')
+			case 'n':
+				b.WriteByte('\n')
+			case 'r':
+				b.WriteByte('\r')
+			case 't':
+				b.WriteByte('\t')
+			case 'Z':
+				b.WriteByte(26)
+			case '%', '_':
+				// they're kept escaped for LIKE
+				b.WriteByte('\\')
+				b.WriteByte(s[i])
+			default:
+				b.WriteByte(s[i])
+			}
+		default:
+			b.WriteByte(c)
+		}
+	}
+	return b.String()
+}
+
+// quoteComment quotes the comment with the escapes understood by all the
+// MySQL compatible parsers.
+func quoteComment(comment string) string {
+	return "'" + strings.NewReplacer(
+		`\`, `\\`,
+		`'`, `''`,
+		"\n", `\n`,
+		"\r", `\r`,
+		"\x00", `\0`,
+		"\x1a", `\Z`,
+	).Replace(comment) + "'"
+}
@@ -0,0 +1,92 @@
+package export
+
+import (
+	"github.com/DATA-DOG/go-sqlmock"
+	. "github.com/pingcap/check"
+)
+
+var _ = Suite(&testCommentsSuite{})
+
+type testCommentsSuite struct{}
+
+const commentedCreateTable = "CREATE TABLE `t` (\n" +
+	"  `id` int(11) NOT NULL COMMENT 'the \\'id\\'',\n" +
+	"  `note` varchar(20) DEFAULT 'COMMENT ''x''',\n" +
+	"  `memo` text COMMENT 'line1\\nline2 \\\\ 100\\%',\n" +
+	"  PRIMARY KEY (`id`),\n" +
+	"  KEY `idx_note` (`note`) COMMENT 'by note'\n" +
+	") ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COMMENT='it''s t'\n" +
+	"/*!50100 PARTITION BY RANGE (`id`)\n" +
+	"(PARTITION p0 VALUES LESS THAN (10) COMMENT = 'first' ENGINE = InnoDB,\n" +
+	" PARTITION `p1` VALUES LESS THAN MAXVALUE ENGINE = InnoDB) */"
+
+func (s *testCommentsSuite) TestFindComments(c *C) {
+	comments := findComments(commentedCreateTable)
+	objects := make([]string, 0, len(comments))
+	texts := make([]string, 0, len(comments))
+	for _, comment := range comments {
+		objects = append(objects, comment.object)
+		texts = append(texts, comment.text)
+		c.Assert(commentedCreateTable[comment.start], Equals, byte('\''))
+		c.Assert(commentedCreateTable[comment.end-1], Equals, byte('\''))
+	}
+	c.Assert(objects, DeepEquals, []string{"column `id`", "column `memo`", "index `idx_note`", "table", "partition `p0`"})
+	c.Assert(texts, DeepEquals, []string{"the 'id'", "line1\nline2 \\ 100\\%", "by note", "it's t", "first"})
+}
+
+func (s *testCommentsSuite) TestVerifyComments(c *C) {
+	db, mock, err := sqlmock.New()
+	c.Assert(err, IsNil)
+	defer db.Close()
+
+	expectComments := func(memo string) {
+		mock.ExpectQuery("SELECT TABLE_COMMENT FROM INFORMATION_SCHEMA.TABLES").WithArgs("test", "t").
+			WillReturnRows(sqlmock.NewRows([]string{"TABLE_COMMENT"}).AddRow("it's t"))
+		mock.ExpectQuery("SELECT COLUMN_NAME, COLUMN_COMMENT FROM INFORMATION_SCHEMA.COLUMNS").WithArgs("test", "t").
+			WillReturnRows(sqlmock.NewRows([]string{"COLUMN_NAME", "COLUMN_COMMENT"}).
+				AddRow("id", "the 'id'").AddRow("note", "").AddRow("memo", memo))
+		mock.ExpectQuery("SELECT DISTINCT INDEX_NAME, INDEX_COMMENT FROM INFORMATION_SCHEMA.STATISTICS").WithArgs("test", "t").
+			WillReturnRows(sqlmock.NewRows([]string{"INDEX_NAME", "INDEX_COMMENT"}).
+				AddRow("PRIMARY", "").AddRow("idx_note", "by note"))
+		mock.ExpectQuery("SELECT PARTITION_NAME, SUBPARTITION_NAME, PARTITION_COMMENT FROM INFORMATION_SCHEMA.PARTITIONS").WithArgs("test", "t").
+			WillReturnRows(sqlmock.NewRows([]string{"PARTITION_NAME", "SUBPARTITION_NAME", "PARTITION_COMMENT"}).
+				AddRow("p0", nil, "first").AddRow("p1", nil, ""))
+	}
+
+	expectComments("line1\nline2 \\ 100\\%")
+	createSQL, err := verifyComments(db, "test", "t", commentedCreateTable)
+	c.Assert(err, IsNil)
+	c.Assert(createSQL, Equals, "CREATE TABLE `t` (\n"+
+		"  `id` int(11) NOT NULL COMMENT 'the ''id''',\n"+
+		"  `note` varchar(20) DEFAULT 'COMMENT ''x''',\n"+
+		"  `memo` text COMMENT 'line1\\nline2 \\\\ 100\\\\%',\n"+
+		"  PRIMARY KEY (`id`),\n"+
+		"  KEY `idx_note` (`note`) COMMENT 'by note'\n"+
+		") ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COMMENT='it''s t'\n"+
+		"/*!50100 PARTITION BY RANGE (`id`)\n"+
+		"(PARTITION p0 VALUES LESS THAN (10) COMMENT = 'first' ENGINE = InnoDB,\n"+
+		" PARTITION `p1` VALUES LESS THAN MAXVALUE ENGINE = InnoDB) */")
+	c.Assert(mock.ExpectationsWereMet(), IsNil)
+
+	// the comment truncated or mangled in the DDL
+	expectComments("line1\nline2 \\ 100\\% and more")
+	_, err = verifyComments(db, "test", "t", commentedCreateTable)
+	c.Assert(err, ErrorMatches, "the comments of `test`.`t` don't round-trip: the comment of column `memo` is .* but .* in INFORMATION_SCHEMA")
+	c.Assert(mock.ExpectationsWereMet(), IsNil)
+}
+
+func (s *testCommentsSuite) TestQuoteComment(c *C) {
+	for _, comment := range []string{"", "it's", "a\\b", "line1\nline2\r\n", "\x00\x1a", "\"quoted\""} {
+		quoted := quoteComment(comment)
+		c.Assert(skipQuoted(quoted, 0), Equals, len(quoted))
+		c.Assert(unescapeSQLString(quoted[1:len(quoted)-1]), Equals, comment)
+	}
+}
+
+func (s *testCommentsSuite) TestValidate(c *C) {
+	conf := DefaultConfig()
+	conf.VerifyComments = true
+	c.Assert(conf.Validate(), IsNil)
+	conf.TargetDialect = TargetPostgres
+	c.Assert(conf.Validate(), ErrorMatches, ".*verify-comments is not supported when the DDL is translated.*")
+}
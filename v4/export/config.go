@@ -7,6 +7,9 @@ import (
 
 	"github.com/coreos/go-semver/semver"
 	"github.com/pingcap/dumpling/v4/log"
+	"github.com/pingcap/errors"
+	router "github.com/pingcap/tidb-tools/pkg/table-router"
+	"go.opentelemetry.io/otel/trace"
 	"go.uber.org/zap"
 )
 
@@ -18,10 +21,13 @@ type Config struct {
 	Password string
 	Threads  int
 
-	LogLevel  string
-	LogFile   string
-	LogFormat string
-	Logger    *zap.Logger
+	LogLevel          string
+	LogFile           string
+	LogFileMaxSize    int
+	LogFileMaxDays    int
+	LogFileMaxBackups int
+	LogFormat         string
+	Logger            *zap.Logger
 
 	FileSize      uint64
 	StatementSize uint64
@@ -39,11 +45,226 @@ type Config struct {
 	CsvNullValue  string
 	Sql           string
 
+	DisableForeignKeyChecks bool
+	DisableUniqueChecks     bool
+	NoAutocommit            bool
+	DisableKeys             bool
+	TransactionRows         uint64
+	MysqldumpCompatible     bool
+	Compact                 bool
+	OrderByForeignKey       bool
+	ShowProgress            bool
+	StateFile               string
+	ThrottleBytesPerSec     uint64
+	ThrottleRowsPerSec      uint64
+	MaxThreadsRunning       uint64
+	MaxMemory               uint64
+	SyncFiles               bool
+	CheckFreeSpace          bool
+	MinFree                 uint64
+	CaptureWarnings         bool
+	StrictWarnings          bool
+	LongQueryGuard          uint64
+	KillLongQueries         bool
+	MaxLockTime             uint64
+	DowngradeOnMaxLockTime  bool
+	StopReplicaSQLThread    bool
+	MaxReplicaLag           uint64
+	ReplicaLagWait          uint64
+	SourceDialect           string
+	PostgresDatabase        string
+	TargetDialect           string
+	BigQuerySchema          bool
+	HiveLocation            string
+	TargetDSN               string
+	LoadDataScripts         bool
+	KafkaBrokers            string
+	KafkaTopic              string
+	KafkaFormat             string
+	KafkaKey                string
+	KafkaSchemaRegistry     string
+	IncrementalColumn       string
+	IncrementalStateFile    string
+	Append                  bool
+	Retention               string
+	BeforeConsistencySQL    string
+	AfterConsistencySQL     string
+	AfterDumpCommand        string
+	HookFailurePolicy       string
+	NotifyURL               string
+	TracingEndpoint         string
+	FetchRows               uint64
+	ChunkTimeout            uint64
+	ServerOutfileDir        string
+	FilesPerChunk           int
+	WriterBufferSize        uint64
+	WriterQueueDepth        int
+	MaxConnections          int
+	ConnIdleTimeout         uint64
+	KeepaliveInterval       uint64
+	NetTimeout              uint64
+	WaitTimeout             uint64
+	EmptyTables             string
+	DumpSystemSchemas       bool
+	// OnlyObjects are the objects dumped without the tables and their data,
+	// which are ObjectViews, ObjectRoutines or ObjectTriggers.
+	OnlyObjects []string
+	// VerifyComments checks the comments of the tables, columns, indexes and
+	// partitions in their DDL are the same as INFORMATION_SCHEMA, and writes
+	// them with the escapes understood by all the MySQL compatible parsers.
+	VerifyComments bool
+	// TiDBReplicas dumps the placement policies and the TiFlash replicas of
+	// the tables of TiDB, so that they're restored with the schemas.
+	TiDBReplicas bool
+	// ExplicitCollations writes the collations of the databases, tables and
+	// columns in their DDL, even if they're the defaults of the source.
+	ExplicitCollations bool
+	// CoerceUTF8MB4 replaces the character sets in the DDL with utf8mb4 and
+	// the collations with the utf8mb4 ones supported by TiDB, which implies
+	// ExplicitCollations.
+	CoerceUTF8MB4 bool
+	// NoSequences skips the sequences of MariaDB and TiDB, whose schemas and
+	// next values are dumped by default with the schemas of the tables.
+	NoSequences bool
+	// RowFilter is the expression evaluated against the decoded rows of every
+	// table, the rows it isn't true for are dropped. It's for the filters
+	// which can't be pushed into the WHERE clause.
+	RowFilter string
+	// RowFilterFuncs are the functions callable in RowFilter besides the
+	// builtin ones, e.g. to decrypt a column before it's compared.
+	RowFilterFuncs map[string]RowFilterFunc
+	// ColumnRewrites rewrite the values of the columns in the output, after
+	// the rows are filtered.
+	ColumnRewrites []ColumnRewrite
+	// PseudonymizeSalt is the key of the pseudonyms of the column rewrites,
+	// a random one is generated for the dump if it's empty.
+	PseudonymizeSalt string
+	// Subset dumps only the rows referencing the dumped rows of the tables
+	// with a where, by following the foreign keys of the tables, so that
+	// the dumped tables are still referentially intact.
+	Subset bool
+	// ForeignKeys are followed by Subset besides the declared ones, e.g. the
+	// ones which are only enforced by the applications.
+	ForeignKeys []ForeignKey
+	// Checksums records the checksums of the rows of the chunks read from the
+	// source into checksumsFile, which are compared by `dumpling diff`.
+	Checksums bool
+	// RestorePlan writes the files of the dump into restorePlanFile, in the
+	// batches they can be restored in, e.g. by `dumpling load`.
+	RestorePlan bool
+	// DedupSchemas writes a single canonical schema file for the tables with
+	// the same CREATE statement except the names, such as the shards of a
+	// table, and maps the other tables to it in schemaDedupFile.
+	DedupSchemas bool
+	// BinlogStop tails the binlog from the position of the dump to it by
+	// mysqlbinlog, into the `{file}-binlog.sql` files replaying the changes
+	// since the dump. It's `file:pos`, or BinlogStopCurrent for the position
+	// when the data are dumped.
+	BinlogStop string
+	// MysqlbinlogPath is the mysqlbinlog command tailing the binlog.
+	MysqlbinlogPath string
+	// ReplicationMeta writes the position of the dump in the format of the
+	// incremental replication, ReplicationMetaDM or ReplicationMetaTiCDC.
+	ReplicationMeta string
+	// ReplicationAPI is the OpenAPI address of the DM master or the TiCDC
+	// server, which the position is registered by if it's set.
+	ReplicationAPI string
+	// ReplicationTask is the DM task or the TiCDC changefeed ID.
+	ReplicationTask string
+	// ReplicationSource is the source of the DM task the position is set to.
+	ReplicationSource string
+	// ReplicationSinkURI is the sink URI of the TiCDC changefeed.
+	ReplicationSinkURI string
+	// ResourceGroup binds the sessions of the dump to the resource group of
+	// TiDB, whose quota limits the load of the dump on the cluster.
+	ResourceGroup string
+	// LowPriority reads the tables of TiDB with SELECT LOW_PRIORITY, so that
+	// the dump doesn't starve the other workloads.
+	LowPriority bool
+	// TiDBReplicaRead is the tidb_replica_read of the sessions to TiDB, e.g.
+	// follower to read from the followers of the regions.
+	TiDBReplicaRead string
+	// StaleRead reads the tables of TiDB as of this many seconds before the
+	// dump by AS OF TIMESTAMP, which may be served by any replica of the
+	// regions, instead of reading the snapshot by tidb_snapshot.
+	StaleRead uint64
+	// SessionParams are the session variables set on every connection to the source.
+	SessionParams map[string]string
+	// TableConfigs overrides the options above for the matched tables.
+	TableConfigs []TableConfig
+	// RouteRules rename the databases and tables in the output, like the
+	// routes of DM and TiDB Lightning.
+	RouteRules []*router.TableRule
+	// Shards are the sources dumped into the output together, given by
+	// `[user[:password]@]host[:port]`, the omitted parts are taken from above.
+	Shards []string
+	// ReadReplicas are the replicas of the source, given like Shards, which
+	// the chunks of the tables are read from in turns with the source.
+	ReadReplicas []string
+
+	// Progress tracks the progress of dump, it's created by Dump if not set.
+	Progress *Progress
+	// Controller pauses, resumes or stops the dump, and reports its Progress,
+	// it's created by Dump if not set.
+	Controller *JobController
+	// Hooks observes the events of dump if set.
+	Hooks Hooks
+	// TracerProvider receives the spans of dump if set and TracingEndpoint is empty.
+	TracerProvider trace.TracerProvider
+	// ExternalStorage is where the files are written to, it's a LocalStorage of OutputDirPath if not set.
+	ExternalStorage ExternalStorage
+
+	// warnings records the warnings of chunks if CaptureWarnings is set.
+	warnings *warningRecorder
+	// writeFailure aborts the dump at the first write error of the files.
+	writeFailure *writeFailure
+	// incremental records the watermarks of the tables if IncrementalColumn is set.
+	incremental *incrementalState
+	// manifest records the tables dumped completely if Append is set.
+	manifest *dumpManifest
+	// downgrades records the tables dumped by a full scan instead of chunks.
+	downgrades *chunkDowngrades
+	// session keeps the session variables of the connections to the source.
+	session *sessionConnector
+	// replicas are the pools of ReadReplicas, opened after the consistency is set up.
+	replicas *replicaPools
+	// shard is set if the config dumps one of Shards.
+	shard *shardDump
+	// checksums records the checksums of the chunks if Checksums is set.
+	checksums *checksumRecorder
+	// restorePlan records the closed files if RestorePlan is set.
+	restorePlan *restorePlanRecorder
+	// schemaDedup records the tables without their own schema files if DedupSchemas is set.
+	schemaDedup *schemaDedupRecorder
+	// subset maps the tables referencing the filtered tables to their where if Subset is set.
+	subset map[string]string
+	// staleReadSnapshot is the TSO the tables are read as of if StaleRead is set.
+	staleReadSnapshot string
+
 	BlackWhiteList  BWListConf
 	Rows            uint64
 	Where           string
+	ChunkColumn     string
 	FileType        string
 	EscapeBackslash bool
+	// QuoteBigIntegers quotes the integers out of the range of BIGINT in the
+	// SQL and CSV files.
+	QuoteBigIntegers bool
+	// ZeroDates is how the zero dates and the other invalid values of the
+	// DATE, DATETIME and TIMESTAMP columns are written, one of ZeroDatesKeep,
+	// ZeroDatesNull and ZeroDatesError.
+	ZeroDates string
+	// CanonicalJSON writes the values of the JSON columns with the sorted keys
+	// and without the insignificant whitespace, and fails the dump at the
+	// malformed ones.
+	CanonicalJSON bool
+	// StatusToken is the bearer token required by the status API and the
+	// daemon API, except for the probes, if it's set.
+	StatusToken string
+	// DaemonAllowSql lets the jobs submitted to the daemon API override Sql
+	// and Where, otherwise they're refused, since the SQL is run with the
+	// credentials of the source.
+	DaemonAllowSql bool
 }
 
 func DefaultConfig() *Config {
@@ -55,7 +276,7 @@ func DefaultConfig() *Config {
 		Password:      "",
 		Threads:       4,
 		Logger:        nil,
-		StatusAddr:    ":8281",
+		StatusAddr:    "127.0.0.1:8281",
 		FileSize:      UnspecifiedSize,
 		StatementSize: UnspecifiedSize,
 		OutputDirPath: ".",
@@ -73,6 +294,26 @@ func DefaultConfig() *Config {
 		NoData:        false,
 		CsvNullValue:  "\\N",
 		Sql:           "",
+
+		TransactionRows: UnspecifiedSize,
+		CheckFreeSpace:  true,
+		SourceDialect:   DialectMySQL,
+		KafkaTopic:      "{db}.{table}",
+		KafkaFormat:     KafkaFormatJSON,
+		KafkaKey:        KafkaKeyPK,
+		ZeroDates:       ZeroDatesKeep,
+
+		HookFailurePolicy: HookFailureAbort,
+		FetchRows:         UnspecifiedSize,
+		ChunkTimeout:      UnspecifiedSize,
+		StaleRead:         UnspecifiedSize,
+		FilesPerChunk:     1,
+		WriterBufferSize:  UnspecifiedSize,
+		WriterQueueDepth:  writerPipeDepth,
+		EmptyTables:       EmptyTablesNone,
+		MysqlbinlogPath:   "mysqlbinlog",
+		NetTimeout:        defaultNetTimeout,
+		WaitTimeout:       defaultWaitTimeout,
 	}
 }
 
@@ -83,8 +324,284 @@ func (conf *Config) getDSN(db string) string {
 const (
 	UnspecifiedSize    = 0
 	defaultDumpThreads = 128
+
+	// orderByAllColumnsRowsLimit is the max estimated rows of a table without
+	// primary key that would still be sorted by all of its columns.
+	orderByAllColumnsRowsLimit = 100000
 )
 
+// TableConfig overrides the options of dumping a table, the zero values
+// mean not overridden.
+type TableConfig struct {
+	Database string
+	Table    string
+	Where    string
+	Rows     uint64
+	// ChunkColumn is the integer column splitting the table into chunks
+	// instead of the primary key or unique key.
+	ChunkColumn string
+	// RowFilter overrides the row filter of the table.
+	RowFilter string
+	// Output is the directory of the files of the table instead of OutputDirPath.
+	Output string
+	// ExternalStorage is where the files of the table are written to, it
+	// overrides Output.
+	ExternalStorage ExternalStorage
+}
+
+// forTable returns conf with the options overridden by the TableConfig and
+// the subset of the table, or conf itself if there isn't any.
+func (conf *Config) forTable(dbName, tableName string) *Config {
+	tableConf := conf
+	for _, tc := range conf.TableConfigs {
+		if tc.Database != dbName || tc.Table != tableName {
+			continue
+		}
+		copied := *conf
+		tableConf = &copied
+		if tc.Where != "" {
+			tableConf.Where = tc.Where
+		}
+		if tc.Rows != UnspecifiedSize {
+			tableConf.Rows = tc.Rows
+		}
+		if tc.ChunkColumn != "" {
+			tableConf.ChunkColumn = tc.ChunkColumn
+		}
+		if tc.RowFilter != "" {
+			tableConf.RowFilter = tc.RowFilter
+		}
+		break
+	}
+	if where, ok := conf.subset[qualifiedTableName(dbName, tableName)]; ok {
+		if tableConf == conf {
+			copied := *conf
+			tableConf = &copied
+		}
+		tableConf.Where = where
+	}
+	return tableConf
+}
+
+// hasRows returns whether any table is split by rows.
+func (conf *Config) hasRows() bool {
+	if conf.Rows != UnspecifiedSize {
+		return true
+	}
+	for _, tc := range conf.TableConfigs {
+		if tc.Rows != UnspecifiedSize {
+			return true
+		}
+	}
+	return false
+}
+
+// hasTableOutputs returns whether any table has its own output.
+func (conf *Config) hasTableOutputs() bool {
+	for _, tc := range conf.TableConfigs {
+		if tc.Output != "" || tc.ExternalStorage != nil {
+			return true
+		}
+	}
+	return false
+}
+
+// Validate checks the conflicts between the options which can be found before
+// connecting to the server, all of them are reported in the returned error.
+func (conf *Config) Validate() error {
+	var conflicts []string
+	if conf.Threads <= 0 {
+		conflicts = append(conflicts, fmt.Sprintf("threads should be positive, got %d", conf.Threads))
+	}
+	switch conf.Consistency {
+	case "auto", "flush", "lock", "snapshot", "none":
+	default:
+		conflicts = append(conflicts, fmt.Sprintf("invalid consistency option %s", conf.Consistency))
+	}
+	if conf.Snapshot != "" && conf.Consistency != "auto" && conf.Consistency != "snapshot" {
+		conflicts = append(conflicts, fmt.Sprintf("snapshot is only valid with consistency snapshot, got %s", conf.Consistency))
+	}
+	if strings.Contains(conf.ResourceGroup, "`") {
+		conflicts = append(conflicts, fmt.Sprintf("invalid resource-group %s", conf.ResourceGroup))
+	}
+	switch strings.ToLower(conf.FileType) {
+	case "sql":
+		if conf.Sql != "" {
+			conflicts = append(conflicts, "unsupported dump data in sql format when specific sql")
+		}
+	case "csv", "tsv":
+	case "sqlite":
+		if conf.Sql != "" {
+			conflicts = append(conflicts, "unsupported dump data in sqlite format when specific sql")
+		}
+		if conf.TargetDialect != "" && conf.TargetDialect != TargetSQLite {
+			conflicts = append(conflicts, "filetype sqlite is only supported with the sqlite target dialect")
+		}
+	case "kafka":
+		if conf.Sql != "" {
+			conflicts = append(conflicts, "unsupported dump data in kafka format when specific sql")
+		}
+		if conf.KafkaBrokers == "" {
+			conflicts = append(conflicts, "kafka-brokers is required by filetype kafka")
+		}
+		switch conf.KafkaFormat {
+		case KafkaFormatJSON:
+		case KafkaFormatAvro:
+			if conf.KafkaSchemaRegistry == "" {
+				conflicts = append(conflicts, "kafka-schema-registry is required by kafka format avro")
+			}
+		default:
+			conflicts = append(conflicts, fmt.Sprintf("invalid kafka format %s", conf.KafkaFormat))
+		}
+		if conf.KafkaKey != KafkaKeyPK && conf.KafkaKey != KafkaKeyNone {
+			conflicts = append(conflicts, fmt.Sprintf("invalid kafka key %s", conf.KafkaKey))
+		}
+	default:
+		conflicts = append(conflicts, fmt.Sprintf("invalid file type %s", conf.FileType))
+	}
+	if conf.BigQuerySchema && strings.ToLower(conf.FileType) != "csv" {
+		conflicts = append(conflicts, "bigquery-schema is only supported with filetype csv")
+	}
+	if conf.HiveLocation != "" && (strings.ToLower(conf.FileType) != "csv" || conf.Sql != "") {
+		conflicts = append(conflicts, "hive-location is only supported with filetype csv of tables")
+	}
+	if conf.LoadDataScripts && strings.ToLower(conf.FileType) != "csv" {
+		conflicts = append(conflicts, "load-data-scripts is only supported with filetype csv")
+	}
+	if conf.TargetDSN != "" && (strings.ToLower(conf.FileType) != "sql" || !conf.writesMySQLSettings()) {
+		conflicts = append(conflicts, "target-dsn is only supported with filetype sql of the mysql and tidb target dialects")
+	}
+	if conf.IncrementalColumn != "" {
+		switch strings.ToLower(conf.FileType) {
+		case "sql", "csv", "tsv":
+			if conf.Sql != "" || conf.TargetDSN != "" {
+				conflicts = append(conflicts, "incremental-column is not supported with sql or target-dsn")
+			}
+		default:
+			conflicts = append(conflicts, "incremental-column is only supported with filetype sql, csv and tsv")
+		}
+	}
+	if conf.Append && (conf.Sql != "" || strings.ToLower(conf.FileType) == "sqlite") {
+		conflicts = append(conflicts, "append is not supported with sql or filetype sqlite")
+	}
+	if conf.Retention != "" {
+		if _, err := parseRetention(conf.Retention); err != nil {
+			conflicts = append(conflicts, err.Error())
+		}
+		// the LocalStorage of OutputDirPath is used if it isn't set
+		if conf.ExternalStorage != nil {
+			if _, ok := baseStorage(conf.ExternalStorage).(DumpPruner); !ok {
+				conflicts = append(conflicts, "retention is only supported by the output storages implementing DumpPruner")
+			}
+		}
+	}
+	if err := validateHookFailurePolicy(conf.HookFailurePolicy); err != nil {
+		conflicts = append(conflicts, err.Error())
+	}
+	if conf.TracingEndpoint != "" {
+		if _, err := parseTracingEndpoint(conf.TracingEndpoint); err != nil {
+			conflicts = append(conflicts, err.Error())
+		}
+	}
+	if conf.WriterQueueDepth <= 0 {
+		conflicts = append(conflicts, fmt.Sprintf("writer-queue-depth should be positive, got %d", conf.WriterQueueDepth))
+	}
+	if conf.MaxConnections < 0 || conf.MaxConnections == 1 {
+		// the consistency may hold a connection during the whole dump
+		conflicts = append(conflicts, fmt.Sprintf("max-connections should be 0 for unlimited or at least 2, got %d", conf.MaxConnections))
+	}
+	if conf.FilesPerChunk <= 0 {
+		conflicts = append(conflicts, fmt.Sprintf("files-per-chunk should be positive, got %d", conf.FilesPerChunk))
+	} else if conf.FilesPerChunk > 1 {
+		switch strings.ToLower(conf.FileType) {
+		case "sql", "csv", "tsv":
+		default:
+			conflicts = append(conflicts, "files-per-chunk is only supported with filetype sql, csv and tsv")
+		}
+		// the next file of a part would be named as the first file of the next part,
+		// filesize is disabled by rows
+		if conf.FileSize != UnspecifiedSize && conf.Rows == UnspecifiedSize {
+			conflicts = append(conflicts, "files-per-chunk is not supported with filesize")
+		}
+	}
+	if conf.ServerOutfileDir != "" {
+		conflicts = append(conflicts, serverOutfileConflicts(conf)...)
+	}
+	conflicts = append(conflicts, emptyTablesConflicts(conf)...)
+	if len(conf.OnlyObjects) > 0 {
+		conflicts = append(conflicts, onlyObjectsConflicts(conf)...)
+	}
+	if conf.TiDBReplicas {
+		conflicts = append(conflicts, tidbReplicasConflicts(conf)...)
+	}
+	if (conf.ExplicitCollations || conf.CoerceUTF8MB4) && conf.translatesDDL() {
+		conflicts = append(conflicts, "explicit-collations and coerce-utf8mb4 are not supported when the DDL is translated to another target dialect")
+	}
+	conflicts = append(conflicts, rowFilterConflicts(conf)...)
+	conflicts = append(conflicts, columnRewritesConflicts(conf)...)
+	conflicts = append(conflicts, subsetConflicts(conf)...)
+	if conf.Checksums && conf.ServerOutfileDir != "" {
+		conflicts = append(conflicts, "checksums are not supported with server-outfile-dir")
+	}
+	conflicts = append(conflicts, restorePlanConflicts(conf)...)
+	conflicts = append(conflicts, schemaDedupConflicts(conf)...)
+	conflicts = append(conflicts, binlogTailConflicts(conf)...)
+	conflicts = append(conflicts, replicationConflicts(conf)...)
+	conflicts = append(conflicts, staleReadConflicts(conf)...)
+	conflicts = append(conflicts, zeroDatesConflicts(conf)...)
+	conflicts = append(conflicts, canonicalJSONConflicts(conf)...)
+	if conf.VerifyComments && conf.translatesDDL() {
+		conflicts = append(conflicts, "verify-comments is not supported when the DDL is translated to another target dialect")
+	}
+	if len(conf.RouteRules) > 0 && (conf.BigQuerySchema || conf.HiveLocation != "" || conf.ServerOutfileDir != "") {
+		conflicts = append(conflicts, "route rules are not supported with bigquery-schema, hive-location or server-outfile-dir")
+	}
+	// the merged chunks are numbered one by one, so the next file of a chunk
+	// would be named as the first file of the next chunk, filesize is disabled by rows
+	if (len(conf.RouteRules) > 0 || len(conf.Shards) > 0) && conf.FileSize != UnspecifiedSize && conf.Rows == UnspecifiedSize {
+		conflicts = append(conflicts, "route rules and shards are not supported with filesize")
+	}
+	if len(conf.Shards) > 0 {
+		conflicts = append(conflicts, shardsConflicts(conf)...)
+	}
+	if len(conf.ReadReplicas) > 0 {
+		conflicts = append(conflicts, readReplicasConflicts(conf)...)
+	}
+	if conf.hasTableOutputs() {
+		switch strings.ToLower(conf.FileType) {
+		case "sql", "csv", "tsv":
+			if conf.TargetDSN != "" || conf.ServerOutfileDir != "" {
+				conflicts = append(conflicts, "the outputs of tables are not supported with target-dsn or server-outfile-dir")
+			}
+		default:
+			conflicts = append(conflicts, "the outputs of tables are only supported with filetype sql, csv and tsv")
+		}
+	}
+	if conf.NoSchemas && conf.NoData {
+		conflicts = append(conflicts, "nothing to dump with both no-schemas and no-data")
+	}
+	if err := validateDialect(conf.SourceDialect); err != nil {
+		conflicts = append(conflicts, err.Error())
+	}
+	if conf.SourceDialect == DialectPostgres && conf.hasRows() {
+		conflicts = append(conflicts, "rows is not supported by the postgres source dialect")
+	}
+	if err := validateTargetDialect(conf.TargetDialect); err != nil {
+		conflicts = append(conflicts, err.Error())
+	} else if !conf.writesMySQLSettings() {
+		if conf.NoAutocommit || conf.DisableKeys || conf.MysqldumpCompatible {
+			conflicts = append(conflicts, fmt.Sprintf("no-autocommit, disable-keys and mysqldump-compatible are not supported by the %s target dialect", conf.targetDialect()))
+		}
+		if conf.targetDialect() == TargetClickHouse && conf.TransactionRows != UnspecifiedSize {
+			conflicts = append(conflicts, "transaction-rows is not supported by the clickhouse target dialect")
+		}
+	}
+	if len(conflicts) > 0 {
+		return errors.New(strings.Join(conflicts, "; "))
+	}
+	return nil
+}
+
 type ServerInfo struct {
 	ServerType    ServerType
 	ServerVersion *semver.Version
@@ -104,6 +621,8 @@ func ParseServerInfo(src string) ServerInfo {
 	serverInfo := ServerInfo{}
 	if strings.Contains(lowerCase, "tidb") {
 		serverInfo.ServerType = ServerTypeTiDB
+	} else if strings.HasPrefix(lowerCase, "postgresql") {
+		serverInfo.ServerType = ServerTypePostgreSQL
 	} else if strings.Contains(lowerCase, "mariadb") {
 		serverInfo.ServerType = ServerTypeMariaDB
 	} else if versionRegex.MatchString(lowerCase) {
@@ -117,7 +636,9 @@ func ParseServerInfo(src string) ServerInfo {
 
 	var versionStr string
 	if serverInfo.ServerType == ServerTypeTiDB {
-		versionStr = tidbVersionRegex.FindString(src)[1:]
+		versionStr = strings.TrimPrefix(tidbVersionRegex.FindString(src), "v")
+	} else if serverInfo.ServerType == ServerTypePostgreSQL {
+		versionStr = parsePostgresVersion(src)
 	} else {
 		versionStr = versionRegex.FindString(src)
 	}
@@ -149,6 +670,7 @@ const (
 	ServerTypeMySQL
 	ServerTypeMariaDB
 	ServerTypeTiDB
+	ServerTypePostgreSQL
 
 	ServerTypeAll
 )
@@ -161,4 +683,5 @@ func init() {
 	serverTypeString[ServerTypeMySQL] = "MySQL"
 	serverTypeString[ServerTypeMariaDB] = "MariaDB"
 	serverTypeString[ServerTypeTiDB] = "TiDB"
+	serverTypeString[ServerTypePostgreSQL] = "PostgreSQL"
 }
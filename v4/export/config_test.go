@@ -0,0 +1,73 @@
+package export
+
+import (
+	. "github.com/pingcap/check"
+)
+
+var _ = Suite(&testConfigSuite{})
+
+type testConfigSuite struct{}
+
+func (s *testConfigSuite) TestValidate(c *C) {
+	conf := DefaultConfig()
+	c.Assert(conf.Validate(), IsNil)
+
+	conf.Snapshot = "417773951312461825"
+	c.Assert(conf.Validate(), IsNil)
+	conf.Consistency = "snapshot"
+	c.Assert(conf.Validate(), IsNil)
+	conf.Consistency = "flush"
+	c.Assert(conf.Validate(), ErrorMatches, "snapshot is only valid with consistency snapshot, got flush")
+
+	conf = DefaultConfig()
+	conf.Threads = 0
+	conf.Consistency = "bad"
+	conf.FileType = "xml"
+	conf.NoSchemas = true
+	conf.NoData = true
+	c.Assert(conf.Validate(), ErrorMatches, "threads should be positive, got 0; "+
+		"invalid consistency option bad; "+
+		"invalid file type xml; "+
+		"nothing to dump with both no-schemas and no-data")
+
+	conf = DefaultConfig()
+	conf.Sql = "select * from t"
+	c.Assert(conf.Validate(), ErrorMatches, "unsupported dump data in sql format when specific sql")
+	conf.FileType = "CSV"
+	c.Assert(conf.Validate(), IsNil)
+}
+
+func (s *testConfigSuite) TestValidateServer(c *C) {
+	conf := DefaultConfig()
+	conf.ServerInfo = ServerInfo{ServerType: ServerTypeMySQL}
+	c.Assert(conf.validateServer(), IsNil)
+	conf.Snapshot = "417773951312461825"
+	c.Assert(conf.validateServer(), ErrorMatches, "snapshot is only supported by TiDB, got MySQL")
+	conf.Consistency = "auto"
+	conf.ServerInfo = ServerInfo{ServerType: ServerTypeTiDB}
+	c.Assert(conf.validateServer(), IsNil)
+	c.Assert(conf.Consistency, Equals, "snapshot")
+
+	conf = DefaultConfig()
+	conf.Consistency = "flush"
+	conf.ServerInfo = ServerInfo{ServerType: ServerTypeTiDB}
+	c.Assert(conf.validateServer(), ErrorMatches, "flush consistency is not supported by TiDB")
+	conf.Consistency = "snapshot"
+	conf.ServerInfo = ServerInfo{ServerType: ServerTypeMariaDB}
+	c.Assert(conf.validateServer(), ErrorMatches, "snapshot consistency is not supported by MariaDB")
+
+	// unsupported replica lag check is skipped instead of failing
+	conf = DefaultConfig()
+	conf.MaxReplicaLag = 10
+	conf.ServerInfo = ServerInfo{ServerType: ServerTypeTiDB}
+	c.Assert(conf.validateServer(), IsNil)
+	c.Assert(conf.MaxReplicaLag, Equals, uint64(UnspecifiedSize))
+
+	conf = DefaultConfig()
+	conf.StopReplicaSQLThread = true
+	conf.ServerInfo = ServerInfo{ServerType: ServerTypeMariaDB}
+	c.Assert(conf.validateServer(), IsNil)
+	conf.Consistency = "auto"
+	conf.ServerInfo = ServerInfo{ServerType: ServerTypeTiDB}
+	c.Assert(conf.validateServer(), ErrorMatches, "stopping replica SQL thread is only supported by MySQL and MariaDB, got TiDB")
+}
@@ -0,0 +1,266 @@
+package export
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pingcap/errors"
+	"go.uber.org/zap"
+
+	"github.com/pingcap/dumpling/v4/log"
+)
+
+// sessionConnector connects to the server, and prepares the session variables
+// on every new connection. The connections reconnected by the pool, e.g. after
+// a proxy like ProxySQL closes them, have the same session as the others.
+type sessionConnector struct {
+	driver.Connector
+	// maxIdle is the max idle connections of the pool
+	maxIdle int
+
+	mu        sync.Mutex
+	variables []sessionVariable
+	// statements are run after the variables are set
+	statements []string
+}
+
+type sessionVariable struct {
+	name  string
+	value string
+	// str is set if the variable is known to be a string
+	str bool
+}
+
+// String returns the SET statement of the variable. The numbers are unquoted
+// unless the variable is a string, since MySQL rejects the strings for the
+// integer variables.
+func (v sessionVariable) String() string {
+	if _, err := strconv.ParseFloat(v.value, 64); err == nil && !v.str {
+		return fmt.Sprintf("SET SESSION %s = %s", v.name, v.value)
+	}
+	return fmt.Sprintf("SET SESSION %s = '%s'", v.name, strings.ReplaceAll(v.value, "'", "''"))
+}
+
+// dsnConnector is the connector of the drivers without driver.DriverContext.
+type dsnConnector struct {
+	driver driver.Driver
+	dsn    string
+}
+
+func (c dsnConnector) Connect(context.Context) (driver.Conn, error) {
+	return c.driver.Open(c.dsn)
+}
+
+func (c dsnConnector) Driver() driver.Driver {
+	return c.driver
+}
+
+// newSessionConnector returns the connector of the driver connecting to dsn,
+// which sets the session variables params, sorted by their names.
+func newSessionConnector(driverName, dsn string, params map[string]string, maxIdle int) (*sessionConnector, error) {
+	// the driver is only registered by name
+	db, err := sql.Open(driverName, dsn)
+	if err != nil {
+		return nil, err
+	}
+	drv := db.Driver()
+	if err = db.Close(); err != nil {
+		return nil, err
+	}
+	var connector driver.Connector = dsnConnector{driver: drv, dsn: dsn}
+	if dc, ok := drv.(driver.DriverContext); ok {
+		if connector, err = dc.OpenConnector(dsn); err != nil {
+			return nil, err
+		}
+	}
+	c := &sessionConnector{Connector: connector, maxIdle: maxIdle}
+	names := make([]string, 0, len(params))
+	for name := range params {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		c.variables = append(c.variables, sessionVariable{name: name, value: params[name]})
+	}
+	return c, nil
+}
+
+func (c *sessionConnector) Connect(ctx context.Context) (driver.Conn, error) {
+	conn, err := c.Connector.Connect(ctx)
+	if err != nil {
+		return nil, err
+	}
+	c.mu.Lock()
+	variables := append([]sessionVariable{}, c.variables...)
+	statements := append([]string{}, c.statements...)
+	c.mu.Unlock()
+	if len(variables) == 0 && len(statements) == 0 {
+		return conn, nil
+	}
+	execer, ok := conn.(driver.ExecerContext)
+	if !ok {
+		conn.Close()
+		return nil, errors.New("the driver doesn't support setting the session variables")
+	}
+	for _, v := range variables {
+		if _, err = execer.ExecContext(ctx, v.String(), nil); err != nil {
+			conn.Close()
+			return nil, errors.WithMessage(err, v.String())
+		}
+	}
+	for _, stmt := range statements {
+		if _, err = execer.ExecContext(ctx, stmt, nil); err != nil {
+			conn.Close()
+			return nil, errors.WithMessage(err, stmt)
+		}
+	}
+	return conn, nil
+}
+
+// keep sets the string session variable on the new connections of db too,
+// after it's set on a connection of db. The idle connections are closed, so that all the
+// connections used later have it.
+func (c *sessionConnector) keep(db *sql.DB, name, value string) {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	c.variables = append(c.variables, sessionVariable{name: name, value: value, str: true})
+	c.mu.Unlock()
+	db.SetMaxIdleConns(0)
+	db.SetMaxIdleConns(c.maxIdle)
+}
+
+// keepStatements runs the statements on the new connections of db. The idle
+// connections are closed like keep, so that the statements are run on all
+// the connections used later.
+func (c *sessionConnector) keepStatements(db *sql.DB, statements []string) {
+	if c == nil || len(statements) == 0 {
+		return
+	}
+	c.mu.Lock()
+	c.statements = append(c.statements, statements...)
+	c.mu.Unlock()
+	db.SetMaxIdleConns(0)
+	db.SetMaxIdleConns(c.maxIdle)
+}
+
+const (
+	defaultNetTimeout        = 3600
+	defaultWaitTimeout       = 28800
+	defaultKeepaliveInterval = 60
+)
+
+// sessionParams returns SessionParams with the timeouts of the MySQL
+// sessions, unless they're set by SessionParams. A chunk may be written
+// slower than the server sends it beyond the default net_write_timeout, and
+// the connections holding the locks are idle during the dump.
+func (conf *Config) sessionParams() map[string]string {
+	if conf.dialect().DriverName() != "mysql" {
+		return conf.SessionParams
+	}
+	params := make(map[string]string, len(conf.SessionParams)+3)
+	if conf.NetTimeout > 0 {
+		params["net_read_timeout"] = strconv.FormatUint(conf.NetTimeout, 10)
+		params["net_write_timeout"] = strconv.FormatUint(conf.NetTimeout, 10)
+	}
+	if conf.WaitTimeout > 0 {
+		params["wait_timeout"] = strconv.FormatUint(conf.WaitTimeout, 10)
+	}
+	for name, value := range conf.SessionParams {
+		params[name] = value
+	}
+	return params
+}
+
+// openConnPool opens the pool connecting to dsn by the driver of conf, with
+// the limits of conf. It returns the connector too, which keeps the session
+// variables of the pool.
+func openConnPool(conf *Config, dsn string) (*sql.DB, *sessionConnector, error) {
+	// the workers and the consistency session are kept by default
+	maxIdle := conf.Threads + 1
+	if conf.MaxConnections > 0 {
+		maxIdle = conf.MaxConnections
+	}
+	connector, err := newSessionConnector(conf.dialect().DriverName(), dsn, conf.sessionParams(), maxIdle)
+	if err != nil {
+		return nil, nil, err
+	}
+	// the pools opened after the source is detected, e.g. of the replicas
+	connector.statements = conf.sessionStatements()
+	db := sql.OpenDB(connector)
+	db.SetMaxOpenConns(conf.MaxConnections)
+	db.SetMaxIdleConns(maxIdle)
+	return db, connector, nil
+}
+
+// setConnIdleTimeout closes the connections of db idle longer than timeout
+// seconds, or never if timeout is UnspecifiedSize.
+func setConnIdleTimeout(db *sql.DB, timeout uint64) {
+	if timeout != UnspecifiedSize {
+		db.SetConnMaxIdleTime(time.Duration(timeout) * time.Second)
+	}
+}
+
+// keepaliveInterval returns the interval of pinging the source, 0 if it's
+// disabled. The connections holding the locks are pinged by default.
+func (conf *Config) keepaliveInterval() time.Duration {
+	switch {
+	case conf.KeepaliveInterval != UnspecifiedSize:
+		return time.Duration(conf.KeepaliveInterval) * time.Second
+	case holdsLocks(conf.Consistency):
+		return defaultKeepaliveInterval * time.Second
+	default:
+		return 0
+	}
+}
+
+// runKeepalive pings the idle connections of db every interval until ctx is
+// done, so that the proxies and the servers don't close them for being idle.
+func runKeepalive(ctx context.Context, db *sql.DB, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			pingIdleConns(ctx, db)
+		}
+	}
+}
+
+// pingIdleConns pings all the idle connections of db, including the one
+// holding the locks of the consistency, which a single ping of db may miss.
+func pingIdleConns(ctx context.Context, db *sql.DB) {
+	idle := db.Stats().Idle
+	if idle == 0 {
+		idle = 1
+	}
+	conns := make([]*sql.Conn, 0, idle)
+	defer func() {
+		for _, conn := range conns {
+			conn.Close()
+		}
+	}()
+	for i := 0; i < idle; i++ {
+		conn, err := db.Conn(ctx)
+		if err == nil {
+			conns = append(conns, conn)
+			err = conn.PingContext(ctx)
+		}
+		if err != nil {
+			if ctx.Err() == nil {
+				log.Warn("keepalive ping failed", zap.Error(err))
+			}
+			return
+		}
+	}
+}
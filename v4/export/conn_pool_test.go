@@ -0,0 +1,188 @@
+package export
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	. "github.com/pingcap/check"
+)
+
+var _ = Suite(&testConnPoolSuite{})
+
+type testConnPoolSuite struct{}
+
+// recordingConnector connects to recordingConns, which record the statements
+// executed on them.
+type recordingConnector struct {
+	mu    sync.Mutex
+	conns []*recordingConn
+}
+
+func (c *recordingConnector) Connect(context.Context) (driver.Conn, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	conn := &recordingConn{}
+	c.conns = append(c.conns, conn)
+	return conn, nil
+}
+
+func (c *recordingConnector) Driver() driver.Driver {
+	return nil
+}
+
+type recordingConn struct {
+	statements []string
+}
+
+func (c *recordingConn) ExecContext(_ context.Context, query string, _ []driver.NamedValue) (driver.Result, error) {
+	c.statements = append(c.statements, query)
+	return driver.RowsAffected(0), nil
+}
+
+func (c *recordingConn) Prepare(string) (driver.Stmt, error) {
+	return nil, driver.ErrSkip
+}
+
+func (c *recordingConn) Close() error {
+	return nil
+}
+
+func (c *recordingConn) Begin() (driver.Tx, error) {
+	return nil, driver.ErrSkip
+}
+
+func (s *testConnPoolSuite) TestSessionVariable(c *C) {
+	c.Assert(sessionVariable{name: "max_execution_time", value: "0"}.String(), Equals, "SET SESSION max_execution_time = 0")
+	c.Assert(sessionVariable{name: "sql_mode", value: ""}.String(), Equals, "SET SESSION sql_mode = ''")
+	c.Assert(sessionVariable{name: "time_zone", value: "'+08:00"}.String(), Equals, "SET SESSION time_zone = '''+08:00'")
+}
+
+func (s *testConnPoolSuite) TestSessionConnector(c *C) {
+	recorder := &recordingConnector{}
+	connector := &sessionConnector{Connector: recorder, maxIdle: 2, variables: []sessionVariable{
+		{name: "time_zone", value: "+08:00"}, {name: "max_execution_time", value: "0"}}}
+	db := sql.OpenDB(connector)
+	defer db.Close()
+	ctx := context.Background()
+
+	_, err := db.ExecContext(ctx, "SELECT 1")
+	c.Assert(err, IsNil)
+	c.Assert(recorder.conns, HasLen, 1)
+	c.Assert(recorder.conns[0].statements, DeepEquals, []string{
+		"SET SESSION time_zone = '+08:00'", "SET SESSION max_execution_time = 0", "SELECT 1"})
+
+	// the idle connections are closed after keeping a variable
+	connector.keep(db, "tidb_snapshot", "417773951312461825")
+	_, err = db.ExecContext(ctx, "SELECT 2")
+	c.Assert(err, IsNil)
+	c.Assert(recorder.conns, HasLen, 2)
+	c.Assert(recorder.conns[1].statements, DeepEquals, []string{
+		"SET SESSION time_zone = '+08:00'", "SET SESSION max_execution_time = 0",
+		"SET SESSION tidb_snapshot = '417773951312461825'", "SELECT 2"})
+
+	var nilConnector *sessionConnector
+	nilConnector.keep(db, "tidb_snapshot", "0")
+}
+
+func (s *testConnPoolSuite) TestOpenConnPool(c *C) {
+	conf := DefaultConfig()
+	conf.MaxConnections = 16
+	conf.SessionParams = map[string]string{"sql_mode": "", "max_execution_time": "0"}
+	db, connector, err := openConnPool(conf, conf.dialect().DSN(conf))
+	c.Assert(err, IsNil)
+	defer db.Close()
+	c.Assert(db.Stats().MaxOpenConnections, Equals, 16)
+	c.Assert(connector.maxIdle, Equals, 16)
+	// sorted by the names
+	c.Assert(connector.variables, DeepEquals, []sessionVariable{
+		{name: "max_execution_time", value: "0"}, {name: "net_read_timeout", value: "3600"},
+		{name: "net_write_timeout", value: "3600"}, {name: "sql_mode", value: ""}, {name: "wait_timeout", value: "28800"}})
+}
+
+func (s *testConnPoolSuite) TestSessionParams(c *C) {
+	conf := DefaultConfig()
+	conf.NetTimeout = 0
+	conf.SessionParams = map[string]string{"wait_timeout": "600"}
+	c.Assert(conf.sessionParams(), DeepEquals, map[string]string{"wait_timeout": "600"})
+	conf.WaitTimeout = 0
+	conf.SessionParams = nil
+	c.Assert(conf.sessionParams(), HasLen, 0)
+
+	// the timeouts are only set on MySQL
+	conf = DefaultConfig()
+	conf.SourceDialect = DialectPostgres
+	c.Assert(conf.sessionParams(), HasLen, 0)
+}
+
+func (s *testConnPoolSuite) TestKeepaliveInterval(c *C) {
+	conf := DefaultConfig()
+	conf.Consistency = "snapshot"
+	c.Assert(conf.keepaliveInterval(), Equals, time.Duration(0))
+	conf.Consistency = "flush"
+	c.Assert(conf.keepaliveInterval(), Equals, time.Minute)
+	conf.KeepaliveInterval = 10
+	conf.Consistency = "none"
+	c.Assert(conf.keepaliveInterval(), Equals, 10*time.Second)
+}
+
+// pingingConn counts the pings on it.
+type pingingConn struct {
+	recordingConn
+	pings *int32
+}
+
+func (c *pingingConn) Ping(context.Context) error {
+	atomic.AddInt32(c.pings, 1)
+	return nil
+}
+
+type pingingConnector struct {
+	pings int32
+}
+
+func (c *pingingConnector) Connect(context.Context) (driver.Conn, error) {
+	return &pingingConn{pings: &c.pings}, nil
+}
+
+func (c *pingingConnector) Driver() driver.Driver {
+	return nil
+}
+
+func (s *testConnPoolSuite) TestPingIdleConns(c *C) {
+	connector := &pingingConnector{}
+	db := sql.OpenDB(connector)
+	defer db.Close()
+	ctx := context.Background()
+
+	// a connection is pinged if there aren't idle ones
+	pingIdleConns(ctx, db)
+	c.Assert(atomic.LoadInt32(&connector.pings), Equals, int32(1))
+
+	conns := make([]*sql.Conn, 3)
+	for i := range conns {
+		conn, err := db.Conn(ctx)
+		c.Assert(err, IsNil)
+		conns[i] = conn
+	}
+	for _, conn := range conns {
+		c.Assert(conn.Close(), IsNil)
+	}
+	c.Assert(db.Stats().Idle, Equals, 2)
+	pingIdleConns(ctx, db)
+	c.Assert(atomic.LoadInt32(&connector.pings), Equals, int32(3))
+	c.Assert(db.Stats().Idle, Equals, 2)
+}
+
+func (s *testConnPoolSuite) TestValidate(c *C) {
+	conf := DefaultConfig()
+	conf.MaxConnections = 2
+	c.Assert(conf.Validate(), IsNil)
+	for _, n := range []int{-1, 1} {
+		conf.MaxConnections = n
+		c.Assert(conf.Validate(), ErrorMatches, ".*max-connections should be 0 for unlimited or at least 2.*")
+	}
+}
@@ -1,9 +1,15 @@
 package export
 
 import (
+	"context"
 	"database/sql"
 	"errors"
 	"fmt"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/pingcap/dumpling/v4/log"
 )
 
 func NewConsistencyController(conf *Config, session *sql.DB) (ConsistencyController, error) {
@@ -11,8 +17,10 @@ func NewConsistencyController(conf *Config, session *sql.DB) (ConsistencyControl
 	switch conf.Consistency {
 	case "flush":
 		return &ConsistencyFlushTableWithReadLock{
-			serverType: conf.ServerInfo.ServerType,
-			db:         session,
+			serverType:      conf.ServerInfo.ServerType,
+			db:              session,
+			longQueryGuard:  time.Duration(conf.LongQueryGuard) * time.Second,
+			killLongQueries: conf.KillLongQueries,
 		}, nil
 	case "lock":
 		return &ConsistencyLockDumpingTables{
@@ -23,7 +31,9 @@ func NewConsistencyController(conf *Config, session *sql.DB) (ConsistencyControl
 		return &ConsistencySnapshot{
 			serverType: conf.ServerInfo.ServerType,
 			snapshot:   conf.Snapshot,
+			staleRead:  conf.StaleRead,
 			db:         session,
+			session:    conf.session,
 		}, nil
 	case "none":
 		return &ConsistencyNone{}, nil
@@ -33,13 +43,13 @@ func NewConsistencyController(conf *Config, session *sql.DB) (ConsistencyControl
 }
 
 type ConsistencyController interface {
-	Setup() error
+	Setup(ctx context.Context) error
 	TearDown() error
 }
 
 type ConsistencyNone struct{}
 
-func (c *ConsistencyNone) Setup() error {
+func (c *ConsistencyNone) Setup(_ context.Context) error {
 	return nil
 }
 
@@ -50,12 +60,21 @@ func (c *ConsistencyNone) TearDown() error {
 type ConsistencyFlushTableWithReadLock struct {
 	serverType ServerType
 	db         *sql.DB
+	// longQueryGuard is the duration of the queries to wait for or kill
+	// before FTWRL, which would stall FTWRL and all writes behind it.
+	longQueryGuard  time.Duration
+	killLongQueries bool
 }
 
-func (c *ConsistencyFlushTableWithReadLock) Setup() error {
+func (c *ConsistencyFlushTableWithReadLock) Setup(ctx context.Context) error {
 	if c.serverType == ServerTypeTiDB {
 		return withStack(errors.New("'flush table with read lock' cannot be used to ensure the consistency in TiDB"))
 	}
+	if c.longQueryGuard > 0 {
+		if err := guardLongQueries(ctx, c.db, c.longQueryGuard, c.killLongQueries, defaultLongQueryCheckInterval); err != nil {
+			return err
+		}
+	}
 	return FlushTableWithReadLock(c.db)
 }
 
@@ -72,7 +91,7 @@ type ConsistencyLockDumpingTables struct {
 	allTables DatabaseTables
 }
 
-func (c *ConsistencyLockDumpingTables) Setup() error {
+func (c *ConsistencyLockDumpingTables) Setup(_ context.Context) error {
 	for dbName, tables := range c.allTables {
 		for _, table := range tables {
 			err := LockTables(c.db, dbName, table.Name)
@@ -95,13 +114,18 @@ func (c *ConsistencyLockDumpingTables) TearDown() error {
 type ConsistencySnapshot struct {
 	serverType ServerType
 	snapshot   string
-	db         *sql.DB
+	// staleRead is the seconds the snapshot is before the current TSO, which
+	// is read by AS OF TIMESTAMP instead of tidb_snapshot if it's set
+	staleRead uint64
+	db        *sql.DB
+	// session sets the snapshot on the new connections of db too
+	session *sessionConnector
 }
 
 const showMasterStatusFieldNum = 5
 const snapshotFieldIndex = 1
 
-func (c *ConsistencySnapshot) Setup() error {
+func (c *ConsistencySnapshot) Setup(_ context.Context) error {
 	if c.serverType != ServerTypeTiDB {
 		return withStack(errors.New("snapshot consistency is not supported for this server"))
 	}
@@ -117,9 +141,20 @@ func (c *ConsistencySnapshot) Setup() error {
 		return err
 	}
 	if !hasTiKV {
+		// the snapshot isn't read without TiKV
+		c.snapshot = ""
 		return nil
 	}
-	return SetTiDBSnapshot(c.db, c.snapshot)
+	if c.staleRead != UnspecifiedSize {
+		// tidb_snapshot can't be set with AS OF TIMESTAMP
+		c.snapshot, err = staleSnapshot(c.snapshot, c.staleRead)
+		return err
+	}
+	if err = SetTiDBSnapshot(c.db, c.snapshot); err != nil {
+		return err
+	}
+	c.session.keep(c.db, "tidb_snapshot", c.snapshot)
+	return nil
 }
 
 func (c *ConsistencySnapshot) TearDown() error {
@@ -130,12 +165,16 @@ func resolveAutoConsistency(conf *Config) {
 	if conf.Consistency != "auto" {
 		return
 	}
-	switch conf.ServerInfo.ServerType {
-	case ServerTypeTiDB:
+	caps := conf.ServerInfo.Capabilities()
+	switch {
+	case caps.Snapshot:
 		conf.Consistency = "snapshot"
-	case ServerTypeMySQL, ServerTypeMariaDB:
+	case caps.FlushTablesWithReadLock:
 		conf.Consistency = "flush"
 	default:
+		// the tables may be changed while they're dumped
+		log.Warn("the server supports neither snapshot nor flush consistency, dump without consistency",
+			zap.Stringer("server type", conf.ServerInfo.ServerType))
 		conf.Consistency = "none"
 	}
 }
@@ -1,6 +1,7 @@
 package export
 
 import (
+	"context"
 	"errors"
 	"strings"
 
@@ -19,7 +20,7 @@ func (s *testConsistencySuite) assertNil(err error, c *C) {
 }
 
 func (s *testConsistencySuite) assertLifetimeErrNil(ctrl ConsistencyController, c *C) {
-	s.assertNil(ctrl.Setup(), c)
+	s.assertNil(ctrl.Setup(context.Background()), c)
 	s.assertNil(ctrl.TearDown(), c)
 }
 
@@ -120,14 +121,14 @@ func (s *testConsistencySuite) TestConsistencyControllerError(c *C) {
 	conf.Consistency = "snapshot"
 	conf.ServerInfo.ServerType = ServerTypeUnknown
 	ctrl, _ := NewConsistencyController(conf, db)
-	err = ctrl.Setup()
+	err = ctrl.Setup(context.Background())
 	c.Assert(err, NotNil)
 
 	// flush consistency is unavailable in TiDB
 	conf.Consistency = "flush"
 	conf.ServerInfo.ServerType = ServerTypeTiDB
 	ctrl, _ = NewConsistencyController(conf, db)
-	err = ctrl.Setup()
+	err = ctrl.Setup(context.Background())
 	c.Assert(err, NotNil)
 
 	// lock table fail
@@ -135,6 +136,6 @@ func (s *testConsistencySuite) TestConsistencyControllerError(c *C) {
 	conf.Tables = NewDatabaseTables().AppendTables("db", "t")
 	mock.ExpectExec("LOCK TABLE").WillReturnError(errors.New(""))
 	ctrl, _ = NewConsistencyController(conf, db)
-	err = ctrl.Setup()
+	err = ctrl.Setup(context.Background())
 	c.Assert(err, NotNil)
 }
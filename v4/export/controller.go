@@ -0,0 +1,197 @@
+package export
+
+import (
+	"context"
+	"errors"
+	"sync"
+)
+
+// ErrDumpStopped is returned by Dump if it's stopped by JobController.Stop.
+var ErrDumpStopped = errors.New("dump is stopped by user")
+
+// JobState is the running state of a dump job.
+type JobState string
+
+const (
+	JobStateRunning JobState = "running"
+	// JobStateThrottled means the job is running but waits for the source load to drop.
+	JobStateThrottled JobState = "throttled"
+	JobStatePaused    JobState = "paused"
+	JobStateStopping  JobState = "stopping"
+	JobStateFinished  JobState = "finished"
+	JobStateFailed    JobState = "failed"
+)
+
+// JobController controls a running dump. Pausing or stopping takes effect before
+// the next table or chunk is dumped, the in-flight ones are always finished.
+// All the methods are safe to be called concurrently, or on a nil *JobController.
+type JobController struct {
+	mu       sync.Mutex
+	state    JobState
+	resumeCh chan struct{}
+	stopCh   chan struct{}
+
+	throttled    bool
+	unthrottleCh chan struct{}
+
+	// progress is the Progress of the job, it's set by track
+	progress *Progress
+}
+
+func NewJobController() *JobController {
+	return &JobController{
+		state:  JobStateRunning,
+		stopCh: make(chan struct{}),
+	}
+}
+
+// State returns the current state of the job.
+func (c *JobController) State() JobState {
+	if c == nil {
+		return JobStateRunning
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.state == JobStateRunning && c.throttled {
+		return JobStateThrottled
+	}
+	return c.state
+}
+
+// Progress returns the progress of the job, including the estimated total
+// rows and bytes, the finished counts, the current rates and the ETA.
+func (c *JobController) Progress() ProgressStatus {
+	if c == nil {
+		return (*Progress)(nil).Status()
+	}
+	c.mu.Lock()
+	p := c.progress
+	c.mu.Unlock()
+	return p.Status()
+}
+
+// track sets the Progress of the job returned by JobController.Progress.
+func (c *JobController) track(p *Progress) {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.progress = p
+}
+
+// setThrottled throttles or unthrottles the job, the throttled job is blocked
+// until it's unthrottled, just like it's paused.
+func (c *JobController) setThrottled(throttled bool) {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.throttled == throttled {
+		return
+	}
+	c.throttled = throttled
+	if throttled {
+		c.unthrottleCh = make(chan struct{})
+	} else {
+		close(c.unthrottleCh)
+	}
+}
+
+// Pause pauses the job, returns false if the job is not running.
+func (c *JobController) Pause() bool {
+	if c == nil {
+		return false
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.state != JobStateRunning {
+		return false
+	}
+	c.state = JobStatePaused
+	c.resumeCh = make(chan struct{})
+	return true
+}
+
+// Resume resumes the paused job, returns false if the job is not paused.
+func (c *JobController) Resume() bool {
+	if c == nil {
+		return false
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.state != JobStatePaused {
+		return false
+	}
+	c.state = JobStateRunning
+	close(c.resumeCh)
+	return true
+}
+
+// Stop stops the job gracefully, returns false if the job is already stopping, finished or failed.
+func (c *JobController) Stop() bool {
+	if c == nil {
+		return false
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	switch c.state {
+	case JobStateStopping, JobStateFinished, JobStateFailed:
+		return false
+	case JobStatePaused:
+		close(c.resumeCh)
+	}
+	c.state = JobStateStopping
+	close(c.stopCh)
+	return true
+}
+
+// finish marks the job as failed if err is not nil, or finished otherwise.
+func (c *JobController) finish(err error) {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.state == JobStatePaused {
+		close(c.resumeCh)
+	}
+	if err != nil {
+		c.state = JobStateFailed
+	} else {
+		c.state = JobStateFinished
+	}
+}
+
+// wait blocks while the job is paused or throttled. It returns ErrDumpStopped
+// if the job is stopped, or the error of ctx if it's done.
+func (c *JobController) wait(ctx context.Context) error {
+	if c == nil {
+		return ctx.Err()
+	}
+	for {
+		c.mu.Lock()
+		state, resumeCh := c.state, c.resumeCh
+		throttled, unthrottleCh := c.throttled, c.unthrottleCh
+		c.mu.Unlock()
+
+		var blockCh chan struct{}
+		switch {
+		case state == JobStateStopping, state == JobStateFinished, state == JobStateFailed:
+			return ErrDumpStopped
+		case state == JobStatePaused:
+			blockCh = resumeCh
+		case throttled:
+			blockCh = unthrottleCh
+		default:
+			return nil
+		}
+		select {
+		case <-blockCh:
+		case <-c.stopCh:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
@@ -0,0 +1,93 @@
+package export
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	. "github.com/pingcap/check"
+)
+
+var _ = Suite(&testControllerSuite{})
+
+type testControllerSuite struct{}
+
+func (s *testControllerSuite) TestJobController(c *C) {
+	var nilController *JobController
+	c.Assert(nilController.wait(context.Background()), IsNil)
+	c.Assert(nilController.Pause(), IsFalse)
+
+	ctrl := NewJobController()
+	c.Assert(ctrl.State(), Equals, JobStateRunning)
+	c.Assert(ctrl.wait(context.Background()), IsNil)
+	c.Assert(ctrl.Resume(), IsFalse)
+
+	c.Assert(ctrl.Pause(), IsTrue)
+	c.Assert(ctrl.Pause(), IsFalse)
+	c.Assert(ctrl.State(), Equals, JobStatePaused)
+
+	waitCh := make(chan error, 1)
+	go func() {
+		waitCh <- ctrl.wait(context.Background())
+	}()
+	select {
+	case <-waitCh:
+		c.Fatal("wait should be blocked when paused")
+	case <-time.After(50 * time.Millisecond):
+	}
+	c.Assert(ctrl.Resume(), IsTrue)
+	c.Assert(<-waitCh, IsNil)
+
+	// cancel the context when paused
+	c.Assert(ctrl.Pause(), IsTrue)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	c.Assert(ctrl.wait(ctx), Equals, context.Canceled)
+
+	// stop when paused
+	go func() {
+		waitCh <- ctrl.wait(context.Background())
+	}()
+	c.Assert(ctrl.Stop(), IsTrue)
+	c.Assert(<-waitCh, Equals, ErrDumpStopped)
+	c.Assert(ctrl.State(), Equals, JobStateStopping)
+	c.Assert(ctrl.Stop(), IsFalse)
+	c.Assert(ctrl.wait(context.Background()), Equals, ErrDumpStopped)
+
+	ctrl.finish(nil)
+	c.Assert(ctrl.State(), Equals, JobStateFinished)
+	c.Assert(ctrl.Stop(), IsFalse)
+
+	failed := NewJobController()
+	failed.finish(errors.New("mock error"))
+	c.Assert(failed.State(), Equals, JobStateFailed)
+	c.Assert(failed.Stop(), IsFalse)
+	c.Assert(failed.wait(context.Background()), Equals, ErrDumpStopped)
+}
+
+func (s *testControllerSuite) TestJobControllerThrottle(c *C) {
+	ctrl := NewJobController()
+	ctrl.setThrottled(true)
+	c.Assert(ctrl.State(), Equals, JobStateThrottled)
+
+	waitCh := make(chan error, 1)
+	go func() {
+		waitCh <- ctrl.wait(context.Background())
+	}()
+	select {
+	case <-waitCh:
+		c.Fatal("wait should be blocked when throttled")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	// pausing a throttled job keeps it blocked after unthrottled
+	c.Assert(ctrl.Pause(), IsTrue)
+	ctrl.setThrottled(false)
+	select {
+	case <-waitCh:
+		c.Fatal("wait should be blocked when paused")
+	case <-time.After(50 * time.Millisecond):
+	}
+	c.Assert(ctrl.Resume(), IsTrue)
+	c.Assert(<-waitCh, IsNil)
+}
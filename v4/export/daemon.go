@@ -0,0 +1,535 @@
+package export
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/pprof"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	pkgerrors "github.com/pingcap/errors"
+	"go.uber.org/zap"
+
+	"github.com/pingcap/dumpling/v4/log"
+)
+
+// defaultDaemonQueueSize is the max number of the queued jobs of a Daemon.
+const defaultDaemonQueueSize = 1024
+
+// defaultDaemonFinishedJobs is the max number of the finished jobs kept by a
+// Daemon, the oldest ones are pruned when it's exceeded.
+const defaultDaemonFinishedJobs = 1024
+
+var errDaemonDraining = errors.New("daemon is draining, no new jobs are accepted")
+
+var errDaemonSqlNotAllowed = errors.New("sql and where of the jobs are not allowed without daemon-allow-sql")
+
+// DaemonJobState is the state of a job submitted to a Daemon.
+type DaemonJobState string
+
+const (
+	DaemonJobQueued    DaemonJobState = "queued"
+	DaemonJobRunning   DaemonJobState = "running"
+	DaemonJobSucceeded DaemonJobState = "succeeded"
+	DaemonJobFailed    DaemonJobState = "failed"
+	// DaemonJobStopped means the job is stopped before or while running.
+	DaemonJobStopped DaemonJobState = "stopped"
+)
+
+// JobRequest is the body of submitting a job to a Daemon, the empty fields
+// are taken from the base Config of the Daemon.
+type JobRequest struct {
+	Database string `json:"database"`
+	Where    string `json:"where"`
+	Sql      string `json:"sql"`
+	FileType string `json:"filetype"`
+	Rows     uint64 `json:"rows"`
+	Threads  int    `json:"threads"`
+	// Name is the output directory of the job in the base OutputDirPath,
+	// it's the job ID if empty.
+	Name string `json:"name"`
+}
+
+// DaemonJobStatus is the response of the job APIs of a Daemon.
+type DaemonJobStatus struct {
+	ID    string         `json:"id"`
+	State DaemonJobState `json:"state"`
+	// Control is the state of the JobController of the running job.
+	Control   JobState       `json:"control,omitempty"`
+	Progress  ProgressStatus `json:"progress"`
+	Error     string         `json:"error,omitempty"`
+	ErrorKind ErrorKind      `json:"error_kind,omitempty"`
+	OutputDir string         `json:"output_dir"`
+	// Schedule is the ID of the schedule which submitted the job.
+	Schedule string `json:"schedule,omitempty"`
+	// Artifacts is the output files closed by the job.
+	Artifacts  []string  `json:"artifacts"`
+	SubmitTime time.Time `json:"submit_time"`
+	StartTime  time.Time `json:"start_time"`
+	FinishTime time.Time `json:"finish_time"`
+}
+
+type daemonJob struct {
+	mu     sync.Mutex
+	status DaemonJobStatus
+	conf   *Config
+	// schedule is nil unless the job is submitted by a schedule
+	schedule *daemonSchedule
+}
+
+// OnFileClosed records the artifacts of the job, the other events are
+// passed to the Hooks of the base Config.
+type daemonJobHooks struct {
+	Hooks
+	job *daemonJob
+}
+
+func (h daemonJobHooks) OnFileClosed(path string) {
+	h.job.mu.Lock()
+	h.job.status.Artifacts = append(h.job.status.Artifacts, path)
+	h.job.mu.Unlock()
+	h.Hooks.OnFileClosed(path)
+}
+
+func (j *daemonJob) snapshot() DaemonJobStatus {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	status := j.status
+	status.Artifacts = append([]string(nil), j.status.Artifacts...)
+	if status.State == DaemonJobRunning {
+		status.Control = j.conf.Controller.State()
+	}
+	status.Progress = j.conf.Controller.Progress()
+	return status
+}
+
+// active returns whether the job is queued or running.
+func (j *daemonJob) active() bool {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return j.status.State == DaemonJobQueued || j.status.State == DaemonJobRunning
+}
+
+// Daemon runs the dump jobs submitted by its REST API on the StatusAddr of
+// the base Config, at most concurrency jobs at the same time. The jobs and
+// schedules are kept in memory, so they're lost when the Daemon exits.
+type Daemon struct {
+	base        *Config
+	concurrency int
+	queue       chan *daemonJob
+	// maxFinishedJobs is the max number of the finished jobs kept
+	maxFinishedJobs int
+
+	mu     sync.Mutex
+	nextID int
+	jobs   []*daemonJob
+	byID   map[string]*daemonJob
+	// ctx is the context of serving, the schedules are started with it
+	ctx context.Context
+	// draining is set by Drain, the new jobs are refused then
+	draining       bool
+	drainCh        chan struct{}
+	nextScheduleID int
+	schedules      []*daemonSchedule
+	scheduleByID   map[string]*daemonSchedule
+}
+
+// NewDaemon creates a Daemon whose jobs are base overridden by the JobRequests.
+func NewDaemon(base *Config, concurrency int) *Daemon {
+	if concurrency <= 0 {
+		concurrency = 1
+	}
+	return &Daemon{
+		base:        base,
+		concurrency: concurrency,
+		queue:       make(chan *daemonJob, defaultDaemonQueueSize),
+		nextID:      1,
+		byID:        map[string]*daemonJob{},
+		drainCh:     make(chan struct{}),
+
+		maxFinishedJobs: defaultDaemonFinishedJobs,
+
+		nextScheduleID: 1,
+		scheduleByID:   map[string]*daemonSchedule{},
+	}
+}
+
+// Run serves the REST API and runs the jobs until ctx is done, the running
+// jobs are canceled then. It also returns after Drain, once the running jobs
+// are finished.
+func (d *Daemon) Run(ctx context.Context) error {
+	if err := initDaemonLogger(d.base); err != nil {
+		return withKind(ErrorKindConfig, err)
+	}
+	lis, err := net.Listen("tcp", d.base.StatusAddr)
+	if err != nil {
+		return withKind(ErrorKindConfig, pkgerrors.Annotate(err, "start listening"))
+	}
+	log.Info("dumpling daemon started", zap.String("address", lis.Addr().String()),
+		zap.Int("concurrency", d.concurrency))
+	warnUnauthenticatedAddr(d.base.StatusAddr, d.base.StatusToken)
+	return d.serve(ctx, lis)
+}
+
+func (d *Daemon) serve(ctx context.Context, lis net.Listener) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	d.mu.Lock()
+	d.ctx = ctx
+	for _, sched := range d.schedules {
+		go d.runSchedule(ctx, sched)
+	}
+	d.mu.Unlock()
+
+	var wg sync.WaitGroup
+	for i := 0; i < d.concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			d.runJobs(ctx)
+		}()
+	}
+
+	server := &http.Server{Handler: d.handler()}
+	serveErr := make(chan error, 1)
+	go func() {
+		serveErr <- server.Serve(lis)
+	}()
+	var err error
+	select {
+	case <-ctx.Done():
+	case err = <-serveErr:
+		cancel()
+	case <-d.drainCh:
+	}
+	// the probes are served until the running jobs are finished
+	wg.Wait()
+	_ = server.Close()
+	if err == http.ErrServerClosed || isErrNetClosing(err) {
+		err = nil
+	}
+	return err
+}
+
+func initDaemonLogger(conf *Config) error {
+	if conf.Logger != nil {
+		log.SetAppLogger(conf.Logger)
+		return nil
+	}
+	return log.InitAppLogger(&log.Config{
+		Level:          conf.LogLevel,
+		File:           conf.LogFile,
+		FileMaxSize:    conf.LogFileMaxSize,
+		FileMaxDays:    conf.LogFileMaxDays,
+		FileMaxBackups: conf.LogFileMaxBackups,
+		Format:         conf.LogFormat,
+	})
+}
+
+func (d *Daemon) runJobs(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-d.drainCh:
+			return
+		case job := <-d.queue:
+			d.runJob(ctx, job)
+		}
+	}
+}
+
+func (d *Daemon) runJob(ctx context.Context, job *daemonJob) {
+	defer d.finishScheduledJob(job)
+	job.mu.Lock()
+	if job.status.State != DaemonJobQueued {
+		job.mu.Unlock()
+		return
+	}
+	job.status.State = DaemonJobRunning
+	job.status.StartTime = time.Now()
+	job.mu.Unlock()
+	log.Info("start dump job", zap.String("id", job.status.ID), zap.String("output", job.status.OutputDir))
+
+	err := Dump(ctx, job.conf)
+
+	job.mu.Lock()
+	defer job.mu.Unlock()
+	job.status.FinishTime = time.Now()
+	switch cause := RootCause(err); {
+	case err == nil:
+		job.status.State = DaemonJobSucceeded
+	case errors.Is(err, ErrDumpStopped), cause == context.Canceled:
+		job.status.State = DaemonJobStopped
+	default:
+		job.status.State = DaemonJobFailed
+	}
+	if err != nil {
+		job.status.Error = RootCause(err).Error()
+		job.status.ErrorKind = ErrorKindOf(err)
+		log.Warn("dump job failed", zap.String("id", job.status.ID), zap.Error(err))
+	} else {
+		log.Info("dump job succeeded", zap.String("id", job.status.ID))
+	}
+}
+
+// submit validates the job of req and queues it.
+func (d *Daemon) submit(req JobRequest) (*daemonJob, error) {
+	return d.submitJob(req, req.Name, nil)
+}
+
+// submitJob queues the job of req dumped into the directory of name, which is
+// the job ID if empty.
+func (d *Daemon) submitJob(req JobRequest, name string, sched *daemonSchedule) (*daemonJob, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.draining {
+		return nil, errDaemonDraining
+	}
+	id := strconv.Itoa(d.nextID)
+	if name == "" {
+		name = id
+	}
+	conf, err := d.jobConfig(req, name)
+	if err != nil {
+		return nil, err
+	}
+	conf.Progress = NewProgress()
+	conf.Controller = NewJobController()
+	conf.Controller.track(conf.Progress)
+	job := &daemonJob{
+		conf: conf,
+		status: DaemonJobStatus{
+			ID:         id,
+			State:      DaemonJobQueued,
+			OutputDir:  conf.OutputDirPath,
+			SubmitTime: time.Now(),
+		},
+		schedule: sched,
+	}
+	if sched != nil {
+		job.status.Schedule = sched.status.ID
+	}
+	conf.Hooks = daemonJobHooks{Hooks: d.base.hooks(), job: job}
+
+	select {
+	case d.queue <- job:
+	default:
+		return nil, pkgerrors.Errorf("too many queued jobs, the max is %d", cap(d.queue))
+	}
+	d.nextID++
+	d.jobs = append(d.jobs, job)
+	d.byID[id] = job
+	d.pruneJobs()
+	return job, nil
+}
+
+// pruneJobs removes the oldest finished jobs beyond maxFinishedJobs, the
+// queued and running jobs are always kept. It's called with d.mu held.
+func (d *Daemon) pruneJobs() {
+	finished := 0
+	for _, job := range d.jobs {
+		if !job.active() {
+			finished++
+		}
+	}
+	if finished <= d.maxFinishedJobs {
+		return
+	}
+	kept := d.jobs[:0]
+	for _, job := range d.jobs {
+		if finished > d.maxFinishedJobs && !job.active() {
+			finished--
+			delete(d.byID, job.status.ID)
+			continue
+		}
+		kept = append(kept, job)
+	}
+	// release the pruned jobs referenced by the rest of the backing array
+	for i := len(kept); i < len(d.jobs); i++ {
+		d.jobs[i] = nil
+	}
+	d.jobs = kept
+}
+
+// jobConfig returns the validated base Config overridden by req.
+func (d *Daemon) jobConfig(req JobRequest, name string) (*Config, error) {
+	if name != filepath.Base(name) || name == "." || name == ".." {
+		return nil, pkgerrors.Errorf("invalid job name %s", name)
+	}
+	conf := *d.base
+	conf.StatusAddr = ""
+	conf.Logger = log.Zap().Logger
+	conf.OutputDirPath = filepath.Join(d.base.OutputDirPath, name)
+	if req.Database != "" {
+		conf.Database = req.Database
+	}
+	// where is spliced into the queries as SQL too
+	if (req.Sql != "" || req.Where != "") && !d.base.DaemonAllowSql {
+		return nil, errDaemonSqlNotAllowed
+	}
+	if req.Where != "" {
+		conf.Where = req.Where
+	}
+	if req.Sql != "" {
+		conf.Sql = req.Sql
+	}
+	if req.FileType != "" {
+		conf.FileType = req.FileType
+	}
+	if req.Rows != UnspecifiedSize {
+		conf.Rows = req.Rows
+	}
+	if req.Threads != 0 {
+		conf.Threads = req.Threads
+	}
+	if err := conf.Validate(); err != nil {
+		return nil, err
+	}
+	return &conf, nil
+}
+
+// Drain refuses the new jobs and stops the queued and running ones, the
+// running jobs finish their in-flight chunks before they return.
+func (d *Daemon) Drain() {
+	d.mu.Lock()
+	if d.draining {
+		d.mu.Unlock()
+		return
+	}
+	d.draining = true
+	close(d.drainCh)
+	jobs := append([]*daemonJob(nil), d.jobs...)
+	d.mu.Unlock()
+	log.Info("drain the dumpling daemon")
+	for _, job := range jobs {
+		d.stop(job)
+	}
+}
+
+func (d *Daemon) isDraining() bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.draining
+}
+
+// stop stops the running job, or the queued job before it's run. It returns
+// false if the job has finished.
+func (d *Daemon) stop(job *daemonJob) bool {
+	job.mu.Lock()
+	defer job.mu.Unlock()
+	switch job.status.State {
+	case DaemonJobQueued:
+		job.status.State = DaemonJobStopped
+		job.status.FinishTime = time.Now()
+		return true
+	case DaemonJobRunning:
+		return job.conf.Controller.Stop()
+	default:
+		return false
+	}
+}
+
+func (d *Daemon) handler() http.Handler {
+	router := http.NewServeMux()
+	token := d.base.StatusToken
+	router.HandleFunc("/jobs", requireToken(token, d.handleJobs))
+	router.HandleFunc("/jobs/", requireToken(token, d.handleJob))
+	router.HandleFunc("/schedules", requireToken(token, d.handleSchedules))
+	router.HandleFunc("/schedules/", requireToken(token, d.handleSchedule))
+	// the probes reveal nothing, so they're served without the token
+	router.HandleFunc("/healthz", newHealthHandler())
+	router.HandleFunc("/readyz", newReadyHandler(func() bool { return !d.isDraining() }))
+
+	router.HandleFunc("/debug/pprof/", requireToken(token, pprof.Index))
+	router.HandleFunc("/debug/pprof/cmdline", requireToken(token, pprof.Cmdline))
+	router.HandleFunc("/debug/pprof/profile", requireToken(token, pprof.Profile))
+	router.HandleFunc("/debug/pprof/symbol", requireToken(token, pprof.Symbol))
+	router.HandleFunc("/debug/pprof/trace", requireToken(token, pprof.Trace))
+	return router
+}
+
+// handleJobs lists the jobs on GET, or submits a job on POST.
+func (d *Daemon) handleJobs(w http.ResponseWriter, req *http.Request) {
+	switch req.Method {
+	case http.MethodGet:
+		d.mu.Lock()
+		jobs := append([]*daemonJob(nil), d.jobs...)
+		d.mu.Unlock()
+		statuses := make([]DaemonJobStatus, 0, len(jobs))
+		for _, job := range jobs {
+			statuses = append(statuses, job.snapshot())
+		}
+		writeJSON(w, http.StatusOK, statuses)
+	case http.MethodPost:
+		var jobReq JobRequest
+		if err := json.NewDecoder(req.Body).Decode(&jobReq); err != nil {
+			http.Error(w, fmt.Sprintf("invalid job request: %s", err), http.StatusBadRequest)
+			return
+		}
+		job, err := d.submit(jobReq)
+		if err == errDaemonDraining {
+			http.Error(w, err.Error(), http.StatusServiceUnavailable)
+			return
+		} else if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		writeJSON(w, http.StatusCreated, job.snapshot())
+	default:
+		w.Header().Set("Allow", "GET, POST")
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// handleJob serves GET /jobs/{id} and POST /jobs/{id}/{pause,resume,stop}.
+func (d *Daemon) handleJob(w http.ResponseWriter, req *http.Request) {
+	parts := strings.Split(strings.TrimPrefix(req.URL.Path, "/jobs/"), "/")
+	d.mu.Lock()
+	job := d.byID[parts[0]]
+	d.mu.Unlock()
+	if job == nil || len(parts) > 2 {
+		http.NotFound(w, req)
+		return
+	}
+	if len(parts) == 1 {
+		if req.Method != http.MethodGet {
+			w.Header().Set("Allow", http.MethodGet)
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		writeJSON(w, http.StatusOK, job.snapshot())
+		return
+	}
+
+	var action func() bool
+	switch parts[1] {
+	case "pause":
+		action = job.conf.Controller.Pause
+	case "resume":
+		action = job.conf.Controller.Resume
+	case "stop":
+		action = func() bool { return d.stop(job) }
+	default:
+		http.NotFound(w, req)
+		return
+	}
+	if req.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	code := http.StatusOK
+	if !action() {
+		code = http.StatusConflict
+	}
+	writeJSON(w, code, job.snapshot())
+}
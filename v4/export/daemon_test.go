@@ -0,0 +1,190 @@
+package export
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+
+	. "github.com/pingcap/check"
+)
+
+var _ = Suite(&testDaemonSuite{})
+
+type testDaemonSuite struct{}
+
+func (s *testDaemonSuite) request(c *C, d *Daemon, method, path, body string, v interface{}) int {
+	w := httptest.NewRecorder()
+	d.handler().ServeHTTP(w, httptest.NewRequest(method, path, strings.NewReader(body)))
+	if v != nil && w.Code < http.StatusBadRequest {
+		c.Assert(json.Unmarshal(w.Body.Bytes(), v), IsNil)
+	}
+	return w.Code
+}
+
+func (s *testDaemonSuite) TestSubmit(c *C) {
+	base := DefaultConfig()
+	base.OutputDirPath = "/backup"
+	d := NewDaemon(base, 2)
+
+	var status DaemonJobStatus
+	c.Assert(s.request(c, d, http.MethodPost, "/jobs", `{"database":"app","filetype":"csv"}`, &status), Equals, http.StatusCreated)
+	c.Assert(status.ID, Equals, "1")
+	c.Assert(status.State, Equals, DaemonJobQueued)
+	c.Assert(status.OutputDir, Equals, filepath.Join("/backup", "1"))
+	job := d.byID["1"]
+	c.Assert(job.conf.Database, Equals, "app")
+	c.Assert(job.conf.FileType, Equals, "csv")
+	c.Assert(job.conf.StatusAddr, Equals, "")
+	c.Assert(base.Database, Equals, "")
+
+	c.Assert(s.request(c, d, http.MethodPost, "/jobs", `{"name":"nightly"}`, &status), Equals, http.StatusCreated)
+	c.Assert(status.ID, Equals, "2")
+	c.Assert(status.OutputDir, Equals, filepath.Join("/backup", "nightly"))
+
+	c.Assert(s.request(c, d, http.MethodPost, "/jobs", `{"name":"../etc"}`, nil), Equals, http.StatusBadRequest)
+	c.Assert(s.request(c, d, http.MethodPost, "/jobs", `{"threads":-1}`, nil), Equals, http.StatusBadRequest)
+	c.Assert(s.request(c, d, http.MethodPost, "/jobs", `{`, nil), Equals, http.StatusBadRequest)
+	c.Assert(s.request(c, d, http.MethodDelete, "/jobs", "", nil), Equals, http.StatusMethodNotAllowed)
+
+	var statuses []DaemonJobStatus
+	c.Assert(s.request(c, d, http.MethodGet, "/jobs", "", &statuses), Equals, http.StatusOK)
+	c.Assert(statuses, HasLen, 2)
+	c.Assert(s.request(c, d, http.MethodGet, "/jobs/2", "", &status), Equals, http.StatusOK)
+	c.Assert(status.ID, Equals, "2")
+	c.Assert(s.request(c, d, http.MethodGet, "/jobs/3", "", nil), Equals, http.StatusNotFound)
+	c.Assert(s.request(c, d, http.MethodPost, "/jobs/2/rewind", "", nil), Equals, http.StatusNotFound)
+}
+
+func (s *testDaemonSuite) TestStopQueuedJob(c *C) {
+	d := NewDaemon(DefaultConfig(), 1)
+	var status DaemonJobStatus
+	c.Assert(s.request(c, d, http.MethodPost, "/jobs", `{}`, &status), Equals, http.StatusCreated)
+	c.Assert(s.request(c, d, http.MethodGet, "/jobs/1/stop", "", nil), Equals, http.StatusMethodNotAllowed)
+	c.Assert(s.request(c, d, http.MethodPost, "/jobs/1/stop", "", &status), Equals, http.StatusOK)
+	c.Assert(status.State, Equals, DaemonJobStopped)
+	c.Assert(s.request(c, d, http.MethodPost, "/jobs/1/stop", "", &status), Equals, http.StatusConflict)
+
+	// the stopped job is skipped
+	d.runJob(context.Background(), <-d.queue)
+	c.Assert(d.byID["1"].snapshot().StartTime.IsZero(), IsTrue)
+}
+
+func (s *testDaemonSuite) TestRunJob(c *C) {
+	d := NewDaemon(DefaultConfig(), 1)
+	job, err := d.submit(JobRequest{})
+	c.Assert(err, IsNil)
+	job.conf.Threads = 0
+	d.runJob(context.Background(), <-d.queue)
+	status := job.snapshot()
+	c.Assert(status.State, Equals, DaemonJobFailed)
+	c.Assert(status.ErrorKind, Equals, ErrorKindConfig)
+	c.Assert(status.Error, Equals, "threads should be positive, got 0")
+	c.Assert(status.FinishTime.IsZero(), IsFalse)
+
+	job.conf.Hooks.OnFileClosed("1/metadata")
+	c.Assert(job.snapshot().Artifacts, DeepEquals, []string{"1/metadata"})
+}
+
+func (s *testDaemonSuite) TestDrain(c *C) {
+	d := NewDaemon(DefaultConfig(), 1)
+	job, err := d.submit(JobRequest{})
+	c.Assert(err, IsNil)
+	c.Assert(s.request(c, d, http.MethodGet, "/readyz", "", nil), Equals, http.StatusOK)
+
+	d.Drain()
+	c.Assert(job.snapshot().State, Equals, DaemonJobStopped)
+	c.Assert(s.request(c, d, http.MethodGet, "/healthz", "", nil), Equals, http.StatusOK)
+	c.Assert(s.request(c, d, http.MethodGet, "/readyz", "", nil), Equals, http.StatusServiceUnavailable)
+	c.Assert(s.request(c, d, http.MethodPost, "/jobs", `{}`, nil), Equals, http.StatusServiceUnavailable)
+}
+
+func (s *testDaemonSuite) TestServeUntilDrained(c *C) {
+	d := NewDaemon(DefaultConfig(), 2)
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	c.Assert(err, IsNil)
+	done := make(chan error, 1)
+	go func() {
+		done <- d.serve(context.Background(), lis)
+	}()
+	resp, err := http.Get("http://" + lis.Addr().String() + "/healthz")
+	c.Assert(err, IsNil)
+	c.Assert(resp.Body.Close(), IsNil)
+	c.Assert(resp.StatusCode, Equals, http.StatusOK)
+
+	d.Drain()
+	c.Assert(<-done, IsNil)
+}
+
+func (s *testDaemonSuite) TestStatusToken(c *C) {
+	base := DefaultConfig()
+	base.StatusToken = "secret"
+	d := NewDaemon(base, 1)
+	c.Assert(s.request(c, d, http.MethodGet, "/jobs", "", nil), Equals, http.StatusUnauthorized)
+	c.Assert(s.request(c, d, http.MethodPost, "/jobs", `{}`, nil), Equals, http.StatusUnauthorized)
+	c.Assert(s.request(c, d, http.MethodGet, "/debug/pprof/", "", nil), Equals, http.StatusUnauthorized)
+	c.Assert(d.jobs, HasLen, 0)
+	// the probes are served without the token
+	c.Assert(s.request(c, d, http.MethodGet, "/healthz", "", nil), Equals, http.StatusOK)
+	c.Assert(s.request(c, d, http.MethodGet, "/readyz", "", nil), Equals, http.StatusOK)
+
+	for _, auth := range []string{"secret", "Bearer wrong", "Bearer secret2"} {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, "/jobs", nil)
+		req.Header.Set("Authorization", auth)
+		d.handler().ServeHTTP(w, req)
+		c.Assert(w.Code, Equals, http.StatusUnauthorized, Commentf("authorization %s", auth))
+	}
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodPost, "/jobs", strings.NewReader(`{}`))
+	req.Header.Set("Authorization", "Bearer secret")
+	d.handler().ServeHTTP(w, req)
+	c.Assert(w.Code, Equals, http.StatusCreated)
+}
+
+func (s *testDaemonSuite) TestSubmitSql(c *C) {
+	d := NewDaemon(DefaultConfig(), 1)
+	c.Assert(s.request(c, d, http.MethodPost, "/jobs", `{"sql":"select * from mysql.user","filetype":"csv"}`, nil), Equals, http.StatusBadRequest)
+	c.Assert(d.jobs, HasLen, 0)
+
+	base := DefaultConfig()
+	base.DaemonAllowSql = true
+	d = NewDaemon(base, 1)
+	c.Assert(s.request(c, d, http.MethodPost, "/jobs", `{"sql":"select * from app.t","filetype":"csv"}`, nil), Equals, http.StatusCreated)
+	c.Assert(d.byID["1"].conf.Sql, Equals, "select * from app.t")
+}
+
+func (s *testDaemonSuite) TestSubmitWhere(c *C) {
+	d := NewDaemon(DefaultConfig(), 1)
+	c.Assert(s.request(c, d, http.MethodPost, "/jobs", `{"where":"1) UNION SELECT user, authentication_string FROM mysql.user -- "}`, nil), Equals, http.StatusBadRequest)
+	c.Assert(d.jobs, HasLen, 0)
+
+	base := DefaultConfig()
+	base.DaemonAllowSql = true
+	d = NewDaemon(base, 1)
+	c.Assert(s.request(c, d, http.MethodPost, "/jobs", `{"where":"id < 100"}`, nil), Equals, http.StatusCreated)
+	c.Assert(d.byID["1"].conf.Where, Equals, "id < 100")
+}
+
+func (s *testDaemonSuite) TestPruneFinishedJobs(c *C) {
+	d := NewDaemon(DefaultConfig(), 1)
+	d.maxFinishedJobs = 2
+	for i := 0; i < 4; i++ {
+		job, err := d.submit(JobRequest{})
+		c.Assert(err, IsNil)
+		c.Assert(d.stop(job), IsTrue)
+	}
+	// the queued job is kept however many jobs are finished
+	_, err := d.submit(JobRequest{})
+	c.Assert(err, IsNil)
+	ids := make([]string, 0, len(d.jobs))
+	for _, job := range d.jobs {
+		ids = append(ids, job.status.ID)
+	}
+	c.Assert(ids, DeepEquals, []string{"3", "4", "5"})
+	c.Assert(d.byID["1"], IsNil)
+	c.Assert(s.request(c, d, http.MethodGet, "/jobs/1", "", nil), Equals, http.StatusNotFound)
+}
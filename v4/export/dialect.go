@@ -0,0 +1,125 @@
+package export
+
+import (
+	"database/sql"
+	"fmt"
+
+	"github.com/pingcap/errors"
+)
+
+const (
+	// DialectMySQL is the source dialect of MySQL, MariaDB and TiDB.
+	DialectMySQL = "mysql"
+	// DialectPostgres is the source dialect of PostgreSQL.
+	DialectPostgres = "postgres"
+)
+
+// Dialect is the SQL dialect of the source database. It covers the queries
+// which differ between the databases, the rows are read by database/sql and
+// written in the same output formats for all dialects.
+type Dialect interface {
+	// DriverName is the database/sql driver to connect with.
+	DriverName() string
+	// DSN is the data source name to connect to the server of conf.
+	DSN(conf *Config) string
+	// QuoteIdentifier quotes a database, table or column name.
+	QuoteIdentifier(name string) string
+
+	ListDatabases(db *sql.DB) ([]string, error)
+	ListTables(db *sql.DB, database string) ([]string, error)
+	ListViews(db *sql.DB, database string) ([]string, error)
+	ShowCreateDatabase(db *sql.DB, database string) (string, error)
+	ShowCreateTable(db *sql.DB, database, table string) (string, error)
+	ShowCreateView(db *sql.DB, database, view string) (string, error)
+
+	// ListColumns returns the columns of a table in their defined order.
+	ListColumns(db *sql.DB, database, table string) ([]ColumnInfo, error)
+	// PrimaryKeyColumns returns the columns of the primary key of a table.
+	PrimaryKeyColumns(db *sql.DB, database, table string) ([]string, error)
+
+	// SelectField returns the quoted columns to dump separated by comma,
+	// or "*" if all the columns can be inserted back.
+	SelectField(db *sql.DB, database, table string) (string, error)
+	// OrderByClause returns the ORDER BY clause which sorts the rows of the
+	// table stably, or "" if it's not sorted.
+	OrderByClause(conf *Config, db *sql.DB, database, table string) (string, error)
+}
+
+var dialects = map[string]Dialect{
+	DialectMySQL:    mysqlDialect{},
+	DialectPostgres: postgresDialect{},
+}
+
+// dialect returns the Dialect of SourceDialect, which defaults to MySQL.
+func (conf *Config) dialect() Dialect {
+	if d, ok := dialects[conf.SourceDialect]; ok {
+		return d
+	}
+	return mysqlDialect{}
+}
+
+func validateDialect(name string) error {
+	if _, ok := dialects[name]; !ok && name != "" {
+		return errors.Errorf("invalid source dialect %s", name)
+	}
+	return nil
+}
+
+// qualifiedName returns the quoted database.table of d.
+func qualifiedName(d Dialect, database, table string) string {
+	return fmt.Sprintf("%s.%s", d.QuoteIdentifier(database), d.QuoteIdentifier(table))
+}
+
+type mysqlDialect struct{}
+
+func (mysqlDialect) DriverName() string {
+	return "mysql"
+}
+
+func (mysqlDialect) DSN(conf *Config) string {
+	return conf.getDSN("")
+}
+
+func (mysqlDialect) QuoteIdentifier(name string) string {
+	return wrapBackTicks(name)
+}
+
+func (mysqlDialect) ListDatabases(db *sql.DB) ([]string, error) {
+	return ShowDatabases(db)
+}
+
+func (mysqlDialect) ListTables(db *sql.DB, database string) ([]string, error) {
+	return ListAllTables(db, database)
+}
+
+func (mysqlDialect) ListViews(db *sql.DB, database string) ([]string, error) {
+	return ListAllViews(db, database)
+}
+
+func (mysqlDialect) ShowCreateDatabase(db *sql.DB, database string) (string, error) {
+	return ShowCreateDatabase(db, database)
+}
+
+func (mysqlDialect) ShowCreateTable(db *sql.DB, database, table string) (string, error) {
+	return ShowCreateTable(db, database, table)
+}
+
+func (mysqlDialect) ShowCreateView(db *sql.DB, database, view string) (string, error) {
+	return ShowCreateView(db, database, view)
+}
+
+func (mysqlDialect) ListColumns(db *sql.DB, database, table string) ([]ColumnInfo, error) {
+	return ListColumns(db, database, table)
+}
+
+func (mysqlDialect) PrimaryKeyColumns(db *sql.DB, database, table string) ([]string, error) {
+	return GetPrimaryKeyColumns(db, database, table)
+}
+
+func (mysqlDialect) SelectField(db *sql.DB, database, table string) (string, error) {
+	return buildSelectField(db, database, table)
+}
+
+func (mysqlDialect) OrderByClause(conf *Config, db *sql.DB, database, table string) (string, error) {
+	return buildOrderByClause(conf, db, database, table)
+}
@@ -0,0 +1,351 @@
+package export
+
+import (
+	"database/sql"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+
+	// register the pgx driver of database/sql
+	_ "github.com/jackc/pgx/v4/stdlib"
+	"github.com/pkg/errors"
+)
+
+const defaultPostgresDatabase = "postgres"
+
+// postgresDialect dumps a database of PostgreSQL, whose schemas are dumped as
+// the databases of MySQL. PostgreSQL doesn't have SHOW CREATE TABLE, so the
+// DDL is built from the catalog.
+type postgresDialect struct{}
+
+func (postgresDialect) DriverName() string {
+	return "pgx"
+}
+
+func (postgresDialect) DSN(conf *Config) string {
+	database := conf.PostgresDatabase
+	if database == "" {
+		database = defaultPostgresDatabase
+	}
+	dsn := url.URL{
+		Scheme: "postgres",
+		User:   url.UserPassword(conf.User, conf.Password),
+		Host:   fmt.Sprintf("%s:%d", conf.Host, conf.Port),
+		Path:   database,
+	}
+	return dsn.String()
+}
+
+func (postgresDialect) QuoteIdentifier(name string) string {
+	return `"` + strings.Replace(name, `"`, `""`, -1) + `"`
+}
+
+func (postgresDialect) ListDatabases(db *sql.DB) ([]string, error) {
+	var schemas oneStrColumnTable
+	const query = "SELECT nspname FROM pg_catalog.pg_namespace " +
+		"WHERE nspname NOT LIKE 'pg\\_%' AND nspname <> 'information_schema' ORDER BY nspname"
+	if err := simpleQuery(db, query, schemas.handleOneRow); err != nil {
+		return nil, errors.WithMessage(err, query)
+	}
+	return schemas.data, nil
+}
+
+func (d postgresDialect) ListTables(db *sql.DB, database string) ([]string, error) {
+	return d.listTables(db, database, "BASE TABLE")
+}
+
+func (d postgresDialect) ListViews(db *sql.DB, database string) ([]string, error) {
+	return d.listTables(db, database, "VIEW")
+}
+
+func (postgresDialect) listTables(db *sql.DB, database, tableType string) ([]string, error) {
+	const query = "SELECT table_name FROM information_schema.tables WHERE table_schema = $1 AND table_type = $2 ORDER BY table_name"
+	return queryStrings(db, query, database, tableType)
+}
+
+func (d postgresDialect) ShowCreateDatabase(_ *sql.DB, database string) (string, error) {
+	return "CREATE SCHEMA " + d.QuoteIdentifier(database), nil
+}
+
+// ShowCreateTable builds CREATE TABLE with the columns and constraints of the
+// table, followed by CREATE INDEX of the indexes which don't belong to a
+// constraint. The columns of the owned sequences are dumped as serial columns
+// since the sequences are not dumped.
+func (d postgresDialect) ShowCreateTable(db *sql.DB, database, table string) (string, error) {
+	const columnsQuery = `SELECT a.attname, pg_catalog.format_type(a.atttypid, a.atttypmod), a.attnotnull,
+		COALESCE(pg_catalog.pg_get_expr(ad.adbin, ad.adrelid), ''),
+		pg_catalog.pg_get_serial_sequence(pg_catalog.quote_ident(n.nspname) || '.' || pg_catalog.quote_ident(c.relname), a.attname) IS NOT NULL
+		FROM pg_catalog.pg_attribute a
+		JOIN pg_catalog.pg_class c ON a.attrelid = c.oid
+		JOIN pg_catalog.pg_namespace n ON c.relnamespace = n.oid
+		LEFT JOIN pg_catalog.pg_attrdef ad ON ad.adrelid = a.attrelid AND ad.adnum = a.attnum
+		WHERE n.nspname = $1 AND c.relname = $2 AND a.attnum > 0 AND NOT a.attisdropped
+		ORDER BY a.attnum`
+	rows, err := db.Query(columnsQuery, database, table)
+	if err != nil {
+		return "", withStack(errors.WithMessage(err, columnsQuery))
+	}
+	var definitions []string
+	for rows.Next() {
+		var (
+			name, colType, colDefault string
+			notNull, serial           bool
+		)
+		if err := rows.Scan(&name, &colType, &notNull, &colDefault, &serial); err != nil {
+			rows.Close()
+			return "", withStack(errors.WithMessage(err, columnsQuery))
+		}
+		definitions = append(definitions, "  "+buildPostgresColumn(d.QuoteIdentifier(name), colType, notNull, colDefault, serial))
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return "", withStack(errors.WithMessage(err, columnsQuery))
+	}
+	if len(definitions) == 0 {
+		return "", errors.Errorf("table %s.%s not found", database, table)
+	}
+
+	const constraintsQuery = `SELECT con.conname, pg_catalog.pg_get_constraintdef(con.oid)
+		FROM pg_catalog.pg_constraint con
+		JOIN pg_catalog.pg_class c ON con.conrelid = c.oid
+		JOIN pg_catalog.pg_namespace n ON c.relnamespace = n.oid
+		WHERE n.nspname = $1 AND c.relname = $2
+		ORDER BY CASE con.contype WHEN 'p' THEN 0 WHEN 'u' THEN 1 WHEN 'c' THEN 2 ELSE 3 END, con.conname`
+	constraints, err := queryStringPairs(db, constraintsQuery, database, table)
+	if err != nil {
+		return "", err
+	}
+	for _, con := range constraints {
+		definitions = append(definitions, fmt.Sprintf("  CONSTRAINT %s %s", d.QuoteIdentifier(con[0]), con[1]))
+	}
+
+	const indexesQuery = `SELECT pg_catalog.pg_get_indexdef(i.indexrelid)
+		FROM pg_catalog.pg_index i
+		JOIN pg_catalog.pg_class c ON i.indrelid = c.oid
+		JOIN pg_catalog.pg_namespace n ON c.relnamespace = n.oid
+		WHERE n.nspname = $1 AND c.relname = $2
+		AND NOT EXISTS (SELECT 1 FROM pg_catalog.pg_constraint con WHERE con.conindid = i.indexrelid)
+		ORDER BY i.indexrelid`
+	indexes, err := queryStrings(db, indexesQuery, database, table)
+	if err != nil {
+		return "", err
+	}
+
+	var createTable strings.Builder
+	fmt.Fprintf(&createTable, "CREATE TABLE %s (\n%s\n)", d.QuoteIdentifier(table), strings.Join(definitions, ",\n"))
+	for _, index := range indexes {
+		createTable.WriteString(";\n")
+		createTable.WriteString(index)
+	}
+	return createTable.String(), nil
+}
+
+// buildPostgresColumn builds the definition of a column in CREATE TABLE.
+func buildPostgresColumn(name, colType string, notNull bool, colDefault string, serial bool) string {
+	if serial && strings.HasPrefix(colDefault, "nextval(") {
+		switch colType {
+		case "smallint":
+			colType, colDefault = "smallserial", ""
+		case "integer":
+			colType, colDefault = "serial", ""
+		case "bigint":
+			colType, colDefault = "bigserial", ""
+		}
+	}
+	column := name + " " + colType
+	if notNull {
+		column += " NOT NULL"
+	}
+	if colDefault != "" {
+		column += " DEFAULT " + colDefault
+	}
+	return column
+}
+
+func (d postgresDialect) ShowCreateView(db *sql.DB, database, view string) (string, error) {
+	const query = `SELECT pg_catalog.pg_get_viewdef(c.oid)
+		FROM pg_catalog.pg_class c JOIN pg_catalog.pg_namespace n ON c.relnamespace = n.oid
+		WHERE n.nspname = $1 AND c.relname = $2`
+	var viewDef string
+	if err := db.QueryRow(query, database, view).Scan(&viewDef); err != nil {
+		return "", withStack(errors.WithMessage(err, query))
+	}
+	viewDef = strings.TrimSuffix(strings.TrimSpace(viewDef), ";")
+	return fmt.Sprintf("CREATE VIEW %s AS\n%s", d.QuoteIdentifier(view), viewDef), nil
+}
+
+func (d postgresDialect) SelectField(db *sql.DB, database, table string) (string, error) {
+	const query = "SELECT column_name, is_generated FROM information_schema.columns " +
+		"WHERE table_schema = $1 AND table_name = $2 ORDER BY ordinal_position"
+	columns, err := queryStringPairs(db, query, database, table)
+	if err != nil {
+		return "", err
+	}
+	availableFields := make([]string, 0, len(columns))
+	hasGenerateColumn := false
+	for _, col := range columns {
+		if col[1] == "ALWAYS" {
+			hasGenerateColumn = true
+			continue
+		}
+		availableFields = append(availableFields, d.QuoteIdentifier(col[0]))
+	}
+	if hasGenerateColumn {
+		return strings.Join(availableFields, ","), nil
+	}
+	return "*", nil
+}
+
+// OrderByClause sorts the rows by the primary key, the tables without primary
+// key are not sorted.
+func (d postgresDialect) OrderByClause(conf *Config, db *sql.DB, database, table string) (string, error) {
+	if !conf.SortByPk {
+		return "", nil
+	}
+	pkColumns, err := d.PrimaryKeyColumns(db, database, table)
+	if err != nil {
+		return "", err
+	}
+	if len(pkColumns) == 0 {
+		return "", nil
+	}
+	quotedColumns := make([]string, 0, len(pkColumns))
+	for _, col := range pkColumns {
+		quotedColumns = append(quotedColumns, d.QuoteIdentifier(col))
+	}
+	return "ORDER BY " + strings.Join(quotedColumns, ","), nil
+}
+
+func (postgresDialect) PrimaryKeyColumns(db *sql.DB, database, table string) ([]string, error) {
+	const query = `SELECT kcu.column_name FROM information_schema.table_constraints tc
+		JOIN information_schema.key_column_usage kcu ON tc.constraint_schema = kcu.constraint_schema
+		AND tc.constraint_name = kcu.constraint_name AND tc.table_name = kcu.table_name
+		WHERE tc.constraint_type = 'PRIMARY KEY' AND tc.table_schema = $1 AND tc.table_name = $2
+		ORDER BY kcu.ordinal_position`
+	return queryStrings(db, query, database, table)
+}
+
+// ListColumns maps the types of PostgreSQL to the names of MySQL, the types
+// without counterparts are text.
+func (postgresDialect) ListColumns(db *sql.DB, database, table string) ([]ColumnInfo, error) {
+	const query = `SELECT column_name, data_type, is_nullable, character_maximum_length, numeric_precision, numeric_scale
+		FROM information_schema.columns WHERE table_schema = $1 AND table_name = $2 ORDER BY ordinal_position`
+	rows, err := db.Query(query, database, table)
+	if err != nil {
+		return nil, withStack(errors.WithMessage(err, query))
+	}
+	defer rows.Close()
+
+	var columns []ColumnInfo
+	for rows.Next() {
+		var (
+			col                      ColumnInfo
+			dataType, nullable       string
+			length, precision, scale sql.NullInt64
+		)
+		if err := rows.Scan(&col.Name, &dataType, &nullable, &length, &precision, &scale); err != nil {
+			return nil, withStack(errors.WithMessage(err, query))
+		}
+		col.Nullable = nullable == "YES"
+		col.DataType = postgresDataTypes[dataType]
+		switch {
+		case col.DataType == "":
+			col.DataType = "text"
+		case dataType == "uuid":
+			col.Length = 36
+		case col.DataType == "varchar" && !length.Valid:
+			col.DataType = "text"
+		case col.DataType == "char" || col.DataType == "varchar":
+			col.Length = length.Int64
+		case col.DataType == "decimal" && precision.Valid:
+			col.Precision, col.Scale = precision.Int64, scale.Int64
+		}
+		columns = append(columns, col)
+	}
+	return columns, withStack(rows.Err())
+}
+
+var postgresDataTypes = map[string]string{
+	"smallint":                    "smallint",
+	"integer":                     "int",
+	"bigint":                      "bigint",
+	"numeric":                     "decimal",
+	"real":                        "float",
+	"double precision":            "double",
+	"boolean":                     "bool",
+	"character varying":           "varchar",
+	"character":                   "char",
+	"uuid":                        "char",
+	"text":                        "text",
+	"bytea":                       "blob",
+	"date":                        "date",
+	"time without time zone":      "time",
+	"time with time zone":         "time",
+	"timestamp without time zone": "datetime",
+	"timestamp with time zone":    "datetime",
+	"json":                        "json",
+	"jsonb":                       "json",
+}
+
+// SelectWALPosition returns the current WAL location of PostgreSQL, which is
+// recorded as the position of the dump.
+func SelectWALPosition(db *sql.DB) (string, error) {
+	var lsn string
+	const query = "SELECT pg_catalog.pg_current_wal_lsn()"
+	if err := db.QueryRow(query).Scan(&lsn); err != nil {
+		return "", withStack(errors.WithMessage(err, query))
+	}
+	return lsn, nil
+}
+
+// parsePostgresVersion parses the version of `SELECT version()` of PostgreSQL,
+// e.g. "PostgreSQL 12.4 on x86_64-pc-linux-gnu, ...".
+func parsePostgresVersion(src string) string {
+	fields := strings.Fields(src)
+	if len(fields) < 2 {
+		return ""
+	}
+	parts := strings.Split(strings.TrimRight(fields[1], ","), ".")
+	for _, part := range parts {
+		if _, err := strconv.Atoi(part); err != nil {
+			return ""
+		}
+	}
+	for len(parts) < 3 {
+		parts = append(parts, "0")
+	}
+	return strings.Join(parts, ".")
+}
+
+func queryStrings(db *sql.DB, query string, args ...interface{}) ([]string, error) {
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		return nil, withStack(errors.WithMessage(err, query))
+	}
+	defer rows.Close()
+	var res oneStrColumnTable
+	for rows.Next() {
+		if err := res.handleOneRow(rows); err != nil {
+			return nil, errors.WithMessage(err, query)
+		}
+	}
+	return res.data, withStack(rows.Err())
+}
+
+func queryStringPairs(db *sql.DB, query string, args ...interface{}) ([][2]string, error) {
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		return nil, withStack(errors.WithMessage(err, query))
+	}
+	defer rows.Close()
+	var res [][2]string
+	for rows.Next() {
+		var oneRow [2]string
+		if err := rows.Scan(&oneRow[0], &oneRow[1]); err != nil {
+			return nil, withStack(errors.WithMessage(err, query))
+		}
+		res = append(res, oneRow)
+	}
+	return res, withStack(rows.Err())
+}
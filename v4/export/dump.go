@@ -3,115 +3,447 @@ package export
 import (
 	"context"
 	"database/sql"
+	"io"
+	"os"
 	"strings"
 	"time"
 
 	"github.com/pingcap/dumpling/v4/log"
 
 	_ "github.com/go-sql-driver/mysql"
+	"go.opentelemetry.io/otel/attribute"
 	"go.uber.org/zap"
 	"golang.org/x/sync/errgroup"
 )
 
-func Dump(conf *Config) (err error) {
+// Dump dumps the databases as configured by conf. It's the entrypoint for both
+// the CLI and the Go programs embedding dumpling. Canceling ctx stops the dump,
+// the in-flight tables and chunks return the error of ctx.
+func Dump(ctx context.Context, conf *Config) (err error) {
+	defer func() {
+		conf.hooks().OnDumpFinish(err)
+	}()
 	if err = adjustConfig(conf); err != nil {
-		return withStack(err)
+		return withStack(withKind(ErrorKindConfig, err))
+	}
+	if len(conf.Shards) > 0 {
+		return dumpShards(ctx, conf)
+	}
+	ctx, endDumpSpan := startDumpSpan(ctx, conf)
+	defer func() {
+		endDumpSpan(err)
+	}()
+	defer func() {
+		if cmdErr := runAfterDumpCommand(conf, err); err == nil {
+			err = cmdErr
+		}
+		notifyDumpFinished(conf, err)
+	}()
+
+	if conf.ExternalStorage, err = newStorage(conf); err != nil {
+		return err
+	}
+	if conf.ExternalStorage, err = newRoutedStorage(conf, conf.ExternalStorage); err != nil {
+		return err
+	}
+	if conf.shard == nil {
+		// the summary of the shards is written after all of them
+		defer func() {
+			if err := writeSummary(conf); err != nil {
+				log.Error("write summary failed", zap.Error(err))
+			}
+		}()
+	}
+	if conf.IncrementalColumn != "" {
+		if conf.incremental, err = loadIncrementalState(conf.incrementalStatePath()); err != nil {
+			return err
+		}
+	}
+	if conf.Append {
+		if conf.manifest, err = loadManifest(conf.OutputDirPath); err != nil {
+			return err
+		}
+	}
+
+	if conf.StateFile != "" {
+		stateCtx, stopStateFile := context.WithCancel(ctx)
+		stateFileDone := make(chan struct{})
+		go func() {
+			runStateFileWriter(stateCtx, conf, defaultStateFileInterval)
+			close(stateFileDone)
+		}()
+		// stopped after the controller is finished, so that the last state is written
+		defer func() {
+			conf.Progress.recordError(err)
+			stopStateFile()
+			<-stateFileDone
+		}()
+	}
+	if conf.shard == nil {
+		// the controller of the shards is finished after all of them
+		defer func() {
+			conf.Controller.finish(err)
+		}()
 	}
 
 	go func() {
 		if conf.StatusAddr != "" {
-			err1 := startDumplingService(conf.StatusAddr)
+			err1 := startDumplingService(conf)
 			if err1 != nil {
 				log.Error("dumpling stops to serving service", zap.Error(err1))
 			}
 		}
 	}()
-	pool, err := sql.Open("mysql", conf.getDSN(""))
+	pool, session, err := openConnPool(conf, conf.dialect().DSN(conf))
 	if err != nil {
-		return withStack(err)
+		return withStack(withKind(ErrorKindConnection, err))
 	}
 	defer pool.Close()
+	conf.session = session
 
 	conf.ServerInfo, err = detectServerInfo(pool)
 	if err != nil {
-		return err
+		// it's the first query to the server
+		return withKind(ErrorKindConnection, err)
 	}
+	if err = conf.validateServer(); err != nil {
+		return withKind(ErrorKindConfig, err)
+	}
+	session.keepStatements(pool, conf.sessionStatements())
 
 	databases, err := prepareDumpingDatabases(conf, pool)
 	if err != nil {
-		return err
+		return withKind(ErrorKindSchema, err)
 	}
 
-	conf.Tables, err = listAllTables(pool, databases)
+	conf.Tables, err = listAllTables(conf.dialect(), pool, databases)
 	if err != nil {
-		return err
+		return withKind(ErrorKindSchema, err)
 	}
 
-	if !conf.NoViews {
-		views, err := listAllViews(pool, databases)
+	if !conf.NoViews || conf.onlyObject(ObjectViews) {
+		views, err := listAllViews(conf.dialect(), pool, databases)
 		if err != nil {
-			return err
+			return withKind(ErrorKindSchema, err)
 		}
 		conf.Tables.Merge(views)
 	}
+	if !conf.NoSequences && !conf.dumpsSequences() {
+		warnSkippedSequences(conf, pool, databases)
+	}
 
 	err = filterTables(conf)
 	if err != nil {
 		return err
 	}
 
+	if len(conf.OnlyObjects) == 0 {
+		if err = estimateProgress(pool, conf.Progress, conf.Tables); err != nil {
+			log.Warn("estimate dump progress failed", zap.Error(err))
+		}
+	}
+	if conf.CheckFreeSpace {
+		if err = checkFreeSpace(conf, conf.Progress.Status().EstimatedBytes); err != nil {
+			return err
+		}
+	}
+
+	if conf.MaxReplicaLag != UnspecifiedSize {
+		wait := time.Duration(conf.ReplicaLagWait) * time.Second
+		if err = checkReplicaLag(ctx, pool, conf.MaxReplicaLag, wait, defaultReplicaLagCheckInterval); err != nil {
+			return withKind(ErrorKindConsistency, err)
+		}
+	}
+	var replica *replicaStatus
+	if conf.StopReplicaSQLThread {
+		if replica, err = stopReplicaSQLThread(pool); err != nil {
+			return withKind(ErrorKindConsistency, err)
+		}
+		if replica.stopped {
+			defer func() {
+				if err := StartSlaveSQLThread(pool); err != nil {
+					log.Error("start replica SQL thread failed, please start it manually", zap.Error(err))
+				}
+			}()
+		}
+	}
+
 	conCtrl, err := NewConsistencyController(conf, pool)
 	if err != nil {
+		return withKind(ErrorKindConfig, err)
+	}
+	if holdsLocks(conf.Consistency) && conf.ConnIdleTimeout != UnspecifiedSize {
+		log.Warn("ignore conn-idle-timeout since the locks are held by an idle connection",
+			zap.String("consistency", conf.Consistency))
+	} else {
+		setConnIdleTimeout(pool, conf.ConnIdleTimeout)
+	}
+	if err = runHookSQL(ctx, conf, pool, "before consistency", conf.BeforeConsistencySQL); err != nil {
 		return err
 	}
-	if err = conCtrl.Setup(); err != nil {
+	consistencyCtx, span := startSpan(ctx, "consistency", attribute.String("consistency", conf.Consistency))
+	err = conCtrl.Setup(consistencyCtx)
+	endSpan(span, err)
+	if err != nil {
+		return withKind(ErrorKindConsistency, err)
+	}
+	if err = runHookSQL(ctx, conf, pool, "after consistency", conf.AfterConsistencySQL); err != nil {
+		return err
+	}
+	if conf.StaleRead != UnspecifiedSize {
+		conf.staleReadSnapshot = consistencySnapshot(conCtrl)
+	}
+	if conf.replicas, err = openReadReplicas(ctx, conf, consistencySnapshot(conCtrl)); err != nil {
 		return err
 	}
+	defer conf.replicas.Close()
 
-	m := newGlobalMetadata(conf.OutputDirPath)
+	m := newGlobalMetadata(conf.ExternalStorage)
+	conf.downgrades = newChunkDowngrades()
+	m.recordChunkDowngrades(conf.downgrades)
 	// write metadata even if dump failed
-	defer m.writeGlobalMetaData()
+	if conf.shard != nil {
+		defer conf.shard.metadata.record(conf.shard.name, m)
+	} else {
+		defer m.writeGlobalMetaData()
+	}
 	m.recordStartTime(time.Now())
-	err = m.getGlobalMetaData(pool, conf.ServerInfo.ServerType)
+	if conf.Retention != "" {
+		m.recordRetentionGroup(retentionGroup(conf))
+	}
+	m.recordReplicaStatus(replica)
+	if conf.MaxLockTime != UnspecifiedSize && holdsLocks(conf.Consistency) {
+		var abortDump context.CancelFunc
+		ctx, abortDump = context.WithCancel(ctx)
+		defer abortDump()
+		guard := startLockGuard(conCtrl, time.Duration(conf.MaxLockTime)*time.Second, conf.DowngradeOnMaxLockTime,
+			abortDump, func() { m.recordDowngradeTime(time.Now()) })
+		defer func() {
+			if guardErr := guard.stop(); guardErr != nil && err != nil {
+				err = guardErr
+			}
+		}()
+	}
+	if conf.CaptureWarnings {
+		conf.warnings = newWarningRecorder(conf.StrictWarnings)
+		defer func() {
+			if err := conf.warnings.writeReport(conf.ExternalStorage); err != nil {
+				log.Error("write warnings report failed", zap.Error(err))
+			}
+		}()
+	}
+	if conf.Checksums {
+		conf.checksums = newChecksumRecorder()
+		defer func() {
+			if err := conf.checksums.writeChecksums(conf.ExternalStorage); err != nil {
+				log.Error("write checksums failed", zap.Error(err))
+			}
+		}()
+	}
+	err = m.getGlobalMetaData(pool, conf.ServerInfo)
 	if err != nil {
 		log.Info("get global metadata failed", zap.Error(err))
 	}
+	if conf.staleReadSnapshot != "" {
+		// the data are read as of the stale snapshot rather than now
+		m.pos = conf.staleReadSnapshot
+	}
+	var binlogStart binlogPosition
+	if conf.BinlogStop != "" {
+		if binlogStart, err = binlogTailStart(conf, m); err != nil {
+			return err
+		}
+	}
+	if conf.ReplicationMeta != "" {
+		if err = checkReplicationSource(conf); err != nil {
+			return err
+		}
+	}
+	if conf.OrderByForeignKey {
+		restoreOrder, err := listRestoreOrder(pool, conf.Tables)
+		if err != nil {
+			return withKind(ErrorKindSchema, err)
+		}
+		m.recordRestoreOrder(restoreOrder)
+	}
+	if conf.RestorePlan {
+		if conf.restorePlan, err = newRestorePlanRecorder(conf, pool); err != nil {
+			return withKind(ErrorKindSchema, err)
+		}
+		// the plan is only written if all the files are dumped
+		defer func() {
+			if err == nil {
+				err = conf.restorePlan.writePlan(conf, conf.ExternalStorage)
+			}
+		}()
+	}
+	// the schemas of the shards are deduplicated together by dumpShards
+	if conf.DedupSchemas && conf.shard == nil {
+		conf.schemaDedup = newSchemaDedupRecorder()
+		defer func() {
+			if err := conf.schemaDedup.write(conf, conf.ExternalStorage); err != nil {
+				log.Error("write schema dedup failed", zap.Error(err))
+			}
+		}()
+	}
+	if conf.Subset {
+		if conf.Consistency == "none" {
+			log.Warn("the subset may not be referentially intact without consistency")
+		}
+		if conf.subset, err = planSubset(conf, pool); err != nil {
+			return withKind(ErrorKindSchema, err)
+		}
+	}
+
+	conf.Progress.start(time.Now())
+	conf.hooks().OnDumpStart(conf.Tables)
+	if conf.ShowProgress {
+		renderCtx, stopRender := context.WithCancel(ctx)
+		renderDone := make(chan struct{})
+		go func() {
+			renderProgress(renderCtx, conf.Controller, os.Stderr, defaultProgressRefresh)
+			close(renderDone)
+		}()
+		defer func() {
+			stopRender()
+			<-renderDone
+		}()
+	}
+
+	if interval := conf.keepaliveInterval(); interval > 0 {
+		keepaliveCtx, stopKeepalive := context.WithCancel(ctx)
+		keepaliveDone := make(chan struct{})
+		go func() {
+			runKeepalive(keepaliveCtx, pool, interval)
+			close(keepaliveDone)
+		}()
+		defer func() {
+			stopKeepalive()
+			<-keepaliveDone
+		}()
+	}
+	if conf.MaxThreadsRunning != UnspecifiedSize {
+		monitorCtx, stopMonitor := context.WithCancel(ctx)
+		monitorDone := make(chan struct{})
+		go func() {
+			newLoadMonitor(conf, pool).run(monitorCtx, defaultLoadCheckInterval)
+			close(monitorDone)
+		}()
+		defer func() {
+			stopMonitor()
+			<-monitorDone
+		}()
+	}
+
+	// a write error aborts the other workers at once, it's returned instead of their errors
+	var abortWrites context.CancelFunc
+	ctx, abortWrites = context.WithCancel(ctx)
+	defer abortWrites()
+	conf.writeFailure = newWriteFailure(abortWrites)
+	defer func() {
+		if writeErr := conf.writeFailure.Err(); writeErr != nil && err != nil {
+			err = writeErr
+		}
+	}()
 
 	var writer Writer
 	switch strings.ToLower(conf.FileType) {
 	case "sql":
-		writer, err = NewSimpleWriter(conf)
+		if conf.TargetDSN != "" {
+			writer, err = NewTargetWriter(conf)
+		} else {
+			writer, err = NewSimpleWriter(conf)
+		}
 	case "csv":
 		writer, err = NewCsvWriter(conf)
+	case "tsv":
+		writer, err = NewTsvWriter(conf)
+	case "sqlite":
+		writer, err = NewSQLiteWriter(conf)
+	case "kafka":
+		writer, err = NewKafkaWriter(conf, pool)
 	}
 	if err != nil {
 		return err
 	}
+	if writer, err = newRoutedWriter(conf, writer); err != nil {
+		return err
+	}
+	if closer, ok := writer.(io.Closer); ok {
+		defer func() {
+			if aborter, ok := writer.(Aborter); ok && err != nil {
+				_ = aborter.Abort()
+			} else if closeErr := closer.Close(); err == nil {
+				err = closeErr
+			}
+		}()
+	}
 
-	if conf.Sql == "" {
-		if err = dumpDatabases(context.Background(), conf, pool, writer); err != nil {
+	switch {
+	case len(conf.OnlyObjects) > 0:
+		if err = dumpObjects(ctx, conf, pool, writer); err != nil {
 			return err
 		}
-	} else {
-		if err = dumpSql(context.Background(), conf, pool, writer); err != nil {
+	case conf.Sql == "":
+		if err = dumpDatabases(ctx, conf, pool, writer); err != nil {
+			return err
+		}
+	default:
+		if err = dumpSql(ctx, conf, pool, writer); err != nil {
+			return err
+		}
+	}
+	if conf.dumpsSequences() && conf.ServerInfo.Capabilities().Sequences {
+		if err = dumpSequences(ctx, conf, pool); err != nil {
+			return err
+		}
+	}
+	if conf.TiDBReplicas {
+		if err = dumpTiDBReplicas(ctx, conf, pool); err != nil {
 			return err
 		}
 	}
 
 	m.recordFinishTime(time.Now())
 
-	return conCtrl.TearDown()
+	if err = conCtrl.TearDown(); err != nil {
+		return withKind(ErrorKindConsistency, err)
+	}
+	if conf.BinlogStop != "" {
+		binlogStop, err := tailBinlog(ctx, conf, pool, binlogStart, defaultBinlogPollInterval)
+		if err != nil {
+			return err
+		}
+		m.recordBinlogTail(binlogStop)
+	}
+	if conf.ReplicationMeta != "" {
+		if err = writeReplicationMeta(ctx, conf, m, consistencySnapshot(conCtrl)); err != nil {
+			return err
+		}
+	}
+	if err = conf.incremental.save(conf); err != nil {
+		return err
+	}
+	return pruneDumps(ctx, conf, time.Now())
 }
 
 func dumpDatabases(ctx context.Context, conf *Config, db *sql.DB, writer Writer) error {
 	allTables := conf.Tables
 	for dbName, tables := range allTables {
-		createDatabaseSQL, err := ShowCreateDatabase(db, dbName)
+		createDatabaseSQL, err := showCreateDatabase(conf, db, dbName)
 		if err != nil {
-			return err
+			return withKind(ErrorKindSchema, err)
 		}
-		if err := writer.WriteDatabaseMeta(ctx, dbName, createDatabaseSQL); err != nil {
-			return err
+		if createDatabaseSQL != "" && conf.ExplicitCollations {
+			if createDatabaseSQL, err = explicitDatabaseCollation(conf, db, dbName, createDatabaseSQL); err != nil {
+				return withKind(ErrorKindSchema, err)
+			}
+		}
+		// the targets without databases don't have the database meta
+		if createDatabaseSQL != "" {
+			if err := writer.WriteDatabaseMeta(ctx, dbName, createDatabaseSQL); err != nil {
+				return err
+			}
 		}
 
 		if len(tables) == 0 {
@@ -124,6 +456,9 @@ func dumpDatabases(ctx context.Context, conf *Config, db *sql.DB, writer Writer)
 			g.Go(func() error {
 				rateLimit.getToken()
 				defer rateLimit.putToken()
+				if err := conf.Controller.wait(ctx); err != nil {
+					return err
+				}
 				return dumpTable(ctx, conf, db, dbName, table, writer)
 			})
 		}
@@ -135,50 +470,176 @@ func dumpDatabases(ctx context.Context, conf *Config, db *sql.DB, writer Writer)
 }
 
 func dumpSql(ctx context.Context, conf *Config, db *sql.DB, writer Writer) error {
-	tableIR, err := SelectFromSql(conf, db)
+	tableIR, err := SelectFromSql(ctx, conf, db)
 	if err != nil {
 		return err
 	}
 
-	return writer.WriteTableData(ctx, tableIR)
+	return writeTableData(ctx, conf, writer, tableIR)
+}
+
+// writeTableData writes a chunk of table data and records it into progress.
+func writeTableData(ctx context.Context, conf *Config, writer Writer, ir TableDataIR) (err error) {
+	ctx, span := startSpan(ctx, "chunk",
+		attribute.String("database", ir.DatabaseName()),
+		attribute.String("table", ir.TableName()),
+		attribute.Int("chunk", ir.ChunkIndex()))
+	defer func() {
+		endSpan(span, err)
+	}()
+	conf.Progress.addChunk()
+	log.Debug("start dumping chunk",
+		zap.String("database", ir.DatabaseName()),
+		zap.String("table", ir.TableName()),
+		zap.Int("chunk", ir.ChunkIndex()))
+	start := time.Now()
+	// the checksums are of the rows read from the source, before they're filtered and rewritten
+	sum, data := withChecksum(conf, ir)
+	filtered, data, err := withRowFilter(conf, data)
+	if err != nil {
+		return err
+	}
+	data = withCanonicalJSON(conf, withZeroDates(conf, data))
+	if data, err = withColumnRewrites(conf, data); err != nil {
+		return err
+	}
+	if conf.FilesPerChunk > 1 && ir.TableName() != "" {
+		err = writeFanOut(ctx, withProgress(data, conf.Progress), conf.FilesPerChunk, fanOutBatchBytes, writer.WriteTableData)
+	} else {
+		err = writer.WriteTableData(ctx, withProgress(data, conf.Progress))
+	}
+	// the writers may stop silently at the first row failing the filter
+	if filtered != nil && err == nil {
+		err = filtered.err
+	}
+	err = chunkTimeoutError(conf, ir, err)
+	// the warnings are always taken to release the connection of ir
+	if warnErr := conf.warnings.record(ctx, ir); err == nil {
+		err = warnErr
+	}
+	if err != nil {
+		return err
+	}
+	conf.checksums.record(conf, ir, sum)
+	conf.Progress.finishChunk()
+	log.Debug("finish dumping chunk",
+		zap.String("database", ir.DatabaseName()),
+		zap.String("table", ir.TableName()),
+		zap.Int("chunk", ir.ChunkIndex()),
+		zap.Duration("cost", time.Since(start)))
+	conf.hooks().OnChunkFinish(ir.DatabaseName(), ir.TableName(), ir.ChunkIndex())
+	return nil
 }
 
-func dumpTable(ctx context.Context, conf *Config, db *sql.DB, dbName string, table *TableInfo, writer Writer) error {
+func dumpTable(ctx context.Context, conf *Config, db *sql.DB, dbName string, table *TableInfo, writer Writer) (err error) {
+	ctx, span := startSpan(ctx, "table", attribute.String("database", dbName), attribute.String("table", table.Name))
+	defer func() {
+		endSpan(span, err)
+	}()
+	if conf.manifest.skip(conf, dbName, table.Name) {
+		log.Info("skip the table completed in the manifest",
+			zap.String("database", dbName), zap.String("table", table.Name))
+		if table.Type != TableTypeView {
+			conf.Progress.finishTable(dbName, table.Name)
+		}
+		return nil
+	}
+	conf.hooks().OnTableStart(dbName, table.Name)
+	if table.Type != TableTypeView {
+		conf.Progress.startTable(dbName, table.Name)
+	}
+	if err = dumpTableSchemaAndData(ctx, conf, db, dbName, table, writer); err != nil {
+		conf.Progress.recordError(err)
+		// the schema files are written without the pipes, which abort the dump by themselves
+		if ErrorKindOf(err) == ErrorKindWrite {
+			conf.writeFailure.fail(err)
+		}
+		return err
+	}
+	if err = conf.manifest.complete(conf, dbName, table.Name); err != nil {
+		return err
+	}
+	if table.Type != TableTypeView {
+		conf.Progress.finishTable(dbName, table.Name)
+	}
+	return nil
+}
+
+func dumpTableSchemaAndData(ctx context.Context, conf *Config, db *sql.DB, dbName string, table *TableInfo, writer Writer) error {
 	tableName := table.Name
+	conf = conf.forTable(dbName, tableName)
+	if conf.incremental != nil && table.Type != TableTypeView && !conf.NoData {
+		var err error
+		if conf, err = conf.forIncrementalTable(db, dbName, tableName); err != nil {
+			return err
+		}
+	}
 	if !conf.NoSchemas {
 		if table.Type == TableTypeView {
-			viewName := table.Name
-			createViewSQL, err := ShowCreateView(db, dbName, viewName)
-			if err != nil {
-				return err
-			}
-			return writer.WriteTableMeta(ctx, dbName, viewName, createViewSQL)
+			return dumpView(ctx, conf, db, dbName, table.Name, writer)
 		}
-		createTableSQL, err := ShowCreateTable(db, dbName, tableName)
+		createTableSQL, err := showCreateTable(conf, db, dbName, tableName)
 		if err != nil {
-			return err
+			return withKind(ErrorKindSchema, err)
+		}
+		if conf.VerifyComments {
+			if createTableSQL, err = verifyComments(db, dbName, tableName, createTableSQL); err != nil {
+				return withKind(ErrorKindSchema, err)
+			}
+		}
+		if conf.ExplicitCollations {
+			if createTableSQL, err = explicitTableCollations(conf, db, dbName, tableName, createTableSQL); err != nil {
+				return withKind(ErrorKindSchema, err)
+			}
 		}
 		if err := writer.WriteTableMeta(ctx, dbName, tableName, createTableSQL); err != nil {
 			return err
 		}
+		if conf.BigQuerySchema {
+			if err := writeBigQuerySchema(ctx, conf, db, dbName, tableName); err != nil {
+				return err
+			}
+		}
+		if conf.HiveLocation != "" {
+			if err := writeHiveCreateTable(ctx, conf, db, dbName, tableName); err != nil {
+				return err
+			}
+		}
 	}
 	// Do not dump table data and return nil
 	if conf.NoData {
 		return nil
 	}
 
+	if conf.ServerOutfileDir != "" {
+		return dumpTableOutfile(ctx, conf, db, dbName, tableName)
+	}
 	if conf.Rows != UnspecifiedSize {
-		finished, err := concurrentDumpTable(ctx, writer, conf, db, dbName, tableName)
+		finished, err := dumpTableChunks(ctx, writer, conf, db, dbName, tableName)
 		if err != nil || finished {
 			return err
 		}
 	}
-	tableIR, err := SelectAllFromTable(conf, db, dbName, tableName)
+	tableIR, err := SelectAllFromTable(ctx, conf, db, dbName, tableName)
 	if err != nil {
 		return err
 	}
 
-	return writer.WriteTableData(ctx, tableIR)
+	return writeTableData(ctx, conf, writer, tableIR)
+}
+
+func dumpView(ctx context.Context, conf *Config, db *sql.DB, dbName, viewName string, writer Writer) error {
+	if conf.translatesDDL() {
+		log.Warn("skip the view which can't be translated to the target dialect",
+			zap.String("database", dbName), zap.String("view", viewName),
+			zap.String("target dialect", conf.targetDialect()))
+		return nil
+	}
+	createViewSQL, err := conf.dialect().ShowCreateView(db, dbName, viewName)
+	if err != nil {
+		return withKind(ErrorKindSchema, err)
+	}
+	return writer.WriteTableMeta(ctx, dbName, viewName, createViewSQL)
 }
 
 func concurrentDumpTable(ctx context.Context, writer Writer, conf *Config, db *sql.DB, dbName string, tableName string) (bool, error) {
@@ -190,6 +651,8 @@ func concurrentDumpTable(ctx context.Context, writer Writer, conf *Config, db *s
 	ctx1, cancel1 := context.WithCancel(ctx)
 	defer cancel1()
 	var g errgroup.Group
+	// the number of the chunks started to be dumped
+	dispatched := 0
 	g.Go(func() error {
 		splitTableDataIntoChunks(ctx1, chunksIterCh, errCh, linear, dbName, tableName, db, conf)
 		return nil
@@ -199,22 +662,44 @@ Loop:
 	for {
 		select {
 		case <-ctx.Done():
-			return true, nil
+			// wait for the in-flight chunks to finish
+			cancel1()
+			_ = g.Wait()
+			return true, ctx.Err()
 		case <-linear:
 			return false, nil
 		case chunksIter, ok := <-chunksIterCh:
 			if !ok {
 				break Loop
 			}
+			if err := conf.Controller.wait(ctx); err != nil {
+				// wait for the in-flight chunks to finish
+				cancel1()
+				_ = g.Wait()
+				return true, err
+			}
+			dispatched++
 			g.Go(func() error {
-				return writer.WriteTableData(ctx, chunksIter)
+				return writeTableData(ctx, conf, writer, chunksIter)
 			})
 		case err := <-errCh:
+			if dispatched == 0 {
+				return false, &chunkSplitError{err: err}
+			}
 			return false, err
 		}
 	}
 	if err := g.Wait(); err != nil {
 		return true, err
 	}
+	if dispatched == 0 && conf.writesEmptyTables() {
+		// the empty table is dumped without chunks, whose data file is written by EmptyTables
+		return false, nil
+	}
+	if dispatched == 0 {
+		if err := conf.checksums.recordEmpty(conf, db, dbName, tableName); err != nil {
+			return true, err
+		}
+	}
 	return true, nil
 }
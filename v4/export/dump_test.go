@@ -59,9 +59,9 @@ func (s *testDumpSuite) TestDumpDatabase(c *C) {
 	rows = mock.NewRows([]string{"column_name", "extra"}).AddRow("id", "").AddRow("name", "")
 	mock.ExpectQuery("SELECT COLUMN_NAME").WithArgs(sqlmock.AnyArg(), sqlmock.AnyArg()).WillReturnRows(rows)
 	rows = mock.NewRows([]string{"a"}).AddRow(1)
-	mock.ExpectQuery("SELECT (.) FROM test.t LIMIT 1").WillReturnRows(rows)
+	mock.ExpectQuery("SELECT (.) FROM `test`.`t` LIMIT 1").WillReturnRows(rows)
 	rows = mock.NewRows([]string{"a"}).AddRow(1).AddRow(2)
-	mock.ExpectQuery("SELECT (.) FROM test.t").WillReturnRows(rows)
+	mock.ExpectQuery("SELECT (.) FROM `test`.`t`").WillReturnRows(rows)
 
 	mockWriter := newMockWriter()
 	err = dumpDatabases(context.Background(), mockConfig, db, mockWriter)
@@ -73,6 +73,25 @@ func (s *testDumpSuite) TestDumpDatabase(c *C) {
 	c.Assert(mock.ExpectationsWereMet(), IsNil)
 }
 
+func (s *testDumpSuite) TestDumpDatabaseWithCanceledContext(c *C) {
+	mockConfig := DefaultConfig()
+	mockConfig.Database = "test"
+	mockConfig.Tables = NewDatabaseTables().AppendTables("test", "t")
+	db, mock, err := sqlmock.New()
+	c.Assert(err, IsNil)
+
+	rows := mock.NewRows([]string{"Database", "Create Database"}).AddRow("test", "CREATE DATABASE `test`")
+	mock.ExpectQuery("SHOW CREATE DATABASE test").WillReturnRows(rows)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	mockWriter := newMockWriter()
+	err = dumpDatabases(ctx, mockConfig, db, mockWriter)
+	c.Assert(err, Equals, context.Canceled)
+	c.Assert(mockWriter.tableMeta, HasLen, 0)
+	c.Assert(mock.ExpectationsWereMet(), IsNil)
+}
+
 func (s *testDumpSuite) TestDumpTable(c *C) {
 	mockConfig := DefaultConfig()
 	mockConfig.SortByPk = false
@@ -85,9 +104,9 @@ func (s *testDumpSuite) TestDumpTable(c *C) {
 	rows = mock.NewRows([]string{"column_name", "extra"}).AddRow("id", "").AddRow("name", "")
 	mock.ExpectQuery("SELECT COLUMN_NAME").WithArgs(sqlmock.AnyArg(), sqlmock.AnyArg()).WillReturnRows(rows)
 	rows = mock.NewRows([]string{"a"}).AddRow(1)
-	mock.ExpectQuery("SELECT (.) FROM test.t LIMIT 1").WillReturnRows(rows)
+	mock.ExpectQuery("SELECT (.) FROM `test`.`t` LIMIT 1").WillReturnRows(rows)
 	rows = mock.NewRows([]string{"a"}).AddRow(1).AddRow(2)
-	mock.ExpectQuery("SELECT (.) FROM test.t").WillReturnRows(rows)
+	mock.ExpectQuery("SELECT (.) FROM `test`.`t`").WillReturnRows(rows)
 
 	mockWriter := newMockWriter()
 	err = dumpTable(context.Background(), mockConfig, db, "test", &TableInfo{Name: "t"}, mockWriter)
@@ -129,12 +148,12 @@ func (s *testDumpSuite) TestDumpTableWhereClause(c *C) {
 	rows = mock.NewRows([]string{"column_name", "extra"}).AddRow("id", "").AddRow("name", "")
 	mock.ExpectQuery("SELECT COLUMN_NAME").WithArgs(sqlmock.AnyArg(), sqlmock.AnyArg()).WillReturnRows(rows)
 	rows = mock.NewRows([]string{"a"}).AddRow(1)
-	mock.ExpectQuery("SELECT (.) FROM test.t LIMIT 1").WillReturnRows(rows)
+	mock.ExpectQuery("SELECT (.) FROM `test`.`t` LIMIT 1").WillReturnRows(rows)
 	rows = mock.NewRows([]string{"a"})
 	for i := 4; i < 9; i++ {
 		rows.AddRow(i)
 	}
-	mock.ExpectQuery("SELECT (.) FROM test.t WHERE a > 3 and a < 9").WillReturnRows(rows)
+	mock.ExpectQuery("SELECT (.) FROM `test`.`t` WHERE a > 3 and a < 9").WillReturnRows(rows)
 
 	mockWriter := newMockWriter()
 	err = dumpTable(context.Background(), mockConfig, db, "test", &TableInfo{Name: "t"}, mockWriter)
@@ -163,3 +182,34 @@ func (s *testDumpSuite) TestDumpTableWhereClause(c *C) {
 	c.Assert(tbDataRes.Rows().HasNext(), IsFalse)
 	c.Assert(mock.ExpectationsWereMet(), IsNil)
 }
+
+func (s *testDumpSuite) TestDumpTableWithTableConfig(c *C) {
+	mockConfig := DefaultConfig()
+	mockConfig.SortByPk = false
+	mockConfig.Where = "a > 3"
+	mockConfig.TableConfigs = []TableConfig{
+		{Database: "test", Table: "other", Where: "a > 100"},
+		{Database: "test", Table: "t", Where: "a < 2"},
+	}
+	db, mock, err := sqlmock.New()
+	c.Assert(err, IsNil)
+
+	showCreateTableResult := "CREATE TABLE t (a INT)"
+	rows := mock.NewRows([]string{"Table", "Create Table"}).AddRow("t", showCreateTableResult)
+	mock.ExpectQuery("SHOW CREATE TABLE test.t").WillReturnRows(rows)
+	rows = mock.NewRows([]string{"column_name", "extra"}).AddRow("id", "").AddRow("name", "")
+	mock.ExpectQuery("SELECT COLUMN_NAME").WithArgs(sqlmock.AnyArg(), sqlmock.AnyArg()).WillReturnRows(rows)
+	rows = mock.NewRows([]string{"a"}).AddRow(1)
+	mock.ExpectQuery("SELECT (.) FROM `test`.`t` LIMIT 1").WillReturnRows(rows)
+	rows = mock.NewRows([]string{"a"}).AddRow(1)
+	mock.ExpectQuery("SELECT (.) FROM `test`.`t` WHERE a < 2").WillReturnRows(rows)
+
+	mockWriter := newMockWriter()
+	err = dumpTable(context.Background(), mockConfig, db, "test", &TableInfo{Name: "t"}, mockWriter)
+	c.Assert(err, IsNil)
+	c.Assert(mockWriter.tableData, HasLen, 1)
+	c.Assert(mock.ExpectationsWereMet(), IsNil)
+	// the overridden options don't leak to other tables
+	c.Assert(mockConfig.Where, Equals, "a > 3")
+	c.Assert(mockConfig.forTable("test", "t2"), Equals, mockConfig)
+}
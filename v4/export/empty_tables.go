@@ -0,0 +1,63 @@
+package export
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"strings"
+)
+
+const (
+	// EmptyTablesNone writes no data file for the tables without any rows.
+	EmptyTablesNone = "none"
+	// EmptyTablesEmpty writes an empty data file for the tables without any rows.
+	EmptyTablesEmpty = "empty"
+	// EmptyTablesHeader writes a data file with only the header for the tables
+	// without any rows, which is the special comments and footers of sql, or
+	// the column names of csv and tsv unless NoHeader.
+	EmptyTablesHeader = "header"
+)
+
+// emptyTablesConflicts returns the options conflicting with EmptyTables.
+func emptyTablesConflicts(conf *Config) []string {
+	switch conf.EmptyTables {
+	case "", EmptyTablesNone:
+		return nil
+	case EmptyTablesEmpty, EmptyTablesHeader:
+	default:
+		return []string{fmt.Sprintf("invalid empty-tables option %s", conf.EmptyTables)}
+	}
+	switch strings.ToLower(conf.FileType) {
+	case "sql", "csv", "tsv":
+		if conf.TargetDSN != "" || conf.ServerOutfileDir != "" {
+			return []string{"empty-tables is not supported with target-dsn or server-outfile-dir"}
+		}
+		return nil
+	default:
+		return []string{"empty-tables is only supported with filetype sql, csv and tsv"}
+	}
+}
+
+// writesEmptyTables returns whether the tables without any rows have data files.
+func (conf *Config) writesEmptyTables() bool {
+	return conf.EmptyTables == EmptyTablesEmpty || conf.EmptyTables == EmptyTablesHeader
+}
+
+// writeEmptyTable writes the data file of ir into w by conf.EmptyTables, if
+// nothing is written into the first data file of ir. Only the tables dumped
+// without chunks are written, the empty chunks of a table never have files.
+// It returns whether the file is written.
+func writeEmptyTable(conf *Config, ir TableDataIR, w io.Writer, header func(*bytes.Buffer)) (bool, error) {
+	if !conf.writesEmptyTables() || ir.TableName() == "" || ir.ChunkIndex() != 0 {
+		return false, nil
+	}
+	var bf bytes.Buffer
+	if conf.EmptyTables == EmptyTablesHeader {
+		header(&bf)
+	}
+	// the file is created even if nothing is written
+	if _, err := w.Write(bf.Bytes()); err != nil {
+		return false, err
+	}
+	return true, nil
+}
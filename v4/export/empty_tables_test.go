@@ -0,0 +1,91 @@
+package export
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	. "github.com/pingcap/check"
+)
+
+var _ = Suite(&testEmptyTablesSuite{})
+
+type testEmptyTablesSuite struct{}
+
+func (s *testEmptyTablesSuite) TestEmptyTables(c *C) {
+	specCmts := []string{"/*!40101 SET NAMES binary*/;"}
+	cases := []struct {
+		fileType    string
+		emptyTables string
+		noHeader    bool
+		file        string
+		// content is empty and no file is written if exists is false
+		exists  bool
+		content string
+	}{
+		{"sql", EmptyTablesNone, false, "test.t.0.sql", false, ""},
+		{"sql", EmptyTablesEmpty, false, "test.t.0.sql", true, ""},
+		{"sql", EmptyTablesHeader, false, "test.t.0.sql", true, "/*!40101 SET NAMES binary*/;\nSET FOREIGN_KEY_CHECKS=1;\n"},
+		{"csv", EmptyTablesNone, false, "test.t.0.csv", false, ""},
+		{"csv", EmptyTablesHeader, false, "test.t.0.csv", true, "\"id\",\"name\"\n"},
+		{"csv", EmptyTablesHeader, true, "test.t.0.csv", true, ""},
+		{"tsv", EmptyTablesHeader, false, "test.t.0.tsv", true, "id\tname\n"},
+	}
+	for _, t := range cases {
+		dir, err := ioutil.TempDir("", "dumpling")
+		c.Assert(err, IsNil)
+		conf := DefaultConfig()
+		conf.OutputDirPath = dir
+		conf.FileType = t.fileType
+		conf.EmptyTables = t.emptyTables
+		conf.NoHeader = t.noHeader
+		var writer Writer
+		switch t.fileType {
+		case "sql":
+			writer, err = NewSimpleWriter(conf)
+		case "csv":
+			writer, err = NewCsvWriter(conf)
+		case "tsv":
+			writer, err = NewTsvWriter(conf)
+		}
+		c.Assert(err, IsNil)
+
+		ir := newMockTableIR("test", "t", nil, specCmts, []string{"INT", "VARCHAR"}).(*mockTableIR)
+		ir.colNames = []string{"id", "name"}
+		ir.specFooter = []string{"SET FOREIGN_KEY_CHECKS=1;"}
+		c.Assert(writer.WriteTableData(context.Background(), ir), IsNil)
+		content, err := ioutil.ReadFile(filepath.Join(dir, t.file))
+		if t.exists {
+			c.Assert(err, IsNil, Commentf("case %+v", t))
+			c.Assert(string(content), Equals, t.content, Commentf("case %+v", t))
+		} else {
+			c.Assert(os.IsNotExist(err), IsTrue, Commentf("case %+v", t))
+		}
+
+		// the empty chunks of a table never have files
+		chunk := newMockTableIR("test", "t", nil, specCmts, []string{"INT", "VARCHAR"}).(*mockTableIR)
+		chunk.chunIndex = 1
+		c.Assert(writer.WriteTableData(context.Background(), chunk), IsNil)
+		files, err := ioutil.ReadDir(dir)
+		c.Assert(err, IsNil)
+		if t.exists {
+			c.Assert(files, HasLen, 1)
+		} else {
+			c.Assert(files, HasLen, 0)
+		}
+		os.RemoveAll(dir)
+	}
+}
+
+func (s *testEmptyTablesSuite) TestValidate(c *C) {
+	conf := DefaultConfig()
+	c.Assert(conf.Validate(), IsNil)
+	conf.EmptyTables = EmptyTablesHeader
+	c.Assert(conf.Validate(), IsNil)
+	conf.EmptyTables = "full"
+	c.Assert(conf.Validate(), ErrorMatches, ".*invalid empty-tables option full.*")
+	conf.EmptyTables = EmptyTablesEmpty
+	conf.FileType = "sqlite"
+	c.Assert(conf.Validate(), ErrorMatches, ".*empty-tables is only supported with filetype sql, csv and tsv.*")
+}
@@ -40,3 +40,85 @@ func withStack(err error) error {
 		stack: debug.Stack(),
 	}
 }
+
+// ErrorKind classifies the errors returned by Dump, so that the callers can
+// decide whether to retry or how to report the failure.
+type ErrorKind string
+
+const (
+	ErrorKindUnknown     ErrorKind = "unknown"
+	ErrorKindConfig      ErrorKind = "config"
+	ErrorKindConnection  ErrorKind = "connection"
+	ErrorKindConsistency ErrorKind = "consistency"
+	ErrorKindSchema      ErrorKind = "schema"
+	ErrorKindWrite       ErrorKind = "write"
+)
+
+// DumpError is an error of a known kind.
+type DumpError struct {
+	Kind ErrorKind
+	Err  error
+}
+
+func (e *DumpError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *DumpError) Unwrap() error {
+	return e.Err
+}
+
+// Cause makes DumpError work with errors.Cause of github.com/pingcap/errors.
+func (e *DumpError) Cause() error {
+	return e.Err
+}
+
+func withKind(kind ErrorKind, err error) error {
+	if err == nil {
+		return nil
+	}
+	// keep the innermost kind, which is the most specific one
+	if ErrorKindOf(err) != ErrorKindUnknown {
+		return err
+	}
+	return &DumpError{Kind: kind, Err: err}
+}
+
+// ErrorKindOf returns the kind of the first DumpError in the chain of err,
+// following both Unwrap and Cause. It returns ErrorKindUnknown if there isn't any.
+func ErrorKindOf(err error) ErrorKind {
+	for err != nil {
+		if dumpErr, ok := err.(*DumpError); ok {
+			return dumpErr.Kind
+		}
+		err = unwrapOnce(err)
+	}
+	return ErrorKindUnknown
+}
+
+// RootCause returns the innermost error in the chain of err, following both Unwrap and Cause.
+func RootCause(err error) error {
+	for {
+		next := unwrapOnce(err)
+		if next == nil {
+			return err
+		}
+		err = next
+	}
+}
+
+func unwrapOnce(err error) error {
+	switch e := err.(type) {
+	case interface{ Unwrap() error }:
+		return e.Unwrap()
+	case interface{ Cause() error }:
+		return e.Cause()
+	default:
+		return nil
+	}
+}
+
+// IsRetryable returns whether the dump may succeed if it's retried.
+func IsRetryable(err error) bool {
+	return ErrorKindOf(err) == ErrorKindConnection
+}
@@ -0,0 +1,49 @@
+package export
+
+import (
+	"errors"
+	"fmt"
+
+	. "github.com/pingcap/check"
+	perrors "github.com/pingcap/errors"
+)
+
+var _ = Suite(&testErrorSuite{})
+
+type testErrorSuite struct{}
+
+func (s *testErrorSuite) TestErrorKindOf(c *C) {
+	rawErr := errors.New("raw error")
+	c.Assert(ErrorKindOf(nil), Equals, ErrorKindUnknown)
+	c.Assert(ErrorKindOf(rawErr), Equals, ErrorKindUnknown)
+	c.Assert(withKind(ErrorKindWrite, nil), IsNil)
+
+	err := withKind(ErrorKindConnection, rawErr)
+	c.Assert(err.Error(), Equals, "raw error")
+	c.Assert(errors.Is(err, rawErr), IsTrue)
+	c.Assert(ErrorKindOf(err), Equals, ErrorKindConnection)
+	c.Assert(IsRetryable(err), IsTrue)
+
+	// the kind is found through all kinds of wrappers
+	c.Assert(ErrorKindOf(withStack(err)), Equals, ErrorKindConnection)
+	c.Assert(ErrorKindOf(perrors.WithMessage(err, "query")), Equals, ErrorKindConnection)
+	c.Assert(ErrorKindOf(fmt.Errorf("dump table: %w", err)), Equals, ErrorKindConnection)
+
+	// the innermost kind is kept
+	err = withKind(ErrorKindSchema, withStack(err))
+	c.Assert(ErrorKindOf(err), Equals, ErrorKindConnection)
+
+	err = withKind(ErrorKindWrite, rawErr)
+	c.Assert(ErrorKindOf(err), Equals, ErrorKindWrite)
+	c.Assert(IsRetryable(err), IsFalse)
+	var dumpErr *DumpError
+	c.Assert(errors.As(withStack(err), &dumpErr), IsTrue)
+	c.Assert(dumpErr.Kind, Equals, ErrorKindWrite)
+}
+
+func (s *testErrorSuite) TestRootCause(c *C) {
+	rawErr := errors.New("raw error")
+	c.Assert(RootCause(rawErr), Equals, rawErr)
+	err := withStack(perrors.WithMessage(withKind(ErrorKindWrite, fmt.Errorf("open file error: %w", rawErr)), "write"))
+	c.Assert(RootCause(err), Equals, rawErr)
+}
@@ -0,0 +1,190 @@
+package export
+
+import (
+	"bytes"
+	"unicode/utf8"
+)
+
+// Escaper writes the content of a value escaped for a format, the quotation
+// marks enclosing it, if any, are written by the callers. The escapers don't
+// allocate, so that they're used on every value of the rows.
+type Escaper interface {
+	Escape(bf *bytes.Buffer, s []byte)
+}
+
+var (
+	// BackslashEscaper escapes the special characters of the strings of MySQL
+	// by backslashes, as mysqldump.
+	BackslashEscaper Escaper = backslashEscaper{}
+	// QuoteDoublingEscaper escapes the single quotation marks of the strings of
+	// SQL by doubling them, which is understood without the backslash escapes,
+	// e.g. with NO_BACKSLASH_ESCAPES.
+	QuoteDoublingEscaper Escaper = quoteDoublingEscaper{quote: quotationMark}
+	// CSVEscaper escapes the double quotation marks of the quoted fields of CSV
+	// by doubling them, as RFC 4180.
+	CSVEscaper Escaper = quoteDoublingEscaper{quote: doubleQuotationMark}
+	// TSVEscaper escapes the special characters of the TabSeparated format,
+	// where the values are separated by tabs and the rows by line feeds.
+	TSVEscaper Escaper = tsvEscaper{}
+	// JSONEscaper escapes the strings of JSON, the invalid UTF-8 is replaced
+	// by U+FFFD as encoding/json.
+	JSONEscaper Escaper = jsonEscaper{}
+)
+
+// sqlEscaper returns the escaper of the strings of SQL.
+func sqlEscaper(escapeBackslash bool) Escaper {
+	if escapeBackslash {
+		return BackslashEscaper
+	}
+	return QuoteDoublingEscaper
+}
+
+// csvEscaper returns the escaper of the quoted fields of CSV, which are
+// loaded by LOAD DATA with the backslash ESCAPED BY if escapeBackslash,
+// otherwise with the empty ESCAPED BY.
+func csvEscaper(escapeBackslash bool) Escaper {
+	if escapeBackslash {
+		return BackslashEscaper
+	}
+	return CSVEscaper
+}
+
+// hexEscaper writes the hex digits of the bytes, between the prefix and the
+// suffix of the binary literals.
+type hexEscaper struct{}
+
+func (hexEscaper) Escape(bf *bytes.Buffer, s []byte) {
+	writeHex(bf, s)
+}
+
+// verbatimEscaper writes the bytes as they are, e.g. the binary values of CSV.
+type verbatimEscaper struct{}
+
+func (verbatimEscaper) Escape(bf *bytes.Buffer, s []byte) {
+	bf.Write(s)
+}
+
+type backslashEscaper struct{}
+
+func (backslashEscaper) Escape(bf *bytes.Buffer, s []byte) {
+	var (
+		escape byte
+		last   = 0
+	)
+	// reference: https://gist.github.com/siddontang/8875771
+	for i := 0; i < len(s); i++ {
+		escape = 0
+
+		switch s[i] {
+		case 0: /* Must be escaped for 'mysql' */
+			escape = '0'
+		case '\n': /* Must be escaped for logs */
+			escape = 'n'
+		case '\r':
+			escape = 'r'
+		case '\\':
+			escape = '\\'
+		case '\'':
+			escape = '\''
+		case '"': /* Better safe than sorry */
+			escape = '"'
+		case '\032': /* This gives problems on Win32 */
+			escape = 'Z'
+		}
+
+		if escape != 0 {
+			bf.Write(s[last:i])
+			bf.WriteByte('\\')
+			bf.WriteByte(escape)
+			last = i + 1
+		}
+	}
+	bf.Write(s[last:])
+}
+
+type quoteDoublingEscaper struct {
+	quote byte
+}
+
+func (e quoteDoublingEscaper) Escape(bf *bytes.Buffer, s []byte) {
+	// write the segments between quotation marks directly, without allocating a replaced copy
+	for {
+		i := bytes.IndexByte(s, e.quote)
+		if i < 0 {
+			bf.Write(s)
+			return
+		}
+		bf.Write(s[:i+1])
+		bf.WriteByte(e.quote)
+		s = s[i+1:]
+	}
+}
+
+type tsvEscaper struct{}
+
+func (tsvEscaper) Escape(bf *bytes.Buffer, s []byte) {
+	last := 0
+	for i := 0; i < len(s); i++ {
+		var escape byte
+		switch s[i] {
+		case 0:
+			escape = '0'
+		case '\b':
+			escape = 'b'
+		case '\f':
+			escape = 'f'
+		case '\t':
+			escape = 't'
+		case '\n':
+			escape = 'n'
+		case '\r':
+			escape = 'r'
+		case '\\':
+			escape = '\\'
+		}
+		if escape != 0 {
+			bf.Write(s[last:i])
+			bf.WriteByte('\\')
+			bf.WriteByte(escape)
+			last = i + 1
+		}
+	}
+	bf.Write(s[last:])
+}
+
+type jsonEscaper struct{}
+
+func (jsonEscaper) Escape(bf *bytes.Buffer, s []byte) {
+	const hex = "0123456789abcdef"
+	for i := 0; i < len(s); {
+		c := s[i]
+		if c < utf8.RuneSelf {
+			switch {
+			case c == '"' || c == '\\':
+				bf.WriteByte('\\')
+				bf.WriteByte(c)
+			case c == '\n':
+				bf.WriteString(`\n`)
+			case c == '\r':
+				bf.WriteString(`\r`)
+			case c == '\t':
+				bf.WriteString(`\t`)
+			case c < 0x20:
+				bf.WriteString(`\u00`)
+				bf.WriteByte(hex[c>>4])
+				bf.WriteByte(hex[c&0xf])
+			default:
+				bf.WriteByte(c)
+			}
+			i++
+			continue
+		}
+		r, size := utf8.DecodeRune(s[i:])
+		if r == utf8.RuneError && size == 1 {
+			bf.WriteString("�")
+		} else {
+			bf.Write(s[i : i+size])
+		}
+		i += size
+	}
+}
@@ -0,0 +1,187 @@
+package export
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/csv"
+	"encoding/json"
+	"math/rand"
+	"strings"
+	"testing"
+	"time"
+	"unicode/utf8"
+
+	. "github.com/pingcap/check"
+)
+
+var _ = Suite(&testEscaperSuite{})
+
+type testEscaperSuite struct{}
+
+// escaperRounds is the number of the random values escaped by each test.
+const escaperRounds = 2000
+
+// escaperSpecialBytes are the bytes escaped by any of the escapers, or
+// separating the values.
+const escaperSpecialBytes = "\x00\b\f\t\n\r\x1a\x7f\\'\",`"
+
+// randomEscaperInput returns a random value mostly made of the special bytes,
+// with the multi-byte and the invalid UTF-8. It's never nil, i.e. NULL.
+func randomEscaperInput(r *rand.Rand) []byte {
+	b := []byte{}
+	for n := r.Intn(32); n > 0; n-- {
+		switch r.Intn(4) {
+		case 0:
+			b = append(b, escaperSpecialBytes[r.Intn(len(escaperSpecialBytes))])
+		case 1:
+			b = append(b, byte('a'+r.Intn(26)))
+		case 2:
+			b = append(b, string(rune(0x80+r.Intn(0x10000-0x80)))...)
+		default:
+			b = append(b, byte(0x80+r.Intn(0x80)))
+		}
+	}
+	return b
+}
+
+// forRandomInputs calls check with the special cases and escaperRounds
+// random values, seeded by the time so that every run tries new values.
+func forRandomInputs(c *C, check func(input []byte, comment CommentInterface)) {
+	seed := time.Now().UnixNano()
+	r := rand.New(rand.NewSource(seed))
+	for _, input := range []string{"", "'", `"`, `\`, "\r\n", "\x00", `''\'`, "\xff\xfe"} {
+		check([]byte(input), Commentf("input %q", input))
+	}
+	for i := 0; i < escaperRounds; i++ {
+		input := randomEscaperInput(r)
+		check(input, Commentf("seed %d, input %q", seed, input))
+	}
+}
+
+func (s *testEscaperSuite) TestBackslashRoundTrip(c *C) {
+	var bf bytes.Buffer
+	forRandomInputs(c, func(input []byte, comment CommentInterface) {
+		bf.Reset()
+		(&SQLTypeString{RawBytes: input}).WriteToBuffer(&bf, true)
+		quoted := bf.String()
+		// the string ends at its closing quotation mark
+		c.Assert(skipQuoted(quoted+",'next'", 0), Equals, len(quoted), comment)
+		c.Assert(unescapeSQLString(quoted[1:len(quoted)-1]), Equals, string(input), comment)
+	})
+}
+
+func (s *testEscaperSuite) TestQuoteDoublingRoundTrip(c *C) {
+	db, err := sql.Open("sqlite3", ":memory:")
+	c.Assert(err, IsNil)
+	defer db.Close()
+	var bf bytes.Buffer
+	forRandomInputs(c, func(input []byte, comment CommentInterface) {
+		// the NUL ends the statements of SQLite
+		if bytes.IndexByte(input, 0) >= 0 {
+			return
+		}
+		bf.Reset()
+		bf.WriteString("SELECT CAST(")
+		(&SQLTypeString{RawBytes: input}).WriteToBuffer(&bf, false)
+		bf.WriteString(" AS BLOB), 'next'")
+		var value []byte
+		var next string
+		c.Assert(db.QueryRow(bf.String()).Scan(&value, &next), IsNil, comment)
+		c.Assert(string(value), Equals, string(input), comment)
+		c.Assert(next, Equals, "next", comment)
+	})
+}
+
+func (s *testEscaperSuite) TestCSVRoundTrip(c *C) {
+	row := MakeRowReceiver([]string{"VARCHAR", "VARCHAR"}).(RowReceiverArr)
+	var bf bytes.Buffer
+	forRandomInputs(c, func(input []byte, comment CommentInterface) {
+		row[0].(*SQLTypeString).RawBytes = input
+		row[1].(*SQLTypeString).RawBytes = []byte("next")
+		bf.Reset()
+		row.WriteToBufferInCsv(&bf, false, "\\N")
+		bf.WriteByte('\n')
+		records, err := csv.NewReader(&bf).ReadAll()
+		c.Assert(err, IsNil, comment)
+		// encoding/csv reads the CRLF in the quoted fields as LF
+		c.Assert(records, DeepEquals, [][]string{{strings.Replace(string(input), "\r\n", "\n", -1), "next"}}, comment)
+	})
+}
+
+// unescapeTsv unescapes a value of the TabSeparated format of ClickHouse.
+func unescapeTsv(s string) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] != '\\' || i+1 == len(s) {
+			b.WriteByte(s[i])
+			continue
+		}
+		i++
+		switch s[i] {
+		case '0':
+			b.WriteByte(0)
+		case 'b':
+			b.WriteByte('\b')
+		case 'f':
+			b.WriteByte('\f')
+		case 't':
+			b.WriteByte('\t')
+		case 'n':
+			b.WriteByte('\n')
+		case 'r':
+			b.WriteByte('\r')
+		default:
+			b.WriteByte(s[i])
+		}
+	}
+	return b.String()
+}
+
+func (s *testEscaperSuite) TestTSVRoundTrip(c *C) {
+	row := MakeRowReceiver([]string{"VARCHAR", "BLOB"}).(RowReceiverArr)
+	var bf bytes.Buffer
+	forRandomInputs(c, func(input []byte, comment CommentInterface) {
+		row[0].(*SQLTypeString).RawBytes = input
+		row[1].(*SQLTypeBytes).RawBytes = input
+		bf.Reset()
+		row.WriteToBufferInTsv(&bf)
+		// the escaped values have neither tabs nor line feeds
+		values := strings.Split(bf.String(), "\t")
+		c.Assert(values, HasLen, 2, comment)
+		for _, value := range values {
+			c.Assert(strings.IndexByte(value, '\n'), Equals, -1, comment)
+			c.Assert(unescapeTsv(value), Equals, string(input), comment)
+		}
+	})
+}
+
+func (s *testEscaperSuite) TestJSONRoundTrip(c *C) {
+	var bf bytes.Buffer
+	forRandomInputs(c, func(input []byte, comment CommentInterface) {
+		bf.Reset()
+		writeJSONString(&bf, string(input))
+		var value string
+		c.Assert(json.Unmarshal(bf.Bytes(), &value), IsNil, comment)
+		// each byte of the invalid UTF-8 is replaced as encoding/json
+		var expected strings.Builder
+		for i := 0; i < len(input); {
+			r, size := utf8.DecodeRune(input[i:])
+			expected.WriteRune(r)
+			i += size
+		}
+		c.Assert(value, Equals, expected.String(), comment)
+	})
+}
+
+func (s *testEscaperSuite) TestEscapeNoAllocation(c *C) {
+	input := []byte("it's \"quoted\"\t\\\n\x00\xff")
+	var bf bytes.Buffer
+	bf.Grow(1 << 10)
+	for _, escaper := range []Escaper{BackslashEscaper, QuoteDoublingEscaper, CSVEscaper, TSVEscaper, JSONEscaper} {
+		allocs := testing.AllocsPerRun(100, func() {
+			bf.Reset()
+			escaper.Escape(&bf, input)
+		})
+		c.Assert(allocs, Equals, float64(0))
+	}
+}
@@ -0,0 +1,192 @@
+package export
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/pingcap/errors"
+	"golang.org/x/sync/errgroup"
+)
+
+// fanOutBatchBytes is the approximate size of the batches of rows dealt to
+// the files of a fanned out chunk.
+const fanOutBatchBytes = 1 << 20
+
+// rawRow receives a row as the raw bytes of its columns.
+type rawRow []sql.RawBytes
+
+func (r rawRow) BindAddress(args []interface{}) {
+	for i := range args {
+		args[i] = &r[i]
+	}
+}
+
+func (r rawRow) ReportSize() uint64 {
+	var size uint64
+	for _, col := range r {
+		size += uint64(len(col))
+	}
+	return size
+}
+
+// copyTo sets the columns of row to the bytes of r, the receivers of row
+// should receive the raw bytes too. args is the buffer of the addresses.
+func (r rawRow) copyTo(row RowReceiver, args []interface{}) error {
+	row.BindAddress(args)
+	for i, arg := range args {
+		dest, ok := arg.(*sql.RawBytes)
+		if !ok {
+			return errors.Errorf("unsupported receiver %T of the raw bytes", arg)
+		}
+		*dest = r[i]
+	}
+	return nil
+}
+
+// writeFanOut writes the rows of ir into n files concurrently. The rows are dealt
+// to the files round-robin by the batches of about batchBytes, and the file i
+// is written as the chunk ir.ChunkIndex()*n+i, so the files of the chunks don't
+// collide.
+func writeFanOut(ctx context.Context, ir TableDataIR, n int, batchBytes uint64, write func(context.Context, TableDataIR) error) error {
+	g, ctx := errgroup.WithContext(ctx)
+	chs := make([]chan [][][]byte, n)
+	for i := range chs {
+		chs[i] = make(chan [][][]byte, 1)
+		part := &fanOutTableData{
+			TableDataIR: ir,
+			chunkIndex:  ir.ChunkIndex()*n + i,
+			iter:        &fanOutRowIter{ch: chs[i]},
+		}
+		g.Go(func() error {
+			return write(ctx, part)
+		})
+	}
+	g.Go(func() error {
+		defer func() {
+			for _, ch := range chs {
+				close(ch)
+			}
+		}()
+		return dealRows(ctx, ir, chs, batchBytes)
+	})
+	return g.Wait()
+}
+
+// dealRows sends the batches of the rows of ir to chs round-robin.
+func dealRows(ctx context.Context, ir TableDataIR, chs []chan [][][]byte, batchBytes uint64) error {
+	iter := ir.Rows()
+	defer iter.Close()
+	row := make(rawRow, ir.ColumnCount())
+	var (
+		batch [][][]byte
+		size  uint64
+		next  int
+	)
+	send := func() error {
+		select {
+		case chs[next] <- batch:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+		next = (next + 1) % len(chs)
+		batch, size = nil, 0
+		return nil
+	}
+	for ; iter.HasNext(); iter.Next() {
+		if err := iter.Decode(row); err != nil {
+			return err
+		}
+		// the raw bytes are reused by the next row
+		values := make([][]byte, len(row))
+		for i, col := range row {
+			if col != nil {
+				values[i] = append([]byte{}, col...)
+			}
+		}
+		batch = append(batch, values)
+		if size += row.ReportSize(); size >= batchBytes {
+			if err := send(); err != nil {
+				return err
+			}
+		}
+	}
+	if err := iter.Error(); err != nil {
+		return withStack(err)
+	}
+	if len(batch) > 0 {
+		return send()
+	}
+	return nil
+}
+
+// fanOutTableData is the part of a TableDataIR written into one of its files.
+type fanOutTableData struct {
+	TableDataIR
+	chunkIndex int
+	iter       *fanOutRowIter
+}
+
+func (td *fanOutTableData) ChunkIndex() int {
+	return td.chunkIndex
+}
+
+func (td *fanOutTableData) Rows() SQLRowIter {
+	return td.iter
+}
+
+func (td *fanOutTableData) tableProgress() *tableProgress {
+	return tableProgressOf(td.TableDataIR)
+}
+
+// fanOutRowIter iterates the rows of the batches received from ch.
+type fanOutRowIter struct {
+	ch    <-chan [][][]byte
+	batch [][][]byte
+	idx   int
+	args  []interface{}
+}
+
+func (iter *fanOutRowIter) Decode(row RowReceiver) error {
+	values := iter.batch[iter.idx]
+	if iter.args == nil {
+		iter.args = make([]interface{}, len(values))
+	}
+	row.BindAddress(iter.args)
+	for i, arg := range iter.args {
+		*arg.(*sql.RawBytes) = values[i]
+	}
+	return nil
+}
+
+func (iter *fanOutRowIter) Next() {
+	iter.idx++
+}
+
+// Error returns nil, the error of the rows is returned by writeFanOut.
+func (iter *fanOutRowIter) Error() error {
+	return nil
+}
+
+func (iter *fanOutRowIter) HasNext() bool {
+	for iter.idx >= len(iter.batch) {
+		batch, ok := <-iter.ch
+		if !ok {
+			return false
+		}
+		iter.batch, iter.idx = batch, 0
+	}
+	return true
+}
+
+func (iter *fanOutRowIter) HasNextSQLRowIter() bool {
+	return iter.HasNext()
+}
+
+func (iter *fanOutRowIter) NextSQLRowIter() SQLRowIter {
+	return iter
+}
+
+// Close is a no-op, the rows are closed after they're dealt.
+func (iter *fanOutRowIter) Close() error {
+	return nil
+}
@@ -0,0 +1,78 @@
+package export
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+
+	. "github.com/pingcap/check"
+)
+
+var _ = Suite(&testFanOutSuite{})
+
+type testFanOutSuite struct{}
+
+func (s *testFanOutSuite) TestWriteFanOut(c *C) {
+	conf := DefaultConfig()
+	storage := newMemStorage()
+	conf.ExternalStorage = storage
+	writer, err := NewSimpleWriter(conf)
+	c.Assert(err, IsNil)
+	rows := [][]driver.Value{{"1", "a"}, {"2", "b"}, {"3", "c"}, {"4", nil}, {"5", "e"}}
+	ir := newMockTableIR("test", "t", rows, nil, []string{"INT", "VARCHAR"}).(*mockTableIR)
+	ir.chunIndex = 1
+
+	// every batch has 2 rows
+	c.Assert(writeFanOut(context.Background(), ir, 2, 3, writer.WriteTableData), IsNil)
+	c.Assert(storage.files, DeepEquals, map[string]string{
+		"test.t.2.sql": "INSERT INTO `t` VALUES\n(1,'a'),\n(2,'b'),\n(5,'e');\n",
+		"test.t.3.sql": "INSERT INTO `t` VALUES\n(3,'c'),\n(4,NULL);\n",
+	})
+
+	// the files without rows aren't written
+	storage = newMemStorage()
+	conf.ExternalStorage = storage
+	writer, err = NewSimpleWriter(conf)
+	c.Assert(err, IsNil)
+	ir = newMockTableIR("test", "t", rows[:1], nil, []string{"INT", "VARCHAR"}).(*mockTableIR)
+	c.Assert(writeFanOut(context.Background(), ir, 3, 3, writer.WriteTableData), IsNil)
+	c.Assert(storage.files, DeepEquals, map[string]string{
+		"test.t.0.sql": "INSERT INTO `t` VALUES\n(1,'a');\n",
+	})
+}
+
+func (s *testFanOutSuite) TestFanOutError(c *C) {
+	rows := make([][]driver.Value, 100)
+	for i := range rows {
+		rows[i] = []driver.Value{"1", "a"}
+	}
+	ir := newMockTableIR("test", "t", rows, nil, []string{"INT", "VARCHAR"})
+	writeErr := errors.New("disk full")
+	// the rows aren't dealt after a file failed
+	err := writeFanOut(context.Background(), ir, 2, 1, func(_ context.Context, part TableDataIR) error {
+		if part.ChunkIndex() == 1 {
+			return writeErr
+		}
+		iter := part.Rows()
+		for ; iter.HasNext(); iter.Next() {
+		}
+		return nil
+	})
+	c.Assert(err, Equals, writeErr)
+}
+
+func (s *testFanOutSuite) TestValidate(c *C) {
+	conf := DefaultConfig()
+	conf.FilesPerChunk = 0
+	c.Assert(conf.Validate(), ErrorMatches, "files-per-chunk should be positive, got 0")
+	conf.FilesPerChunk = 4
+	c.Assert(conf.Validate(), IsNil)
+	conf.FileType = "sqlite"
+	c.Assert(conf.Validate(), ErrorMatches, "files-per-chunk is only supported with filetype sql, csv and tsv")
+	conf.FileType = "csv"
+	conf.FileSize = 1 << 20
+	c.Assert(conf.Validate(), ErrorMatches, "files-per-chunk is not supported with filesize")
+	// filesize is disabled by rows
+	conf.Rows = 1000
+	c.Assert(conf.Validate(), IsNil)
+}
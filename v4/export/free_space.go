@@ -0,0 +1,49 @@
+package export
+
+import (
+	"syscall"
+
+	"github.com/pingcap/errors"
+	"go.uber.org/zap"
+
+	"github.com/pingcap/dumpling/v4/log"
+)
+
+// FreeSpaceReporter is implemented by the ExternalStorages which know how many
+// bytes can still be written into them, it's used to check the free space
+// before dumping.
+type FreeSpaceReporter interface {
+	FreeSpace() (uint64, error)
+}
+
+// FreeSpace returns the bytes available to unprivileged users in the file
+// system of the directory.
+func (s *LocalStorage) FreeSpace() (uint64, error) {
+	return diskFreeSpace(s.dir)
+}
+
+// checkFreeSpace fails with ENOSPC if the storage reports less free space than
+// the estimated output bytes plus conf.MinFree. It's skipped if the storage
+// doesn't report its free space.
+func checkFreeSpace(conf *Config, estimatedBytes uint64) error {
+	reporter, ok := baseStorage(conf.ExternalStorage).(FreeSpaceReporter)
+	if !ok {
+		return nil
+	}
+	free, err := reporter.FreeSpace()
+	if err != nil {
+		log.Warn("get free space of output storage failed, skip checking", zap.Error(err))
+		return nil
+	}
+	required := estimatedBytes + conf.MinFree
+	log.Info("check free space of output storage",
+		zap.Uint64("free", free),
+		zap.Uint64("estimated", estimatedBytes),
+		zap.Uint64("min-free", conf.MinFree))
+	if free < required {
+		return withKind(ErrorKindWrite, errors.Annotatef(syscall.ENOSPC,
+			"free space %d bytes is less than the estimated %d bytes plus the min free %d bytes",
+			free, estimatedBytes, conf.MinFree))
+	}
+	return nil
+}
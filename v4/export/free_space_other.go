@@ -0,0 +1,10 @@
+//go:build !linux && !darwin
+// +build !linux,!darwin
+
+package export
+
+import "github.com/pingcap/errors"
+
+func diskFreeSpace(string) (uint64, error) {
+	return 0, errors.New("getting free disk space is not supported on this platform")
+}
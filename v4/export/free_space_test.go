@@ -0,0 +1,65 @@
+package export
+
+import (
+	"io"
+	"io/ioutil"
+	"os"
+	"syscall"
+
+	. "github.com/pingcap/check"
+)
+
+var _ = Suite(&testFreeSpaceSuite{})
+
+type testFreeSpaceSuite struct{}
+
+type mockFreeSpaceStorage struct {
+	*memStorage
+	free uint64
+}
+
+func (s *mockFreeSpaceStorage) FreeSpace() (uint64, error) {
+	return s.free, nil
+}
+
+func (s *testFreeSpaceSuite) TestCheckFreeSpace(c *C) {
+	conf := DefaultConfig()
+	conf.ExternalStorage = &mockFreeSpaceStorage{memStorage: newMemStorage(), free: 100}
+	c.Assert(checkFreeSpace(conf, 100), IsNil)
+
+	err := checkFreeSpace(conf, 101)
+	c.Assert(err, NotNil)
+	c.Assert(ErrorKindOf(err), Equals, ErrorKindWrite)
+	c.Assert(RootCause(err), Equals, syscall.ENOSPC)
+
+	conf.MinFree = 10
+	c.Assert(checkFreeSpace(conf, 90), IsNil)
+	c.Assert(checkFreeSpace(conf, 91), NotNil)
+
+	// the wrapped storage is checked too
+	conf.ExternalStorage = WrapStorage(conf.ExternalStorage, func(_ string, w io.WriteCloser) (io.WriteCloser, error) {
+		return w, nil
+	})
+	c.Assert(checkFreeSpace(conf, 91), NotNil)
+
+	// storages not reporting free space are not checked
+	conf.ExternalStorage = newMemStorage()
+	c.Assert(checkFreeSpace(conf, 1<<62), IsNil)
+}
+
+func (s *testFreeSpaceSuite) TestLocalStorageFreeSpace(c *C) {
+	dir, err := ioutil.TempDir("", "dumpling")
+	c.Assert(err, IsNil)
+	defer os.RemoveAll(dir)
+
+	storage, err := NewLocalStorage(dir, false)
+	c.Assert(err, IsNil)
+	free, err := storage.FreeSpace()
+	c.Assert(err, IsNil)
+	c.Assert(free, Greater, uint64(0))
+
+	conf := DefaultConfig()
+	conf.ExternalStorage = storage
+	c.Assert(checkFreeSpace(conf, 0), IsNil)
+	c.Assert(checkFreeSpace(conf, free+1<<40), NotNil)
+}
@@ -0,0 +1,137 @@
+package export
+
+import (
+	"context"
+	"database/sql/driver"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	. "github.com/pingcap/check"
+)
+
+var _ = Suite(&testGoldenSuite{})
+
+type testGoldenSuite struct{}
+
+// goldenDir has the golden files shared with the integration tests of
+// tests/golden, which dump the same fixture from MySQL.
+var goldenDir = filepath.Join("..", "..", "tests", "golden", "expect")
+
+// goldenRows are the rows of golden.t in tests/golden/data/golden.t.sql, as
+// they're returned by MySQL.
+var goldenRows = [][]driver.Value{
+	{"1", "apple", "1.50", "2020-01-01 00:00:00", []byte{1, 2}, nil},
+	{"2", "O'Brien", "-0.25", "2020-02-29 12:34:56", []byte{}, "line1\nline2"},
+	{"3", "back\\slash", "100.00", "1999-12-31 23:59:59", nil, "tab\there, \"quoted\""},
+}
+
+// selectGoldenTable selects golden.t by SelectAllFromTable from a mock server,
+// which expects query and returns rows as the rows matching it.
+func selectGoldenTable(c *C, conf *Config, query string, rows [][]driver.Value) {
+	db, mock, err := sqlmock.New()
+	c.Assert(err, IsNil)
+	defer db.Close()
+
+	mock.ExpectQuery("SELECT COLUMN_NAME,EXTRA FROM INFORMATION_SCHEMA.COLUMNS").WithArgs("golden", "t").
+		WillReturnRows(sqlmock.NewRows([]string{"COLUMN_NAME", "EXTRA"}).AddRow("id", ""))
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM `golden`.`t` LIMIT 1")).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}))
+	mock.ExpectPrepare("SELECT COLUMN_NAME FROM INFORMATION_SCHEMA.STATISTICS").ExpectQuery().WithArgs("golden", "t").
+		WillReturnRows(sqlmock.NewRows([]string{"COLUMN_NAME"}).AddRow("id"))
+	result := sqlmock.NewRows([]string{"id"})
+	for _, row := range rows {
+		result.AddRow(row[0])
+	}
+	mock.ExpectQuery("^" + regexp.QuoteMeta(query) + "$").WillReturnRows(result)
+
+	ir, err := SelectAllFromTable(context.Background(), conf, db, "golden", "t")
+	c.Assert(err, IsNil)
+	c.Assert(ir.Rows().Close(), IsNil)
+	c.Assert(mock.ExpectationsWereMet(), IsNil)
+}
+
+// TestGolden writes the rows like the cases of tests/golden/cases, and compares
+// the data files with the golden files. Set DUMPLING_UPDATE_GOLDEN=1 to update
+// the golden files after changing the output deliberately.
+func (s *testGoldenSuite) TestGolden(c *C) {
+	cases := []struct {
+		name  string
+		setup func(*Config)
+		rows  [][]driver.Value
+		// query is the query of the rows, which is checked if it's not empty
+		query string
+	}{
+		{"sql", func(*Config) {}, goldenRows, ""},
+		{"sql-no-escape", func(conf *Config) { conf.EscapeBackslash = false }, goldenRows, ""},
+		{"sql-compact", func(conf *Config) { conf.Compact = true }, goldenRows, ""},
+		{"sql-transaction", func(conf *Config) {
+			conf.TransactionRows = 2
+			conf.DisableForeignKeyChecks = true
+		}, goldenRows, ""},
+		{"csv", func(conf *Config) { conf.FileType = "csv" }, goldenRows, ""},
+		{"csv-null-no-header", func(conf *Config) {
+			conf.FileType = "csv"
+			conf.CsvNullValue = "NULL"
+			conf.NoHeader = true
+		}, goldenRows, ""},
+		{"tsv", func(conf *Config) { conf.FileType = "tsv" }, goldenRows, ""},
+		{"where", func(conf *Config) { conf.Where = "id<3" }, goldenRows[:2],
+			"SELECT * FROM `golden`.`t` WHERE id<3 ORDER BY `id`"},
+	}
+	update := os.Getenv("DUMPLING_UPDATE_GOLDEN") == "1"
+	for _, t := range cases {
+		conf := DefaultConfig()
+		conf.EscapeBackslash = true
+		conf.CsvNullValue = "\\N"
+		t.setup(conf)
+		c.Assert(adjustConfig(conf), IsNil)
+		if t.query != "" {
+			selectGoldenTable(c, conf, t.query, t.rows)
+		}
+		storage := newMemStorage()
+		conf.ExternalStorage = storage
+		conf.Progress = NewProgress()
+
+		var (
+			writer Writer
+			err    error
+		)
+		switch strings.ToLower(conf.FileType) {
+		case "sql":
+			writer, err = NewSimpleWriter(conf)
+		case "csv":
+			writer, err = NewCsvWriter(conf)
+		case "tsv":
+			writer, err = NewTsvWriter(conf)
+		}
+		c.Assert(err, IsNil)
+		ir := newMockTableIR("golden", "t", t.rows, buildSpecialComments(conf, "golden", "t"),
+			[]string{"INT", "VARCHAR", "DECIMAL", "DATETIME", "BLOB", "TEXT"}).(*mockTableIR)
+		ir.colNames = []string{"id", "name", "price", "created", "data", "note"}
+		ir.specFooter = buildSpecialFooters(conf, "t")
+		ir.escapeBackSlash = conf.EscapeBackslash
+		c.Assert(writer.WriteTableData(context.Background(), ir), IsNil)
+
+		dir := filepath.Join(goldenDir, t.name)
+		if update {
+			c.Assert(os.RemoveAll(dir), IsNil)
+			c.Assert(os.MkdirAll(dir, 0755), IsNil)
+			for name, content := range storage.files {
+				c.Assert(ioutil.WriteFile(filepath.Join(dir, name), []byte(content), 0644), IsNil)
+			}
+			continue
+		}
+		files, err := ioutil.ReadDir(dir)
+		c.Assert(err, IsNil)
+		c.Assert(storage.files, HasLen, len(files), Commentf("case %s", t.name))
+		for _, file := range files {
+			expect, err := ioutil.ReadFile(filepath.Join(dir, file.Name()))
+			c.Assert(err, IsNil)
+			c.Assert(storage.files[file.Name()], Equals, string(expect), Commentf("case %s file %s", t.name, file.Name()))
+		}
+	}
+}
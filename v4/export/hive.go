@@ -0,0 +1,100 @@
+package export
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+)
+
+// hiveTableDir is the directory of the CSV files of a table with HiveLocation,
+// since the location of an external table contains only the files of the table.
+func hiveTableDir(database, table string) string {
+	return fmt.Sprintf("%s.%s", database, table)
+}
+
+// hiveType returns the type of Hive for col.
+func hiveType(col ColumnInfo) string {
+	switch col.DataType {
+	case "tinyint":
+		if col.Unsigned {
+			return "SMALLINT"
+		}
+		return "TINYINT"
+	case "smallint", "year":
+		if col.Unsigned {
+			return "INT"
+		}
+		return "SMALLINT"
+	case "mediumint":
+		return "INT"
+	case "int":
+		if col.Unsigned {
+			return "BIGINT"
+		}
+		return "INT"
+	case "bigint":
+		if col.Unsigned {
+			return "DECIMAL(20,0)"
+		}
+		return "BIGINT"
+	case "decimal":
+		if col.Precision <= 0 || col.Precision > 38 {
+			return "STRING"
+		}
+		return fmt.Sprintf("DECIMAL(%d,%d)", col.Precision, col.Scale)
+	case "float":
+		return "FLOAT"
+	case "double":
+		return "DOUBLE"
+	case "bool":
+		return "BOOLEAN"
+	case "date":
+		return "DATE"
+	case "datetime", "timestamp":
+		return "TIMESTAMP"
+	default:
+		return "STRING"
+	}
+}
+
+// buildHiveCreateTable builds CREATE EXTERNAL TABLE of the CSV files in
+// location, which are parsed in the same way as they're written.
+func buildHiveCreateTable(conf *Config, database, table string, columns []ColumnInfo) string {
+	definitions := make([]string, 0, len(columns))
+	for _, col := range columns {
+		definitions = append(definitions, fmt.Sprintf("  %s %s", wrapBackTicks(col.Name), hiveType(col)))
+	}
+	escapeChar := ""
+	if conf.EscapeBackslash {
+		escapeChar = `, "escapeChar" = "\\"`
+	}
+	properties := []string{fmt.Sprintf(`"serialization.null.format" = "%s"`, escapeHiveString(conf.CsvNullValue))}
+	if !conf.NoHeader {
+		properties = append(properties, `"skip.header.line.count" = "1"`)
+	}
+	location := strings.TrimSuffix(conf.HiveLocation, "/") + "/" + hiveTableDir(database, table)
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "CREATE EXTERNAL TABLE %s.%s (\n%s\n)\n", wrapBackTicks(database), wrapBackTicks(table), strings.Join(definitions, ",\n"))
+	b.WriteString("ROW FORMAT SERDE 'org.apache.hadoop.hive.serde2.OpenCSVSerde'\n")
+	fmt.Fprintf(&b, "WITH SERDEPROPERTIES (\"separatorChar\" = \",\", \"quoteChar\" = \"\\\"\"%s)\n", escapeChar)
+	b.WriteString("STORED AS TEXTFILE\n")
+	fmt.Fprintf(&b, "LOCATION '%s'\n", escapeHiveString(location))
+	fmt.Fprintf(&b, "TBLPROPERTIES (%s)", strings.Join(properties, ", "))
+	return b.String()
+}
+
+func escapeHiveString(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `'`, `\'`, `"`, `\"`).Replace(s)
+}
+
+// writeHiveCreateTable writes the Hive DDL of a table as `<db>.<table>-schema-hive.sql`.
+func writeHiveCreateTable(ctx context.Context, conf *Config, db *sql.DB, database, table string) error {
+	columns, err := conf.dialect().ListColumns(db, database, table)
+	if err != nil {
+		return withKind(ErrorKindSchema, err)
+	}
+	fileName := fmt.Sprintf("%s.%s-schema-hive.sql", database, table)
+	return writeMetaToFile(withFileTable(ctx, database, table), conf, conf.ExternalStorage, database, buildHiveCreateTable(conf, database, table, columns), fileName)
+}
@@ -0,0 +1,84 @@
+package export
+
+import (
+	"context"
+	"database/sql/driver"
+	"io/ioutil"
+	"os"
+	"path"
+
+	. "github.com/pingcap/check"
+)
+
+var _ = Suite(&testHiveSuite{})
+
+type testHiveSuite struct{}
+
+func (s *testHiveSuite) TestHiveType(c *C) {
+	for _, t := range []struct {
+		col      ColumnInfo
+		expected string
+	}{
+		{ColumnInfo{DataType: "tinyint"}, "TINYINT"},
+		{ColumnInfo{DataType: "int", Unsigned: true}, "BIGINT"},
+		{ColumnInfo{DataType: "bigint", Unsigned: true}, "DECIMAL(20,0)"},
+		{ColumnInfo{DataType: "decimal", Precision: 10, Scale: 2}, "DECIMAL(10,2)"},
+		{ColumnInfo{DataType: "decimal", Precision: 65, Scale: 30}, "STRING"},
+		{ColumnInfo{DataType: "datetime"}, "TIMESTAMP"},
+		{ColumnInfo{DataType: "varchar", Length: 16}, "STRING"},
+	} {
+		c.Assert(hiveType(t.col), Equals, t.expected, Commentf("%s", t.col.DataType))
+	}
+}
+
+func (s *testHiveSuite) TestBuildHiveCreateTable(c *C) {
+	conf := DefaultConfig()
+	conf.HiveLocation = "s3a://bucket/dump/"
+	conf.EscapeBackslash = true
+	columns := []ColumnInfo{{Name: "id", DataType: "bigint"}, {Name: "name", DataType: "varchar", Length: 16}}
+	c.Assert(buildHiveCreateTable(conf, "test", "t", columns), Equals, "CREATE EXTERNAL TABLE `test`.`t` (\n"+
+		"  `id` BIGINT,\n"+
+		"  `name` STRING\n"+
+		")\n"+
+		"ROW FORMAT SERDE 'org.apache.hadoop.hive.serde2.OpenCSVSerde'\n"+
+		`WITH SERDEPROPERTIES ("separatorChar" = ",", "quoteChar" = "\"", "escapeChar" = "\\")`+"\n"+
+		"STORED AS TEXTFILE\n"+
+		"LOCATION 's3a://bucket/dump/test.t'\n"+
+		`TBLPROPERTIES ("serialization.null.format" = "\\N", "skip.header.line.count" = "1")`)
+
+	conf.EscapeBackslash = false
+	conf.NoHeader = true
+	conf.CsvNullValue = ""
+	createTable := buildHiveCreateTable(conf, "test", "t", columns)
+	c.Assert(createTable, Matches, `(?s).*WITH SERDEPROPERTIES \("separatorChar" = ",", "quoteChar" = "\\""\)\n.*`)
+	c.Assert(createTable, Matches, `(?s).*TBLPROPERTIES \("serialization.null.format" = ""\)`)
+}
+
+func (s *testHiveSuite) TestWriteTableDataInTableDir(c *C) {
+	dir, err := ioutil.TempDir("", "dumpling")
+	c.Assert(err, IsNil)
+	defer os.RemoveAll(dir)
+
+	conf := DefaultConfig()
+	conf.OutputDirPath = dir
+	conf.HiveLocation = "/warehouse"
+	conf.NoHeader = true
+	writer, err := NewCsvWriter(conf)
+	c.Assert(err, IsNil)
+
+	data := [][]driver.Value{{"1", "a"}}
+	tableIR := newMockTableIR("test", "t", data, nil, []string{"INT", "VARCHAR"})
+	c.Assert(writer.WriteTableData(context.Background(), tableIR), IsNil)
+
+	bytes, err := ioutil.ReadFile(path.Join(dir, "test.t", "test.t.0.csv"))
+	c.Assert(err, IsNil)
+	c.Assert(string(bytes), Equals, "1,\"a\"\n")
+}
+
+func (s *testHiveSuite) TestValidate(c *C) {
+	conf := DefaultConfig()
+	conf.HiveLocation = "/warehouse"
+	c.Assert(conf.Validate(), ErrorMatches, "hive-location is only supported with filetype csv of tables")
+	conf.FileType = "csv"
+	c.Assert(conf.Validate(), IsNil)
+}
@@ -0,0 +1,130 @@
+package export
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+
+	"github.com/pingcap/errors"
+	"go.uber.org/zap"
+
+	"github.com/pingcap/dumpling/v4/log"
+)
+
+const (
+	// HookFailureAbort fails the dump if a hook fails.
+	HookFailureAbort = "abort"
+	// HookFailureWarn logs the failure of a hook and continues.
+	HookFailureWarn = "warn"
+)
+
+// splitStatements splits sql into the statements separated by the semicolons
+// outside of the quoted strings, identifiers and comments. The statements of
+// only blanks or comments are dropped, but the executable comments like
+// /*!40101 ... */ are kept as statements.
+func splitStatements(sql string) []string {
+	var (
+		stmts   []string
+		scanner = sqlScanner{escapeBackslash: true}
+		b       = []byte(sql)
+		start   int
+		// content is whether the current statement has anything but blanks and comments
+		content bool
+	)
+	for i := 0; i < len(b); {
+		kind, n := scanner.scan(b[i:], true)
+		switch c := b[i]; {
+		case kind == sqlLiteral || kind == sqlExecutableComment:
+			content = true
+		case kind != sqlCode:
+		case c == ';':
+			if content {
+				stmts = append(stmts, strings.TrimSpace(sql[start:i]))
+			}
+			start, content = i+1, false
+		case c != ' ' && c != '\t' && c != '\n' && c != '\r':
+			content = true
+		}
+		i += n
+	}
+	if content {
+		stmts = append(stmts, strings.TrimSpace(sql[start:]))
+	}
+	return stmts
+}
+
+// runHookSQL executes the statements of the hook named name on the source. They
+// are executed in one session, so that the session variables set by the former
+// statements are seen by the latter ones.
+func runHookSQL(ctx context.Context, conf *Config, db *sql.DB, name, hookSQL string) error {
+	stmts := splitStatements(hookSQL)
+	if len(stmts) == 0 {
+		return nil
+	}
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		return withKind(ErrorKindConnection, errors.Annotatef(err, "run %s sql", name))
+	}
+	defer conn.Close()
+	for _, stmt := range stmts {
+		log.Info("run hook sql", zap.String("hook", name), zap.String("sql", stmt))
+		if _, err := conn.ExecContext(ctx, stmt); err != nil {
+			err = withKind(ErrorKindConsistency, errors.Annotatef(err, "run %s sql %s", name, stmt))
+			if conf.HookFailurePolicy != HookFailureWarn {
+				return err
+			}
+			log.Warn("hook sql failed, continue dumping", zap.String("hook", name), zap.Error(err))
+		}
+	}
+	return nil
+}
+
+// runAfterDumpCommand runs AfterDumpCommand with the shell of the system. The
+// result of the dump is given by the environment variables DUMPLING_STATUS,
+// which is success or failure, DUMPLING_ERROR and DUMPLING_OUTPUT_DIR.
+func runAfterDumpCommand(conf *Config, dumpErr error) error {
+	if conf.AfterDumpCommand == "" {
+		return nil
+	}
+	var cmd *exec.Cmd
+	if runtime.GOOS == "windows" {
+		cmd = exec.Command("cmd", "/C", conf.AfterDumpCommand)
+	} else {
+		cmd = exec.Command("sh", "-c", conf.AfterDumpCommand)
+	}
+	status, errMsg := "success", ""
+	if dumpErr != nil {
+		status, errMsg = "failure", RootCause(dumpErr).Error()
+	}
+	cmd.Env = append(os.Environ(),
+		"DUMPLING_STATUS="+status,
+		"DUMPLING_ERROR="+errMsg,
+		"DUMPLING_OUTPUT_DIR="+conf.OutputDirPath,
+	)
+	log.Info("run after dump command", zap.String("command", conf.AfterDumpCommand), zap.String("status", status))
+	output, err := cmd.CombinedOutput()
+	if err == nil {
+		log.Info("after dump command succeeded", zap.ByteString("output", output))
+		return nil
+	}
+	err = errors.Annotatef(err, "run after dump command %s: %s", conf.AfterDumpCommand, bytes.TrimSpace(output))
+	if conf.HookFailurePolicy == HookFailureWarn {
+		log.Warn("after dump command failed", zap.Error(err))
+		return nil
+	}
+	return err
+}
+
+func validateHookFailurePolicy(policy string) error {
+	switch policy {
+	case "", HookFailureAbort, HookFailureWarn:
+		return nil
+	default:
+		return fmt.Errorf("invalid hook failure policy %s", policy)
+	}
+}
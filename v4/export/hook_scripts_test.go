@@ -0,0 +1,85 @@
+package export
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"path/filepath"
+	"runtime"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	. "github.com/pingcap/check"
+)
+
+var _ = Suite(&testHookScriptsSuite{})
+
+type testHookScriptsSuite struct{}
+
+func (s *testHookScriptsSuite) TestSplitStatements(c *C) {
+	c.Assert(splitStatements(""), HasLen, 0)
+	c.Assert(splitStatements("FLUSH LOGS"), DeepEquals, []string{"FLUSH LOGS"})
+	c.Assert(splitStatements("SET @a = 'x;y'; SELECT `a;b`, \"c\\\";\" ;\n ; "), DeepEquals,
+		[]string{"SET @a = 'x;y'", "SELECT `a;b`, \"c\\\";\""})
+
+	// the semicolons in the comments don't split the statements
+	c.Assert(splitStatements("-- flush; lock\nFLUSH LOGS; # done;\nSELECT 1 /* a; b */ + 1; SELECT 2--1;\n-- the end;"), DeepEquals,
+		[]string{"-- flush; lock\nFLUSH LOGS", "# done;\nSELECT 1 /* a; b */ + 1", "SELECT 2--1"})
+	c.Assert(splitStatements("/*!40101 SET @a = 1; */; /* unclosed;"), DeepEquals, []string{"/*!40101 SET @a = 1; */"})
+	// the quotation marks in the comments don't start literals
+	c.Assert(splitStatements("-- it's\nSELECT 1; # don't\nSELECT 2; /* 'a */ SELECT 3"), DeepEquals,
+		[]string{"-- it's\nSELECT 1", "# don't\nSELECT 2", "/* 'a */ SELECT 3"})
+}
+
+func (s *testHookScriptsSuite) TestRunHookSQL(c *C) {
+	db, mock, err := sqlmock.New()
+	c.Assert(err, IsNil)
+	defer db.Close()
+
+	conf := DefaultConfig()
+	mock.ExpectExec("FLUSH LOGS").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec("SET GLOBAL a = 1").WillReturnError(errors.New("access denied"))
+	err = runHookSQL(context.Background(), conf, db, "before consistency", "FLUSH LOGS; SET GLOBAL a = 1; SELECT 1")
+	c.Assert(err, ErrorMatches, "run before consistency sql SET GLOBAL a = 1: access denied")
+	c.Assert(ErrorKindOf(err), Equals, ErrorKindConsistency)
+	c.Assert(mock.ExpectationsWereMet(), IsNil)
+
+	// the hook of only comments doesn't take a connection
+	c.Assert(runHookSQL(context.Background(), conf, db, "before consistency", "-- nothing to do;"), IsNil)
+	c.Assert(mock.ExpectationsWereMet(), IsNil)
+
+	conf.HookFailurePolicy = HookFailureWarn
+	mock.ExpectExec("SET GLOBAL a = 1").WillReturnError(errors.New("access denied"))
+	mock.ExpectExec("SELECT 1").WillReturnResult(sqlmock.NewResult(0, 0))
+	c.Assert(runHookSQL(context.Background(), conf, db, "after consistency", "SET GLOBAL a = 1; SELECT 1"), IsNil)
+	c.Assert(mock.ExpectationsWereMet(), IsNil)
+}
+
+func (s *testHookScriptsSuite) TestRunAfterDumpCommand(c *C) {
+	if runtime.GOOS == "windows" {
+		c.Skip("the command is a POSIX shell script")
+	}
+	dir := c.MkDir()
+	conf := DefaultConfig()
+	conf.OutputDirPath = dir
+	conf.AfterDumpCommand = `echo "$DUMPLING_STATUS $DUMPLING_ERROR" > "$DUMPLING_OUTPUT_DIR/result"`
+	c.Assert(runAfterDumpCommand(conf, nil), IsNil)
+	content, err := ioutil.ReadFile(filepath.Join(dir, "result"))
+	c.Assert(err, IsNil)
+	c.Assert(string(content), Equals, "success \n")
+
+	c.Assert(runAfterDumpCommand(conf, withKind(ErrorKindWrite, errors.New("disk full"))), IsNil)
+	content, err = ioutil.ReadFile(filepath.Join(dir, "result"))
+	c.Assert(err, IsNil)
+	c.Assert(string(content), Equals, "failure disk full\n")
+
+	conf.AfterDumpCommand = "echo uploading; exit 3"
+	c.Assert(runAfterDumpCommand(conf, nil), ErrorMatches, "(?s)run after dump command .*: exit status 3")
+	conf.HookFailurePolicy = HookFailureWarn
+	c.Assert(runAfterDumpCommand(conf, nil), IsNil)
+}
+
+func (s *testHookScriptsSuite) TestValidate(c *C) {
+	conf := DefaultConfig()
+	conf.HookFailurePolicy = "retry"
+	c.Assert(conf.Validate(), ErrorMatches, "invalid hook failure policy retry")
+}
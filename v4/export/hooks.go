@@ -0,0 +1,38 @@
+package export
+
+// Hooks observes the events of a dump, so that the programs embedding dumpling
+// can drive their own progress UIs and bookkeeping. The methods are called
+// concurrently by the dumping goroutines, and should return quickly.
+type Hooks interface {
+	// OnDumpStart is called when the tables to dump are listed and the consistency is ensured.
+	OnDumpStart(tables DatabaseTables)
+	// OnTableStart is called before the schema and data of a table or view are dumped.
+	OnTableStart(db, table string)
+	// OnChunkFinish is called after a chunk of table data is written.
+	OnChunkFinish(db, table string, chunkIndex int)
+	// OnFileClosed is called after an output file is written and closed.
+	OnFileClosed(path string)
+	// OnDumpFinish is called when Dump returns, err is nil if it succeeds.
+	OnDumpFinish(err error)
+}
+
+// NopHooks implements Hooks with empty methods, it can be embedded to
+// implement only part of the Hooks.
+type NopHooks struct{}
+
+func (NopHooks) OnDumpStart(DatabaseTables)        {}
+func (NopHooks) OnTableStart(string, string)       {}
+func (NopHooks) OnChunkFinish(string, string, int) {}
+func (NopHooks) OnFileClosed(string)               {}
+func (NopHooks) OnDumpFinish(error)                {}
+
+func (conf *Config) hooks() Hooks {
+	var hooks Hooks = NopHooks{}
+	if conf.Hooks != nil {
+		hooks = conf.Hooks
+	}
+	if conf.restorePlan != nil {
+		return restorePlanHooks{Hooks: hooks, plan: conf.restorePlan}
+	}
+	return hooks
+}
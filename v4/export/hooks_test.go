@@ -0,0 +1,93 @@
+package export
+
+import (
+	"context"
+	"database/sql/driver"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path"
+	"sync"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	. "github.com/pingcap/check"
+)
+
+var _ = Suite(&testHooksSuite{})
+
+type testHooksSuite struct{}
+
+type recordingHooks struct {
+	NopHooks
+	mu     sync.Mutex
+	events []string
+}
+
+func (h *recordingHooks) record(format string, args ...interface{}) {
+	h.mu.Lock()
+	h.events = append(h.events, fmt.Sprintf(format, args...))
+	h.mu.Unlock()
+}
+
+func (h *recordingHooks) OnTableStart(db, table string) {
+	h.record("table start %s.%s", db, table)
+}
+
+func (h *recordingHooks) OnChunkFinish(db, table string, chunkIndex int) {
+	h.record("chunk finish %s.%s.%d", db, table, chunkIndex)
+}
+
+func (h *recordingHooks) OnFileClosed(path string) {
+	h.record("file closed %s", path)
+}
+
+func (s *testHooksSuite) TestDumpTableHooks(c *C) {
+	hooks := &recordingHooks{}
+	mockConfig := DefaultConfig()
+	mockConfig.SortByPk = false
+	mockConfig.Hooks = hooks
+	db, mock, err := sqlmock.New()
+	c.Assert(err, IsNil)
+
+	rows := mock.NewRows([]string{"Table", "Create Table"}).AddRow("t", "CREATE TABLE t (a INT)")
+	mock.ExpectQuery("SHOW CREATE TABLE test.t").WillReturnRows(rows)
+	rows = mock.NewRows([]string{"column_name", "extra"}).AddRow("id", "").AddRow("name", "")
+	mock.ExpectQuery("SELECT COLUMN_NAME").WithArgs(sqlmock.AnyArg(), sqlmock.AnyArg()).WillReturnRows(rows)
+	rows = mock.NewRows([]string{"a"}).AddRow(1)
+	mock.ExpectQuery("SELECT (.) FROM `test`.`t` LIMIT 1").WillReturnRows(rows)
+	rows = mock.NewRows([]string{"a"}).AddRow(1).AddRow(2)
+	mock.ExpectQuery("SELECT (.) FROM `test`.`t`").WillReturnRows(rows)
+
+	err = dumpTable(context.Background(), mockConfig, db, "test", &TableInfo{Name: "t"}, newMockWriter())
+	c.Assert(err, IsNil)
+	c.Assert(hooks.events, DeepEquals, []string{"table start test.t", "chunk finish test.t.0"})
+	c.Assert(mock.ExpectationsWereMet(), IsNil)
+}
+
+func (s *testHooksSuite) TestWriterFileClosedHook(c *C) {
+	dir, err := ioutil.TempDir("", "dumpling")
+	c.Assert(err, IsNil)
+	defer os.RemoveAll(dir)
+
+	hooks := &recordingHooks{}
+	config := DefaultConfig()
+	config.OutputDirPath = dir
+	config.Hooks = hooks
+	ctx := context.Background()
+
+	writer, err := NewSimpleWriter(config)
+	c.Assert(err, IsNil)
+	err = writer.WriteTableMeta(ctx, "test", "t", "CREATE TABLE t (a INT)")
+	c.Assert(err, IsNil)
+	data := [][]driver.Value{{"1"}, {"2"}}
+	err = writer.WriteTableData(ctx, newMockTableIR("test", "t", data, nil, []string{"INT"}))
+	c.Assert(err, IsNil)
+	// no file for empty data
+	err = writer.WriteTableData(ctx, newMockTableIR("test", "empty", nil, nil, []string{"INT"}))
+	c.Assert(err, IsNil)
+
+	c.Assert(hooks.events, DeepEquals, []string{
+		"file closed " + path.Join(dir, "test.t-schema.sql"),
+		"file closed " + path.Join(dir, "test.t.0.sql"),
+	})
+}
@@ -1,6 +1,8 @@
 package export
 
 import (
+	"crypto/subtle"
+	"encoding/json"
 	"net"
 	"net/http"
 	"net/http/pprof"
@@ -18,17 +20,131 @@ var (
 	cmuxReadTimeout = 10 * time.Second
 )
 
-func startHTTPServer(lis net.Listener) {
+// jobStatus is the response of the status API.
+type jobStatus struct {
+	State    JobState       `json:"state"`
+	Progress ProgressStatus `json:"progress"`
+}
+
+func newStatusHandler(conf *Config) http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		writeJSON(w, http.StatusOK, jobStatus{
+			State:    conf.Controller.State(),
+			Progress: conf.Controller.Progress(),
+		})
+	}
+}
+
+// newControlHandler builds a handler which applies action on the job controller.
+// action returns false if it can't be applied in current state.
+func newControlHandler(conf *Config, action func(*JobController) bool) http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		if req.Method != http.MethodPost {
+			w.Header().Set("Allow", http.MethodPost)
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		code := http.StatusOK
+		if !action(conf.Controller) {
+			code = http.StatusConflict
+		}
+		writeJSON(w, code, jobStatus{
+			State:    conf.Controller.State(),
+			Progress: conf.Controller.Progress(),
+		})
+	}
+}
+
+// newHealthHandler is the liveness probe, it always responds OK while the
+// process is serving.
+func newHealthHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		_, _ = w.Write([]byte("ok\n"))
+	}
+}
+
+// newReadyHandler is the readiness probe, it responds 503 unless ready
+// returns true.
+func newReadyHandler(ready func() bool) http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		if !ready() {
+			http.Error(w, "not ready", http.StatusServiceUnavailable)
+			return
+		}
+		_, _ = w.Write([]byte("ok\n"))
+	}
+}
+
+// dumpReady returns whether the dump has started and isn't being stopped.
+func dumpReady(conf *Config) bool {
+	state := conf.Controller.State()
+	return conf.Progress.started() && state != JobStateStopping && state != JobStateFinished && state != JobStateFailed
+}
+
+// requireToken returns the handler responding 401 unless the request has the
+// bearer token, or h itself if token is empty.
+func requireToken(token string, h http.HandlerFunc) http.HandlerFunc {
+	if token == "" {
+		return h
+	}
+	expected := []byte("Bearer " + token)
+	return func(w http.ResponseWriter, req *http.Request) {
+		if subtle.ConstantTimeCompare([]byte(req.Header.Get("Authorization")), expected) != 1 {
+			w.Header().Set("WWW-Authenticate", "Bearer")
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		h(w, req)
+	}
+}
+
+// warnUnauthenticatedAddr warns if the API on addr is reachable from the
+// other hosts without a token.
+func warnUnauthenticatedAddr(addr, token string) {
+	if token != "" {
+		return
+	}
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil || host == "localhost" {
+		return
+	}
+	if ip := net.ParseIP(host); ip != nil && ip.IsLoopback() {
+		return
+	}
+	log.Warn("the API is served on a non-loopback address without status-token, anyone reaching it can control the dump",
+		zap.String("address", addr))
+}
+
+func writeJSON(w http.ResponseWriter, code int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		log.Warn("write http response failed", zap.Error(err))
+	}
+}
+
+func newHTTPHandler(conf *Config) http.Handler {
 	router := http.NewServeMux()
+	token := conf.StatusToken
+	router.HandleFunc("/status", requireToken(token, newStatusHandler(conf)))
+	router.HandleFunc("/pause", requireToken(token, newControlHandler(conf, (*JobController).Pause)))
+	router.HandleFunc("/resume", requireToken(token, newControlHandler(conf, (*JobController).Resume)))
+	router.HandleFunc("/stop", requireToken(token, newControlHandler(conf, (*JobController).Stop)))
+	// the probes reveal nothing, so they're served without the token
+	router.HandleFunc("/healthz", newHealthHandler())
+	router.HandleFunc("/readyz", newReadyHandler(func() bool { return dumpReady(conf) }))
 
-	router.HandleFunc("/debug/pprof/", pprof.Index)
-	router.HandleFunc("/debug/pprof/cmdline", pprof.Cmdline)
-	router.HandleFunc("/debug/pprof/profile", pprof.Profile)
-	router.HandleFunc("/debug/pprof/symbol", pprof.Symbol)
-	router.HandleFunc("/debug/pprof/trace", pprof.Trace)
+	router.HandleFunc("/debug/pprof/", requireToken(token, pprof.Index))
+	router.HandleFunc("/debug/pprof/cmdline", requireToken(token, pprof.Cmdline))
+	router.HandleFunc("/debug/pprof/profile", requireToken(token, pprof.Profile))
+	router.HandleFunc("/debug/pprof/symbol", requireToken(token, pprof.Symbol))
+	router.HandleFunc("/debug/pprof/trace", requireToken(token, pprof.Trace))
+	return router
+}
 
+func startHTTPServer(lis net.Listener, conf *Config) {
 	httpServer := &http.Server{
-		Handler: router,
+		Handler: newHTTPHandler(conf),
 	}
 	err := httpServer.Serve(lis)
 	if err != nil && !isErrNetClosing(err) && err != http.ErrServerClosed {
@@ -36,18 +152,19 @@ func startHTTPServer(lis net.Listener) {
 	}
 }
 
-func startDumplingService(addr string) error {
-	rootLis, err := net.Listen("tcp", addr)
+func startDumplingService(conf *Config) error {
+	rootLis, err := net.Listen("tcp", conf.StatusAddr)
 	if err != nil {
 		return errors.Annotate(err, "start listening")
 	}
+	warnUnauthenticatedAddr(conf.StatusAddr, conf.StatusToken)
 
 	// create a cmux
 	m := cmux.New(rootLis)
 	m.SetReadTimeout(cmuxReadTimeout) // set a timeout, ref: https://github.com/pingcap/tidb-binlog/pull/352
 
 	httpL := m.Match(cmux.HTTP1Fast())
-	go startHTTPServer(httpL)
+	go startHTTPServer(httpL, conf)
 
 	err = m.Serve() // start serving, block
 	if err != nil && isErrNetClosing(err) {
@@ -0,0 +1,98 @@
+package export
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"time"
+
+	. "github.com/pingcap/check"
+)
+
+var _ = Suite(&testHTTPHandlerSuite{})
+
+type testHTTPHandlerSuite struct{}
+
+func (s *testHTTPHandlerSuite) TestControlHandler(c *C) {
+	conf := DefaultConfig()
+	conf.Progress = NewProgress()
+	conf.Controller = NewJobController()
+	conf.Controller.track(conf.Progress)
+	conf.Progress.addRows(10, 100)
+
+	var status jobStatus
+	w := httptest.NewRecorder()
+	newStatusHandler(conf)(w, httptest.NewRequest(http.MethodGet, "/status", nil))
+	c.Assert(w.Code, Equals, http.StatusOK)
+	c.Assert(json.Unmarshal(w.Body.Bytes(), &status), IsNil)
+	c.Assert(status.State, Equals, JobStateRunning)
+	c.Assert(status.Progress.FinishedRows, Equals, uint64(10))
+
+	pause := newControlHandler(conf, (*JobController).Pause)
+	w = httptest.NewRecorder()
+	pause(w, httptest.NewRequest(http.MethodGet, "/pause", nil))
+	c.Assert(w.Code, Equals, http.StatusMethodNotAllowed)
+
+	w = httptest.NewRecorder()
+	pause(w, httptest.NewRequest(http.MethodPost, "/pause", nil))
+	c.Assert(w.Code, Equals, http.StatusOK)
+	c.Assert(json.Unmarshal(w.Body.Bytes(), &status), IsNil)
+	c.Assert(status.State, Equals, JobStatePaused)
+
+	w = httptest.NewRecorder()
+	pause(w, httptest.NewRequest(http.MethodPost, "/pause", nil))
+	c.Assert(w.Code, Equals, http.StatusConflict)
+}
+
+func (s *testHTTPHandlerSuite) TestProbes(c *C) {
+	conf := DefaultConfig()
+	conf.Progress = NewProgress()
+	conf.Controller = NewJobController()
+
+	w := httptest.NewRecorder()
+	newHealthHandler()(w, httptest.NewRequest(http.MethodGet, "/healthz", nil))
+	c.Assert(w.Code, Equals, http.StatusOK)
+
+	ready := newReadyHandler(func() bool { return dumpReady(conf) })
+	w = httptest.NewRecorder()
+	ready(w, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+	c.Assert(w.Code, Equals, http.StatusServiceUnavailable)
+
+	conf.Progress.start(time.Now())
+	w = httptest.NewRecorder()
+	ready(w, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+	c.Assert(w.Code, Equals, http.StatusOK)
+
+	c.Assert(conf.Controller.Stop(), IsTrue)
+	w = httptest.NewRecorder()
+	ready(w, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+	c.Assert(w.Code, Equals, http.StatusServiceUnavailable)
+}
+
+func (s *testHTTPHandlerSuite) TestRequireToken(c *C) {
+	conf := DefaultConfig()
+	conf.Progress = NewProgress()
+	conf.Controller = NewJobController()
+	conf.Controller.track(conf.Progress)
+	conf.StatusToken = "secret"
+	handler := newHTTPHandler(conf)
+
+	for _, path := range []string{"/status", "/pause", "/resume", "/stop", "/debug/pprof/"} {
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, httptest.NewRequest(http.MethodPost, path, nil))
+		c.Assert(w.Code, Equals, http.StatusUnauthorized, Commentf("path %s", path))
+	}
+	c.Assert(conf.Controller.State(), Equals, JobStateRunning)
+
+	req := httptest.NewRequest(http.MethodPost, "/stop", nil)
+	req.Header.Set("Authorization", "Bearer secret")
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+	c.Assert(w.Code, Equals, http.StatusOK)
+	c.Assert(conf.Controller.State(), Equals, JobStateStopping)
+
+	// the probes don't need the token
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/healthz", nil))
+	c.Assert(w.Code, Equals, http.StatusOK)
+}
@@ -0,0 +1,185 @@
+package export
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/pingcap/errors"
+	"go.uber.org/zap"
+
+	"github.com/pingcap/dumpling/v4/log"
+)
+
+// defaultIncrementalStateFile is the name of the incremental state file in
+// the output directory if IncrementalStateFile is not set.
+const defaultIncrementalStateFile = "incremental-state.json"
+
+// incrementalTable is the high watermark of a table dumped in the previous runs.
+type incrementalTable struct {
+	Column    string `json:"column"`
+	Watermark string `json:"watermark"`
+}
+
+// incrementalState is the content of the incremental state file. The
+// watermarks of a run are saved only after all the tables are dumped, so the
+// failed runs are dumped again from the same watermarks.
+type incrementalState struct {
+	// Runs is the number of the successful runs, the data files of a run are
+	// the deltas numbered Runs since the second run.
+	Runs   int                         `json:"runs"`
+	Tables map[string]incrementalTable `json:"tables"`
+
+	mu sync.Mutex
+	// next is the watermarks of this run
+	next map[string]incrementalTable
+}
+
+// incrementalStatePath returns the path of the incremental state file.
+func (conf *Config) incrementalStatePath() string {
+	if conf.IncrementalStateFile != "" {
+		return conf.IncrementalStateFile
+	}
+	return filepath.Join(conf.OutputDirPath, defaultIncrementalStateFile)
+}
+
+// loadIncrementalState reads the state file of path, it's the state before
+// the first run if the file doesn't exist.
+func loadIncrementalState(path string) (*incrementalState, error) {
+	state := &incrementalState{}
+	content, err := ioutil.ReadFile(path)
+	switch {
+	case os.IsNotExist(err):
+	case err != nil:
+		return nil, withStack(withKind(ErrorKindConfig, err))
+	default:
+		if err = json.Unmarshal(content, state); err != nil {
+			return nil, withStack(withKind(ErrorKindConfig, errors.WithMessage(err, path)))
+		}
+	}
+	if state.Tables == nil {
+		state.Tables = map[string]incrementalTable{}
+	}
+	state.next = make(map[string]incrementalTable, len(state.Tables))
+	for key, table := range state.Tables {
+		state.next[key] = table
+	}
+	return state, nil
+}
+
+// delta returns the number of the delta files of this run, 0 if it's the
+// first run or not incremental.
+func (s *incrementalState) delta() int {
+	if s == nil {
+		return 0
+	}
+	return s.Runs
+}
+
+// save writes the watermarks of this run into the incremental state file.
+// The file in the output directory is written by the storage of the dump, and
+// the file of IncrementalStateFile by a LocalStorage of its directory, so
+// that it's replaced only after written completely.
+func (s *incrementalState) save(conf *Config) error {
+	if s == nil {
+		return nil
+	}
+	s.mu.Lock()
+	state := incrementalState{Runs: s.Runs + 1, Tables: s.next}
+	s.mu.Unlock()
+	content, err := json.MarshalIndent(&state, "", "  ")
+	if err != nil {
+		return withStack(err)
+	}
+	storage, name := conf.ExternalStorage, defaultIncrementalStateFile
+	if conf.IncrementalStateFile != "" {
+		if storage, err = NewLocalStorage(filepath.Dir(conf.IncrementalStateFile), conf.SyncFiles); err != nil {
+			return withStack(err)
+		}
+		name = filepath.Base(conf.IncrementalStateFile)
+	}
+	fileWriter, err := storage.Create(context.Background(), name)
+	if err != nil {
+		return withStack(err)
+	}
+	return withStack(closeFile(fileWriter, write(fileWriter, string(content))))
+}
+
+// forIncrementalTable returns conf whose Where selects the rows changed since
+// the last run, i.e. the IncrementalColumn greater than the last watermark and
+// not greater than the current one. The current watermark is recorded to be
+// saved after the run. The tables without the column are dumped entirely.
+func (conf *Config) forIncrementalTable(db *sql.DB, database, table string) (*Config, error) {
+	d := conf.dialect()
+	columns, err := d.ListColumns(db, database, table)
+	if err != nil {
+		return nil, withKind(ErrorKindSchema, err)
+	}
+	found := false
+	for _, col := range columns {
+		if col.Name == conf.IncrementalColumn {
+			found = true
+			break
+		}
+	}
+	if !found {
+		log.Warn("dump the entire table without the incremental column",
+			zap.String("database", database), zap.String("table", table),
+			zap.String("column", conf.IncrementalColumn))
+		return conf, nil
+	}
+
+	column := d.QuoteIdentifier(conf.IncrementalColumn)
+	query := fmt.Sprintf("SELECT MAX(%s) FROM %s%s", column, qualifiedName(d, database, table), buildWhereCondition(conf, ""))
+	var watermark sql.NullString
+	if err = db.QueryRow(query).Scan(&watermark); err != nil {
+		return nil, withStack(errors.WithMessage(err, query))
+	}
+
+	state := conf.incremental
+	key := fmt.Sprintf("%s.%s", database, table)
+	state.mu.Lock()
+	last, hasLast := state.Tables[key]
+	hasLast = hasLast && last.Column == conf.IncrementalColumn
+	if watermark.Valid {
+		state.next[key] = incrementalTable{Column: conf.IncrementalColumn, Watermark: watermark.String}
+	}
+	state.mu.Unlock()
+
+	// the first run dumps the entire table up to the watermark, including the
+	// rows whose column is NULL, so that the next run doesn't dump the rows
+	// written since the watermark again
+	if !hasLast && !watermark.Valid {
+		return conf, nil
+	}
+	var conditions []string
+	if conf.Where != "" {
+		conditions = append(conditions, "("+conf.Where+")")
+	}
+	switch {
+	case !hasLast:
+		conditions = append(conditions, fmt.Sprintf("(%s <= %s OR %s IS NULL)", column, conf.quoteLiteral(watermark.String), column))
+	case watermark.Valid:
+		conditions = append(conditions, fmt.Sprintf("%s > %s", column, conf.quoteLiteral(last.Watermark)),
+			fmt.Sprintf("%s <= %s", column, conf.quoteLiteral(watermark.String)))
+	default:
+		conditions = append(conditions, fmt.Sprintf("%s > %s", column, conf.quoteLiteral(last.Watermark)))
+	}
+	tableConf := *conf
+	tableConf.Where = strings.Join(conditions, " AND ")
+	return &tableConf, nil
+}
+
+// quoteLiteral returns s as a string literal of the source dialect.
+func (conf *Config) quoteLiteral(s string) string {
+	if conf.SourceDialect == DialectPostgres {
+		return "'" + strings.Replace(s, "'", "''", -1) + "'"
+	}
+	return "'" + escapeSQLString(s) + "'"
+}
@@ -0,0 +1,142 @@
+package export
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	. "github.com/pingcap/check"
+)
+
+var _ = Suite(&testIncrementalSuite{})
+
+type testIncrementalSuite struct{}
+
+func expectIncrementalColumns(mock sqlmock.Sqlmock, names ...string) {
+	columns := []string{"COLUMN_NAME", "DATA_TYPE", "COLUMN_TYPE", "IS_NULLABLE", "CHARACTER_MAXIMUM_LENGTH", "NUMERIC_PRECISION", "NUMERIC_SCALE"}
+	rows := sqlmock.NewRows(columns)
+	for _, name := range names {
+		rows.AddRow(name, "datetime", "datetime", "YES", nil, nil, nil)
+	}
+	mock.ExpectQuery("SELECT COLUMN_NAME, DATA_TYPE").WithArgs("test", "t").WillReturnRows(rows)
+}
+
+func (s *testIncrementalSuite) TestForIncrementalTable(c *C) {
+	db, mock, err := sqlmock.New()
+	c.Assert(err, IsNil)
+	defer db.Close()
+
+	path := filepath.Join(c.MkDir(), "state.json")
+	conf := DefaultConfig()
+	conf.IncrementalColumn = "updated_at"
+	conf.IncrementalStateFile = path
+	conf.Where = "a > 1"
+	conf.incremental, err = loadIncrementalState(path)
+	c.Assert(err, IsNil)
+	c.Assert(conf.incremental.delta(), Equals, 0)
+
+	// the first run dumps the entire table up to the watermark
+	expectIncrementalColumns(mock, "id", "updated_at")
+	mock.ExpectQuery("SELECT MAX\\(`updated_at`\\) FROM `test`.`t` WHERE a > 1").
+		WillReturnRows(sqlmock.NewRows([]string{"MAX"}).AddRow("2020-10-01 10:00:00"))
+	tableConf, err := conf.forIncrementalTable(db, "test", "t")
+	c.Assert(err, IsNil)
+	c.Assert(tableConf.Where, Equals, "(a > 1) AND (`updated_at` <= '2020-10-01 10:00:00' OR `updated_at` IS NULL)")
+	c.Assert(mock.ExpectationsWereMet(), IsNil)
+	c.Assert(conf.incremental.save(conf), IsNil)
+	_, err = os.Stat(path + partFileSuffix)
+	c.Assert(os.IsNotExist(err), IsTrue)
+
+	conf.incremental, err = loadIncrementalState(path)
+	c.Assert(err, IsNil)
+	c.Assert(conf.incremental.delta(), Equals, 1)
+	expectIncrementalColumns(mock, "id", "updated_at")
+	mock.ExpectQuery("SELECT MAX").
+		WillReturnRows(sqlmock.NewRows([]string{"MAX"}).AddRow("2020-10-02 10:00:00"))
+	tableConf, err = conf.forIncrementalTable(db, "test", "t")
+	c.Assert(err, IsNil)
+	c.Assert(tableConf.Where, Equals, "(a > 1) AND `updated_at` > '2020-10-01 10:00:00' AND `updated_at` <= '2020-10-02 10:00:00'")
+	c.Assert(conf.Where, Equals, "a > 1")
+
+	// the watermark is kept if there aren't any rows
+	conf.incremental, err = loadIncrementalState(path)
+	c.Assert(err, IsNil)
+	expectIncrementalColumns(mock, "id", "updated_at")
+	mock.ExpectQuery("SELECT MAX").WillReturnRows(sqlmock.NewRows([]string{"MAX"}).AddRow(nil))
+	conf.Where = ""
+	tableConf, err = conf.forIncrementalTable(db, "test", "t")
+	c.Assert(err, IsNil)
+	c.Assert(tableConf.Where, Equals, "`updated_at` > '2020-10-01 10:00:00'")
+	c.Assert(conf.incremental.next["test.t"].Watermark, Equals, "2020-10-01 10:00:00")
+	c.Assert(mock.ExpectationsWereMet(), IsNil)
+
+	// the first run of a table without the watermark dumps the entire table
+	conf.incremental, err = loadIncrementalState(filepath.Join(c.MkDir(), "state.json"))
+	c.Assert(err, IsNil)
+	expectIncrementalColumns(mock, "id", "updated_at")
+	mock.ExpectQuery("SELECT MAX").WillReturnRows(sqlmock.NewRows([]string{"MAX"}).AddRow(nil))
+	tableConf, err = conf.forIncrementalTable(db, "test", "t")
+	c.Assert(err, IsNil)
+	c.Assert(tableConf.Where, Equals, "")
+	c.Assert(mock.ExpectationsWereMet(), IsNil)
+}
+
+func (s *testIncrementalSuite) TestSaveIntoStorage(c *C) {
+	conf := DefaultConfig()
+	conf.IncrementalColumn = "updated_at"
+	storage := newMemStorage()
+	conf.ExternalStorage = storage
+	var err error
+	conf.incremental, err = loadIncrementalState(conf.incrementalStatePath())
+	c.Assert(err, IsNil)
+	conf.incremental.next["test.t"] = incrementalTable{Column: "updated_at", Watermark: "2020-10-01 10:00:00"}
+
+	// the state file in the output directory is written by the storage of the dump
+	c.Assert(conf.incremental.save(conf), IsNil)
+	var state incrementalState
+	c.Assert(json.Unmarshal([]byte(storage.files[defaultIncrementalStateFile]), &state), IsNil)
+	c.Assert(state.Runs, Equals, 1)
+	c.Assert(state.Tables, DeepEquals, map[string]incrementalTable{
+		"test.t": {Column: "updated_at", Watermark: "2020-10-01 10:00:00"},
+	})
+}
+
+func (s *testIncrementalSuite) TestWithoutIncrementalColumn(c *C) {
+	db, mock, err := sqlmock.New()
+	c.Assert(err, IsNil)
+	defer db.Close()
+
+	conf := DefaultConfig()
+	conf.IncrementalColumn = "updated_at"
+	conf.incremental, err = loadIncrementalState(filepath.Join(c.MkDir(), "state.json"))
+	c.Assert(err, IsNil)
+	expectIncrementalColumns(mock, "id")
+	tableConf, err := conf.forIncrementalTable(db, "test", "t")
+	c.Assert(err, IsNil)
+	c.Assert(tableConf, Equals, conf)
+	c.Assert(mock.ExpectationsWereMet(), IsNil)
+}
+
+func (s *testIncrementalSuite) TestDeltaFileName(c *C) {
+	conf := DefaultConfig()
+	ir := newMockTableIR("test", "t", nil, nil, nil)
+	namer := newOutputFileNamer(conf, ir)
+	c.Assert(namer.NextName(), Equals, "test.t.0")
+
+	conf.incremental = &incrementalState{Runs: 2}
+	namer = newOutputFileNamer(conf, ir)
+	c.Assert(namer.NextName(), Equals, "test.t.delta2.0")
+	c.Assert(namer.NextName(), Equals, "test.t.delta2.1")
+}
+
+func (s *testIncrementalSuite) TestValidate(c *C) {
+	conf := DefaultConfig()
+	conf.IncrementalColumn = "updated_at"
+	c.Assert(conf.Validate(), IsNil)
+	conf.FileType = "sqlite"
+	c.Assert(conf.Validate(), ErrorMatches, "incremental-column is only supported with filetype sql, csv and tsv")
+	conf.FileType = "csv"
+	conf.Sql = "SELECT 1"
+	c.Assert(conf.Validate(), ErrorMatches, "incremental-column is not supported with sql or target-dsn")
+}
@@ -15,8 +15,18 @@ type TableDataIR interface {
 	ColumnNames() []string
 	SelectedField() string
 	EscapeBackSlash() bool
+	// Output is the dialect of the INSERT statements.
+	Output() OutputDialect
+	// QuoteBigIntegers is whether the integers out of the range of BIGINT are
+	// quoted in the SQL and CSV files.
+	QuoteBigIntegers() bool
 
 	SpecialComments() StringIter
+	// SpecialFooters are written after all the rows in every data file.
+	SpecialFooters() StringIter
+	// MysqldumpCompleted is whether every data file ends with the mysqldump
+	// line of the time it's completed, after SpecialFooters.
+	MysqldumpCompleted() bool
 	Rows() SQLRowIter
 }
 
@@ -40,6 +50,8 @@ type RowReceiverStringer interface {
 type Stringer interface {
 	WriteToBuffer(*bytes.Buffer, bool)
 	WriteToBufferInCsv(*bytes.Buffer, bool, string)
+	// WriteToBufferInTsv writes the value in the TabSeparated format of ClickHouse.
+	WriteToBufferInTsv(*bytes.Buffer)
 }
 
 type RowReceiver interface {
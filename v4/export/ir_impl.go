@@ -18,6 +18,8 @@ type rowIter struct {
 	rows    *sql.Rows
 	hasNext bool
 	args    []interface{}
+	// release is called after the rows are closed if it's not nil
+	release func()
 }
 
 func newRowIter(rows *sql.Rows, argLen int) *rowIter {
@@ -31,7 +33,11 @@ func newRowIter(rows *sql.Rows, argLen int) *rowIter {
 }
 
 func (iter *rowIter) Close() error {
-	return iter.rows.Close()
+	err := iter.rows.Close()
+	if iter.release != nil {
+		iter.release()
+	}
+	return err
 }
 
 func (iter *rowIter) Decode(row RowReceiver) error {
@@ -65,6 +71,9 @@ type fileRowIter struct {
 
 	currentStatementSize uint64
 	currentFileSize      uint64
+	// newStatement is whether the row decoded last is larger than a statement,
+	// so that it ends the statement of the rows before it and starts its own
+	newStatement bool
 }
 
 func (c *fileRowIter) Close() error {
@@ -78,10 +87,21 @@ func (c *fileRowIter) Decode(row RowReceiver) error {
 	}
 	size := row.ReportSize()
 	c.currentFileSize += size
-	c.currentStatementSize += size
+	c.newStatement = c.statementSizeLimit != UnspecifiedSize && c.currentStatementSize > 0 && size >= c.statementSizeLimit
+	if c.newStatement {
+		c.currentStatementSize = size
+	} else {
+		c.currentStatementSize += size
+	}
 	return nil
 }
 
+// startsStatement returns whether the row decoded last starts a statement,
+// which is ended after it, instead of joining the rows before it.
+func (c *fileRowIter) startsStatement() bool {
+	return c.newStatement
+}
+
 func (c *fileRowIter) Error() error {
 	return c.rowIter.Error()
 }
@@ -144,14 +164,57 @@ func (m *stringIter) HasNext() bool {
 }
 
 type tableData struct {
-	database        string
-	table           string
-	chunkIndex      int
-	rows            *sql.Rows
-	colTypes        []*sql.ColumnType
-	selectedField   string
-	specCmts        []string
-	escapeBackslash bool
+	database      string
+	table         string
+	chunkIndex    int
+	rows          *sql.Rows
+	conn          *sql.Conn
+	colTypes      []*sql.ColumnType
+	selectedField string
+	specCmts      []string
+	specFooters   []string
+	// mysqldumpCompleted is whether the files end with the mysqldump line of
+	// the time they're completed
+	mysqldumpCompleted bool
+	escapeBackslash    bool
+	// output is the dialect of the INSERT statements, it's MySQL if nil
+	output           OutputDialect
+	quoteBigIntegers bool
+	// pager queries the next pages of rows if FetchRows is set
+	pager *tablePager
+	// chunkRange is the key range of the chunk, it's empty for the whole table
+	chunkRange string
+	// ctx is the context of the queries of the rows, which is canceled by
+	// cancel after the rows are read
+	ctx    context.Context
+	cancel context.CancelFunc
+}
+
+// release cancels the context of the queries of td.
+func (td *tableData) release() {
+	if td.cancel != nil {
+		td.cancel()
+	}
+}
+
+// queryContext returns the context of the queries of td.
+func (td *tableData) queryContext() context.Context {
+	if td.ctx == nil {
+		return context.Background()
+	}
+	return td.ctx
+}
+
+func (td *tableData) takeWarnings(ctx context.Context) ([]sqlWarning, error) {
+	defer td.release()
+	if td.conn == nil {
+		return nil, nil
+	}
+	defer td.conn.Close()
+	if err := td.rows.Close(); err != nil {
+		return nil, withStack(err)
+	}
+	return showWarnings(ctx, td.conn)
 }
 
 func (td *tableData) ColumnTypes() []string {
@@ -187,24 +250,52 @@ func (td *tableData) ColumnCount() uint {
 }
 
 func (td *tableData) Rows() SQLRowIter {
-	return newRowIter(td.rows, len(td.colTypes))
+	if td.pager != nil {
+		return newPagedRowIter(td)
+	}
+	iter := newRowIter(td.rows, len(td.colTypes))
+	iter.release = td.release
+	return iter
 }
 
 func (td *tableData) SelectedField() string {
 	if td.selectedField == "*" {
 		return ""
 	}
-	return fmt.Sprintf("(%s)", td.selectedField)
+	if td.output == nil || td.selectedField == "" {
+		return fmt.Sprintf("(%s)", td.selectedField)
+	}
+	// the selected field is quoted by the source
+	return fmt.Sprintf("(%s)", quoteIdentifiers(td.output, td.ColumnNames()))
 }
 
 func (td *tableData) SpecialComments() StringIter {
 	return newStringIter(td.specCmts...)
 }
 
+func (td *tableData) SpecialFooters() StringIter {
+	return newStringIter(td.specFooters...)
+}
+
 func (td *tableData) EscapeBackSlash() bool {
 	return td.escapeBackslash
 }
 
+func (td *tableData) QuoteBigIntegers() bool {
+	return td.quoteBigIntegers
+}
+
+func (td *tableData) MysqldumpCompleted() bool {
+	return td.mysqldumpCompleted
+}
+
+func (td *tableData) Output() OutputDialect {
+	if td.output == nil {
+		return mysqlOutput{}
+	}
+	return td.output
+}
+
 type tableDataChunks struct {
 	TableDataIR
 	rows               SQLRowIter
@@ -250,12 +341,16 @@ func splitTableDataIntoChunks(
 	if field == "" {
 		// skip split chunk logic if not found proper field
 		log.Debug("skip concurrent dump due to no proper field", zap.String("field", field))
-		linear <- struct{}{}
+		select {
+		case linear <- struct{}{}:
+		case <-ctx.Done():
+		}
 		return
 	}
 
-	query := fmt.Sprintf("SELECT MIN(`%s`),MAX(`%s`) FROM `%s`.`%s` ",
-		field, field, dbName, tableName)
+	// the range of the rows read as of the snapshot of stale read
+	query := fmt.Sprintf("SELECT MIN(`%s`),MAX(`%s`) FROM `%s`.`%s`%s ",
+		field, field, dbName, tableName, conf.asOfClause())
 	if conf.Where != "" {
 		query = fmt.Sprintf("%s WHERE %s", query, conf.Where)
 	}
@@ -295,14 +390,16 @@ func splitTableDataIntoChunks(
 			zap.Uint64("estimate count", count),
 			zap.Uint64("conf.rows", conf.Rows),
 		)
-		linear <- struct{}{}
+		select {
+		case linear <- struct{}{}:
+		case <-ctx.Done():
+		}
 		return
 	}
 
 	// every chunk would have eventual adjustments
 	estimatedChunks := count / conf.Rows
 	estimatedStep := (max-min)/estimatedChunks + 1
-	cutoff := min
 
 	selectedField, err := buildSelectField(db, dbName, tableName)
 	if err != nil {
@@ -310,7 +407,7 @@ func splitTableDataIntoChunks(
 		return
 	}
 
-	colTypes, err := GetColumnTypes(db, selectedField, dbName, tableName)
+	colTypes, err := GetColumnTypes(db, selectedField, wrapBackTicks(dbName), wrapBackTicks(tableName))
 	if err != nil {
 		errCh <- withStack(err)
 		return
@@ -321,32 +418,78 @@ func splitTableDataIntoChunks(
 		return
 	}
 
+	// the oversized chunks are split into parts and the chunks timing out are
+	// split into halves, which are queried next
+	pending := []chunkBounds{{lo: min, hi: min + estimatedStep, planned: true}}
 	chunkIndex := 0
+	planChecked := false
 LOOP:
-	for cutoff <= max {
+	for len(pending) > 0 {
+		bounds := pending[len(pending)-1]
+		pending = pending[:len(pending)-1]
+		if bounds.planned && bounds.hi <= max {
+			// the next chunk of the plan
+			pending = append(pending, chunkBounds{lo: bounds.hi, hi: bounds.hi + estimatedStep, planned: true})
+		}
+		where := fmt.Sprintf("(`%s` >= %d AND `%s` < %d)", field, bounds.lo, field, bounds.hi)
+		query = buildSelectQuery(wrapBackTicks(dbName), wrapBackTicks(tableName)+conf.asOfClause(), selectedField, buildWhereCondition(conf, where), orderByClause)
+		if bounds.planned {
+			if parts := bounds.splitOversized(estimateChunkRows(db, query), conf.Rows); parts != nil {
+				log.Info("split the oversized chunk",
+					zap.String("database", dbName), zap.String("table", tableName),
+					zap.String("range", where), zap.Int("parts", len(parts)))
+				for i := len(parts) - 1; i >= 0; i-- {
+					pending = append(pending, parts[i])
+				}
+				continue
+			}
+		}
 		chunkIndex += 1
-		where := fmt.Sprintf("(`%s` >= %d AND `%s` < %d)", field, cutoff, field, cutoff+estimatedStep)
-		query = buildSelectQuery(dbName, tableName, selectedField, buildWhereCondition(conf, where), orderByClause)
-		rows, err := db.Query(query)
+		if !planChecked {
+			checkChunkPlan(db, conf.ServerInfo.ServerType, dbName, tableName, field, query)
+			planChecked = true
+		}
+		queryCtx, cancel := conf.chunkContext(ctx)
+		rows, conn, err := queryTableData(queryCtx, conf, conf.replicas.pick(db), query)
 		if err != nil {
+			cancel()
+			if first, second, ok := bounds.resplit(); ok && isQueryTimeout(err) {
+				log.Warn("split the chunk timing out into halves",
+					zap.String("database", dbName), zap.String("table", tableName),
+					zap.String("range", where), zap.Uint64("chunk-timeout", conf.ChunkTimeout))
+				// the chunk index is taken by the halves
+				chunkIndex -= 1
+				pending = append(pending, second, first)
+				continue
+			}
 			errCh <- errors.WithMessage(err, query)
 			return
 		}
 
 		td := &tableData{
-			database:      dbName,
-			table:         tableName,
-			rows:          rows,
-			chunkIndex:    chunkIndex,
-			colTypes:      colTypes,
-			selectedField: selectedField,
-			specCmts: []string{
-				"/*!40101 SET NAMES binary*/;",
-			},
+			database:           dbName,
+			table:              tableName,
+			rows:               rows,
+			conn:               conn,
+			chunkIndex:         chunkIndex,
+			chunkRange:         where,
+			colTypes:           colTypes,
+			selectedField:      selectedField,
+			output:             conf.output(),
+			quoteBigIntegers:   conf.QuoteBigIntegers,
+			specCmts:           buildSpecialComments(conf, dbName, tableName),
+			specFooters:        buildSpecialFooters(conf, tableName),
+			mysqldumpCompleted: writesMysqldumpCompleted(conf),
+			ctx:                queryCtx,
+			cancel:             cancel,
 		}
-		cutoff += estimatedStep
 		select {
 		case <-ctx.Done():
+			rows.Close()
+			if conn != nil {
+				conn.Close()
+			}
+			cancel()
 			break LOOP
 		case tableDataIRCh <- td:
 		}
@@ -0,0 +1,537 @@
+package export
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"math"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/Shopify/sarama"
+	"github.com/pingcap/errors"
+	"go.uber.org/zap"
+
+	"github.com/pingcap/dumpling/v4/log"
+)
+
+const (
+	// KafkaFormatJSON encodes the messages as JSON objects of the columns.
+	KafkaFormatJSON = "json"
+	// KafkaFormatAvro encodes the messages as Avro records in the wire format
+	// of the Confluent Schema Registry.
+	KafkaFormatAvro = "avro"
+
+	// KafkaKeyPK keys the messages by the primary key columns.
+	KafkaKeyPK = "pk"
+	// KafkaKeyNone sends the messages without keys.
+	KafkaKeyNone = "none"
+
+	// defaultKafkaBatchSize is the bytes of the messages sent at once if
+	// StatementSize is not set.
+	defaultKafkaBatchSize = 1 << 20
+)
+
+// kafkaFieldType is the type of a column in the messages.
+type kafkaFieldType int
+
+const (
+	kafkaString kafkaFieldType = iota
+	kafkaLong
+	kafkaDouble
+	// kafkaNumber is a number not fitting in long or double, it's a number in
+	// JSON and a string in Avro.
+	kafkaNumber
+	kafkaBoolean
+	kafkaBytes
+)
+
+func kafkaFieldTypeOf(col ColumnInfo) kafkaFieldType {
+	switch col.DataType {
+	case "tinyint", "smallint", "mediumint", "int", "year":
+		return kafkaLong
+	case "bigint":
+		if col.Unsigned {
+			return kafkaNumber
+		}
+		return kafkaLong
+	case "decimal":
+		return kafkaNumber
+	case "float", "double":
+		return kafkaDouble
+	case "bool":
+		return kafkaBoolean
+	case "bit", "binary", "varbinary", "tinyblob", "blob", "mediumblob", "longblob":
+		return kafkaBytes
+	default:
+		return kafkaString
+	}
+}
+
+func (t kafkaFieldType) avroType() string {
+	switch t {
+	case kafkaLong:
+		return "long"
+	case kafkaDouble:
+		return "double"
+	case kafkaBoolean:
+		return "boolean"
+	case kafkaBytes:
+		return "bytes"
+	default:
+		return "string"
+	}
+}
+
+// kafkaField is a column of the messages, index is its position in the rows.
+type kafkaField struct {
+	name  string
+	typ   kafkaFieldType
+	index int
+}
+
+// kafkaTable is how the rows of a table are sent, it's shared by the chunks.
+type kafkaTable struct {
+	topic  string
+	fields []kafkaField
+	// keyFields is nil if the messages have no keys
+	keyFields []kafkaField
+	// the ids of the Avro schemas in the Schema Registry
+	valueSchemaID int32
+	keySchemaID   int32
+}
+
+// kafkaTopicName returns the topic of the table by the template, where {db}
+// and {table} are replaced, and the characters invalid in topics are replaced
+// by '_'.
+func kafkaTopicName(template, database, table string) string {
+	topic := strings.NewReplacer("{db}", database, "{table}", table).Replace(template)
+	return strings.Map(func(r rune) rune {
+		if (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9') || r == '.' || r == '_' || r == '-' {
+			return r
+		}
+		return '_'
+	}, topic)
+}
+
+// avroName returns name with the characters invalid in Avro names replaced by '_'.
+func avroName(name string) string {
+	var b strings.Builder
+	for i, r := range name {
+		switch {
+		case (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') || r == '_':
+			b.WriteRune(r)
+		case r >= '0' && r <= '9':
+			if i == 0 {
+				b.WriteByte('_')
+			}
+			b.WriteRune(r)
+		default:
+			b.WriteByte('_')
+		}
+	}
+	return b.String()
+}
+
+type avroField struct {
+	Name    string      `json:"name"`
+	Type    []string    `json:"type"`
+	Default interface{} `json:"default"`
+}
+
+type avroSchema struct {
+	Type      string      `json:"type"`
+	Name      string      `json:"name"`
+	Namespace string      `json:"namespace"`
+	Fields    []avroField `json:"fields"`
+}
+
+// buildAvroSchema returns the schema of the Avro records of fields, all of
+// them are nullable.
+func buildAvroSchema(database, name string, fields []kafkaField) (string, error) {
+	schema := avroSchema{Type: "record", Name: avroName(name), Namespace: avroName(database)}
+	for _, field := range fields {
+		schema.Fields = append(schema.Fields, avroField{
+			Name: avroName(field.name),
+			Type: []string{"null", field.typ.avroType()},
+		})
+	}
+	b, err := json.Marshal(schema)
+	return string(b), withStack(err)
+}
+
+// schemaRegistry registers the Avro schemas in the Confluent Schema Registry.
+type schemaRegistry struct {
+	url    string
+	client *http.Client
+}
+
+// register registers schema under subject and returns its id.
+func (r *schemaRegistry) register(ctx context.Context, subject, schema string) (int32, error) {
+	body, err := json.Marshal(map[string]string{"schema": schema})
+	if err != nil {
+		return 0, withStack(err)
+	}
+	u := strings.TrimSuffix(r.url, "/") + "/subjects/" + url.PathEscape(subject) + "/versions"
+	req, err := http.NewRequest(http.MethodPost, u, bytes.NewReader(body))
+	if err != nil {
+		return 0, withStack(withKind(ErrorKindConfig, err))
+	}
+	req.Header.Set("Content-Type", "application/vnd.schemaregistry.v1+json")
+	resp, err := r.client.Do(req.WithContext(ctx))
+	if err != nil {
+		return 0, withStack(withKind(ErrorKindConnection, err))
+	}
+	defer resp.Body.Close()
+	respBody, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return 0, withStack(withKind(ErrorKindConnection, err))
+	}
+	if resp.StatusCode != http.StatusOK {
+		return 0, withStack(withKind(ErrorKindWrite, errors.Errorf("register schema of %s: %s %s", subject, resp.Status, respBody)))
+	}
+	var result struct {
+		ID int32 `json:"id"`
+	}
+	if err = json.Unmarshal(respBody, &result); err != nil {
+		return 0, withStack(withKind(ErrorKindWrite, err))
+	}
+	return result.ID, nil
+}
+
+// rawBytesOf returns the bytes of the value received, nil if it's NULL.
+func rawBytesOf(receiver RowReceiverStringer) sql.RawBytes {
+	switch r := receiver.(type) {
+	case *SQLTypeNumber:
+		return r.RawBytes
+	case *SQLTypeString:
+		return r.RawBytes
+	case *SQLTypeBytes:
+		return r.RawBytes
+	}
+	return nil
+}
+
+// writeJSONRecord writes the fields of row as an object of JSON.
+func writeJSONRecord(bf *bytes.Buffer, fields []kafkaField, row RowReceiverArr) {
+	bf.WriteByte('{')
+	for i, field := range fields {
+		if i > 0 {
+			bf.WriteByte(',')
+		}
+		writeJSONString(bf, field.name)
+		bf.WriteByte(':')
+		writeJSONValue(bf, field.typ, rawBytesOf(row[field.index]))
+	}
+	bf.WriteByte('}')
+}
+
+func writeJSONValue(bf *bytes.Buffer, typ kafkaFieldType, b sql.RawBytes) {
+	if b == nil {
+		bf.WriteString("null")
+		return
+	}
+	switch typ {
+	case kafkaLong, kafkaDouble, kafkaNumber:
+		b = trimZeroFill(b)
+		// NaN and Infinity of PostgreSQL aren't numbers of JSON
+		if len(b) > 0 && (b[0] == '-' || (b[0] >= '0' && b[0] <= '9')) && json.Valid(b) {
+			bf.Write(b)
+			return
+		}
+	case kafkaBoolean:
+		if v, err := strconv.ParseBool(string(b)); err == nil {
+			bf.WriteString(strconv.FormatBool(v))
+			return
+		}
+	case kafkaBytes:
+		bf.WriteByte('"')
+		enc := base64.NewEncoder(base64.StdEncoding, bf)
+		enc.Write(b)
+		enc.Close()
+		bf.WriteByte('"')
+		return
+	}
+	writeJSONString(bf, string(b))
+}
+
+// writeJSONString writes s as a string of JSON, the invalid UTF-8 is replaced
+// by U+FFFD like encoding/json.
+func writeJSONString(bf *bytes.Buffer, s string) {
+	bf.WriteByte('"')
+	JSONEscaper.Escape(bf, []byte(s))
+	bf.WriteByte('"')
+}
+
+// writeAvroRecord writes the fields of row as an Avro record in the wire
+// format of the Confluent Schema Registry, which starts with the 0 magic byte
+// and the id of the schema.
+func writeAvroRecord(bf *bytes.Buffer, schemaID int32, fields []kafkaField, row RowReceiverArr) error {
+	var header [5]byte
+	binary.BigEndian.PutUint32(header[1:], uint32(schemaID))
+	bf.Write(header[:])
+	for _, field := range fields {
+		b := rawBytesOf(row[field.index])
+		if b == nil {
+			writeAvroLong(bf, 0)
+			continue
+		}
+		writeAvroLong(bf, 1)
+		switch field.typ {
+		case kafkaLong:
+			v, err := strconv.ParseInt(string(b), 10, 64)
+			if err != nil {
+				return withStack(errors.Errorf("column %s: %s is not a long of Avro", field.name, b))
+			}
+			writeAvroLong(bf, v)
+		case kafkaDouble:
+			v, err := strconv.ParseFloat(string(b), 64)
+			if err != nil {
+				return withStack(errors.Errorf("column %s: %s is not a double of Avro", field.name, b))
+			}
+			var d [8]byte
+			binary.LittleEndian.PutUint64(d[:], math.Float64bits(v))
+			bf.Write(d[:])
+		case kafkaBoolean:
+			v, err := strconv.ParseBool(string(b))
+			if err != nil {
+				return withStack(errors.Errorf("column %s: %s is not a boolean of Avro", field.name, b))
+			}
+			if v {
+				bf.WriteByte(1)
+			} else {
+				bf.WriteByte(0)
+			}
+		case kafkaNumber:
+			b = trimZeroFill(b)
+			writeAvroLong(bf, int64(len(b)))
+			bf.Write(b)
+		default:
+			// the strings and bytes are both written with their lengths
+			writeAvroLong(bf, int64(len(b)))
+			bf.Write(b)
+		}
+	}
+	return nil
+}
+
+// writeAvroLong writes v in the zig-zag variable length encoding of Avro.
+func writeAvroLong(bf *bytes.Buffer, v int64) {
+	var b [binary.MaxVarintLen64]byte
+	bf.Write(b[:binary.PutVarint(b[:], v)])
+}
+
+// KafkaWriter publishes the rows of each table as the messages of its own
+// topic, so that the consumers can be bootstrapped before switching to CDC.
+// The schema files are written as SimpleWriter does.
+type KafkaWriter struct {
+	*SimpleWriter
+	db       *sql.DB
+	producer sarama.SyncProducer
+	registry *schemaRegistry
+
+	mu     sync.Mutex
+	tables map[string]*kafkaTable
+}
+
+func NewKafkaWriter(config *Config, db *sql.DB) (Writer, error) {
+	sw, err := NewSimpleWriter(config)
+	if err != nil {
+		return nil, err
+	}
+	kafkaConf := sarama.NewConfig()
+	kafkaConf.ClientID = "dumpling"
+	kafkaConf.Producer.Return.Successes = true
+	kafkaConf.Producer.RequiredAcks = sarama.WaitForAll
+	producer, err := sarama.NewSyncProducer(strings.Split(config.KafkaBrokers, ","), kafkaConf)
+	if err != nil {
+		return nil, withStack(withKind(ErrorKindConnection, err))
+	}
+	return newKafkaWriter(sw.(*SimpleWriter), db, producer), nil
+}
+
+func newKafkaWriter(sw *SimpleWriter, db *sql.DB, producer sarama.SyncProducer) *KafkaWriter {
+	w := &KafkaWriter{
+		SimpleWriter: sw,
+		db:           db,
+		producer:     producer,
+		tables:       map[string]*kafkaTable{},
+	}
+	if sw.cfg.KafkaSchemaRegistry != "" {
+		w.registry = &schemaRegistry{url: sw.cfg.KafkaSchemaRegistry, client: http.DefaultClient}
+	}
+	return w
+}
+
+// Close closes the producer after the messages are sent.
+func (f *KafkaWriter) Close() error {
+	return withStack(f.producer.Close())
+}
+
+// table returns the kafkaTable of ir, which is prepared by the first chunk.
+func (f *KafkaWriter) table(ctx context.Context, ir TableDataIR) (*kafkaTable, error) {
+	key := fmt.Sprintf("%s.%s", ir.DatabaseName(), ir.TableName())
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if t, ok := f.tables[key]; ok {
+		return t, nil
+	}
+
+	database, tableName := ir.DatabaseName(), ir.TableName()
+	columns, err := f.cfg.dialect().ListColumns(f.db, database, tableName)
+	if err != nil {
+		return nil, withKind(ErrorKindSchema, err)
+	}
+	types := make(map[string]kafkaFieldType, len(columns))
+	for _, col := range columns {
+		types[col.Name] = kafkaFieldTypeOf(col)
+	}
+	t := &kafkaTable{topic: kafkaTopicName(f.cfg.KafkaTopic, database, tableName)}
+	indexes := make(map[string]int)
+	for i, name := range ir.ColumnNames() {
+		t.fields = append(t.fields, kafkaField{name: name, typ: types[name], index: i})
+		indexes[name] = i
+	}
+
+	if f.cfg.KafkaKey == KafkaKeyPK {
+		pkColumns, err := f.cfg.dialect().PrimaryKeyColumns(f.db, database, tableName)
+		if err != nil {
+			return nil, withKind(ErrorKindSchema, err)
+		}
+		for _, name := range pkColumns {
+			i, ok := indexes[name]
+			if !ok {
+				t.keyFields = nil
+				break
+			}
+			t.keyFields = append(t.keyFields, t.fields[i])
+		}
+		if len(t.keyFields) == 0 {
+			log.Warn("send the messages without keys as the table has no primary key",
+				zap.String("database", database), zap.String("table", tableName))
+		}
+	}
+
+	if f.cfg.KafkaFormat == KafkaFormatAvro {
+		schema, err := buildAvroSchema(database, tableName, t.fields)
+		if err != nil {
+			return nil, err
+		}
+		if t.valueSchemaID, err = f.registry.register(ctx, t.topic+"-value", schema); err != nil {
+			return nil, err
+		}
+		if t.keyFields != nil {
+			if schema, err = buildAvroSchema(database, tableName+"_key", t.keyFields); err != nil {
+				return nil, err
+			}
+			if t.keySchemaID, err = f.registry.register(ctx, t.topic+"-key", schema); err != nil {
+				return nil, err
+			}
+		}
+	}
+	f.tables[key] = t
+	return t, nil
+}
+
+// encode returns the bytes of the fields of row in the format of the messages.
+func (f *KafkaWriter) encode(bf *bytes.Buffer, schemaID int32, fields []kafkaField, row RowReceiverArr) ([]byte, error) {
+	bf.Reset()
+	if f.cfg.KafkaFormat == KafkaFormatAvro {
+		if err := writeAvroRecord(bf, schemaID, fields, row); err != nil {
+			return nil, err
+		}
+	} else {
+		writeJSONRecord(bf, fields, row)
+	}
+	return append([]byte(nil), bf.Bytes()...), nil
+}
+
+func (f *KafkaWriter) WriteTableData(ctx context.Context, ir TableDataIR) error {
+	log.Debug("start sending table...", zap.String("table", ir.TableName()))
+
+	t, err := f.table(ctx, ir)
+	if err != nil {
+		return err
+	}
+	batchSize := f.cfg.StatementSize
+	if batchSize == UnspecifiedSize {
+		batchSize = defaultKafkaBatchSize
+	}
+
+	ir = withRowsThrottle(ctx, ir, f.rowsLimiter)
+	fileRowIter := ir.Rows()
+	defer fileRowIter.Close()
+	var (
+		row       = makeRowReceiver(ir.ColumnTypes(), ir.Output(), false).(RowReceiverArr)
+		bf        bytes.Buffer
+		msgs      []*sarama.ProducerMessage
+		msgsBytes uint64
+		counter   = 0
+	)
+	send := func() error {
+		if len(msgs) == 0 {
+			return nil
+		}
+		if err := f.bytesLimiter.wait(ctx, msgsBytes); err != nil {
+			return err
+		}
+		if err := f.producer.SendMessages(msgs); err != nil {
+			return withStack(withKind(ErrorKindWrite, err))
+		}
+		msgs, msgsBytes = msgs[:0], 0
+		return nil
+	}
+	for fileRowIter.HasNextSQLRowIter() {
+		fileRowIter = fileRowIter.NextSQLRowIter()
+		for fileRowIter.HasNext() {
+			if err = ctx.Err(); err != nil {
+				return err
+			}
+			if err = fileRowIter.Decode(row); err != nil {
+				log.Error("scanning from sql.Row failed", zap.Error(err))
+				return err
+			}
+			msg := &sarama.ProducerMessage{Topic: t.topic}
+			value, err := f.encode(&bf, t.valueSchemaID, t.fields, row)
+			if err != nil {
+				return err
+			}
+			msg.Value = sarama.ByteEncoder(value)
+			msgsBytes += uint64(len(value))
+			if t.keyFields != nil {
+				key, err := f.encode(&bf, t.keySchemaID, t.keyFields, row)
+				if err != nil {
+					return err
+				}
+				msg.Key = sarama.ByteEncoder(key)
+				msgsBytes += uint64(len(key))
+			}
+			msgs = append(msgs, msg)
+			counter++
+			if msgsBytes >= batchSize {
+				if err = send(); err != nil {
+					return err
+				}
+			}
+			fileRowIter.Next()
+		}
+	}
+	if err = fileRowIter.Error(); err != nil {
+		return err
+	}
+	if err = send(); err != nil {
+		return err
+	}
+	log.Debug("sending table successfully",
+		zap.String("table", ir.TableName()),
+		zap.Int("record counts", counter))
+	return nil
+}
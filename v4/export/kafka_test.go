@@ -0,0 +1,209 @@
+package export
+
+import (
+	"bytes"
+	"context"
+	"database/sql/driver"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/Shopify/sarama"
+	. "github.com/pingcap/check"
+)
+
+var _ = Suite(&testKafkaSuite{})
+
+type testKafkaSuite struct{}
+
+// mockProducer is a sarama.SyncProducer keeping the messages sent.
+type mockProducer struct {
+	msgs   []*sarama.ProducerMessage
+	closed bool
+}
+
+func (p *mockProducer) SendMessage(msg *sarama.ProducerMessage) (int32, int64, error) {
+	p.msgs = append(p.msgs, msg)
+	return 0, int64(len(p.msgs) - 1), nil
+}
+
+func (p *mockProducer) SendMessages(msgs []*sarama.ProducerMessage) error {
+	p.msgs = append(p.msgs, msgs...)
+	return nil
+}
+
+func (p *mockProducer) Close() error {
+	p.closed = true
+	return nil
+}
+
+func (p *mockProducer) messages(c *C) [][3]string {
+	var result [][3]string
+	for _, msg := range p.msgs {
+		value, err := msg.Value.Encode()
+		c.Assert(err, IsNil)
+		var key []byte
+		if msg.Key != nil {
+			key, err = msg.Key.Encode()
+			c.Assert(err, IsNil)
+		}
+		result = append(result, [3]string{msg.Topic, string(key), string(value)})
+	}
+	return result
+}
+
+func (s *testKafkaSuite) newWriter(c *C, conf *Config) (*KafkaWriter, *mockProducer, sqlmock.Sqlmock) {
+	db, mock, err := sqlmock.New()
+	c.Assert(err, IsNil)
+	conf.ExternalStorage = newMemStorage()
+	sw, err := NewSimpleWriter(conf)
+	c.Assert(err, IsNil)
+	producer := &mockProducer{}
+	return newKafkaWriter(sw.(*SimpleWriter), db, producer), producer, mock
+}
+
+func expectTableColumns(mock sqlmock.Sqlmock) {
+	columns := []string{"COLUMN_NAME", "DATA_TYPE", "COLUMN_TYPE", "IS_NULLABLE", "CHARACTER_MAXIMUM_LENGTH", "NUMERIC_PRECISION", "NUMERIC_SCALE"}
+	mock.ExpectQuery("SELECT COLUMN_NAME, DATA_TYPE").WithArgs("test", "t").WillReturnRows(
+		sqlmock.NewRows(columns).
+			AddRow("id", "bigint", "bigint(20)", "NO", nil, 19, 0).
+			AddRow("name", "varchar", "varchar(16)", "YES", 16, nil, nil).
+			AddRow("price", "decimal", "decimal(10,2)", "YES", nil, 10, 2).
+			AddRow("data", "blob", "blob", "YES", nil, nil, nil))
+}
+
+func newKafkaTableIR() TableDataIR {
+	data := [][]driver.Value{
+		{"1", "a\"\n", "1.50", []byte{0x01, 0xff}},
+		{"2", nil, nil, nil},
+	}
+	tableIR := newMockTableIR("test", "t", data, nil, []string{"BIGINT", "VARCHAR", "DECIMAL", "BLOB"})
+	tableIR.(*mockTableIR).colNames = []string{"id", "name", "price", "data"}
+	return tableIR
+}
+
+func (s *testKafkaSuite) TestWriteJSON(c *C) {
+	conf := DefaultConfig()
+	conf.StatementSize = 1
+	writer, producer, mock := s.newWriter(c, conf)
+	expectTableColumns(mock)
+	mock.ExpectPrepare("SELECT COLUMN_NAME FROM INFORMATION_SCHEMA.STATISTICS").ExpectQuery().WithArgs("test", "t").
+		WillReturnRows(sqlmock.NewRows([]string{"column_name"}).AddRow("id"))
+
+	c.Assert(writer.WriteTableData(context.Background(), newKafkaTableIR()), IsNil)
+	c.Assert(mock.ExpectationsWereMet(), IsNil)
+	c.Assert(producer.messages(c), DeepEquals, [][3]string{
+		{"test.t", `{"id":1}`, `{"id":1,"name":"a\"\n","price":1.50,"data":"Af8="}`},
+		{"test.t", `{"id":2}`, `{"id":2,"name":null,"price":null,"data":null}`},
+	})
+
+	// the columns are queried once for all the chunks
+	c.Assert(writer.WriteTableData(context.Background(), newKafkaTableIR()), IsNil)
+	c.Assert(producer.msgs, HasLen, 4)
+	c.Assert(writer.Close(), IsNil)
+	c.Assert(producer.closed, IsTrue)
+}
+
+func (s *testKafkaSuite) TestWriteWithoutKey(c *C) {
+	conf := DefaultConfig()
+	conf.KafkaTopic = "dump-{db}-{table}"
+	conf.KafkaKey = KafkaKeyNone
+	writer, producer, mock := s.newWriter(c, conf)
+	expectTableColumns(mock)
+
+	c.Assert(writer.WriteTableData(context.Background(), newKafkaTableIR()), IsNil)
+	c.Assert(mock.ExpectationsWereMet(), IsNil)
+	messages := producer.messages(c)
+	c.Assert(messages, HasLen, 2)
+	c.Assert(messages[0][0], Equals, "dump-test-t")
+	c.Assert(messages[0][1], Equals, "")
+}
+
+func (s *testKafkaSuite) TestWriteAvro(c *C) {
+	var subjects []string
+	var schemas []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := ioutil.ReadAll(r.Body)
+		c.Assert(err, IsNil)
+		var req map[string]string
+		c.Assert(json.Unmarshal(body, &req), IsNil)
+		subjects = append(subjects, r.URL.Path)
+		schemas = append(schemas, req["schema"])
+		_, _ = w.Write([]byte(`{"id":7}`))
+	}))
+	defer server.Close()
+
+	conf := DefaultConfig()
+	conf.KafkaFormat = KafkaFormatAvro
+	conf.KafkaSchemaRegistry = server.URL
+	writer, producer, mock := s.newWriter(c, conf)
+	expectTableColumns(mock)
+	mock.ExpectPrepare("SELECT COLUMN_NAME FROM INFORMATION_SCHEMA.STATISTICS").ExpectQuery().WithArgs("test", "t").
+		WillReturnRows(sqlmock.NewRows([]string{"column_name"}).AddRow("id"))
+
+	c.Assert(writer.WriteTableData(context.Background(), newKafkaTableIR()), IsNil)
+	c.Assert(mock.ExpectationsWereMet(), IsNil)
+	c.Assert(subjects, DeepEquals, []string{"/subjects/test.t-value/versions", "/subjects/test.t-key/versions"})
+	c.Assert(schemas[0], Equals, `{"type":"record","name":"t","namespace":"test","fields":[`+
+		`{"name":"id","type":["null","long"],"default":null},`+
+		`{"name":"name","type":["null","string"],"default":null},`+
+		`{"name":"price","type":["null","string"],"default":null},`+
+		`{"name":"data","type":["null","bytes"],"default":null}]}`)
+	c.Assert(schemas[1], Equals, `{"type":"record","name":"t_key","namespace":"test","fields":[`+
+		`{"name":"id","type":["null","long"],"default":null}]}`)
+
+	header := "\x00\x00\x00\x00\x07"
+	c.Assert(producer.messages(c), DeepEquals, [][3]string{
+		{"test.t", header + "\x02\x02", header + "\x02\x02" + "\x02\x06a\"\n" + "\x02\x081.50" + "\x02\x04\x01\xff"},
+		{"test.t", header + "\x02\x04", header + "\x02\x04" + "\x00\x00\x00"},
+	})
+}
+
+func (s *testKafkaSuite) TestNames(c *C) {
+	c.Assert(kafkaTopicName("{db}.{table}", "app", "order items"), Equals, "app.order_items")
+	c.Assert(kafkaTopicName("cdc-{table}", "app", "订单"), Equals, "cdc-__")
+	c.Assert(avroName("1st-col"), Equals, "_1st_col")
+}
+
+func (s *testKafkaSuite) TestValidate(c *C) {
+	conf := DefaultConfig()
+	conf.FileType = "kafka"
+	c.Assert(conf.Validate(), ErrorMatches, "kafka-brokers is required by filetype kafka")
+	conf.KafkaBrokers = "127.0.0.1:9092"
+	c.Assert(conf.Validate(), IsNil)
+	conf.KafkaFormat = KafkaFormatAvro
+	c.Assert(conf.Validate(), ErrorMatches, "kafka-schema-registry is required by kafka format avro")
+	conf.KafkaFormat = "protobuf"
+	conf.KafkaKey = "uk"
+	c.Assert(conf.Validate(), ErrorMatches, "invalid kafka format protobuf; invalid kafka key uk")
+}
+
+func (s *testKafkaSuite) TestWriteNumbers(c *C) {
+	var bf bytes.Buffer
+	for _, t := range []struct {
+		typ      kafkaFieldType
+		value    string
+		expected string
+	}{
+		{kafkaNumber, "18446744073709551615", "18446744073709551615"},
+		// the zeros padded by ZEROFILL aren't valid in JSON
+		{kafkaLong, "0000000042", "42"},
+		{kafkaNumber, "001.50", "1.50"},
+		{kafkaLong, "0", "0"},
+		{kafkaDouble, "NaN", `"NaN"`},
+	} {
+		bf.Reset()
+		writeJSONValue(&bf, t.typ, []byte(t.value))
+		c.Assert(bf.String(), Equals, t.expected, Commentf("value %s", t.value))
+	}
+
+	fields := []kafkaField{{name: "id", typ: kafkaLong, index: 0}, {name: "price", typ: kafkaNumber, index: 1}}
+	row := MakeRowReceiver([]string{"BIGINT", "DECIMAL"}).(RowReceiverArr)
+	row[0].(*SQLTypeNumber).RawBytes = []byte("0000000042")
+	row[1].(*SQLTypeNumber).RawBytes = []byte("001.50")
+	bf.Reset()
+	c.Assert(writeAvroRecord(&bf, 7, fields, row), IsNil)
+	c.Assert(bf.String(), Equals, "\x00\x00\x00\x00\x07"+"\x02\x54"+"\x02\x081.50")
+}
@@ -0,0 +1,128 @@
+package export
+
+import (
+	"bytes"
+	"context"
+)
+
+// valueSegmentSize is the number of the bytes of a value escaped at a time.
+// The larger values are streamed through their escapers or the hex encoder
+// segment by segment, swapping the full buffers between the segments, so that
+// the escaped copy of a huge BLOB or TEXT is never held in a single buffer.
+const valueSegmentSize = 64 * 1024
+
+// rowFormat is the file format the rows are written in.
+type rowFormat int
+
+const (
+	sqlRowFormat rowFormat = iota
+	csvRowFormat
+	tsvRowFormat
+)
+
+// rowWriter writes the rows of a file in its format through a writerPipe.
+type rowWriter struct {
+	format          rowFormat
+	escapeBackslash bool
+	csvNullValue    string
+}
+
+// writeRow writes row into bf, and returns the buffer to write the following
+// bytes into. The rows smaller than valueSegmentSize are written by their
+// receivers, the values larger than it are written in segments.
+func (w rowWriter) writeRow(ctx context.Context, wp *writerPipe, bf *bytes.Buffer, row RowReceiverArr) (*bytes.Buffer, error) {
+	if row.ReportSize() <= valueSegmentSize {
+		w.write(bf, row)
+		return bf, nil
+	}
+	var separator byte = ','
+	switch w.format {
+	case sqlRowFormat:
+		bf.WriteByte('(')
+	case tsvRowFormat:
+		separator = '\t'
+	}
+	var err error
+	for i, receiver := range row {
+		if i > 0 {
+			bf.WriteByte(separator)
+		}
+		if bf, err = w.writeValue(ctx, wp, bf, receiver); err != nil {
+			return nil, err
+		}
+	}
+	if w.format == sqlRowFormat {
+		bf.WriteByte(')')
+	}
+	return bf, nil
+}
+
+func (w rowWriter) write(bf *bytes.Buffer, s Stringer) {
+	switch w.format {
+	case sqlRowFormat:
+		s.WriteToBuffer(bf, w.escapeBackslash)
+	case csvRowFormat:
+		s.WriteToBufferInCsv(bf, w.escapeBackslash, w.csvNullValue)
+	default:
+		s.WriteToBufferInTsv(bf)
+	}
+}
+
+// writeValue writes the value of receiver as write does, but in segments if
+// it's larger than valueSegmentSize.
+func (w rowWriter) writeValue(ctx context.Context, wp *writerPipe, bf *bytes.Buffer, receiver RowReceiverStringer) (*bytes.Buffer, error) {
+	prefix, suffix, escaper, value := w.encoding(receiver)
+	if escaper == nil || len(value) <= valueSegmentSize {
+		w.write(bf, receiver)
+		return bf, nil
+	}
+	bf.WriteString(prefix)
+	var err error
+	for len(value) > 0 {
+		n := len(value)
+		if n > valueSegmentSize {
+			n = valueSegmentSize
+		}
+		escaper.Escape(bf, value[:n])
+		value = value[n:]
+		if bf, err = wp.swap(ctx); err != nil {
+			return nil, err
+		}
+	}
+	bf.WriteString(suffix)
+	return bf, nil
+}
+
+// encoding returns how the receivers of the strings and the binary values
+// write their non-NULL values, which are escaped by escaper between prefix
+// and suffix. The escaper is nil for the other receivers.
+func (w rowWriter) encoding(receiver RowReceiverStringer) (prefix, suffix string, escaper Escaper, value []byte) {
+	switch r := receiver.(type) {
+	case *SQLTypeString:
+		if r.RawBytes == nil {
+			return "", "", nil, nil
+		}
+		switch w.format {
+		case sqlRowFormat:
+			return "'", "'", sqlEscaper(w.escapeBackslash), r.RawBytes
+		case csvRowFormat:
+			return `"`, `"`, csvEscaper(w.escapeBackslash), r.RawBytes
+		default:
+			return "", "", TSVEscaper, r.RawBytes
+		}
+	case *SQLTypeBytes:
+		if r.RawBytes == nil {
+			return "", "", nil, nil
+		}
+		switch w.format {
+		case sqlRowFormat:
+			prefix, suffix := r.binaryLiteral()
+			return prefix, suffix, hexEscaper{}, r.RawBytes
+		case csvRowFormat:
+			return `"`, `"`, verbatimEscaper{}, r.RawBytes
+		default:
+			return "", "", TSVEscaper, r.RawBytes
+		}
+	}
+	return "", "", nil, nil
+}
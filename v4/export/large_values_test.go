@@ -0,0 +1,99 @@
+package export
+
+import (
+	"bytes"
+	"context"
+	"database/sql/driver"
+	"strings"
+
+	. "github.com/pingcap/check"
+)
+
+var _ = Suite(&testLargeValuesSuite{})
+
+type testLargeValuesSuite struct{}
+
+// maxWriteRecorder records the largest write into it.
+type maxWriteRecorder struct {
+	bytes.Buffer
+	maxWrite int
+}
+
+func (r *maxWriteRecorder) Write(p []byte) (int, error) {
+	if len(p) > r.maxWrite {
+		r.maxWrite = len(p)
+	}
+	return r.Buffer.Write(p)
+}
+
+// writeRowThroughPipe writes row by w into a pipe of the buffers of size, and
+// returns the file and the largest buffer written into it.
+func writeRowThroughPipe(c *C, w rowWriter, row RowReceiverArr, size uint64) (string, int) {
+	ctx := context.Background()
+	out := &maxWriteRecorder{}
+	wp, ctx, stop := startWriterPipe(ctx, out, NewBufferPool(size, UnspecifiedSize))
+	defer stop()
+	bf, err := w.writeRow(ctx, wp, wp.Buffer(), row)
+	c.Assert(err, IsNil)
+	bf.WriteByte('\n')
+	c.Assert(wp.Close(ctx), IsNil)
+	return out.String(), out.maxWrite
+}
+
+func (s *testLargeValuesSuite) TestWriteLargeValues(c *C) {
+	large := bytes.Repeat([]byte("it's \"large\"\t\\\n\x00\xff"), 3*valueSegmentSize/16+1)
+	for _, d := range []OutputDialect{mysqlOutput{}, postgresOutput{}, clickhouseOutput{}} {
+		row := makeRowReceiver([]string{"INT", "TEXT", "BLOB", "VARCHAR", "BLOB"}, d, false).(RowReceiverArr)
+		row[0].(*SQLTypeNumber).RawBytes = []byte("1")
+		row[1].(*SQLTypeString).RawBytes = large
+		row[2].(*SQLTypeBytes).RawBytes = large
+		row[3].(*SQLTypeString).RawBytes = []byte("small")
+		for _, w := range []rowWriter{
+			{format: sqlRowFormat, escapeBackslash: true},
+			{format: sqlRowFormat},
+			{format: csvRowFormat, escapeBackslash: true, csvNullValue: "\\N"},
+			{format: csvRowFormat, csvNullValue: "NULL"},
+			{format: tsvRowFormat},
+		} {
+			comment := Commentf("dialect %T, writer %+v", d, w)
+			// the values are written in segments as the same bytes written by the receivers
+			var expected bytes.Buffer
+			w.write(&expected, row)
+			expected.WriteByte('\n')
+			written, maxWrite := writeRowThroughPipe(c, w, row, UnspecifiedSize)
+			c.Assert(written == expected.String(), IsTrue, comment)
+			c.Assert(maxWrite < lengthLimit+2*valueSegmentSize, IsTrue, comment)
+		}
+	}
+}
+
+func (s *testLargeValuesSuite) TestMemoryIndependentOfValueSize(c *C) {
+	// the hex of the value is 8 times larger than the buffers
+	row := makeRowReceiver([]string{"BLOB"}, mysqlOutput{}, false).(RowReceiverArr)
+	row[0].(*SQLTypeBytes).RawBytes = bytes.Repeat([]byte{0xab}, 4*lengthLimit)
+	written, maxWrite := writeRowThroughPipe(c, rowWriter{format: sqlRowFormat}, row, UnspecifiedSize)
+	c.Assert(written, HasLen, len("(x'')\n")+8*lengthLimit)
+	c.Assert(maxWrite <= lengthLimit+2*valueSegmentSize, IsTrue, Commentf("max write %d", maxWrite))
+
+	// the small rows aren't split
+	row[0].(*SQLTypeBytes).RawBytes = []byte{0xab}
+	written, _ = writeRowThroughPipe(c, rowWriter{format: sqlRowFormat}, row, UnspecifiedSize)
+	c.Assert(written, Equals, "(x'ab')\n")
+	row[0].(*SQLTypeBytes).RawBytes = nil
+	written, _ = writeRowThroughPipe(c, rowWriter{format: csvRowFormat, csvNullValue: "\\N"}, row, UnspecifiedSize)
+	c.Assert(written, Equals, "\\N\n")
+}
+
+func (s *testLargeValuesSuite) TestWriteInsertLargeValues(c *C) {
+	large := bytes.Repeat([]byte{0x01, 0xfe}, 2*valueSegmentSize)
+	data := [][]driver.Value{
+		{"1", large, "it's " + string(large[:3])},
+		{"2", []byte{0xab}, strings.Repeat("'", 3*valueSegmentSize)},
+	}
+	tableIR := newMockTableIR("test", "t", data, nil, []string{"INT", "BLOB", "TEXT"})
+	bf := &bytes.Buffer{}
+	c.Assert(WriteInsert(context.Background(), tableIR, bf, UnspecifiedSize, nil), IsNil)
+	c.Assert(bf.String() == "INSERT INTO `t` VALUES\n"+
+		"(1,x'"+strings.Repeat("01fe", 2*valueSegmentSize)+"','it''s \x01\xfe\x01'),\n"+
+		"(2,x'ab','"+strings.Repeat("''", 3*valueSegmentSize)+"');\n", IsTrue)
+}
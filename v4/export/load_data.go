@@ -0,0 +1,108 @@
+package export
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// loadDataScripts collects the CSV files of every table, which are loaded by
+// the LOAD DATA statements in `<db>.<table>-load.sql`.
+type loadDataScripts struct {
+	mu     sync.Mutex
+	tables map[string]*loadDataTable
+}
+
+type loadDataTable struct {
+	database string
+	table    string
+	columns  []string
+	files    []string
+}
+
+func newLoadDataScripts() *loadDataScripts {
+	return &loadDataScripts{tables: map[string]*loadDataTable{}}
+}
+
+// record records fileName as a CSV file of ir.
+func (s *loadDataScripts) record(ir TableDataIR, fileName string) {
+	if s == nil || ir.TableName() == "" {
+		return
+	}
+	key := fmt.Sprintf("%s.%s", ir.DatabaseName(), ir.TableName())
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	t, ok := s.tables[key]
+	if !ok {
+		t = &loadDataTable{database: ir.DatabaseName(), table: ir.TableName(), columns: ir.ColumnNames()}
+		s.tables[key] = t
+	}
+	t.files = append(t.files, fileName)
+}
+
+// write writes the script of every table into storage.
+func (s *loadDataScripts) write(ctx context.Context, conf *Config, storage ExternalStorage) error {
+	if s == nil {
+		return nil
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, t := range s.tables {
+		sort.Strings(t.files)
+		statements := make([]string, 0, len(t.files))
+		for _, file := range t.files {
+			statements = append(statements, buildLoadData(conf, t.database, t.table, t.columns, file))
+		}
+		fileName := fmt.Sprintf("%s.%s-load.sql", t.database, t.table)
+		if err := writeMetaToFile(ctx, conf, storage, t.database, strings.Join(statements, ";\n"), fileName); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// buildLoadData builds LOAD DATA of a CSV file with the format of conf. The
+// values equal to CsvNullValue are loaded as NULL, which is built in for \N with
+// the backslash escapes and for NULL.
+func buildLoadData(conf *Config, database, table string, columns []string, fileName string) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "LOAD DATA LOCAL INFILE '%s' INTO TABLE %s.%s CHARACTER SET binary\n",
+		escapeSQLString(fileName), wrapBackTicks(database), wrapBackTicks(table))
+	b.WriteString(`FIELDS TERMINATED BY ',' OPTIONALLY ENCLOSED BY '"'`)
+	if conf.EscapeBackslash {
+		b.WriteString(` ESCAPED BY '\\'`)
+	} else {
+		b.WriteString(` ESCAPED BY ''`)
+	}
+	b.WriteString(` LINES TERMINATED BY '\n'`)
+	if !conf.NoHeader {
+		b.WriteString(" IGNORE 1 LINES")
+	}
+	builtinNull := conf.CsvNullValue == "NULL" || (conf.CsvNullValue == `\N` && conf.EscapeBackslash)
+	if len(columns) == 0 {
+		return b.String()
+	}
+	quoted := make([]string, 0, len(columns))
+	for i, col := range columns {
+		if builtinNull {
+			quoted = append(quoted, wrapBackTicks(col))
+		} else {
+			quoted = append(quoted, fmt.Sprintf("@v%d", i))
+		}
+	}
+	fmt.Fprintf(&b, "\n(%s)", strings.Join(quoted, ","))
+	if !builtinNull {
+		assignments := make([]string, 0, len(columns))
+		for i, col := range columns {
+			assignments = append(assignments, fmt.Sprintf("%s = NULLIF(@v%d, '%s')", wrapBackTicks(col), i, escapeSQLString(conf.CsvNullValue)))
+		}
+		fmt.Fprintf(&b, "\nSET %s", strings.Join(assignments, ", "))
+	}
+	return b.String()
+}
+
+func escapeSQLString(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `'`, `''`).Replace(s)
+}
@@ -0,0 +1,61 @@
+package export
+
+import (
+	"context"
+	"database/sql/driver"
+
+	. "github.com/pingcap/check"
+)
+
+var _ = Suite(&testLoadDataSuite{})
+
+type testLoadDataSuite struct{}
+
+func (s *testLoadDataSuite) TestBuildLoadData(c *C) {
+	conf := DefaultConfig()
+	conf.EscapeBackslash = true
+	columns := []string{"id", "name"}
+	c.Assert(buildLoadData(conf, "test", "t", columns, "test.t.0.csv"), Equals,
+		"LOAD DATA LOCAL INFILE 'test.t.0.csv' INTO TABLE `test`.`t` CHARACTER SET binary\n"+
+			`FIELDS TERMINATED BY ',' OPTIONALLY ENCLOSED BY '"' ESCAPED BY '\\' LINES TERMINATED BY '\n' IGNORE 1 LINES`+"\n"+
+			"(`id`,`name`)")
+
+	conf.EscapeBackslash = false
+	conf.NoHeader = true
+	c.Assert(buildLoadData(conf, "test", "t", columns, "it's.csv"), Equals,
+		"LOAD DATA LOCAL INFILE 'it''s.csv' INTO TABLE `test`.`t` CHARACTER SET binary\n"+
+			`FIELDS TERMINATED BY ',' OPTIONALLY ENCLOSED BY '"' ESCAPED BY '' LINES TERMINATED BY '\n'`+"\n"+
+			"(@v0,@v1)\n"+
+			`SET `+"`id`"+` = NULLIF(@v0, '\\N'), `+"`name`"+` = NULLIF(@v1, '\\N')`)
+
+	conf.CsvNullValue = "NULL"
+	c.Assert(buildLoadData(conf, "test", "t", columns, "test.t.0.csv"), Matches, "(?s).*\n\\(`id`,`name`\\)")
+}
+
+func (s *testLoadDataSuite) TestWriteLoadDataScripts(c *C) {
+	conf := DefaultConfig()
+	conf.FileType = "csv"
+	conf.LoadDataScripts = true
+	conf.EscapeBackslash = true
+	storage := newMemStorage()
+	conf.ExternalStorage = storage
+
+	writer, err := NewCsvWriter(conf)
+	c.Assert(err, IsNil)
+	data := [][]driver.Value{{"1", "male"}, {"2", "female"}}
+	tableIR := newMockTableIR("test", "employee", data, nil, []string{"INT", "SET"})
+	tableIR.(*mockTableIR).colNames = []string{"id", "gender"}
+	c.Assert(writer.WriteTableData(context.Background(), tableIR), IsNil)
+	c.Assert(writer.(*CsvWriter).Close(), IsNil)
+
+	script := storage.files["test.employee-load.sql"]
+	c.Assert(script, Matches, "LOAD DATA LOCAL INFILE 'test.employee.0.csv' INTO TABLE `test`.`employee` .*\n.* IGNORE 1 LINES\n\\(`id`,`gender`\\);\n")
+}
+
+func (s *testLoadDataSuite) TestValidate(c *C) {
+	conf := DefaultConfig()
+	conf.LoadDataScripts = true
+	c.Assert(conf.Validate(), ErrorMatches, "load-data-scripts is only supported with filetype csv")
+	conf.FileType = "csv"
+	c.Assert(conf.Validate(), IsNil)
+}
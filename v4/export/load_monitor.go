@@ -0,0 +1,71 @@
+package export
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/pingcap/dumpling/v4/log"
+)
+
+const defaultLoadCheckInterval = 2 * time.Second
+
+// loadMonitor polls the load of the source database, and throttles the dump
+// when the running threads exceed the limit, until the load drops.
+type loadMonitor struct {
+	db                *sql.DB
+	caps              ServerCapabilities
+	maxThreadsRunning uint64
+	controller        *JobController
+}
+
+func newLoadMonitor(conf *Config, db *sql.DB) *loadMonitor {
+	return &loadMonitor{
+		db:                db,
+		caps:              conf.ServerInfo.Capabilities(),
+		maxThreadsRunning: conf.MaxThreadsRunning,
+		controller:        conf.Controller,
+	}
+}
+
+func (m *loadMonitor) run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		m.check()
+		select {
+		case <-ctx.Done():
+			m.controller.setThrottled(false)
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (m *loadMonitor) check() {
+	threadsRunning, err := m.threadsRunning()
+	if err != nil {
+		// don't block the dump if the load is unknown
+		log.Warn("check source load failed", zap.Error(err))
+		m.controller.setThrottled(false)
+		return
+	}
+	overloaded := threadsRunning > m.maxThreadsRunning
+	if overloaded != (m.controller.State() == JobStateThrottled) {
+		log.Info("source load changed",
+			zap.Uint64("threads running", threadsRunning),
+			zap.Uint64("max threads running", m.maxThreadsRunning),
+			zap.Bool("throttled", overloaded))
+	}
+	m.controller.setThrottled(overloaded)
+}
+
+func (m *loadMonitor) threadsRunning() (uint64, error) {
+	if !m.caps.ThreadsRunning {
+		// e.g. TiDB doesn't maintain Threads_running
+		return CountRunningProcesses(m.db)
+	}
+	return ShowThreadsRunning(m.db)
+}
@@ -0,0 +1,47 @@
+package export
+
+import (
+	"errors"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	. "github.com/pingcap/check"
+)
+
+var _ = Suite(&testLoadMonitorSuite{})
+
+type testLoadMonitorSuite struct{}
+
+func (s *testLoadMonitorSuite) TestLoadMonitorCheck(c *C) {
+	db, mock, err := sqlmock.New()
+	c.Assert(err, IsNil)
+	defer db.Close()
+
+	conf := DefaultConfig()
+	conf.ServerInfo.ServerType = ServerTypeMySQL
+	conf.MaxThreadsRunning = 10
+	conf.Controller = NewJobController()
+	m := newLoadMonitor(conf, db)
+
+	mock.ExpectQuery("SHOW GLOBAL STATUS LIKE 'Threads_running'").
+		WillReturnRows(sqlmock.NewRows([]string{"Variable_name", "Value"}).AddRow("Threads_running", "11"))
+	m.check()
+	c.Assert(conf.Controller.State(), Equals, JobStateThrottled)
+
+	mock.ExpectQuery("SHOW GLOBAL STATUS LIKE 'Threads_running'").
+		WillReturnRows(sqlmock.NewRows([]string{"Variable_name", "Value"}).AddRow("Threads_running", "10"))
+	m.check()
+	c.Assert(conf.Controller.State(), Equals, JobStateRunning)
+
+	// unthrottle if the load is unknown
+	conf.Controller.setThrottled(true)
+	mock.ExpectQuery("SHOW GLOBAL STATUS LIKE 'Threads_running'").WillReturnError(errors.New("mock error"))
+	m.check()
+	c.Assert(conf.Controller.State(), Equals, JobStateRunning)
+
+	m.caps = ServerInfo{ServerType: ServerTypeTiDB}.Capabilities()
+	mock.ExpectQuery("SELECT COUNT\\(1\\) FROM INFORMATION_SCHEMA.PROCESSLIST").
+		WillReturnRows(sqlmock.NewRows([]string{"COUNT(1)"}).AddRow(20))
+	m.check()
+	c.Assert(conf.Controller.State(), Equals, JobStateThrottled)
+	c.Assert(mock.ExpectationsWereMet(), IsNil)
+}
@@ -0,0 +1,262 @@
+package export
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/pingcap/errors"
+	"go.uber.org/zap"
+	"golang.org/x/sync/errgroup"
+
+	"github.com/pingcap/dumpling/v4/log"
+)
+
+// LoadConfig is the config of Load.
+type LoadConfig struct {
+	// Dir is the local directory of the dump to load.
+	Dir string
+	// TargetDSN is the DSN of the MySQL or TiDB server to load into.
+	TargetDSN string
+	// Threads is the number of the files loaded at the same time.
+	Threads int
+	// EscapeBackslash is whether the backslashes in the strings of the dump
+	// are escapes, which is the EscapeBackslash of the dump.
+	EscapeBackslash bool
+}
+
+// Load replays the SQL files of the dump in conf.Dir into the server of
+// conf.TargetDSN, in the batches of its restore plan. The files of a
+// parallel batch are loaded by conf.Threads connections.
+func Load(ctx context.Context, conf *LoadConfig) error {
+	if conf.Threads <= 0 {
+		return withKind(ErrorKindConfig, errors.Errorf("threads should be positive, got %d", conf.Threads))
+	}
+	plan, err := loadPlan(conf.Dir)
+	if err != nil {
+		return err
+	}
+	db, err := sql.Open("mysql", conf.TargetDSN)
+	if err != nil {
+		return withStack(withKind(ErrorKindConnection, err))
+	}
+	defer db.Close()
+	return load(ctx, conf, db, plan)
+}
+
+// loadPlan returns the restore plan of the dump in dir, which is planned
+// from the files of dir if it's dumped without RestorePlan. Only the SQL
+// files can be loaded.
+func loadPlan(dir string) (*RestorePlan, error) {
+	if info, err := os.Stat(dir); err != nil {
+		return nil, withStack(withKind(ErrorKindConfig, err))
+	} else if !info.IsDir() {
+		return nil, withKind(ErrorKindConfig, errors.Errorf("%s isn't a directory", dir))
+	}
+	var plan *RestorePlan
+	if _, err := os.Stat(filepath.Join(dir, restorePlanFile)); err == nil {
+		if plan, err = ReadRestorePlan(dir); err != nil {
+			return nil, err
+		}
+	} else if plan, err = planDumpedFiles(dir); err != nil {
+		return nil, err
+	}
+	for _, batch := range plan.Batches {
+		for _, file := range batch.Files {
+			if filepath.Ext(file) != ".sql" {
+				return nil, withKind(ErrorKindConfig, errors.Errorf("only the SQL files can be loaded, got %s", file))
+			}
+		}
+	}
+	return plan, nil
+}
+
+// planDumpedFiles plans the files of the dump in dir, the tables and their
+// data are in single batches since the foreign keys aren't known.
+func planDumpedFiles(dir string) (*RestorePlan, error) {
+	names, err := filepath.Glob(filepath.Join(dir, "*"))
+	if err != nil {
+		return nil, withStack(err)
+	}
+	databases := dumpedDatabases(names)
+	conf := DefaultConfig()
+	conf.OutputDirPath = dir
+	conf.FileType = "sql"
+	conf.Tables = NewDatabaseTables()
+	for _, name := range names {
+		if !strings.HasSuffix(name, "-schema.sql") {
+			continue
+		}
+		dbName, tableName := splitSchemaFileName(strings.TrimSuffix(filepath.Base(name), "-schema.sql"), databases)
+		createSQL, err := readSchemaFile(name)
+		if err != nil {
+			return nil, err
+		}
+		if strings.HasPrefix(strings.ToUpper(createSQL), "CREATE TABLE") {
+			conf.Tables.AppendTables(dbName, tableName)
+		} else {
+			conf.Tables.AppendViews(dbName, tableName)
+		}
+	}
+	// the tables without the schema files, e.g. with NoSchemas, are
+	// found by their data files
+	for _, name := range names {
+		if kind, _ := classifyRestoreFile(conf, databases, name); kind != restoreIgnored {
+			continue
+		}
+		if dbName, tableName, ok := splitDataFileName(filepath.Base(name), databases); ok {
+			conf.Tables.AppendTables(dbName, tableName)
+		}
+	}
+	recorder := &restorePlanRecorder{files: names}
+	return recorder.plan(conf), nil
+}
+
+// dumpedDatabases returns the databases of the `{db}-schema-create.sql`
+// files in names, the longer names first.
+func dumpedDatabases(names []string) []string {
+	var databases []string
+	for _, name := range names {
+		if base := filepath.Base(name); strings.HasSuffix(base, "-schema-create.sql") {
+			databases = append(databases, strings.TrimSuffix(base, "-schema-create.sql"))
+		}
+	}
+	sort.Slice(databases, func(i, j int) bool { return len(databases[i]) > len(databases[j]) })
+	return databases
+}
+
+// load loads the files of the batches of plan into db one batch after
+// another, and stops at the first error.
+func load(ctx context.Context, conf *LoadConfig, db *sql.DB, plan *RestorePlan) error {
+	var databases []string
+	for _, batch := range plan.Batches {
+		databases = append(databases, dumpedDatabases(batch.Files)...)
+	}
+	sort.Slice(databases, func(i, j int) bool { return len(databases[i]) > len(databases[j]) })
+	dedup, err := ReadSchemaDedup(conf.Dir)
+	if err != nil {
+		return err
+	}
+	deduped := dedup.byFile()
+
+	for _, batch := range plan.Batches {
+		log.Info("load batch", zap.String("batch", batch.Name), zap.Int("files", len(batch.Files)))
+		threads := 1
+		if batch.Parallel {
+			threads = conf.Threads
+		}
+		files := make(chan string, len(batch.Files))
+		for _, file := range batch.Files {
+			files <- file
+		}
+		close(files)
+		g, gCtx := errgroup.WithContext(ctx)
+		for i := 0; i < threads; i++ {
+			g.Go(func() error {
+				for file := range files {
+					if err := gCtx.Err(); err != nil {
+						return err
+					}
+					if !filepath.IsAbs(file) {
+						file = filepath.Join(conf.Dir, file)
+					}
+					if err := loadFile(gCtx, conf, db, loadDatabase(file, databases), file); err != nil {
+						return err
+					}
+					if err := loadDedupedSchemas(gCtx, db, file, deduped[filepath.Base(file)]); err != nil {
+						return err
+					}
+				}
+				return nil
+			})
+		}
+		if err := g.Wait(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// loadDatabase returns the database the statements of file are executed in,
+// which is empty for the CREATE DATABASE and binlog files.
+func loadDatabase(file string, databases []string) string {
+	base := filepath.Base(file)
+	if strings.HasSuffix(base, "-schema-create.sql") || strings.HasSuffix(base, "-binlog.sql") {
+		return ""
+	}
+	for _, suffix := range []string{"-schema-routines.sql", "-schema-triggers.sql", "-schema-tiflash.sql"} {
+		if strings.HasSuffix(base, suffix) {
+			return strings.TrimSuffix(base, suffix)
+		}
+	}
+	dbName, _ := splitSchemaFileName(base, databases)
+	return dbName
+}
+
+// loadFile executes the statements of file in dbName on a new connection,
+// without checking the foreign keys, since the referenced rows may be in the
+// files loaded at the same time.
+func loadFile(ctx context.Context, conf *LoadConfig, db *sql.DB, dbName, file string) error {
+	f, err := os.Open(file)
+	if err != nil {
+		return withStack(withKind(ErrorKindConfig, err))
+	}
+	defer f.Close()
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		return withStack(withKind(ErrorKindConnection, err))
+	}
+	queries := []string{"SET SESSION FOREIGN_KEY_CHECKS = 0"}
+	if dbName != "" {
+		queries = append(queries, "USE "+wrapBackTicks(dbName))
+	}
+	for _, query := range queries {
+		if _, err = conn.ExecContext(ctx, query); err != nil {
+			conn.Close()
+			return withStack(withKind(ErrorKindWrite, errors.WithMessage(err, query)))
+		}
+	}
+	log.Debug("load file", zap.String("file", file))
+	w := newStatementWriter(ctx, conn, conf.EscapeBackslash)
+	if _, err = io.Copy(w, f); err != nil {
+		_ = w.Abort()
+		return errors.WithMessage(err, fmt.Sprintf("load %s", file))
+	}
+	if err = w.Close(); err != nil {
+		return errors.WithMessage(err, fmt.Sprintf("load %s", file))
+	}
+	return nil
+}
+
+// loadDedupedSchemas creates the tables deduplicated by DedupSchemas into the
+// canonical schema file, after the file is loaded.
+func loadDedupedSchemas(ctx context.Context, db *sql.DB, file string, tables []DedupedSchema) error {
+	if len(tables) == 0 {
+		return nil
+	}
+	canonicalSQL, err := readSchemaFile(file)
+	if err != nil {
+		return withKind(ErrorKindConfig, err)
+	}
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		return withStack(withKind(ErrorKindConnection, err))
+	}
+	defer conn.Close()
+	queries := []string{"SET SESSION FOREIGN_KEY_CHECKS = 0"}
+	for _, table := range tables {
+		queries = append(queries, "USE "+wrapBackTicks(table.Database), table.createStatement(canonicalSQL))
+	}
+	log.Debug("load deduplicated schemas", zap.String("file", file), zap.Int("tables", len(tables)))
+	for _, query := range queries {
+		if _, err = conn.ExecContext(ctx, query); err != nil {
+			return withStack(withKind(ErrorKindWrite, errors.WithMessage(err, query)))
+		}
+	}
+	return nil
+}
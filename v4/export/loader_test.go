@@ -0,0 +1,118 @@
+package export
+
+import (
+	"context"
+	"io/ioutil"
+	"path/filepath"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	. "github.com/pingcap/check"
+)
+
+var _ = Suite(&testLoaderSuite{})
+
+type testLoaderSuite struct{}
+
+func writeDumpFiles(c *C, files map[string]string) string {
+	dir := c.MkDir()
+	for name, content := range files {
+		c.Assert(ioutil.WriteFile(filepath.Join(dir, name), []byte(content), 0644), IsNil)
+	}
+	return dir
+}
+
+func (s *testLoaderSuite) TestPlanDumpedFiles(c *C) {
+	dir := writeDumpFiles(c, map[string]string{
+		"metadata":                 "Started dump at: 2020-01-01 00:00:00\n",
+		"shop-schema-create.sql":   "/*!40101 SET NAMES binary*/;\nCREATE DATABASE `shop`;\n",
+		"shop.orders-schema.sql":   "/*!40101 SET NAMES binary*/;\nCREATE TABLE `orders` (`id` int);\n",
+		"shop.v-schema.sql":        "/*!40101 SET NAMES binary*/;\nCREATE ALGORITHM=UNDEFINED VIEW `v` AS SELECT 1;\n",
+		"shop.orders.0.sql":        "INSERT INTO `orders` VALUES\n(1);\n",
+		"shop.items.0.sql":         "INSERT INTO `items` VALUES\n(1);\n",
+		"shop.items.1.sql":         "INSERT INTO `items` VALUES\n(2);\n",
+		"shop-schema-triggers.sql": "DELIMITER ;;\nCREATE TRIGGER `t` BEFORE INSERT ON `orders` FOR EACH ROW SET @a = 1;;\nDELIMITER ;\n",
+	})
+	plan, err := loadPlan(dir)
+	c.Assert(err, IsNil)
+	c.Assert(plan.Batches, DeepEquals, []RestoreBatch{
+		{Name: "databases", Parallel: true, Files: []string{"shop-schema-create.sql"}},
+		{Name: "tables", Parallel: true, Files: []string{"shop.orders-schema.sql"}},
+		{Name: "data", Parallel: true, Files: []string{"shop.items.0.sql", "shop.items.1.sql", "shop.orders.0.sql"}},
+		{Name: "views", Parallel: false, Files: []string{"shop.v-schema.sql"}},
+		{Name: "triggers", Parallel: true, Files: []string{"shop-schema-triggers.sql"}},
+	})
+
+	// the CSV files can't be loaded
+	dir = writeDumpFiles(c, map[string]string{"shop.orders.0.csv": "id\n1\n"})
+	_, err = loadPlan(dir)
+	c.Assert(err, ErrorMatches, "only the SQL files can be loaded, got shop.orders.0.csv")
+	c.Assert(ErrorKindOf(err), Equals, ErrorKindConfig)
+	_, err = loadPlan(filepath.Join(dir, "shop.orders.0.csv"))
+	c.Assert(err, ErrorMatches, ".*shop.orders.0.csv isn't a directory")
+}
+
+func (s *testLoaderSuite) TestLoad(c *C) {
+	dir := writeDumpFiles(c, map[string]string{
+		restorePlanFile: `{"batches": [
+			{"name": "databases", "parallel": true, "files": ["shop-schema-create.sql"]},
+			{"name": "tables-0", "parallel": true, "files": ["shop.orders-schema.sql"]},
+			{"name": "data-0", "parallel": true, "files": ["shop.orders.0.sql"]},
+			{"name": "triggers", "parallel": true, "files": ["shop-schema-triggers.sql"]}
+		]}`,
+		"shop-schema-create.sql":   "/*!40101 SET NAMES binary*/;\nCREATE DATABASE `shop`;\n",
+		"shop.orders-schema.sql":   "/*!40101 SET NAMES binary*/;\nCREATE TABLE `orders` (`id` int, `note` text);\n",
+		"shop.orders.0.sql":        "INSERT INTO `orders` VALUES\n(1,'a;\nb'),\n(2,'c\\';\n');\n",
+		"shop-schema-triggers.sql": "DELIMITER ;;\nCREATE TRIGGER `t` BEFORE INSERT ON `orders` FOR EACH ROW BEGIN\nSET @a = 1;\nEND;;\nDELIMITER ;\n",
+	})
+	db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+	c.Assert(err, IsNil)
+	defer db.Close()
+
+	result := sqlmock.NewResult(0, 0)
+	mock.ExpectExec("SET SESSION FOREIGN_KEY_CHECKS = 0").WillReturnResult(result)
+	mock.ExpectExec("/*!40101 SET NAMES binary*/").WillReturnResult(result)
+	mock.ExpectExec("CREATE DATABASE `shop`").WillReturnResult(result)
+	mock.ExpectExec("SET SESSION FOREIGN_KEY_CHECKS = 0").WillReturnResult(result)
+	mock.ExpectExec("USE `shop`").WillReturnResult(result)
+	mock.ExpectExec("/*!40101 SET NAMES binary*/").WillReturnResult(result)
+	mock.ExpectExec("CREATE TABLE `orders` (`id` int, `note` text)").WillReturnResult(result)
+	mock.ExpectExec("SET SESSION FOREIGN_KEY_CHECKS = 0").WillReturnResult(result)
+	mock.ExpectExec("USE `shop`").WillReturnResult(result)
+	mock.ExpectExec("INSERT INTO `orders` VALUES\n(1,'a;\nb'),\n(2,'c\\';\n')").WillReturnResult(result)
+	mock.ExpectExec("SET SESSION FOREIGN_KEY_CHECKS = 0").WillReturnResult(result)
+	mock.ExpectExec("USE `shop`").WillReturnResult(result)
+	mock.ExpectExec("CREATE TRIGGER `t` BEFORE INSERT ON `orders` FOR EACH ROW BEGIN\nSET @a = 1;\nEND").WillReturnResult(result)
+
+	conf := &LoadConfig{Dir: dir, Threads: 1, EscapeBackslash: true}
+	plan, err := loadPlan(dir)
+	c.Assert(err, IsNil)
+	c.Assert(load(context.Background(), conf, db, plan), IsNil)
+	c.Assert(mock.ExpectationsWereMet(), IsNil)
+}
+
+func (s *testLoaderSuite) TestLoadReturnsError(c *C) {
+	dir := writeDumpFiles(c, map[string]string{
+		"shop.orders.0.sql": "INSERT INTO `orders` VALUES\n(1);\n",
+		"shop.orders.1.sql": "INSERT INTO `orders` VALUES\n(2);\n",
+		"shop.v-schema.sql": "CREATE VIEW `v` AS SELECT 1;\n",
+	})
+	db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+	c.Assert(err, IsNil)
+	defer db.Close()
+
+	result := sqlmock.NewResult(0, 0)
+	mock.ExpectExec("SET SESSION FOREIGN_KEY_CHECKS = 0").WillReturnResult(result)
+	mock.ExpectExec("USE `shop`").WillReturnResult(result)
+	mock.ExpectExec("INSERT INTO `orders` VALUES\n(1)").WillReturnError(sqlmock.ErrCancelled)
+
+	plan, err := loadPlan(dir)
+	c.Assert(err, IsNil)
+	err = load(context.Background(), &LoadConfig{Dir: dir, Threads: 1}, db, plan)
+	c.Assert(err, ErrorMatches, "(?s)load .*shop.orders.0.sql: .*INSERT INTO `orders`.*")
+	c.Assert(ErrorKindOf(err), Equals, ErrorKindWrite)
+	// the batches after the failed one aren't loaded
+	c.Assert(mock.ExpectationsWereMet(), IsNil)
+
+	err = Load(context.Background(), &LoadConfig{Dir: dir})
+	c.Assert(err, ErrorMatches, "threads should be positive, got 0")
+}
@@ -0,0 +1,79 @@
+package export
+
+import (
+	"sync"
+	"time"
+
+	"github.com/pingcap/errors"
+	"go.uber.org/zap"
+
+	"github.com/pingcap/dumpling/v4/log"
+)
+
+// holdsLocks reports whether the consistency level locks the tables of the
+// server until the dump is finished.
+func holdsLocks(consistency string) bool {
+	return consistency == "flush" || consistency == "lock"
+}
+
+// lockGuard releases the locks of a ConsistencyController if they are held
+// longer than the max lock time. The dump is aborted then, or continued
+// without the consistency guarantee if downgrade is true.
+type lockGuard struct {
+	ctrl      ConsistencyController
+	downgrade bool
+	// abort cancels the dump.
+	abort func()
+	// onDowngrade is called after the locks are released in downgrade mode.
+	onDowngrade func()
+
+	mu      sync.Mutex
+	timer   *time.Timer
+	stopped bool
+	err     error
+}
+
+func startLockGuard(ctrl ConsistencyController, maxLockTime time.Duration, downgrade bool, abort, onDowngrade func()) *lockGuard {
+	g := &lockGuard{
+		ctrl:        ctrl,
+		downgrade:   downgrade,
+		abort:       abort,
+		onDowngrade: onDowngrade,
+	}
+	g.mu.Lock()
+	g.timer = time.AfterFunc(maxLockTime, func() { g.expire(maxLockTime) })
+	g.mu.Unlock()
+	return g
+}
+
+func (g *lockGuard) expire(maxLockTime time.Duration) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if g.stopped {
+		return
+	}
+	g.stopped = true
+	if err := g.ctrl.TearDown(); err != nil {
+		log.Error("release locks held longer than max lock time failed", zap.Error(err))
+	}
+	if g.downgrade {
+		log.Warn("locks are held longer than max lock time, released them and continue dumping without consistency",
+			zap.Duration("max lock time", maxLockTime))
+		g.onDowngrade()
+		return
+	}
+	log.Error("locks are held longer than max lock time, released them and abort dumping",
+		zap.Duration("max lock time", maxLockTime))
+	g.err = withKind(ErrorKindConsistency, errors.Errorf("locks are held longer than max lock time %s", maxLockTime))
+	g.abort()
+}
+
+// stop stops the guard, it's guaranteed that the guard doesn't act after stop
+// returns. It returns the error of the dump aborted by the guard, or nil.
+func (g *lockGuard) stop() error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.stopped = true
+	g.timer.Stop()
+	return g.err
+}
@@ -0,0 +1,66 @@
+package export
+
+import (
+	"context"
+	"time"
+
+	. "github.com/pingcap/check"
+)
+
+var _ = Suite(&testLockGuardSuite{})
+
+type testLockGuardSuite struct{}
+
+type mockConsistencyController struct {
+	tearDowns int
+}
+
+func (m *mockConsistencyController) Setup(context.Context) error {
+	return nil
+}
+
+func (m *mockConsistencyController) TearDown() error {
+	m.tearDowns++
+	return nil
+}
+
+func (s *testLockGuardSuite) TestLockGuardAbort(c *C) {
+	ctrl := &mockConsistencyController{}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	g := startLockGuard(ctrl, time.Millisecond, false, cancel, func() { c.Fatal("unexpected downgrade") })
+
+	select {
+	case <-ctx.Done():
+	case <-time.After(10 * time.Second):
+		c.Fatal("dump is not aborted")
+	}
+	err := g.stop()
+	c.Assert(err, ErrorMatches, "locks are held longer than max lock time 1ms")
+	c.Assert(ErrorKindOf(err), Equals, ErrorKindConsistency)
+	c.Assert(ctrl.tearDowns, Equals, 1)
+}
+
+func (s *testLockGuardSuite) TestLockGuardDowngrade(c *C) {
+	ctrl := &mockConsistencyController{}
+	downgraded := make(chan struct{})
+	g := startLockGuard(ctrl, time.Millisecond, true, func() { c.Fatal("unexpected abort") }, func() { close(downgraded) })
+
+	select {
+	case <-downgraded:
+	case <-time.After(10 * time.Second):
+		c.Fatal("consistency is not downgraded")
+	}
+	c.Assert(g.stop(), IsNil)
+	c.Assert(ctrl.tearDowns, Equals, 1)
+}
+
+func (s *testLockGuardSuite) TestLockGuardStop(c *C) {
+	ctrl := &mockConsistencyController{}
+	g := startLockGuard(ctrl, time.Hour, false, func() { c.Fatal("unexpected abort") }, nil)
+	c.Assert(g.stop(), IsNil)
+	c.Assert(ctrl.tearDowns, Equals, 0)
+	// expiring after stopped does nothing
+	g.expire(time.Hour)
+	c.Assert(ctrl.tearDowns, Equals, 0)
+}
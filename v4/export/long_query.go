@@ -0,0 +1,51 @@
+package export
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/pingcap/dumpling/v4/log"
+)
+
+const (
+	defaultLongQueryCheckInterval = 5 * time.Second
+	// defaultLongQueryGuard is the guard in seconds used by KillLongQueries
+	// if LongQueryGuard is not set.
+	defaultLongQueryGuard = 60
+)
+
+// guardLongQueries waits until there are no queries running longer than guard,
+// or kills them if kill is true. FTWRL waits for the running queries to finish,
+// and blocks all the writes of the server meanwhile.
+func guardLongQueries(ctx context.Context, db *sql.DB, guard time.Duration, kill bool, interval time.Duration) error {
+	seconds := uint64(guard / time.Second)
+	for {
+		processes, err := ListLongQueries(db, seconds)
+		if err != nil {
+			return err
+		}
+		if len(processes) == 0 {
+			return nil
+		}
+		for _, p := range processes {
+			fields := []zap.Field{zap.Uint64("id", p.ID), zap.Uint64("time", p.Time), zap.String("query", p.Info)}
+			if !kill {
+				log.Warn("wait for long query before flush tables with read lock", fields...)
+				continue
+			}
+			log.Warn("kill long query before flush tables with read lock", fields...)
+			// the query may have finished, it's checked again in the next round
+			if err := KillQuery(db, p.ID); err != nil {
+				log.Warn("kill long query failed", append(fields, zap.Error(err))...)
+			}
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(interval):
+		}
+	}
+}
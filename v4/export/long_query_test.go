@@ -0,0 +1,85 @@
+package export
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	. "github.com/pingcap/check"
+)
+
+var _ = Suite(&testLongQuerySuite{})
+
+type testLongQuerySuite struct{}
+
+const listLongQueries = "SELECT ID, TIME, INFO FROM INFORMATION_SCHEMA.PROCESSLIST"
+
+func (s *testLongQuerySuite) TestWaitLongQueries(c *C) {
+	db, mock, err := sqlmock.New()
+	c.Assert(err, IsNil)
+	defer db.Close()
+
+	mock.ExpectQuery(listLongQueries).WithArgs(60).
+		WillReturnRows(sqlmock.NewRows([]string{"ID", "TIME", "INFO"}).AddRow(3, 100, "SELECT SLEEP(100)"))
+	mock.ExpectQuery(listLongQueries).WithArgs(60).
+		WillReturnRows(sqlmock.NewRows([]string{"ID", "TIME", "INFO"}))
+
+	err = guardLongQueries(context.Background(), db, time.Minute, false, time.Millisecond)
+	c.Assert(err, IsNil)
+	c.Assert(mock.ExpectationsWereMet(), IsNil)
+}
+
+func (s *testLongQuerySuite) TestKillLongQueries(c *C) {
+	db, mock, err := sqlmock.New()
+	c.Assert(err, IsNil)
+	defer db.Close()
+
+	mock.ExpectQuery(listLongQueries).WithArgs(60).
+		WillReturnRows(sqlmock.NewRows([]string{"ID", "TIME", "INFO"}).
+			AddRow(3, 100, "SELECT SLEEP(100)").
+			AddRow(4, 61, nil))
+	mock.ExpectExec("KILL QUERY 3").WillReturnResult(sqlmock.NewResult(0, 0))
+	// the query has finished
+	mock.ExpectExec("KILL QUERY 4").WillReturnError(errors.New("Unknown thread id: 4"))
+	mock.ExpectQuery(listLongQueries).WithArgs(60).
+		WillReturnRows(sqlmock.NewRows([]string{"ID", "TIME", "INFO"}))
+
+	err = guardLongQueries(context.Background(), db, time.Minute, true, time.Millisecond)
+	c.Assert(err, IsNil)
+	c.Assert(mock.ExpectationsWereMet(), IsNil)
+}
+
+func (s *testLongQuerySuite) TestWaitLongQueriesCanceled(c *C) {
+	db, mock, err := sqlmock.New()
+	c.Assert(err, IsNil)
+	defer db.Close()
+
+	mock.ExpectQuery(listLongQueries).WithArgs(60).
+		WillReturnRows(sqlmock.NewRows([]string{"ID", "TIME", "INFO"}).AddRow(3, 100, "SELECT SLEEP(100)"))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	err = guardLongQueries(ctx, db, time.Minute, false, time.Hour)
+	c.Assert(err, Equals, context.Canceled)
+	c.Assert(mock.ExpectationsWereMet(), IsNil)
+}
+
+func (s *testLongQuerySuite) TestFlushWithLongQueryGuard(c *C) {
+	db, mock, err := sqlmock.New()
+	c.Assert(err, IsNil)
+	defer db.Close()
+
+	conf := DefaultConfig()
+	conf.Consistency = "flush"
+	conf.ServerInfo.ServerType = ServerTypeMySQL
+	conf.LongQueryGuard = 60
+	mock.ExpectQuery(listLongQueries).WithArgs(60).
+		WillReturnRows(sqlmock.NewRows([]string{"ID", "TIME", "INFO"}))
+	mock.ExpectExec("FLUSH TABLES WITH READ LOCK").WillReturnResult(sqlmock.NewResult(0, 0))
+
+	ctrl, err := NewConsistencyController(conf, db)
+	c.Assert(err, IsNil)
+	c.Assert(ctrl.Setup(context.Background()), IsNil)
+	c.Assert(mock.ExpectationsWereMet(), IsNil)
+}
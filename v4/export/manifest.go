@@ -0,0 +1,107 @@
+package export
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/pingcap/errors"
+)
+
+// manifestFile is the name of the manifest in the output directory.
+const manifestFile = "manifest.json"
+
+// manifestTable is a table dumped completely into the output directory.
+type manifestTable struct {
+	// Delta is the number of the incremental run which dumped the table
+	Delta int `json:"delta"`
+	// Watermark is the incremental watermark the table was dumped to
+	Watermark *incrementalTable `json:"watermark,omitempty"`
+}
+
+// dumpManifest records the tables dumped completely with Append, so that
+// the next run into the same directory skips them. It's written after every
+// table, so the tables dumped by a failed run are skipped when it's run
+// again. With IncrementalColumn, the tables are skipped only if they're dumped
+// by the same incremental run, otherwise the new delta files are appended.
+type dumpManifest struct {
+	mu     sync.Mutex
+	Tables map[string]manifestTable `json:"tables"`
+}
+
+func loadManifest(dir string) (*dumpManifest, error) {
+	m := &dumpManifest{}
+	path := filepath.Join(dir, manifestFile)
+	content, err := ioutil.ReadFile(path)
+	switch {
+	case os.IsNotExist(err):
+	case err != nil:
+		return nil, withStack(withKind(ErrorKindConfig, err))
+	default:
+		if err = json.Unmarshal(content, m); err != nil {
+			return nil, withStack(withKind(ErrorKindConfig, errors.WithMessage(err, path)))
+		}
+	}
+	if m.Tables == nil {
+		m.Tables = map[string]manifestTable{}
+	}
+	return m, nil
+}
+
+// skip returns whether the table is complete in the run of delta. The
+// watermark of the skipped table is carried to the next run.
+func (m *dumpManifest) skip(conf *Config, dbName, tableName string) bool {
+	if m == nil {
+		return false
+	}
+	key := fmt.Sprintf("%s.%s", dbName, tableName)
+	delta := conf.incremental.delta()
+	m.mu.Lock()
+	table, ok := m.Tables[key]
+	m.mu.Unlock()
+	if !ok || table.Delta != delta {
+		return false
+	}
+	if conf.incremental != nil && table.Watermark != nil {
+		conf.incremental.mu.Lock()
+		conf.incremental.next[key] = *table.Watermark
+		conf.incremental.mu.Unlock()
+	}
+	return true
+}
+
+// complete records the table as complete in this run and writes the manifest
+// by the storage of the dump, which replaces the file only after it's written
+// completely.
+func (m *dumpManifest) complete(conf *Config, dbName, tableName string) error {
+	if m == nil {
+		return nil
+	}
+	key := fmt.Sprintf("%s.%s", dbName, tableName)
+	table := manifestTable{Delta: conf.incremental.delta()}
+	if conf.incremental != nil {
+		conf.incremental.mu.Lock()
+		if watermark, ok := conf.incremental.next[key]; ok {
+			table.Watermark = &watermark
+		}
+		conf.incremental.mu.Unlock()
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.Tables[key] = table
+	content, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return withStack(err)
+	}
+	// the table is recorded even if the dump is canceled right after it
+	fileWriter, err := conf.ExternalStorage.Create(context.Background(), manifestFile)
+	if err != nil {
+		return withStack(err)
+	}
+	return withStack(closeFile(fileWriter, write(fileWriter, string(content))))
+}
@@ -0,0 +1,72 @@
+package export
+
+import (
+	"context"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	. "github.com/pingcap/check"
+)
+
+var _ = Suite(&testManifestSuite{})
+
+type testManifestSuite struct{}
+
+func (s *testManifestSuite) TestManifest(c *C) {
+	dir := c.MkDir()
+	conf := DefaultConfig()
+	storage, err := NewLocalStorage(dir, false)
+	c.Assert(err, IsNil)
+	conf.ExternalStorage = storage
+	m, err := loadManifest(dir)
+	c.Assert(err, IsNil)
+	c.Assert(m.skip(conf, "test", "t"), IsFalse)
+	c.Assert(m.complete(conf, "test", "t"), IsNil)
+
+	m, err = loadManifest(dir)
+	c.Assert(err, IsNil)
+	c.Assert(m.skip(conf, "test", "t"), IsTrue)
+	c.Assert(m.skip(conf, "test", "t2"), IsFalse)
+
+	// the next incremental run appends the delta files of the table
+	conf.incremental = &incrementalState{Runs: 1, next: map[string]incrementalTable{}}
+	c.Assert(m.skip(conf, "test", "t"), IsFalse)
+	conf.incremental.next["test.t"] = incrementalTable{Column: "updated_at", Watermark: "2020-10-01 10:00:00"}
+	c.Assert(m.complete(conf, "test", "t"), IsNil)
+
+	// the watermark is carried when the failed run is run again
+	m, err = loadManifest(dir)
+	c.Assert(err, IsNil)
+	conf.incremental = &incrementalState{Runs: 1, next: map[string]incrementalTable{}}
+	c.Assert(m.skip(conf, "test", "t"), IsTrue)
+	c.Assert(conf.incremental.next["test.t"], Equals, incrementalTable{Column: "updated_at", Watermark: "2020-10-01 10:00:00"})
+}
+
+func (s *testManifestSuite) TestDumpTableSkipped(c *C) {
+	db, mock, err := sqlmock.New()
+	c.Assert(err, IsNil)
+	defer db.Close()
+
+	conf := DefaultConfig()
+	conf.Progress = NewProgress()
+	storage := newMemStorage()
+	conf.ExternalStorage = storage
+	conf.manifest, err = loadManifest(c.MkDir())
+	c.Assert(err, IsNil)
+	c.Assert(conf.manifest.complete(conf, "test", "t"), IsNil)
+	c.Assert(storage.files[manifestFile], Matches, `(?s).*"test.t".*`)
+
+	mockWriter := newMockWriter()
+	c.Assert(dumpTable(context.Background(), conf, db, "test", &TableInfo{Name: "t"}, mockWriter), IsNil)
+	c.Assert(mockWriter.tableMeta, HasLen, 0)
+	c.Assert(mockWriter.tableData, HasLen, 0)
+	c.Assert(conf.Progress.TableStates()[TableStateDone], DeepEquals, []string{"`test`.`t`"})
+	c.Assert(mock.ExpectationsWereMet(), IsNil)
+}
+
+func (s *testManifestSuite) TestValidate(c *C) {
+	conf := DefaultConfig()
+	conf.Append = true
+	c.Assert(conf.Validate(), IsNil)
+	conf.FileType = "sqlite"
+	c.Assert(conf.Validate(), ErrorMatches, "append is not supported with sql or filetype sqlite")
+}
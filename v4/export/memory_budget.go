@@ -0,0 +1,72 @@
+package export
+
+import (
+	"context"
+	"sync"
+)
+
+// MemoryBudget limits the total bytes buffered in memory before written to
+// the output, the producers are blocked until enough bytes are released.
+// All the methods are safe to be called concurrently, or on a nil *MemoryBudget.
+type MemoryBudget struct {
+	mu       sync.Mutex
+	limit    uint64
+	used     uint64
+	released chan struct{}
+}
+
+// NewMemoryBudget returns nil if limit is UnspecifiedSize.
+func NewMemoryBudget(limit uint64) *MemoryBudget {
+	if limit == UnspecifiedSize {
+		return nil
+	}
+	return &MemoryBudget{
+		limit:    limit,
+		released: make(chan struct{}),
+	}
+}
+
+// acquire blocks until n bytes can be taken from the budget. A request larger
+// than the whole budget is admitted when no bytes are in use, so it never blocks forever.
+func (b *MemoryBudget) acquire(ctx context.Context, n uint64) error {
+	if b == nil || n == 0 {
+		return nil
+	}
+	for {
+		b.mu.Lock()
+		if b.used == 0 || b.used+n <= b.limit {
+			b.used += n
+			b.mu.Unlock()
+			return nil
+		}
+		released := b.released
+		b.mu.Unlock()
+
+		select {
+		case <-released:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+func (b *MemoryBudget) release(n uint64) {
+	if b == nil || n == 0 {
+		return
+	}
+	b.mu.Lock()
+	b.used -= n
+	close(b.released)
+	b.released = make(chan struct{})
+	b.mu.Unlock()
+}
+
+// Used returns the bytes in use.
+func (b *MemoryBudget) Used() uint64 {
+	if b == nil {
+		return 0
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.used
+}
@@ -0,0 +1,67 @@
+package export
+
+import (
+	"bytes"
+	"context"
+	"database/sql/driver"
+	"time"
+
+	. "github.com/pingcap/check"
+)
+
+var _ = Suite(&testMemoryBudgetSuite{})
+
+type testMemoryBudgetSuite struct{}
+
+func (s *testMemoryBudgetSuite) TestMemoryBudget(c *C) {
+	c.Assert(NewMemoryBudget(UnspecifiedSize), IsNil)
+	var nilBudget *MemoryBudget
+	c.Assert(nilBudget.acquire(context.Background(), 100), IsNil)
+	nilBudget.release(100)
+
+	ctx := context.Background()
+	budget := NewMemoryBudget(100)
+	// a request larger than the budget is admitted when nothing is in use
+	c.Assert(budget.acquire(ctx, 200), IsNil)
+	c.Assert(budget.Used(), Equals, uint64(200))
+	budget.release(200)
+
+	c.Assert(budget.acquire(ctx, 60), IsNil)
+	acquired := make(chan error, 1)
+	go func() {
+		acquired <- budget.acquire(ctx, 60)
+	}()
+	select {
+	case <-acquired:
+		c.Fatal("acquire should be blocked when the budget is exceeded")
+	case <-time.After(50 * time.Millisecond):
+	}
+	budget.release(60)
+	c.Assert(<-acquired, IsNil)
+	c.Assert(budget.Used(), Equals, uint64(60))
+
+	cancelCtx, cancel := context.WithCancel(ctx)
+	cancel()
+	c.Assert(budget.acquire(cancelCtx, 60), Equals, context.Canceled)
+}
+
+func (s *testMemoryBudgetSuite) TestWriteInsertReleasesBudget(c *C) {
+	data := [][]driver.Value{
+		{"1", "male"},
+		{"2", "female"},
+	}
+	colTypes := []string{"INT", "SET"}
+	buffers := NewBufferPool(UnspecifiedSize, 1)
+
+	bf := &bytes.Buffer{}
+	err := WriteInsert(context.Background(), newMockTableIR("test", "employee", data, nil, colTypes), bf, UnspecifiedSize, buffers)
+	c.Assert(err, IsNil)
+	c.Assert(bf.String(), Equals, "INSERT INTO `employee` VALUES\n(1,'male'),\n(2,'female');\n")
+	c.Assert(buffers.budget.Used(), Equals, uint64(0))
+
+	bf.Reset()
+	err = WriteInsertInCsv(context.Background(), newMockTableIR("test", "employee", data, nil, colTypes), bf, true, "\\N", buffers)
+	c.Assert(err, IsNil)
+	c.Assert(bf.String(), Equals, "1,\"male\"\n2,\"female\"\n")
+	c.Assert(buffers.budget.Used(), Equals, uint64(0))
+}
@@ -1,9 +1,10 @@
 package export
 
 import (
+	"context"
 	"database/sql"
 	"errors"
-	"path"
+	"strconv"
 	"time"
 )
 
@@ -12,14 +13,25 @@ type globalMetadata struct {
 	pos     string
 	gtidSet string
 
-	filePath   string
-	startTime  time.Time
-	finishTime time.Time
+	// the position of the replica whose SQL thread is stopped during the dump
+	replica *replicaStatus
+	// the position the binlog is tailed to if BinlogStop is set
+	binlogTail *binlogPosition
+
+	storage       ExternalStorage
+	startTime     time.Time
+	finishTime    time.Time
+	downgradeTime time.Time
+	// the group of the dump pruned by Retention, it's empty without Retention
+	retentionGroup string
+	restoreOrder   []string
+	downgrades     *chunkDowngrades
 }
 
 const (
-	metadataPath       = "metadata"
-	metadataTimeLayout = "2006-01-02 15:04:05"
+	metadataPath         = "metadata"
+	metadataTimeLayout   = "2006-01-02 15:04:05"
+	retentionGroupPrefix = "Retention group: "
 
 	fileFieldIndex    = 0
 	posFieldIndex     = 1
@@ -28,9 +40,9 @@ const (
 	mariadbShowMasterStatusFieldNum = 4
 )
 
-func newGlobalMetadata(outputDir string) *globalMetadata {
+func newGlobalMetadata(storage ExternalStorage) *globalMetadata {
 	return &globalMetadata{
-		filePath: path.Join(outputDir, metadataPath),
+		storage: storage,
 	}
 }
 
@@ -40,6 +52,9 @@ func (m globalMetadata) String() string {
 		return str
 	}
 	str += "Started dump at: " + m.startTime.Format(metadataTimeLayout) + "\n"
+	if m.retentionGroup != "" {
+		str += retentionGroupPrefix + m.retentionGroup + "\n"
+	}
 
 	str += "SHOW MASTER STATUS:\n"
 	if m.logFile != "" {
@@ -52,6 +67,37 @@ func (m globalMetadata) String() string {
 		str += "\t\tGTID:" + m.gtidSet + "\n"
 	}
 
+	if m.replica != nil {
+		str += "SHOW SLAVE STATUS:\n"
+		str += "\t\tHost: " + m.replica.masterHost + "\n"
+		str += "\t\tLog: " + m.replica.logFile + "\n"
+		str += "\t\tPos: " + m.replica.pos + "\n"
+		if m.replica.gtidSet != "" {
+			str += "\t\tGTID:" + m.replica.gtidSet + "\n"
+		}
+	}
+
+	if m.binlogTail != nil {
+		str += "BINLOG TAIL:\n"
+		str += "\t\tLog: " + m.binlogTail.file + "\n"
+		str += "\t\tPos: " + strconv.FormatUint(m.binlogTail.pos, 10) + "\n"
+	}
+
+	if len(m.restoreOrder) > 0 {
+		str += "RESTORE ORDER:\n"
+		for _, table := range m.restoreOrder {
+			str += "\t\t" + table + "\n"
+		}
+	}
+
+	if downgrades := m.downgrades.String(); downgrades != "" {
+		str += "CHUNKING DOWNGRADED:\n" + downgrades
+	}
+
+	if !m.downgradeTime.IsZero() {
+		str += "Consistency downgraded at: " + m.downgradeTime.Format(metadataTimeLayout) + "\n"
+	}
+
 	if m.finishTime.IsZero() {
 		return str
 	}
@@ -67,8 +113,38 @@ func (m *globalMetadata) recordFinishTime(t time.Time) {
 	m.finishTime = t
 }
 
-func (m *globalMetadata) getGlobalMetaData(db *sql.DB, serverType ServerType) error {
-	switch serverType {
+func (m *globalMetadata) recordRetentionGroup(group string) {
+	m.retentionGroup = group
+}
+
+// recordDowngradeTime records when the locks are released before the dump is
+// finished, the data dumped since then may be inconsistent.
+func (m *globalMetadata) recordDowngradeTime(t time.Time) {
+	m.downgradeTime = t
+}
+
+func (m *globalMetadata) recordReplicaStatus(status *replicaStatus) {
+	m.replica = status
+}
+
+// recordBinlogTail records the position the binlog is tailed to, the dump is
+// consistent as of it.
+func (m *globalMetadata) recordBinlogTail(pos binlogPosition) {
+	m.binlogTail = &pos
+}
+
+func (m *globalMetadata) recordRestoreOrder(order []string) {
+	m.restoreOrder = order
+}
+
+// recordChunkDowngrades records the tables dumped by a full scan since they
+// can't be split into chunks.
+func (m *globalMetadata) recordChunkDowngrades(downgrades *chunkDowngrades) {
+	m.downgrades = downgrades
+}
+
+func (m *globalMetadata) getGlobalMetaData(db *sql.DB, info ServerInfo) error {
+	switch info.ServerType {
 	// For MySQL:
 	// mysql> SHOW MASTER STATUS;
 	// +-----------+----------+--------------+------------------+-------------------------------------------+
@@ -87,13 +163,20 @@ func (m *globalMetadata) getGlobalMetaData(db *sql.DB, serverType ServerType) er
 	// +-------------+--------------------+--------------+------------------+-------------------+
 	// 1 row in set (0.00 sec)
 	case ServerTypeMySQL, ServerTypeTiDB:
-		str, err := ShowMasterStatus(db, showMasterStatusFieldNum)
+		fieldNum := showMasterStatusFieldNum
+		if !info.Capabilities().MasterStatusGTID {
+			// MySQL 5.5 doesn't have Executed_Gtid_Set
+			fieldNum = gtidSetFieldIndex
+		}
+		str, err := ShowMasterStatus(db, fieldNum)
 		if err != nil {
 			return err
 		}
 		m.logFile = str[fileFieldIndex]
 		m.pos = str[posFieldIndex]
-		m.gtidSet = str[gtidSetFieldIndex]
+		if fieldNum > gtidSetFieldIndex {
+			m.gtidSet = str[gtidSetFieldIndex]
+		}
 	// For MariaDB:
 	// SHOW MASTER STATUS;
 	// +--------------------+----------+--------------+------------------+
@@ -119,18 +202,24 @@ func (m *globalMetadata) getGlobalMetaData(db *sql.DB, serverType ServerType) er
 		if err != nil {
 			return err
 		}
+	// For PostgreSQL, the current WAL location is recorded as Pos.
+	case ServerTypePostgreSQL:
+		pos, err := SelectWALPosition(db)
+		if err != nil {
+			return err
+		}
+		m.pos = pos
 	default:
-		return errors.New("unsupported serverType" + serverType.String() + "for getGlobalMetaData")
+		return errors.New("unsupported serverType" + info.ServerType.String() + "for getGlobalMetaData")
 	}
 	return nil
 }
 
 func (m *globalMetadata) writeGlobalMetaData() error {
-	fileWriter, tearDown, err := buildFileWriter(m.filePath)
+	// write metadata even if the dump is canceled
+	fileWriter, err := m.storage.Create(context.Background(), metadataPath)
 	if err != nil {
 		return err
 	}
-	defer tearDown()
-
-	return write(fileWriter, m.String())
+	return closeFile(fileWriter, write(fileWriter, m.String()))
 }
@@ -3,7 +3,7 @@ package export
 import (
 	"github.com/DATA-DOG/go-sqlmock"
 	. "github.com/pingcap/check"
-	"path"
+	"time"
 )
 
 var _ = Suite(&testMetaDataSuite{})
@@ -22,10 +22,11 @@ func (s *testMetaDataSuite) TestMysqlMetaData(c *C) {
 		AddRow(logFile, pos, "", "", gtidSet)
 	mock.ExpectQuery("SHOW MASTER STATUS").WillReturnRows(rows)
 
-	testFilePath := "/test"
-	m := newGlobalMetadata(testFilePath)
-	c.Assert(m.getGlobalMetaData(db, ServerTypeMySQL), IsNil)
-	c.Assert(m.filePath, Equals, path.Join(testFilePath, metadataPath))
+	storage := newMemStorage()
+	m := newGlobalMetadata(storage)
+	c.Assert(m.getGlobalMetaData(db, ServerInfo{ServerType: ServerTypeMySQL}), IsNil)
+	c.Assert(m.writeGlobalMetaData(), IsNil)
+	c.Assert(storage.files[metadataPath], Equals, m.String())
 
 	c.Assert(m.logFile, Equals, logFile)
 	c.Assert(m.pos, Equals, pos)
@@ -48,10 +49,8 @@ func (s *testMetaDataSuite) TestMariaDBMetaData(c *C) {
 	rows = sqlmock.NewRows([]string{"@@global.gtid_binlog_pos"}).
 		AddRow(gtidSet)
 	mock.ExpectQuery("SELECT @@global.gtid_binlog_pos").WillReturnRows(rows)
-	testFilePath := "/test"
-	m := newGlobalMetadata(testFilePath)
-	c.Assert(m.getGlobalMetaData(db, ServerTypeMariaDB), IsNil)
-	c.Assert(m.filePath, Equals, path.Join(testFilePath, metadataPath))
+	m := newGlobalMetadata(newMemStorage())
+	c.Assert(m.getGlobalMetaData(db, ServerInfo{ServerType: ServerTypeMariaDB}), IsNil)
 
 	c.Assert(m.logFile, Equals, logFile)
 	c.Assert(m.pos, Equals, pos)
@@ -59,3 +58,25 @@ func (s *testMetaDataSuite) TestMariaDBMetaData(c *C) {
 
 	c.Assert(mock.ExpectationsWereMet(), IsNil)
 }
+
+func (s *testMetaDataSuite) TestMetaDataRestoreOrder(c *C) {
+	m := newGlobalMetadata(newMemStorage())
+	m.recordStartTime(time.Date(2020, 5, 1, 10, 0, 0, 0, time.UTC))
+	m.recordRestoreOrder([]string{"`db`.`users`", "`db`.`orders`"})
+	c.Assert(m.String(), Equals, "Started dump at: 2020-05-01 10:00:00\n"+
+		"SHOW MASTER STATUS:\n"+
+		"RESTORE ORDER:\n"+
+		"\t\t`db`.`users`\n"+
+		"\t\t`db`.`orders`\n")
+}
+
+func (s *testMetaDataSuite) TestMetaDataDowngradeTime(c *C) {
+	m := newGlobalMetadata(newMemStorage())
+	m.recordStartTime(time.Date(2020, 5, 1, 10, 0, 0, 0, time.UTC))
+	m.recordDowngradeTime(time.Date(2020, 5, 1, 10, 5, 0, 0, time.UTC))
+	m.recordFinishTime(time.Date(2020, 5, 1, 11, 0, 0, 0, time.UTC))
+	c.Assert(m.String(), Equals, "Started dump at: 2020-05-01 10:00:00\n"+
+		"SHOW MASTER STATUS:\n"+
+		"Consistency downgraded at: 2020-05-01 10:05:00\n"+
+		"Finished dump at: 2020-05-01 11:00:00\n")
+}
@@ -0,0 +1,86 @@
+package export
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"time"
+
+	"github.com/pingcap/errors"
+	"go.uber.org/zap"
+
+	"github.com/pingcap/dumpling/v4/log"
+)
+
+const defaultNotifyTimeout = 10 * time.Second
+
+// notifySummary is the JSON posted to NotifyURL when the dump finishes. Text
+// is the summary in a line, which is shown by the Slack incoming webhooks.
+// Duration is in seconds.
+type notifySummary struct {
+	Text      string    `json:"text"`
+	Status    string    `json:"status"`
+	OutputDir string    `json:"output_dir"`
+	Duration  float64   `json:"duration"`
+	Tables    uint64    `json:"tables"`
+	Rows      uint64    `json:"rows"`
+	Bytes     uint64    `json:"bytes"`
+	Error     string    `json:"error,omitempty"`
+	ErrorKind ErrorKind `json:"error_kind,omitempty"`
+}
+
+func newNotifySummary(conf *Config, dumpErr error) notifySummary {
+	progress := conf.Progress.Status()
+	summary := notifySummary{
+		Status:    "success",
+		OutputDir: conf.OutputDirPath,
+		Duration:  progress.Elapsed.Seconds(),
+		Tables:    progress.FinishedTables,
+		Rows:      progress.FinishedRows,
+		Bytes:     progress.FinishedBytes,
+	}
+	if dumpErr != nil {
+		summary.Status = "failure"
+		summary.Error = RootCause(dumpErr).Error()
+		summary.ErrorKind = ErrorKindOf(dumpErr)
+	}
+	summary.Text = fmt.Sprintf("dumpling %s: %d tables, %d rows, %s into %s in %s",
+		summary.Status, summary.Tables, summary.Rows, formatBytes(summary.Bytes),
+		summary.OutputDir, progress.Elapsed.Round(time.Second))
+	if summary.Error != "" {
+		summary.Text += ", error: " + summary.Error
+	}
+	return summary
+}
+
+// notifyDumpFinished posts the summary of the dump to NotifyURL. The failure
+// of notifying is only logged, it doesn't fail the dump.
+func notifyDumpFinished(conf *Config, dumpErr error) {
+	if conf.NotifyURL == "" {
+		return
+	}
+	if err := postNotification(conf.NotifyURL, newNotifySummary(conf, dumpErr)); err != nil {
+		log.Warn("notify the dump result failed", zap.String("url", conf.NotifyURL), zap.Error(err))
+	}
+}
+
+func postNotification(url string, summary notifySummary) error {
+	body, err := json.Marshal(summary)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	client := &http.Client{Timeout: defaultNotifyTimeout}
+	resp, err := client.Post(url, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return errors.Trace(err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		msg, _ := ioutil.ReadAll(io.LimitReader(resp.Body, 1024))
+		return errors.Errorf("webhook responded %s: %s", resp.Status, bytes.TrimSpace(msg))
+	}
+	return nil
+}
@@ -0,0 +1,69 @@
+package export
+
+import (
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"time"
+
+	. "github.com/pingcap/check"
+)
+
+var _ = Suite(&testNotifySuite{})
+
+type testNotifySuite struct{}
+
+func (s *testNotifySuite) TestNotify(c *C) {
+	var summaries []notifySummary
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		c.Assert(r.Method, Equals, http.MethodPost)
+		c.Assert(r.Header.Get("Content-Type"), Equals, "application/json")
+		body, err := ioutil.ReadAll(r.Body)
+		c.Assert(err, IsNil)
+		var summary notifySummary
+		c.Assert(json.Unmarshal(body, &summary), IsNil)
+		summaries = append(summaries, summary)
+	}))
+	defer server.Close()
+
+	conf := DefaultConfig()
+	conf.OutputDirPath = "/backup/app"
+	conf.NotifyURL = server.URL
+	conf.Progress = NewProgress()
+	conf.Progress.finishTable("app", "t")
+	conf.Progress.addRows(10, 2048)
+	notifyDumpFinished(conf, nil)
+	notifyDumpFinished(conf, withKind(ErrorKindWrite, errors.New("disk full")))
+
+	c.Assert(summaries, HasLen, 2)
+	c.Assert(summaries[0], DeepEquals, notifySummary{
+		Text:      "dumpling success: 1 tables, 10 rows, 2.0 KiB into /backup/app in 0s",
+		Status:    "success",
+		OutputDir: "/backup/app",
+		Tables:    1,
+		Rows:      10,
+		Bytes:     2048,
+	})
+	c.Assert(summaries[1].Status, Equals, "failure")
+	c.Assert(summaries[1].Error, Equals, "disk full")
+	c.Assert(summaries[1].ErrorKind, Equals, ErrorKindWrite)
+	c.Assert(summaries[1].Text, Equals, "dumpling failure: 1 tables, 10 rows, 2.0 KiB into /backup/app in 0s, error: disk full")
+
+	// the duration is in seconds
+	conf.Progress.start(time.Now().Add(-90 * time.Second))
+	c.Assert(newNotifySummary(conf, nil).Duration >= 90, IsTrue)
+	content, err := json.Marshal(notifySummary{Duration: 205.5})
+	c.Assert(err, IsNil)
+	c.Assert(string(content), Matches, `.*"duration":205.5,.*`)
+}
+
+func (s *testNotifySuite) TestPostFailed(c *C) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "invalid_token", http.StatusForbidden)
+	}))
+	defer server.Close()
+	err := postNotification(server.URL, notifySummary{Status: "success"})
+	c.Assert(err, ErrorMatches, "webhook responded 403 Forbidden: invalid_token")
+}
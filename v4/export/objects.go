@@ -0,0 +1,258 @@
+package export
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+
+	"github.com/pingcap/errors"
+	"go.uber.org/zap"
+
+	"github.com/pingcap/dumpling/v4/log"
+)
+
+const (
+	// ObjectViews are the views of the databases.
+	ObjectViews = "views"
+	// ObjectRoutines are the stored procedures and functions of the databases.
+	ObjectRoutines = "routines"
+	// ObjectTriggers are the triggers of the tables.
+	ObjectTriggers = "triggers"
+)
+
+// onlyObject returns whether the objects are dumped by OnlyObjects.
+func (conf *Config) onlyObject(object string) bool {
+	for _, o := range conf.OnlyObjects {
+		if o == object {
+			return true
+		}
+	}
+	return false
+}
+
+// onlyObjectsConflicts returns the options conflicting with OnlyObjects.
+func onlyObjectsConflicts(conf *Config) []string {
+	var conflicts []string
+	for _, object := range conf.OnlyObjects {
+		switch object {
+		case ObjectViews, ObjectRoutines, ObjectTriggers:
+		default:
+			conflicts = append(conflicts, fmt.Sprintf("invalid only-objects option %s, should be views, routines or triggers", object))
+		}
+	}
+	if strings.ToLower(conf.FileType) != "sql" || conf.TargetDSN != "" || conf.Sql != "" || conf.NoSchemas {
+		conflicts = append(conflicts, "only-objects is only supported with filetype sql, and not with target-dsn, sql or no-schemas")
+	}
+	if conf.onlyObject(ObjectRoutines) || conf.onlyObject(ObjectTriggers) {
+		if conf.SourceDialect == DialectPostgres {
+			conflicts = append(conflicts, "only-objects routines and triggers are not supported by the postgres source dialect")
+		}
+		if len(conf.RouteRules) > 0 {
+			conflicts = append(conflicts, "only-objects routines and triggers are not supported with route rules")
+		}
+	}
+	return conflicts
+}
+
+// dumpObjects dumps only the objects of OnlyObjects in the filtered
+// databases, the tables and their data aren't dumped. The routines of a
+// database are written into `{db}-schema-routines.sql`, and the triggers of
+// its filtered tables into `{db}-schema-triggers.sql`.
+func dumpObjects(ctx context.Context, conf *Config, db *sql.DB, writer Writer) error {
+	for dbName, tables := range conf.Tables {
+		if conf.onlyObject(ObjectViews) {
+			for _, table := range tables {
+				if table.Type != TableTypeView {
+					continue
+				}
+				if err := dumpView(ctx, conf, db, dbName, table.Name, writer); err != nil {
+					return err
+				}
+			}
+		}
+		if conf.onlyObject(ObjectRoutines) {
+			routines, err := listRoutines(db, dbName)
+			if err != nil {
+				return withKind(ErrorKindSchema, err)
+			}
+			if err = writeObjects(ctx, conf, fmt.Sprintf("%s-schema-routines.sql", dbName), routines); err != nil {
+				return err
+			}
+		}
+		if conf.onlyObject(ObjectTriggers) {
+			triggers, err := listTriggers(db, dbName, tables)
+			if err != nil {
+				return withKind(ErrorKindSchema, err)
+			}
+			if err = writeObjects(ctx, conf, fmt.Sprintf("%s-schema-triggers.sql", dbName), triggers); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// schemaObject is a routine or trigger, which is created in the sql mode it
+// was created in.
+type schemaObject struct {
+	name      string
+	sqlMode   string
+	createSQL string
+}
+
+// listRoutines returns the stored procedures and functions of the database.
+func listRoutines(db *sql.DB, dbName string) ([]schemaObject, error) {
+	query := "SELECT ROUTINE_NAME, ROUTINE_TYPE FROM INFORMATION_SCHEMA.ROUTINES WHERE ROUTINE_SCHEMA = ? ORDER BY ROUTINE_TYPE, ROUTINE_NAME"
+	rows, err := db.Query(query, dbName)
+	if err != nil {
+		return nil, withStack(errors.WithMessage(err, query))
+	}
+	var names, types []string
+	for rows.Next() {
+		var name, typ string
+		if err = rows.Scan(&name, &typ); err != nil {
+			rows.Close()
+			return nil, withStack(err)
+		}
+		names = append(names, name)
+		types = append(types, typ)
+	}
+	rows.Close()
+	if err = rows.Err(); err != nil {
+		return nil, withStack(err)
+	}
+	routines := make([]schemaObject, 0, len(names))
+	for i, name := range names {
+		// FUNCTION or PROCEDURE
+		routine, err := showCreateObject(db, fmt.Sprintf("SHOW CREATE %s %s.%s", types[i], wrapBackTicks(dbName), wrapBackTicks(name)),
+			"Create "+strings.Title(strings.ToLower(types[i])))
+		if err != nil {
+			return nil, err
+		}
+		routines = append(routines, routine)
+	}
+	return routines, nil
+}
+
+// listTriggers returns the triggers of the tables of the database, in the
+// order they're activated.
+func listTriggers(db *sql.DB, dbName string, tables []*TableInfo) ([]schemaObject, error) {
+	filtered := map[string]bool{}
+	for _, table := range tables {
+		if table.Type == TableTypeBase {
+			filtered[table.Name] = true
+		}
+	}
+	query := "SELECT TRIGGER_NAME, EVENT_OBJECT_TABLE FROM INFORMATION_SCHEMA.TRIGGERS WHERE TRIGGER_SCHEMA = ? ORDER BY EVENT_OBJECT_TABLE, ACTION_ORDER"
+	rows, err := db.Query(query, dbName)
+	if err != nil {
+		return nil, withStack(errors.WithMessage(err, query))
+	}
+	var names []string
+	for rows.Next() {
+		var name, table string
+		if err = rows.Scan(&name, &table); err != nil {
+			rows.Close()
+			return nil, withStack(err)
+		}
+		if filtered[table] {
+			names = append(names, name)
+		}
+	}
+	rows.Close()
+	if err = rows.Err(); err != nil {
+		return nil, withStack(err)
+	}
+	triggers := make([]schemaObject, 0, len(names))
+	for _, name := range names {
+		trigger, err := showCreateObject(db, fmt.Sprintf("SHOW CREATE TRIGGER %s.%s", wrapBackTicks(dbName), wrapBackTicks(name)),
+			"SQL Original Statement")
+		if err != nil {
+			return nil, err
+		}
+		triggers = append(triggers, trigger)
+	}
+	return triggers, nil
+}
+
+// showCreateObject runs the SHOW CREATE statement of a routine or trigger,
+// whose CREATE statement is in the column.
+func showCreateObject(db *sql.DB, query, column string) (schemaObject, error) {
+	rows, err := db.Query(query)
+	if err != nil {
+		return schemaObject{}, withStack(errors.WithMessage(err, query))
+	}
+	defer rows.Close()
+	columns, err := rows.Columns()
+	if err != nil {
+		return schemaObject{}, withStack(err)
+	}
+	values := make([]sql.NullString, len(columns))
+	args := make([]interface{}, len(columns))
+	for i := range values {
+		args[i] = &values[i]
+	}
+	if !rows.Next() {
+		if err = rows.Err(); err == nil {
+			err = errors.New("no rows")
+		}
+		return schemaObject{}, withStack(errors.WithMessage(err, query))
+	}
+	if err = rows.Scan(args...); err != nil {
+		return schemaObject{}, withStack(errors.WithMessage(err, query))
+	}
+	var object schemaObject
+	object.name = values[0].String
+	for i, name := range columns {
+		switch {
+		case strings.EqualFold(name, "sql_mode"):
+			object.sqlMode = values[i].String
+		case strings.EqualFold(name, column):
+			object.createSQL = values[i].String
+		}
+	}
+	if object.createSQL == "" {
+		// the definition isn't shown without the privileges like SHOW_ROUTINE
+		return schemaObject{}, withStack(errors.Errorf("%s: the definition of %s is empty, is it granted to the user?", query, object.name))
+	}
+	return object, nil
+}
+
+// writeObjects writes the objects into the file, which is run by the mysql
+// client. The objects are delimited by `;;` since their bodies have `;`, and
+// each of them is created in its sql mode. Nothing is written if there isn't
+// any object.
+func writeObjects(ctx context.Context, conf *Config, fileName string, objects []schemaObject) error {
+	if len(objects) == 0 {
+		return nil
+	}
+	var b strings.Builder
+	b.WriteString("SET @dumpling_sql_mode = @@SESSION.sql_mode;\n")
+	b.WriteString("DELIMITER ;;\n")
+	for _, object := range objects {
+		fmt.Fprintf(&b, "SET SESSION sql_mode = '%s';;\n", escapeSQLString(object.sqlMode))
+		fmt.Fprintf(&b, "%s;;\n", object.createSQL)
+	}
+	b.WriteString("DELIMITER ;\n")
+	b.WriteString("SET SESSION sql_mode = @dumpling_sql_mode;\n")
+	if err := writeSchemaFile(ctx, conf, fileName, b.String()); err != nil {
+		return err
+	}
+	log.Debug("finish dumping objects", zap.String("file", fileName), zap.Int("objects", len(objects)))
+	return nil
+}
+
+// writeSchemaFile writes the content into a file of the output besides the
+// schemas of the databases and tables.
+func writeSchemaFile(ctx context.Context, conf *Config, fileName, content string) error {
+	fileWriter, err := conf.ExternalStorage.Create(ctx, fileName)
+	if err != nil {
+		return err
+	}
+	if err = closeFile(fileWriter, write(fileWriter, content)); err != nil {
+		return err
+	}
+	conf.hooks().OnFileClosed(conf.outputPath(ctx, fileName))
+	return nil
+}
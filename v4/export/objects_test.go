@@ -0,0 +1,101 @@
+package export
+
+import (
+	"context"
+	"regexp"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	. "github.com/pingcap/check"
+)
+
+var _ = Suite(&testObjectsSuite{})
+
+type testObjectsSuite struct{}
+
+func (s *testObjectsSuite) TestListRoutines(c *C) {
+	db, mock, err := sqlmock.New()
+	c.Assert(err, IsNil)
+	defer db.Close()
+
+	mock.ExpectQuery("SELECT ROUTINE_NAME, ROUTINE_TYPE FROM INFORMATION_SCHEMA.ROUTINES").WithArgs("test").
+		WillReturnRows(sqlmock.NewRows([]string{"ROUTINE_NAME", "ROUTINE_TYPE"}).
+			AddRow("f", "FUNCTION").AddRow("p", "PROCEDURE"))
+	mock.ExpectQuery(regexp.QuoteMeta("SHOW CREATE FUNCTION `test`.`f`")).
+		WillReturnRows(sqlmock.NewRows([]string{"Function", "sql_mode", "Create Function", "character_set_client"}).
+			AddRow("f", "ANSI_QUOTES", "CREATE FUNCTION `f`() RETURNS int RETURN 1", "utf8mb4"))
+	mock.ExpectQuery(regexp.QuoteMeta("SHOW CREATE PROCEDURE `test`.`p`")).
+		WillReturnRows(sqlmock.NewRows([]string{"Procedure", "sql_mode", "Create Procedure", "character_set_client"}).
+			AddRow("p", "", "CREATE PROCEDURE `p`() BEGIN SELECT 1; END", "utf8mb4"))
+	routines, err := listRoutines(db, "test")
+	c.Assert(err, IsNil)
+	c.Assert(routines, DeepEquals, []schemaObject{
+		{name: "f", sqlMode: "ANSI_QUOTES", createSQL: "CREATE FUNCTION `f`() RETURNS int RETURN 1"},
+		{name: "p", createSQL: "CREATE PROCEDURE `p`() BEGIN SELECT 1; END"},
+	})
+	c.Assert(mock.ExpectationsWereMet(), IsNil)
+
+	// the definition is hidden without the privileges
+	mock.ExpectQuery("SELECT ROUTINE_NAME, ROUTINE_TYPE FROM INFORMATION_SCHEMA.ROUTINES").WithArgs("test").
+		WillReturnRows(sqlmock.NewRows([]string{"ROUTINE_NAME", "ROUTINE_TYPE"}).AddRow("p", "PROCEDURE"))
+	mock.ExpectQuery(regexp.QuoteMeta("SHOW CREATE PROCEDURE `test`.`p`")).
+		WillReturnRows(sqlmock.NewRows([]string{"Procedure", "sql_mode", "Create Procedure"}).AddRow("p", "", nil))
+	_, err = listRoutines(db, "test")
+	c.Assert(RootCause(err), ErrorMatches, ".*the definition of p is empty.*")
+}
+
+func (s *testObjectsSuite) TestListTriggers(c *C) {
+	db, mock, err := sqlmock.New()
+	c.Assert(err, IsNil)
+	defer db.Close()
+
+	mock.ExpectQuery("SELECT TRIGGER_NAME, EVENT_OBJECT_TABLE FROM INFORMATION_SCHEMA.TRIGGERS").WithArgs("test").
+		WillReturnRows(sqlmock.NewRows([]string{"TRIGGER_NAME", "EVENT_OBJECT_TABLE"}).
+			AddRow("t1_bi", "t1").AddRow("t2_bi", "t2"))
+	mock.ExpectQuery(regexp.QuoteMeta("SHOW CREATE TRIGGER `test`.`t1_bi`")).
+		WillReturnRows(sqlmock.NewRows([]string{"Trigger", "sql_mode", "SQL Original Statement"}).
+			AddRow("t1_bi", "", "CREATE TRIGGER `t1_bi` BEFORE INSERT ON `t1` FOR EACH ROW SET NEW.a = 1"))
+	// the triggers of the tables filtered out are skipped
+	tables := NewDatabaseTables().AppendTables("test", "t1").AppendViews("test", "t2")
+	triggers, err := listTriggers(db, "test", tables["test"])
+	c.Assert(err, IsNil)
+	c.Assert(triggers, DeepEquals, []schemaObject{
+		{name: "t1_bi", createSQL: "CREATE TRIGGER `t1_bi` BEFORE INSERT ON `t1` FOR EACH ROW SET NEW.a = 1"},
+	})
+	c.Assert(mock.ExpectationsWereMet(), IsNil)
+}
+
+func (s *testObjectsSuite) TestWriteObjects(c *C) {
+	storage := newMemStorage()
+	conf := DefaultConfig()
+	conf.ExternalStorage = storage
+	c.Assert(writeObjects(context.Background(), conf, "test-schema-routines.sql", nil), IsNil)
+	c.Assert(storage.files, HasLen, 0)
+
+	objects := []schemaObject{
+		{name: "f", sqlMode: "ANSI_QUOTES", createSQL: "CREATE FUNCTION `f`() RETURNS int RETURN 1"},
+		{name: "p", createSQL: "CREATE PROCEDURE `p`() BEGIN SELECT 1; END"},
+	}
+	c.Assert(writeObjects(context.Background(), conf, "test-schema-routines.sql", objects), IsNil)
+	c.Assert(storage.files["test-schema-routines.sql"], Equals, "SET @dumpling_sql_mode = @@SESSION.sql_mode;\n"+
+		"DELIMITER ;;\n"+
+		"SET SESSION sql_mode = 'ANSI_QUOTES';;\n"+
+		"CREATE FUNCTION `f`() RETURNS int RETURN 1;;\n"+
+		"SET SESSION sql_mode = '';;\n"+
+		"CREATE PROCEDURE `p`() BEGIN SELECT 1; END;;\n"+
+		"DELIMITER ;\n"+
+		"SET SESSION sql_mode = @dumpling_sql_mode;\n")
+}
+
+func (s *testObjectsSuite) TestValidate(c *C) {
+	conf := DefaultConfig()
+	conf.OnlyObjects = []string{ObjectViews, ObjectTriggers}
+	c.Assert(conf.Validate(), IsNil)
+	conf.OnlyObjects = []string{"events"}
+	c.Assert(conf.Validate(), ErrorMatches, ".*invalid only-objects option events.*")
+	conf.OnlyObjects = []string{ObjectRoutines}
+	conf.FileType = "csv"
+	c.Assert(conf.Validate(), ErrorMatches, ".*only-objects is only supported with filetype sql.*")
+	conf.FileType = "sql"
+	conf.SourceDialect = DialectPostgres
+	c.Assert(conf.Validate(), ErrorMatches, ".*only-objects routines and triggers are not supported by the postgres source dialect.*")
+}
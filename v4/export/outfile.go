@@ -0,0 +1,116 @@
+package export
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/pingcap/errors"
+	"go.opentelemetry.io/otel/attribute"
+	"go.uber.org/zap"
+
+	"github.com/pingcap/dumpling/v4/log"
+)
+
+// serverOutfileConflicts returns the options which SELECT ... INTO OUTFILE
+// can't follow, the server writes the CSV files in its own way.
+func serverOutfileConflicts(conf *Config) []string {
+	var conflicts []string
+	if strings.ToLower(conf.FileType) != "csv" || conf.Sql != "" {
+		conflicts = append(conflicts, "server-outfile-dir is only supported with filetype csv of tables")
+	}
+	if conf.SourceDialect != DialectMySQL {
+		conflicts = append(conflicts, "server-outfile-dir is only supported by the mysql source dialect")
+	}
+	if conf.hasRows() || conf.FileSize != UnspecifiedSize || conf.FetchRows != UnspecifiedSize {
+		conflicts = append(conflicts, "server-outfile-dir is not supported with rows, filesize or fetch-rows")
+	}
+	if !conf.NoHeader {
+		conflicts = append(conflicts, "server-outfile-dir needs no-header since the server doesn't write the header")
+	}
+	// without ESCAPED BY, the server doesn't double the quotes enclosed by quotes
+	if !conf.EscapeBackslash {
+		conflicts = append(conflicts, "server-outfile-dir needs escape-backslash since the server doesn't escape the quotes without it")
+	}
+	if conf.CsvNullValue != outfileNullValue {
+		conflicts = append(conflicts, fmt.Sprintf("server-outfile-dir needs csv-null-value %s which is written by the server", outfileNullValue))
+	}
+	return conflicts
+}
+
+// outfileNullValue is how the server writes NULL with the backslash escapes.
+const outfileNullValue = `\N`
+
+// buildOutfileClause builds the INTO OUTFILE clause of the file on the server,
+// in the same format as the CSV files written by dumpling.
+func buildOutfileClause(serverPath string) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, " INTO OUTFILE '%s' CHARACTER SET binary", escapeSQLString(serverPath))
+	b.WriteString(` FIELDS TERMINATED BY ',' OPTIONALLY ENCLOSED BY '"' ESCAPED BY '\\' LINES TERMINATED BY '\n'`)
+	return b.String()
+}
+
+// dumpTableOutfile dumps the data of the table by SELECT ... INTO OUTFILE, so
+// the server writes the file into ServerOutfileDir instead of sending the rows.
+// ServerOutfileDir should be the output directory shared with the server, the
+// file is only recorded by dumpling.
+func dumpTableOutfile(ctx context.Context, conf *Config, db *sql.DB, dbName, tableName string) (err error) {
+	ctx, span := startSpan(ctx, "chunk",
+		attribute.String("database", dbName),
+		attribute.String("table", tableName),
+		attribute.Int("chunk", 0))
+	defer func() {
+		endSpan(span, err)
+	}()
+	d := conf.dialect()
+	selectedField, err := d.SelectField(db, dbName, tableName)
+	if err != nil {
+		return err
+	}
+	orderByClause, err := d.OrderByClause(conf, db, dbName, tableName)
+	if err != nil {
+		return err
+	}
+	fileName := fmt.Sprintf("%s.%s.0.csv", dbName, tableName)
+	if delta := conf.incremental.delta(); delta > 0 {
+		fileName = fmt.Sprintf("%s.%s.delta%d.0.csv", dbName, tableName, delta)
+	}
+	query := buildSelectQuery(d.QuoteIdentifier(dbName), d.QuoteIdentifier(tableName)+conf.asOfClause(), selectedField,
+		buildWhereCondition(conf, ""), orderByClause) + buildOutfileClause(path.Join(conf.ServerOutfileDir, fileName))
+
+	conf.Progress.addChunk()
+	start := time.Now()
+	result, err := db.ExecContext(ctx, query)
+	if err != nil {
+		return withStack(errors.WithMessage(err, query))
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return withStack(err)
+	}
+	filePath := filepath.Join(conf.OutputDirPath, fileName)
+	var size int64
+	if info, err := os.Stat(filePath); err == nil {
+		size = info.Size()
+	} else {
+		log.Warn("the file written by the server isn't found in the output directory, is it shared with the server?",
+			zap.String("file", filePath), zap.Error(err))
+	}
+	conf.Progress.addRows(uint64(rows), uint64(size))
+	conf.Progress.table(dbName, tableName).addRows(uint64(rows), uint64(size))
+	conf.Progress.finishChunk()
+	log.Debug("finish dumping table by the server",
+		zap.String("database", dbName),
+		zap.String("table", tableName),
+		zap.Int64("rows", rows),
+		zap.Duration("cost", time.Since(start)))
+	conf.hooks().OnFileClosed(filePath)
+	conf.Progress.table(dbName, tableName).addFile()
+	conf.hooks().OnChunkFinish(dbName, tableName, 0)
+	return nil
+}
@@ -0,0 +1,66 @@
+package export
+
+import (
+	"context"
+	"io/ioutil"
+	"path/filepath"
+	"regexp"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	. "github.com/pingcap/check"
+)
+
+var _ = Suite(&testOutfileSuite{})
+
+type testOutfileSuite struct{}
+
+func (s *testOutfileSuite) TestDumpTableOutfile(c *C) {
+	db, mock, err := sqlmock.New()
+	c.Assert(err, IsNil)
+	defer db.Close()
+	conf := DefaultConfig()
+	conf.SortByPk = false
+	conf.OutputDirPath = c.MkDir()
+	conf.ServerOutfileDir = "/var/lib/mysql-files/dump"
+	conf.Where = "id < 3"
+	conf.EscapeBackslash = true
+	conf.Progress = NewProgress()
+	conf.staleReadSnapshot = "417773951312461825"
+
+	// the file written by the server in the shared output directory
+	c.Assert(ioutil.WriteFile(filepath.Join(conf.OutputDirPath, "test.t.0.csv"), []byte("1,\"a\"\n2,\\N\n"), 0644), IsNil)
+	mock.ExpectQuery("SELECT COLUMN_NAME,EXTRA FROM INFORMATION_SCHEMA.COLUMNS").WithArgs("test", "t").
+		WillReturnRows(sqlmock.NewRows([]string{"COLUMN_NAME", "EXTRA"}).AddRow("id", "").AddRow("name", ""))
+	mock.ExpectExec(regexp.QuoteMeta("SELECT * FROM `test`.`t` AS OF TIMESTAMP TIDB_PARSE_TSO(417773951312461825)  WHERE id < 3 " +
+		`INTO OUTFILE '/var/lib/mysql-files/dump/test.t.0.csv' CHARACTER SET binary ` +
+		`FIELDS TERMINATED BY ',' OPTIONALLY ENCLOSED BY '"' ESCAPED BY '\\' LINES TERMINATED BY '\n'`)).
+		WillReturnResult(sqlmock.NewResult(0, 2))
+	c.Assert(dumpTableOutfile(context.Background(), conf, db, "test", "t"), IsNil)
+	c.Assert(mock.ExpectationsWereMet(), IsNil)
+	status := conf.Progress.Status()
+	c.Assert(status.FinishedRows, Equals, uint64(2))
+	c.Assert(status.FinishedBytes, Equals, uint64(11))
+}
+
+func (s *testOutfileSuite) TestValidate(c *C) {
+	conf := DefaultConfig()
+	conf.ServerOutfileDir = "/var/lib/mysql-files"
+	conf.FileType = "csv"
+	conf.NoHeader = true
+	// the quotes in the values aren't escaped without the backslash escapes
+	c.Assert(conf.Validate(), ErrorMatches, "server-outfile-dir needs escape-backslash since the server doesn't escape the quotes without it")
+	conf.EscapeBackslash = true
+	conf.CsvNullValue = "NULL"
+	c.Assert(conf.Validate(), ErrorMatches, `server-outfile-dir needs csv-null-value \\N which is written by the server`)
+	conf.CsvNullValue = `\N`
+	c.Assert(conf.Validate(), IsNil)
+	conf.StaleRead = 10
+	c.Assert(conf.Validate(), IsNil)
+
+	conf.FileType = "sql"
+	conf.NoHeader = false
+	conf.Rows = 100
+	c.Assert(conf.Validate(), ErrorMatches, "server-outfile-dir is only supported with filetype csv of tables; "+
+		"server-outfile-dir is not supported with rows, filesize or fetch-rows; "+
+		"server-outfile-dir needs no-header since the server doesn't write the header")
+}
@@ -0,0 +1,438 @@
+package export
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+
+	"github.com/pingcap/errors"
+)
+
+const (
+	// TargetMySQL is the default target dialect of the MySQL source.
+	TargetMySQL = "mysql"
+	// TargetTiDB is the same as TargetMySQL.
+	TargetTiDB = "tidb"
+	// TargetPostgres is the default target dialect of the PostgreSQL source.
+	TargetPostgres = "postgres"
+	// TargetSQLite is the dialect of SQLite 3.
+	TargetSQLite = "sqlite"
+	// TargetClickHouse is the dialect of ClickHouse.
+	TargetClickHouse = "clickhouse"
+)
+
+// OutputDialect is the SQL dialect of the output files given by --target-dialect,
+// so that the dump is loadable into the databases other than the source.
+type OutputDialect interface {
+	// QuoteIdentifier quotes the table and column names.
+	QuoteIdentifier(name string) string
+	// EscapeBackslash returns whether backslashes are escaped in the string
+	// literals, given the configured EscapeBackslash.
+	EscapeBackslash(configured bool) bool
+	// BinaryLiteral returns the prefix and the suffix of the hex digits of
+	// the binary literals.
+	BinaryLiteral() (prefix, suffix string)
+	// ColumnType returns the type of col in CREATE TABLE.
+	ColumnType(col ColumnInfo) string
+	// CreateDatabase returns the statement creating the database, or "" if
+	// the target doesn't have databases.
+	CreateDatabase(database string) string
+	// PrimaryKey returns the definition of the primary key in CREATE TABLE,
+	// or "" if it's defined by the options.
+	PrimaryKey(pkColumns []string) string
+	// CreateTableOptions is written after the column definitions of CREATE TABLE.
+	CreateTableOptions(pkColumns []string) string
+}
+
+// ColumnInfo is the definition of a column read from the source, which is
+// used to build CREATE TABLE for the targets of the other dialects.
+type ColumnInfo struct {
+	Name string
+	// DataType is the type without the length and attributes in the names of
+	// MySQL, e.g. varchar, int and datetime.
+	DataType  string
+	Length    int64
+	Precision int64
+	Scale     int64
+	Unsigned  bool
+	Nullable  bool
+}
+
+var outputDialects = map[string]OutputDialect{
+	TargetMySQL:      mysqlOutput{},
+	TargetTiDB:       mysqlOutput{},
+	TargetPostgres:   postgresOutput{},
+	TargetSQLite:     sqliteOutput{},
+	TargetClickHouse: clickhouseOutput{},
+}
+
+// targetDialect returns the name of TargetDialect, which defaults to SQLite
+// with filetype sqlite, or the dialect of the source.
+func (conf *Config) targetDialect() string {
+	if conf.TargetDialect != "" {
+		return conf.TargetDialect
+	}
+	if strings.ToLower(conf.FileType) == "sqlite" {
+		return TargetSQLite
+	}
+	if conf.SourceDialect == DialectPostgres {
+		return TargetPostgres
+	}
+	return TargetMySQL
+}
+
+// output returns the OutputDialect of the target.
+func (conf *Config) output() OutputDialect {
+	if d, ok := outputDialects[conf.targetDialect()]; ok {
+		return d
+	}
+	return mysqlOutput{}
+}
+
+// translatesDDL returns whether the target is of another dialect than the
+// source, so that the DDL is built from the columns instead of the source DDL.
+func (conf *Config) translatesDDL() bool {
+	switch conf.targetDialect() {
+	case TargetMySQL, TargetTiDB:
+		return conf.SourceDialect == DialectPostgres
+	case TargetPostgres:
+		return conf.SourceDialect != DialectPostgres
+	default:
+		return true
+	}
+}
+
+func validateTargetDialect(name string) error {
+	if _, ok := outputDialects[name]; !ok && name != "" {
+		return errors.Errorf("invalid target dialect %s", name)
+	}
+	return nil
+}
+
+// writesMySQLSettings returns whether the session settings of MySQL are
+// written in the data files.
+func (conf *Config) writesMySQLSettings() bool {
+	switch conf.targetDialect() {
+	case TargetMySQL, TargetTiDB:
+		return true
+	default:
+		return false
+	}
+}
+
+// buildCreateTable builds CREATE TABLE of the target from the columns and
+// primary key of the source table.
+func buildCreateTable(d OutputDialect, table string, columns []ColumnInfo, pkColumns []string) string {
+	definitions := make([]string, 0, len(columns)+1)
+	for _, col := range columns {
+		definitions = append(definitions, "  "+d.QuoteIdentifier(col.Name)+" "+d.ColumnType(col))
+	}
+	if pk := d.PrimaryKey(pkColumns); pk != "" {
+		definitions = append(definitions, "  "+pk)
+	}
+	return fmt.Sprintf("CREATE TABLE %s (\n%s\n)%s", d.QuoteIdentifier(table),
+		strings.Join(definitions, ",\n"), d.CreateTableOptions(pkColumns))
+}
+
+func quoteIdentifiers(d OutputDialect, names []string) string {
+	quoted := make([]string, 0, len(names))
+	for _, name := range names {
+		quoted = append(quoted, d.QuoteIdentifier(name))
+	}
+	return strings.Join(quoted, ",")
+}
+
+// showCreateTable returns the DDL of the table in the target dialect.
+func showCreateTable(conf *Config, db *sql.DB, database, table string) (string, error) {
+	d := conf.dialect()
+	if !conf.translatesDDL() {
+		return d.ShowCreateTable(db, database, table)
+	}
+	columns, err := d.ListColumns(db, database, table)
+	if err != nil {
+		return "", err
+	}
+	pkColumns, err := d.PrimaryKeyColumns(db, database, table)
+	if err != nil {
+		return "", err
+	}
+	return buildCreateTable(conf.output(), table, columns, pkColumns), nil
+}
+
+// showCreateDatabase returns the DDL of the database in the target dialect.
+func showCreateDatabase(conf *Config, db *sql.DB, database string) (string, error) {
+	if !conf.translatesDDL() {
+		return conf.dialect().ShowCreateDatabase(db, database)
+	}
+	return conf.output().CreateDatabase(database), nil
+}
+
+// withLength appends the length of col to typ if it's set.
+func withLength(typ string, col ColumnInfo) string {
+	if col.Length <= 0 {
+		return typ
+	}
+	return fmt.Sprintf("%s(%d)", typ, col.Length)
+}
+
+// withPrecision appends the precision and scale of col to typ if it's set.
+func withPrecision(typ string, col ColumnInfo) string {
+	if col.Precision <= 0 {
+		return typ
+	}
+	return fmt.Sprintf("%s(%d,%d)", typ, col.Precision, col.Scale)
+}
+
+// primaryKey returns the PRIMARY KEY definition of the SQL standard.
+func primaryKey(d OutputDialect, pkColumns []string) string {
+	if len(pkColumns) == 0 {
+		return ""
+	}
+	return "PRIMARY KEY (" + quoteIdentifiers(d, pkColumns) + ")"
+}
+
+func notNull(typ string, col ColumnInfo) string {
+	if col.Nullable {
+		return typ
+	}
+	return typ + " NOT NULL"
+}
+
+type mysqlOutput struct{}
+
+func (mysqlOutput) QuoteIdentifier(name string) string {
+	return wrapBackTicks(name)
+}
+
+func (mysqlOutput) EscapeBackslash(configured bool) bool {
+	return configured
+}
+
+func (mysqlOutput) BinaryLiteral() (string, string) {
+	return "x'", "'"
+}
+
+func (mysqlOutput) ColumnType(col ColumnInfo) string {
+	var typ string
+	switch col.DataType {
+	case "bool":
+		typ = "tinyint(1)"
+	case "char", "varchar", "binary", "varbinary":
+		typ = withLength(col.DataType, col)
+	case "decimal":
+		if col.Precision <= 0 {
+			typ = "decimal(65,30)"
+		} else {
+			typ = withPrecision("decimal", col)
+		}
+	case "datetime", "timestamp", "time":
+		typ = col.DataType + "(6)"
+	default:
+		typ = col.DataType
+	}
+	if col.Unsigned {
+		typ += " unsigned"
+	}
+	return notNull(typ, col)
+}
+
+func (mysqlOutput) CreateDatabase(database string) string {
+	return "CREATE DATABASE " + wrapBackTicks(database)
+}
+
+func (d mysqlOutput) PrimaryKey(pkColumns []string) string {
+	return primaryKey(d, pkColumns)
+}
+
+func (mysqlOutput) CreateTableOptions([]string) string {
+	return ""
+}
+
+type postgresOutput struct{}
+
+func (postgresOutput) QuoteIdentifier(name string) string {
+	return postgresDialect{}.QuoteIdentifier(name)
+}
+
+func (postgresOutput) EscapeBackslash(bool) bool {
+	// backslashes are not special with standard_conforming_strings
+	return false
+}
+
+func (postgresOutput) BinaryLiteral() (string, string) {
+	return "'\\x", "'"
+}
+
+func (postgresOutput) ColumnType(col ColumnInfo) string {
+	var typ string
+	switch col.DataType {
+	case "tinyint", "smallint", "year":
+		typ = "smallint"
+		if col.Unsigned && col.DataType == "smallint" {
+			typ = "integer"
+		}
+	case "mediumint", "int":
+		typ = "integer"
+		if col.Unsigned && col.DataType == "int" {
+			typ = "bigint"
+		}
+	case "bigint":
+		typ = "bigint"
+		if col.Unsigned {
+			typ = "numeric(20)"
+		}
+	case "decimal":
+		typ = withPrecision("numeric", col)
+	case "float":
+		typ = "real"
+	case "double":
+		typ = "double precision"
+	case "bool":
+		typ = "boolean"
+	case "char", "varchar":
+		typ = withLength(col.DataType, col)
+	case "bit", "binary", "varbinary", "tinyblob", "blob", "mediumblob", "longblob":
+		typ = "bytea"
+	case "date":
+		typ = "date"
+	case "time":
+		typ = "time"
+	case "datetime", "timestamp":
+		typ = "timestamp"
+	case "json":
+		typ = "jsonb"
+	default:
+		typ = "text"
+	}
+	return notNull(typ, col)
+}
+
+func (d postgresOutput) CreateDatabase(database string) string {
+	return "CREATE SCHEMA " + d.QuoteIdentifier(database)
+}
+
+func (d postgresOutput) PrimaryKey(pkColumns []string) string {
+	return primaryKey(d, pkColumns)
+}
+
+func (postgresOutput) CreateTableOptions([]string) string {
+	return ""
+}
+
+type sqliteOutput struct{}
+
+func (sqliteOutput) QuoteIdentifier(name string) string {
+	return postgresDialect{}.QuoteIdentifier(name)
+}
+
+func (sqliteOutput) EscapeBackslash(bool) bool {
+	return false
+}
+
+func (sqliteOutput) BinaryLiteral() (string, string) {
+	return mysqlOutput{}.BinaryLiteral()
+}
+
+// ColumnType returns the type affinity of SQLite.
+func (sqliteOutput) ColumnType(col ColumnInfo) string {
+	var typ string
+	switch col.DataType {
+	case "tinyint", "smallint", "mediumint", "int", "bigint", "year", "bool":
+		typ = "INTEGER"
+	case "decimal":
+		typ = "NUMERIC"
+	case "float", "double":
+		typ = "REAL"
+	case "bit", "binary", "varbinary", "tinyblob", "blob", "mediumblob", "longblob":
+		typ = "BLOB"
+	default:
+		typ = "TEXT"
+	}
+	return notNull(typ, col)
+}
+
+// CreateDatabase returns "" since the databases of SQLite are files.
+func (sqliteOutput) CreateDatabase(string) string {
+	return ""
+}
+
+func (d sqliteOutput) PrimaryKey(pkColumns []string) string {
+	return primaryKey(d, pkColumns)
+}
+
+func (sqliteOutput) CreateTableOptions([]string) string {
+	return ""
+}
+
+type clickhouseOutput struct{}
+
+func (clickhouseOutput) QuoteIdentifier(name string) string {
+	return wrapBackTicks(name)
+}
+
+func (clickhouseOutput) EscapeBackslash(bool) bool {
+	// backslashes are always escapes in the string literals of ClickHouse
+	return true
+}
+
+func (clickhouseOutput) BinaryLiteral() (string, string) {
+	return "unhex('", "')"
+}
+
+func (clickhouseOutput) ColumnType(col ColumnInfo) string {
+	var typ string
+	switch col.DataType {
+	case "tinyint":
+		typ = "Int8"
+	case "smallint":
+		typ = "Int16"
+	case "mediumint", "int":
+		typ = "Int32"
+	case "bigint":
+		typ = "Int64"
+	case "year":
+		typ = "UInt16"
+	case "bool":
+		typ = "UInt8"
+	case "decimal":
+		if col.Precision <= 0 || col.Precision > 76 {
+			typ = "Decimal(76,30)"
+		} else {
+			typ = fmt.Sprintf("Decimal(%d,%d)", col.Precision, col.Scale)
+		}
+	case "float":
+		typ = "Float32"
+	case "double":
+		typ = "Float64"
+	case "date":
+		typ = "Date"
+	case "datetime", "timestamp":
+		typ = "DateTime64(6)"
+	default:
+		typ = "String"
+	}
+	if col.Unsigned && strings.HasPrefix(typ, "Int") {
+		typ = "U" + typ
+	}
+	if col.Nullable {
+		typ = "Nullable(" + typ + ")"
+	}
+	return typ
+}
+
+func (clickhouseOutput) CreateDatabase(database string) string {
+	return "CREATE DATABASE " + wrapBackTicks(database)
+}
+
+// PrimaryKey returns "" since the primary key of MergeTree is ORDER BY.
+func (clickhouseOutput) PrimaryKey([]string) string {
+	return ""
+}
+
+// CreateTableOptions sorts the table by the primary key in MergeTree.
+func (d clickhouseOutput) CreateTableOptions(pkColumns []string) string {
+	if len(pkColumns) == 0 {
+		return " ENGINE = MergeTree() ORDER BY tuple()"
+	}
+	return " ENGINE = MergeTree() ORDER BY (" + quoteIdentifiers(d, pkColumns) + ")"
+}
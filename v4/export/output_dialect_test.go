@@ -0,0 +1,169 @@
+package export
+
+import (
+	"bytes"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	. "github.com/pingcap/check"
+)
+
+var _ = Suite(&testOutputDialectSuite{})
+
+type testOutputDialectSuite struct{}
+
+var testColumns = []ColumnInfo{
+	{Name: "id", DataType: "bigint", Unsigned: true},
+	{Name: "name", DataType: "varchar", Length: 32, Nullable: true},
+	{Name: "price", DataType: "decimal", Precision: 10, Scale: 2},
+	{Name: "data", DataType: "blob", Nullable: true},
+	{Name: "created", DataType: "datetime"},
+}
+
+func (s *testOutputDialectSuite) TestBuildCreateTable(c *C) {
+	pk := []string{"id"}
+	c.Assert(buildCreateTable(mysqlOutput{}, "t", testColumns, pk), Equals, "CREATE TABLE `t` (\n"+
+		"  `id` bigint unsigned NOT NULL,\n"+
+		"  `name` varchar(32),\n"+
+		"  `price` decimal(10,2) NOT NULL,\n"+
+		"  `data` blob,\n"+
+		"  `created` datetime(6) NOT NULL,\n"+
+		"  PRIMARY KEY (`id`)\n"+
+		")")
+	c.Assert(buildCreateTable(postgresOutput{}, "t", testColumns, pk), Equals, "CREATE TABLE \"t\" (\n"+
+		"  \"id\" numeric(20) NOT NULL,\n"+
+		"  \"name\" varchar(32),\n"+
+		"  \"price\" numeric(10,2) NOT NULL,\n"+
+		"  \"data\" bytea,\n"+
+		"  \"created\" timestamp NOT NULL,\n"+
+		"  PRIMARY KEY (\"id\")\n"+
+		")")
+	c.Assert(buildCreateTable(sqliteOutput{}, "t", testColumns, pk), Equals, "CREATE TABLE \"t\" (\n"+
+		"  \"id\" INTEGER NOT NULL,\n"+
+		"  \"name\" TEXT,\n"+
+		"  \"price\" NUMERIC NOT NULL,\n"+
+		"  \"data\" BLOB,\n"+
+		"  \"created\" TEXT NOT NULL,\n"+
+		"  PRIMARY KEY (\"id\")\n"+
+		")")
+	c.Assert(buildCreateTable(clickhouseOutput{}, "t", testColumns, pk), Equals, "CREATE TABLE `t` (\n"+
+		"  `id` UInt64,\n"+
+		"  `name` Nullable(String),\n"+
+		"  `price` Decimal(10,2),\n"+
+		"  `data` Nullable(String),\n"+
+		"  `created` DateTime64(6)\n"+
+		") ENGINE = MergeTree() ORDER BY (`id`)")
+	c.Assert(buildCreateTable(clickhouseOutput{}, "t", testColumns[:1], nil), Equals,
+		"CREATE TABLE `t` (\n  `id` UInt64\n) ENGINE = MergeTree() ORDER BY tuple()")
+}
+
+func (s *testOutputDialectSuite) TestWriteBytes(c *C) {
+	b := []byte{0x01, 0xab}
+	for _, t := range []struct {
+		d        OutputDialect
+		expected string
+	}{
+		{mysqlOutput{}, "x'01ab'"},
+		{postgresOutput{}, `'\x01ab'`},
+		{sqliteOutput{}, "x'01ab'"},
+		{clickhouseOutput{}, "unhex('01ab')"},
+	} {
+		receiver := makeRowReceiver([]string{"BLOB"}, t.d, false).(RowReceiverArr)
+		receiver[0].(*SQLTypeBytes).RawBytes = b
+		var bf bytes.Buffer
+		receiver.WriteToBuffer(&bf, true)
+		c.Assert(bf.String(), Equals, "("+t.expected+")")
+
+		receiver[0].(*SQLTypeBytes).RawBytes = nil
+		bf.Reset()
+		receiver.WriteToBuffer(&bf, true)
+		c.Assert(bf.String(), Equals, "(NULL)")
+	}
+}
+
+func (s *testOutputDialectSuite) TestTargetDialect(c *C) {
+	conf := DefaultConfig()
+	c.Assert(conf.targetDialect(), Equals, TargetMySQL)
+	c.Assert(conf.translatesDDL(), IsFalse)
+	c.Assert(conf.writesMySQLSettings(), IsTrue)
+	conf.TargetDialect = TargetTiDB
+	c.Assert(conf.translatesDDL(), IsFalse)
+	conf.TargetDialect = TargetSQLite
+	c.Assert(conf.translatesDDL(), IsTrue)
+	c.Assert(conf.writesMySQLSettings(), IsFalse)
+
+	conf = DefaultConfig()
+	conf.SourceDialect = DialectPostgres
+	c.Assert(conf.targetDialect(), Equals, TargetPostgres)
+	c.Assert(conf.translatesDDL(), IsFalse)
+	conf.TargetDialect = TargetMySQL
+	c.Assert(conf.translatesDDL(), IsTrue)
+	c.Assert(conf.output().EscapeBackslash(true), IsTrue)
+}
+
+func (s *testOutputDialectSuite) TestValidate(c *C) {
+	conf := DefaultConfig()
+	conf.TargetDialect = "oracle"
+	c.Assert(conf.Validate(), ErrorMatches, "invalid target dialect oracle")
+
+	conf.TargetDialect = TargetPostgres
+	c.Assert(conf.Validate(), IsNil)
+	conf.NoAutocommit = true
+	c.Assert(conf.Validate(), ErrorMatches, "no-autocommit, disable-keys and mysqldump-compatible are not supported by the postgres target dialect")
+
+	conf = DefaultConfig()
+	conf.TargetDialect = TargetClickHouse
+	conf.TransactionRows = 100
+	c.Assert(conf.Validate(), ErrorMatches, "transaction-rows is not supported by the clickhouse target dialect")
+	conf.TargetDialect = TargetSQLite
+	c.Assert(conf.Validate(), IsNil)
+}
+
+func (s *testOutputDialectSuite) TestShowCreateTable(c *C) {
+	db, mock, err := sqlmock.New()
+	c.Assert(err, IsNil)
+	defer db.Close()
+
+	conf := DefaultConfig()
+	conf.TargetDialect = TargetPostgres
+	columns := []string{"COLUMN_NAME", "DATA_TYPE", "COLUMN_TYPE", "IS_NULLABLE", "CHARACTER_MAXIMUM_LENGTH", "NUMERIC_PRECISION", "NUMERIC_SCALE"}
+	mock.ExpectQuery("SELECT COLUMN_NAME, DATA_TYPE").WithArgs("test", "t").WillReturnRows(
+		sqlmock.NewRows(columns).
+			AddRow("id", "int", "int(10) unsigned", "NO", nil, 10, 0).
+			AddRow("name", "varchar", "varchar(16)", "YES", 16, nil, nil))
+	mock.ExpectPrepare("SELECT COLUMN_NAME FROM INFORMATION_SCHEMA.STATISTICS").ExpectQuery().WithArgs("test", "t").
+		WillReturnRows(sqlmock.NewRows([]string{"column_name"}).AddRow("id"))
+
+	createTable, err := showCreateTable(conf, db, "test", "t")
+	c.Assert(err, IsNil)
+	c.Assert(createTable, Equals, "CREATE TABLE \"t\" (\n"+
+		"  \"id\" bigint NOT NULL,\n"+
+		"  \"name\" varchar(16),\n"+
+		"  PRIMARY KEY (\"id\")\n"+
+		")")
+	c.Assert(mock.ExpectationsWereMet(), IsNil)
+
+	createDatabase, err := showCreateDatabase(conf, db, "test")
+	c.Assert(err, IsNil)
+	c.Assert(createDatabase, Equals, `CREATE SCHEMA "test"`)
+	conf.TargetDialect = TargetSQLite
+	createDatabase, err = showCreateDatabase(conf, db, "test")
+	c.Assert(err, IsNil)
+	c.Assert(createDatabase, Equals, "")
+}
+
+func (s *testOutputDialectSuite) TestSelectedField(c *C) {
+	db, mock, err := sqlmock.New()
+	c.Assert(err, IsNil)
+	defer db.Close()
+
+	mock.ExpectQuery("SELECT").WillReturnRows(sqlmock.NewRows([]string{"id", "name"}))
+	rows, err := db.Query("SELECT")
+	c.Assert(err, IsNil)
+	colTypes, err := rows.ColumnTypes()
+	c.Assert(err, IsNil)
+	td := &tableData{rows: rows, colTypes: colTypes, selectedField: "`id`,`name`", output: postgresOutput{}}
+	c.Assert(td.SelectedField(), Equals, `("id","name")`)
+	td.selectedField = "*"
+	c.Assert(td.SelectedField(), Equals, "")
+	rows.Close()
+}
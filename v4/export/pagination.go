@@ -0,0 +1,188 @@
+package export
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+
+	"github.com/pingcap/errors"
+	"go.uber.org/zap"
+
+	"github.com/pingcap/dumpling/v4/log"
+)
+
+// tablePager builds the queries of the pages of FetchRows rows of a table, for
+// the servers and proxies which buffer the entire result set of a query. The
+// pages are sorted by the primary key and continue after the key of the last
+// row, or continue by OFFSET if the table is sorted without a primary key.
+type tablePager struct {
+	conf *Config
+	db   *sql.DB
+	// query is the SELECT of the table without the WHERE and ORDER BY
+	query   string
+	orderBy string
+	// keyColumns are the quoted columns of the primary key, and keyIndexes
+	// are their indexes in the selected columns. They're empty for OFFSET.
+	keyColumns []string
+	keyIndexes []int
+}
+
+// newTablePager returns the pager of the table, or nil if the table can't be
+// paginated since it isn't sorted.
+func newTablePager(conf *Config, db *sql.DB, database, table, selectedField string,
+	colTypes []*sql.ColumnType, orderByClause string) (*tablePager, error) {
+	d := conf.dialect()
+	pager := &tablePager{
+		conf:  conf,
+		db:    db,
+		query: buildSelectQuery(d.QuoteIdentifier(database), d.QuoteIdentifier(table)+conf.asOfClause(), selectedField, "", ""),
+	}
+	pkColumns, err := d.PrimaryKeyColumns(db, database, table)
+	if err != nil {
+		return nil, withStack(err)
+	}
+	for _, pk := range pkColumns {
+		for i, colType := range colTypes {
+			if colType.Name() == pk {
+				pager.keyColumns = append(pager.keyColumns, d.QuoteIdentifier(pk))
+				pager.keyIndexes = append(pager.keyIndexes, i)
+				break
+			}
+		}
+	}
+	if len(pkColumns) > 0 && len(pager.keyColumns) == len(pkColumns) {
+		pager.orderBy = "ORDER BY " + strings.Join(pager.keyColumns, ",")
+		return pager, nil
+	}
+	pager.keyColumns, pager.keyIndexes = nil, nil
+	if orderByClause == "" {
+		log.Warn("fetch the table in a query since it can't be paginated without an order",
+			zap.String("database", database), zap.String("table", table))
+		return nil, nil
+	}
+	pager.orderBy = orderByClause
+	return pager, nil
+}
+
+// pageQuery returns the query of the page after the row of lastKey, or after
+// offset rows if the pages continue by OFFSET. lastKey is nil for the first page.
+func (p *tablePager) pageQuery(lastKey []string, offset uint64) string {
+	var after string
+	if lastKey != nil && p.keyColumns != nil {
+		literals := make([]string, len(lastKey))
+		for i, key := range lastKey {
+			literals[i] = p.conf.quoteLiteral(key)
+		}
+		after = fmt.Sprintf("(%s) > (%s)", strings.Join(p.keyColumns, ","), strings.Join(literals, ","))
+	}
+	where := buildWhereCondition(p.conf, after)
+	query := fmt.Sprintf("%s%s %s LIMIT %d", p.query, where, p.orderBy, p.conf.FetchRows)
+	if p.keyColumns == nil && offset > 0 {
+		query += fmt.Sprintf(" OFFSET %d", offset)
+	}
+	return query
+}
+
+// pagedRowIter iterates the rows of the pages of td, the next page is queried
+// after the rows of a full page are taken.
+type pagedRowIter struct {
+	td      *tableData
+	args    []interface{}
+	hasNext bool
+	err     error
+
+	// taken is the number of the rows taken in the current page
+	taken   uint64
+	offset  uint64
+	lastKey []string
+}
+
+func newPagedRowIter(td *tableData) *pagedRowIter {
+	iter := &pagedRowIter{
+		td:   td,
+		args: make([]interface{}, len(td.colTypes)),
+	}
+	iter.hasNext = td.rows.Next()
+	return iter
+}
+
+func (iter *pagedRowIter) Decode(row RowReceiver) error {
+	if err := decodeFromRows(iter.td.rows, iter.args, row); err != nil {
+		return err
+	}
+	if keyIndexes := iter.td.pager.keyIndexes; keyIndexes != nil {
+		if iter.lastKey == nil {
+			iter.lastKey = make([]string, len(keyIndexes))
+		}
+		for i, idx := range keyIndexes {
+			value, ok := iter.args[idx].(*sql.RawBytes)
+			if !ok || *value == nil {
+				return errors.Errorf("can't paginate by the NULL key column %s", iter.td.pager.keyColumns[i])
+			}
+			// the bytes are reused by the next row
+			iter.lastKey[i] = string(*value)
+		}
+	}
+	return nil
+}
+
+func (iter *pagedRowIter) Next() {
+	iter.taken++
+	iter.offset++
+	if iter.hasNext = iter.td.rows.Next(); iter.hasNext || iter.td.rows.Err() != nil {
+		return
+	}
+	if iter.taken < iter.td.pager.conf.FetchRows {
+		// the last page
+		return
+	}
+	if err := iter.td.rows.Close(); err != nil {
+		iter.err = withStack(err)
+		return
+	}
+	query := iter.td.pager.pageQuery(iter.lastKey, iter.offset)
+	ctx := iter.td.queryContext()
+	var (
+		rows *sql.Rows
+		err  error
+	)
+	if iter.td.conn != nil {
+		// the pages share the connection, the warnings are taken after the last page
+		rows, err = iter.td.conn.QueryContext(ctx, iter.td.pager.conf.tableDataQuery(query))
+	} else {
+		rows, err = iter.td.pager.db.QueryContext(ctx, iter.td.pager.conf.tableDataQuery(query))
+	}
+	if err != nil {
+		iter.err = withStack(errors.WithMessage(err, query))
+		return
+	}
+	log.Debug("fetch the next page", zap.String("query", query))
+	iter.td.rows = rows
+	iter.taken = 0
+	iter.hasNext = rows.Next()
+}
+
+func (iter *pagedRowIter) Error() error {
+	if iter.err != nil {
+		return iter.err
+	}
+	return iter.td.rows.Err()
+}
+
+func (iter *pagedRowIter) HasNext() bool {
+	return iter.hasNext
+}
+
+func (iter *pagedRowIter) HasNextSQLRowIter() bool {
+	return iter.hasNext
+}
+
+func (iter *pagedRowIter) NextSQLRowIter() SQLRowIter {
+	return iter
+}
+
+func (iter *pagedRowIter) Close() error {
+	err := iter.td.rows.Close()
+	iter.td.release()
+	return err
+}
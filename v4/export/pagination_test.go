@@ -0,0 +1,100 @@
+package export
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"regexp"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	. "github.com/pingcap/check"
+)
+
+var _ = Suite(&testPaginationSuite{})
+
+type testPaginationSuite struct{}
+
+func (s *testPaginationSuite) columnTypes(c *C, mock sqlmock.Sqlmock, db *sql.DB) []*sql.ColumnType {
+	mock.ExpectQuery("SELECT columns").WillReturnRows(sqlmock.NewRows([]string{"id", "name"}))
+	rows, err := db.Query("SELECT columns")
+	c.Assert(err, IsNil)
+	defer rows.Close()
+	colTypes, err := rows.ColumnTypes()
+	c.Assert(err, IsNil)
+	return colTypes
+}
+
+func (s *testPaginationSuite) TestKeyPages(c *C) {
+	db, mock, err := sqlmock.New()
+	c.Assert(err, IsNil)
+	defer db.Close()
+	conf := DefaultConfig()
+	conf.FetchRows = 2
+	// the OR mustn't take the key of the next page out of the condition
+	conf.Where = "name != 'x' OR name IS NULL"
+	colTypes := s.columnTypes(c, mock, db)
+
+	mock.ExpectPrepare("SELECT COLUMN_NAME FROM INFORMATION_SCHEMA.STATISTICS").ExpectQuery().WithArgs("test", "t").
+		WillReturnRows(sqlmock.NewRows([]string{"column_name"}).AddRow("id"))
+	pager, err := newTablePager(conf, db, "test", "t", "*", colTypes, "")
+	c.Assert(err, IsNil)
+	firstPage := pager.pageQuery(nil, 0)
+	c.Assert(firstPage, Equals, "SELECT * FROM `test`.`t` WHERE name != 'x' OR name IS NULL ORDER BY `id` LIMIT 2")
+
+	mock.ExpectQuery(regexp.QuoteMeta(firstPage)).WillReturnRows(
+		sqlmock.NewRows([]string{"id", "name"}).AddRow(1, "a").AddRow(2, "b"))
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM `test`.`t` WHERE (name != 'x' OR name IS NULL) AND (`id`) > ('2') ORDER BY `id` LIMIT 2")).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name"}).AddRow(3, "c").AddRow(4, "d"))
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM `test`.`t` WHERE (name != 'x' OR name IS NULL) AND (`id`) > ('4') ORDER BY `id` LIMIT 2")).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name"}))
+	rows, err := db.Query(firstPage)
+	c.Assert(err, IsNil)
+	td := &tableData{database: "test", table: "t", rows: rows, colTypes: colTypes, selectedField: "*", pager: pager}
+
+	var bf bytes.Buffer
+	c.Assert(WriteInsert(context.Background(), td, &bf, UnspecifiedSize, nil), IsNil)
+	c.Assert(bf.String(), Equals, "INSERT INTO `t` VALUES\n('1','a'),\n('2','b'),\n('3','c'),\n('4','d');\n")
+	c.Assert(mock.ExpectationsWereMet(), IsNil)
+}
+
+func (s *testPaginationSuite) TestOffsetPages(c *C) {
+	db, mock, err := sqlmock.New()
+	c.Assert(err, IsNil)
+	defer db.Close()
+	conf := DefaultConfig()
+	conf.FetchRows = 2
+	colTypes := s.columnTypes(c, mock, db)
+
+	// the tables without a primary key are paginated by OFFSET if they're sorted
+	mock.ExpectPrepare("SELECT COLUMN_NAME FROM INFORMATION_SCHEMA.STATISTICS").ExpectQuery().WithArgs("test", "t").
+		WillReturnRows(sqlmock.NewRows([]string{"column_name"}))
+	pager, err := newTablePager(conf, db, "test", "t", "*", colTypes, "ORDER BY `id`,`name`")
+	c.Assert(err, IsNil)
+	c.Assert(pager.pageQuery(nil, 0), Equals, "SELECT * FROM `test`.`t` ORDER BY `id`,`name` LIMIT 2")
+	c.Assert(pager.pageQuery([]string{"2"}, 2), Equals, "SELECT * FROM `test`.`t` ORDER BY `id`,`name` LIMIT 2 OFFSET 2")
+
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM `test`.`t` ORDER BY `id`,`name` LIMIT 2")).WillReturnRows(
+		sqlmock.NewRows([]string{"id", "name"}).AddRow(1, "a").AddRow(1, "b"))
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM `test`.`t` ORDER BY `id`,`name` LIMIT 2 OFFSET 2")).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name"}).AddRow(2, "c"))
+	rows, err := db.Query(pager.pageQuery(nil, 0))
+	c.Assert(err, IsNil)
+	td := &tableData{database: "test", table: "t", rows: rows, colTypes: colTypes, selectedField: "*", pager: pager}
+	iter := td.Rows()
+	row := MakeRowReceiver([]string{"INT", "VARCHAR"})
+	var count int
+	for ; iter.HasNext(); iter.Next() {
+		c.Assert(iter.Decode(row), IsNil)
+		count++
+	}
+	c.Assert(iter.Error(), IsNil)
+	c.Assert(count, Equals, 3)
+	c.Assert(mock.ExpectationsWereMet(), IsNil)
+
+	// the unsorted tables are fetched in a query
+	mock.ExpectPrepare("SELECT COLUMN_NAME FROM INFORMATION_SCHEMA.STATISTICS").ExpectQuery().WithArgs("test", "t").
+		WillReturnRows(sqlmock.NewRows([]string{"column_name"}))
+	pager, err = newTablePager(conf, db, "test", "t", "*", colTypes, "")
+	c.Assert(err, IsNil)
+	c.Assert(pager, IsNil)
+}
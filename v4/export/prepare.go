@@ -1,27 +1,78 @@
 package export
 
 import (
+	"crypto/rand"
 	"database/sql"
+	"encoding/hex"
+	"fmt"
+	"sort"
 	"strings"
 
+	"github.com/pingcap/errors"
+	"go.uber.org/zap"
+
 	"github.com/pingcap/dumpling/v4/log"
 )
 
-func adjustConfig(conf *Config) error {
-	// Init logger
+func initLogger(conf *Config) error {
 	if conf.Logger != nil {
 		log.SetAppLogger(conf.Logger)
-	} else {
-		err := log.InitAppLogger(&log.Config{
-			Level:  conf.LogLevel,
-			File:   conf.LogFile,
-			Format: conf.LogFormat,
-		})
-		if err != nil {
+		return nil
+	}
+	return log.InitAppLogger(&log.Config{
+		Level:          conf.LogLevel,
+		File:           conf.LogFile,
+		FileMaxSize:    conf.LogFileMaxSize,
+		FileMaxDays:    conf.LogFileMaxDays,
+		FileMaxBackups: conf.LogFileMaxBackups,
+		Format:         conf.LogFormat,
+	})
+}
+
+func adjustConfig(conf *Config) error {
+	if err := conf.Validate(); err != nil {
+		return err
+	}
+
+	// Init logger, the shards share the logger of the whole dump
+	if conf.shard == nil {
+		if err := initLogger(conf); err != nil {
 			return err
 		}
 	}
 
+	if conf.Progress == nil {
+		conf.Progress = NewProgress()
+	}
+	if conf.Controller == nil {
+		conf.Controller = NewJobController()
+	}
+	conf.Controller.track(conf.Progress)
+
+	if conf.KillLongQueries && conf.LongQueryGuard == UnspecifiedSize {
+		conf.LongQueryGuard = defaultLongQueryGuard
+	}
+	if conf.StrictWarnings {
+		conf.CaptureWarnings = true
+	}
+	if conf.CoerceUTF8MB4 {
+		conf.ExplicitCollations = true
+	}
+	if conf.pseudonymizes() && conf.PseudonymizeSalt == "" {
+		salt := make([]byte, 32)
+		if _, err := rand.Read(salt); err != nil {
+			return errors.Trace(err)
+		}
+		conf.PseudonymizeSalt = hex.EncodeToString(salt)
+		log.Info("generated a random pseudonymize salt, the pseudonyms are only joinable within this dump")
+	}
+
+	conf.EscapeBackslash = conf.output().EscapeBackslash(conf.EscapeBackslash)
+	if conf.TargetDSN != "" {
+		// the target MySQL interprets the backslashes in the strings as escapes
+		conf.EscapeBackslash = true
+	}
+
 	if conf.Rows != UnspecifiedSize {
 		// Disable filesize if rows was set
 		conf.FileSize = UnspecifiedSize
@@ -40,17 +91,17 @@ func detectServerInfo(db *sql.DB) (ServerInfo, error) {
 
 func prepareDumpingDatabases(conf *Config, db *sql.DB) ([]string, error) {
 	if conf.Database == "" {
-		return ShowDatabases(db)
+		return conf.dialect().ListDatabases(db)
 	} else {
 		return strings.Split(conf.Database, ","), nil
 	}
 }
 
-func listAllTables(db *sql.DB, databaseNames []string) (DatabaseTables, error) {
+func listAllTables(d Dialect, db *sql.DB, databaseNames []string) (DatabaseTables, error) {
 	log.Debug("list all the tables")
 	dbTables := DatabaseTables{}
 	for _, dbName := range databaseNames {
-		tables, err := ListAllTables(db, dbName)
+		tables, err := d.ListTables(db, dbName)
 		if err != nil {
 			return nil, err
 		}
@@ -60,11 +111,11 @@ func listAllTables(db *sql.DB, databaseNames []string) (DatabaseTables, error) {
 	return dbTables, nil
 }
 
-func listAllViews(db *sql.DB, databaseNames []string) (DatabaseTables, error) {
+func listAllViews(d Dialect, db *sql.DB, databaseNames []string) (DatabaseTables, error) {
 	log.Debug("list all the views")
 	dbTables := DatabaseTables{}
 	for _, dbName := range databaseNames {
-		views, err := ListAllViews(db, dbName)
+		views, err := d.ListViews(db, dbName)
 		if err != nil {
 			return nil, err
 		}
@@ -73,6 +124,105 @@ func listAllViews(db *sql.DB, databaseNames []string) (DatabaseTables, error) {
 	return dbTables, nil
 }
 
+// listRestoreOrder returns all the dumping tables sorted by their foreign key
+// dependencies, so that a referenced table is always restored before the tables
+// referencing it. Tables in a dependency cycle are appended at the end.
+func listRestoreOrder(db *sql.DB, allTables DatabaseTables) ([]string, error) {
+	deps, err := listForeignKeyDependencies(db, allTables)
+	if err != nil {
+		return nil, err
+	}
+	order, cyclic := sortTablesByDependencies(allTables, deps)
+	if len(cyclic) > 0 {
+		log.Warn("found foreign key dependency cycle, these tables can't be restored with foreign key checks",
+			zap.Strings("tables", cyclic))
+	}
+	return append(order, cyclic...), nil
+}
+
+// listForeignKeyDependencies maps the dumping tables to the tables they
+// reference by foreign keys.
+func listForeignKeyDependencies(db *sql.DB, allTables DatabaseTables) (map[string][]string, error) {
+	log.Debug("list foreign key dependencies")
+	deps := map[string][]string{}
+	for dbName := range allTables {
+		references, err := ListForeignKeyReferences(db, dbName)
+		if err != nil {
+			return nil, err
+		}
+		for _, ref := range references {
+			table := qualifiedTableName(dbName, ref[0])
+			deps[table] = append(deps[table], qualifiedTableName(ref[1], ref[2]))
+		}
+	}
+	return deps, nil
+}
+
+// sortTablesByDependencies sorts tables topologically. deps maps a table to the
+// tables it depends on. Dependencies on tables which are not dumped and on the
+// table itself are ignored. The tables which can't be sorted due to a cycle are
+// returned separately.
+func sortTablesByDependencies(allTables DatabaseTables, deps map[string][]string) (order []string, cyclic []string) {
+	inDegree := map[string]int{}
+	dependents := map[string][]string{}
+	var views []string
+	for dbName, tables := range allTables {
+		for _, table := range tables {
+			name := qualifiedTableName(dbName, table.Name)
+			if table.Type == TableTypeView {
+				views = append(views, name)
+				continue
+			}
+			inDegree[name] = 0
+		}
+	}
+	for table := range inDegree {
+		for _, ref := range deps[table] {
+			if _, ok := inDegree[ref]; !ok || ref == table {
+				continue
+			}
+			inDegree[table]++
+			dependents[ref] = append(dependents[ref], table)
+		}
+	}
+
+	var queue []string
+	for table, degree := range inDegree {
+		if degree == 0 {
+			queue = append(queue, table)
+		}
+	}
+	sort.Strings(queue)
+	for len(queue) > 0 {
+		table := queue[0]
+		queue = queue[1:]
+		order = append(order, table)
+		delete(inDegree, table)
+
+		var ready []string
+		for _, dependent := range dependents[table] {
+			inDegree[dependent]--
+			if inDegree[dependent] == 0 {
+				ready = append(ready, dependent)
+			}
+		}
+		sort.Strings(ready)
+		queue = append(queue, ready...)
+	}
+
+	for table := range inDegree {
+		cyclic = append(cyclic, table)
+	}
+	sort.Strings(cyclic)
+	// views depend on tables, they are always restored at last
+	sort.Strings(views)
+	return append(order, views...), cyclic
+}
+
+func qualifiedTableName(dbName, tableName string) string {
+	return fmt.Sprintf("%s.%s", wrapBackTicks(dbName), wrapBackTicks(tableName))
+}
+
 type databaseName = string
 
 type TableType int8
@@ -116,6 +266,15 @@ func (d DatabaseTables) AppendViews(dbName string, viewNames ...string) Database
 	return d
 }
 
+func (d DatabaseTables) contains(dbName, tableName string) bool {
+	for _, table := range d[dbName] {
+		if table.Name == tableName {
+			return true
+		}
+	}
+	return false
+}
+
 func (d DatabaseTables) Merge(other DatabaseTables) {
 	for name, infos := range other {
 		d[name] = append(d[name], infos...)
@@ -62,7 +62,7 @@ func (s *testPrepareSuite) TestListAllTables(c *C) {
 		mock.ExpectQuery(query).WillReturnRows(rows)
 	}
 
-	tables, err := listAllTables(db, dbNames)
+	tables, err := listAllTables(mysqlDialect{}, db, dbNames)
 	c.Assert(err, IsNil)
 
 	for d, t := range tables {
@@ -80,7 +80,7 @@ func (s *testPrepareSuite) TestListAllTables(c *C) {
 		AppendViews("db", "t2")
 	query := "SELECT table_name FROM information_schema.tables WHERE table_schema = (.*) and table_type = (.*)"
 	mock.ExpectQuery(query).WillReturnRows(sqlmock.NewRows([]string{"Table_name"}).AddRow("t2"))
-	tables, err = listAllViews(db, []string{"db"})
+	tables, err = listAllViews(mysqlDialect{}, db, []string{"db"})
 	c.Assert(err, IsNil)
 	c.Assert(len(tables), Equals, 1)
 	c.Assert(len(tables["db"]), Equals, 1)
@@ -88,3 +88,36 @@ func (s *testPrepareSuite) TestListAllTables(c *C) {
 
 	c.Assert(mock.ExpectationsWereMet(), IsNil)
 }
+
+func (s *testPrepareSuite) TestListRestoreOrder(c *C) {
+	db, mock, err := sqlmock.New()
+	c.Assert(err, IsNil)
+	defer db.Close()
+
+	tables := NewDatabaseTables().
+		AppendTables("db", "orders", "users", "items", "self").
+		AppendViews("db", "v")
+	rows := sqlmock.NewRows([]string{"TABLE_NAME", "REFERENCED_TABLE_SCHEMA", "REFERENCED_TABLE_NAME"}).
+		AddRow("orders", "db", "users").
+		AddRow("orders", "db", "items").
+		AddRow("items", "other", "t").
+		AddRow("self", "db", "self")
+	mock.ExpectQuery("SELECT DISTINCT TABLE_NAME").WithArgs("db").WillReturnRows(rows)
+
+	order, err := listRestoreOrder(db, tables)
+	c.Assert(err, IsNil)
+	c.Assert(order, DeepEquals, []string{"`db`.`items`", "`db`.`self`", "`db`.`users`", "`db`.`orders`", "`db`.`v`"})
+	c.Assert(mock.ExpectationsWereMet(), IsNil)
+}
+
+func (s *testPrepareSuite) TestSortTablesByDependenciesWithCycle(c *C) {
+	tables := NewDatabaseTables().AppendTables("db", "a", "b", "c", "d")
+	deps := map[string][]string{
+		"`db`.`a`": {"`db`.`b`"},
+		"`db`.`b`": {"`db`.`a`"},
+		"`db`.`c`": {"`db`.`a`"},
+	}
+	order, cyclic := sortTablesByDependencies(tables, deps)
+	c.Assert(order, DeepEquals, []string{"`db`.`d`"})
+	c.Assert(cyclic, DeepEquals, []string{"`db`.`a`", "`db`.`b`", "`db`.`c`"})
+}
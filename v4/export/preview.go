@@ -0,0 +1,103 @@
+package export
+
+import (
+	"context"
+	"database/sql"
+	"io"
+	"strings"
+
+	"github.com/pingcap/errors"
+)
+
+// previewStorage writes all the files created in it into w.
+type previewStorage struct {
+	w io.Writer
+}
+
+func (s previewStorage) Create(context.Context, string) (io.WriteCloser, error) {
+	return previewFile{s.w}, nil
+}
+
+type previewFile struct {
+	io.Writer
+}
+
+func (previewFile) Close() error {
+	return nil
+}
+
+// Preview writes the first rows rows of dbName.tableName into w as they're
+// dumped by conf, i.e. selected by the where of the table, filtered by the
+// row filter, rewritten by the column rewrites and escaped in the file type,
+// so that the rules can be validated before a long dump. The rows are
+// limited before they're filtered.
+func Preview(ctx context.Context, conf *Config, dbName, tableName string, rows uint64, w io.Writer) error {
+	if err := previewConflicts(conf, rows); err != nil {
+		return withKind(ErrorKindConfig, err)
+	}
+	if err := adjustConfig(conf); err != nil {
+		return withStack(withKind(ErrorKindConfig, err))
+	}
+	pool, session, err := openConnPool(conf, conf.dialect().DSN(conf))
+	if err != nil {
+		return withStack(withKind(ErrorKindConnection, err))
+	}
+	defer pool.Close()
+	conf.session = session
+
+	if conf.ServerInfo, err = detectServerInfo(pool); err != nil {
+		return withKind(ErrorKindConnection, err)
+	}
+	if err = conf.validateServer(); err != nil {
+		return withKind(ErrorKindConfig, err)
+	}
+	session.keepStatements(pool, conf.sessionStatements())
+	return preview(ctx, conf, pool, dbName, tableName, rows, w)
+}
+
+// previewConflicts returns the error if the table can't be previewed by conf.
+func previewConflicts(conf *Config, rows uint64) error {
+	if rows == 0 {
+		return errors.New("rows should be positive")
+	}
+	switch strings.ToLower(conf.FileType) {
+	case "sql", "csv", "tsv":
+	default:
+		return errors.Errorf("preview is only supported with filetype sql, csv and tsv, got %s", conf.FileType)
+	}
+	if conf.Sql != "" {
+		return errors.New("preview is not supported with sql")
+	}
+	return nil
+}
+
+// preview writes the first rows rows of the table read from db into w, by
+// the writer of conf.FileType.
+func preview(ctx context.Context, conf *Config, db *sql.DB, dbName, tableName string, rows uint64, w io.Writer) error {
+	previewConf := *conf.forTable(dbName, tableName)
+	previewConf.ExternalStorage = previewStorage{w: w}
+	previewConf.FileSize = UnspecifiedSize
+	previewConf.FilesPerChunk = 0
+	// the preview isn't a dump, e.g. the hooks don't see its files
+	previewConf.Hooks = nil
+	previewConf.Progress = nil
+
+	var writer Writer
+	var err error
+	switch strings.ToLower(previewConf.FileType) {
+	case "sql":
+		writer, err = NewSimpleWriter(&previewConf)
+	case "csv":
+		writer, err = NewCsvWriter(&previewConf)
+	case "tsv":
+		writer, err = NewTsvWriter(&previewConf)
+	}
+	if err != nil {
+		return err
+	}
+	ir, err := selectFromTable(ctx, &previewConf, db, dbName, tableName, rows)
+	if err != nil {
+		return withKind(ErrorKindSchema, err)
+	}
+	return writeTableData(ctx, &previewConf, writer, ir)
+}
@@ -0,0 +1,63 @@
+package export
+
+import (
+	"bytes"
+	"context"
+	"regexp"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	. "github.com/pingcap/check"
+)
+
+var _ = Suite(&testPreviewSuite{})
+
+type testPreviewSuite struct{}
+
+func expectPreviewQueries(mock sqlmock.Sqlmock, query string) {
+	mock.ExpectQuery("SELECT COLUMN_NAME").WithArgs("app", "users").
+		WillReturnRows(sqlmock.NewRows([]string{"column_name", "extra"}).AddRow("id", "").AddRow("email", ""))
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM `app`.`users` LIMIT 1")).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "email"}))
+	mock.ExpectQuery(regexp.QuoteMeta(query)).WillReturnRows(sqlmock.NewRows([]string{"id", "email"}).
+		AddRow(1, "alice@mail.com").AddRow(2, "bob's@mail.com").AddRow(3, nil))
+}
+
+func (s *testPreviewSuite) TestPreview(c *C) {
+	db, mock, err := sqlmock.New()
+	c.Assert(err, IsNil)
+	defer db.Close()
+
+	conf := DefaultConfig()
+	conf.SortByPk = false
+	conf.TableConfigs = []TableConfig{{Database: "app", Table: "users", Where: "id < 10"}}
+	conf.RowFilter = "id != 2"
+	conf.ColumnRewrites = []ColumnRewrite{{Database: "app", Table: "users", Column: "email", Regexp: "^[^@]+@", Replace: "user@"}}
+	// sqlmock has no column types, so the values are quoted as strings
+	expectPreviewQueries(mock, "SELECT * FROM `app`.`users` WHERE id < 10 LIMIT 3")
+	out := &bytes.Buffer{}
+	c.Assert(preview(context.Background(), conf, db, "app", "users", 3, out), IsNil)
+	c.Assert(out.String(), Equals, "/*!40101 SET NAMES binary*/;\nINSERT INTO `users` VALUES\n('1','user@mail.com'),\n('3',NULL);\n")
+	c.Assert(mock.ExpectationsWereMet(), IsNil)
+
+	// the rows are escaped as the file type
+	conf = DefaultConfig()
+	conf.SortByPk = false
+	conf.FileType = "csv"
+	conf.CsvNullValue = `\N`
+	expectPreviewQueries(mock, "SELECT * FROM `app`.`users` LIMIT 20")
+	out.Reset()
+	c.Assert(preview(context.Background(), conf, db, "app", "users", 20, out), IsNil)
+	c.Assert(out.String(), Equals, "\"id\",\"email\"\n\"1\",\"alice@mail.com\"\n\"2\",\"bob's@mail.com\"\n\"3\",\\N\n")
+	c.Assert(mock.ExpectationsWereMet(), IsNil)
+}
+
+func (s *testPreviewSuite) TestPreviewConflicts(c *C) {
+	conf := DefaultConfig()
+	c.Assert(previewConflicts(conf, 20), IsNil)
+	c.Assert(previewConflicts(conf, 0), ErrorMatches, "rows should be positive")
+	conf.FileType = "sqlite"
+	c.Assert(previewConflicts(conf, 20), ErrorMatches, "preview is only supported with filetype sql, csv and tsv, got sqlite")
+	conf.FileType = "csv"
+	conf.Sql = "SELECT 1"
+	c.Assert(previewConflicts(conf, 20), ErrorMatches, "preview is not supported with sql")
+}
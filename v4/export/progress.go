@@ -0,0 +1,465 @@
+package export
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/pingcap/dumpling/v4/log"
+)
+
+const (
+	progressBarWidth       = 30
+	defaultProgressRefresh = time.Second
+	// progressRateWindow is the window of the current rates of Progress
+	progressRateWindow = 10 * time.Second
+	// progressSampleInterval is the min interval of the samples of the current rates
+	progressSampleInterval = time.Second
+)
+
+// Progress tracks the progress of a dump.
+// All the methods are safe to be called concurrently, or on a nil *Progress.
+type Progress struct {
+	startTime int64
+
+	estimatedRows  uint64
+	estimatedBytes uint64
+	totalTables    uint64
+	finishedTables uint64
+	totalChunks    uint64
+	finishedChunks uint64
+	finishedRows   uint64
+	finishedBytes  uint64
+
+	mu          sync.Mutex
+	tableStates map[string]TableState
+	tables      map[string]*tableProgress
+	lastError   error
+	// samples are the finished rows and bytes in the last progressRateWindow,
+	// they're taken when the status is read
+	samples []progressSample
+}
+
+type progressSample struct {
+	time  time.Time
+	rows  uint64
+	bytes uint64
+}
+
+// TableState is the dumping state of a table.
+type TableState string
+
+const (
+	TableStatePending TableState = "pending"
+	TableStateRunning TableState = "running"
+	TableStateDone    TableState = "done"
+)
+
+// ProgressStatus is a snapshot of Progress.
+type ProgressStatus struct {
+	EstimatedRows  uint64 `json:"estimated_rows"`
+	EstimatedBytes uint64 `json:"estimated_bytes"`
+	TotalTables    uint64 `json:"total_tables"`
+	FinishedTables uint64 `json:"finished_tables"`
+	TotalChunks    uint64 `json:"total_chunks"`
+	FinishedChunks uint64 `json:"finished_chunks"`
+	FinishedRows   uint64 `json:"finished_rows"`
+	FinishedBytes  uint64 `json:"finished_bytes"`
+
+	Elapsed time.Duration `json:"elapsed"`
+	// RowsPerSecond and BytesPerSecond are the average rates since start.
+	RowsPerSecond  float64 `json:"rows_per_second"`
+	BytesPerSecond float64 `json:"bytes_per_second"`
+	// CurrentRowsPerSecond and CurrentBytesPerSecond are the rates in the last
+	// progressRateWindow, they're the average rates if there isn't a sample
+	// in the window.
+	CurrentRowsPerSecond  float64 `json:"current_rows_per_second"`
+	CurrentBytesPerSecond float64 `json:"current_bytes_per_second"`
+	// Percent is the percentage of finished rows in estimated rows, it's -1 if unknown.
+	Percent float64 `json:"percent"`
+	// ETA is the estimated remaining time by the current rate, it's -1 if unknown.
+	ETA time.Duration `json:"eta"`
+}
+
+func NewProgress() *Progress {
+	return &Progress{
+		tableStates: map[string]TableState{},
+		tables:      map[string]*tableProgress{},
+	}
+}
+
+func (p *Progress) start(t time.Time) {
+	if p == nil {
+		return
+	}
+	atomic.StoreInt64(&p.startTime, t.UnixNano())
+}
+
+// started returns whether the dump has started dumping the tables.
+func (p *Progress) started() bool {
+	return p != nil && atomic.LoadInt64(&p.startTime) != 0
+}
+
+func (p *Progress) addEstimate(rows, bytes uint64) {
+	if p == nil {
+		return
+	}
+	atomic.AddUint64(&p.estimatedRows, rows)
+	atomic.AddUint64(&p.estimatedBytes, bytes)
+}
+
+func (p *Progress) addTable(dbName, tableName string) {
+	if p == nil {
+		return
+	}
+	p.setTableState(dbName, tableName, TableStatePending)
+	// the tables without any rows are in the summary too
+	p.table(dbName, tableName)
+}
+
+func (p *Progress) startTable(dbName, tableName string) {
+	if p == nil {
+		return
+	}
+	p.setTableState(dbName, tableName, TableStateRunning)
+	atomic.CompareAndSwapInt64(&p.table(dbName, tableName).startTime, 0, time.Now().UnixNano())
+}
+
+func (p *Progress) finishTable(dbName, tableName string) {
+	if p == nil {
+		return
+	}
+	p.setTableState(dbName, tableName, TableStateDone)
+	atomic.StoreInt64(&p.table(dbName, tableName).finishTime, time.Now().UnixNano())
+	atomic.AddUint64(&p.finishedTables, 1)
+}
+
+// tableProgress is the progress of a table, its methods are safe to be
+// called concurrently, or on a nil *tableProgress.
+type tableProgress struct {
+	rows       uint64
+	bytes      uint64
+	files      uint64
+	startTime  int64
+	finishTime int64
+}
+
+func (t *tableProgress) addRows(rows, bytes uint64) {
+	if t == nil {
+		return
+	}
+	atomic.AddUint64(&t.rows, rows)
+	atomic.AddUint64(&t.bytes, bytes)
+}
+
+func (t *tableProgress) addFile() {
+	if t == nil {
+		return
+	}
+	atomic.AddUint64(&t.files, 1)
+}
+
+// table returns the progress of the table, or nil if p is nil or the table
+// name is empty like the result of Config.Sql.
+func (p *Progress) table(dbName, tableName string) *tableProgress {
+	if p == nil || tableName == "" {
+		return nil
+	}
+	name := qualifiedTableName(dbName, tableName)
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	t, ok := p.tables[name]
+	if !ok {
+		t = &tableProgress{}
+		p.tables[name] = t
+	}
+	return t
+}
+
+func (p *Progress) setTableState(dbName, tableName string, state TableState) {
+	name := qualifiedTableName(dbName, tableName)
+	p.mu.Lock()
+	if _, ok := p.tableStates[name]; !ok {
+		atomic.AddUint64(&p.totalTables, 1)
+	}
+	p.tableStates[name] = state
+	p.mu.Unlock()
+}
+
+func (p *Progress) recordError(err error) {
+	if p == nil || err == nil {
+		return
+	}
+	p.mu.Lock()
+	p.lastError = err
+	p.mu.Unlock()
+}
+
+// TableStates returns the sorted names of the tables in each state.
+func (p *Progress) TableStates() map[TableState][]string {
+	states := map[TableState][]string{
+		TableStatePending: {},
+		TableStateRunning: {},
+		TableStateDone:    {},
+	}
+	if p == nil {
+		return states
+	}
+	p.mu.Lock()
+	for name, state := range p.tableStates {
+		states[state] = append(states[state], name)
+	}
+	p.mu.Unlock()
+	for _, names := range states {
+		sort.Strings(names)
+	}
+	return states
+}
+
+// LastError returns the last error occurred during dump.
+func (p *Progress) LastError() error {
+	if p == nil {
+		return nil
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.lastError
+}
+
+func (p *Progress) addChunk() {
+	if p == nil {
+		return
+	}
+	atomic.AddUint64(&p.totalChunks, 1)
+}
+
+func (p *Progress) finishChunk() {
+	if p == nil {
+		return
+	}
+	atomic.AddUint64(&p.finishedChunks, 1)
+}
+
+func (p *Progress) addRows(rows, bytes uint64) {
+	if p == nil {
+		return
+	}
+	atomic.AddUint64(&p.finishedRows, rows)
+	atomic.AddUint64(&p.finishedBytes, bytes)
+}
+
+// Status returns the current status of the dump.
+func (p *Progress) Status() ProgressStatus {
+	return p.status(time.Now())
+}
+
+func (p *Progress) status(now time.Time) ProgressStatus {
+	if p == nil {
+		return ProgressStatus{Percent: -1, ETA: -1}
+	}
+	s := ProgressStatus{
+		EstimatedRows:  atomic.LoadUint64(&p.estimatedRows),
+		EstimatedBytes: atomic.LoadUint64(&p.estimatedBytes),
+		TotalTables:    atomic.LoadUint64(&p.totalTables),
+		FinishedTables: atomic.LoadUint64(&p.finishedTables),
+		TotalChunks:    atomic.LoadUint64(&p.totalChunks),
+		FinishedChunks: atomic.LoadUint64(&p.finishedChunks),
+		FinishedRows:   atomic.LoadUint64(&p.finishedRows),
+		FinishedBytes:  atomic.LoadUint64(&p.finishedBytes),
+		Percent:        -1,
+		ETA:            -1,
+	}
+	startTime := atomic.LoadInt64(&p.startTime)
+	if startTime == 0 {
+		return s
+	}
+	s.Elapsed = now.Sub(time.Unix(0, startTime))
+	if seconds := s.Elapsed.Seconds(); seconds > 0 {
+		s.RowsPerSecond = float64(s.FinishedRows) / seconds
+		s.BytesPerSecond = float64(s.FinishedBytes) / seconds
+	}
+	s.CurrentRowsPerSecond, s.CurrentBytesPerSecond = s.RowsPerSecond, s.BytesPerSecond
+	if oldest, ok := p.sample(now, s.FinishedRows, s.FinishedBytes); ok {
+		seconds := now.Sub(oldest.time).Seconds()
+		s.CurrentRowsPerSecond = float64(s.FinishedRows-oldest.rows) / seconds
+		s.CurrentBytesPerSecond = float64(s.FinishedBytes-oldest.bytes) / seconds
+	}
+	if s.EstimatedRows > 0 {
+		// the estimated rows from information_schema may be less than the real rows
+		s.Percent = 100
+		s.ETA = 0
+		if s.FinishedRows < s.EstimatedRows {
+			s.Percent = float64(s.FinishedRows) * 100 / float64(s.EstimatedRows)
+			if s.CurrentRowsPerSecond > 0 {
+				s.ETA = time.Duration(float64(s.EstimatedRows-s.FinishedRows) / s.CurrentRowsPerSecond * float64(time.Second))
+			} else {
+				s.ETA = -1
+			}
+		}
+	}
+	return s
+}
+
+// sample records the finished rows and bytes at now, and returns the oldest
+// sample in progressRateWindow before now, if there is one.
+func (p *Progress) sample(now time.Time, rows, bytes uint64) (progressSample, bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	i := 0
+	for i < len(p.samples)-1 && now.Sub(p.samples[i].time) > progressRateWindow {
+		i++
+	}
+	p.samples = p.samples[i:]
+	var oldest progressSample
+	ok := len(p.samples) > 0 && now.After(p.samples[0].time) && now.Sub(p.samples[0].time) <= progressRateWindow
+	if ok {
+		oldest = p.samples[0]
+	}
+	if len(p.samples) == 0 || now.Sub(p.samples[len(p.samples)-1].time) >= progressSampleInterval {
+		p.samples = append(p.samples, progressSample{time: now, rows: rows, bytes: bytes})
+	}
+	return oldest, ok
+}
+
+func (s ProgressStatus) String() string {
+	var b strings.Builder
+	if s.Percent >= 0 {
+		filled := int(s.Percent * progressBarWidth / 100)
+		b.WriteByte('[')
+		b.WriteString(strings.Repeat("=", filled))
+		if filled < progressBarWidth {
+			b.WriteByte('>')
+			b.WriteString(strings.Repeat(" ", progressBarWidth-filled-1))
+		}
+		fmt.Fprintf(&b, "] %5.1f%% ", s.Percent)
+	}
+	fmt.Fprintf(&b, "%d/%d rows, %d/%d tables, %.1f rows/s, %s/s",
+		s.FinishedRows, s.EstimatedRows, s.FinishedTables, s.TotalTables,
+		s.CurrentRowsPerSecond, formatBytes(uint64(s.CurrentBytesPerSecond)))
+	if s.ETA >= 0 {
+		fmt.Fprintf(&b, ", ETA %s", s.ETA.Round(time.Second))
+	}
+	return b.String()
+}
+
+func formatBytes(n uint64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := uint64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(n)/float64(div), "KMGTPE"[exp])
+}
+
+// renderProgress writes the progress bar of the job to w every interval until
+// ctx is done.
+func renderProgress(ctx context.Context, job *JobController, w io.Writer, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			fmt.Fprintf(w, "\r%s\n", job.Progress())
+			return
+		case <-ticker.C:
+			fmt.Fprintf(w, "\r%s", job.Progress())
+		}
+	}
+}
+
+// estimateProgress estimates the total rows and bytes of the dumping tables via information_schema.
+func estimateProgress(db *sql.DB, p *Progress, allTables DatabaseTables) error {
+	for dbName, tables := range allTables {
+		stats, err := listTableStatistics(db, dbName)
+		if err != nil {
+			return err
+		}
+		for _, table := range tables {
+			if table.Type == TableTypeView {
+				continue
+			}
+			p.addTable(dbName, table.Name)
+			if stat, ok := stats[table.Name]; ok {
+				p.addEstimate(stat.rows, stat.dataLength)
+			}
+		}
+	}
+	status := p.Status()
+	log.Info("estimated dump progress",
+		zap.Uint64("tables", status.TotalTables),
+		zap.Uint64("rows", status.EstimatedRows),
+		zap.Uint64("bytes", status.EstimatedBytes))
+	return nil
+}
+
+// progressTableData counts the decoded rows of a TableDataIR into Progress,
+// and into the progress of its table.
+type progressTableData struct {
+	TableDataIR
+	progress *Progress
+	table    *tableProgress
+}
+
+func (td *progressTableData) Rows() SQLRowIter {
+	return &progressRowIter{
+		SQLRowIter: td.TableDataIR.Rows(),
+		progress:   td.progress,
+		table:      td.table,
+	}
+}
+
+func (td *progressTableData) tableProgress() *tableProgress {
+	return td.table
+}
+
+type progressRowIter struct {
+	SQLRowIter
+	progress *Progress
+	table    *tableProgress
+}
+
+func (iter *progressRowIter) Decode(row RowReceiver) error {
+	if err := iter.SQLRowIter.Decode(row); err != nil {
+		return err
+	}
+	size := row.ReportSize()
+	iter.progress.addRows(1, size)
+	iter.table.addRows(1, size)
+	return nil
+}
+
+func (iter *progressRowIter) NextSQLRowIter() SQLRowIter {
+	return &progressRowIter{
+		SQLRowIter: iter.SQLRowIter.NextSQLRowIter(),
+		progress:   iter.progress,
+		table:      iter.table,
+	}
+}
+
+func withProgress(ir TableDataIR, p *Progress) TableDataIR {
+	if p == nil {
+		return ir
+	}
+	return &progressTableData{TableDataIR: ir, progress: p, table: p.table(ir.DatabaseName(), ir.TableName())}
+}
+
+// tableProgressOf returns the progress of the table of ir wrapped by
+// withProgress, the wrappers of ir like the routed ones forward it.
+func tableProgressOf(ir TableDataIR) *tableProgress {
+	if td, ok := ir.(interface{ tableProgress() *tableProgress }); ok {
+		return td.tableProgress()
+	}
+	return nil
+}
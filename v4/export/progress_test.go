@@ -0,0 +1,142 @@
+package export
+
+import (
+	"bytes"
+	"context"
+	"database/sql/driver"
+	"errors"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	. "github.com/pingcap/check"
+)
+
+var _ = Suite(&testProgressSuite{})
+
+type testProgressSuite struct{}
+
+func (s *testProgressSuite) TestProgressStatus(c *C) {
+	var nilProgress *Progress
+	nilProgress.addRows(1, 1)
+	c.Assert(nilProgress.Status().ETA, Equals, time.Duration(-1))
+
+	p := NewProgress()
+	status := p.Status()
+	c.Assert(status.Percent, Equals, float64(-1))
+	c.Assert(status.ETA, Equals, time.Duration(-1))
+
+	p.start(time.Now().Add(-10 * time.Second))
+	p.addTable("test", "t1")
+	p.addTable("test", "t2")
+	p.addEstimate(100, 1000)
+	p.addRows(25, 250)
+	status = p.Status()
+	c.Assert(status.Percent, Equals, float64(25))
+	c.Assert(status.ETA > 29*time.Second && status.ETA < 31*time.Second, IsTrue, Commentf("eta %s", status.ETA))
+	c.Assert(status.RowsPerSecond > 2 && status.RowsPerSecond <= 2.5, IsTrue)
+	c.Assert(status.String(), Matches, `\[=======>\s+\]  25.0% 25/100 rows, 0/2 tables, .* rows/s, .* B/s, ETA 30s`)
+
+	// finished rows exceeds the estimated rows
+	p.addRows(100, 1000)
+	status = p.Status()
+	c.Assert(status.Percent, Equals, float64(100))
+	c.Assert(status.ETA, Equals, time.Duration(0))
+}
+
+func (s *testProgressSuite) TestCurrentRate(c *C) {
+	now := time.Now()
+	p := NewProgress()
+	p.start(now.Add(-100 * time.Second))
+	p.addEstimate(10000, 100000)
+	p.addRows(1000, 10000)
+	// the average rates until there's a sample
+	status := p.status(now)
+	c.Assert(status.CurrentRowsPerSecond, Equals, float64(10))
+	c.Assert(status.ETA, Equals, 900*time.Second)
+
+	p.addRows(1000, 10000)
+	status = p.status(now.Add(5 * time.Second))
+	c.Assert(status.CurrentRowsPerSecond, Equals, float64(200))
+	c.Assert(status.CurrentBytesPerSecond, Equals, float64(2000))
+	c.Assert(status.ETA, Equals, 40*time.Second)
+	c.Assert(status.RowsPerSecond < 20, IsTrue)
+	c.Assert(status.String(), Matches, `.* 200.0 rows/s, 2.0 KiB/s, ETA 40s`)
+
+	// the samples out of the window are dropped
+	status = p.status(now.Add(20 * time.Second))
+	c.Assert(status.CurrentRowsPerSecond, Equals, status.RowsPerSecond)
+	status = p.status(now.Add(22 * time.Second))
+	c.Assert(status.CurrentRowsPerSecond, Equals, float64(0))
+	c.Assert(status.ETA, Equals, time.Duration(-1))
+}
+
+func (s *testProgressSuite) TestJobProgress(c *C) {
+	var nilController *JobController
+	c.Assert(nilController.Progress().ETA, Equals, time.Duration(-1))
+
+	ctrl := NewJobController()
+	c.Assert(ctrl.Progress().Percent, Equals, float64(-1))
+	p := NewProgress()
+	p.addEstimate(10, 100)
+	p.addRows(5, 50)
+	ctrl.track(p)
+	c.Assert(ctrl.Progress().FinishedRows, Equals, uint64(5))
+	c.Assert(ctrl.Progress().EstimatedBytes, Equals, uint64(100))
+}
+
+func (s *testProgressSuite) TestTableStates(c *C) {
+	p := NewProgress()
+	p.addTable("test", "t1")
+	p.addTable("test", "t2")
+	p.addTable("test", "t3")
+	p.startTable("test", "t2")
+	p.startTable("test", "t3")
+	p.finishTable("test", "t3")
+	p.recordError(errors.New("mock error"))
+
+	c.Assert(p.TableStates(), DeepEquals, map[TableState][]string{
+		TableStatePending: {"`test`.`t1`"},
+		TableStateRunning: {"`test`.`t2`"},
+		TableStateDone:    {"`test`.`t3`"},
+	})
+	c.Assert(p.Status().TotalTables, Equals, uint64(3))
+	c.Assert(p.Status().FinishedTables, Equals, uint64(1))
+	c.Assert(p.LastError(), ErrorMatches, "mock error")
+}
+
+func (s *testProgressSuite) TestProgressTableData(c *C) {
+	data := [][]driver.Value{
+		{"1", "male"},
+		{"2", "female"},
+	}
+	colTypes := []string{"INT", "SET"}
+	tableIR := newMockTableIR("test", "employee", data, nil, colTypes)
+	p := NewProgress()
+
+	err := WriteInsert(context.Background(), withProgress(tableIR, p), &bytes.Buffer{}, UnspecifiedSize, nil)
+	c.Assert(err, IsNil)
+	status := p.Status()
+	c.Assert(status.FinishedRows, Equals, uint64(2))
+	c.Assert(status.FinishedBytes, Equals, uint64(len("1male2female")))
+}
+
+func (s *testProgressSuite) TestEstimateProgress(c *C) {
+	db, mock, err := sqlmock.New()
+	c.Assert(err, IsNil)
+	defer db.Close()
+
+	rows := sqlmock.NewRows([]string{"TABLE_NAME", "TABLE_ROWS", "DATA_LENGTH"}).
+		AddRow("t1", 10, 1024).
+		AddRow("t2", 20, 2048).
+		AddRow("v", nil, nil)
+	mock.ExpectQuery("SELECT TABLE_NAME, TABLE_ROWS, DATA_LENGTH").WithArgs("test").WillReturnRows(rows)
+
+	p := NewProgress()
+	tables := NewDatabaseTables().AppendTables("test", "t1").AppendViews("test", "v")
+	c.Assert(estimateProgress(db, p, tables), IsNil)
+	status := p.Status()
+	c.Assert(status.TotalTables, Equals, uint64(1))
+	c.Assert(status.EstimatedRows, Equals, uint64(10))
+	c.Assert(status.EstimatedBytes, Equals, uint64(1024))
+	c.Assert(mock.ExpectationsWereMet(), IsNil)
+}
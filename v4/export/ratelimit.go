@@ -1,5 +1,12 @@
 package export
 
+import (
+	"context"
+	"io"
+	"sync"
+	"time"
+)
+
 type rateLimit struct {
 	token chan struct{}
 }
@@ -21,3 +28,116 @@ func (r *rateLimit) putToken() {
 		panic("put a redundant token")
 	}
 }
+
+// throughputLimiter limits the throughput to a number of units per second.
+// All the methods are safe to be called concurrently, or on a nil *throughputLimiter.
+type throughputLimiter struct {
+	mu        sync.Mutex
+	perSecond uint64
+	next      time.Time
+}
+
+// newThroughputLimiter returns nil if perSecond is UnspecifiedSize.
+func newThroughputLimiter(perSecond uint64) *throughputLimiter {
+	if perSecond == UnspecifiedSize {
+		return nil
+	}
+	return &throughputLimiter{perSecond: perSecond}
+}
+
+// cost returns the time taken by n units. It's not the multiple of the time of
+// one unit, which is truncated to zero for more than a billion units per second.
+func (l *throughputLimiter) cost(n uint64) time.Duration {
+	return time.Duration(float64(n) / float64(l.perSecond) * float64(time.Second))
+}
+
+// wait blocks until the previously taken units are paid off, then takes n units.
+func (l *throughputLimiter) wait(ctx context.Context, n uint64) error {
+	if l == nil || n == 0 {
+		return nil
+	}
+	l.mu.Lock()
+	now := time.Now()
+	if l.next.Before(now) {
+		l.next = now
+	}
+	delay := l.next.Sub(now)
+	l.next = l.next.Add(l.cost(n))
+	l.mu.Unlock()
+
+	if delay <= 0 {
+		return nil
+	}
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// throttledWriter limits the bytes per second written to the underlying writer.
+type throttledWriter struct {
+	io.Writer
+	ctx     context.Context
+	limiter *throughputLimiter
+}
+
+func newThrottledWriter(ctx context.Context, w io.Writer, limiter *throughputLimiter) io.Writer {
+	if limiter == nil {
+		return w
+	}
+	return &throttledWriter{Writer: w, ctx: ctx, limiter: limiter}
+}
+
+func (w *throttledWriter) Write(p []byte) (int, error) {
+	if err := w.limiter.wait(w.ctx, uint64(len(p))); err != nil {
+		return 0, err
+	}
+	return w.Writer.Write(p)
+}
+
+// throttledTableData limits the rows per second decoded from a TableDataIR.
+type throttledTableData struct {
+	TableDataIR
+	ctx     context.Context
+	limiter *throughputLimiter
+}
+
+func withRowsThrottle(ctx context.Context, ir TableDataIR, limiter *throughputLimiter) TableDataIR {
+	if limiter == nil {
+		return ir
+	}
+	return &throttledTableData{TableDataIR: ir, ctx: ctx, limiter: limiter}
+}
+
+func (td *throttledTableData) Rows() SQLRowIter {
+	return &throttledRowIter{
+		SQLRowIter: td.TableDataIR.Rows(),
+		ctx:        td.ctx,
+		limiter:    td.limiter,
+	}
+}
+
+type throttledRowIter struct {
+	SQLRowIter
+	ctx     context.Context
+	limiter *throughputLimiter
+}
+
+func (iter *throttledRowIter) Decode(row RowReceiver) error {
+	if err := iter.limiter.wait(iter.ctx, 1); err != nil {
+		return err
+	}
+	return iter.SQLRowIter.Decode(row)
+}
+
+func (iter *throttledRowIter) NextSQLRowIter() SQLRowIter {
+	return &throttledRowIter{
+		SQLRowIter: iter.SQLRowIter.NextSQLRowIter(),
+		ctx:        iter.ctx,
+		limiter:    iter.limiter,
+	}
+}
@@ -0,0 +1,56 @@
+package export
+
+import (
+	"bytes"
+	"context"
+	"database/sql/driver"
+	"time"
+
+	. "github.com/pingcap/check"
+)
+
+var _ = Suite(&testRateLimitSuite{})
+
+type testRateLimitSuite struct{}
+
+func (s *testRateLimitSuite) TestThroughputLimiter(c *C) {
+	c.Assert(newThroughputLimiter(UnspecifiedSize), IsNil)
+	var nilLimiter *throughputLimiter
+	c.Assert(nilLimiter.wait(context.Background(), 100), IsNil)
+
+	ctx := context.Background()
+	limiter := newThroughputLimiter(1000)
+	start := time.Now()
+	// the first call is free, the second one pays for the first 100 units
+	c.Assert(limiter.wait(ctx, 100), IsNil)
+	c.Assert(limiter.wait(ctx, 100), IsNil)
+	c.Assert(time.Since(start) >= 100*time.Millisecond, IsTrue)
+
+	cancelCtx, cancel := context.WithCancel(ctx)
+	cancel()
+	c.Assert(limiter.wait(cancelCtx, 100), Equals, context.Canceled)
+
+	// the rates not dividing a second, or above a unit per nanosecond, aren't truncated
+	c.Assert(newThroughputLimiter(3).cost(3), Equals, time.Second)
+	c.Assert(newThroughputLimiter(3).cost(1), Equals, time.Second/3)
+	c.Assert(newThroughputLimiter(1500).cost(1500), Equals, time.Second)
+	c.Assert(newThroughputLimiter(4<<30).cost(2<<30), Equals, time.Second/2)
+}
+
+func (s *testRateLimitSuite) TestThrottledWriteInsert(c *C) {
+	data := [][]driver.Value{
+		{"1", "male"},
+		{"2", "female"},
+		{"3", "male"},
+	}
+	colTypes := []string{"INT", "SET"}
+	ctx := context.Background()
+	tableIR := withRowsThrottle(ctx, newMockTableIR("test", "employee", data, nil, colTypes), newThroughputLimiter(40))
+	bf := &bytes.Buffer{}
+
+	start := time.Now()
+	err := WriteInsert(ctx, tableIR, newThrottledWriter(ctx, bf, newThroughputLimiter(1<<20)), UnspecifiedSize, nil)
+	c.Assert(err, IsNil)
+	c.Assert(time.Since(start) >= 50*time.Millisecond, IsTrue)
+	c.Assert(bf.String(), Equals, "INSERT INTO `employee` VALUES\n(1,'male'),\n(2,'female'),\n(3,'male');\n")
+}
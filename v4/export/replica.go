@@ -0,0 +1,106 @@
+package export
+
+import (
+	"context"
+	"database/sql"
+	"strconv"
+	"time"
+
+	"github.com/pingcap/errors"
+	"go.uber.org/zap"
+
+	"github.com/pingcap/dumpling/v4/log"
+)
+
+// replicaStatus is the position of the source applied by a replica.
+type replicaStatus struct {
+	masterHost string
+	logFile    string
+	pos        string
+	gtidSet    string
+	// stopped is whether the SQL thread was running and stopped by dumpling,
+	// in which case it should be started again after the dump.
+	stopped bool
+}
+
+// stopReplicaSQLThread stops the SQL thread of the replica, so that the data
+// doesn't change during the dump, and returns the position it stopped at.
+// The SQL thread is left as it is if it's not running.
+func stopReplicaSQLThread(db *sql.DB) (*replicaStatus, error) {
+	status, err := ShowSlaveStatus(db)
+	if err != nil {
+		return nil, err
+	}
+	if status == nil {
+		return nil, errors.New("can't stop the replica SQL thread since the server is not a replica")
+	}
+	running := status["Slave_SQL_Running"] == "Yes"
+	if running {
+		if err = StopSlaveSQLThread(db); err != nil {
+			return nil, err
+		}
+		// query again for the position after stopped
+		if status, err = ShowSlaveStatus(db); err == nil && status == nil {
+			err = errors.New("can't get the position of the stopped replica SQL thread")
+		}
+		if err != nil {
+			if startErr := StartSlaveSQLThread(db); startErr != nil {
+				log.Error("start replica SQL thread failed, please start it manually", zap.Error(startErr))
+			}
+			return nil, err
+		}
+	} else {
+		log.Warn("replica SQL thread is not running, so it's not stopped nor started by dumpling")
+	}
+	replica := &replicaStatus{
+		masterHost: status["Master_Host"],
+		logFile:    status["Relay_Master_Log_File"],
+		pos:        status["Exec_Master_Log_Pos"],
+		gtidSet:    status["Executed_Gtid_Set"],
+		stopped:    running,
+	}
+	log.Info("stopped replica SQL thread",
+		zap.String("master host", replica.masterHost),
+		zap.String("log", replica.logFile),
+		zap.String("pos", replica.pos),
+		zap.String("gtid", replica.gtidSet))
+	return replica, nil
+}
+
+const defaultReplicaLagCheckInterval = 5 * time.Second
+
+// checkReplicaLag waits until Seconds_Behind_Master of the replica is at most
+// maxLag seconds, and fails if it's still behind after waiting for wait. The
+// lag is unknown if replication is not running, which is treated as behind.
+// It does nothing if the server is not a replica.
+func checkReplicaLag(ctx context.Context, db *sql.DB, maxLag uint64, wait, interval time.Duration) error {
+	deadline := time.Now().Add(wait)
+	for {
+		status, err := ShowSlaveStatus(db)
+		if err != nil {
+			return err
+		}
+		if status == nil {
+			log.Warn("skip checking replica lag since the server is not a replica")
+			return nil
+		}
+		lagStr := status["Seconds_Behind_Master"]
+		lag, err := strconv.ParseUint(lagStr, 10, 64)
+		if err == nil && lag <= maxLag {
+			log.Info("replica lag is acceptable", zap.Uint64("lag", lag), zap.Uint64("max lag", maxLag))
+			return nil
+		}
+		if lagStr == "" {
+			lagStr = "unknown"
+		}
+		if !time.Now().Before(deadline) {
+			return errors.Errorf("replica lag %s is greater than max replica lag %d seconds", lagStr, maxLag)
+		}
+		log.Warn("wait for replica to catch up", zap.String("lag", lagStr), zap.Uint64("max lag", maxLag))
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(interval):
+		}
+	}
+}
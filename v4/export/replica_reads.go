@@ -0,0 +1,136 @@
+package export
+
+import (
+	"context"
+	"database/sql"
+	"net/url"
+	"sync/atomic"
+	"time"
+
+	"github.com/pingcap/errors"
+	"go.uber.org/zap"
+
+	"github.com/pingcap/dumpling/v4/log"
+)
+
+// replicaPools are the connection pools of Config.ReadReplicas. The chunks of
+// the tables are read from the source and the replicas in turns.
+type replicaPools struct {
+	pools []*sql.DB
+	next  uint64
+}
+
+// openReadReplicas connects to conf.ReadReplicas, or returns nil if there
+// isn't any. If snapshot isn't empty, the replicas read at the snapshot too,
+// so that the chunks read from them are consistent with the source.
+func openReadReplicas(ctx context.Context, conf *Config, snapshot string) (r *replicaPools, err error) {
+	if len(conf.ReadReplicas) == 0 {
+		return nil, nil
+	}
+	// auto is resolved after Validate, the replicas aren't locked by flush or lock
+	if !replicaConsistency(conf.Consistency) {
+		return nil, withKind(ErrorKindConfig, errors.Errorf("read-replicas is not supported with the consistency %s, which doesn't apply to the replicas", conf.Consistency))
+	}
+	r = &replicaPools{}
+	defer func() {
+		if err != nil {
+			_ = r.Close()
+		}
+	}()
+	for _, dsn := range conf.ReadReplicas {
+		source, err := parseSourceAddr(conf, dsn)
+		if err != nil {
+			return nil, withKind(ErrorKindConfig, err)
+		}
+		pool, _, err := openConnPool(conf, replicaDSN(conf.forSource(source), snapshot))
+		if err != nil {
+			return nil, withStack(withKind(ErrorKindConnection, err))
+		}
+		r.pools = append(r.pools, pool)
+		setConnIdleTimeout(pool, conf.ConnIdleTimeout)
+		if err = pool.PingContext(ctx); err != nil {
+			return nil, withStack(withKind(ErrorKindConnection, errors.WithMessage(err, "connect to replica "+source.String())))
+		}
+		if conf.MaxReplicaLag != UnspecifiedSize {
+			wait := time.Duration(conf.ReplicaLagWait) * time.Second
+			if err = checkReplicaLag(ctx, pool, conf.MaxReplicaLag, wait, defaultReplicaLagCheckInterval); err != nil {
+				return nil, withKind(ErrorKindConsistency, errors.WithMessage(err, "replica "+source.String()))
+			}
+		}
+		log.Info("read the chunks from replica", zap.String("replica", source.String()))
+	}
+	return r, nil
+}
+
+// replicaDSN returns the DSN of the replica of conf, whose sessions read at
+// snapshot if it isn't empty.
+func replicaDSN(conf *Config, snapshot string) string {
+	dsn := conf.dialect().DSN(conf)
+	// the tables are read as of the snapshot by stale read then
+	if snapshot != "" && conf.staleReadSnapshot == "" {
+		// the driver sets the unknown parameters as the session variables
+		dsn += "&tidb_snapshot=" + url.QueryEscape("'"+snapshot+"'")
+	}
+	return dsn
+}
+
+// consistencySnapshot returns the snapshot read by the sessions of conCtrl,
+// or empty if it doesn't read at a snapshot.
+// replicaConsistency returns whether the chunks read from the replicas are as
+// consistent as the chunks read from the source with the consistency.
+func replicaConsistency(consistency string) bool {
+	return consistency == "snapshot" || consistency == "none"
+}
+
+func consistencySnapshot(conCtrl ConsistencyController) string {
+	if c, ok := conCtrl.(*ConsistencySnapshot); ok {
+		return c.snapshot
+	}
+	return ""
+}
+
+// pick returns the pool to read the next chunk from, db is the pool of the
+// source, which is returned if there aren't any replicas.
+func (r *replicaPools) pick(db *sql.DB) *sql.DB {
+	if r == nil {
+		return db
+	}
+	i := atomic.AddUint64(&r.next, 1) % uint64(len(r.pools)+1)
+	if i == 0 {
+		return db
+	}
+	return r.pools[i-1]
+}
+
+func (r *replicaPools) Close() error {
+	if r == nil {
+		return nil
+	}
+	var err error
+	for _, pool := range r.pools {
+		if closeErr := pool.Close(); err == nil {
+			err = closeErr
+		}
+	}
+	return err
+}
+
+// readReplicasConflicts returns the options conflicting with ReadReplicas.
+func readReplicasConflicts(conf *Config) []string {
+	var conflicts []string
+	for _, dsn := range conf.ReadReplicas {
+		if _, err := parseSourceAddr(conf, dsn); err != nil {
+			conflicts = append(conflicts, err.Error())
+		}
+	}
+	if len(conf.Shards) > 0 {
+		conflicts = append(conflicts, "read-replicas is not supported with shards")
+	}
+	if conf.Consistency != "auto" && !replicaConsistency(conf.Consistency) {
+		conflicts = append(conflicts, "read-replicas is only supported with the consistency snapshot or none")
+	}
+	if conf.SourceDialect == DialectPostgres {
+		conflicts = append(conflicts, "read-replicas is not supported by the postgres source dialect")
+	}
+	return conflicts
+}
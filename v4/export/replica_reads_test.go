@@ -0,0 +1,90 @@
+package export
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	. "github.com/pingcap/check"
+)
+
+var _ = Suite(&testReplicaReadsSuite{})
+
+type testReplicaReadsSuite struct{}
+
+func (s *testReplicaReadsSuite) TestPick(c *C) {
+	source, _, err := sqlmock.New()
+	c.Assert(err, IsNil)
+	defer source.Close()
+	var r *replicaPools
+	c.Assert(r.pick(source), Equals, source)
+	c.Assert(r.Close(), IsNil)
+
+	r = &replicaPools{}
+	for i := 0; i < 2; i++ {
+		db, mock, err := sqlmock.New()
+		c.Assert(err, IsNil)
+		mock.ExpectClose()
+		r.pools = append(r.pools, db)
+	}
+	var picked []*sql.DB
+	for i := 0; i < 6; i++ {
+		picked = append(picked, r.pick(source))
+	}
+	c.Assert(picked, DeepEquals, []*sql.DB{r.pools[0], r.pools[1], source, r.pools[0], r.pools[1], source})
+	c.Assert(r.Close(), IsNil)
+}
+
+func (s *testReplicaReadsSuite) TestReplicaDSN(c *C) {
+	conf := DefaultConfig()
+	source, err := parseSourceAddr(conf, "dump:secret@10.0.1.2:4000")
+	c.Assert(err, IsNil)
+	replicaConf := conf.forSource(source)
+	c.Assert(replicaDSN(replicaConf, ""), Equals, "dump:secret@tcp(10.0.1.2:4000)/?charset=utf8mb4")
+	c.Assert(replicaDSN(replicaConf, "417773951312461825"), Equals,
+		"dump:secret@tcp(10.0.1.2:4000)/?charset=utf8mb4&tidb_snapshot=%27417773951312461825%27")
+}
+
+func (s *testReplicaReadsSuite) TestConsistencySnapshot(c *C) {
+	c.Assert(consistencySnapshot(&ConsistencySnapshot{snapshot: "417773951312461825"}), Equals, "417773951312461825")
+	c.Assert(consistencySnapshot(&ConsistencyNone{}), Equals, "")
+}
+
+func (s *testReplicaReadsSuite) TestOpenWithoutReplicas(c *C) {
+	r, err := openReadReplicas(context.Background(), DefaultConfig(), "")
+	c.Assert(err, IsNil)
+	c.Assert(r, IsNil)
+
+	// the replicas aren't locked by the consistency resolved from auto
+	conf := DefaultConfig()
+	conf.ReadReplicas = []string{"10.0.1.2"}
+	conf.Consistency = "flush"
+	_, err = openReadReplicas(context.Background(), conf, "")
+	c.Assert(ErrorKindOf(err), Equals, ErrorKindConfig)
+}
+
+func (s *testReplicaReadsSuite) TestValidate(c *C) {
+	conf := DefaultConfig()
+	conf.ReadReplicas = []string{"10.0.1.2", "10.0.1.3:3306"}
+	c.Assert(conf.Validate(), IsNil)
+
+	conf.ReadReplicas = append(conf.ReadReplicas, "root@")
+	conf.Shards = []string{"10.0.1.1"}
+	err := conf.Validate()
+	c.Assert(err, ErrorMatches, ".*invalid source root@.*")
+	c.Assert(err, ErrorMatches, ".*read-replicas is not supported with shards.*")
+
+	conf = DefaultConfig()
+	conf.ReadReplicas = []string{"10.0.1.2"}
+	conf.SourceDialect = DialectPostgres
+	c.Assert(conf.Validate(), ErrorMatches, ".*read-replicas is not supported by the postgres source dialect.*")
+
+	for _, consistency := range []string{"flush", "lock"} {
+		conf = DefaultConfig()
+		conf.ReadReplicas = []string{"10.0.1.2"}
+		conf.Consistency = consistency
+		c.Assert(conf.Validate(), ErrorMatches, ".*read-replicas is only supported with the consistency snapshot or none.*")
+	}
+	conf.Consistency = "none"
+	c.Assert(conf.Validate(), IsNil)
+}
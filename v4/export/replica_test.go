@@ -0,0 +1,132 @@
+package export
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	. "github.com/pingcap/check"
+)
+
+var _ = Suite(&testReplicaSuite{})
+
+type testReplicaSuite struct{}
+
+var slaveStatusColumns = []string{"Slave_IO_State", "Master_Host", "Relay_Master_Log_File", "Exec_Master_Log_Pos", "Seconds_Behind_Master", "Executed_Gtid_Set"}
+
+var sqlThreadStatusColumns = []string{"Master_Host", "Relay_Master_Log_File", "Exec_Master_Log_Pos", "Executed_Gtid_Set", "Slave_SQL_Running"}
+
+func (s *testReplicaSuite) TestStopReplicaSQLThread(c *C) {
+	db, mock, err := sqlmock.New()
+	c.Assert(err, IsNil)
+	defer db.Close()
+
+	mock.ExpectQuery("SHOW SLAVE STATUS").WillReturnRows(sqlmock.NewRows(sqlThreadStatusColumns).
+		AddRow("10.0.0.1", "mysql-bin.000003", "1200", "uuid:1-10", "Yes"))
+	mock.ExpectExec("STOP SLAVE SQL_THREAD").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery("SHOW SLAVE STATUS").WillReturnRows(sqlmock.NewRows(sqlThreadStatusColumns).
+		AddRow("10.0.0.1", "mysql-bin.000003", "1234", "uuid:1-11", "No"))
+
+	replica, err := stopReplicaSQLThread(db)
+	c.Assert(err, IsNil)
+	c.Assert(replica, DeepEquals, &replicaStatus{
+		masterHost: "10.0.0.1",
+		logFile:    "mysql-bin.000003",
+		pos:        "1234",
+		gtidSet:    "uuid:1-11",
+		stopped:    true,
+	})
+	c.Assert(mock.ExpectationsWereMet(), IsNil)
+
+	m := newGlobalMetadata(newMemStorage())
+	m.recordStartTime(time.Date(2020, 5, 1, 10, 0, 0, 0, time.UTC))
+	m.recordReplicaStatus(replica)
+	c.Assert(m.String(), Equals, "Started dump at: 2020-05-01 10:00:00\n"+
+		"SHOW MASTER STATUS:\n"+
+		"SHOW SLAVE STATUS:\n"+
+		"\t\tHost: 10.0.0.1\n"+
+		"\t\tLog: mysql-bin.000003\n"+
+		"\t\tPos: 1234\n"+
+		"\t\tGTID:uuid:1-11\n")
+}
+
+func (s *testReplicaSuite) TestStopReplicaSQLThreadNotRunning(c *C) {
+	db, mock, err := sqlmock.New()
+	c.Assert(err, IsNil)
+	defer db.Close()
+
+	// the stopped SQL thread isn't stopped again, nor started after the dump
+	mock.ExpectQuery("SHOW SLAVE STATUS").WillReturnRows(sqlmock.NewRows(sqlThreadStatusColumns).
+		AddRow("10.0.0.1", "mysql-bin.000003", "1200", "uuid:1-10", "No"))
+	replica, err := stopReplicaSQLThread(db)
+	c.Assert(err, IsNil)
+	c.Assert(replica, DeepEquals, &replicaStatus{
+		masterHost: "10.0.0.1",
+		logFile:    "mysql-bin.000003",
+		pos:        "1200",
+		gtidSet:    "uuid:1-10",
+	})
+	c.Assert(mock.ExpectationsWereMet(), IsNil)
+}
+
+func (s *testReplicaSuite) TestStopReplicaSQLThreadRestartOnError(c *C) {
+	db, mock, err := sqlmock.New()
+	c.Assert(err, IsNil)
+	defer db.Close()
+
+	mock.ExpectQuery("SHOW SLAVE STATUS").WillReturnRows(sqlmock.NewRows(sqlThreadStatusColumns).
+		AddRow("10.0.0.1", "mysql-bin.000003", "1200", "uuid:1-10", "Yes"))
+	mock.ExpectExec("STOP SLAVE SQL_THREAD").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery("SHOW SLAVE STATUS").WillReturnError(errors.New("connection lost"))
+	mock.ExpectExec("START SLAVE SQL_THREAD").WillReturnResult(sqlmock.NewResult(0, 0))
+	_, err = stopReplicaSQLThread(db)
+	c.Assert(err, ErrorMatches, "(?s).*connection lost.*")
+	c.Assert(mock.ExpectationsWereMet(), IsNil)
+}
+
+func (s *testReplicaSuite) TestStopReplicaSQLThreadOnSource(c *C) {
+	db, mock, err := sqlmock.New()
+	c.Assert(err, IsNil)
+	defer db.Close()
+
+	mock.ExpectQuery("SHOW SLAVE STATUS").WillReturnRows(sqlmock.NewRows(slaveStatusColumns))
+	_, err = stopReplicaSQLThread(db)
+	c.Assert(err, ErrorMatches, ".*the server is not a replica")
+	c.Assert(mock.ExpectationsWereMet(), IsNil)
+}
+
+func (s *testReplicaSuite) TestCheckReplicaLag(c *C) {
+	db, mock, err := sqlmock.New()
+	c.Assert(err, IsNil)
+	defer db.Close()
+	ctx := context.Background()
+
+	mock.ExpectQuery("SHOW SLAVE STATUS").WillReturnRows(sqlmock.NewRows(slaveStatusColumns).
+		AddRow("", "10.0.0.1", "mysql-bin.000003", "1200", "30", ""))
+	c.Assert(checkReplicaLag(ctx, db, 60, 0, time.Millisecond), IsNil)
+
+	// abort without waiting
+	mock.ExpectQuery("SHOW SLAVE STATUS").WillReturnRows(sqlmock.NewRows(slaveStatusColumns).
+		AddRow("", "10.0.0.1", "mysql-bin.000003", "1200", "7200", ""))
+	err = checkReplicaLag(ctx, db, 60, 0, time.Millisecond)
+	c.Assert(err, ErrorMatches, "replica lag 7200 is greater than max replica lag 60 seconds")
+
+	// wait until the replica catches up
+	mock.ExpectQuery("SHOW SLAVE STATUS").WillReturnRows(sqlmock.NewRows(slaveStatusColumns).
+		AddRow("", "10.0.0.1", "mysql-bin.000003", "1200", nil, ""))
+	mock.ExpectQuery("SHOW SLAVE STATUS").WillReturnRows(sqlmock.NewRows(slaveStatusColumns).
+		AddRow("", "10.0.0.1", "mysql-bin.000003", "1200", "0", ""))
+	c.Assert(checkReplicaLag(ctx, db, 60, time.Hour, time.Millisecond), IsNil)
+
+	// replication is not running
+	mock.ExpectQuery("SHOW SLAVE STATUS").WillReturnRows(sqlmock.NewRows(slaveStatusColumns).
+		AddRow("", "10.0.0.1", "mysql-bin.000003", "1200", nil, ""))
+	err = checkReplicaLag(ctx, db, 60, 0, time.Millisecond)
+	c.Assert(err, ErrorMatches, "replica lag unknown is greater than max replica lag 60 seconds")
+
+	// not a replica
+	mock.ExpectQuery("SHOW SLAVE STATUS").WillReturnRows(sqlmock.NewRows(slaveStatusColumns))
+	c.Assert(checkReplicaLag(ctx, db, 60, 0, time.Millisecond), IsNil)
+	c.Assert(mock.ExpectationsWereMet(), IsNil)
+}
@@ -0,0 +1,257 @@
+package export
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/pingcap/errors"
+	"go.uber.org/zap"
+
+	"github.com/pingcap/dumpling/v4/log"
+)
+
+const (
+	// ReplicationMetaDM writes the position of the dump as the meta of a DM
+	// incremental task.
+	ReplicationMetaDM = "dm"
+	// ReplicationMetaTiCDC writes the snapshot of the dump as the start-ts of
+	// a TiCDC changefeed.
+	ReplicationMetaTiCDC = "ticdc"
+
+	dmMetaFile          = "dm-meta.yaml"
+	ticdcChangefeedFile = "ticdc-changefeed.json"
+)
+
+// replicationConflicts returns the invalid replication options and the
+// options conflicting with them.
+func replicationConflicts(conf *Config) []string {
+	if conf.ReplicationMeta == "" {
+		if conf.ReplicationAPI != "" {
+			return []string{"replication-api needs replication-meta"}
+		}
+		return nil
+	}
+	var conflicts []string
+	switch conf.ReplicationMeta {
+	case ReplicationMetaDM:
+		if conf.ReplicationAPI != "" && (conf.ReplicationTask == "" || conf.ReplicationSource == "") {
+			conflicts = append(conflicts, "replication-api of dm needs replication-task and replication-source")
+		}
+	case ReplicationMetaTiCDC:
+		if conf.ReplicationAPI != "" && (conf.ReplicationTask == "" || conf.ReplicationSinkURI == "") {
+			conflicts = append(conflicts, "replication-api of ticdc needs replication-task and replication-sink-uri")
+		}
+	default:
+		conflicts = append(conflicts, fmt.Sprintf("replication-meta should be dm or ticdc, got %s", conf.ReplicationMeta))
+	}
+	if conf.Consistency == "none" {
+		conflicts = append(conflicts, "replication-meta needs the consistency to replicate from the position of the dump")
+	}
+	if len(conf.Shards) > 0 {
+		conflicts = append(conflicts, "replication-meta is not supported with shards")
+	}
+	return conflicts
+}
+
+// checkReplicationSource checks that the source can be replicated by the
+// system of ReplicationMeta.
+func checkReplicationSource(conf *Config) error {
+	serverType := conf.ServerInfo.ServerType
+	switch {
+	case conf.ReplicationMeta == ReplicationMetaDM && (serverType == ServerTypeMySQL || serverType == ServerTypeMariaDB):
+	case conf.ReplicationMeta == ReplicationMetaTiCDC && serverType == ServerTypeTiDB:
+	default:
+		return withKind(ErrorKindConfig, errors.Errorf("replication-meta %s is not supported with the %s sources", conf.ReplicationMeta, serverType))
+	}
+	return nil
+}
+
+// dmMeta is the `meta` of an instance of a DM task, the position the
+// incremental replication starts from.
+type dmMeta struct {
+	BinlogName string
+	BinlogPos  uint64
+	BinlogGTID string
+}
+
+// newDMMeta returns the position the dump is consistent as of, which is
+// the stop position if the binlog is tailed. The GTID set isn't known then.
+func newDMMeta(m *globalMetadata) (dmMeta, error) {
+	if m.binlogTail != nil {
+		return dmMeta{BinlogName: m.binlogTail.file, BinlogPos: m.binlogTail.pos}, nil
+	}
+	if m.logFile == "" {
+		return dmMeta{}, withKind(ErrorKindConsistency, errors.New("the binlog position of the dump is unknown"))
+	}
+	pos, err := strconv.ParseUint(m.pos, 10, 64)
+	if err != nil {
+		return dmMeta{}, withKind(ErrorKindConsistency, errors.Errorf("invalid binlog position %s of the dump", m.pos))
+	}
+	return dmMeta{BinlogName: m.logFile, BinlogPos: pos, BinlogGTID: m.gtidSet}, nil
+}
+
+// yaml returns the meta as the `mysql-instances` of a DM task config.
+func (meta dmMeta) yaml(source string) string {
+	var b strings.Builder
+	b.WriteString("mysql-instances:\n")
+	if source != "" {
+		fmt.Fprintf(&b, "  - source-id: %s\n    meta:\n", strconv.Quote(source))
+	} else {
+		b.WriteString("  - meta:\n")
+	}
+	fmt.Fprintf(&b, "      binlog-name: %s\n", strconv.Quote(meta.BinlogName))
+	fmt.Fprintf(&b, "      binlog-pos: %d\n", meta.BinlogPos)
+	if meta.BinlogGTID != "" {
+		fmt.Fprintf(&b, "      binlog-gtid: %s\n", strconv.Quote(meta.BinlogGTID))
+	}
+	return b.String()
+}
+
+// ticdcChangefeed is the config of a TiCDC changefeed created by the OpenAPI,
+// which replicates the changes committed after the snapshot of the dump.
+type ticdcChangefeed struct {
+	ChangefeedID string `json:"changefeed_id,omitempty"`
+	StartTS      uint64 `json:"start_ts"`
+	SinkURI      string `json:"sink_uri,omitempty"`
+}
+
+// newTiCDCChangefeed returns the changefeed starting from snapshot, or the
+// position of the metadata if the dump isn't read from a snapshot.
+func newTiCDCChangefeed(conf *Config, m *globalMetadata, snapshot string) (ticdcChangefeed, error) {
+	if snapshot == "" {
+		snapshot = m.pos
+	}
+	if snapshot == "" {
+		return ticdcChangefeed{}, withKind(ErrorKindConsistency, errors.New("the snapshot of the dump is unknown"))
+	}
+	startTS, err := strconv.ParseUint(snapshot, 10, 64)
+	if err != nil || startTS == 0 {
+		return ticdcChangefeed{}, withKind(ErrorKindConsistency, errors.Errorf("invalid snapshot %s of the dump", snapshot))
+	}
+	return ticdcChangefeed{ChangefeedID: conf.ReplicationTask, StartTS: startTS, SinkURI: conf.ReplicationSinkURI}, nil
+}
+
+// writeReplicationMeta writes the position of the dump in the format of
+// ReplicationMeta into the output, and registers it by ReplicationAPI if
+// it's set. snapshot is the TiDB snapshot the data are read from.
+func writeReplicationMeta(ctx context.Context, conf *Config, m *globalMetadata, snapshot string) error {
+	var (
+		fileName string
+		content  string
+		register func() error
+	)
+	switch conf.ReplicationMeta {
+	case ReplicationMetaDM:
+		meta, err := newDMMeta(m)
+		if err != nil {
+			return err
+		}
+		fileName, content = dmMetaFile, meta.yaml(conf.ReplicationSource)
+		register = func() error { return registerDMMeta(conf, meta) }
+	case ReplicationMetaTiCDC:
+		changefeed, err := newTiCDCChangefeed(conf, m, snapshot)
+		if err != nil {
+			return err
+		}
+		body, err := json.MarshalIndent(changefeed, "", "  ")
+		if err != nil {
+			return errors.Trace(err)
+		}
+		fileName, content = ticdcChangefeedFile, string(body)+"\n"
+		register = func() error { return registerTiCDCChangefeed(conf, changefeed) }
+	default:
+		return nil
+	}
+	fileWriter, err := conf.ExternalStorage.Create(ctx, fileName)
+	if err != nil {
+		return err
+	}
+	if err = closeFile(fileWriter, write(fileWriter, content)); err != nil {
+		return err
+	}
+	conf.hooks().OnFileClosed(conf.outputPath(ctx, fileName))
+	if conf.ReplicationAPI == "" {
+		return nil
+	}
+	if err = register(); err != nil {
+		return withKind(ErrorKindWrite, errors.Annotatef(err, "register the position of the dump by %s, it's written in %s", conf.ReplicationAPI, fileName))
+	}
+	log.Info("register the position of the dump successfully", zap.String("api", conf.ReplicationAPI), zap.String("task", conf.ReplicationTask))
+	return nil
+}
+
+// registerDMMeta sets the meta of ReplicationSource in the DM task
+// ReplicationTask, by getting and updating the task by the OpenAPI of the
+// DM master. The task should be created without starting it.
+func registerDMMeta(conf *Config, meta dmMeta) error {
+	url := strings.TrimSuffix(conf.ReplicationAPI, "/") + "/api/v1/tasks/" + conf.ReplicationTask
+	var task map[string]interface{}
+	if err := doReplicationRequest(http.MethodGet, url, nil, &task); err != nil {
+		return err
+	}
+	// the other fields of the task are kept as they are
+	sourceConfig, _ := task["source_config"].(map[string]interface{})
+	sourceConfs, _ := sourceConfig["source_conf"].([]interface{})
+	found := false
+	for _, sourceConf := range sourceConfs {
+		if source, ok := sourceConf.(map[string]interface{}); ok && source["source_name"] == conf.ReplicationSource {
+			source["binlog_name"] = meta.BinlogName
+			source["binlog_pos"] = meta.BinlogPos
+			source["binlog_gtid"] = meta.BinlogGTID
+			found = true
+		}
+	}
+	if !found {
+		return errors.Errorf("source %s isn't in the task %s", conf.ReplicationSource, conf.ReplicationTask)
+	}
+	return doReplicationRequest(http.MethodPut, url, map[string]interface{}{"task": task}, nil)
+}
+
+// registerTiCDCChangefeed creates the changefeed by the OpenAPI of TiCDC.
+func registerTiCDCChangefeed(conf *Config, changefeed ticdcChangefeed) error {
+	url := strings.TrimSuffix(conf.ReplicationAPI, "/") + "/api/v1/changefeeds"
+	return doReplicationRequest(http.MethodPost, url, changefeed, nil)
+}
+
+// doReplicationRequest sends the JSON of body to url, and decodes the
+// response into result if it's not nil.
+func doReplicationRequest(method, url string, body, result interface{}) error {
+	var reader io.Reader
+	if body != nil {
+		b, err := json.Marshal(body)
+		if err != nil {
+			return errors.Trace(err)
+		}
+		reader = bytes.NewReader(b)
+	}
+	req, err := http.NewRequest(method, url, reader)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	client := &http.Client{Timeout: defaultNotifyTimeout}
+	resp, err := client.Do(req)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		msg, _ := ioutil.ReadAll(io.LimitReader(resp.Body, 1024))
+		return errors.Errorf("%s %s responded %s: %s", method, url, resp.Status, bytes.TrimSpace(msg))
+	}
+	if result != nil {
+		if err = json.NewDecoder(resp.Body).Decode(result); err != nil {
+			return errors.Annotatef(err, "decode the response of %s %s", method, url)
+		}
+	}
+	return nil
+}
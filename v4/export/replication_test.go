@@ -0,0 +1,160 @@
+package export
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+
+	. "github.com/pingcap/check"
+)
+
+var _ = Suite(&testReplicationSuite{})
+
+type testReplicationSuite struct{}
+
+func (s *testReplicationSuite) TestValidate(c *C) {
+	conf := DefaultConfig()
+	conf.ReplicationMeta = ReplicationMetaDM
+	c.Assert(conf.Validate(), IsNil)
+	conf.ReplicationAPI = "http://127.0.0.1:8261"
+	c.Assert(conf.Validate(), ErrorMatches, "(?s).*replication-api of dm needs replication-task and replication-source.*")
+	conf.ReplicationTask, conf.ReplicationSource = "task", "mysql-01"
+	c.Assert(conf.Validate(), IsNil)
+
+	conf.ReplicationMeta = ReplicationMetaTiCDC
+	c.Assert(conf.Validate(), ErrorMatches, "(?s).*replication-api of ticdc needs replication-task and replication-sink-uri.*")
+	conf.ReplicationMeta = "canal"
+	c.Assert(conf.Validate(), ErrorMatches, "(?s).*replication-meta should be dm or ticdc, got canal.*")
+	conf.ReplicationMeta = ""
+	c.Assert(conf.Validate(), ErrorMatches, "(?s).*replication-api needs replication-meta.*")
+	conf.ReplicationMeta, conf.ReplicationAPI = ReplicationMetaDM, ""
+	conf.Consistency = "none"
+	c.Assert(conf.Validate(), ErrorMatches, "(?s).*replication-meta needs the consistency.*")
+
+	conf = DefaultConfig()
+	conf.ReplicationMeta = ReplicationMetaTiCDC
+	conf.ServerInfo.ServerType = ServerTypeMySQL
+	c.Assert(checkReplicationSource(conf), ErrorMatches, "replication-meta ticdc is not supported with the MySQL sources")
+	conf.ServerInfo.ServerType = ServerTypeTiDB
+	c.Assert(checkReplicationSource(conf), IsNil)
+}
+
+func (s *testReplicationSuite) TestWriteDMMeta(c *C) {
+	conf := DefaultConfig()
+	conf.ReplicationMeta = ReplicationMetaDM
+	conf.ReplicationSource = "mysql-01"
+	storage := newMemStorage()
+	conf.ExternalStorage = storage
+	m := &globalMetadata{logFile: "mysql-bin.000002", pos: "7502", gtidSet: "6ce40be3-e359-11e9-87e0-36933cb0ca5a:1-29"}
+	c.Assert(writeReplicationMeta(context.Background(), conf, m, ""), IsNil)
+	c.Assert(storage.files[dmMetaFile], Equals, `mysql-instances:
+  - source-id: "mysql-01"
+    meta:
+      binlog-name: "mysql-bin.000002"
+      binlog-pos: 7502
+      binlog-gtid: "6ce40be3-e359-11e9-87e0-36933cb0ca5a:1-29"
+`)
+
+	// the binlog is tailed to the stop position
+	m.recordBinlogTail(binlogPosition{file: "mysql-bin.000003", pos: 1024})
+	c.Assert(writeReplicationMeta(context.Background(), conf, m, ""), IsNil)
+	c.Assert(storage.files[dmMetaFile], Equals, `mysql-instances:
+  - source-id: "mysql-01"
+    meta:
+      binlog-name: "mysql-bin.000003"
+      binlog-pos: 1024
+`)
+
+	_, err := newDMMeta(&globalMetadata{})
+	c.Assert(err, ErrorMatches, "the binlog position of the dump is unknown")
+}
+
+func (s *testReplicationSuite) TestRegisterDMMeta(c *C) {
+	var updated map[string]interface{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		c.Assert(r.URL.Path, Equals, "/api/v1/tasks/task")
+		switch r.Method {
+		case http.MethodGet:
+			_, _ = w.Write([]byte(`{"name": "task", "task_mode": "incremental", "source_config": {"source_conf": [
+				{"source_name": "mysql-01"}, {"source_name": "mysql-02", "binlog_name": "mysql-bin.000001", "binlog_pos": 4}
+			]}}`))
+		case http.MethodPut:
+			body, err := ioutil.ReadAll(r.Body)
+			c.Assert(err, IsNil)
+			c.Assert(json.Unmarshal(body, &updated), IsNil)
+		default:
+			c.Fatalf("unexpected method %s", r.Method)
+		}
+	}))
+	defer server.Close()
+
+	conf := DefaultConfig()
+	conf.ReplicationMeta = ReplicationMetaDM
+	conf.ReplicationAPI = server.URL + "/"
+	conf.ReplicationTask = "task"
+	conf.ReplicationSource = "mysql-01"
+	conf.ExternalStorage = newMemStorage()
+	m := &globalMetadata{logFile: "mysql-bin.000002", pos: "7502"}
+	c.Assert(writeReplicationMeta(context.Background(), conf, m, ""), IsNil)
+	c.Assert(updated, DeepEquals, map[string]interface{}{"task": map[string]interface{}{
+		"name":      "task",
+		"task_mode": "incremental",
+		"source_config": map[string]interface{}{"source_conf": []interface{}{
+			map[string]interface{}{"source_name": "mysql-01", "binlog_name": "mysql-bin.000002", "binlog_pos": float64(7502), "binlog_gtid": ""},
+			map[string]interface{}{"source_name": "mysql-02", "binlog_name": "mysql-bin.000001", "binlog_pos": float64(4)},
+		}},
+	}})
+
+	conf.ReplicationSource = "mysql-03"
+	err := writeReplicationMeta(context.Background(), conf, m, "")
+	c.Assert(err, ErrorMatches, "register the position of the dump by .*, it's written in dm-meta.yaml: source mysql-03 isn't in the task task")
+	c.Assert(ErrorKindOf(err), Equals, ErrorKindWrite)
+}
+
+func (s *testReplicationSuite) TestRegisterTiCDCChangefeed(c *C) {
+	var created ticdcChangefeed
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		c.Assert(r.Method, Equals, http.MethodPost)
+		c.Assert(r.URL.Path, Equals, "/api/v1/changefeeds")
+		body, err := ioutil.ReadAll(r.Body)
+		c.Assert(err, IsNil)
+		c.Assert(json.Unmarshal(body, &created), IsNil)
+		if created.ChangefeedID == "exists" {
+			http.Error(w, `{"error_msg": "changefeed already exists"}`, http.StatusBadRequest)
+			return
+		}
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer server.Close()
+
+	conf := DefaultConfig()
+	conf.ReplicationMeta = ReplicationMetaTiCDC
+	conf.ReplicationTask = "app"
+	conf.ReplicationSinkURI = "mysql://root@10.0.1.2:3306/"
+	storage := newMemStorage()
+	conf.ExternalStorage = storage
+	m := &globalMetadata{logFile: "tidb-binlog", pos: "415195906970746880"}
+
+	// the snapshot the data are read from is preferred
+	c.Assert(writeReplicationMeta(context.Background(), conf, m, "415195906970746000"), IsNil)
+	c.Assert(storage.files[ticdcChangefeedFile], Equals, `{
+  "changefeed_id": "app",
+  "start_ts": 415195906970746000,
+  "sink_uri": "mysql://root@10.0.1.2:3306/"
+}
+`)
+	conf.ReplicationAPI = server.URL
+	c.Assert(writeReplicationMeta(context.Background(), conf, m, ""), IsNil)
+	c.Assert(created, Equals, ticdcChangefeed{ChangefeedID: "app", StartTS: 415195906970746880, SinkURI: "mysql://root@10.0.1.2:3306/"})
+
+	conf.ReplicationTask = "exists"
+	err := writeReplicationMeta(context.Background(), conf, m, "")
+	c.Assert(err, ErrorMatches, `.*POST .*/api/v1/changefeeds responded 400 Bad Request: {"error_msg": "changefeed already exists"}`)
+
+	_, err = newTiCDCChangefeed(conf, &globalMetadata{}, "")
+	c.Assert(err, ErrorMatches, "the snapshot of the dump is unknown")
+	_, err = newTiCDCChangefeed(conf, &globalMetadata{pos: "0/16B3748"}, "")
+	c.Assert(err, ErrorMatches, "invalid snapshot 0/16B3748 of the dump")
+}
@@ -0,0 +1,356 @@
+package export
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/pingcap/errors"
+	"go.uber.org/zap"
+
+	"github.com/pingcap/dumpling/v4/log"
+)
+
+// restorePlanFile is the name of the restore plan in the output directory.
+const restorePlanFile = "restore-plan.json"
+
+// RestorePlan lists the files of a dump in the batches they're restored in,
+// a batch is restored after all the files of the previous batches are.
+type RestorePlan struct {
+	Batches []RestoreBatch `json:"batches"`
+}
+
+// RestoreBatch is a batch of files of a RestorePlan. The files are relative
+// to the output directory unless they're written out of it.
+type RestoreBatch struct {
+	Name string `json:"name"`
+	// Parallel is whether the files can be restored in parallel, otherwise
+	// they're restored one by one in order.
+	Parallel bool     `json:"parallel"`
+	Files    []string `json:"files"`
+}
+
+// restorePlanConflicts returns the options conflicting with RestorePlan.
+func restorePlanConflicts(conf *Config) []string {
+	if !conf.RestorePlan {
+		return nil
+	}
+	var conflicts []string
+	switch strings.ToLower(conf.FileType) {
+	case "sql", "csv", "tsv":
+	default:
+		conflicts = append(conflicts, "restore-plan is only supported with filetype sql, csv and tsv")
+	}
+	if conf.Sql != "" || conf.TargetDSN != "" || conf.ServerOutfileDir != "" {
+		conflicts = append(conflicts, "restore-plan is not supported with sql, target-dsn or server-outfile-dir")
+	}
+	if len(conf.RouteRules) > 0 || len(conf.Shards) > 0 {
+		conflicts = append(conflicts, "restore-plan is not supported with route rules or shards")
+	}
+	return conflicts
+}
+
+// restorePlanRecorder collects the closed files of a dump, it does nothing
+// if it's nil.
+type restorePlanRecorder struct {
+	// levels maps the tables to their foreign key levels, a table only
+	// references the tables of the lower levels.
+	levels map[string]int
+
+	mu    sync.Mutex
+	files []string
+}
+
+// newRestorePlanRecorder returns the recorder of the tables to dump, whose
+// foreign keys are listed from db unless the source is PostgreSQL.
+func newRestorePlanRecorder(conf *Config, db *sql.DB) (*restorePlanRecorder, error) {
+	deps := map[string][]string{}
+	if conf.SourceDialect != DialectPostgres {
+		var err error
+		if deps, err = listForeignKeyDependencies(db, conf.Tables); err != nil {
+			return nil, err
+		}
+	}
+	return &restorePlanRecorder{levels: foreignKeyLevels(conf.Tables, deps)}, nil
+}
+
+// foreignKeyLevels returns the levels of the tables in allTables by their
+// dependencies deps, which are 0 for the tables not referencing any others.
+// The tables in a dependency cycle are at the highest level.
+func foreignKeyLevels(allTables DatabaseTables, deps map[string][]string) map[string]int {
+	order, cyclic := sortTablesByDependencies(allTables, deps)
+	levels := map[string]int{}
+	isView := map[string]bool{}
+	for dbName, tables := range allTables {
+		for _, table := range tables {
+			if table.Type == TableTypeView {
+				isView[qualifiedTableName(dbName, table.Name)] = true
+			} else {
+				levels[qualifiedTableName(dbName, table.Name)] = 0
+			}
+		}
+	}
+	maxLevel := 0
+	for _, table := range order {
+		if isView[table] {
+			continue
+		}
+		for _, ref := range deps[table] {
+			if refLevel, ok := levels[ref]; ok && ref != table && refLevel+1 > levels[table] {
+				levels[table] = refLevel + 1
+			}
+		}
+		if levels[table] > maxLevel {
+			maxLevel = levels[table]
+		}
+	}
+	for _, table := range cyclic {
+		levels[table] = maxLevel + 1
+	}
+	return levels
+}
+
+func (r *restorePlanRecorder) record(filePath string) {
+	if r == nil {
+		return
+	}
+	r.mu.Lock()
+	r.files = append(r.files, filePath)
+	r.mu.Unlock()
+}
+
+// restorePlanHooks records the closed files into the restore plan.
+type restorePlanHooks struct {
+	Hooks
+	plan *restorePlanRecorder
+}
+
+func (h restorePlanHooks) OnFileClosed(path string) {
+	h.plan.record(path)
+	h.Hooks.OnFileClosed(path)
+}
+
+// restoreFileKind is the kind of a dumped file, in the order they're
+// restored, the files of the same kind are restored in the same batches.
+type restoreFileKind int
+
+const (
+	restoreDatabases restoreFileKind = iota
+	restoreSequences
+	restoreTables
+	restoreData
+	restoreRoutines
+	restoreViews
+	restoreTriggers
+	restoreTiFlashReplicas
+	// restoreBinlog are the changes tailed from the binlog after the dump,
+	// which are replayed on all the objects of the dump.
+	restoreBinlog
+	// restoreIgnored are the files not restored by statements, such as the
+	// metadata and the schemas for other systems.
+	restoreIgnored
+)
+
+// classifyRestoreFile returns the kind of a dumped file, and its table if
+// it's the schema or data of a table. databases are the dumped databases,
+// the longer names first.
+func classifyRestoreFile(conf *Config, databases []string, fileName string) (restoreFileKind, string) {
+	base := path.Base(fileName)
+	tableOf := func(name string) (string, bool) {
+		dbName, tableName := splitSchemaFileName(name, databases)
+		for _, table := range conf.Tables[dbName] {
+			if table.Name == tableName {
+				return qualifiedTableName(dbName, tableName), table.Type == TableTypeView
+			}
+		}
+		return "", false
+	}
+	switch {
+	case strings.HasSuffix(base, "-schema-create.sql"):
+		return restoreDatabases, ""
+	case strings.HasSuffix(base, "-schema-sequence.sql"):
+		return restoreSequences, ""
+	case strings.HasSuffix(base, "-schema-routines.sql"):
+		return restoreRoutines, ""
+	case strings.HasSuffix(base, "-schema-triggers.sql"):
+		return restoreTriggers, ""
+	case strings.HasSuffix(base, "-schema-tiflash.sql"):
+		return restoreTiFlashReplicas, ""
+	case strings.HasSuffix(base, "-binlog.sql"):
+		return restoreBinlog, ""
+	case strings.HasSuffix(base, "-schema.sql"):
+		table, isView := tableOf(strings.TrimSuffix(base, "-schema.sql"))
+		if isView {
+			return restoreViews, table
+		}
+		if table != "" {
+			return restoreTables, table
+		}
+	default:
+		dbName, tableName, ok := splitDataFileName(base, databases)
+		if !ok {
+			break
+		}
+		if table, isView := tableOf(dbName + "." + tableName); table != "" && !isView {
+			return restoreData, table
+		}
+	}
+	return restoreIgnored, ""
+}
+
+// splitDataFileName splits the database and table of a data file named like
+// `{db}.{table}.{n}.{ext}` or `{db}.{table}.delta{d}.{n}.{ext}`, by the
+// databases like splitSchemaFileName.
+func splitDataFileName(base string, databases []string) (string, string, bool) {
+	name := strings.TrimSuffix(base, path.Ext(base))
+	i := strings.LastIndex(name, ".")
+	if i < 0 || !isDigits(name[i+1:]) {
+		return "", "", false
+	}
+	name = name[:i]
+	if i = strings.LastIndex(name, ".delta"); i >= 0 && isDigits(name[i+len(".delta"):]) {
+		name = name[:i]
+	}
+	dbName, tableName := splitSchemaFileName(name, databases)
+	return dbName, tableName, tableName != ""
+}
+
+func isDigits(s string) bool {
+	if s == "" {
+		return false
+	}
+	for _, c := range s {
+		if c < '0' || c > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// dataChecksForeignKeys is whether the foreign keys are checked when the data
+// files are restored, which is false if the files disable the checks.
+func (conf *Config) dataChecksForeignKeys() bool {
+	if strings.ToLower(conf.FileType) != "sql" || !conf.writesMySQLSettings() {
+		return true
+	}
+	return !conf.DisableForeignKeyChecks && !conf.MysqldumpCompatible
+}
+
+// plan returns the restore plan of the recorded files. The schemas and the
+// data of the tables are in the batches of their foreign key levels, and the
+// data are in one batch if the data files disable the foreign key checks.
+func (r *restorePlanRecorder) plan(conf *Config) *RestorePlan {
+	type batchKey struct {
+		kind  restoreFileKind
+		level int
+	}
+	databases := make([]string, 0, len(conf.Tables))
+	for dbName := range conf.Tables {
+		databases = append(databases, dbName)
+	}
+	// a database named like `db.x` is matched before `db`
+	sort.Slice(databases, func(i, j int) bool { return len(databases[i]) > len(databases[j]) })
+	r.mu.Lock()
+	batches := map[batchKey][]string{}
+	for _, filePath := range r.files {
+		kind, table := classifyRestoreFile(conf, databases, filePath)
+		if kind == restoreIgnored {
+			continue
+		}
+		key := batchKey{kind: kind}
+		if kind == restoreTables || (kind == restoreData && conf.dataChecksForeignKeys()) {
+			key.level = r.levels[table]
+		}
+		if rel, err := filepath.Rel(conf.OutputDirPath, filePath); err == nil && !strings.HasPrefix(rel, "..") {
+			filePath = filepath.ToSlash(rel)
+		}
+		batches[key] = append(batches[key], filePath)
+	}
+	r.mu.Unlock()
+
+	keys := make([]batchKey, 0, len(batches))
+	levels := map[restoreFileKind]int{}
+	for key := range batches {
+		keys = append(keys, key)
+		levels[key.kind]++
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].kind != keys[j].kind {
+			return keys[i].kind < keys[j].kind
+		}
+		return keys[i].level < keys[j].level
+	})
+	names := map[restoreFileKind]string{
+		restoreDatabases:       "databases",
+		restoreSequences:       "sequences",
+		restoreTables:          "tables",
+		restoreData:            "data",
+		restoreRoutines:        "routines",
+		restoreViews:           "views",
+		restoreTriggers:        "triggers",
+		restoreTiFlashReplicas: "tiflash-replicas",
+		restoreBinlog:          "binlog",
+	}
+	plan := &RestorePlan{Batches: make([]RestoreBatch, 0, len(keys))}
+	seen := map[restoreFileKind]int{}
+	for _, key := range keys {
+		files := batches[key]
+		sort.Strings(files)
+		name := names[key.kind]
+		if levels[key.kind] > 1 {
+			name = fmt.Sprintf("%s-%d", name, seen[key.kind])
+			seen[key.kind]++
+		}
+		plan.Batches = append(plan.Batches, RestoreBatch{
+			Name: name,
+			// a view may select from another view, the routines may be
+			// called by each other, and the binlog is replayed in order
+			Parallel: key.kind != restoreViews && key.kind != restoreRoutines && key.kind != restoreBinlog,
+			Files:    files,
+		})
+	}
+	return plan
+}
+
+// writePlan writes the restore plan of the recorded files into
+// restorePlanFile of storage.
+func (r *restorePlanRecorder) writePlan(conf *Config, storage ExternalStorage) error {
+	if r == nil {
+		return nil
+	}
+	content, err := json.MarshalIndent(r.plan(conf), "", "  ")
+	if err != nil {
+		return withStack(err)
+	}
+	fileWriter, err := storage.Create(context.Background(), restorePlanFile)
+	if err != nil {
+		return err
+	}
+	if err = closeFile(fileWriter, write(fileWriter, string(content))); err != nil {
+		return err
+	}
+	log.Info("write restore plan", zap.String("file", conf.outputPath(context.Background(), restorePlanFile)))
+	return nil
+}
+
+// ReadRestorePlan reads the restore plan of the dump in dir, which is dumped
+// with RestorePlan.
+func ReadRestorePlan(dir string) (*RestorePlan, error) {
+	path := filepath.Join(dir, restorePlanFile)
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, withStack(withKind(ErrorKindConfig, err))
+	}
+	plan := &RestorePlan{}
+	if err = json.Unmarshal(content, plan); err != nil {
+		return nil, withStack(withKind(ErrorKindConfig, errors.WithMessage(err, path)))
+	}
+	return plan, nil
+}
@@ -0,0 +1,126 @@
+package export
+
+import (
+	"context"
+	"io/ioutil"
+	"path/filepath"
+
+	. "github.com/pingcap/check"
+)
+
+var _ = Suite(&testRestorePlanSuite{})
+
+type testRestorePlanSuite struct{}
+
+func (s *testRestorePlanSuite) TestForeignKeyLevels(c *C) {
+	tables := NewDatabaseTables().
+		AppendTables("shop", "customers", "orders", "items", "countries", "a", "b").
+		AppendViews("shop", "v")
+	deps := map[string][]string{
+		"`shop`.`customers`": {"`shop`.`countries`"},
+		"`shop`.`orders`":    {"`shop`.`customers`", "`shop`.`orders`"},
+		"`shop`.`items`":     {"`shop`.`orders`", "`shop`.`countries`", "`other`.`missing`"},
+		"`shop`.`a`":         {"`shop`.`b`"},
+		"`shop`.`b`":         {"`shop`.`a`"},
+	}
+	c.Assert(foreignKeyLevels(tables, deps), DeepEquals, map[string]int{
+		"`shop`.`countries`": 0,
+		"`shop`.`customers`": 1,
+		"`shop`.`orders`":    2,
+		"`shop`.`items`":     3,
+		"`shop`.`a`":         4,
+		"`shop`.`b`":         4,
+	})
+}
+
+func newRestorePlanConfig() *Config {
+	conf := DefaultConfig()
+	conf.OutputDirPath = "/data/export"
+	conf.Tables = NewDatabaseTables().
+		AppendTables("shop", "customers", "orders", "t.v1").
+		AppendViews("shop", "v").
+		AppendTables("shop.archive", "orders")
+	conf.restorePlan = &restorePlanRecorder{levels: map[string]int{
+		"`shop`.`customers`":      0,
+		"`shop`.`orders`":         1,
+		"`shop`.`t.v1`":           0,
+		"`shop.archive`.`orders`": 0,
+	}}
+	for _, name := range []string{
+		"metadata",
+		"shop-schema-create.sql",
+		"shop.archive-schema-create.sql",
+		"shop.orders-schema.sql",
+		"shop.customers-schema.sql",
+		"shop.t.v1-schema.sql",
+		"shop.v-schema.sql",
+		"shop.archive.orders-schema.sql",
+		"shop.s-schema-sequence.sql",
+		"shop-schema-routines.sql",
+		"shop-schema-triggers.sql",
+		"shop-schema-tiflash.sql",
+		"shop.orders.0.sql",
+		"shop.orders.1.sql",
+		"shop.customers.0.sql",
+		"shop.t.v1.0.sql",
+		"shop.archive.orders.delta2.0.sql",
+		"shop.orders-load.sql",
+	} {
+		conf.hooks().OnFileClosed(conf.outputPath(context.Background(), name))
+	}
+	conf.hooks().OnFileClosed("/elsewhere/shop.customers.1.sql")
+	return conf
+}
+
+func (s *testRestorePlanSuite) TestPlan(c *C) {
+	conf := newRestorePlanConfig()
+	plan := conf.restorePlan.plan(conf)
+	c.Assert(plan.Batches, DeepEquals, []RestoreBatch{
+		{Name: "databases", Parallel: true, Files: []string{"shop-schema-create.sql", "shop.archive-schema-create.sql"}},
+		{Name: "sequences", Parallel: true, Files: []string{"shop.s-schema-sequence.sql"}},
+		{Name: "tables-0", Parallel: true, Files: []string{"shop.archive.orders-schema.sql", "shop.customers-schema.sql", "shop.t.v1-schema.sql"}},
+		{Name: "tables-1", Parallel: true, Files: []string{"shop.orders-schema.sql"}},
+		{Name: "data-0", Parallel: true, Files: []string{"/elsewhere/shop.customers.1.sql", "shop.archive.orders.delta2.0.sql", "shop.customers.0.sql", "shop.t.v1.0.sql"}},
+		{Name: "data-1", Parallel: true, Files: []string{"shop.orders.0.sql", "shop.orders.1.sql"}},
+		{Name: "routines", Parallel: false, Files: []string{"shop-schema-routines.sql"}},
+		{Name: "views", Parallel: false, Files: []string{"shop.v-schema.sql"}},
+		{Name: "triggers", Parallel: true, Files: []string{"shop-schema-triggers.sql"}},
+		{Name: "tiflash-replicas", Parallel: true, Files: []string{"shop-schema-tiflash.sql"}},
+	})
+
+	// the data are restored together if the data files disable the foreign key checks
+	conf.DisableForeignKeyChecks = true
+	plan = conf.restorePlan.plan(conf)
+	c.Assert(plan.Batches[4], DeepEquals, RestoreBatch{Name: "data", Parallel: true, Files: []string{
+		"/elsewhere/shop.customers.1.sql", "shop.archive.orders.delta2.0.sql", "shop.customers.0.sql",
+		"shop.orders.0.sql", "shop.orders.1.sql", "shop.t.v1.0.sql",
+	}})
+	c.Assert(plan.Batches[5].Name, Equals, "routines")
+}
+
+func (s *testRestorePlanSuite) TestWritePlan(c *C) {
+	conf := newRestorePlanConfig()
+	storage := newMemStorage()
+	c.Assert(conf.restorePlan.writePlan(conf, storage), IsNil)
+	dir := c.MkDir()
+	c.Assert(ioutil.WriteFile(filepath.Join(dir, restorePlanFile), []byte(storage.files[restorePlanFile]), 0644), IsNil)
+	plan, err := ReadRestorePlan(dir)
+	c.Assert(err, IsNil)
+	c.Assert(plan, DeepEquals, conf.restorePlan.plan(conf))
+
+	// nothing is recorded by default
+	conf = DefaultConfig()
+	c.Assert(conf.hooks(), Equals, Hooks(NopHooks{}))
+	c.Assert(conf.restorePlan.writePlan(conf, storage), IsNil)
+}
+
+func (s *testRestorePlanSuite) TestValidate(c *C) {
+	conf := DefaultConfig()
+	conf.RestorePlan = true
+	c.Assert(conf.Validate(), IsNil)
+	conf.FileType = "sqlite"
+	c.Assert(conf.Validate(), ErrorMatches, "(?s).*restore-plan is only supported with filetype sql, csv and tsv.*")
+	conf.FileType = "csv"
+	conf.Shards = []string{"127.0.0.1:3307"}
+	c.Assert(conf.Validate(), ErrorMatches, "(?s).*restore-plan is not supported with route rules or shards.*")
+}
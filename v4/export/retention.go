@@ -0,0 +1,221 @@
+package export
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pingcap/errors"
+	"go.uber.org/zap"
+
+	"github.com/pingcap/dumpling/v4/log"
+)
+
+// DumpPruner is implemented by the ExternalStorages which can list and remove
+// the other dumps at the same destination, it's used to prune the old dumps
+// by Retention after a successful dump.
+type DumpPruner interface {
+	// ListDumps returns the other dumps finished successfully at the destination.
+	ListDumps(ctx context.Context) ([]DumpInfo, error)
+	// RemoveDump removes the dump of name returned by ListDumps.
+	RemoveDump(ctx context.Context, name string) error
+}
+
+// DumpInfo is a dump listed by a DumpPruner. Group is the retention group
+// recorded in the metadata of the dump, only the dumps of the same group as
+// the current one are pruned.
+type DumpInfo struct {
+	Name       string
+	FinishTime time.Time
+	Group      string
+}
+
+// retentionPolicy keeps the newest count dumps, including the current one,
+// and the dumps finished within age. The zero values mean unlimited.
+type retentionPolicy struct {
+	count int
+	age   time.Duration
+}
+
+// parseRetention parses s as a count of dumps, e.g. "7", or an age, e.g.
+// "7d" or "36h".
+func parseRetention(s string) (retentionPolicy, error) {
+	if count, err := strconv.Atoi(s); err == nil {
+		if count <= 0 {
+			return retentionPolicy{}, errors.Errorf("retention should be positive, got %s", s)
+		}
+		return retentionPolicy{count: count}, nil
+	}
+	var age time.Duration
+	if days := strings.TrimSuffix(s, "d"); days != s {
+		n, err := strconv.Atoi(days)
+		if err != nil {
+			return retentionPolicy{}, errors.Errorf("invalid retention %s", s)
+		}
+		age = time.Duration(n) * 24 * time.Hour
+	} else {
+		var err error
+		if age, err = time.ParseDuration(s); err != nil {
+			return retentionPolicy{}, errors.Errorf("invalid retention %s", s)
+		}
+	}
+	if age <= 0 {
+		return retentionPolicy{}, errors.Errorf("retention should be positive, got %s", s)
+	}
+	return retentionPolicy{age: age}, nil
+}
+
+// retentionTimestampSuffix matches the timestamp or sequence number at the end
+// of the name of a dump, e.g. "-20201001" or "-2020-10-01T10:00:00+08:00".
+var retentionTimestampSuffix = regexp.MustCompile(`[-_.]*[0-9][0-9TZ:+\-_.]*$`)
+
+// retentionGroup returns the group recorded in the metadata of the dump by
+// conf, which is the name of the output directory without the timestamp and
+// the databases, so that the dumps of the other jobs next to it aren't pruned.
+func retentionGroup(conf *Config) string {
+	name := filepath.Base(filepath.Clean(conf.OutputDirPath))
+	group := retentionTimestampSuffix.ReplaceAllString(name, "")
+	if conf.Database != "" {
+		group += " " + conf.Database
+	}
+	return group
+}
+
+// baseStorage returns the ExternalStorage wrapped by WrapStorage.
+func baseStorage(storage ExternalStorage) ExternalStorage {
+	for {
+		switch wrapped := storage.(type) {
+		case *wrappedStorage:
+			storage = wrapped.ExternalStorage
+		case *routedStorage:
+			storage = wrapped.ExternalStorage
+		default:
+			return storage
+		}
+	}
+}
+
+// pruneDumps removes the other dumps at the destination which are expired by
+// conf.Retention. The storages which aren't DumpPruners are rejected by
+// Validate.
+func pruneDumps(ctx context.Context, conf *Config, now time.Time) error {
+	if conf.Retention == "" {
+		return nil
+	}
+	policy, err := parseRetention(conf.Retention)
+	if err != nil {
+		return withKind(ErrorKindConfig, err)
+	}
+	pruner, ok := baseStorage(conf.ExternalStorage).(DumpPruner)
+	if !ok {
+		return withKind(ErrorKindConfig, errors.New("retention is only supported by the output storages implementing DumpPruner"))
+	}
+	all, err := pruner.ListDumps(ctx)
+	if err != nil {
+		return withKind(ErrorKindWrite, err)
+	}
+	group := retentionGroup(conf)
+	dumps := all[:0]
+	for _, dump := range all {
+		if dump.Group == group {
+			dumps = append(dumps, dump)
+		}
+	}
+	sort.Slice(dumps, func(i, j int) bool {
+		return dumps[i].FinishTime.After(dumps[j].FinishTime)
+	})
+	for i, dump := range dumps {
+		// the current dump is the newest one
+		expired := policy.count > 0 && i+1 >= policy.count ||
+			policy.age > 0 && now.Sub(dump.FinishTime) > policy.age
+		if !expired {
+			continue
+		}
+		log.Info("remove the expired dump",
+			zap.String("name", dump.Name),
+			zap.Time("finish time", dump.FinishTime))
+		if err = pruner.RemoveDump(ctx, dump.Name); err != nil {
+			return withKind(ErrorKindWrite, err)
+		}
+	}
+	return nil
+}
+
+// ListDumps returns the sibling directories of the LocalStorage whose metadata
+// records the finish time and the retention group, the failed and running
+// dumps and the dumps without retention are not listed.
+func (s *LocalStorage) ListDumps(_ context.Context) ([]DumpInfo, error) {
+	dir, err := filepath.Abs(s.dir)
+	if err != nil {
+		return nil, withStack(err)
+	}
+	parent := filepath.Dir(dir)
+	entries, err := ioutil.ReadDir(parent)
+	if err != nil {
+		return nil, withStack(err)
+	}
+	var dumps []DumpInfo
+	for _, entry := range entries {
+		if !entry.IsDir() || filepath.Join(parent, entry.Name()) == dir {
+			continue
+		}
+		content, err := ioutil.ReadFile(filepath.Join(parent, entry.Name(), metadataPath))
+		if os.IsNotExist(err) {
+			continue
+		} else if err != nil {
+			return nil, withStack(err)
+		}
+		finishTime, group, ok := parseRetentionMetadata(content)
+		if ok {
+			dumps = append(dumps, DumpInfo{Name: entry.Name(), FinishTime: finishTime, Group: group})
+		}
+	}
+	return dumps, nil
+}
+
+// RemoveDump removes the sibling directory of name.
+func (s *LocalStorage) RemoveDump(_ context.Context, name string) error {
+	if name == "" || name != filepath.Base(name) || name == "." || name == ".." {
+		return errors.Errorf("invalid dump name %s", name)
+	}
+	dir, err := filepath.Abs(s.dir)
+	if err != nil {
+		return withStack(err)
+	}
+	return withStack(os.RemoveAll(filepath.Join(filepath.Dir(dir), name)))
+}
+
+// parseRetentionMetadata returns the finish time and the retention group in
+// the content of a metadata file, it's false if the dump isn't finished or
+// isn't dumped with retention.
+func parseRetentionMetadata(content []byte) (time.Time, string, bool) {
+	const finishPrefix = "Finished dump at: "
+	var (
+		finishTime        time.Time
+		group             string
+		finished, grouped bool
+	)
+	scanner := bufio.NewScanner(bytes.NewReader(content))
+	for scanner.Scan() {
+		line := scanner.Text()
+		switch {
+		case !grouped && strings.HasPrefix(line, retentionGroupPrefix):
+			group, grouped = strings.TrimPrefix(line, retentionGroupPrefix), true
+		case !finished && strings.HasPrefix(line, finishPrefix):
+			t, err := time.ParseInLocation(metadataTimeLayout, strings.TrimPrefix(line, finishPrefix), time.Local)
+			if err != nil {
+				return time.Time{}, "", false
+			}
+			finishTime, finished = t, true
+		}
+	}
+	return finishTime, group, finished && grouped
+}
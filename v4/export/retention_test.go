@@ -0,0 +1,151 @@
+package export
+
+import (
+	"context"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+
+	. "github.com/pingcap/check"
+)
+
+var _ = Suite(&testRetentionSuite{})
+
+type testRetentionSuite struct{}
+
+func (s *testRetentionSuite) TestParseRetention(c *C) {
+	policy, err := parseRetention("7")
+	c.Assert(err, IsNil)
+	c.Assert(policy, Equals, retentionPolicy{count: 7})
+	policy, err = parseRetention("7d")
+	c.Assert(err, IsNil)
+	c.Assert(policy, Equals, retentionPolicy{age: 7 * 24 * time.Hour})
+	policy, err = parseRetention("36h")
+	c.Assert(err, IsNil)
+	c.Assert(policy, Equals, retentionPolicy{age: 36 * time.Hour})
+
+	_, err = parseRetention("0")
+	c.Assert(err, ErrorMatches, "retention should be positive, got 0")
+	_, err = parseRetention("-1h")
+	c.Assert(err, ErrorMatches, "retention should be positive, got -1h")
+	_, err = parseRetention("weekly")
+	c.Assert(err, ErrorMatches, "invalid retention weekly")
+}
+
+func writeDump(c *C, dir string, metadata string) {
+	c.Assert(os.MkdirAll(dir, 0755), IsNil)
+	c.Assert(ioutil.WriteFile(filepath.Join(dir, metadataPath), []byte(metadata), 0644), IsNil)
+}
+
+func finishedMetadata(t time.Time, group string) string {
+	m := newGlobalMetadata(nil)
+	m.recordStartTime(t)
+	m.recordRetentionGroup(group)
+	m.recordFinishTime(t)
+	return m.String()
+}
+
+func (s *testRetentionSuite) newDumps(c *C, now time.Time) (string, *Config) {
+	parent := c.MkDir()
+	for i, name := range []string{"export-1", "export-2", "export-3"} {
+		writeDump(c, filepath.Join(parent, name), finishedMetadata(now.Add(time.Duration(i-3)*24*time.Hour), "export app"))
+	}
+	// the failed dump and the other directories are kept
+	writeDump(c, filepath.Join(parent, "export-failed"), "Started dump at: 2020-10-01 10:00:00\n")
+	c.Assert(os.MkdirAll(filepath.Join(parent, "other"), 0755), IsNil)
+	// so are the dumps of the other jobs, and the ones without retention
+	old := now.Add(-30 * 24 * time.Hour)
+	writeDump(c, filepath.Join(parent, "export-crm"), finishedMetadata(old, "export crm"))
+	writeDump(c, filepath.Join(parent, "export-manual"), finishedMetadata(old, ""))
+
+	conf := DefaultConfig()
+	conf.Database = "app"
+	conf.OutputDirPath = filepath.Join(parent, "export-4")
+	storage, err := NewLocalStorage(conf.OutputDirPath, false)
+	c.Assert(err, IsNil)
+	conf.ExternalStorage = WrapStorage(storage, nopWrapper)
+	return parent, conf
+}
+
+func nopWrapper(_ string, w io.WriteCloser) (io.WriteCloser, error) {
+	return w, nil
+}
+
+func listDirs(c *C, dir string) []string {
+	entries, err := ioutil.ReadDir(dir)
+	c.Assert(err, IsNil)
+	var names []string
+	for _, entry := range entries {
+		names = append(names, entry.Name())
+	}
+	return names
+}
+
+func (s *testRetentionSuite) TestPruneByCount(c *C) {
+	now := time.Now().Truncate(time.Second)
+	parent, conf := s.newDumps(c, now)
+	conf.Retention = "2"
+	c.Assert(pruneDumps(context.Background(), conf, now), IsNil)
+	c.Assert(listDirs(c, parent), DeepEquals, []string{"export-3", "export-4", "export-crm", "export-failed", "export-manual", "other"})
+}
+
+func (s *testRetentionSuite) TestPruneByAge(c *C) {
+	now := time.Now().Truncate(time.Second)
+	parent, conf := s.newDumps(c, now)
+	conf.Retention = "50h"
+	c.Assert(pruneDumps(context.Background(), conf, now), IsNil)
+	c.Assert(listDirs(c, parent), DeepEquals, []string{"export-2", "export-3", "export-4", "export-crm", "export-failed", "export-manual", "other"})
+}
+
+func (s *testRetentionSuite) TestRetentionGroup(c *C) {
+	conf := DefaultConfig()
+	for _, x := range []struct {
+		dir   string
+		group string
+	}{
+		{"./export-2020-10-01T10:00:00+08:00", "export"},
+		{"/backup/app-20201001", "app"},
+		{"/backup/app-20201001-100000/", "app"},
+		{"/backup/nightly", "nightly"},
+		{"/backup/20201001", ""},
+	} {
+		conf.OutputDirPath = x.dir
+		c.Assert(retentionGroup(conf), Equals, x.group, Commentf("dir %s", x.dir))
+	}
+	conf.OutputDirPath = "/backup/app-20201001"
+	conf.Database = "app,crm"
+	c.Assert(retentionGroup(conf), Equals, "app app,crm")
+}
+
+func (s *testRetentionSuite) TestPruneWithoutPruner(c *C) {
+	conf := DefaultConfig()
+	conf.Retention = "1"
+	conf.ExternalStorage = newMemStorage()
+	c.Assert(pruneDumps(context.Background(), conf, time.Now()), ErrorMatches, "retention is only supported by the output storages implementing DumpPruner")
+}
+
+func (s *testRetentionSuite) TestRemoveDump(c *C) {
+	storage, err := NewLocalStorage(filepath.Join(c.MkDir(), "export"), false)
+	c.Assert(err, IsNil)
+	c.Assert(storage.RemoveDump(context.Background(), "../export"), ErrorMatches, "invalid dump name ../export")
+	c.Assert(storage.RemoveDump(context.Background(), ".."), ErrorMatches, "invalid dump name ..")
+}
+
+func (s *testRetentionSuite) TestValidate(c *C) {
+	conf := DefaultConfig()
+	conf.Retention = "7d"
+	c.Assert(conf.Validate(), IsNil)
+	conf.Retention = "weekly"
+	c.Assert(conf.Validate(), ErrorMatches, "invalid retention weekly")
+
+	// the storages which can't list the dumps are rejected
+	conf.Retention = "7"
+	conf.ExternalStorage = newMemStorage()
+	c.Assert(conf.Validate(), ErrorMatches, "retention is only supported by the output storages implementing DumpPruner")
+	storage, err := NewLocalStorage(c.MkDir(), false)
+	c.Assert(err, IsNil)
+	conf.ExternalStorage = WrapStorage(storage, nil)
+	c.Assert(conf.Validate(), IsNil)
+}
@@ -0,0 +1,279 @@
+package export
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+	"sync"
+
+	router "github.com/pingcap/tidb-tools/pkg/table-router"
+	"go.uber.org/zap"
+
+	"github.com/pingcap/dumpling/v4/log"
+)
+
+// routedWriter writes the databases and tables under the names routed by
+// RouteRules, into the file names, the DDL and the INSERT statements. The
+// schemas of the tables routed to the same target are only written once, and
+// their chunks are numbered in sequence so the files don't collide.
+type routedWriter struct {
+	Writer
+	*routeState
+	conf *Config
+	// merged are the targets routed from more than one table, nil if any
+	// target may be merged, such as the tables of the shards
+	merged map[string]bool
+}
+
+// routeState is the state of routing shared by the writers of a dump, or of
+// all the shards of a dump.
+type routeState struct {
+	router *router.Table
+
+	mu sync.Mutex
+	// createDatabases are the DDL of the source databases
+	createDatabases map[string]string
+	written         map[string]bool
+	chunks          map[string]int
+}
+
+func newRouteState(rules []*router.TableRule) (*routeState, error) {
+	r, err := router.NewTableRouter(false, rules)
+	if err != nil {
+		return nil, withKind(ErrorKindConfig, err)
+	}
+	return &routeState{
+		router:          r,
+		createDatabases: map[string]string{},
+		written:         map[string]bool{},
+		chunks:          map[string]int{},
+	}, nil
+}
+
+// newRoutedWriter returns the writer routing the names of the tables in
+// conf.Tables by RouteRules, or w itself if there aren't any rules. The
+// writers of the shards always route, since their tables are merged.
+func newRoutedWriter(conf *Config, w Writer) (Writer, error) {
+	if conf.shard != nil {
+		return &routedWriter{Writer: w, routeState: conf.shard.routes, conf: conf}, nil
+	}
+	if len(conf.RouteRules) == 0 {
+		return w, nil
+	}
+	state, err := newRouteState(conf.RouteRules)
+	if err != nil {
+		return nil, err
+	}
+	rw := &routedWriter{Writer: w, routeState: state, conf: conf, merged: map[string]bool{}}
+	sources := map[string]int{}
+	for dbName, tables := range conf.Tables {
+		for _, table := range tables {
+			targetDB, targetTable, err := rw.route(dbName, table.Name)
+			if err != nil {
+				return nil, err
+			}
+			key := tableKey(targetDB, targetTable)
+			if sources[key]++; sources[key] > 1 {
+				rw.merged[key] = true
+			}
+		}
+	}
+	return rw, nil
+}
+
+func tableKey(dbName, tableName string) string {
+	return fmt.Sprintf("%s.%s", dbName, tableName)
+}
+
+func (w *routeState) route(dbName, tableName string) (string, string, error) {
+	targetDB, targetTable, err := w.router.Route(dbName, tableName)
+	if err != nil {
+		return "", "", withKind(ErrorKindConfig, err)
+	}
+	return targetDB, targetTable, nil
+}
+
+func (w *routedWriter) WriteDatabaseMeta(ctx context.Context, db, createSQL string) error {
+	w.mu.Lock()
+	w.createDatabases[db] = createSQL
+	w.mu.Unlock()
+	targetDB, _, err := w.route(db, "")
+	if err != nil {
+		return err
+	}
+	return w.writeDatabaseMeta(ctx, db, targetDB)
+}
+
+// writeDatabaseMeta writes the DDL of the source database db as targetDB, if
+// it's not written yet.
+func (w *routedWriter) writeDatabaseMeta(ctx context.Context, db, targetDB string) error {
+	w.mu.Lock()
+	createSQL, ok := w.createDatabases[db]
+	if !ok || w.written[targetDB] {
+		w.mu.Unlock()
+		return nil
+	}
+	w.written[targetDB] = true
+	w.mu.Unlock()
+	return w.Writer.WriteDatabaseMeta(ctx, targetDB, renameCreateStatement(w.conf, createSQL, db, targetDB))
+}
+
+func (w *routedWriter) WriteTableMeta(ctx context.Context, db, table, createSQL string) error {
+	targetDB, targetTable, err := w.route(db, table)
+	if err != nil {
+		return err
+	}
+	if targetDB != db {
+		// the target database of a routed table may not be routed from any database
+		if err = w.writeDatabaseMeta(ctx, db, targetDB); err != nil {
+			return err
+		}
+	}
+	key := tableKey(targetDB, targetTable)
+	w.mu.Lock()
+	if w.written[key] {
+		w.mu.Unlock()
+		log.Debug("skip the schema written by another routed table",
+			zap.String("database", db), zap.String("table", table), zap.String("target", key))
+		return nil
+	}
+	w.written[key] = true
+	w.mu.Unlock()
+	if createViewRe.MatchString(createSQL) {
+		if createSQL, err = w.routeViewReferences(createSQL); err != nil {
+			return err
+		}
+	}
+	return w.Writer.WriteTableMeta(withFileTable(ctx, db, table), targetDB, targetTable, renameCreateStatement(w.conf, createSQL, table, targetTable))
+}
+
+var (
+	createViewRe = regexp.MustCompile(`(?i)^\s*CREATE\b[^\n(]*?\bVIEW\s`)
+	// viewReferenceRe matches the string literals, which are kept as they're,
+	// and the qualified names quoted by backticks, e.g. `db`.`t`.`column`
+	viewReferenceRe = regexp.MustCompile("'(?:[^'\\\\]|\\\\.)*'|`(?:[^`]|``)+`(?:\\.`(?:[^`]|``)+`)+")
+)
+
+// routeViewReferences routes the tables referenced by the view createSQL,
+// which are qualified by their databases in SHOW CREATE VIEW. The names
+// whose first two parts aren't one of the dumped tables are kept.
+func (w *routedWriter) routeViewReferences(createSQL string) (string, error) {
+	var routeErr error
+	routed := viewReferenceRe.ReplaceAllStringFunc(createSQL, func(s string) string {
+		if s[0] == '\'' || routeErr != nil {
+			return s
+		}
+		names := strings.Split(s[1:len(s)-1], "`.`")
+		for i := range names {
+			names[i] = strings.Replace(names[i], "``", "`", -1)
+		}
+		if !w.conf.Tables.contains(names[0], names[1]) {
+			return s
+		}
+		targetDB, targetTable, err := w.route(names[0], names[1])
+		if err != nil {
+			routeErr = err
+			return s
+		}
+		names[0], names[1] = targetDB, targetTable
+		quote := w.conf.output().QuoteIdentifier
+		for i := range names {
+			names[i] = quote(names[i])
+		}
+		return strings.Join(names, ".")
+	})
+	return routed, routeErr
+}
+
+func (w *routedWriter) WriteTableData(ctx context.Context, ir TableDataIR) error {
+	if ir.TableName() == "" {
+		return w.Writer.WriteTableData(ctx, ir)
+	}
+	targetDB, targetTable, err := w.route(ir.DatabaseName(), ir.TableName())
+	if err != nil {
+		return err
+	}
+	routed := &routedTableData{
+		TableDataIR: ir,
+		conf:        w.conf,
+		database:    targetDB,
+		table:       targetTable,
+		chunkIndex:  ir.ChunkIndex(),
+	}
+	if key := tableKey(targetDB, targetTable); w.merged == nil || w.merged[key] {
+		w.mu.Lock()
+		routed.chunkIndex = w.chunks[key]
+		w.chunks[key]++
+		w.mu.Unlock()
+	}
+	// the files are written into the output of the source table
+	return w.Writer.WriteTableData(withFileTable(ctx, ir.DatabaseName(), ir.TableName()), routed)
+}
+
+func (w *routedWriter) Close() error {
+	if closer, ok := w.Writer.(io.Closer); ok {
+		return closer.Close()
+	}
+	return nil
+}
+
+func (w *routedWriter) Abort() error {
+	if aborter, ok := w.Writer.(Aborter); ok {
+		return aborter.Abort()
+	}
+	return w.Close()
+}
+
+// routedTableData is a TableDataIR under the routed names.
+type routedTableData struct {
+	TableDataIR
+	conf       *Config
+	database   string
+	table      string
+	chunkIndex int
+}
+
+func (td *routedTableData) DatabaseName() string {
+	return td.database
+}
+
+func (td *routedTableData) TableName() string {
+	return td.table
+}
+
+func (td *routedTableData) tableProgress() *tableProgress {
+	return tableProgressOf(td.TableDataIR)
+}
+
+func (td *routedTableData) ChunkIndex() int {
+	return td.chunkIndex
+}
+
+func (td *routedTableData) SpecialComments() StringIter {
+	return newStringIter(buildSpecialComments(td.conf, td.database, td.table)...)
+}
+
+func (td *routedTableData) SpecialFooters() StringIter {
+	return newStringIter(buildSpecialFooters(td.conf, td.table)...)
+}
+
+// renameCreateStatement replaces the name of the database, table or view
+// created by createSQL from name to target.
+func renameCreateStatement(conf *Config, createSQL, name, target string) string {
+	if name == target {
+		return createSQL
+	}
+	quote := conf.output().QuoteIdentifier
+	re := regexp.MustCompile(`(?i)(\bCREATE\b[^\n]*?\b(?:DATABASE|SCHEMA|TABLE|VIEW)\s+(?:IF\s+NOT\s+EXISTS\s+)?)` +
+		regexp.QuoteMeta(quote(name)))
+	replaced := false
+	return re.ReplaceAllStringFunc(createSQL, func(s string) string {
+		if replaced {
+			return s
+		}
+		replaced = true
+		return re.ReplaceAllString(s, "${1}") + quote(target)
+	})
+}
@@ -0,0 +1,106 @@
+package export
+
+import (
+	"context"
+	"database/sql/driver"
+
+	. "github.com/pingcap/check"
+	router "github.com/pingcap/tidb-tools/pkg/table-router"
+)
+
+var _ = Suite(&testRouteSuite{})
+
+type testRouteSuite struct{}
+
+func (s *testRouteSuite) TestRoutedWriter(c *C) {
+	conf := DefaultConfig()
+	storage := newMemStorage()
+	conf.ExternalStorage = storage
+	conf.DisableKeys = true
+	conf.RouteRules = []*router.TableRule{
+		{SchemaPattern: "shard_*", TablePattern: "orders", TargetSchema: "merged", TargetTable: "orders"},
+		{SchemaPattern: "shard_*", TablePattern: "users_*", TargetSchema: "merged", TargetTable: "users"},
+	}
+	conf.Tables = DatabaseTables{
+		"shard_1": {{Name: "orders"}, {Name: "users_1"}, {Name: "logs"}},
+		"shard_2": {{Name: "orders"}},
+	}
+	w, err := NewSimpleWriter(conf)
+	c.Assert(err, IsNil)
+	w, err = newRoutedWriter(conf, w)
+	c.Assert(err, IsNil)
+	ctx := context.Background()
+
+	c.Assert(w.WriteDatabaseMeta(ctx, "shard_1", "CREATE DATABASE `shard_1` /*!40100 DEFAULT CHARACTER SET utf8mb4 */"), IsNil)
+	c.Assert(w.WriteDatabaseMeta(ctx, "shard_2", "CREATE DATABASE `shard_2` /*!40100 DEFAULT CHARACTER SET utf8mb4 */"), IsNil)
+	for _, db := range []string{"shard_1", "shard_2"} {
+		c.Assert(w.WriteTableMeta(ctx, db, "orders", "CREATE TABLE `orders` (\n  `id` int\n)"), IsNil)
+		ir := newMockTableIR(db, "orders", [][]driver.Value{{db}}, buildSpecialComments(conf, db, "orders"), []string{"VARCHAR"}).(*mockTableIR)
+		ir.specFooter = buildSpecialFooters(conf, "orders")
+		c.Assert(w.WriteTableData(ctx, ir), IsNil)
+	}
+	c.Assert(w.WriteTableMeta(ctx, "shard_1", "users_1", "CREATE TABLE IF NOT EXISTS `users_1` (\n  `users_1` int\n)"), IsNil)
+	c.Assert(w.WriteTableMeta(ctx, "shard_1", "logs", "CREATE TABLE `logs` (\n  `id` int\n)"), IsNil)
+
+	c.Assert(storage.files, DeepEquals, map[string]string{
+		// the tables aren't routed by the databases
+		"shard_1-schema-create.sql": "CREATE DATABASE `shard_1` /*!40100 DEFAULT CHARACTER SET utf8mb4 */;\n",
+		"shard_2-schema-create.sql": "CREATE DATABASE `shard_2` /*!40100 DEFAULT CHARACTER SET utf8mb4 */;\n",
+		"merged-schema-create.sql":  "CREATE DATABASE `merged` /*!40100 DEFAULT CHARACTER SET utf8mb4 */;\n",
+		"merged.orders-schema.sql":  "CREATE TABLE `orders` (\n  `id` int\n);\n",
+		"merged.orders.0.sql": "/*!40101 SET NAMES binary*/;\n/*!40000 ALTER TABLE `orders` DISABLE KEYS*/;\n" +
+			"INSERT INTO `orders` VALUES\n('shard_1');\n/*!40000 ALTER TABLE `orders` ENABLE KEYS*/;\n",
+		"merged.orders.1.sql": "/*!40101 SET NAMES binary*/;\n/*!40000 ALTER TABLE `orders` DISABLE KEYS*/;\n" +
+			"INSERT INTO `orders` VALUES\n('shard_2');\n/*!40000 ALTER TABLE `orders` ENABLE KEYS*/;\n",
+		"merged.users-schema.sql": "CREATE TABLE IF NOT EXISTS `users` (\n  `users_1` int\n);\n",
+		"shard_1.logs-schema.sql": "CREATE TABLE `logs` (\n  `id` int\n);\n",
+	})
+}
+
+func (s *testRouteSuite) TestRenameCreateStatement(c *C) {
+	conf := DefaultConfig()
+	c.Assert(renameCreateStatement(conf, "CREATE ALGORITHM=UNDEFINED DEFINER=`root`@`%` SQL SECURITY DEFINER VIEW `v` AS SELECT `v` FROM `t`", "v", "v2"),
+		Equals, "CREATE ALGORITHM=UNDEFINED DEFINER=`root`@`%` SQL SECURITY DEFINER VIEW `v2` AS SELECT `v` FROM `t`")
+	c.Assert(renameCreateStatement(conf, "CREATE TABLE `t` (`id` int)", "t", "t"), Equals, "CREATE TABLE `t` (`id` int)")
+}
+
+func (s *testRouteSuite) TestInvalidRules(c *C) {
+	conf := DefaultConfig()
+	conf.RouteRules = []*router.TableRule{{TablePattern: "t", TargetSchema: "merged"}}
+	_, err := newRoutedWriter(conf, nil)
+	c.Assert(ErrorKindOf(err), Equals, ErrorKindConfig)
+}
+
+func (s *testRouteSuite) TestRouteViewReferences(c *C) {
+	conf := DefaultConfig()
+	storage := newMemStorage()
+	conf.ExternalStorage = storage
+	conf.RouteRules = []*router.TableRule{
+		{SchemaPattern: "shard_*", TablePattern: "orders", TargetSchema: "merged", TargetTable: "all_orders"},
+		{SchemaPattern: "shard_*", TablePattern: "v", TargetSchema: "merged", TargetTable: "v"},
+	}
+	conf.Tables = DatabaseTables{
+		"shard_1": {{Name: "orders"}, {Name: "logs"}, {Name: "v", Type: TableTypeView}},
+	}
+	w, err := NewSimpleWriter(conf)
+	c.Assert(err, IsNil)
+	w, err = newRoutedWriter(conf, w)
+	c.Assert(err, IsNil)
+
+	// the string literals and the names of the tables which aren't dumped are kept
+	c.Assert(w.WriteTableMeta(context.Background(), "shard_1", "v", "CREATE ALGORITHM=UNDEFINED DEFINER=`root`@`%` SQL SECURITY DEFINER VIEW `v` AS "+
+		"select `shard_1`.`orders`.`id` AS `id`,'`shard_1`.`orders`' AS `s` from ((`shard_1`.`orders` join `shard_1`.`logs`) join `other`.`orders`)"), IsNil)
+	c.Assert(storage.files["merged.v-schema.sql"], Equals, "CREATE ALGORITHM=UNDEFINED DEFINER=`root`@`%` SQL SECURITY DEFINER VIEW `v` AS "+
+		"select `merged`.`all_orders`.`id` AS `id`,'`shard_1`.`orders`' AS `s` from ((`merged`.`all_orders` join `shard_1`.`logs`) join `other`.`orders`);\n")
+}
+
+func (s *testRouteSuite) TestValidate(c *C) {
+	conf := DefaultConfig()
+	conf.RouteRules = []*router.TableRule{{SchemaPattern: "shard_*", TablePattern: "orders", TargetSchema: "merged", TargetTable: "orders"}}
+	c.Assert(conf.Validate(), IsNil)
+	// the files of the merged chunks would be named as each other
+	conf.FileSize = 1 << 20
+	c.Assert(conf.Validate(), ErrorMatches, ".*route rules and shards are not supported with filesize.*")
+	conf.Rows = 1000
+	c.Assert(conf.Validate(), IsNil)
+}
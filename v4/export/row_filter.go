@@ -0,0 +1,904 @@
+package export
+
+import (
+	"database/sql"
+	"fmt"
+	"math/big"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/pingcap/errors"
+)
+
+// RowFilterFunc is a function callable in the row filter expressions, e.g.
+// to decrypt or derive a value before it's compared. The arguments and the
+// result are nil for NULL, bool, string or *big.Rat for the numbers.
+type RowFilterFunc func(args []interface{}) (interface{}, error)
+
+// rowFilterFuncs are the builtin functions of the row filter expressions,
+// which return NULL if any argument is NULL.
+var rowFilterFuncs = map[string]struct {
+	minArgs, maxArgs int
+	fn               func(args []interface{}) (interface{}, error)
+}{
+	"len": {1, 1, func(args []interface{}) (interface{}, error) {
+		return new(big.Rat).SetInt64(int64(utf8.RuneCountInString(toFilterString(args[0])))), nil
+	}},
+	"lower": {1, 1, func(args []interface{}) (interface{}, error) {
+		return strings.ToLower(toFilterString(args[0])), nil
+	}},
+	"upper": {1, 1, func(args []interface{}) (interface{}, error) {
+		return strings.ToUpper(toFilterString(args[0])), nil
+	}},
+	"trim": {1, 1, func(args []interface{}) (interface{}, error) {
+		return strings.TrimSpace(toFilterString(args[0])), nil
+	}},
+	"contains": {2, 2, func(args []interface{}) (interface{}, error) {
+		return strings.Contains(toFilterString(args[0]), toFilterString(args[1])), nil
+	}},
+	"starts_with": {2, 2, func(args []interface{}) (interface{}, error) {
+		return strings.HasPrefix(toFilterString(args[0]), toFilterString(args[1])), nil
+	}},
+	"ends_with": {2, 2, func(args []interface{}) (interface{}, error) {
+		return strings.HasSuffix(toFilterString(args[0]), toFilterString(args[1])), nil
+	}},
+	// substr(s, pos[, len]) counts the characters from 1 like SQL
+	"substr": {2, 3, func(args []interface{}) (interface{}, error) {
+		runes := []rune(toFilterString(args[0]))
+		pos, err := toFilterInt(args[1])
+		if err != nil {
+			return nil, err
+		}
+		length := int64(len(runes))
+		if len(args) == 3 {
+			if length, err = toFilterInt(args[2]); err != nil {
+				return nil, err
+			}
+		}
+		if pos < 1 || pos > int64(len(runes)) || length <= 0 {
+			return "", nil
+		}
+		end := pos - 1 + length
+		if end > int64(len(runes)) {
+			end = int64(len(runes))
+		}
+		return string(runes[pos-1 : end]), nil
+	}},
+}
+
+// rowFilterConflicts returns the invalid row filters of conf and the
+// options conflicting with them.
+func rowFilterConflicts(conf *Config) []string {
+	var conflicts []string
+	filters := conf.RowFilter != ""
+	if conf.RowFilter != "" {
+		if _, err := parseRowFilter(conf.RowFilter, conf.RowFilterFuncs, nil, nil); err != nil {
+			conflicts = append(conflicts, err.Error())
+		}
+	}
+	for _, tc := range conf.TableConfigs {
+		if tc.RowFilter == "" {
+			continue
+		}
+		filters = true
+		if _, err := parseRowFilter(tc.RowFilter, conf.RowFilterFuncs, nil, nil); err != nil {
+			conflicts = append(conflicts, fmt.Sprintf("%s of %s", err.Error(), qualifiedTableName(tc.Database, tc.Table)))
+		}
+	}
+	if filters && conf.ServerOutfileDir != "" {
+		conflicts = append(conflicts, "row filter is not supported with server-outfile-dir")
+	}
+	return conflicts
+}
+
+// rowFilter is a compiled row filter expression, which keeps the rows it's
+// evaluated to true for, and drops the rows of false or NULL.
+type rowFilter struct {
+	expr rowFilterNode
+}
+
+// parseRowFilter compiles the expression for the columns, a column is
+// resolved to its index by the names case-insensitively. If columns is nil,
+// any column is accepted, which only checks the syntax.
+func parseRowFilter(expr string, funcs map[string]RowFilterFunc, columns, colTypes []string) (*rowFilter, error) {
+	p := &rowFilterParser{input: expr, funcs: funcs, columns: columns, colTypes: colTypes}
+	if err := p.next(); err != nil {
+		return nil, err
+	}
+	node, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if p.tok.kind != tokenEOF {
+		return nil, p.errorf("unexpected %s", p.tok.text)
+	}
+	return &rowFilter{expr: node}, nil
+}
+
+// match returns whether the row is kept.
+func (f *rowFilter) match(row []sql.RawBytes) (bool, error) {
+	v, err := f.expr.eval(row)
+	if err != nil {
+		return false, err
+	}
+	switch v := v.(type) {
+	case nil:
+		return false, nil
+	case bool:
+		return v, nil
+	default:
+		return false, errors.Errorf("the row filter is evaluated to %s instead of a boolean", formatFilterValue(v))
+	}
+}
+
+// rowFilterTableData drops the rows of a TableDataIR the filter isn't true for.
+type rowFilterTableData struct {
+	TableDataIR
+	filter *rowFilter
+	// err is the error of evaluating the filter, which stops the rows
+	err error
+}
+
+// withRowFilter returns ir with the rows filtered by the row filter of conf,
+// or ir itself if there isn't one.
+func withRowFilter(conf *Config, ir TableDataIR) (*rowFilterTableData, TableDataIR, error) {
+	if conf.RowFilter == "" {
+		return nil, ir, nil
+	}
+	filter, err := parseRowFilter(conf.RowFilter, conf.RowFilterFuncs, ir.ColumnNames(), ir.ColumnTypes())
+	if err != nil {
+		return nil, nil, withKind(ErrorKindConfig, errors.WithMessage(err, "row filter of "+qualifiedTableName(ir.DatabaseName(), ir.TableName())))
+	}
+	td := &rowFilterTableData{TableDataIR: ir, filter: filter}
+	return td, td, nil
+}
+
+func (td *rowFilterTableData) Rows() SQLRowIter {
+	n := td.ColumnCount()
+	return &rowFilterRowIter{
+		SQLRowIter: td.TableDataIR.Rows(),
+		td:         td,
+		row:        make(rawRow, n),
+		args:       make([]interface{}, n),
+	}
+}
+
+// rowFilterRowIter decodes the next row in HasNext to evaluate the filter,
+// and keeps it for Decode if it passes.
+type rowFilterRowIter struct {
+	SQLRowIter
+	td     *rowFilterTableData
+	row    rawRow
+	args   []interface{}
+	staged bool
+}
+
+func (iter *rowFilterRowIter) HasNext() bool {
+	for !iter.staged {
+		if iter.td.err != nil || !iter.SQLRowIter.HasNext() {
+			return false
+		}
+		if err := iter.SQLRowIter.Decode(iter.row); err != nil {
+			iter.td.err = err
+			return false
+		}
+		keep, err := iter.td.filter.match(iter.row)
+		if err != nil {
+			iter.td.err = err
+			return false
+		}
+		if keep {
+			iter.staged = true
+		} else {
+			iter.SQLRowIter.Next()
+		}
+	}
+	return true
+}
+
+func (iter *rowFilterRowIter) HasNextSQLRowIter() bool {
+	return iter.HasNext()
+}
+
+func (iter *rowFilterRowIter) NextSQLRowIter() SQLRowIter {
+	iter.SQLRowIter = iter.SQLRowIter.NextSQLRowIter()
+	return iter
+}
+
+func (iter *rowFilterRowIter) Decode(row RowReceiver) error {
+	if !iter.staged && !iter.HasNext() {
+		if iter.td.err != nil {
+			return iter.td.err
+		}
+		return errors.New("decode beyond the last row")
+	}
+	return iter.row.copyTo(row, iter.args)
+}
+
+func (iter *rowFilterRowIter) Next() {
+	iter.staged = false
+	iter.SQLRowIter.Next()
+}
+
+func (iter *rowFilterRowIter) Error() error {
+	if iter.td.err != nil {
+		return iter.td.err
+	}
+	return iter.SQLRowIter.Error()
+}
+
+type rowFilterNode interface {
+	eval(row []sql.RawBytes) (interface{}, error)
+}
+
+type filterLiteral struct {
+	value interface{}
+}
+
+func (n filterLiteral) eval([]sql.RawBytes) (interface{}, error) {
+	return n.value, nil
+}
+
+// filterColumn is a column of the row, whose value is a number if the
+// column is of a numeric type.
+type filterColumn struct {
+	index  int
+	number bool
+}
+
+func (n filterColumn) eval(row []sql.RawBytes) (interface{}, error) {
+	value := row[n.index]
+	if value == nil {
+		return nil, nil
+	}
+	if n.number {
+		if r, ok := new(big.Rat).SetString(string(value)); ok {
+			return r, nil
+		}
+	}
+	return string(value), nil
+}
+
+type filterNot struct {
+	x rowFilterNode
+}
+
+func (n filterNot) eval(row []sql.RawBytes) (interface{}, error) {
+	v, err := n.x.eval(row)
+	if err != nil || v == nil {
+		return nil, err
+	}
+	b, ok := v.(bool)
+	if !ok {
+		return nil, errors.Errorf("! of %s which isn't a boolean", formatFilterValue(v))
+	}
+	return !b, nil
+}
+
+type filterNeg struct {
+	x rowFilterNode
+}
+
+func (n filterNeg) eval(row []sql.RawBytes) (interface{}, error) {
+	v, err := n.x.eval(row)
+	if err != nil || v == nil {
+		return nil, err
+	}
+	r, err := toFilterNumber(v)
+	if err != nil {
+		return nil, err
+	}
+	return new(big.Rat).Neg(r), nil
+}
+
+// filterLogic is && or || in the three-valued logic of SQL.
+type filterLogic struct {
+	and  bool
+	x, y rowFilterNode
+}
+
+func (n filterLogic) eval(row []sql.RawBytes) (interface{}, error) {
+	x, err := n.evalBool(n.x, row)
+	if err != nil {
+		return nil, err
+	}
+	// false && y and true || y are decided without y
+	if x != nil && *x != n.and {
+		return *x, nil
+	}
+	y, err := n.evalBool(n.y, row)
+	if err != nil {
+		return nil, err
+	}
+	switch {
+	case y != nil && *y != n.and:
+		return *y, nil
+	case x == nil || y == nil:
+		return nil, nil
+	default:
+		return n.and, nil
+	}
+}
+
+func (n filterLogic) evalBool(node rowFilterNode, row []sql.RawBytes) (*bool, error) {
+	v, err := node.eval(row)
+	if err != nil || v == nil {
+		return nil, err
+	}
+	b, ok := v.(bool)
+	if !ok {
+		op := "||"
+		if n.and {
+			op = "&&"
+		}
+		return nil, errors.Errorf("%s of %s which isn't a boolean", op, formatFilterValue(v))
+	}
+	return &b, nil
+}
+
+type filterCompare struct {
+	op   string
+	x, y rowFilterNode
+}
+
+func (n filterCompare) eval(row []sql.RawBytes) (interface{}, error) {
+	x, err := n.x.eval(row)
+	if err != nil {
+		return nil, err
+	}
+	y, err := n.y.eval(row)
+	if err != nil {
+		return nil, err
+	}
+	if x == nil || y == nil {
+		// == and != compare NULL as a value
+		switch n.op {
+		case "==":
+			return x == nil && y == nil, nil
+		case "!=":
+			return x != nil || y != nil, nil
+		default:
+			return nil, nil
+		}
+	}
+	cmp, err := compareFilterValues(x, y)
+	if err != nil {
+		return nil, err
+	}
+	switch n.op {
+	case "==":
+		return cmp == 0, nil
+	case "!=":
+		return cmp != 0, nil
+	case "<":
+		return cmp < 0, nil
+	case "<=":
+		return cmp <= 0, nil
+	case ">":
+		return cmp > 0, nil
+	default:
+		return cmp >= 0, nil
+	}
+}
+
+// filterMatch is =~ or !~ of a regular expression, which is compiled once if
+// it's a literal.
+type filterMatch struct {
+	negate  bool
+	x, y    rowFilterNode
+	pattern *regexp.Regexp
+}
+
+func (n filterMatch) eval(row []sql.RawBytes) (interface{}, error) {
+	x, err := n.x.eval(row)
+	if err != nil || x == nil {
+		return nil, err
+	}
+	re := n.pattern
+	if re == nil {
+		y, err := n.y.eval(row)
+		if err != nil || y == nil {
+			return nil, err
+		}
+		if re, err = regexp.Compile(toFilterString(y)); err != nil {
+			return nil, errors.Trace(err)
+		}
+	}
+	return re.MatchString(toFilterString(x)) != n.negate, nil
+}
+
+type filterArith struct {
+	op   byte
+	x, y rowFilterNode
+}
+
+func (n filterArith) eval(row []sql.RawBytes) (interface{}, error) {
+	var operands [2]*big.Rat
+	for i, node := range []rowFilterNode{n.x, n.y} {
+		v, err := node.eval(row)
+		if err != nil || v == nil {
+			return nil, err
+		}
+		if operands[i], err = toFilterNumber(v); err != nil {
+			return nil, err
+		}
+	}
+	x, y := operands[0], operands[1]
+	switch n.op {
+	case '+':
+		return new(big.Rat).Add(x, y), nil
+	case '-':
+		return new(big.Rat).Sub(x, y), nil
+	case '*':
+		return new(big.Rat).Mul(x, y), nil
+	default:
+		// division by zero is NULL like SQL
+		if y.Sign() == 0 {
+			return nil, nil
+		}
+		return new(big.Rat).Quo(x, y), nil
+	}
+}
+
+type filterCall struct {
+	name string
+	fn   func(args []interface{}) (interface{}, error)
+	// nullable is whether the function is called with NULL arguments
+	nullable bool
+	args     []rowFilterNode
+}
+
+func (n filterCall) eval(row []sql.RawBytes) (interface{}, error) {
+	args := make([]interface{}, len(n.args))
+	for i, node := range n.args {
+		v, err := node.eval(row)
+		if err != nil {
+			return nil, err
+		}
+		if v == nil && !n.nullable {
+			return nil, nil
+		}
+		args[i] = v
+	}
+	v, err := n.fn(args)
+	if err != nil {
+		return nil, errors.WithMessage(err, "call "+n.name)
+	}
+	switch v.(type) {
+	case nil, bool, string, *big.Rat:
+		return v, nil
+	default:
+		return nil, errors.Errorf("%s returns unsupported %T", n.name, v)
+	}
+}
+
+// compareFilterValues compares the values of the same type, a string is
+// compared with a number as a number.
+func compareFilterValues(x, y interface{}) (int, error) {
+	switch x := x.(type) {
+	case bool:
+		if y, ok := y.(bool); ok {
+			switch {
+			case x == y:
+				return 0, nil
+			case !x:
+				return -1, nil
+			default:
+				return 1, nil
+			}
+		}
+	case string:
+		if y, ok := y.(string); ok {
+			return strings.Compare(x, y), nil
+		}
+	}
+	if _, ok := x.(bool); !ok {
+		if _, ok := y.(bool); !ok {
+			rx, err := toFilterNumber(x)
+			if err != nil {
+				return 0, err
+			}
+			ry, err := toFilterNumber(y)
+			if err != nil {
+				return 0, err
+			}
+			return rx.Cmp(ry), nil
+		}
+	}
+	return 0, errors.Errorf("can't compare %s with %s", formatFilterValue(x), formatFilterValue(y))
+}
+
+func toFilterNumber(v interface{}) (*big.Rat, error) {
+	switch v := v.(type) {
+	case *big.Rat:
+		return v, nil
+	case string:
+		if r, ok := new(big.Rat).SetString(strings.TrimSpace(v)); ok {
+			return r, nil
+		}
+	}
+	return nil, errors.Errorf("%s isn't a number", formatFilterValue(v))
+}
+
+func toFilterInt(v interface{}) (int64, error) {
+	r, err := toFilterNumber(v)
+	if err != nil {
+		return 0, err
+	}
+	if !r.IsInt() || !r.Num().IsInt64() {
+		return 0, errors.Errorf("%s isn't an integer", formatFilterValue(v))
+	}
+	return r.Num().Int64(), nil
+}
+
+func toFilterString(v interface{}) string {
+	switch v := v.(type) {
+	case string:
+		return v
+	case *big.Rat:
+		if v.IsInt() {
+			return v.Num().String()
+		}
+		return strings.TrimRight(v.FloatString(30), "0")
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+func formatFilterValue(v interface{}) string {
+	switch v := v.(type) {
+	case nil:
+		return "NULL"
+	case string:
+		return fmt.Sprintf("%q", v)
+	default:
+		return toFilterString(v)
+	}
+}
+
+type rowFilterTokenKind int
+
+const (
+	tokenEOF rowFilterTokenKind = iota
+	tokenIdent
+	tokenNumber
+	tokenString
+	tokenOp
+)
+
+type rowFilterToken struct {
+	kind rowFilterTokenKind
+	text string
+	// value is the unquoted identifier or string
+	value string
+	pos   int
+}
+
+// rowFilterParser is a recursive descent parser of the expressions, from the
+// lowest precedence: ||, &&, !, comparisons, + and -, * and /, unary -.
+type rowFilterParser struct {
+	input    string
+	pos      int
+	tok      rowFilterToken
+	funcs    map[string]RowFilterFunc
+	columns  []string
+	colTypes []string
+}
+
+func (p *rowFilterParser) errorf(format string, args ...interface{}) error {
+	return errors.Errorf("invalid row filter at %d: %s", p.tok.pos+1, fmt.Sprintf(format, args...))
+}
+
+var rowFilterOps = []string{"||", "&&", "==", "!=", "<=", ">=", "=~", "!~", "!", "<", ">", "+", "-", "*", "/", "(", ")", ","}
+
+func (p *rowFilterParser) next() error {
+	for p.pos < len(p.input) && strings.IndexByte(" \t\r\n", p.input[p.pos]) >= 0 {
+		p.pos++
+	}
+	start := p.pos
+	p.tok = rowFilterToken{pos: start}
+	if p.pos >= len(p.input) {
+		p.tok.kind = tokenEOF
+		p.tok.text = "end"
+		return nil
+	}
+	c := p.input[p.pos]
+	switch {
+	case c == '\'' || c == '"' || c == '`':
+		var b strings.Builder
+		for p.pos++; ; p.pos++ {
+			if p.pos >= len(p.input) {
+				return p.errorf("unterminated %c", c)
+			}
+			ch := p.input[p.pos]
+			if ch == c {
+				break
+			}
+			if ch == '\\' && c != '`' && p.pos+1 < len(p.input) {
+				p.pos++
+				switch ch = p.input[p.pos]; ch {
+				case 'n':
+					ch = '\n'
+				case 't':
+					ch = '\t'
+				case 'r':
+					ch = '\r'
+				case '0':
+					ch = 0
+				}
+			}
+			b.WriteByte(ch)
+		}
+		p.pos++
+		p.tok.kind = tokenString
+		if c == '`' {
+			p.tok.kind = tokenIdent
+		}
+		p.tok.value = b.String()
+	case c >= '0' && c <= '9' || c == '.':
+		for p.pos < len(p.input) && (isWordByte(p.input[p.pos]) || p.input[p.pos] == '.' ||
+			(p.input[p.pos] == '+' || p.input[p.pos] == '-') && (p.input[p.pos-1] == 'e' || p.input[p.pos-1] == 'E')) {
+			p.pos++
+		}
+		p.tok.kind = tokenNumber
+	case isWordByte(c):
+		for p.pos < len(p.input) && (isWordByte(p.input[p.pos]) || p.input[p.pos] == '$') {
+			p.pos++
+		}
+		p.tok.kind = tokenIdent
+		p.tok.value = p.input[start:p.pos]
+	default:
+		for _, op := range rowFilterOps {
+			if strings.HasPrefix(p.input[p.pos:], op) {
+				p.pos += len(op)
+				p.tok.kind = tokenOp
+				break
+			}
+		}
+		if p.tok.kind != tokenOp {
+			return p.errorf("unexpected %c", c)
+		}
+	}
+	p.tok.text = p.input[start:p.pos]
+	return nil
+}
+
+func (p *rowFilterParser) isOp(ops ...string) bool {
+	if p.tok.kind != tokenOp {
+		return false
+	}
+	for _, op := range ops {
+		if p.tok.text == op {
+			return true
+		}
+	}
+	return false
+}
+
+func (p *rowFilterParser) parseOr() (rowFilterNode, error) {
+	return p.parseLogic(false)
+}
+
+func (p *rowFilterParser) parseLogic(and bool) (rowFilterNode, error) {
+	op, operand := "||", func() (rowFilterNode, error) { return p.parseLogic(true) }
+	if and {
+		op, operand = "&&", p.parseNot
+	}
+	x, err := operand()
+	if err != nil {
+		return nil, err
+	}
+	for p.isOp(op) {
+		if err = p.next(); err != nil {
+			return nil, err
+		}
+		y, err := operand()
+		if err != nil {
+			return nil, err
+		}
+		x = filterLogic{and: and, x: x, y: y}
+	}
+	return x, nil
+}
+
+func (p *rowFilterParser) parseNot() (rowFilterNode, error) {
+	if !p.isOp("!") {
+		return p.parseCompare()
+	}
+	if err := p.next(); err != nil {
+		return nil, err
+	}
+	x, err := p.parseNot()
+	if err != nil {
+		return nil, err
+	}
+	return filterNot{x: x}, nil
+}
+
+func (p *rowFilterParser) parseCompare() (rowFilterNode, error) {
+	x, err := p.parseArith(false)
+	if err != nil {
+		return nil, err
+	}
+	if !p.isOp("==", "!=", "<", "<=", ">", ">=", "=~", "!~") {
+		return x, nil
+	}
+	op := p.tok.text
+	if err = p.next(); err != nil {
+		return nil, err
+	}
+	y, err := p.parseArith(false)
+	if err != nil {
+		return nil, err
+	}
+	if op != "=~" && op != "!~" {
+		return filterCompare{op: op, x: x, y: y}, nil
+	}
+	match := filterMatch{negate: op == "!~", x: x, y: y}
+	if literal, ok := y.(filterLiteral); ok {
+		pattern, ok := literal.value.(string)
+		if !ok {
+			return nil, p.errorf("the pattern of %s isn't a string", op)
+		}
+		if match.pattern, err = regexp.Compile(pattern); err != nil {
+			return nil, p.errorf("%v", err)
+		}
+	}
+	return match, nil
+}
+
+func (p *rowFilterParser) parseArith(mul bool) (rowFilterNode, error) {
+	ops, operand := []string{"+", "-"}, func() (rowFilterNode, error) { return p.parseArith(true) }
+	if mul {
+		ops, operand = []string{"*", "/"}, p.parseUnary
+	}
+	x, err := operand()
+	if err != nil {
+		return nil, err
+	}
+	for p.isOp(ops...) {
+		op := p.tok.text[0]
+		if err = p.next(); err != nil {
+			return nil, err
+		}
+		y, err := operand()
+		if err != nil {
+			return nil, err
+		}
+		x = filterArith{op: op, x: x, y: y}
+	}
+	return x, nil
+}
+
+func (p *rowFilterParser) parseUnary() (rowFilterNode, error) {
+	if !p.isOp("-") {
+		return p.parsePrimary()
+	}
+	if err := p.next(); err != nil {
+		return nil, err
+	}
+	x, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	return filterNeg{x: x}, nil
+}
+
+func (p *rowFilterParser) parsePrimary() (rowFilterNode, error) {
+	tok := p.tok
+	switch tok.kind {
+	case tokenNumber:
+		r, ok := new(big.Rat).SetString(tok.text)
+		if !ok {
+			return nil, p.errorf("invalid number %s", tok.text)
+		}
+		return filterLiteral{value: r}, p.next()
+	case tokenString:
+		return filterLiteral{value: tok.value}, p.next()
+	case tokenIdent:
+		if err := p.next(); err != nil {
+			return nil, err
+		}
+		if tok.text[0] != '`' {
+			if p.isOp("(") {
+				return p.parseCall(tok)
+			}
+			switch strings.ToLower(tok.value) {
+			case "null":
+				return filterLiteral{}, nil
+			case "true":
+				return filterLiteral{value: true}, nil
+			case "false":
+				return filterLiteral{value: false}, nil
+			}
+		}
+		return p.resolveColumn(tok)
+	case tokenOp:
+		if tok.text == "(" {
+			if err := p.next(); err != nil {
+				return nil, err
+			}
+			x, err := p.parseOr()
+			if err != nil {
+				return nil, err
+			}
+			if !p.isOp(")") {
+				return nil, p.errorf("expect ) but got %s", p.tok.text)
+			}
+			return x, p.next()
+		}
+	}
+	return nil, p.errorf("unexpected %s", tok.text)
+}
+
+func (p *rowFilterParser) parseCall(name rowFilterToken) (rowFilterNode, error) {
+	if err := p.next(); err != nil {
+		return nil, err
+	}
+	var args []rowFilterNode
+	for !p.isOp(")") {
+		if len(args) > 0 {
+			if !p.isOp(",") {
+				return nil, p.errorf("expect , or ) but got %s", p.tok.text)
+			}
+			if err := p.next(); err != nil {
+				return nil, err
+			}
+		}
+		arg, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		args = append(args, arg)
+	}
+	if err := p.next(); err != nil {
+		return nil, err
+	}
+	if fn, ok := p.funcs[name.value]; ok {
+		return filterCall{name: name.value, fn: fn, nullable: true, args: args}, nil
+	}
+	builtin, ok := rowFilterFuncs[strings.ToLower(name.value)]
+	if !ok {
+		p.tok.pos = name.pos
+		return nil, p.errorf("unknown function %s", name.value)
+	}
+	if len(args) < builtin.minArgs || len(args) > builtin.maxArgs {
+		p.tok.pos = name.pos
+		return nil, p.errorf("wrong number of arguments of %s: %d", name.value, len(args))
+	}
+	return filterCall{name: name.value, fn: builtin.fn, args: args}, nil
+}
+
+func (p *rowFilterParser) resolveColumn(tok rowFilterToken) (rowFilterNode, error) {
+	if p.columns == nil {
+		return filterColumn{index: -1}, nil
+	}
+	index := -1
+	for i, column := range p.columns {
+		if column == tok.value {
+			index = i
+			break
+		}
+		if index < 0 && strings.EqualFold(column, tok.value) {
+			index = i
+		}
+	}
+	if index < 0 {
+		p.tok.pos = tok.pos
+		return nil, p.errorf("unknown column %s", tok.value)
+	}
+	return filterColumn{index: index, number: index < len(p.colTypes) && isNumberType(p.colTypes[index])}, nil
+}
+
+// isNumberType returns whether the values of the column type are numbers.
+func isNumberType(colType string) bool {
+	for _, types := range [][]string{dataTypeNum, dataTypeNumPostgres} {
+		for _, t := range types {
+			if t == colType {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,182 @@
+package export
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"strings"
+
+	. "github.com/pingcap/check"
+)
+
+var _ = Suite(&testRowFilterSuite{})
+
+type testRowFilterSuite struct{}
+
+func (s *testRowFilterSuite) TestMatch(c *C) {
+	columns := []string{"id", "Name", "amount", "note", "order id"}
+	colTypes := []string{"INT", "VARCHAR", "DECIMAL", "TEXT", "BIGINT"}
+	row := []sql.RawBytes{sql.RawBytes("10"), sql.RawBytes(" Alice "), sql.RawBytes("12.50"), nil, sql.RawBytes("9007199254740993")}
+	cases := []struct {
+		expr     string
+		expected bool
+	}{
+		{"id == 10", true},
+		{"id > 9 && id < 11", true},
+		{"ID != 10", false},
+		{"name == ' Alice '", true},
+		{"lower(trim(name)) == \"alice\"", true},
+		{"name =~ '^ ?A'", true},
+		{"name !~ 'li'", false},
+		{"amount == 12.5", true},
+		{"amount * 2 >= 25 && amount / 0 == null", true},
+		{"amount + id - 22.5 == 0", true},
+		{"-amount < 0", true},
+		{"`order id` == 9007199254740993", true},
+		{"`order id` == 9007199254740992", false},
+		{"note == null", true},
+		{"note != null", false},
+		{"note == 'x' || note != 'x'", true},
+		// the other comparisons of NULL are NULL, which drops the row
+		{"note < 'x' || note >= 'x'", false},
+		{"note == 'x' || id == 10", true},
+		{"!(note < 'x') && id == 10", false},
+		{"id == '10'", true},
+		{"len(name) == 7 && substr(name, 2, 3) == 'Ali' && upper(substr(name, 5)) == 'CE '", true},
+		{"contains(name, 'lic') && starts_with(name, ' A') && ends_with(name, 'e ')", true},
+		{"len(note) == 7", false},
+		{"true && !false", true},
+	}
+	for _, t := range cases {
+		filter, err := parseRowFilter(t.expr, nil, columns, colTypes)
+		c.Assert(err, IsNil, Commentf("expr %s", t.expr))
+		keep, err := filter.match(row)
+		c.Assert(err, IsNil, Commentf("expr %s", t.expr))
+		c.Assert(keep, Equals, t.expected, Commentf("expr %s", t.expr))
+	}
+
+	for expr, msg := range map[string]string{
+		"name > 1":      `" Alice " isn't a number`,
+		"id == true":    `can't compare 10 with true`,
+		"id + 1":        `the row filter is evaluated to 11 instead of a boolean`,
+		"decrypt(name)": `call decrypt: bad key`,
+	} {
+		funcs := map[string]RowFilterFunc{"decrypt": func([]interface{}) (interface{}, error) {
+			return nil, errors.New("bad key")
+		}}
+		filter, err := parseRowFilter(expr, funcs, columns, colTypes)
+		c.Assert(err, IsNil, Commentf("expr %s", expr))
+		_, err = filter.match(row)
+		c.Assert(err, NotNil, Commentf("expr %s", expr))
+		c.Assert(err.Error(), Equals, msg)
+	}
+}
+
+func (s *testRowFilterSuite) TestFuncs(c *C) {
+	funcs := map[string]RowFilterFunc{
+		"decrypt": func(args []interface{}) (interface{}, error) {
+			if args[0] == nil {
+				return "none", nil
+			}
+			return strings.TrimPrefix(args[0].(string), "enc:"), nil
+		},
+	}
+	filter, err := parseRowFilter("decrypt(secret) == 'vip' || decrypt(secret) == 'none'", funcs, []string{"secret"}, []string{"BLOB"})
+	c.Assert(err, IsNil)
+	for value, expected := range map[string]bool{"enc:vip": true, "enc:other": false} {
+		keep, err := filter.match([]sql.RawBytes{sql.RawBytes(value)})
+		c.Assert(err, IsNil)
+		c.Assert(keep, Equals, expected)
+	}
+	keep, err := filter.match([]sql.RawBytes{nil})
+	c.Assert(err, IsNil)
+	c.Assert(keep, IsTrue)
+}
+
+func (s *testRowFilterSuite) TestParseError(c *C) {
+	cases := map[string]string{
+		"id ==":               "invalid row filter at 6: unexpected end",
+		"(id == 1":            "invalid row filter at 9: expect \\) but got end",
+		"id == 'a":            "invalid row filter at 7: unterminated '",
+		"id # 1":              "invalid row filter at 4: unexpected #",
+		"missing == 1":        "invalid row filter at 1: unknown column missing",
+		"foo(id)":             "invalid row filter at 1: unknown function foo",
+		"len(id, 1) == 1":     "invalid row filter at 1: wrong number of arguments of len: 2",
+		"id =~ '('":           "invalid row filter at 10: error parsing regexp: .*",
+		"id == 1 id":          "invalid row filter at 9: unexpected id",
+		"id == 1.2.3":         "invalid row filter at 7: invalid number 1.2.3",
+		"substr(id 1) == '1'": "invalid row filter at 11: expect , or \\) but got 1",
+	}
+	for expr, msg := range cases {
+		_, err := parseRowFilter(expr, nil, []string{"id"}, []string{"INT"})
+		c.Assert(err, ErrorMatches, msg, Commentf("expr %s", expr))
+	}
+	// the columns are only checked with the columns of a table
+	_, err := parseRowFilter("missing == 1", nil, nil, nil)
+	c.Assert(err, IsNil)
+}
+
+func (s *testRowFilterSuite) TestWriteFilteredRows(c *C) {
+	data := [][]driver.Value{
+		{"1", "bob", "enc:vip"},
+		{"2", "sarah", "enc:guest"},
+		{"3", "john", nil},
+		{"4", "alice", "enc:vip"},
+	}
+	ir := &mockTableIR{
+		dbName:        "test",
+		tblName:       "users",
+		data:          data,
+		selectedField: "*",
+		colTypes:      []string{"INT", "VARCHAR", "BLOB"},
+		colNames:      []string{"id", "name", "secret"},
+	}
+	conf := DefaultConfig()
+	conf.RowFilter = "id != 1 && decrypt(secret) == 'vip'"
+	conf.RowFilterFuncs = map[string]RowFilterFunc{
+		"decrypt": func(args []interface{}) (interface{}, error) {
+			if args[0] == nil {
+				return nil, nil
+			}
+			return strings.TrimPrefix(args[0].(string), "enc:"), nil
+		},
+	}
+	filtered, filteredIR, err := withRowFilter(conf, ir)
+	c.Assert(err, IsNil)
+	bf := &bytes.Buffer{}
+	c.Assert(WriteInsert(context.Background(), filteredIR, bf, UnspecifiedSize, nil), IsNil)
+	c.Assert(filtered.err, IsNil)
+	c.Assert(bf.String(), Equals, "INSERT INTO `users` VALUES\n(4,'alice',x'656e633a766970');\n")
+
+	// all the rows are dropped
+	conf.RowFilter = "id > 4"
+	_, filteredIR, err = withRowFilter(conf, ir)
+	c.Assert(err, IsNil)
+	bf.Reset()
+	c.Assert(WriteInsert(context.Background(), filteredIR, bf, UnspecifiedSize, nil), IsNil)
+	c.Assert(bf.String(), Equals, "")
+
+	// the evaluation error stops the rows
+	conf.RowFilter = "name > 1"
+	filtered, filteredIR, err = withRowFilter(conf, ir)
+	c.Assert(err, IsNil)
+	c.Assert(WriteInsert(context.Background(), filteredIR, bf, UnspecifiedSize, nil), IsNil)
+	c.Assert(filtered.err, ErrorMatches, `"bob" isn't a number`)
+
+	conf.RowFilter = "age > 1"
+	_, _, err = withRowFilter(conf, ir)
+	c.Assert(err, ErrorMatches, "row filter of `test`.`users`: invalid row filter at 1: unknown column age")
+}
+
+func (s *testRowFilterSuite) TestValidate(c *C) {
+	conf := DefaultConfig()
+	conf.RowFilter = "id > 1"
+	c.Assert(conf.Validate(), IsNil)
+	conf.TableConfigs = []TableConfig{{Database: "test", Table: "t", Rows: UnspecifiedSize, RowFilter: "id >"}}
+	c.Assert(conf.Validate(), ErrorMatches, "(?s).*invalid row filter at 5: unexpected end of `test`.`t`.*")
+	conf.TableConfigs = nil
+	conf.ServerOutfileDir = "/var/lib/mysql-files"
+	c.Assert(conf.Validate(), ErrorMatches, "(?s).*row filter is not supported with server-outfile-dir.*")
+}
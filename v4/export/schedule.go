@@ -0,0 +1,263 @@
+package export
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pingcap/errors"
+	"github.com/robfig/cron/v3"
+	"go.uber.org/zap"
+
+	"github.com/pingcap/dumpling/v4/log"
+)
+
+const (
+	// ScheduleOverlapSkip skips the run if the previous run is still going.
+	ScheduleOverlapSkip = "skip"
+	// ScheduleOverlapQueue runs once after the previous run is finished, if
+	// any runs were due while it was going.
+	ScheduleOverlapQueue = "queue"
+
+	// scheduleTimeLayout is the suffix of the output directories of the runs.
+	scheduleTimeLayout = "20060102-150405"
+)
+
+// ScheduleRequest is the body of adding a schedule to a Daemon.
+type ScheduleRequest struct {
+	// Schedule is a cron expression of 5 fields, or a descriptor like @daily.
+	Schedule string `json:"schedule"`
+	// Overlap is ScheduleOverlapSkip or ScheduleOverlapQueue, it's skip if empty.
+	Overlap string `json:"overlap"`
+	// Job is the job of every run. The runs are dumped into the directories
+	// of its Name, or "schedule-<id>", suffixed with the time of the run.
+	Job JobRequest `json:"job"`
+}
+
+// DaemonScheduleStatus is the response of the schedule APIs of a Daemon.
+type DaemonScheduleStatus struct {
+	ID       string     `json:"id"`
+	Schedule string     `json:"schedule"`
+	Overlap  string     `json:"overlap"`
+	Job      JobRequest `json:"job"`
+	NextTime time.Time  `json:"next_time"`
+	// Runs is the IDs of the jobs submitted by the schedule.
+	Runs []string `json:"runs"`
+	// Skipped is the number of the runs skipped by the overlap protection.
+	Skipped int `json:"skipped"`
+	// Pending is whether a run is queued after the previous run.
+	Pending bool `json:"pending"`
+}
+
+type daemonSchedule struct {
+	cron   cron.Schedule
+	stopCh chan struct{}
+
+	mu     sync.Mutex
+	status DaemonScheduleStatus
+	last   *daemonJob
+}
+
+func (s *daemonSchedule) snapshot() DaemonScheduleStatus {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	status := s.status
+	status.Runs = append([]string(nil), s.status.Runs...)
+	return status
+}
+
+// AddSchedule adds a schedule of req, it's started when the Daemon is running.
+func (d *Daemon) AddSchedule(req ScheduleRequest) (DaemonScheduleStatus, error) {
+	schedule, err := cron.ParseStandard(req.Schedule)
+	if err != nil {
+		return DaemonScheduleStatus{}, errors.Errorf("invalid schedule %s: %s", req.Schedule, err)
+	}
+	switch req.Overlap {
+	case "":
+		req.Overlap = ScheduleOverlapSkip
+	case ScheduleOverlapSkip, ScheduleOverlapQueue:
+	default:
+		return DaemonScheduleStatus{}, errors.Errorf("invalid schedule overlap %s", req.Overlap)
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	id := strconv.Itoa(d.nextScheduleID)
+	if req.Job.Name == "" {
+		req.Job.Name = "schedule-" + id
+	}
+	// validate the job before it's run
+	if _, err = d.jobConfig(req.Job, req.Job.Name); err != nil {
+		return DaemonScheduleStatus{}, err
+	}
+	sched := &daemonSchedule{
+		cron:   schedule,
+		stopCh: make(chan struct{}),
+		status: DaemonScheduleStatus{
+			ID:       id,
+			Schedule: req.Schedule,
+			Overlap:  req.Overlap,
+			Job:      req.Job,
+			NextTime: schedule.Next(time.Now()),
+		},
+	}
+	status := sched.status
+	d.nextScheduleID++
+	d.schedules = append(d.schedules, sched)
+	d.scheduleByID[id] = sched
+	if d.ctx != nil {
+		go d.runSchedule(d.ctx, sched)
+	}
+	log.Info("add dump schedule", zap.String("id", id), zap.String("schedule", req.Schedule),
+		zap.String("overlap", req.Overlap))
+	return status, nil
+}
+
+// removeSchedule stops and removes the schedule, the submitted jobs are kept.
+func (d *Daemon) removeSchedule(sched *daemonSchedule) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if _, ok := d.scheduleByID[sched.status.ID]; !ok {
+		return
+	}
+	delete(d.scheduleByID, sched.status.ID)
+	for i, s := range d.schedules {
+		if s == sched {
+			d.schedules = append(d.schedules[:i], d.schedules[i+1:]...)
+			break
+		}
+	}
+	close(sched.stopCh)
+}
+
+func (d *Daemon) runSchedule(ctx context.Context, sched *daemonSchedule) {
+	for {
+		next := sched.cron.Next(time.Now())
+		sched.mu.Lock()
+		sched.status.NextTime = next
+		sched.mu.Unlock()
+
+		timer := time.NewTimer(time.Until(next))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-sched.stopCh:
+			timer.Stop()
+			return
+		case now := <-timer.C:
+			d.triggerSchedule(sched, now)
+		}
+	}
+}
+
+// triggerSchedule submits a run of the schedule at now, unless the previous
+// run is still going.
+func (d *Daemon) triggerSchedule(sched *daemonSchedule, now time.Time) {
+	sched.mu.Lock()
+	defer sched.mu.Unlock()
+	if sched.last != nil && sched.last.active() {
+		if sched.status.Overlap == ScheduleOverlapSkip {
+			sched.status.Skipped++
+			log.Warn("skip the scheduled dump, the previous run is still going",
+				zap.String("schedule", sched.status.ID), zap.String("previous", sched.last.status.ID))
+		} else {
+			sched.status.Pending = true
+		}
+		return
+	}
+	d.submitScheduled(sched, now)
+}
+
+// submitScheduled submits a run of the locked schedule.
+func (d *Daemon) submitScheduled(sched *daemonSchedule, now time.Time) {
+	name := sched.status.Job.Name + "-" + now.Format(scheduleTimeLayout)
+	job, err := d.submitJob(sched.status.Job, name, sched)
+	if err != nil {
+		log.Error("submit the scheduled dump failed", zap.String("schedule", sched.status.ID), zap.Error(err))
+		return
+	}
+	sched.last = job
+	sched.status.Runs = append(sched.status.Runs, job.status.ID)
+	// the runs beyond the kept jobs are pruned with them
+	if extra := len(sched.status.Runs) - d.maxFinishedJobs; extra > 0 {
+		sched.status.Runs = append([]string(nil), sched.status.Runs[extra:]...)
+	}
+}
+
+// finishScheduledJob submits the pending run of the schedule of job.
+func (d *Daemon) finishScheduledJob(job *daemonJob) {
+	sched := job.schedule
+	if sched == nil {
+		return
+	}
+	sched.mu.Lock()
+	defer sched.mu.Unlock()
+	if sched.last != job || !sched.status.Pending {
+		return
+	}
+	sched.status.Pending = false
+	select {
+	case <-sched.stopCh:
+		return
+	default:
+	}
+	d.submitScheduled(sched, time.Now())
+}
+
+// handleSchedules lists the schedules on GET, or adds a schedule on POST.
+func (d *Daemon) handleSchedules(w http.ResponseWriter, req *http.Request) {
+	switch req.Method {
+	case http.MethodGet:
+		d.mu.Lock()
+		schedules := append([]*daemonSchedule(nil), d.schedules...)
+		d.mu.Unlock()
+		statuses := make([]DaemonScheduleStatus, 0, len(schedules))
+		for _, sched := range schedules {
+			statuses = append(statuses, sched.snapshot())
+		}
+		writeJSON(w, http.StatusOK, statuses)
+	case http.MethodPost:
+		var schedReq ScheduleRequest
+		if err := json.NewDecoder(req.Body).Decode(&schedReq); err != nil {
+			http.Error(w, fmt.Sprintf("invalid schedule request: %s", err), http.StatusBadRequest)
+			return
+		}
+		status, err := d.AddSchedule(schedReq)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		writeJSON(w, http.StatusCreated, status)
+	default:
+		w.Header().Set("Allow", "GET, POST")
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// handleSchedule serves GET and DELETE /schedules/{id}.
+func (d *Daemon) handleSchedule(w http.ResponseWriter, req *http.Request) {
+	id := strings.TrimPrefix(req.URL.Path, "/schedules/")
+	d.mu.Lock()
+	sched := d.scheduleByID[id]
+	d.mu.Unlock()
+	if sched == nil {
+		http.NotFound(w, req)
+		return
+	}
+	switch req.Method {
+	case http.MethodGet:
+		writeJSON(w, http.StatusOK, sched.snapshot())
+	case http.MethodDelete:
+		d.removeSchedule(sched)
+		writeJSON(w, http.StatusOK, sched.snapshot())
+	default:
+		w.Header().Set("Allow", "GET, DELETE")
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+	}
+}
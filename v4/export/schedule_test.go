@@ -0,0 +1,94 @@
+package export
+
+import (
+	"context"
+	"net/http"
+	"path/filepath"
+	"time"
+
+	. "github.com/pingcap/check"
+)
+
+var _ = Suite(&testScheduleSuite{})
+
+type testScheduleSuite struct{}
+
+func (s *testScheduleSuite) TestAddSchedule(c *C) {
+	d := NewDaemon(DefaultConfig(), 1)
+	_, err := d.AddSchedule(ScheduleRequest{Schedule: "nightly"})
+	c.Assert(err, ErrorMatches, "invalid schedule nightly: .*")
+	_, err = d.AddSchedule(ScheduleRequest{Schedule: "@daily", Overlap: "cancel"})
+	c.Assert(err, ErrorMatches, "invalid schedule overlap cancel")
+	_, err = d.AddSchedule(ScheduleRequest{Schedule: "@daily", Job: JobRequest{Threads: -1}})
+	c.Assert(err, ErrorMatches, "threads should be positive, got -1")
+
+	status, err := d.AddSchedule(ScheduleRequest{Schedule: "0 2 * * *"})
+	c.Assert(err, IsNil)
+	c.Assert(status.ID, Equals, "1")
+	c.Assert(status.Overlap, Equals, ScheduleOverlapSkip)
+	c.Assert(status.Job.Name, Equals, "schedule-1")
+	c.Assert(status.NextTime.Hour(), Equals, 2)
+
+	var statuses []DaemonScheduleStatus
+	ts := &testDaemonSuite{}
+	c.Assert(ts.request(c, d, http.MethodPost, "/schedules", `{"schedule":"@hourly","job":{"name":"hourly"}}`, &status), Equals, http.StatusCreated)
+	c.Assert(status.ID, Equals, "2")
+	c.Assert(ts.request(c, d, http.MethodPost, "/schedules", `{"schedule":"@never"}`, nil), Equals, http.StatusBadRequest)
+	c.Assert(ts.request(c, d, http.MethodGet, "/schedules", "", &statuses), Equals, http.StatusOK)
+	c.Assert(statuses, HasLen, 2)
+	c.Assert(ts.request(c, d, http.MethodGet, "/schedules/2", "", &status), Equals, http.StatusOK)
+	c.Assert(status.Job.Name, Equals, "hourly")
+	c.Assert(ts.request(c, d, http.MethodDelete, "/schedules/2", "", &status), Equals, http.StatusOK)
+	c.Assert(ts.request(c, d, http.MethodGet, "/schedules/2", "", nil), Equals, http.StatusNotFound)
+	c.Assert(ts.request(c, d, http.MethodGet, "/schedules", "", &statuses), Equals, http.StatusOK)
+	c.Assert(statuses, HasLen, 1)
+}
+
+func (s *testScheduleSuite) TestOverlapSkip(c *C) {
+	base := DefaultConfig()
+	base.OutputDirPath = "/backup"
+	d := NewDaemon(base, 1)
+	_, err := d.AddSchedule(ScheduleRequest{Schedule: "@daily", Job: JobRequest{Name: "nightly"}})
+	c.Assert(err, IsNil)
+	sched := d.scheduleByID["1"]
+
+	now := time.Date(2020, 10, 1, 0, 0, 0, 0, time.Local)
+	d.triggerSchedule(sched, now)
+	d.triggerSchedule(sched, now.Add(24*time.Hour))
+	status := sched.snapshot()
+	c.Assert(status.Runs, DeepEquals, []string{"1"})
+	c.Assert(status.Skipped, Equals, 1)
+	job := d.byID["1"].snapshot()
+	c.Assert(job.Schedule, Equals, "1")
+	c.Assert(job.OutputDir, Equals, filepath.Join("/backup", "nightly-20201001-000000"))
+
+	// the next run is submitted after the previous one is stopped
+	c.Assert(d.stop(d.byID["1"]), IsTrue)
+	d.triggerSchedule(sched, now.Add(48*time.Hour))
+	c.Assert(sched.snapshot().Runs, DeepEquals, []string{"1", "2"})
+}
+
+func (s *testScheduleSuite) TestOverlapQueue(c *C) {
+	d := NewDaemon(DefaultConfig(), 1)
+	_, err := d.AddSchedule(ScheduleRequest{Schedule: "@hourly", Overlap: ScheduleOverlapQueue})
+	c.Assert(err, IsNil)
+	sched := d.scheduleByID["1"]
+
+	now := time.Date(2020, 10, 1, 0, 0, 0, 0, time.Local)
+	d.triggerSchedule(sched, now)
+	d.triggerSchedule(sched, now.Add(time.Hour))
+	d.triggerSchedule(sched, now.Add(2*time.Hour))
+	status := sched.snapshot()
+	c.Assert(status.Runs, DeepEquals, []string{"1"})
+	c.Assert(status.Pending, IsTrue)
+
+	// the runs due while the previous one is going are run once after it
+	job := <-d.queue
+	job.conf.Threads = 0
+	d.runJob(context.Background(), job)
+	c.Assert(job.snapshot().State, Equals, DaemonJobFailed)
+	status = sched.snapshot()
+	c.Assert(status.Runs, DeepEquals, []string{"1", "2"})
+	c.Assert(status.Pending, IsFalse)
+	c.Assert(d.queue, HasLen, 1)
+}
@@ -0,0 +1,157 @@
+package export
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/pingcap/errors"
+	"go.uber.org/zap"
+
+	"github.com/pingcap/dumpling/v4/log"
+)
+
+// schemaDedupFile is the name of the mapping of the deduplicated schema files
+// in the output directory.
+const schemaDedupFile = "schema-dedup.json"
+
+// SchemaDedup maps the tables without their own schema files, since they're
+// dumped with DedupSchemas, to the canonical schema files of the tables
+// identical to them.
+type SchemaDedup struct {
+	Tables []DedupedSchema `json:"tables"`
+}
+
+// DedupedSchema is a table whose schema is the CREATE statement of File
+// renamed from CanonicalDatabase.CanonicalTable.
+type DedupedSchema struct {
+	Database string `json:"database"`
+	Table    string `json:"table"`
+	// File is the canonical schema file, relative to the output directory.
+	File              string `json:"file"`
+	CanonicalDatabase string `json:"canonical_database"`
+	CanonicalTable    string `json:"canonical_table"`
+}
+
+// schemaDedupConflicts returns the options conflicting with DedupSchemas.
+func schemaDedupConflicts(conf *Config) []string {
+	if !conf.DedupSchemas {
+		return nil
+	}
+	var conflicts []string
+	switch strings.ToLower(conf.FileType) {
+	case "sql", "csv", "tsv":
+	default:
+		conflicts = append(conflicts, "dedup-schemas is only supported with filetype sql, csv and tsv")
+	}
+	if conf.TargetDSN != "" || conf.NoSchemas || conf.Append {
+		conflicts = append(conflicts, "dedup-schemas is not supported with target-dsn, no-schemas or append")
+	}
+	return conflicts
+}
+
+// schemaDedupRecorder collects the tables identical to the tables whose
+// schema files are written before, it does nothing if it's nil.
+type schemaDedupRecorder struct {
+	mu sync.Mutex
+	// canonical maps the normalized CREATE statements to the first tables
+	// written with them
+	canonical map[string]DedupedSchema
+	deduped   []DedupedSchema
+}
+
+func newSchemaDedupRecorder() *schemaDedupRecorder {
+	return &schemaDedupRecorder{canonical: map[string]DedupedSchema{}}
+}
+
+// dedup returns whether the schema file of db.table is skipped, since a
+// table with the same CREATE statement except the name and AUTO_INCREMENT is
+// written into its schema file before. Otherwise the table is recorded to be
+// canonical, whose schema is written into fileName.
+func (r *schemaDedupRecorder) dedup(conf *Config, db, table, fileName, createSQL string) bool {
+	if r == nil {
+		return false
+	}
+	key := normalizeCreateSQL(renameCreateStatement(conf, createSQL, table, ""))
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	canonical, ok := r.canonical[key]
+	if !ok {
+		r.canonical[key] = DedupedSchema{File: fileName, CanonicalDatabase: db, CanonicalTable: table}
+		return false
+	}
+	canonical.Database, canonical.Table = db, table
+	r.deduped = append(r.deduped, canonical)
+	log.Debug("skip the schema identical to a written one",
+		zap.String("database", db), zap.String("table", table), zap.String("file", canonical.File))
+	return true
+}
+
+// write writes the mapping of the deduplicated tables into schemaDedupFile
+// of storage.
+func (r *schemaDedupRecorder) write(conf *Config, storage ExternalStorage) error {
+	if r == nil {
+		return nil
+	}
+	r.mu.Lock()
+	dedup := SchemaDedup{Tables: append([]DedupedSchema{}, r.deduped...)}
+	r.mu.Unlock()
+	sort.Slice(dedup.Tables, func(i, j int) bool {
+		if dedup.Tables[i].Database != dedup.Tables[j].Database {
+			return dedup.Tables[i].Database < dedup.Tables[j].Database
+		}
+		return dedup.Tables[i].Table < dedup.Tables[j].Table
+	})
+	content, err := json.MarshalIndent(&dedup, "", "  ")
+	if err != nil {
+		return withStack(err)
+	}
+	// write the mapping even if the dump is canceled
+	fileWriter, err := storage.Create(context.Background(), schemaDedupFile)
+	if err != nil {
+		return err
+	}
+	if err = closeFile(fileWriter, write(fileWriter, string(content))); err != nil {
+		return err
+	}
+	log.Info("write schema dedup", zap.String("file", conf.outputPath(context.Background(), schemaDedupFile)),
+		zap.Int("deduped", len(dedup.Tables)))
+	return nil
+}
+
+// ReadSchemaDedup reads the mapping of the deduplicated schema files of the
+// dump in dir, it's empty if the dump isn't dumped with DedupSchemas.
+func ReadSchemaDedup(dir string) (*SchemaDedup, error) {
+	path := filepath.Join(dir, schemaDedupFile)
+	content, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return &SchemaDedup{}, nil
+	} else if err != nil {
+		return nil, withStack(withKind(ErrorKindConfig, err))
+	}
+	dedup := &SchemaDedup{}
+	if err = json.Unmarshal(content, dedup); err != nil {
+		return nil, withStack(withKind(ErrorKindConfig, errors.WithMessage(err, path)))
+	}
+	return dedup, nil
+}
+
+// byFile returns the deduplicated tables of the canonical schema files.
+func (d *SchemaDedup) byFile() map[string][]DedupedSchema {
+	tables := map[string][]DedupedSchema{}
+	for _, table := range d.Tables {
+		tables[table.File] = append(tables[table.File], table)
+	}
+	return tables
+}
+
+// createStatement returns the CREATE statement of the deduplicated table,
+// from the statement of its canonical schema file.
+func (t DedupedSchema) createStatement(canonicalSQL string) string {
+	return renameCreateStatement(DefaultConfig(), canonicalSQL, t.CanonicalTable, t.Table)
+}
@@ -0,0 +1,151 @@
+package export
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	. "github.com/pingcap/check"
+)
+
+var _ = Suite(&testSchemaDedupSuite{})
+
+type testSchemaDedupSuite struct{}
+
+func shardCreateTable(table string, autoIncrement int) string {
+	return fmt.Sprintf("CREATE TABLE `%s` (\n  `id` int NOT NULL AUTO_INCREMENT,\n  PRIMARY KEY (`id`)\n) ENGINE=InnoDB AUTO_INCREMENT=%d", table, autoIncrement)
+}
+
+func (s *testSchemaDedupSuite) TestDedupSchemas(c *C) {
+	conf := DefaultConfig()
+	storage := newMemStorage()
+	conf.ExternalStorage = storage
+	conf.schemaDedup = newSchemaDedupRecorder()
+	writer, err := NewSimpleWriter(conf)
+	c.Assert(err, IsNil)
+	ctx := context.Background()
+	c.Assert(writer.WriteTableMeta(ctx, "shop_0", "orders", shardCreateTable("orders", 5)), IsNil)
+	c.Assert(writer.WriteTableMeta(ctx, "shop_1", "orders", shardCreateTable("orders", 9)), IsNil)
+	c.Assert(writer.WriteTableMeta(ctx, "shop_0", "orders_1", shardCreateTable("orders_1", 1)), IsNil)
+	c.Assert(writer.WriteTableMeta(ctx, "shop_0", "items", "CREATE TABLE `items` (`id` int)"), IsNil)
+	c.Assert(conf.schemaDedup.write(conf, storage), IsNil)
+
+	c.Assert(storage.files["shop_0.orders-schema.sql"], Equals, shardCreateTable("orders", 5)+";\n")
+	c.Assert(storage.files["shop_0.items-schema.sql"], Equals, "CREATE TABLE `items` (`id` int);\n")
+	_, ok := storage.files["shop_1.orders-schema.sql"]
+	c.Assert(ok, IsFalse)
+	_, ok = storage.files["shop_0.orders_1-schema.sql"]
+	c.Assert(ok, IsFalse)
+	c.Assert(storage.files[schemaDedupFile], Equals, `{
+  "tables": [
+    {
+      "database": "shop_0",
+      "table": "orders_1",
+      "file": "shop_0.orders-schema.sql",
+      "canonical_database": "shop_0",
+      "canonical_table": "orders"
+    },
+    {
+      "database": "shop_1",
+      "table": "orders",
+      "file": "shop_0.orders-schema.sql",
+      "canonical_database": "shop_0",
+      "canonical_table": "orders"
+    }
+  ]
+}`)
+
+	// the schemas aren't deduplicated by default
+	conf = DefaultConfig()
+	storage = newMemStorage()
+	conf.ExternalStorage = storage
+	writer, err = NewSimpleWriter(conf)
+	c.Assert(err, IsNil)
+	c.Assert(writer.WriteTableMeta(ctx, "shop_0", "orders", shardCreateTable("orders", 5)), IsNil)
+	c.Assert(writer.WriteTableMeta(ctx, "shop_1", "orders", shardCreateTable("orders", 5)), IsNil)
+	c.Assert(storage.files, HasLen, 2)
+	c.Assert(conf.schemaDedup.write(conf, storage), IsNil)
+	c.Assert(storage.files, HasLen, 2)
+}
+
+func (s *testSchemaDedupSuite) TestReadDedupedSchemas(c *C) {
+	dir := writeDumpFiles(c, map[string]string{
+		"shop_0-schema-create.sql": "/*!40101 SET NAMES binary*/;\nCREATE DATABASE `shop_0`;\n",
+		"shop_1-schema-create.sql": "/*!40101 SET NAMES binary*/;\nCREATE DATABASE `shop_1`;\n",
+		"shop_0.orders-schema.sql": "/*!40101 SET NAMES binary*/;\nCREATE TABLE `orders` (`id` int);\n",
+		"shop_0.v-schema.sql":      "/*!40101 SET NAMES binary*/;\nCREATE VIEW `v` AS SELECT 1;\n",
+		"shop_1.orders.0.sql":      "INSERT INTO `orders` VALUES\n(1);\n",
+		schemaDedupFile: `{"tables": [
+			{"database": "shop_1", "table": "orders", "file": "shop_0.orders-schema.sql", "canonical_database": "shop_0", "canonical_table": "orders"},
+			{"database": "shop_1", "table": "v", "file": "shop_0.v-schema.sql", "canonical_database": "shop_0", "canonical_table": "v"}
+		]}`,
+	})
+
+	conf := DefaultConfig()
+	conf.NoViews = false
+	schemas, err := ReadSchemas(conf, dir)
+	c.Assert(err, IsNil)
+	c.Assert(schemas, DeepEquals, SchemaSet{
+		"`shop_0`":          "CREATE DATABASE `shop_0`",
+		"`shop_1`":          "CREATE DATABASE `shop_1`",
+		"`shop_0`.`orders`": "CREATE TABLE `orders` (`id` int)",
+		"`shop_0`.`v`":      "CREATE VIEW `v` AS SELECT 1",
+		"`shop_1`.`orders`": "CREATE TABLE `orders` (`id` int)",
+		"`shop_1`.`v`":      "CREATE VIEW `v` AS SELECT 1",
+	})
+	// the deduplicated tables are read without their canonical ones
+	conf.Database = "shop_1"
+	conf.NoViews = true
+	schemas, err = ReadSchemas(conf, dir)
+	c.Assert(err, IsNil)
+	c.Assert(schemas, DeepEquals, SchemaSet{
+		"`shop_1`":          "CREATE DATABASE `shop_1`",
+		"`shop_1`.`orders`": "CREATE TABLE `orders` (`id` int)",
+	})
+
+	// the deduplicated tables are created after their canonical schema files are loaded
+	db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+	c.Assert(err, IsNil)
+	defer db.Close()
+	result := sqlmock.NewResult(0, 0)
+	for _, dbName := range []string{"shop_0", "shop_1"} {
+		mock.ExpectExec("SET SESSION FOREIGN_KEY_CHECKS = 0").WillReturnResult(result)
+		mock.ExpectExec("/*!40101 SET NAMES binary*/").WillReturnResult(result)
+		mock.ExpectExec("CREATE DATABASE " + wrapBackTicks(dbName)).WillReturnResult(result)
+	}
+	mock.ExpectExec("SET SESSION FOREIGN_KEY_CHECKS = 0").WillReturnResult(result)
+	mock.ExpectExec("USE `shop_0`").WillReturnResult(result)
+	mock.ExpectExec("/*!40101 SET NAMES binary*/").WillReturnResult(result)
+	mock.ExpectExec("CREATE TABLE `orders` (`id` int)").WillReturnResult(result)
+	mock.ExpectExec("SET SESSION FOREIGN_KEY_CHECKS = 0").WillReturnResult(result)
+	mock.ExpectExec("USE `shop_1`").WillReturnResult(result)
+	mock.ExpectExec("CREATE TABLE `orders` (`id` int)").WillReturnResult(result)
+	mock.ExpectExec("SET SESSION FOREIGN_KEY_CHECKS = 0").WillReturnResult(result)
+	mock.ExpectExec("USE `shop_1`").WillReturnResult(result)
+	mock.ExpectExec("INSERT INTO `orders` VALUES\n(1)").WillReturnResult(result)
+	mock.ExpectExec("SET SESSION FOREIGN_KEY_CHECKS = 0").WillReturnResult(result)
+	mock.ExpectExec("USE `shop_0`").WillReturnResult(result)
+	mock.ExpectExec("/*!40101 SET NAMES binary*/").WillReturnResult(result)
+	mock.ExpectExec("CREATE VIEW `v` AS SELECT 1").WillReturnResult(result)
+	mock.ExpectExec("SET SESSION FOREIGN_KEY_CHECKS = 0").WillReturnResult(result)
+	mock.ExpectExec("USE `shop_1`").WillReturnResult(result)
+	mock.ExpectExec("CREATE VIEW `v` AS SELECT 1").WillReturnResult(result)
+
+	plan, err := loadPlan(dir)
+	c.Assert(err, IsNil)
+	c.Assert(load(context.Background(), &LoadConfig{Dir: dir, Threads: 1}, db, plan), IsNil)
+	c.Assert(mock.ExpectationsWereMet(), IsNil)
+}
+
+func (s *testSchemaDedupSuite) TestValidate(c *C) {
+	conf := DefaultConfig()
+	conf.DedupSchemas = true
+	c.Assert(conf.Validate(), IsNil)
+	conf.FileType = "csv"
+	c.Assert(conf.Validate(), IsNil)
+	conf.FileType = "sqlite"
+	c.Assert(conf.Validate(), ErrorMatches, "(?s).*dedup-schemas is only supported with filetype sql, csv and tsv.*")
+	conf.FileType = "sql"
+	conf.NoSchemas = true
+	c.Assert(conf.Validate(), ErrorMatches, "(?s).*dedup-schemas is not supported with target-dsn, no-schemas or append.*")
+}
@@ -0,0 +1,410 @@
+package export
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"go.uber.org/zap"
+
+	"github.com/pingcap/dumpling/v4/log"
+)
+
+// SchemaSet is the CREATE statements of the databases, tables and views, keyed
+// by their quoted names like `db` and `db`.`table`.
+type SchemaSet map[string]string
+
+// FetchSchemas reads the CREATE statements of the databases, tables and views
+// of the server of dsn filtered by conf, the same as they are dumped. The
+// filtered tables are left in conf.Tables.
+func FetchSchemas(ctx context.Context, conf *Config, dsn string) (SchemaSet, error) {
+	pool, _, err := openConnPool(conf, dsn)
+	if err != nil {
+		return nil, withStack(withKind(ErrorKindConnection, err))
+	}
+	defer pool.Close()
+	if conf.ServerInfo, err = detectServerInfo(pool); err != nil {
+		// it's the first query to the server
+		return nil, withKind(ErrorKindConnection, err)
+	}
+
+	databases, err := prepareDumpingDatabases(conf, pool)
+	if err != nil {
+		return nil, withKind(ErrorKindSchema, err)
+	}
+	if conf.Tables, err = listAllTables(conf.dialect(), pool, databases); err != nil {
+		return nil, withKind(ErrorKindSchema, err)
+	}
+	if !conf.NoViews {
+		views, err := listAllViews(conf.dialect(), pool, databases)
+		if err != nil {
+			return nil, withKind(ErrorKindSchema, err)
+		}
+		conf.Tables.Merge(views)
+	}
+	if err = filterTables(conf); err != nil {
+		return nil, err
+	}
+
+	schemas := SchemaSet{}
+	for dbName, tables := range conf.Tables {
+		if err = ctx.Err(); err != nil {
+			return nil, err
+		}
+		createSQL, err := showCreateDatabase(conf, pool, dbName)
+		if err != nil {
+			return nil, withKind(ErrorKindSchema, err)
+		}
+		schemas[wrapBackTicks(dbName)] = createSQL
+		for _, table := range tables {
+			if table.Type == TableTypeView {
+				if conf.translatesDDL() {
+					continue
+				}
+				createSQL, err = conf.dialect().ShowCreateView(pool, dbName, table.Name)
+			} else {
+				createSQL, err = showCreateTable(conf, pool, dbName, table.Name)
+			}
+			if err != nil {
+				return nil, withKind(ErrorKindSchema, err)
+			}
+			schemas[qualifiedTableName(dbName, table.Name)] = createSQL
+		}
+	}
+	log.Info("fetch schemas finished", zap.Int("schemas", len(schemas)))
+	return schemas, nil
+}
+
+// ReadSchemas reads the CREATE statements of a dump in the local directory
+// dir, from its `{db}-schema-create.sql` and `{db}.{table}-schema.sql` files,
+// and the tables mapped to the canonical schema files by schemaDedupFile.
+// Only the databases of conf.Database are read if it's set, and the views are
+// skipped by conf.NoViews.
+func ReadSchemas(conf *Config, dir string) (SchemaSet, error) {
+	var onlyDatabases map[string]bool
+	if conf.Database != "" {
+		onlyDatabases = map[string]bool{}
+		for _, dbName := range strings.Split(conf.Database, ",") {
+			onlyDatabases[dbName] = true
+		}
+	}
+
+	schemas := SchemaSet{}
+	databaseFiles, err := filepath.Glob(filepath.Join(dir, "*-schema-create.sql"))
+	if err != nil {
+		return nil, withStack(err)
+	}
+	var databases []string
+	for _, file := range databaseFiles {
+		dbName := strings.TrimSuffix(filepath.Base(file), "-schema-create.sql")
+		databases = append(databases, dbName)
+		if onlyDatabases != nil && !onlyDatabases[dbName] {
+			continue
+		}
+		if schemas[wrapBackTicks(dbName)], err = readSchemaFile(file); err != nil {
+			return nil, err
+		}
+	}
+	// the longer names are matched first, for the databases named with dots
+	sort.Slice(databases, func(i, j int) bool {
+		return len(databases[i]) > len(databases[j])
+	})
+
+	tableFiles, err := filepath.Glob(filepath.Join(dir, "*-schema.sql"))
+	if err != nil {
+		return nil, withStack(err)
+	}
+	for _, file := range tableFiles {
+		name := strings.TrimSuffix(filepath.Base(file), "-schema.sql")
+		dbName, tableName := splitSchemaFileName(name, databases)
+		if tableName == "" {
+			log.Warn("skip the schema file without the table name", zap.String("file", file))
+			continue
+		}
+		if onlyDatabases != nil && !onlyDatabases[dbName] {
+			continue
+		}
+		createSQL, err := readSchemaFile(file)
+		if err != nil {
+			return nil, err
+		}
+		if conf.NoViews && !strings.HasPrefix(strings.ToUpper(createSQL), "CREATE TABLE") {
+			continue
+		}
+		schemas[qualifiedTableName(dbName, tableName)] = createSQL
+	}
+	dedup, err := ReadSchemaDedup(dir)
+	if err != nil {
+		return nil, err
+	}
+	canonicalSQLs := map[string]string{}
+	for _, table := range dedup.Tables {
+		if onlyDatabases != nil && !onlyDatabases[table.Database] {
+			continue
+		}
+		createSQL, ok := canonicalSQLs[table.File]
+		if !ok {
+			if createSQL, err = readSchemaFile(filepath.Join(dir, table.File)); err != nil {
+				return nil, err
+			}
+			canonicalSQLs[table.File] = createSQL
+		}
+		if conf.NoViews && !strings.HasPrefix(strings.ToUpper(createSQL), "CREATE TABLE") {
+			continue
+		}
+		schemas[qualifiedTableName(table.Database, table.Table)] = table.createStatement(createSQL)
+	}
+	return schemas, nil
+}
+
+// splitSchemaFileName splits the `{db}.{table}` of a schema file name by the
+// databases of the dump, or by the first dot if none of them matches.
+func splitSchemaFileName(name string, databases []string) (string, string) {
+	for _, dbName := range databases {
+		if strings.HasPrefix(name, dbName+".") {
+			return dbName, name[len(dbName)+1:]
+		}
+	}
+	if i := strings.Index(name, "."); i >= 0 {
+		return name[:i], name[i+1:]
+	}
+	return name, ""
+}
+
+// readSchemaFile returns the CREATE statement of a schema file written by
+// WriteMeta, without its special comments.
+func readSchemaFile(file string) (string, error) {
+	content, err := ioutil.ReadFile(file)
+	if err != nil {
+		return "", withStack(err)
+	}
+	var lines []string
+	for _, line := range strings.Split(string(content), "\n") {
+		trimmed := strings.TrimSpace(line)
+		if strings.HasPrefix(trimmed, "/*!") && strings.HasSuffix(trimmed, "*/;") {
+			continue
+		}
+		if trimmed == "" && len(lines) == 0 {
+			continue
+		}
+		lines = append(lines, line)
+	}
+	return strings.TrimSuffix(strings.TrimSpace(strings.Join(lines, "\n")), ";"), nil
+}
+
+// SchemaDiff is the structural difference of the schemas of a source and a
+// target, whose names are sorted.
+type SchemaDiff struct {
+	// OnlyInSource are the databases, tables and views only in the source.
+	OnlyInSource []string
+	// OnlyInTarget are the databases, tables and views only in the target.
+	OnlyInTarget []string
+	// Changed are the databases, tables and views changed in the target.
+	Changed []TableDiff
+}
+
+// TableDiff is the difference of a database, table or view in both the source
+// and the target. The tables are compared by their columns, indexes,
+// constraints and options, and the others by their whole statements.
+type TableDiff struct {
+	Name string
+	// Removed are the definitions only in the source.
+	Removed []string
+	// Added are the definitions only in the target.
+	Added []string
+	// Modified are the definitions changed from the source to the target.
+	Modified [][2]string
+}
+
+// DiffSchemas returns the difference of the target schemas from the source.
+func DiffSchemas(source, target SchemaSet) SchemaDiff {
+	var diff SchemaDiff
+	for name, sourceSQL := range source {
+		targetSQL, ok := target[name]
+		if !ok {
+			diff.OnlyInSource = append(diff.OnlyInSource, name)
+			continue
+		}
+		if tableDiff := diffCreateSQL(name, sourceSQL, targetSQL); tableDiff != nil {
+			diff.Changed = append(diff.Changed, *tableDiff)
+		}
+	}
+	for name := range target {
+		if _, ok := source[name]; !ok {
+			diff.OnlyInTarget = append(diff.OnlyInTarget, name)
+		}
+	}
+	sort.Strings(diff.OnlyInSource)
+	sort.Strings(diff.OnlyInTarget)
+	sort.Slice(diff.Changed, func(i, j int) bool {
+		return diff.Changed[i].Name < diff.Changed[j].Name
+	})
+	return diff
+}
+
+// Empty returns whether the source and the target have the same schemas.
+func (d SchemaDiff) Empty() bool {
+	return len(d.OnlyInSource) == 0 && len(d.OnlyInTarget) == 0 && len(d.Changed) == 0
+}
+
+// String formats the difference like a unified diff, the names only in the
+// source are prefixed with -, the ones only in the target with +, and the
+// changed ones with ~ followed by their changed definitions.
+func (d SchemaDiff) String() string {
+	var b strings.Builder
+	for _, name := range d.OnlyInSource {
+		fmt.Fprintf(&b, "- %s\n", name)
+	}
+	for _, name := range d.OnlyInTarget {
+		fmt.Fprintf(&b, "+ %s\n", name)
+	}
+	for _, table := range d.Changed {
+		fmt.Fprintf(&b, "~ %s\n", table.Name)
+		for _, def := range table.Removed {
+			fmt.Fprintf(&b, "  - %s\n", def)
+		}
+		for _, def := range table.Added {
+			fmt.Fprintf(&b, "  + %s\n", def)
+		}
+		for _, defs := range table.Modified {
+			fmt.Fprintf(&b, "  ~ %s => %s\n", defs[0], defs[1])
+		}
+	}
+	return b.String()
+}
+
+// diffCreateSQL returns the difference of the CREATE statements of name, or
+// nil if they're the same.
+func diffCreateSQL(name, sourceSQL, targetSQL string) *TableDiff {
+	sourceSQL, targetSQL = normalizeCreateSQL(sourceSQL), normalizeCreateSQL(targetSQL)
+	if sourceSQL == targetSQL {
+		return nil
+	}
+	sourceTable, ok1 := parseTableStructure(sourceSQL)
+	targetTable, ok2 := parseTableStructure(targetSQL)
+	if !ok1 || !ok2 {
+		return &TableDiff{Name: name, Modified: [][2]string{{oneLine(sourceSQL), oneLine(targetSQL)}}}
+	}
+
+	diff := &TableDiff{Name: name}
+	for _, key := range sourceTable.keys {
+		targetDef, ok := targetTable.definitions[key]
+		switch {
+		case !ok:
+			diff.Removed = append(diff.Removed, sourceTable.definitions[key])
+		case targetDef != sourceTable.definitions[key]:
+			diff.Modified = append(diff.Modified, [2]string{sourceTable.definitions[key], targetDef})
+		}
+	}
+	for _, key := range targetTable.keys {
+		if _, ok := sourceTable.definitions[key]; !ok {
+			diff.Added = append(diff.Added, targetTable.definitions[key])
+		}
+	}
+	// the columns in both of them are compared by their orders
+	sourceOrder, targetOrder := commonColumns(sourceTable, targetTable), commonColumns(targetTable, sourceTable)
+	if strings.Join(sourceOrder, ", ") != strings.Join(targetOrder, ", ") {
+		diff.Modified = append(diff.Modified, [2]string{
+			"column order " + strings.Join(sourceOrder, ", "),
+			"column order " + strings.Join(targetOrder, ", "),
+		})
+	}
+	if sourceTable.options != targetTable.options {
+		diff.Modified = append(diff.Modified, [2]string{sourceTable.options, targetTable.options})
+	}
+	if len(diff.Removed) == 0 && len(diff.Added) == 0 && len(diff.Modified) == 0 {
+		// only the whitespaces differ
+		return nil
+	}
+	return diff
+}
+
+// autoIncrementOption is the next value of the AUTO_INCREMENT column shown in
+// the table options, which depends on the rows instead of the structure.
+var autoIncrementOption = regexp.MustCompile(` AUTO_INCREMENT=\d+`)
+
+func normalizeCreateSQL(createSQL string) string {
+	return autoIncrementOption.ReplaceAllString(strings.TrimSpace(createSQL), "")
+}
+
+func oneLine(s string) string {
+	return strings.Join(strings.Fields(s), " ")
+}
+
+// tableStructure is the definitions and options of a CREATE TABLE statement.
+type tableStructure struct {
+	// keys are like "column `id`" or "index `idx`" in the defined order
+	keys        []string
+	definitions map[string]string
+	options     string
+}
+
+var (
+	indexDefinition      = regexp.MustCompile("^(?:UNIQUE |FULLTEXT |SPATIAL )?(?:KEY|INDEX) (`(?:[^`]|``)+`)")
+	constraintDefinition = regexp.MustCompile("^CONSTRAINT (`(?:[^`]|``)+`)")
+	columnDefinition     = regexp.MustCompile("^`(?:[^`]|``)+`")
+)
+
+// parseTableStructure parses the CREATE TABLE statement of SHOW CREATE TABLE,
+// which has a line for each of its columns, indexes and constraints. It
+// returns false if the statement isn't in this format, e.g. of a view.
+func parseTableStructure(createSQL string) (tableStructure, bool) {
+	lines := strings.Split(createSQL, "\n")
+	if len(lines) < 3 || !strings.HasPrefix(strings.ToUpper(lines[0]), "CREATE TABLE") ||
+		!strings.HasSuffix(strings.TrimSpace(lines[0]), "(") {
+		return tableStructure{}, false
+	}
+	end := -1
+	for i := len(lines) - 1; i > 0; i-- {
+		if strings.HasPrefix(lines[i], ")") {
+			end = i
+			break
+		}
+	}
+	if end < 0 {
+		return tableStructure{}, false
+	}
+
+	table := tableStructure{definitions: map[string]string{}}
+	for _, line := range lines[1:end] {
+		def := strings.TrimSuffix(strings.TrimSpace(line), ",")
+		var key string
+		if m := indexDefinition.FindStringSubmatch(def); m != nil {
+			key = "index " + m[1]
+		} else if m := constraintDefinition.FindStringSubmatch(def); m != nil {
+			key = "constraint " + m[1]
+		} else if strings.HasPrefix(def, "PRIMARY KEY") {
+			key = "index PRIMARY"
+		} else if m := columnDefinition.FindString(def); m != "" {
+			key = "column " + m
+		} else {
+			key = def
+		}
+		if _, ok := table.definitions[key]; ok {
+			return tableStructure{}, false
+		}
+		table.keys = append(table.keys, key)
+		table.definitions[key] = def
+	}
+	table.options = oneLine(strings.TrimPrefix(strings.Join(lines[end:], "\n"), ")"))
+	return table, true
+}
+
+// commonColumns returns the columns of table which are also in other, in the
+// order of table.
+func commonColumns(table, other tableStructure) []string {
+	var columns []string
+	for _, key := range table.keys {
+		if !strings.HasPrefix(key, "column ") {
+			continue
+		}
+		if _, ok := other.definitions[key]; ok {
+			columns = append(columns, strings.TrimPrefix(key, "column "))
+		}
+	}
+	return columns
+}
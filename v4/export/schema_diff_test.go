@@ -0,0 +1,132 @@
+package export
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+
+	. "github.com/pingcap/check"
+)
+
+var _ = Suite(&testSchemaDiffSuite{})
+
+type testSchemaDiffSuite struct{}
+
+const (
+	sourceCreateTable = "CREATE TABLE `t` (\n" +
+		"  `id` int(11) NOT NULL,\n" +
+		"  `name` varchar(20) DEFAULT NULL,\n" +
+		"  `age` int(11) DEFAULT NULL,\n" +
+		"  `email` varchar(64) DEFAULT NULL,\n" +
+		"  PRIMARY KEY (`id`),\n" +
+		"  KEY `idx_name` (`name`)\n" +
+		") ENGINE=InnoDB AUTO_INCREMENT=10 DEFAULT CHARSET=latin1"
+	targetCreateTable = "CREATE TABLE `t` (\n" +
+		"  `id` int(11) NOT NULL,\n" +
+		"  `age` int(11) DEFAULT NULL,\n" +
+		"  `name` varchar(64) DEFAULT NULL,\n" +
+		"  `phone` varchar(20) DEFAULT NULL,\n" +
+		"  PRIMARY KEY (`id`),\n" +
+		"  UNIQUE KEY `idx_phone` (`phone`)\n" +
+		") ENGINE=InnoDB AUTO_INCREMENT=200 DEFAULT CHARSET=utf8mb4"
+)
+
+func (s *testSchemaDiffSuite) TestDiffSchemas(c *C) {
+	source := SchemaSet{
+		"`test`":          "CREATE DATABASE `test` /*!40100 DEFAULT CHARACTER SET latin1 */",
+		"`test`.`t`":      sourceCreateTable,
+		"`test`.`same`":   "CREATE TABLE `same` (\n  `id` int(11)\n) ENGINE=InnoDB AUTO_INCREMENT=5",
+		"`test`.`v`":      "CREATE VIEW `v` AS SELECT 1",
+		"`test`.`source`": "CREATE TABLE `source` (\n  `id` int(11)\n)",
+	}
+	target := SchemaSet{
+		"`test`":          "CREATE DATABASE `test` /*!40100 DEFAULT CHARACTER SET utf8mb4 */",
+		"`test`.`t`":      targetCreateTable,
+		"`test`.`same`":   "CREATE TABLE `same` (\n  `id` int(11)\n) ENGINE=InnoDB AUTO_INCREMENT=7",
+		"`test`.`v`":      "CREATE VIEW `v` AS\n  SELECT 2",
+		"`test`.`target`": "CREATE TABLE `target` (\n  `id` int(11)\n)",
+	}
+	diff := DiffSchemas(source, target)
+	c.Assert(diff.Empty(), IsFalse)
+	c.Assert(diff.OnlyInSource, DeepEquals, []string{"`test`.`source`"})
+	c.Assert(diff.OnlyInTarget, DeepEquals, []string{"`test`.`target`"})
+	c.Assert(diff.Changed, DeepEquals, []TableDiff{
+		{Name: "`test`", Modified: [][2]string{{
+			"CREATE DATABASE `test` /*!40100 DEFAULT CHARACTER SET latin1 */",
+			"CREATE DATABASE `test` /*!40100 DEFAULT CHARACTER SET utf8mb4 */",
+		}}},
+		{
+			Name:    "`test`.`t`",
+			Removed: []string{"`email` varchar(64) DEFAULT NULL", "KEY `idx_name` (`name`)"},
+			Added:   []string{"`phone` varchar(20) DEFAULT NULL", "UNIQUE KEY `idx_phone` (`phone`)"},
+			Modified: [][2]string{
+				{"`name` varchar(20) DEFAULT NULL", "`name` varchar(64) DEFAULT NULL"},
+				{"column order `id`, `name`, `age`", "column order `id`, `age`, `name`"},
+				{"ENGINE=InnoDB DEFAULT CHARSET=latin1", "ENGINE=InnoDB DEFAULT CHARSET=utf8mb4"},
+			},
+		},
+		{Name: "`test`.`v`", Modified: [][2]string{{"CREATE VIEW `v` AS SELECT 1", "CREATE VIEW `v` AS SELECT 2"}}},
+	})
+	c.Assert(diff.String(), Equals, "- `test`.`source`\n"+
+		"+ `test`.`target`\n"+
+		"~ `test`\n"+
+		"  ~ CREATE DATABASE `test` /*!40100 DEFAULT CHARACTER SET latin1 */ => CREATE DATABASE `test` /*!40100 DEFAULT CHARACTER SET utf8mb4 */\n"+
+		"~ `test`.`t`\n"+
+		"  - `email` varchar(64) DEFAULT NULL\n"+
+		"  - KEY `idx_name` (`name`)\n"+
+		"  + `phone` varchar(20) DEFAULT NULL\n"+
+		"  + UNIQUE KEY `idx_phone` (`phone`)\n"+
+		"  ~ `name` varchar(20) DEFAULT NULL => `name` varchar(64) DEFAULT NULL\n"+
+		"  ~ column order `id`, `name`, `age` => column order `id`, `age`, `name`\n"+
+		"  ~ ENGINE=InnoDB DEFAULT CHARSET=latin1 => ENGINE=InnoDB DEFAULT CHARSET=utf8mb4\n"+
+		"~ `test`.`v`\n"+
+		"  ~ CREATE VIEW `v` AS SELECT 1 => CREATE VIEW `v` AS SELECT 2\n")
+
+	c.Assert(DiffSchemas(source, source).Empty(), IsTrue)
+	c.Assert(DiffSchemas(source, source).String(), Equals, "")
+}
+
+func (s *testSchemaDiffSuite) TestReadSchemas(c *C) {
+	dir, err := ioutil.TempDir("", "dumpling")
+	c.Assert(err, IsNil)
+	defer os.RemoveAll(dir)
+
+	conf := DefaultConfig()
+	conf.OutputDirPath = dir
+	writer, err := NewSimpleWriter(conf)
+	c.Assert(err, IsNil)
+	ctx := context.Background()
+	c.Assert(writer.WriteDatabaseMeta(ctx, "test", "CREATE DATABASE `test`"), IsNil)
+	c.Assert(writer.WriteDatabaseMeta(ctx, "test.db", "CREATE DATABASE `test.db`"), IsNil)
+	c.Assert(writer.WriteDatabaseMeta(ctx, "other", "CREATE DATABASE `other`"), IsNil)
+	c.Assert(writer.WriteTableMeta(ctx, "test", "t", sourceCreateTable), IsNil)
+	c.Assert(writer.WriteTableMeta(ctx, "test", "v", "CREATE VIEW `v` AS SELECT 1"), IsNil)
+	c.Assert(writer.WriteTableMeta(ctx, "test.db", "t", sourceCreateTable), IsNil)
+	c.Assert(writer.WriteTableMeta(ctx, "other", "t", sourceCreateTable), IsNil)
+
+	readConf := DefaultConfig()
+	readConf.NoViews = false
+	schemas, err := ReadSchemas(readConf, dir)
+	c.Assert(err, IsNil)
+	c.Assert(schemas, DeepEquals, SchemaSet{
+		"`test`":        "CREATE DATABASE `test`",
+		"`test.db`":     "CREATE DATABASE `test.db`",
+		"`other`":       "CREATE DATABASE `other`",
+		"`test`.`t`":    sourceCreateTable,
+		"`test`.`v`":    "CREATE VIEW `v` AS SELECT 1",
+		"`test.db`.`t`": sourceCreateTable,
+		"`other`.`t`":   sourceCreateTable,
+	})
+
+	// the databases and views are filtered
+	readConf.Database = "test,test.db"
+	readConf.NoViews = true
+	schemas, err = ReadSchemas(readConf, dir)
+	c.Assert(err, IsNil)
+	c.Assert(schemas, DeepEquals, SchemaSet{
+		"`test`":        "CREATE DATABASE `test`",
+		"`test.db`":     "CREATE DATABASE `test.db`",
+		"`test`.`t`":    sourceCreateTable,
+		"`test.db`.`t`": sourceCreateTable,
+	})
+}
@@ -0,0 +1,111 @@
+package export
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+
+	"github.com/pingcap/errors"
+	"go.uber.org/zap"
+
+	"github.com/pingcap/dumpling/v4/log"
+)
+
+// dumpsSequences returns whether the sequences are dumped, which are only
+// written besides the schema files of the tables.
+func (conf *Config) dumpsSequences() bool {
+	if conf.NoSequences || conf.NoSchemas || conf.TargetDSN != "" || conf.Sql != "" ||
+		len(conf.OnlyObjects) > 0 || len(conf.RouteRules) > 0 || conf.translatesDDL() {
+		return false
+	}
+	switch strings.ToLower(conf.FileType) {
+	case "sql", "csv", "tsv":
+		return true
+	default:
+		return false
+	}
+}
+
+// dumpSequences writes the CREATE SEQUENCE statement of every filtered
+// sequence of the databases into `{db}.{sequence}-schema-sequence.sql`,
+// followed by a SETVAL of the next value not allocated yet. The restored
+// sequence may skip some values, but never returns a value already used.
+func dumpSequences(ctx context.Context, conf *Config, db *sql.DB) error {
+	bwList, err := NewBWList(conf.BlackWhiteList)
+	if err != nil {
+		return withStack(err)
+	}
+	for dbName := range conf.Tables {
+		sequences, err := ListAllSequences(db, dbName)
+		if err != nil {
+			return withKind(ErrorKindSchema, err)
+		}
+		for _, sequence := range sequences {
+			if !bwList.Apply(dbName, sequence) {
+				continue
+			}
+			createSQL, err := showCreateSequence(conf.ServerInfo.ServerType, db, dbName, sequence)
+			if err != nil {
+				return withKind(ErrorKindSchema, err)
+			}
+			fileName := fmt.Sprintf("%s.%s-schema-sequence.sql", dbName, sequence)
+			if err = writeMetaToFile(ctx, conf, conf.ExternalStorage, dbName, createSQL, fileName); err != nil {
+				return err
+			}
+			log.Debug("dump sequence finished", zap.String("database", dbName), zap.String("sequence", sequence))
+		}
+	}
+	return nil
+}
+
+// showCreateSequence returns the CREATE SEQUENCE statement of the sequence
+// and the SETVAL restoring its next value.
+func showCreateSequence(serverType ServerType, db *sql.DB, dbName, sequence string) (string, error) {
+	var oneRow [2]string
+	handleOneRow := func(rows *sql.Rows) error {
+		return rows.Scan(&oneRow[0], &oneRow[1])
+	}
+	query := fmt.Sprintf("SHOW CREATE SEQUENCE %s", qualifiedTableName(dbName, sequence))
+	if err := simpleQuery(db, query, handleOneRow); err != nil {
+		return "", errors.WithMessage(err, query)
+	}
+	next, err := nextSequenceValue(serverType, db, dbName, sequence)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%s;\nSELECT SETVAL(%s, %s)", oneRow[1], wrapBackTicks(sequence), next), nil
+}
+
+// nextSequenceValue returns the next value of the sequence which isn't
+// allocated or cached by any session.
+func nextSequenceValue(serverType ServerType, db *sql.DB, dbName, sequence string) (string, error) {
+	if serverType != ServerTypeTiDB {
+		var next string
+		query := fmt.Sprintf("SELECT NEXT_NOT_CACHED_VALUE FROM %s", qualifiedTableName(dbName, sequence))
+		if err := db.QueryRow(query).Scan(&next); err != nil {
+			return "", withStack(errors.WithMessage(err, query))
+		}
+		return next, nil
+	}
+	// DB_NAME, TABLE_NAME, COLUMN_NAME, NEXT_GLOBAL_ROW_ID, ID_TYPE
+	var next string
+	handleOneRow := func(rows *sql.Rows) error {
+		var dbName, tableName, columnName, nextID, idType sql.NullString
+		if err := rows.Scan(&dbName, &tableName, &columnName, &nextID, &idType); err != nil {
+			return err
+		}
+		if idType.String == "SEQUENCE" {
+			next = nextID.String
+		}
+		return nil
+	}
+	query := fmt.Sprintf("SHOW TABLE %s NEXT_ROW_ID", qualifiedTableName(dbName, sequence))
+	if err := simpleQuery(db, query, handleOneRow); err != nil {
+		return "", errors.WithMessage(err, query)
+	}
+	if next == "" {
+		return "", errors.Errorf("the next value of sequence %s isn't found", qualifiedTableName(dbName, sequence))
+	}
+	return next, nil
+}
@@ -0,0 +1,81 @@
+package export
+
+import (
+	"context"
+	"regexp"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	. "github.com/pingcap/check"
+	"github.com/pingcap/tidb-tools/pkg/filter"
+)
+
+var _ = Suite(&testSequencesSuite{})
+
+type testSequencesSuite struct{}
+
+func (s *testSequencesSuite) TestDumpSequences(c *C) {
+	db, mock, err := sqlmock.New()
+	c.Assert(err, IsNil)
+	defer db.Close()
+
+	storage := newMemStorage()
+	conf := DefaultConfig()
+	conf.ExternalStorage = storage
+	conf.ServerInfo = ServerInfo{ServerType: ServerTypeTiDB}
+	conf.Tables = NewDatabaseTables().AppendTables("test", "t")
+	conf.BlackWhiteList = BWListConf{
+		Mode: MySQLReplicationMode,
+		Rules: &MySQLReplicationConf{
+			Rules: &filter.Rules{
+				IgnoreTables: []*filter.Table{{Schema: "test", Name: "ignored"}},
+			},
+		},
+	}
+
+	mock.ExpectQuery("SELECT table_name FROM information_schema.tables WHERE table_schema = 'test' and table_type = 'SEQUENCE'").
+		WillReturnRows(sqlmock.NewRows([]string{"table_name"}).AddRow("s").AddRow("ignored"))
+	mock.ExpectQuery(regexp.QuoteMeta("SHOW CREATE SEQUENCE `test`.`s`")).
+		WillReturnRows(sqlmock.NewRows([]string{"Sequence", "Create Sequence"}).
+			AddRow("s", "CREATE SEQUENCE `s` start with 1 minvalue 1 maxvalue 9223372036854775806 increment by 1 cache 1000 nocycle ENGINE=InnoDB"))
+	mock.ExpectQuery(regexp.QuoteMeta("SHOW TABLE `test`.`s` NEXT_ROW_ID")).
+		WillReturnRows(sqlmock.NewRows([]string{"DB_NAME", "TABLE_NAME", "COLUMN_NAME", "NEXT_GLOBAL_ROW_ID", "ID_TYPE"}).
+			AddRow("test", "s", nil, 1001, "SEQUENCE"))
+	c.Assert(dumpSequences(context.Background(), conf, db), IsNil)
+	c.Assert(mock.ExpectationsWereMet(), IsNil)
+	c.Assert(storage.files, HasLen, 1)
+	c.Assert(storage.files["test.s-schema-sequence.sql"], Equals,
+		"CREATE SEQUENCE `s` start with 1 minvalue 1 maxvalue 9223372036854775806 increment by 1 cache 1000 nocycle ENGINE=InnoDB;\n"+
+			"SELECT SETVAL(`s`, 1001);\n")
+}
+
+func (s *testSequencesSuite) TestNextSequenceValue(c *C) {
+	db, mock, err := sqlmock.New()
+	c.Assert(err, IsNil)
+	defer db.Close()
+
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT NEXT_NOT_CACHED_VALUE FROM `test`.`s`")).
+		WillReturnRows(sqlmock.NewRows([]string{"next_not_cached_value"}).AddRow(1001))
+	next, err := nextSequenceValue(ServerTypeMariaDB, db, "test", "s")
+	c.Assert(err, IsNil)
+	c.Assert(next, Equals, "1001")
+
+	mock.ExpectQuery(regexp.QuoteMeta("SHOW TABLE `test`.`s` NEXT_ROW_ID")).
+		WillReturnRows(sqlmock.NewRows([]string{"DB_NAME", "TABLE_NAME", "COLUMN_NAME", "NEXT_GLOBAL_ROW_ID", "ID_TYPE"}).
+			AddRow("test", "s", "_tidb_rowid", 1, "_TIDB_ROWID"))
+	_, err = nextSequenceValue(ServerTypeTiDB, db, "test", "s")
+	c.Assert(err, ErrorMatches, "the next value of sequence `test`.`s` isn't found")
+	c.Assert(mock.ExpectationsWereMet(), IsNil)
+}
+
+func (s *testSequencesSuite) TestDumpsSequences(c *C) {
+	conf := DefaultConfig()
+	c.Assert(conf.dumpsSequences(), IsTrue)
+	conf.NoSequences = true
+	c.Assert(conf.dumpsSequences(), IsFalse)
+	conf.NoSequences = false
+	conf.FileType = "sqlite"
+	c.Assert(conf.dumpsSequences(), IsFalse)
+	conf.FileType = "csv"
+	conf.NoSchemas = true
+	c.Assert(conf.dumpsSequences(), IsFalse)
+}
@@ -0,0 +1,198 @@
+package export
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pingcap/errors"
+	"go.uber.org/zap"
+	"golang.org/x/sync/errgroup"
+
+	"github.com/pingcap/dumpling/v4/log"
+)
+
+// shardDump is the dump of a source in Config.Shards, which shares the output
+// with the other shards.
+type shardDump struct {
+	name     string
+	routes   *routeState
+	metadata *shardMetadata
+}
+
+// shardsConflicts returns the options conflicting with Shards.
+func shardsConflicts(conf *Config) []string {
+	var conflicts []string
+	for _, dsn := range conf.Shards {
+		if _, err := parseSourceAddr(conf, dsn); err != nil {
+			conflicts = append(conflicts, err.Error())
+		}
+	}
+	if conf.Sql != "" || conf.TargetDSN != "" || conf.ServerOutfileDir != "" {
+		conflicts = append(conflicts, "shards are not supported with sql, target-dsn or server-outfile-dir")
+	}
+	if conf.BigQuerySchema || conf.HiveLocation != "" {
+		conflicts = append(conflicts, "shards are not supported with bigquery-schema or hive-location")
+	}
+	if conf.Append || conf.IncrementalColumn != "" || conf.StateFile != "" || conf.CaptureWarnings || conf.StrictWarnings {
+		conflicts = append(conflicts, "shards are not supported with append, incremental-column, state-file, capture-warnings or strict-warnings")
+	}
+	return conflicts
+}
+
+// shardHooks doesn't pass OnDumpFinish of the shards, it's called once
+// after all the shards are dumped.
+type shardHooks struct {
+	Hooks
+}
+
+func (shardHooks) OnDumpFinish(error) {}
+
+// forShard returns the config of dumping source into the output of conf.
+func (conf *Config) forShard(source sourceAddr, routes *routeState, metadata *shardMetadata) *Config {
+	shardConf := *conf.forSource(source)
+	shardConf.Shards = nil
+	shardConf.Hooks = shardHooks{conf.hooks()}
+	// the following are served once for all the shards
+	shardConf.StatusAddr = ""
+	shardConf.ShowProgress = false
+	shardConf.AfterDumpCommand = ""
+	shardConf.NotifyURL = ""
+	shardConf.Retention = ""
+	shardConf.shard = &shardDump{name: source.String(), routes: routes, metadata: metadata}
+	return &shardConf
+}
+
+// dumpShards dumps the shards concurrently into the output of conf. The
+// tables of all the shards are written as if they were routed to the same
+// tables by RouteRules, and the metadata has the positions of every shard.
+func dumpShards(ctx context.Context, conf *Config) (err error) {
+	defer func() {
+		if cmdErr := runAfterDumpCommand(conf, err); err == nil {
+			err = cmdErr
+		}
+		notifyDumpFinished(conf, err)
+	}()
+	sources := make([]sourceAddr, 0, len(conf.Shards))
+	for _, dsn := range conf.Shards {
+		source, err := parseSourceAddr(conf, dsn)
+		if err != nil {
+			return withKind(ErrorKindConfig, err)
+		}
+		sources = append(sources, source)
+	}
+	if conf.ExternalStorage, err = newStorage(conf); err != nil {
+		return err
+	}
+	routes, err := newRouteState(conf.RouteRules)
+	if err != nil {
+		return err
+	}
+	metadata := newShardMetadata()
+	if conf.Retention != "" {
+		metadata.retentionGroup = retentionGroup(conf)
+	}
+	if conf.DedupSchemas {
+		conf.schemaDedup = newSchemaDedupRecorder()
+	}
+	defer func() {
+		conf.Controller.finish(err)
+	}()
+
+	go func() {
+		if conf.StatusAddr != "" {
+			if err := startDumplingService(conf); err != nil {
+				log.Error("dumpling stops to serving service", zap.Error(err))
+			}
+		}
+	}()
+	if conf.ShowProgress {
+		renderCtx, stopRender := context.WithCancel(ctx)
+		renderDone := make(chan struct{})
+		go func() {
+			renderProgress(renderCtx, conf.Controller, os.Stderr, defaultProgressRefresh)
+			close(renderDone)
+		}()
+		defer func() {
+			stopRender()
+			<-renderDone
+		}()
+	}
+
+	g, gCtx := errgroup.WithContext(ctx)
+	for _, source := range sources {
+		shardConf := conf.forShard(source, routes, metadata)
+		g.Go(func() error {
+			log.Info("start dumping shard", zap.String("shard", shardConf.shard.name))
+			if err := Dump(gCtx, shardConf); err != nil {
+				return errors.WithMessage(err, "dump shard "+shardConf.shard.name)
+			}
+			return nil
+		})
+	}
+	err = g.Wait()
+	if writeErr := metadata.write(conf.ExternalStorage); err == nil {
+		err = writeErr
+	}
+	if writeErr := conf.schemaDedup.write(conf, conf.ExternalStorage); err == nil {
+		err = writeErr
+	}
+	if err := writeSummary(conf); err != nil {
+		log.Error("write summary failed", zap.Error(err))
+	}
+	if err != nil {
+		return err
+	}
+	return pruneDumps(ctx, conf, time.Now())
+}
+
+// shardMetadata collects the metadata of the shards, which are written into
+// one metadata file.
+type shardMetadata struct {
+	mu     sync.Mutex
+	shards map[string]string
+	// retentionGroup is written before the shards, since the shards are
+	// dumped without Retention
+	retentionGroup string
+}
+
+func newShardMetadata() *shardMetadata {
+	return &shardMetadata{shards: map[string]string{}}
+}
+
+func (m *shardMetadata) record(shard string, meta *globalMetadata) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.shards[shard] = meta.String()
+}
+
+// write writes the metadata of the shards sorted by their names, each of
+// them starts with `Shard: <host:port>`.
+func (m *shardMetadata) write(storage ExternalStorage) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	names := make([]string, 0, len(m.shards))
+	for name := range m.shards {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var b strings.Builder
+	if m.retentionGroup != "" {
+		b.WriteString(retentionGroupPrefix + m.retentionGroup + "\n")
+	}
+	for i, name := range names {
+		if i > 0 {
+			b.WriteString("\n")
+		}
+		fmt.Fprintf(&b, "Shard: %s\n%s", name, m.shards[name])
+	}
+	fileWriter, err := storage.Create(context.Background(), metadataPath)
+	if err != nil {
+		return err
+	}
+	return closeFile(fileWriter, write(fileWriter, b.String()))
+}
@@ -0,0 +1,108 @@
+package export
+
+import (
+	"context"
+	"database/sql/driver"
+	"strings"
+	"time"
+
+	. "github.com/pingcap/check"
+)
+
+var _ = Suite(&testShardsSuite{})
+
+type testShardsSuite struct{}
+
+func (s *testShardsSuite) TestValidate(c *C) {
+	conf := DefaultConfig()
+	conf.Shards = []string{"10.0.1.1:3306", "10.0.1.2:3306"}
+	c.Assert(conf.Validate(), IsNil)
+
+	conf.Shards = append(conf.Shards, "root@")
+	conf.Sql = "SELECT 1"
+	conf.Append = true
+	err := conf.Validate()
+	c.Assert(err, ErrorMatches, ".*invalid source root@.*")
+	c.Assert(err, ErrorMatches, ".*shards are not supported with sql, target-dsn or server-outfile-dir.*")
+	c.Assert(err, ErrorMatches, ".*shards are not supported with append, incremental-column, state-file.*")
+}
+
+func (s *testShardsSuite) TestForShard(c *C) {
+	conf := DefaultConfig()
+	conf.Shards = []string{"10.0.1.1"}
+	conf.StatusAddr = ":8281"
+	conf.AfterDumpCommand = "echo done"
+	conf.Retention = "7"
+	metadata := newShardMetadata()
+	shardConf := conf.forShard(sourceAddr{user: "dump", host: "10.0.1.1", port: 3306}, nil, metadata)
+
+	c.Assert(shardConf.Host, Equals, "10.0.1.1")
+	c.Assert(shardConf.User, Equals, "dump")
+	c.Assert(shardConf.Shards, IsNil)
+	c.Assert(shardConf.StatusAddr, Equals, "")
+	c.Assert(shardConf.AfterDumpCommand, Equals, "")
+	c.Assert(shardConf.Retention, Equals, "")
+	c.Assert(shardConf.shard.name, Equals, "10.0.1.1:3306")
+	c.Assert(shardConf.shard.metadata, Equals, metadata)
+	// the config of dump isn't changed
+	c.Assert(conf.StatusAddr, Equals, ":8281")
+	c.Assert(conf.shard, IsNil)
+}
+
+func (s *testShardsSuite) TestShardMetadata(c *C) {
+	storage := newMemStorage()
+	metadata := newShardMetadata()
+	start, err := time.Parse(metadataTimeLayout, "2020-06-01 10:00:00")
+	c.Assert(err, IsNil)
+	for i, shard := range []string{"10.0.1.2:3306", "10.0.1.1:3306"} {
+		m := newGlobalMetadata(storage)
+		m.recordStartTime(start)
+		m.logFile, m.pos = "mysql-bin.000001", []string{"154", "4"}[i]
+		metadata.record(shard, m)
+	}
+	c.Assert(metadata.write(storage), IsNil)
+	c.Assert(storage.files[metadataPath], Equals, strings.Join([]string{
+		"Shard: 10.0.1.1:3306",
+		"Started dump at: 2020-06-01 10:00:00",
+		"SHOW MASTER STATUS:",
+		"\t\tLog: mysql-bin.000001",
+		"\t\tPos: 4",
+		"",
+		"Shard: 10.0.1.2:3306",
+		"Started dump at: 2020-06-01 10:00:00",
+		"SHOW MASTER STATUS:",
+		"\t\tLog: mysql-bin.000001",
+		"\t\tPos: 154",
+		"",
+	}, "\n"))
+}
+
+func (s *testShardsSuite) TestSharedRoutes(c *C) {
+	conf := DefaultConfig()
+	storage := newMemStorage()
+	conf.ExternalStorage = storage
+	routes, err := newRouteState(nil)
+	c.Assert(err, IsNil)
+	ctx := context.Background()
+
+	for _, host := range []string{"10.0.1.1", "10.0.1.2"} {
+		shardConf := conf.forShard(sourceAddr{host: host, port: 3306}, routes, newShardMetadata())
+		shardConf.Tables = DatabaseTables{"test": {{Name: "t"}}}
+		w, err := NewSimpleWriter(shardConf)
+		c.Assert(err, IsNil)
+		w, err = newRoutedWriter(shardConf, w)
+		c.Assert(err, IsNil)
+		c.Assert(w.WriteDatabaseMeta(ctx, "test", "CREATE DATABASE `test`"), IsNil)
+		c.Assert(w.WriteTableMeta(ctx, "test", "t", "CREATE TABLE `t` (`host` varchar(16))"), IsNil)
+		ir := newMockTableIR("test", "t", [][]driver.Value{{host}}, nil, []string{"VARCHAR"})
+		c.Assert(w.WriteTableData(ctx, ir), IsNil)
+	}
+
+	// the tables of the shards are merged even without the routes
+	c.Assert(storage.files, DeepEquals, map[string]string{
+		"test-schema-create.sql": "CREATE DATABASE `test`;\n",
+		"test.t-schema.sql":      "CREATE TABLE `t` (`host` varchar(16));\n",
+		"test.t.0.sql":           "/*!40101 SET NAMES binary*/;\nINSERT INTO `t` VALUES\n('10.0.1.1');\n",
+		"test.t.1.sql":           "/*!40101 SET NAMES binary*/;\nINSERT INTO `t` VALUES\n('10.0.1.2');\n",
+	})
+}
@@ -0,0 +1,70 @@
+package export
+
+import (
+	"net"
+	"strconv"
+	"strings"
+
+	"github.com/pingcap/errors"
+)
+
+// sourceAddr is a source given by `[user[:password]@]host[:port]`.
+type sourceAddr struct {
+	user     string
+	password string
+	host     string
+	port     int
+}
+
+// parseSourceAddr parses the source dsn, the user, password and port not given
+// are taken from conf.
+func parseSourceAddr(conf *Config, dsn string) (sourceAddr, error) {
+	source := sourceAddr{user: conf.User, password: conf.Password, port: conf.Port}
+	hostPort := dsn
+	if i := strings.LastIndex(dsn, "@"); i >= 0 {
+		hostPort = dsn[i+1:]
+		userInfo := dsn[:i]
+		if j := strings.Index(userInfo, ":"); j >= 0 {
+			source.user, source.password = userInfo[:j], userInfo[j+1:]
+		} else {
+			source.user = userInfo
+		}
+	}
+	source.host = hostPort
+	if host, port, err := net.SplitHostPort(hostPort); err == nil {
+		p, err := strconv.Atoi(port)
+		if err != nil {
+			return source, errors.Errorf("invalid source %s, it should be like user:password@host:port", RedactSourceAddr(dsn))
+		}
+		source.host, source.port = host, p
+	}
+	if source.host == "" {
+		return source, errors.Errorf("invalid source %s, it should be like user:password@host:port", RedactSourceAddr(dsn))
+	}
+	return source, nil
+}
+
+// RedactSourceAddr returns dsn with its password replaced by ******, so that
+// it can be logged or printed.
+func RedactSourceAddr(dsn string) string {
+	i := strings.LastIndex(dsn, "@")
+	if i < 0 {
+		return dsn
+	}
+	if j := strings.Index(dsn[:i], ":"); j >= 0 {
+		return dsn[:j+1] + "******" + dsn[i:]
+	}
+	return dsn
+}
+
+func (s sourceAddr) String() string {
+	return net.JoinHostPort(s.host, strconv.Itoa(s.port))
+}
+
+// forSource returns a copy of conf connecting to source.
+func (conf *Config) forSource(source sourceAddr) *Config {
+	sourceConf := *conf
+	sourceConf.Host, sourceConf.Port = source.host, source.port
+	sourceConf.User, sourceConf.Password = source.user, source.password
+	return &sourceConf
+}
@@ -0,0 +1,46 @@
+package export
+
+import (
+	. "github.com/pingcap/check"
+)
+
+var _ = Suite(&testSourceAddrSuite{})
+
+type testSourceAddrSuite struct{}
+
+func (s *testSourceAddrSuite) TestParseSourceAddr(c *C) {
+	conf := DefaultConfig()
+	conf.User, conf.Password, conf.Port = "root", "secret", 3306
+
+	source, err := parseSourceAddr(conf, "10.0.1.1")
+	c.Assert(err, IsNil)
+	c.Assert(source, Equals, sourceAddr{user: "root", password: "secret", host: "10.0.1.1", port: 3306})
+	c.Assert(source.String(), Equals, "10.0.1.1:3306")
+
+	source, err = parseSourceAddr(conf, "dump@10.0.1.2:4000")
+	c.Assert(err, IsNil)
+	c.Assert(source, Equals, sourceAddr{user: "dump", password: "secret", host: "10.0.1.2", port: 4000})
+
+	// the password may contain '@' and ':'
+	source, err = parseSourceAddr(conf, "dump:p@ss:w@[::1]:4000")
+	c.Assert(err, IsNil)
+	c.Assert(source, Equals, sourceAddr{user: "dump", password: "p@ss:w", host: "::1", port: 4000})
+	c.Assert(source.String(), Equals, "[::1]:4000")
+
+	for _, dsn := range []string{"root@", "10.0.1.1:port", ""} {
+		_, err = parseSourceAddr(conf, dsn)
+		c.Assert(err, ErrorMatches, "invalid source .*, it should be like user:password@host:port")
+	}
+}
+
+func (s *testSourceAddrSuite) TestRedactSourceAddr(c *C) {
+	c.Assert(RedactSourceAddr("10.0.1.1:4000"), Equals, "10.0.1.1:4000")
+	c.Assert(RedactSourceAddr("dump@10.0.1.1"), Equals, "dump@10.0.1.1")
+	c.Assert(RedactSourceAddr("dump:p@ss:w@[::1]:4000"), Equals, "dump:******@[::1]:4000")
+	c.Assert(RedactSourceAddr("root:secret@tcp(127.0.0.1:4000)/"), Equals, "root:******@tcp(127.0.0.1:4000)/")
+
+	// the password isn't in the errors
+	conf := DefaultConfig()
+	_, err := parseSourceAddr(conf, "dump:secret@10.0.1.1:port")
+	c.Assert(err, ErrorMatches, "invalid source dump:\\*\\*\\*\\*\\*\\*@10.0.1.1:port, .*")
+}
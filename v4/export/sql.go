@@ -1,6 +1,7 @@
 package export
 
 import (
+	"context"
 	"database/sql"
 	"fmt"
 	"strconv"
@@ -86,6 +87,39 @@ func ListAllViews(db *sql.DB, database string) ([]string, error) {
 	return views.data, nil
 }
 
+// ListAllSequences lists the sequences of a database, which are listed as
+// tables of type SEQUENCE by MariaDB and TiDB.
+func ListAllSequences(db *sql.DB, database string) ([]string, error) {
+	var sequences oneStrColumnTable
+	const query = "SELECT table_name FROM information_schema.tables WHERE table_schema = '%s' and table_type = 'SEQUENCE'"
+	if err := simpleQuery(db, fmt.Sprintf(query, database), sequences.handleOneRow); err != nil {
+		return nil, errors.WithMessage(err, query)
+	}
+	return sequences.data, nil
+}
+
+// ListForeignKeyReferences lists the tables referenced by foreign keys in a database,
+// each result is [table, referenced schema, referenced table].
+func ListForeignKeyReferences(db *sql.DB, database string) ([][3]string, error) {
+	const query = "SELECT DISTINCT TABLE_NAME, REFERENCED_TABLE_SCHEMA, REFERENCED_TABLE_NAME " +
+		"FROM INFORMATION_SCHEMA.KEY_COLUMN_USAGE WHERE TABLE_SCHEMA = ? AND REFERENCED_TABLE_NAME IS NOT NULL"
+	rows, err := db.Query(query, database)
+	if err != nil {
+		return nil, withStack(errors.WithMessage(err, query))
+	}
+	defer rows.Close()
+
+	var references [][3]string
+	for rows.Next() {
+		var oneRow [3]string
+		if err := rows.Scan(&oneRow[0], &oneRow[1], &oneRow[2]); err != nil {
+			return nil, withStack(errors.WithMessage(err, query))
+		}
+		references = append(references, oneRow)
+	}
+	return references, withStack(rows.Err())
+}
+
 func SelectVersion(db *sql.DB) (string, error) {
 	var versionInfo string
 	handleOneRow := func(rows *sql.Rows) error {
@@ -98,63 +132,214 @@ func SelectVersion(db *sql.DB) (string, error) {
 	return versionInfo, nil
 }
 
-func SelectAllFromTable(conf *Config, db *sql.DB, database, table string) (TableDataIR, error) {
-	selectedField, err := buildSelectField(db, database, table)
+func SelectAllFromTable(ctx context.Context, conf *Config, db *sql.DB, database, table string) (TableDataIR, error) {
+	return selectFromTable(ctx, conf, db, database, table, UnspecifiedSize)
+}
+
+// selectFromTable selects the rows of the table like SelectAllFromTable, but
+// only the first limit rows if it's set.
+func selectFromTable(ctx context.Context, conf *Config, db *sql.DB, database, table string, limit uint64) (TableDataIR, error) {
+	d := conf.dialect()
+	selectedField, err := d.SelectField(db, database, table)
 	if err != nil {
 		return nil, err
 	}
 
-	colTypes, err := GetColumnTypes(db, selectedField, database, table)
+	quotedDatabase, quotedTable := d.QuoteIdentifier(database), d.QuoteIdentifier(table)
+	colTypes, err := GetColumnTypes(db, selectedField, quotedDatabase, quotedTable)
 	if err != nil {
 		return nil, err
 	}
 
-	orderByClause, err := buildOrderByClause(conf, db, database, table)
+	orderByClause, err := d.OrderByClause(conf, db, database, table)
 	if err != nil {
 		return nil, err
 	}
 
-	query := buildSelectQuery(database, table, selectedField, buildWhereCondition(conf, ""), orderByClause)
-	rows, err := db.Query(query)
+	query := buildSelectQuery(quotedDatabase, quotedTable+conf.asOfClause(), selectedField, buildWhereCondition(conf, ""), orderByClause)
+	// all the pages are read from the same source or replica
+	readDB := conf.replicas.pick(db)
+	var pager *tablePager
+	if limit != UnspecifiedSize {
+		query += fmt.Sprintf(" LIMIT %d", limit)
+	} else if conf.FetchRows != UnspecifiedSize {
+		if pager, err = newTablePager(conf, readDB, database, table, selectedField, colTypes, orderByClause); err != nil {
+			return nil, err
+		}
+		if pager != nil {
+			query = pager.pageQuery(nil, 0)
+		}
+	}
+	ctx, cancel := conf.chunkContext(ctx)
+	rows, conn, err := queryTableData(ctx, conf, readDB, query)
 	if err != nil {
+		cancel()
 		return nil, withStack(errors.WithMessage(err, query))
 	}
 
 	return &tableData{
-		database:        database,
-		table:           table,
-		rows:            rows,
-		colTypes:        colTypes,
-		selectedField:   selectedField,
-		escapeBackslash: conf.EscapeBackslash,
-		specCmts: []string{
-			"/*!40101 SET NAMES binary*/;",
-		},
+		database:           database,
+		table:              table,
+		rows:               rows,
+		conn:               conn,
+		colTypes:           colTypes,
+		selectedField:      selectedField,
+		escapeBackslash:    conf.EscapeBackslash,
+		output:             conf.output(),
+		quoteBigIntegers:   conf.QuoteBigIntegers,
+		specCmts:           buildSpecialComments(conf, database, table),
+		specFooters:        buildSpecialFooters(conf, table),
+		mysqldumpCompleted: writesMysqldumpCompleted(conf),
+		pager:              pager,
+		ctx:                ctx,
+		cancel:             cancel,
 	}, nil
 }
 
-func SelectFromSql(conf *Config, db *sql.DB) (TableDataIR, error) {
-	rows, err := db.Query(conf.Sql)
+func SelectFromSql(ctx context.Context, conf *Config, db *sql.DB) (TableDataIR, error) {
+	ctx, cancel := conf.chunkContext(ctx)
+	rows, conn, err := queryTableData(ctx, conf, db, conf.Sql)
 	if err != nil {
+		cancel()
 		return nil, withStack(errors.WithMessage(err, conf.Sql))
 	}
 	colTypes, err := rows.ColumnTypes()
 	if err != nil {
+		rows.Close()
+		if conn != nil {
+			conn.Close()
+		}
+		cancel()
 		return nil, withStack(errors.WithMessage(err, conf.Sql))
 	}
 	return &tableData{
-		database:        "",
-		table:           "",
-		rows:            rows,
-		colTypes:        colTypes,
-		selectedField:   "",
-		escapeBackslash: conf.EscapeBackslash,
-		specCmts: []string{
-			"/*!40101 SET NAMES binary*/;",
-		},
+		database:           "",
+		table:              "",
+		rows:               rows,
+		conn:               conn,
+		colTypes:           colTypes,
+		selectedField:      "",
+		escapeBackslash:    conf.EscapeBackslash,
+		output:             conf.output(),
+		quoteBigIntegers:   conf.QuoteBigIntegers,
+		specCmts:           buildSpecialComments(conf, "", ""),
+		specFooters:        buildSpecialFooters(conf, ""),
+		mysqldumpCompleted: writesMysqldumpCompleted(conf),
+		ctx:                ctx,
+		cancel:             cancel,
 	}, nil
 }
 
+func buildSpecialComments(conf *Config, database, table string) []string {
+	// the settings are only known by MySQL
+	if !conf.writesMySQLSettings() {
+		return nil
+	}
+	var specCmts []string
+	if conf.MysqldumpCompatible {
+		specCmts = append(specCmts, buildMysqldumpHeader(conf, database)...)
+	}
+	// compact mode doesn't write the comments and the default settings, but
+	// the ones asked by the other flags are kept
+	if !conf.Compact {
+		specCmts = append(specCmts, "/*!40101 SET NAMES binary*/;")
+	}
+	if conf.DisableForeignKeyChecks || conf.MysqldumpCompatible {
+		specCmts = append(specCmts, "/*!40014 SET @OLD_FOREIGN_KEY_CHECKS=@@FOREIGN_KEY_CHECKS, FOREIGN_KEY_CHECKS=0*/;")
+	}
+	if conf.DisableUniqueChecks || conf.MysqldumpCompatible {
+		specCmts = append(specCmts, "/*!40014 SET @OLD_UNIQUE_CHECKS=@@UNIQUE_CHECKS, UNIQUE_CHECKS=0*/;")
+	}
+	if conf.MysqldumpCompatible {
+		specCmts = append(specCmts,
+			"/*!40101 SET @OLD_SQL_MODE=@@SQL_MODE, SQL_MODE='NO_AUTO_VALUE_ON_ZERO'*/;",
+			"/*!40111 SET @OLD_SQL_NOTES=@@SQL_NOTES, SQL_NOTES=0*/;",
+		)
+	}
+	if conf.DisableKeys && table != "" {
+		specCmts = append(specCmts, fmt.Sprintf("/*!40000 ALTER TABLE %s DISABLE KEYS*/;", wrapBackTicks(table)))
+	}
+	if conf.NoAutocommit {
+		specCmts = append(specCmts, "SET AUTOCOMMIT=0;")
+	}
+	return specCmts
+}
+
+// buildSpecialFooters returns the statements that undo the session settings
+// of buildSpecialComments, in reverse order.
+func buildSpecialFooters(conf *Config, table string) []string {
+	if !conf.writesMySQLSettings() {
+		return nil
+	}
+	var specFooters []string
+	if conf.NoAutocommit {
+		specFooters = append(specFooters, "COMMIT;")
+	}
+	if conf.DisableKeys && table != "" {
+		specFooters = append(specFooters, fmt.Sprintf("/*!40000 ALTER TABLE %s ENABLE KEYS*/;", wrapBackTicks(table)))
+	}
+	if conf.MysqldumpCompatible {
+		specFooters = append(specFooters,
+			"/*!40111 SET SQL_NOTES=@OLD_SQL_NOTES*/;",
+			"/*!40101 SET SQL_MODE=@OLD_SQL_MODE*/;",
+		)
+	}
+	if conf.DisableUniqueChecks || conf.MysqldumpCompatible {
+		specFooters = append(specFooters, "/*!40014 SET UNIQUE_CHECKS=@OLD_UNIQUE_CHECKS*/;")
+	}
+	if conf.DisableForeignKeyChecks || conf.MysqldumpCompatible {
+		specFooters = append(specFooters, "/*!40014 SET FOREIGN_KEY_CHECKS=@OLD_FOREIGN_KEY_CHECKS*/;")
+	}
+	if conf.MysqldumpCompatible {
+		specFooters = append(specFooters, buildMysqldumpFooter()...)
+	}
+	return specFooters
+}
+
+// buildMysqldumpHeader builds the header comments in the same layout as mysqldump,
+// so that scripts parsing mysqldump output can be used on dumpling output. The
+// comments are left out in compact mode.
+func buildMysqldumpHeader(conf *Config, database string) []string {
+	var header []string
+	if !conf.Compact {
+		serverVersion := "unknown"
+		if conf.ServerInfo.ServerVersion != nil {
+			serverVersion = conf.ServerInfo.ServerVersion.String()
+		}
+		header = append(header,
+			fmt.Sprintf("-- MySQL dump 10.13  Distrib %s, for dumpling", serverVersion),
+			"--",
+			fmt.Sprintf("-- Host: %s    Database: %s", conf.Host, database),
+			"-- ------------------------------------------------------",
+			fmt.Sprintf("-- Server version\t%s", serverVersion),
+			"",
+		)
+	}
+	return append(header,
+		"/*!40101 SET @OLD_CHARACTER_SET_CLIENT=@@CHARACTER_SET_CLIENT*/;",
+		"/*!40101 SET @OLD_CHARACTER_SET_RESULTS=@@CHARACTER_SET_RESULTS*/;",
+		"/*!40101 SET @OLD_COLLATION_CONNECTION=@@COLLATION_CONNECTION*/;",
+	)
+}
+
+// mysqldumpCompletedFooter is the last line of the mysqldump footer, which is
+// followed by the time the file is completed.
+const mysqldumpCompletedFooter = "-- Dump completed on "
+
+func buildMysqldumpFooter() []string {
+	return []string{
+		"/*!40101 SET CHARACTER_SET_CLIENT=@OLD_CHARACTER_SET_CLIENT*/;",
+		"/*!40101 SET CHARACTER_SET_RESULTS=@OLD_CHARACTER_SET_RESULTS*/;",
+		"/*!40101 SET COLLATION_CONNECTION=@OLD_COLLATION_CONNECTION*/;",
+	}
+}
+
+// writesMysqldumpCompleted returns whether the data files end with the line
+// of mysqldump recording when they're completed, which is left out by Compact.
+func writesMysqldumpCompleted(conf *Config) bool {
+	return conf.writesMySQLSettings() && conf.MysqldumpCompatible && !conf.Compact
+}
+
 func buildSelectQuery(database, table string, fields string, where string, orderByClause string) string {
 	var query strings.Builder
 	query.WriteString("SELECT ")
@@ -181,7 +366,7 @@ func buildOrderByClause(conf *Config, db *sql.DB, database, table string) (strin
 	if !conf.SortByPk {
 		return "", nil
 	}
-	if conf.ServerInfo.ServerType == ServerTypeTiDB {
+	if conf.ServerInfo.Capabilities().TiDBRowID {
 		ok, err := SelectTiDBRowID(db, database, table)
 		if err != nil {
 			return "", withStack(err)
@@ -192,15 +377,42 @@ func buildOrderByClause(conf *Config, db *sql.DB, database, table string) (strin
 			return "", nil
 		}
 	}
-	pkName, err := GetPrimaryKeyName(db, database, table)
+	pkColumns, err := GetPrimaryKeyColumns(db, database, table)
 	if err != nil {
 		return "", withStack(err)
 	}
-	tableContainsPriKey := pkName != ""
+	tableContainsPriKey := len(pkColumns) != 0
 	if tableContainsPriKey {
-		return fmt.Sprintf("ORDER BY %s", pkName), nil
+		return buildOrderByColumns(pkColumns), nil
 	}
-	return "", nil
+
+	// fall back to sort by all the columns for small tables without primary key
+	estRows, err := GetTableRows(db, database, table)
+	if err != nil {
+		return "", withStack(err)
+	}
+	if estRows > orderByAllColumnsRowsLimit {
+		log.Debug("skip ordering by all columns due to table is too large",
+			zap.String("database", database), zap.String("table", table),
+			zap.Uint64("estimate rows", estRows))
+		return "", nil
+	}
+	columns, err := GetColumnNames(db, database, table)
+	if err != nil {
+		return "", withStack(err)
+	}
+	return buildOrderByColumns(columns), nil
+}
+
+func buildOrderByColumns(columns []string) string {
+	if len(columns) == 0 {
+		return ""
+	}
+	quotedColumns := make([]string, 0, len(columns))
+	for _, col := range columns {
+		quotedColumns = append(quotedColumns, wrapBackTicks(col))
+	}
+	return fmt.Sprintf("ORDER BY %s", strings.Join(quotedColumns, ","))
 }
 
 func SelectTiDBRowID(db *sql.DB, database, table string) (bool, error) {
@@ -217,6 +429,8 @@ func SelectTiDBRowID(db *sql.DB, database, table string) (bool, error) {
 	return true, nil
 }
 
+// GetColumnTypes returns the column types of the fields of a table, database
+// and table should be quoted if necessary.
 func GetColumnTypes(db *sql.DB, fields, database, table string) ([]*sql.ColumnType, error) {
 	query := fmt.Sprintf("SELECT %s FROM %s.%s LIMIT 1", fields, database, table)
 	rows, err := db.Query(query)
@@ -250,6 +464,137 @@ func GetPrimaryKeyName(db *sql.DB, database, table string) (string, error) {
 	return colName, nil
 }
 
+// GetPrimaryKeyColumns returns all the columns of the primary key of a table,
+// in their order in the key rather than in the table.
+func GetPrimaryKeyColumns(db *sql.DB, database, table string) ([]string, error) {
+	priKeyQuery := `SELECT COLUMN_NAME FROM INFORMATION_SCHEMA.STATISTICS
+		WHERE TABLE_SCHEMA = ? AND TABLE_NAME = ? AND INDEX_NAME = 'PRIMARY' ORDER BY SEQ_IN_INDEX;`
+	stmt, err := db.Prepare(priKeyQuery)
+	if err != nil {
+		return nil, err
+	}
+	defer stmt.Close()
+	rows, err := stmt.Query(database, table)
+	if err != nil {
+		return nil, withStack(errors.WithMessage(err, priKeyQuery))
+	}
+	defer rows.Close()
+
+	var columns oneStrColumnTable
+	for rows.Next() {
+		if err := columns.handleOneRow(rows); err != nil {
+			return nil, errors.WithMessage(err, priKeyQuery)
+		}
+	}
+	return columns.data, withStack(rows.Err())
+}
+
+// GetColumnNames returns all the column names of a table in their defined order.
+func GetColumnNames(db *sql.DB, database, table string) ([]string, error) {
+	query := "SELECT COLUMN_NAME FROM INFORMATION_SCHEMA.COLUMNS " +
+		"WHERE TABLE_SCHEMA = ? AND TABLE_NAME = ? ORDER BY ORDINAL_POSITION;"
+	rows, err := db.Query(query, database, table)
+	if err != nil {
+		return nil, withStack(errors.WithMessage(err, query))
+	}
+	defer rows.Close()
+
+	var columns oneStrColumnTable
+	for rows.Next() {
+		if err := columns.handleOneRow(rows); err != nil {
+			return nil, errors.WithMessage(err, query)
+		}
+	}
+	return columns.data, withStack(rows.Err())
+}
+
+// ListColumns returns the definitions of the columns of a table in their defined order.
+func ListColumns(db *sql.DB, database, table string) ([]ColumnInfo, error) {
+	query := "SELECT COLUMN_NAME, DATA_TYPE, COLUMN_TYPE, IS_NULLABLE, CHARACTER_MAXIMUM_LENGTH, NUMERIC_PRECISION, NUMERIC_SCALE " +
+		"FROM INFORMATION_SCHEMA.COLUMNS WHERE TABLE_SCHEMA = ? AND TABLE_NAME = ? ORDER BY ORDINAL_POSITION;"
+	rows, err := db.Query(query, database, table)
+	if err != nil {
+		return nil, withStack(errors.WithMessage(err, query))
+	}
+	defer rows.Close()
+
+	var columns []ColumnInfo
+	for rows.Next() {
+		var (
+			col                      ColumnInfo
+			columnType, nullable     string
+			length, precision, scale sql.NullInt64
+		)
+		if err := rows.Scan(&col.Name, &col.DataType, &columnType, &nullable, &length, &precision, &scale); err != nil {
+			return nil, withStack(errors.WithMessage(err, query))
+		}
+		col.DataType = strings.ToLower(col.DataType)
+		col.Unsigned = strings.Contains(strings.ToLower(columnType), "unsigned")
+		col.Nullable = nullable == "YES"
+		switch col.DataType {
+		case "char", "varchar", "binary", "varbinary":
+			col.Length = length.Int64
+		case "decimal":
+			col.Precision, col.Scale = precision.Int64, scale.Int64
+		}
+		columns = append(columns, col)
+	}
+	return columns, withStack(rows.Err())
+}
+
+// GetTableRows returns the estimated row count of a table from information_schema.
+func GetTableRows(db *sql.DB, database, table string) (uint64, error) {
+	query := "SELECT TABLE_ROWS FROM INFORMATION_SCHEMA.TABLES WHERE TABLE_SCHEMA = ? AND TABLE_NAME = ?;"
+	var tableRows sql.NullInt64
+	row := db.QueryRow(query, database, table)
+	if err := row.Scan(&tableRows); err != nil {
+		if err == sql.ErrNoRows {
+			return 0, nil
+		}
+		return 0, withStack(errors.WithMessage(err, query))
+	}
+	if !tableRows.Valid || tableRows.Int64 < 0 {
+		return 0, nil
+	}
+	return uint64(tableRows.Int64), nil
+}
+
+type tableStatistics struct {
+	rows       uint64
+	dataLength uint64
+}
+
+// listTableStatistics returns the estimated rows and data length of all the tables in a database.
+func listTableStatistics(db *sql.DB, database string) (map[string]tableStatistics, error) {
+	query := "SELECT TABLE_NAME, TABLE_ROWS, DATA_LENGTH FROM INFORMATION_SCHEMA.TABLES WHERE TABLE_SCHEMA = ?;"
+	rows, err := db.Query(query, database)
+	if err != nil {
+		return nil, withStack(errors.WithMessage(err, query))
+	}
+	defer rows.Close()
+
+	stats := map[string]tableStatistics{}
+	for rows.Next() {
+		var (
+			table      string
+			tableRows  sql.NullInt64
+			dataLength sql.NullInt64
+		)
+		if err := rows.Scan(&table, &tableRows, &dataLength); err != nil {
+			return nil, withStack(errors.WithMessage(err, query))
+		}
+		var stat tableStatistics
+		if tableRows.Valid && tableRows.Int64 > 0 {
+			stat.rows = uint64(tableRows.Int64)
+		}
+		if dataLength.Valid && dataLength.Int64 > 0 {
+			stat.dataLength = uint64(dataLength.Int64)
+		}
+		stats[table] = stat
+	}
+	return stats, withStack(rows.Err())
+}
+
 func GetUniqueIndexName(db *sql.DB, database, table string) (string, error) {
 	uniKeyQuery := "SELECT column_name FROM information_schema.columns " +
 		"WHERE table_schema = ? AND table_name = ? AND column_key = 'UNI';"
@@ -301,6 +646,111 @@ func ShowMasterStatus(db *sql.DB, fieldNum int) ([]string, error) {
 	return oneRow, nil
 }
 
+// ShowSlaveStatus returns the columns of SHOW SLAVE STATUS by name,
+// or nil if the server is not a replica.
+func ShowSlaveStatus(db *sql.DB) (map[string]string, error) {
+	query := "SHOW SLAVE STATUS"
+	rows, err := db.Query(query)
+	if err != nil {
+		return nil, withStack(errors.WithMessage(err, query))
+	}
+	defer rows.Close()
+	columns, err := rows.Columns()
+	if err != nil {
+		return nil, withStack(errors.WithMessage(err, query))
+	}
+	if !rows.Next() {
+		return nil, withStack(rows.Err())
+	}
+	values := make([]sql.NullString, len(columns))
+	addr := make([]interface{}, len(columns))
+	for i := range values {
+		addr[i] = &values[i]
+	}
+	if err := rows.Scan(addr...); err != nil {
+		return nil, withStack(errors.WithMessage(err, query))
+	}
+	status := make(map[string]string, len(columns))
+	for i, column := range columns {
+		status[column] = values[i].String
+	}
+	return status, nil
+}
+
+func StopSlaveSQLThread(db *sql.DB) error {
+	_, err := db.Exec("STOP SLAVE SQL_THREAD")
+	return withStack(err)
+}
+
+func StartSlaveSQLThread(db *sql.DB) error {
+	_, err := db.Exec("START SLAVE SQL_THREAD")
+	return withStack(err)
+}
+
+// ShowThreadsRunning returns the number of running threads of the server, including dumpling's own connections.
+func ShowThreadsRunning(db *sql.DB) (uint64, error) {
+	var oneRow [2]string
+	handleOneRow := func(rows *sql.Rows) error {
+		return rows.Scan(&oneRow[0], &oneRow[1])
+	}
+	query := "SHOW GLOBAL STATUS LIKE 'Threads_running'"
+	if err := simpleQuery(db, query, handleOneRow); err != nil {
+		return 0, errors.WithMessage(err, query)
+	}
+	threadsRunning, err := strconv.ParseUint(oneRow[1], 10, 64)
+	if err != nil {
+		return 0, errors.WithMessagef(err, "fail to parse Threads_running value %s", oneRow[1])
+	}
+	return threadsRunning, nil
+}
+
+// CountRunningProcesses returns the number of non-idle connections of the server.
+func CountRunningProcesses(db *sql.DB) (uint64, error) {
+	var count uint64
+	handleOneRow := func(rows *sql.Rows) error {
+		return rows.Scan(&count)
+	}
+	query := "SELECT COUNT(1) FROM INFORMATION_SCHEMA.PROCESSLIST WHERE COMMAND != 'Sleep'"
+	if err := simpleQuery(db, query, handleOneRow); err != nil {
+		return 0, errors.WithMessage(err, query)
+	}
+	return count, nil
+}
+
+// ListLongQueries returns the id, time and info of the queries running for
+// at least seconds.
+func ListLongQueries(db *sql.DB, seconds uint64) ([]ProcessInfo, error) {
+	query := "SELECT ID, TIME, INFO FROM INFORMATION_SCHEMA.PROCESSLIST WHERE COMMAND = 'Query' AND TIME >= ?"
+	rows, err := db.Query(query, seconds)
+	if err != nil {
+		return nil, withStack(errors.WithMessage(err, query))
+	}
+	defer rows.Close()
+	var processes []ProcessInfo
+	for rows.Next() {
+		var p ProcessInfo
+		var info sql.NullString
+		if err := rows.Scan(&p.ID, &p.Time, &info); err != nil {
+			return nil, withStack(errors.WithMessage(err, query))
+		}
+		p.Info = info.String
+		processes = append(processes, p)
+	}
+	return processes, withStack(rows.Err())
+}
+
+// ProcessInfo is a row of INFORMATION_SCHEMA.PROCESSLIST.
+type ProcessInfo struct {
+	ID   uint64
+	Time uint64
+	Info string
+}
+
+func KillQuery(db *sql.DB, id uint64) error {
+	_, err := db.Exec(fmt.Sprintf("KILL QUERY %d", id))
+	return withStack(err)
+}
+
 func SetTiDBSnapshot(db *sql.DB, snapshot string) error {
 	_, err := db.Exec("SET SESSION tidb_snapshot = ?", snapshot)
 	return withStack(err)
@@ -377,8 +827,11 @@ func simpleQuery(db *sql.DB, sql string, handleOneRow func(*sql.Rows) error) err
 }
 
 func pickupPossibleField(dbName, tableName string, db *sql.DB, conf *Config) (string, error) {
+	if conf.ChunkColumn != "" {
+		return conf.ChunkColumn, nil
+	}
 	// If detected server is TiDB, try using _tidb_rowid
-	if conf.ServerInfo.ServerType == ServerTypeTiDB {
+	if conf.ServerInfo.Capabilities().TiDBRowID {
 		ok, err := SelectTiDBRowID(db, dbName, tableName)
 		if err != nil {
 			return "", nil
@@ -473,13 +926,11 @@ func detectEstimateRows(db *sql.DB, query string, fieldNames []string) int {
 	addr := make([]interface{}, len(columns))
 	oneRow := make([]sql.NullString, len(columns))
 	var fieldIndex = -1
-found:
-	for i, row := range oneRow {
-		addr[i] = &row
+	for i := range oneRow {
+		addr[i] = &oneRow[i]
 		for _, fieldName := range fieldNames {
-			if strings.EqualFold(columns[i], fieldName) {
+			if fieldIndex < 0 && strings.EqualFold(columns[i], fieldName) {
 				fieldIndex = i
-				break found
 			}
 		}
 	}
@@ -506,7 +957,12 @@ func buildWhereCondition(conf *Config, where string) string {
 		query.WriteString(" ")
 		query.WriteString(separator)
 		query.WriteString(" ")
-		query.WriteString(conf.Where)
+		// the OR of conf.Where mustn't take the conditions of where out of it
+		if where != "" {
+			query.WriteString("(" + conf.Where + ")")
+		} else {
+			query.WriteString(conf.Where)
+		}
 		separator = "AND"
 	}
 	if where != "" {
@@ -0,0 +1,123 @@
+package export
+
+import (
+	"bytes"
+)
+
+// sqlByteKind is the kind of the bytes of SQL scanned by sqlScanner.
+type sqlByteKind int
+
+const (
+	// sqlCode is outside of the literals and the comments.
+	sqlCode sqlByteKind = iota
+	// sqlLiteral is a quoted string or identifier, including the quotation marks.
+	sqlLiteral
+	// sqlComment is a comment of "-- ", "#" or "/* */".
+	sqlComment
+	// sqlExecutableComment is a comment like /*!40101 ... */, which is executed by MySQL.
+	sqlExecutableComment
+	// sqlIncomplete is returned if the bytes after the scanned ones are needed
+	// to tell the kind.
+	sqlIncomplete
+)
+
+// sqlScanner tells the quoted strings, identifiers and comments of SQL apart
+// from the code, so that the quotation marks in the comments and the
+// delimiters in the literals are ignored. It's shared by the statements of
+// the hooks and the statements executed on the target.
+type sqlScanner struct {
+	escapeBackslash bool
+
+	// quote is the quotation mark of the current literal, 0 if it's not in a
+	// literal
+	quote   byte
+	escaped bool
+	// comment is '-' in a line comment, '*' in a block comment, 0 otherwise
+	comment    byte
+	executable bool
+}
+
+// scan returns the kind of the bytes at the start of b and how many of them
+// are scanned. It's sqlIncomplete and 0 if b may start a comment but the
+// bytes after b are needed to tell, unless eof is set. The newline ending a
+// line comment is code.
+func (s *sqlScanner) scan(b []byte, eof bool) (sqlByteKind, int) {
+	c := b[0]
+	switch {
+	case s.escaped:
+		s.escaped = false
+		return sqlLiteral, 1
+	case s.quote != 0:
+		if c == '\\' && s.escapeBackslash && s.quote != '`' {
+			s.escaped = true
+		} else if c == s.quote {
+			// the doubled quotation marks end and start the literal again
+			s.quote = 0
+		}
+		return sqlLiteral, 1
+	case s.comment == '-':
+		if c == '\n' {
+			s.comment = 0
+			return sqlCode, 1
+		}
+		return sqlComment, 1
+	case s.comment == '*':
+		kind := sqlComment
+		if s.executable {
+			kind = sqlExecutableComment
+		}
+		if c != '*' {
+			return kind, 1
+		}
+		if len(b) < 2 {
+			if !eof {
+				return sqlIncomplete, 0
+			}
+			return kind, 1
+		}
+		if b[1] == '/' {
+			s.comment = 0
+			return kind, 2
+		}
+		return kind, 1
+	case c == '\'' || c == '"' || c == '`':
+		s.quote = c
+		return sqlLiteral, 1
+	case c == '#':
+		s.comment = '-'
+		return sqlComment, 1
+	case c == '-':
+		if len(b) < 3 && !eof && (len(b) < 2 || b[1] == '-') {
+			return sqlIncomplete, 0
+		}
+		if isDashComment(b) {
+			s.comment = '-'
+			return sqlComment, 1
+		}
+		return sqlCode, 1
+	case c == '/':
+		if len(b) < 3 && !eof && (len(b) < 2 || b[1] == '*') {
+			return sqlIncomplete, 0
+		}
+		if !bytes.HasPrefix(b, []byte("/*")) {
+			return sqlCode, 1
+		}
+		s.comment = '*'
+		s.executable = bytes.HasPrefix(b, []byte("/*!"))
+		if s.executable {
+			return sqlExecutableComment, 2
+		}
+		return sqlComment, 2
+	default:
+		return sqlCode, 1
+	}
+}
+
+// isDashComment returns whether b starts with a comment of "-- ", the double
+// dashes not followed by a blank are not a comment in MySQL.
+func isDashComment(b []byte) bool {
+	if !bytes.HasPrefix(b, []byte("--")) {
+		return false
+	}
+	return len(b) == 2 || b[2] == ' ' || b[2] == '\t' || b[2] == '\n' || b[2] == '\r'
+}
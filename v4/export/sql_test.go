@@ -101,7 +101,7 @@ func (s *testDumpSuite) TestBuildSelectAllQuery(c *C) {
 	for _, serverTp := range otherServers {
 		mockConf.ServerInfo.ServerType = serverTp
 		cmt := Commentf("server type: %s", serverTp)
-		mock.ExpectPrepare("SELECT column_name FROM information_schema.columns").
+		mock.ExpectPrepare("SELECT COLUMN_NAME FROM INFORMATION_SCHEMA.STATISTICS").
 			ExpectQuery().WithArgs("test", "t").
 			WillReturnRows(sqlmock.NewRows([]string{"column_name"}).AddRow("id"))
 		orderByClause, err := buildOrderByClause(mockConf, db, "test", "t")
@@ -114,7 +114,7 @@ func (s *testDumpSuite) TestBuildSelectAllQuery(c *C) {
 		selectedField, err = buildSelectField(db, "test", "t")
 		c.Assert(err, IsNil)
 		q = buildSelectQuery("test", "t", selectedField, "", orderByClause)
-		c.Assert(q, Equals, "SELECT * FROM test.t ORDER BY id", cmt)
+		c.Assert(q, Equals, "SELECT * FROM test.t ORDER BY `id`", cmt)
 		err = mock.ExpectationsWereMet()
 		c.Assert(err, IsNil, cmt)
 		c.Assert(mock.ExpectationsWereMet(), IsNil, cmt)
@@ -124,9 +124,11 @@ func (s *testDumpSuite) TestBuildSelectAllQuery(c *C) {
 	for _, serverTp := range otherServers {
 		mockConf.ServerInfo.ServerType = serverTp
 		cmt := Commentf("server type: %s", serverTp)
-		mock.ExpectPrepare("SELECT column_name FROM information_schema.columns").
+		mock.ExpectPrepare("SELECT COLUMN_NAME FROM INFORMATION_SCHEMA.STATISTICS").
 			ExpectQuery().WithArgs("test", "t").
 			WillReturnRows(sqlmock.NewRows([]string{"column_name"}))
+		mock.ExpectQuery("SELECT TABLE_ROWS FROM INFORMATION_SCHEMA.TABLES").WithArgs("test", "t").
+			WillReturnRows(sqlmock.NewRows([]string{"TABLE_ROWS"}).AddRow(orderByAllColumnsRowsLimit + 1))
 
 		orderByClause, err := buildOrderByClause(mockConf, db, "test", "t")
 		c.Assert(err, IsNil, cmt)
@@ -144,6 +146,34 @@ func (s *testDumpSuite) TestBuildSelectAllQuery(c *C) {
 		c.Assert(mock.ExpectationsWereMet(), IsNil)
 	}
 
+	// Test small table without primary key, sort by all the columns.
+	for _, serverTp := range otherServers {
+		mockConf.ServerInfo.ServerType = serverTp
+		cmt := Commentf("server type: %s", serverTp)
+		mock.ExpectPrepare("SELECT COLUMN_NAME FROM INFORMATION_SCHEMA.STATISTICS").
+			ExpectQuery().WithArgs("test", "t").
+			WillReturnRows(sqlmock.NewRows([]string{"column_name"}))
+		mock.ExpectQuery("SELECT TABLE_ROWS FROM INFORMATION_SCHEMA.TABLES").WithArgs("test", "t").
+			WillReturnRows(sqlmock.NewRows([]string{"TABLE_ROWS"}).AddRow(10))
+		mock.ExpectQuery("SELECT COLUMN_NAME FROM INFORMATION_SCHEMA.COLUMNS").WithArgs("test", "t").
+			WillReturnRows(sqlmock.NewRows([]string{"COLUMN_NAME"}).AddRow("id").AddRow("name"))
+
+		orderByClause, err := buildOrderByClause(mockConf, db, "test", "t")
+		c.Assert(err, IsNil, cmt)
+		c.Assert(orderByClause, Equals, "ORDER BY `id`,`name`", cmt)
+		c.Assert(mock.ExpectationsWereMet(), IsNil, cmt)
+	}
+
+	// Test table with composite primary key, which is ordered by the key rather than the table.
+	mockConf.ServerInfo.ServerType = ServerTypeMySQL
+	mock.ExpectPrepare("(?s)SELECT COLUMN_NAME FROM INFORMATION_SCHEMA.STATISTICS.*INDEX_NAME = 'PRIMARY' ORDER BY SEQ_IN_INDEX").
+		ExpectQuery().WithArgs("test", "t").
+		WillReturnRows(sqlmock.NewRows([]string{"column_name"}).AddRow("b").AddRow("a"))
+	orderByClause, err = buildOrderByClause(mockConf, db, "test", "t")
+	c.Assert(err, IsNil)
+	c.Assert(orderByClause, Equals, "ORDER BY `b`,`a`")
+	c.Assert(mock.ExpectationsWereMet(), IsNil)
+
 	// Test when config.SortByPk is disabled.
 	mockConf.SortByPk = false
 	for tp := ServerTypeUnknown; tp < ServerTypeAll; tp += 1 {
@@ -190,6 +220,72 @@ func (s *testDumpSuite) TestBuildSelectField(c *C) {
 
 }
 
+func (s *testDumpSuite) TestDetectEstimateRows(c *C) {
+	db, mock, err := sqlmock.New()
+	c.Assert(err, IsNil)
+	defer db.Close()
+
+	// every column is scanned, not only the ones before the estimated rows
+	mock.ExpectQuery("EXPLAIN SELECT").WillReturnRows(
+		sqlmock.NewRows([]string{"id", "select_type", "table", "rows", "filtered", "Extra"}).
+			AddRow("1", "SIMPLE", "t", "15000049", "100.00", "Using index"))
+	c.Assert(detectEstimateRows(db, "EXPLAIN SELECT `id` FROM `test`.`t`", []string{"rows", "estRows", "count"}), Equals, 15000049)
+	mock.ExpectQuery("EXPLAIN SELECT").WillReturnRows(
+		sqlmock.NewRows([]string{"id", "estRows", "task"}).AddRow("tablereader_5", "10000.00", "root"))
+	c.Assert(detectEstimateRows(db, "EXPLAIN SELECT `id` FROM `test`.`t`", []string{"rows", "estRows", "count"}), Equals, 10000)
+	c.Assert(mock.ExpectationsWereMet(), IsNil)
+}
+
+func (s *testDumpSuite) TestBuildMysqldumpSpecialComments(c *C) {
+	conf := DefaultConfig()
+	conf.MysqldumpCompatible = true
+	conf.ServerInfo.ServerVersion = makeVersion(8, 0, 18, "")
+
+	specCmts := buildSpecialComments(conf, "test", "t")
+	c.Assert(specCmts[:5], DeepEquals, []string{
+		"-- MySQL dump 10.13  Distrib 8.0.18, for dumpling",
+		"--",
+		"-- Host: 127.0.0.1    Database: test",
+		"-- ------------------------------------------------------",
+		"-- Server version\t8.0.18",
+	})
+	c.Assert(specCmts, HasLen, 14)
+	c.Assert(specCmts[9], Equals, "/*!40101 SET NAMES binary*/;")
+
+	specFooters := buildSpecialFooters(conf, "t")
+	c.Assert(specFooters, HasLen, 7)
+	c.Assert(specFooters[0], Equals, "/*!40111 SET SQL_NOTES=@OLD_SQL_NOTES*/;")
+	c.Assert(writesMysqldumpCompleted(conf), IsTrue)
+
+	// compact mode suppresses the comments and SET NAMES, but keeps the settings
+	conf.Compact = true
+	conf.DisableKeys = true
+	conf.NoAutocommit = true
+	specCmts = buildSpecialComments(conf, "test", "t")
+	c.Assert(specCmts, HasLen, 9)
+	c.Assert(specCmts[0], Equals, "/*!40101 SET @OLD_CHARACTER_SET_CLIENT=@@CHARACTER_SET_CLIENT*/;")
+	c.Assert(specCmts[3], Equals, "/*!40014 SET @OLD_FOREIGN_KEY_CHECKS=@@FOREIGN_KEY_CHECKS, FOREIGN_KEY_CHECKS=0*/;")
+	c.Assert(specCmts[7], Equals, "/*!40000 ALTER TABLE `t` DISABLE KEYS*/;")
+	c.Assert(specCmts[8], Equals, "SET AUTOCOMMIT=0;")
+	specFooters = buildSpecialFooters(conf, "t")
+	c.Assert(specFooters, HasLen, 9)
+	c.Assert(writesMysqldumpCompleted(conf), IsFalse)
+	c.Assert(specFooters[0], Equals, "COMMIT;")
+	c.Assert(specFooters[1], Equals, "/*!40000 ALTER TABLE `t` ENABLE KEYS*/;")
+	c.Assert(specFooters[8], Equals, "/*!40101 SET COLLATION_CONNECTION=@OLD_COLLATION_CONNECTION*/;")
+
+	conf.MysqldumpCompatible = false
+	conf.DisableKeys = false
+	conf.NoAutocommit = false
+	c.Assert(buildSpecialComments(conf, "test", "t"), HasLen, 0)
+	c.Assert(buildSpecialFooters(conf, "t"), HasLen, 0)
+	conf.DisableForeignKeyChecks = true
+	c.Assert(buildSpecialComments(conf, "test", "t"), DeepEquals,
+		[]string{"/*!40014 SET @OLD_FOREIGN_KEY_CHECKS=@@FOREIGN_KEY_CHECKS, FOREIGN_KEY_CHECKS=0*/;"})
+	c.Assert(buildSpecialFooters(conf, "t"), DeepEquals,
+		[]string{"/*!40014 SET FOREIGN_KEY_CHECKS=@OLD_FOREIGN_KEY_CHECKS*/;"})
+}
+
 func makeVersion(major, minor, patch int64, preRelease string) *semver.Version {
 	return &semver.Version{
 		Major:      major,
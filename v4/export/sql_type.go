@@ -3,7 +3,7 @@ package export
 import (
 	"bytes"
 	"database/sql"
-	"fmt"
+	"encoding/hex"
 )
 
 var colTypeRowReceiverMap = map[string]func() RowReceiverStringer{}
@@ -11,8 +11,7 @@ var colTypeRowReceiverMap = map[string]func() RowReceiverStringer{}
 var nullValue = "NULL"
 var quotationMark byte = '\''
 var doubleQuotationMark byte = '"'
-var quotationMarkNotQuote = []byte{quotationMark}
-var quotationMarkQuote = []byte{quotationMark, quotationMark}
+var tsvNullValue = "\\N"
 
 func init() {
 	for _, s := range dataTypeString {
@@ -24,6 +23,10 @@ func init() {
 	for _, s := range dataTypeBin {
 		colTypeRowReceiverMap[s] = SQLTypeBytesMaker
 	}
+	for _, s := range dataTypeNumPostgres {
+		colTypeRowReceiverMap[s] = SQLTypeNumberMaker
+	}
+	colTypeRowReceiverMap["BYTEA"] = SQLTypeBytesMaker
 }
 
 var dataTypeString = []string{
@@ -41,62 +44,18 @@ var dataTypeNum = []string{
 	"BOOL", "BOOLEAN",
 }
 
+// dataTypeNumPostgres are the numeric types of PostgreSQL not listed above,
+// the other types of PostgreSQL are written as strings.
+var dataTypeNumPostgres = []string{
+	"INT4", "FLOAT4", "FLOAT8", "OID",
+}
+
 var dataTypeBin = []string{
 	"BLOB", "TINYBLOB", "MEDIUMBLOB", "LONGBLOB", "LONG",
 	"BINARY", "VARBINARY",
 	"BIT",
 }
 
-func escape(s []byte, bf *bytes.Buffer, escapeBackslash bool) {
-	if !escapeBackslash {
-		bf.Write(bytes.ReplaceAll(s, quotationMarkNotQuote, quotationMarkQuote))
-		return
-	}
-	var (
-		escape byte
-		last   = 0
-	)
-	// reference: https://gist.github.com/siddontang/8875771
-	for i := 0; i < len(s); i++ {
-		escape = 0
-
-		switch s[i] {
-		case 0: /* Must be escaped for 'mysql' */
-			escape = '0'
-			break
-		case '\n': /* Must be escaped for logs */
-			escape = 'n'
-			break
-		case '\r':
-			escape = 'r'
-			break
-		case '\\':
-			escape = '\\'
-			break
-		case '\'':
-			escape = '\''
-			break
-		case '"': /* Better safe than sorry */
-			escape = '"'
-			break
-		case '\032': /* This gives problems on Win32 */
-			escape = 'Z'
-		}
-
-		if escape != 0 {
-			bf.Write(s[last:i])
-			bf.WriteByte('\\')
-			bf.WriteByte(escape)
-			last = i + 1
-		}
-	}
-	if last == 0 {
-		bf.Write(s)
-	} else if last < len(s) {
-		bf.Write(s[last:])
-	}
-}
-
 func SQLTypeStringMaker() RowReceiverStringer {
 	return &SQLTypeString{}
 }
@@ -110,6 +69,12 @@ func SQLTypeNumberMaker() RowReceiverStringer {
 }
 
 func MakeRowReceiver(colTypes []string) RowReceiverStringer {
+	return makeRowReceiver(colTypes, mysqlOutput{}, false)
+}
+
+// makeRowReceiver makes the receivers which write the binary literals of d,
+// and quote the integers out of the range of BIGINT if quoteBigIntegers.
+func makeRowReceiver(colTypes []string, d OutputDialect, quoteBigIntegers bool) RowReceiverStringer {
 	rowReceiverArr := make(RowReceiverArr, len(colTypes))
 	for i, colTp := range colTypes {
 		recMaker, ok := colTypeRowReceiverMap[colTp]
@@ -117,6 +82,12 @@ func MakeRowReceiver(colTypes []string) RowReceiverStringer {
 			recMaker = SQLTypeStringMaker
 		}
 		rowReceiverArr[i] = recMaker()
+		switch receiver := rowReceiverArr[i].(type) {
+		case *SQLTypeBytes:
+			receiver.output = d
+		case *SQLTypeNumber:
+			receiver.quoteBigIntegers = quoteBigIntegers
+		}
 	}
 	return rowReceiverArr
 }
@@ -156,13 +127,26 @@ func (r RowReceiverArr) WriteToBufferInCsv(bf *bytes.Buffer, escapeBackslash boo
 	}
 }
 
+func (r RowReceiverArr) WriteToBufferInTsv(bf *bytes.Buffer) {
+	for i, receiver := range r {
+		receiver.WriteToBufferInTsv(bf)
+		if i != len(r)-1 {
+			bf.WriteByte('\t')
+		}
+	}
+}
+
 type SQLTypeNumber struct {
 	SQLTypeString
+	// quoteBigIntegers quotes the integers out of the range of BIGINT, so that
+	// the targets parsing the integer literals as signed 64-bit read them as
+	// strings, which are cast by the column types.
+	quoteBigIntegers bool
 }
 
 func (s SQLTypeNumber) WriteToBuffer(bf *bytes.Buffer, _ bool) {
 	if s.RawBytes != nil {
-		bf.Write(s.RawBytes)
+		s.writeNumber(bf, quotationMark)
 	} else {
 		bf.WriteString(nullValue)
 	}
@@ -170,12 +154,65 @@ func (s SQLTypeNumber) WriteToBuffer(bf *bytes.Buffer, _ bool) {
 
 func (s SQLTypeNumber) WriteToBufferInCsv(bf *bytes.Buffer, _ bool, csvNullValue string) {
 	if s.RawBytes != nil {
-		bf.Write(s.RawBytes)
+		s.writeNumber(bf, doubleQuotationMark)
 	} else {
 		bf.WriteString(csvNullValue)
 	}
 }
 
+func (s SQLTypeNumber) WriteToBufferInTsv(bf *bytes.Buffer) {
+	if s.RawBytes != nil {
+		bf.Write(s.RawBytes)
+	} else {
+		bf.WriteString(tsvNullValue)
+	}
+}
+
+// writeNumber writes the number, which is enclosed by quote if it's an integer
+// out of the range of BIGINT and quoteBigIntegers is set.
+func (s SQLTypeNumber) writeNumber(bf *bytes.Buffer, quote byte) {
+	if s.quoteBigIntegers && isBigInteger(s.RawBytes) {
+		bf.WriteByte(quote)
+		bf.Write(s.RawBytes)
+		bf.WriteByte(quote)
+		return
+	}
+	bf.Write(s.RawBytes)
+}
+
+// isBigInteger returns whether b is an integer out of the range of BIGINT,
+// e.g. a BIGINT UNSIGNED above 2^63-1. The zeros padded by ZEROFILL are
+// ignored.
+func isBigInteger(b []byte) bool {
+	limit := "9223372036854775807"
+	if len(b) > 0 && (b[0] == '-' || b[0] == '+') {
+		if b[0] == '-' {
+			limit = "9223372036854775808"
+		}
+		b = b[1:]
+	}
+	if len(b) == 0 {
+		return false
+	}
+	for _, c := range b {
+		if c < '0' || c > '9' {
+			return false
+		}
+	}
+	b = trimZeroFill(b)
+	return len(b) > len(limit) || len(b) == len(limit) && string(b) > limit
+}
+
+// trimZeroFill trims the leading zeros of the number b padded by ZEROFILL,
+// e.g. 00042 and 001.50, which aren't numbers of JSON.
+func trimZeroFill(b []byte) []byte {
+	i := 0
+	for i+1 < len(b) && b[i] == '0' && b[i+1] >= '0' && b[i+1] <= '9' {
+		i++
+	}
+	return b[i:]
+}
+
 type SQLTypeString struct {
 	sql.RawBytes
 }
@@ -193,7 +230,7 @@ func (s *SQLTypeString) ReportSize() uint64 {
 func (s *SQLTypeString) WriteToBuffer(bf *bytes.Buffer, escapeBackslash bool) {
 	if s.RawBytes != nil {
 		bf.WriteByte(quotationMark)
-		escape(s.RawBytes, bf, escapeBackslash)
+		sqlEscaper(escapeBackslash).Escape(bf, s.RawBytes)
 		bf.WriteByte(quotationMark)
 	} else {
 		bf.WriteString(nullValue)
@@ -203,15 +240,25 @@ func (s *SQLTypeString) WriteToBuffer(bf *bytes.Buffer, escapeBackslash bool) {
 func (s *SQLTypeString) WriteToBufferInCsv(bf *bytes.Buffer, escapeBackslash bool, csvNullValue string) {
 	if s.RawBytes != nil {
 		bf.WriteByte(doubleQuotationMark)
-		escape(s.RawBytes, bf, escapeBackslash)
+		csvEscaper(escapeBackslash).Escape(bf, s.RawBytes)
 		bf.WriteByte(doubleQuotationMark)
 	} else {
 		bf.WriteString(csvNullValue)
 	}
 }
 
+func (s *SQLTypeString) WriteToBufferInTsv(bf *bytes.Buffer) {
+	if s.RawBytes != nil {
+		TSVEscaper.Escape(bf, s.RawBytes)
+	} else {
+		bf.WriteString(tsvNullValue)
+	}
+}
+
 type SQLTypeBytes struct {
 	sql.RawBytes
+	// output writes the binary literal, it's MySQL if nil
+	output OutputDialect
 }
 
 func (s *SQLTypeBytes) BindAddress(arg []interface{}) {
@@ -222,7 +269,38 @@ func (s *SQLTypeBytes) ReportSize() uint64 {
 }
 
 func (s *SQLTypeBytes) WriteToBuffer(bf *bytes.Buffer, _ bool) {
-	fmt.Fprintf(bf, "x'%x'", s.RawBytes)
+	if s.RawBytes == nil {
+		bf.WriteString(nullValue)
+		return
+	}
+	prefix, suffix := s.binaryLiteral()
+	bf.WriteString(prefix)
+	writeHex(bf, s.RawBytes)
+	bf.WriteString(suffix)
+}
+
+// binaryLiteral returns the prefix and the suffix of the binary literals of
+// the output dialect.
+func (s *SQLTypeBytes) binaryLiteral() (string, string) {
+	if s.output == nil {
+		return mysqlOutput{}.BinaryLiteral()
+	}
+	return s.output.BinaryLiteral()
+}
+
+// writeHex writes the hex encoding of src to bf through a stack buffer, so that
+// it doesn't allocate.
+func writeHex(bf *bytes.Buffer, src []byte) {
+	var dst [256]byte
+	for len(src) > 0 {
+		n := len(src)
+		if n > len(dst)/2 {
+			n = len(dst) / 2
+		}
+		hex.Encode(dst[:], src[:n])
+		bf.Write(dst[:2*n])
+		src = src[n:]
+	}
 }
 
 func (s *SQLTypeBytes) WriteToBufferInCsv(bf *bytes.Buffer, _ bool, csvNullValue string) {
@@ -234,3 +312,13 @@ func (s *SQLTypeBytes) WriteToBufferInCsv(bf *bytes.Buffer, _ bool, csvNullValue
 		bf.WriteString(csvNullValue)
 	}
 }
+
+// WriteToBufferInTsv writes the raw bytes, which are loadable into the String
+// of ClickHouse.
+func (s *SQLTypeBytes) WriteToBufferInTsv(bf *bytes.Buffer) {
+	if s.RawBytes != nil {
+		TSVEscaper.Escape(bf, s.RawBytes)
+	} else {
+		bf.WriteString(tsvNullValue)
+	}
+}
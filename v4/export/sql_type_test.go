@@ -2,6 +2,10 @@ package export
 
 import (
 	"bytes"
+	"context"
+	"database/sql/driver"
+	"strings"
+	"testing"
 
 	. "github.com/pingcap/check"
 )
@@ -15,9 +19,115 @@ func (s *testSqlByteSuite) TestEscape(c *C) {
 	str := []byte(`MWQeWw""'\rNmtGxzGp`)
 	expectStrBackslash := `MWQeWw\"\"\'\\rNmtGxzGp`
 	expectStrWithoutBackslash := `MWQeWw""''\rNmtGxzGp`
-	escape(str, &bf, true)
+	sqlEscaper(true).Escape(&bf, str)
 	c.Assert(bf.String(), Equals, expectStrBackslash)
 	bf.Reset()
-	escape(str, &bf, false)
+	sqlEscaper(false).Escape(&bf, str)
 	c.Assert(bf.String(), Equals, expectStrWithoutBackslash)
+	bf.Reset()
+	csvEscaper(true).Escape(&bf, str)
+	c.Assert(bf.String(), Equals, expectStrBackslash)
+	bf.Reset()
+	csvEscaper(false).Escape(&bf, str)
+	c.Assert(bf.String(), Equals, `MWQeWw""""'\rNmtGxzGp`)
+}
+
+func (s *testSqlByteSuite) TestWriteBytesToBuffer(c *C) {
+	var bf bytes.Buffer
+	receiver := &SQLTypeBytes{RawBytes: []byte{0x01, 0xab, 0xff}}
+	receiver.WriteToBuffer(&bf, true)
+	c.Assert(bf.String(), Equals, "x'01abff'")
+
+	bf.Reset()
+	long := bytes.Repeat([]byte{0x5a}, 300)
+	receiver.RawBytes = long
+	receiver.WriteToBuffer(&bf, true)
+	c.Assert(bf.String(), Equals, "x'"+strings.Repeat("5a", 300)+"'")
+}
+
+func (s *testSqlByteSuite) TestWriteToBufferNoAllocation(c *C) {
+	row := MakeRowReceiver([]string{"INT", "VARCHAR", "BLOB"}).(RowReceiverArr)
+	row[0].(*SQLTypeNumber).RawBytes = []byte("1")
+	row[1].(*SQLTypeString).RawBytes = []byte(`it's "quoted"`)
+	row[2].(*SQLTypeBytes).RawBytes = bytes.Repeat([]byte{0xab}, 1024)
+
+	var bf bytes.Buffer
+	bf.Grow(1 << 16)
+	for _, escapeBackslash := range []bool{true, false} {
+		allocs := testing.AllocsPerRun(100, func() {
+			bf.Reset()
+			row.WriteToBuffer(&bf, escapeBackslash)
+			row.WriteToBufferInCsv(&bf, escapeBackslash, "\\N")
+		})
+		c.Assert(allocs, Equals, float64(0))
+	}
+}
+
+func (s *testSqlByteSuite) TestIsBigInteger(c *C) {
+	for _, t := range []struct {
+		number string
+		big    bool
+	}{
+		{"0", false},
+		{"9223372036854775807", false},
+		{"9223372036854775808", true},
+		{"18446744073709551615", true},
+		{"-9223372036854775808", false},
+		{"-9223372036854775809", true},
+		{"+9223372036854775808", true},
+		// ZEROFILL
+		{"00000000000000000042", false},
+		{"000009223372036854775808", true},
+		{"100000000000000000000", true},
+		{"18446744073709551615.5", false},
+		{"1e30", false},
+		{"-", false},
+		{"", false},
+	} {
+		c.Assert(isBigInteger([]byte(t.number)), Equals, t.big, Commentf("number %s", t.number))
+	}
+	c.Assert(string(trimZeroFill([]byte("00042"))), Equals, "42")
+	c.Assert(string(trimZeroFill([]byte("001.50"))), Equals, "1.50")
+	c.Assert(string(trimZeroFill([]byte("0000"))), Equals, "0")
+	c.Assert(string(trimZeroFill([]byte("0.5"))), Equals, "0.5")
+}
+
+func (s *testSqlByteSuite) TestQuoteBigIntegers(c *C) {
+	data := [][]driver.Value{
+		{"18446744073709551615", "9223372036854775807"},
+		{"-9223372036854775809", "00000000000000000042"},
+	}
+	tableIR := newMockTableIR("test", "t", data, nil, []string{"BIGINT", "BIGINT"})
+	bf := &bytes.Buffer{}
+	c.Assert(WriteInsert(context.Background(), tableIR, bf, UnspecifiedSize, nil), IsNil)
+	c.Assert(bf.String(), Equals, "INSERT INTO `t` VALUES\n"+
+		"(18446744073709551615,9223372036854775807),\n"+
+		"(-9223372036854775809,00000000000000000042);\n")
+
+	tableIR.(*mockTableIR).quoteBigIntegers = true
+	bf.Reset()
+	c.Assert(WriteInsert(context.Background(), tableIR, bf, UnspecifiedSize, nil), IsNil)
+	c.Assert(bf.String(), Equals, "INSERT INTO `t` VALUES\n"+
+		"('18446744073709551615',9223372036854775807),\n"+
+		"('-9223372036854775809',00000000000000000042);\n")
+	bf.Reset()
+	c.Assert(WriteInsertInCsv(context.Background(), tableIR, bf, true, "\\N", nil), IsNil)
+	c.Assert(bf.String(), Equals, "\"18446744073709551615\",9223372036854775807\n"+
+		"\"-9223372036854775809\",00000000000000000042\n")
+	// the values of TSV are never quoted
+	bf.Reset()
+	c.Assert(WriteInsertInTsv(context.Background(), tableIR, bf, true, nil), IsNil)
+	c.Assert(bf.String(), Equals, "18446744073709551615\t9223372036854775807\n"+
+		"-9223372036854775809\t00000000000000000042\n")
+
+	row := makeRowReceiver([]string{"BIGINT"}, mysqlOutput{}, true).(RowReceiverArr)
+	row[0].(*SQLTypeNumber).RawBytes = []byte("18446744073709551615")
+	var buf bytes.Buffer
+	buf.Grow(1 << 10)
+	allocs := testing.AllocsPerRun(100, func() {
+		buf.Reset()
+		row.WriteToBuffer(&buf, true)
+		row.WriteToBufferInCsv(&buf, true, "\\N")
+	})
+	c.Assert(allocs, Equals, float64(0))
 }
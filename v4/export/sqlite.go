@@ -0,0 +1,117 @@
+package export
+
+import (
+	"context"
+	"database/sql"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sync"
+
+	// the database/sql driver of SQLite
+	_ "github.com/mattn/go-sqlite3"
+	"go.uber.org/zap"
+
+	"github.com/pingcap/dumpling/v4/log"
+)
+
+// sqliteFileSuffix is the suffix of the SQLite file of each schema.
+const sqliteFileSuffix = ".sqlite"
+
+// SQLiteWriter writes each schema into the SQLite file <db>.sqlite in
+// OutputDirPath, by executing the statements translated for SQLite.
+// The files are written locally bypassing ExternalStorage, and they keep the
+// partFileSuffix until the dump finishes successfully.
+type SQLiteWriter struct {
+	execWriter
+
+	mu  sync.Mutex
+	dbs map[string]*sql.DB
+}
+
+func NewSQLiteWriter(config *Config) (Writer, error) {
+	if err := os.MkdirAll(config.OutputDirPath, 0755); err != nil {
+		return nil, withKind(ErrorKindWrite, err)
+	}
+	w := &SQLiteWriter{execWriter: newExecWriter(config), dbs: map[string]*sql.DB{}}
+	w.open = w.openConn
+	return w, nil
+}
+
+func (f *SQLiteWriter) WriteDatabaseMeta(ctx context.Context, db, createSQL string) error {
+	return f.writeMeta(ctx, db, createSQL)
+}
+
+func (f *SQLiteWriter) filePath(db string) string {
+	return filepath.Join(f.cfg.OutputDirPath, db+sqliteFileSuffix)
+}
+
+// openConn returns a statementWriter on the connection of the SQLite file of
+// db. SQLite has only one writer at a time, so the tables of a schema are
+// written one by one.
+func (f *SQLiteWriter) openConn(ctx context.Context, db string) (*statementWriter, error) {
+	sqliteDB, err := f.openDB(db)
+	if err != nil {
+		return nil, err
+	}
+	conn, err := sqliteDB.Conn(ctx)
+	if err != nil {
+		return nil, withStack(withKind(ErrorKindWrite, err))
+	}
+	return newStatementWriter(ctx, conn, f.cfg.EscapeBackslash), nil
+}
+
+func (f *SQLiteWriter) openDB(db string) (*sql.DB, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if sqliteDB, ok := f.dbs[db]; ok {
+		return sqliteDB, nil
+	}
+	partPath := f.filePath(db) + partFileSuffix
+	// truncate the file left by the last dump like the other files
+	if err := os.Remove(partPath); err != nil && !os.IsNotExist(err) {
+		return nil, withKind(ErrorKindWrite, err)
+	}
+	// the file is discarded if the dump fails, so it needn't survive crashes
+	dsn := "file:" + (&url.URL{Path: partPath}).EscapedPath() + "?_journal=OFF&_sync=OFF"
+	sqliteDB, err := sql.Open("sqlite3", dsn)
+	if err != nil {
+		return nil, withStack(withKind(ErrorKindWrite, err))
+	}
+	sqliteDB.SetMaxOpenConns(1)
+	f.dbs[db] = sqliteDB
+	return sqliteDB, nil
+}
+
+// Close closes the SQLite files and renames them to the final names.
+func (f *SQLiteWriter) Close() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for db, sqliteDB := range f.dbs {
+		if err := sqliteDB.Close(); err != nil {
+			return withStack(withKind(ErrorKindWrite, err))
+		}
+		filePath := f.filePath(db)
+		if err := os.Rename(filePath+partFileSuffix, filePath); err != nil {
+			return withKind(ErrorKindWrite, err)
+		}
+		delete(f.dbs, db)
+		log.Debug("finish writing SQLite file", zap.String("path", filePath))
+		f.cfg.hooks().OnFileClosed(filePath)
+	}
+	return nil
+}
+
+// Abort closes the SQLite files, which keep the partFileSuffix as incomplete.
+func (f *SQLiteWriter) Abort() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	var err error
+	for db, sqliteDB := range f.dbs {
+		if closeErr := sqliteDB.Close(); err == nil && closeErr != nil {
+			err = withStack(withKind(ErrorKindWrite, closeErr))
+		}
+		delete(f.dbs, db)
+	}
+	return err
+}
@@ -0,0 +1,99 @@
+package export
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"os"
+	"path/filepath"
+
+	. "github.com/pingcap/check"
+)
+
+var _ = Suite(&testSQLiteSuite{})
+
+type testSQLiteSuite struct{}
+
+func (s *testSQLiteSuite) newWriter(c *C) (*SQLiteWriter, *Config) {
+	conf := DefaultConfig()
+	conf.FileType = "sqlite"
+	conf.OutputDirPath = c.MkDir()
+	writer, err := NewSQLiteWriter(conf)
+	c.Assert(err, IsNil)
+	return writer.(*SQLiteWriter), conf
+}
+
+func (s *testSQLiteSuite) TestWriteSQLite(c *C) {
+	writer, conf := s.newWriter(c)
+	ctx := context.Background()
+	c.Assert(writer.WriteTableMeta(ctx, "test", "t", `CREATE TABLE "t" ("a" INTEGER NOT NULL, "b" TEXT)`), IsNil)
+
+	data := [][]driver.Value{
+		{"1", "a;\nb"},
+		{"2", "it's"},
+		{"3", nil},
+	}
+	tableIR := newMockTableIR("test", "t", data, nil, []string{"INT", "VARCHAR"})
+	c.Assert(writer.WriteTableData(ctx, tableIR), IsNil)
+	filePath := filepath.Join(conf.OutputDirPath, "test.sqlite")
+	_, err := os.Stat(filePath + partFileSuffix)
+	c.Assert(err, IsNil)
+	c.Assert(writer.Close(), IsNil)
+
+	_, err = os.Stat(filePath + partFileSuffix)
+	c.Assert(os.IsNotExist(err), IsTrue)
+	db, err := sql.Open("sqlite3", filePath)
+	c.Assert(err, IsNil)
+	defer db.Close()
+	rows, err := db.Query(`SELECT "a", "b" FROM "t" ORDER BY "a"`)
+	c.Assert(err, IsNil)
+	defer rows.Close()
+	var result [][]interface{}
+	for rows.Next() {
+		var a int
+		var b sql.NullString
+		c.Assert(rows.Scan(&a, &b), IsNil)
+		result = append(result, []interface{}{a, b})
+	}
+	c.Assert(rows.Err(), IsNil)
+	c.Assert(result, DeepEquals, [][]interface{}{
+		{1, sql.NullString{String: "a;\nb", Valid: true}},
+		{2, sql.NullString{String: "it's", Valid: true}},
+		{3, sql.NullString{}},
+	})
+}
+
+func (s *testSQLiteSuite) TestAbort(c *C) {
+	writer, conf := s.newWriter(c)
+	ctx := context.Background()
+	c.Assert(writer.WriteTableMeta(ctx, "test", "t", `CREATE TABLE "t" ("a" INTEGER)`), IsNil)
+	c.Assert(writer.Abort(), IsNil)
+
+	filePath := filepath.Join(conf.OutputDirPath, "test.sqlite")
+	_, err := os.Stat(filePath)
+	c.Assert(os.IsNotExist(err), IsTrue)
+	_, err = os.Stat(filePath + partFileSuffix)
+	c.Assert(err, IsNil)
+
+	// the next dump truncates the incomplete file
+	next, err := NewSQLiteWriter(conf)
+	c.Assert(err, IsNil)
+	c.Assert(next.WriteTableMeta(ctx, "test", "t", `CREATE TABLE "t" ("a" INTEGER)`), IsNil)
+	c.Assert(next.(*SQLiteWriter).Close(), IsNil)
+	_, err = os.Stat(filePath)
+	c.Assert(err, IsNil)
+}
+
+func (s *testSQLiteSuite) TestValidate(c *C) {
+	conf := DefaultConfig()
+	conf.FileType = "sqlite"
+	c.Assert(conf.Validate(), IsNil)
+	c.Assert(conf.targetDialect(), Equals, TargetSQLite)
+	c.Assert(conf.translatesDDL(), IsTrue)
+
+	conf.TargetDialect = TargetPostgres
+	c.Assert(conf.Validate(), ErrorMatches, "filetype sqlite is only supported with the sqlite target dialect")
+	conf.TargetDialect = ""
+	conf.Sql = "SELECT 1"
+	c.Assert(conf.Validate(), ErrorMatches, "unsupported dump data in sqlite format when specific sql")
+}
@@ -0,0 +1,55 @@
+package export
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/pingcap/errors"
+)
+
+// tsoPhysicalShift is the bits of the logical part of a TSO of TiDB, the
+// physical part above it is the milliseconds since the Unix epoch.
+const tsoPhysicalShift = 18
+
+// staleReadConflicts returns the invalid TiDBReplicaRead and StaleRead, and
+// the options conflicting with them.
+func staleReadConflicts(conf *Config) []string {
+	var conflicts []string
+	switch conf.TiDBReplicaRead {
+	case "", "leader", "follower", "leader-and-follower", "closest-replicas":
+	default:
+		conflicts = append(conflicts, fmt.Sprintf("tidb-replica-read should be leader, follower, leader-and-follower or closest-replicas, got %s", conf.TiDBReplicaRead))
+	}
+	if conf.StaleRead == UnspecifiedSize {
+		return conflicts
+	}
+	if conf.Consistency != "auto" && conf.Consistency != "snapshot" {
+		conflicts = append(conflicts, fmt.Sprintf("stale-read is only valid with consistency snapshot, got %s", conf.Consistency))
+	}
+	if conf.Snapshot != "" {
+		conflicts = append(conflicts, "stale-read is not supported with snapshot")
+	}
+	return conflicts
+}
+
+// staleSnapshot returns the TSO seconds before the TSO snapshot.
+func staleSnapshot(snapshot string, seconds uint64) (string, error) {
+	tso, err := strconv.ParseUint(snapshot, 10, 64)
+	if err != nil {
+		return "", errors.Errorf("invalid snapshot %s", snapshot)
+	}
+	physical := tso >> tsoPhysicalShift
+	if physical <= seconds*1000 {
+		return "", errors.Errorf("the snapshot %s is less than %d seconds after the epoch", snapshot, seconds)
+	}
+	return strconv.FormatUint((physical-seconds*1000)<<tsoPhysicalShift, 10), nil
+}
+
+// asOfClause returns the AS OF TIMESTAMP clause of the tables read by stale
+// read, or empty if they're not.
+func (conf *Config) asOfClause() string {
+	if conf.staleReadSnapshot == "" {
+		return ""
+	}
+	return fmt.Sprintf(" AS OF TIMESTAMP TIDB_PARSE_TSO(%s)", conf.staleReadSnapshot)
+}
@@ -0,0 +1,89 @@
+package export
+
+import (
+	"context"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/coreos/go-semver/semver"
+	. "github.com/pingcap/check"
+)
+
+var _ = Suite(&testStaleReadSuite{})
+
+type testStaleReadSuite struct{}
+
+func (s *testStaleReadSuite) TestValidate(c *C) {
+	conf := DefaultConfig()
+	conf.TiDBReplicaRead = "follower"
+	conf.StaleRead = 5
+	c.Assert(conf.Validate(), IsNil)
+
+	conf.TiDBReplicaRead = "learner"
+	c.Assert(conf.Validate(), ErrorMatches, "(?s).*tidb-replica-read should be leader, follower, leader-and-follower or closest-replicas, got learner.*")
+	conf.TiDBReplicaRead = ""
+	conf.Consistency = "flush"
+	c.Assert(conf.Validate(), ErrorMatches, "(?s).*stale-read is only valid with consistency snapshot, got flush.*")
+	conf.Consistency = "snapshot"
+	conf.Snapshot = "417773951312461825"
+	c.Assert(conf.Validate(), ErrorMatches, "(?s).*stale-read is not supported with snapshot.*")
+
+	conf = DefaultConfig()
+	conf.StaleRead = 5
+	conf.ServerInfo = ServerInfo{ServerType: ServerTypeTiDB, ServerVersion: semver.New("5.0.6")}
+	c.Assert(conf.validateServer(), ErrorMatches, "stale-read is only supported by TiDB 5.1 and later, got TiDB")
+	conf.ServerInfo.ServerVersion = semver.New("5.1.0")
+	c.Assert(conf.validateServer(), IsNil)
+	c.Assert(conf.Consistency, Equals, "snapshot")
+	conf = DefaultConfig()
+	conf.TiDBReplicaRead = "follower"
+	conf.ServerInfo = ServerInfo{ServerType: ServerTypeMySQL, ServerVersion: semver.New("8.0.18")}
+	c.Assert(conf.validateServer(), ErrorMatches, "tidb-replica-read is only supported by TiDB 4.0 and later, got MySQL")
+}
+
+func (s *testStaleReadSuite) TestStaleSnapshot(c *C) {
+	// 2021-06-01 08:00:10.000 UTC
+	snapshot, err := staleSnapshot("425337660375040005", 10)
+	c.Assert(err, IsNil)
+	c.Assert(snapshot, Equals, "425337657753600000")
+	_, err = staleSnapshot("x", 10)
+	c.Assert(err, ErrorMatches, "invalid snapshot x")
+	_, err = staleSnapshot("262144", 10)
+	c.Assert(err, ErrorMatches, "the snapshot 262144 is less than 10 seconds after the epoch")
+}
+
+func (s *testStaleReadSuite) TestReadAsOf(c *C) {
+	conf := DefaultConfig()
+	c.Assert(conf.asOfClause(), Equals, "")
+	c.Assert(replicaDSN(conf, "425337657753600000"), Matches, ".*&tidb_snapshot=.*")
+
+	conf.staleReadSnapshot = "425337657753600000"
+	c.Assert(conf.asOfClause(), Equals, " AS OF TIMESTAMP TIDB_PARSE_TSO(425337657753600000)")
+	// the replicas read as of the snapshot by stale read too
+	c.Assert(replicaDSN(conf, "425337657753600000"), Not(Matches), ".*tidb_snapshot.*")
+
+	conf.TiDBReplicaRead = "closest-replicas"
+	conf.ServerInfo = ServerInfo{ServerType: ServerTypeTiDB, ServerVersion: semver.New("6.5.0")}
+	c.Assert(conf.sessionStatements(), DeepEquals, []string{"SET SESSION tidb_replica_read = 'closest-replicas'"})
+}
+
+func (s *testStaleReadSuite) TestSetupStaleRead(c *C) {
+	db, mock, err := sqlmock.New()
+	c.Assert(err, IsNil)
+	defer db.Close()
+
+	conf := DefaultConfig()
+	conf.Consistency = "snapshot"
+	conf.StaleRead = 10
+	conf.ServerInfo = ServerInfo{ServerType: ServerTypeTiDB, ServerVersion: semver.New("5.1.0")}
+	mock.ExpectQuery("SHOW MASTER STATUS").WillReturnRows(
+		sqlmock.NewRows([]string{"File", "Position", "Binlog_Do_DB", "Binlog_Ignore_DB", "Executed_Gtid_Set"}).
+			AddRow("tidb-binlog", "425337660375040005", "", "", ""))
+	mock.ExpectQuery("SELECT COUNT\\(1\\) as c FROM MYSQL.TiDB WHERE VARIABLE_NAME='tikv_gc_safe_point'").
+		WillReturnRows(sqlmock.NewRows([]string{"c"}).AddRow(1))
+	ctrl, err := NewConsistencyController(conf, db)
+	c.Assert(err, IsNil)
+	// tidb_snapshot isn't set
+	c.Assert(ctrl.Setup(context.Background()), IsNil)
+	c.Assert(consistencySnapshot(ctrl), Equals, "425337657753600000")
+	c.Assert(mock.ExpectationsWereMet(), IsNil)
+}
@@ -0,0 +1,70 @@
+package export
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/pingcap/dumpling/v4/log"
+)
+
+const defaultStateFileInterval = 5 * time.Second
+
+// dumpState is the content of the state file, which is polled by external tools.
+type dumpState struct {
+	State     JobState                `json:"state"`
+	UpdatedAt time.Time               `json:"updated_at"`
+	Progress  ProgressStatus          `json:"progress"`
+	Tables    map[TableState][]string `json:"tables"`
+	LastError string                  `json:"last_error,omitempty"`
+}
+
+func newDumpState(conf *Config) dumpState {
+	state := dumpState{
+		State:     conf.Controller.State(),
+		UpdatedAt: time.Now(),
+		Progress:  conf.Progress.Status(),
+		Tables:    conf.Progress.TableStates(),
+	}
+	if err := conf.Progress.LastError(); err != nil {
+		state.LastError = err.Error()
+	}
+	return state
+}
+
+// writeStateFile writes the state to a temporary file and renames it to path,
+// so that readers never see a partially written file.
+func writeStateFile(path string, state dumpState) error {
+	content, err := json.MarshalIndent(state, "", "  ")
+	if err != nil {
+		return withStack(err)
+	}
+	tmpPath := path + ".tmp"
+	if err = ioutil.WriteFile(tmpPath, content, 0644); err != nil {
+		return withStack(err)
+	}
+	return withStack(os.Rename(tmpPath, path))
+}
+
+// runStateFileWriter writes the state file every interval, and once more when ctx is done.
+func runStateFileWriter(ctx context.Context, conf *Config, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			if err := writeStateFile(conf.StateFile, newDumpState(conf)); err != nil {
+				log.Warn("write state file failed", zap.String("path", conf.StateFile), zap.Error(err))
+			}
+			return
+		case <-ticker.C:
+			if err := writeStateFile(conf.StateFile, newDumpState(conf)); err != nil {
+				log.Warn("write state file failed", zap.String("path", conf.StateFile), zap.Error(err))
+			}
+		}
+	}
+}
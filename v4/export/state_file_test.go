@@ -0,0 +1,49 @@
+package export
+
+import (
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path"
+
+	. "github.com/pingcap/check"
+)
+
+var _ = Suite(&testStateFileSuite{})
+
+type testStateFileSuite struct{}
+
+func (s *testStateFileSuite) TestWriteStateFile(c *C) {
+	dir, err := ioutil.TempDir("", "dumpling")
+	c.Assert(err, IsNil)
+	defer os.RemoveAll(dir)
+
+	conf := DefaultConfig()
+	conf.Progress = NewProgress()
+	conf.Controller = NewJobController()
+	conf.StateFile = path.Join(dir, "state.json")
+	conf.Progress.addTable("test", "t1")
+	conf.Progress.addTable("test", "t2")
+	conf.Progress.finishTable("test", "t2")
+	conf.Progress.addRows(3, 30)
+	conf.Progress.recordError(errors.New("mock error"))
+
+	c.Assert(writeStateFile(conf.StateFile, newDumpState(conf)), IsNil)
+	_, err = os.Stat(conf.StateFile + ".tmp")
+	c.Assert(os.IsNotExist(err), IsTrue)
+
+	content, err := ioutil.ReadFile(conf.StateFile)
+	c.Assert(err, IsNil)
+	var state dumpState
+	c.Assert(json.Unmarshal(content, &state), IsNil)
+	c.Assert(state.State, Equals, JobStateRunning)
+	c.Assert(state.Progress.FinishedBytes, Equals, uint64(30))
+	c.Assert(state.Tables[TableStatePending], DeepEquals, []string{"`test`.`t1`"})
+	c.Assert(state.Tables[TableStateDone], DeepEquals, []string{"`test`.`t2`"})
+	c.Assert(state.LastError, Equals, "mock error")
+
+	// the failed dump isn't recorded as finished
+	conf.Controller.finish(conf.Progress.LastError())
+	c.Assert(newDumpState(conf).State, Equals, JobStateFailed)
+}
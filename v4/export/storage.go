@@ -0,0 +1,174 @@
+package export
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"os"
+	"path"
+
+	"go.uber.org/zap"
+
+	"github.com/pingcap/dumpling/v4/log"
+)
+
+// ExternalStorage is where the dumped files are written to. Compression,
+// encryption or remote backends can be composed by wrapping an ExternalStorage,
+// see WrapStorage.
+type ExternalStorage interface {
+	// Create creates or truncates the file of name for writing.
+	// The file is complete only after Close returns nil.
+	Create(ctx context.Context, name string) (io.WriteCloser, error)
+}
+
+// Aborter is implemented by the files which can be discarded without being
+// finalized, when errors occur in writing them.
+type Aborter interface {
+	Abort() error
+}
+
+// closeFile closes w if err is nil, or aborts w if it's an Aborter.
+// It returns err, or the error of closing if err is nil.
+func closeFile(w io.WriteCloser, err error) error {
+	if err == nil {
+		return w.Close()
+	}
+	if aborter, ok := w.(Aborter); ok {
+		_ = aborter.Abort()
+	} else {
+		_ = w.Close()
+	}
+	return err
+}
+
+// partFileSuffix is the suffix of the local files being written, they are
+// renamed to the final names after written successfully, so the files without
+// the suffix are always complete. The files of incomplete chunks keep the suffix.
+const partFileSuffix = ".part"
+
+// LocalStorage writes the files into a local directory.
+type LocalStorage struct {
+	dir       string
+	syncFiles bool
+}
+
+// NewLocalStorage creates a LocalStorage, and the directory if it doesn't exist.
+// If syncFiles is true, the files and the directory are fsynced before the files
+// are closed, so that they are durable on disk once reported complete.
+func NewLocalStorage(dir string, syncFiles bool) (*LocalStorage, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, withKind(ErrorKindWrite, err)
+	}
+	return &LocalStorage{dir: dir, syncFiles: syncFiles}, nil
+}
+
+// Create creates name with partFileSuffix, it's renamed to name on Close.
+// The directories in name are created if they don't exist.
+func (s *LocalStorage) Create(_ context.Context, name string) (io.WriteCloser, error) {
+	filePath := path.Join(s.dir, name)
+	partPath := filePath + partFileSuffix
+	if err := os.MkdirAll(path.Dir(filePath), 0755); err != nil {
+		return nil, withKind(ErrorKindWrite, err)
+	}
+	file, err := os.OpenFile(partPath, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0755)
+	if err != nil {
+		log.Error("open file failed",
+			zap.String("path", partPath),
+			zap.Error(err))
+		return nil, withKind(ErrorKindWrite, err)
+	}
+	log.Debug("opened file", zap.String("path", partPath))
+	return &localFile{Writer: bufio.NewWriter(file), file: file, path: filePath, sync: s.syncFiles}, nil
+}
+
+type localFile struct {
+	*bufio.Writer
+	file *os.File
+	path string
+	sync bool
+}
+
+func (f *localFile) Close() error {
+	err := f.Flush()
+	if err == nil && f.sync {
+		err = f.file.Sync()
+	}
+	if closeErr := f.file.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(f.file.Name(), f.path)
+	}
+	if err == nil && f.sync {
+		// make the rename durable
+		err = syncDir(path.Dir(f.path))
+	}
+	if err != nil {
+		log.Error("close file failed",
+			zap.String("path", f.path),
+			zap.Error(err))
+	}
+	return withKind(ErrorKindWrite, err)
+}
+
+func syncDir(dir string) error {
+	d, err := os.Open(dir)
+	if err != nil {
+		return err
+	}
+	err = d.Sync()
+	if closeErr := d.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
+
+// Abort closes the file and leaves it with partFileSuffix.
+func (f *localFile) Abort() error {
+	log.Warn("abort incomplete file", zap.String("path", f.file.Name()))
+	return withKind(ErrorKindWrite, f.file.Close())
+}
+
+// WriterWrapper wraps a file created by an ExternalStorage. Closing the
+// returned writer must close w too.
+type WriterWrapper func(name string, w io.WriteCloser) (io.WriteCloser, error)
+
+type wrappedStorage struct {
+	ExternalStorage
+	wrap WriterWrapper
+}
+
+// WrapStorage returns an ExternalStorage whose files are wrapped by wrap.
+func WrapStorage(s ExternalStorage, wrap WriterWrapper) ExternalStorage {
+	return &wrappedStorage{ExternalStorage: s, wrap: wrap}
+}
+
+func (s *wrappedStorage) Create(ctx context.Context, name string) (io.WriteCloser, error) {
+	w, err := s.ExternalStorage.Create(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	wrapped, err := s.wrap(name, w)
+	if err != nil {
+		return nil, closeFile(w, err)
+	}
+	return &wrappedFile{WriteCloser: wrapped, file: w}, nil
+}
+
+// wrappedFile is a file wrapped by a WriterWrapper, it forwards Abort to the
+// wrapper, or to the file under it if the wrapper isn't an Aborter, since
+// closing the wrapper would finalize the incomplete file.
+type wrappedFile struct {
+	io.WriteCloser
+	file io.WriteCloser
+}
+
+func (f *wrappedFile) Abort() error {
+	if aborter, ok := f.WriteCloser.(Aborter); ok {
+		return aborter.Abort()
+	}
+	if aborter, ok := f.file.(Aborter); ok {
+		return aborter.Abort()
+	}
+	return f.WriteCloser.Close()
+}
@@ -0,0 +1,164 @@
+package export
+
+import (
+	"bytes"
+	"context"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"io/ioutil"
+	"os"
+	"path"
+
+	. "github.com/pingcap/check"
+)
+
+var _ = Suite(&testStorageSuite{})
+
+type testStorageSuite struct{}
+
+func (s *testStorageSuite) TestLocalStorage(c *C) {
+	dir, err := ioutil.TempDir("", "dumpling")
+	c.Assert(err, IsNil)
+	defer os.RemoveAll(dir)
+
+	// the directory is created if not exists
+	storage, err := NewLocalStorage(path.Join(dir, "sub"), false)
+	c.Assert(err, IsNil)
+	w, err := storage.Create(context.Background(), "test.sql")
+	c.Assert(err, IsNil)
+	_, err = w.Write([]byte("SELECT 1;\n"))
+	c.Assert(err, IsNil)
+	c.Assert(w.Close(), IsNil)
+
+	content, err := ioutil.ReadFile(path.Join(dir, "sub", "test.sql"))
+	c.Assert(err, IsNil)
+	c.Assert(string(content), Equals, "SELECT 1;\n")
+
+	storage, err = NewLocalStorage(dir, true)
+	c.Assert(err, IsNil)
+	w, err = storage.Create(context.Background(), "synced.sql")
+	c.Assert(err, IsNil)
+	_, err = w.Write([]byte("SELECT 2;\n"))
+	c.Assert(err, IsNil)
+	c.Assert(w.Close(), IsNil)
+	content, err = ioutil.ReadFile(path.Join(dir, "synced.sql"))
+	c.Assert(err, IsNil)
+	c.Assert(string(content), Equals, "SELECT 2;\n")
+}
+
+type upperWriter struct {
+	io.WriteCloser
+}
+
+func (w upperWriter) Write(p []byte) (int, error) {
+	return w.WriteCloser.Write(bytes.ToUpper(p))
+}
+
+func (s *testStorageSuite) TestWriterWithWrappedStorage(c *C) {
+	storage := newMemStorage()
+	var wrappedNames []string
+	config := DefaultConfig()
+	config.ExternalStorage = WrapStorage(storage, func(name string, w io.WriteCloser) (io.WriteCloser, error) {
+		wrappedNames = append(wrappedNames, name)
+		return upperWriter{w}, nil
+	})
+	ctx := context.Background()
+
+	writer, err := NewSimpleWriter(config)
+	c.Assert(err, IsNil)
+	c.Assert(writer.WriteTableMeta(ctx, "test", "t", "create table t (a int)"), IsNil)
+	data := [][]driver.Value{{"1"}, {"2"}}
+	c.Assert(writer.WriteTableData(ctx, newMockTableIR("test", "t", data, nil, []string{"INT"})), IsNil)
+
+	c.Assert(wrappedNames, DeepEquals, []string{"test.t-schema.sql", "test.t.0.sql"})
+	c.Assert(storage.files, DeepEquals, map[string]string{
+		"test.t-schema.sql": "CREATE TABLE T (A INT);\n",
+		"test.t.0.sql":      "INSERT INTO `T` VALUES\n(1),\n(2);\n",
+	})
+}
+
+func (s *testStorageSuite) TestLocalStoragePartFile(c *C) {
+	dir, err := ioutil.TempDir("", "dumpling")
+	c.Assert(err, IsNil)
+	defer os.RemoveAll(dir)
+	storage, err := NewLocalStorage(dir, false)
+	c.Assert(err, IsNil)
+	ctx := context.Background()
+
+	w, err := storage.Create(ctx, "complete.sql")
+	c.Assert(err, IsNil)
+	_, err = w.Write([]byte("SELECT 1;\n"))
+	c.Assert(err, IsNil)
+	// the file is invisible until closed
+	_, err = os.Stat(path.Join(dir, "complete.sql"))
+	c.Assert(os.IsNotExist(err), IsTrue)
+	_, err = os.Stat(path.Join(dir, "complete.sql"+partFileSuffix))
+	c.Assert(err, IsNil)
+	c.Assert(closeFile(w, nil), IsNil)
+	_, err = os.Stat(path.Join(dir, "complete.sql"))
+	c.Assert(err, IsNil)
+	_, err = os.Stat(path.Join(dir, "complete.sql"+partFileSuffix))
+	c.Assert(os.IsNotExist(err), IsTrue)
+
+	// the aborted file keeps the suffix
+	w, err = storage.Create(ctx, "incomplete.sql")
+	c.Assert(err, IsNil)
+	writeErr := errors.New("mock write error")
+	c.Assert(closeFile(w, writeErr), Equals, writeErr)
+	_, err = os.Stat(path.Join(dir, "incomplete.sql"))
+	c.Assert(os.IsNotExist(err), IsTrue)
+	_, err = os.Stat(path.Join(dir, "incomplete.sql"+partFileSuffix))
+	c.Assert(err, IsNil)
+}
+
+func (s *testStorageSuite) TestWrappedStorageAbort(c *C) {
+	dir := c.MkDir()
+	local, err := NewLocalStorage(dir, false)
+	c.Assert(err, IsNil)
+	// the part files are kept through the wrappers, whether they're Aborters or not
+	storage := WrapStorage(WrapStorage(local, func(_ string, w io.WriteCloser) (io.WriteCloser, error) {
+		return upperWriter{w}, nil
+	}), nopWrapper)
+	ctx := context.Background()
+
+	w, err := storage.Create(ctx, "incomplete.sql")
+	c.Assert(err, IsNil)
+	_, err = w.Write([]byte("select 1;\n"))
+	c.Assert(err, IsNil)
+	writeErr := errors.New("mock write error")
+	c.Assert(closeFile(w, writeErr), Equals, writeErr)
+	_, err = os.Stat(path.Join(dir, "incomplete.sql"))
+	c.Assert(os.IsNotExist(err), IsTrue)
+	_, err = os.Stat(path.Join(dir, "incomplete.sql"+partFileSuffix))
+	c.Assert(err, IsNil)
+
+	w, err = storage.Create(ctx, "complete.sql")
+	c.Assert(err, IsNil)
+	_, err = w.Write([]byte("select 1;\n"))
+	c.Assert(err, IsNil)
+	c.Assert(closeFile(w, nil), IsNil)
+	content, err := ioutil.ReadFile(path.Join(dir, "complete.sql"))
+	c.Assert(err, IsNil)
+	c.Assert(string(content), Equals, "SELECT 1;\n")
+}
+
+func (s *testStorageSuite) TestWriteTableDataLeavesPartFileOnError(c *C) {
+	dir, err := ioutil.TempDir("", "dumpling")
+	c.Assert(err, IsNil)
+	defer os.RemoveAll(dir)
+	config := DefaultConfig()
+	config.OutputDirPath = dir
+
+	writer, err := NewSimpleWriter(config)
+	c.Assert(err, IsNil)
+	rowErr := errors.New("mock row error")
+	data := [][]driver.Value{{"1"}, {"2"}, {"3"}}
+	tableIR := newMockTableIRWithError("test", "t", data, nil, []string{"INT"}, rowErr)
+	c.Assert(writer.WriteTableData(context.Background(), tableIR), Equals, rowErr)
+
+	_, err = os.Stat(path.Join(dir, "test.t.0.sql"))
+	c.Assert(os.IsNotExist(err), IsTrue)
+	_, err = os.Stat(path.Join(dir, "test.t.0.sql"+partFileSuffix))
+	c.Assert(err, IsNil)
+}
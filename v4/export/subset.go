@@ -0,0 +1,245 @@
+package export
+
+import (
+	"database/sql"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/pingcap/dumpling/v4/log"
+	"github.com/pingcap/errors"
+	"go.uber.org/zap"
+)
+
+// ForeignKey is a foreign key from the Columns of a table to the
+// ReferencedColumns of another table.
+type ForeignKey struct {
+	Database string
+	Table    string
+	Columns  []string
+	// ReferencedDatabase is the database of ReferencedTable, it's Database if
+	// it's empty.
+	ReferencedDatabase string
+	ReferencedTable    string
+	ReferencedColumns  []string
+}
+
+func (fk *ForeignKey) referencedDatabase() string {
+	if fk.ReferencedDatabase == "" {
+		return fk.Database
+	}
+	return fk.ReferencedDatabase
+}
+
+// subsetConflicts returns the invalid foreign keys of conf and the options
+// conflicting with Subset.
+func subsetConflicts(conf *Config) []string {
+	var conflicts []string
+	for _, fk := range conf.ForeignKeys {
+		name := qualifiedTableName(fk.Database, fk.Table)
+		if fk.Database == "" || fk.Table == "" || fk.ReferencedTable == "" {
+			conflicts = append(conflicts, fmt.Sprintf("the database, table and referenced table of the foreign key should be set, got %s referencing %s",
+				name, qualifiedTableName(fk.referencedDatabase(), fk.ReferencedTable)))
+			continue
+		}
+		if len(fk.Columns) == 0 || len(fk.Columns) != len(fk.ReferencedColumns) {
+			conflicts = append(conflicts, fmt.Sprintf("the columns and referenced columns of the foreign key of %s should be the same number, got %d and %d",
+				name, len(fk.Columns), len(fk.ReferencedColumns)))
+		}
+	}
+	if !conf.Subset {
+		if len(conf.ForeignKeys) > 0 {
+			conflicts = append(conflicts, "the foreign keys are only followed with subset")
+		}
+		return conflicts
+	}
+	if conf.Where == "" {
+		hasSeed := false
+		for _, tc := range conf.TableConfigs {
+			hasSeed = hasSeed || tc.Where != ""
+		}
+		if !hasSeed {
+			conflicts = append(conflicts, "subset needs the where of some tables to select the rows it starts from")
+		}
+	}
+	if conf.Sql != "" {
+		conflicts = append(conflicts, "subset is not supported with sql")
+	}
+	return conflicts
+}
+
+// ListForeignKeys lists the foreign keys declared by the tables of a database.
+func ListForeignKeys(db *sql.DB, database string) ([]ForeignKey, error) {
+	const query = "SELECT TABLE_NAME, CONSTRAINT_NAME, COLUMN_NAME, REFERENCED_TABLE_SCHEMA, REFERENCED_TABLE_NAME, REFERENCED_COLUMN_NAME " +
+		"FROM INFORMATION_SCHEMA.KEY_COLUMN_USAGE WHERE TABLE_SCHEMA = ? AND REFERENCED_TABLE_NAME IS NOT NULL " +
+		"ORDER BY TABLE_NAME, CONSTRAINT_NAME, ORDINAL_POSITION"
+	rows, err := db.Query(query, database)
+	if err != nil {
+		return nil, withStack(errors.WithMessage(err, query))
+	}
+	defer rows.Close()
+
+	var (
+		fks        []ForeignKey
+		lastTable  string
+		lastConstr string
+	)
+	for rows.Next() {
+		var table, constraint, column, refDatabase, refTable, refColumn string
+		if err := rows.Scan(&table, &constraint, &column, &refDatabase, &refTable, &refColumn); err != nil {
+			return nil, withStack(errors.WithMessage(err, query))
+		}
+		if len(fks) == 0 || table != lastTable || constraint != lastConstr {
+			fks = append(fks, ForeignKey{
+				Database:           database,
+				Table:              table,
+				ReferencedDatabase: refDatabase,
+				ReferencedTable:    refTable,
+			})
+			lastTable, lastConstr = table, constraint
+		}
+		fk := &fks[len(fks)-1]
+		fk.Columns = append(fk.Columns, column)
+		fk.ReferencedColumns = append(fk.ReferencedColumns, refColumn)
+	}
+	return fks, withStack(rows.Err())
+}
+
+// subsetPlanner derives the where of the tables referencing the filtered
+// tables, so that only the rows referencing the dumped rows are dumped.
+type subsetPlanner struct {
+	conf *Config
+	// references maps a table to its foreign keys to the other dumped tables.
+	references map[string][]ForeignKey
+	wheres     map[string]string
+	visiting   map[string]bool
+}
+
+// planSubset returns the where of the tables referencing the filtered tables
+// by the declared foreign keys and ForeignKeys, directly or indirectly. The
+// foreign keys in a cycle are ignored.
+func planSubset(conf *Config, db *sql.DB) (map[string]string, error) {
+	var fks []ForeignKey
+	if conf.SourceDialect != DialectPostgres {
+		dbNames := make([]string, 0, len(conf.Tables))
+		for dbName := range conf.Tables {
+			dbNames = append(dbNames, dbName)
+		}
+		sort.Strings(dbNames)
+		for _, dbName := range dbNames {
+			declared, err := ListForeignKeys(db, dbName)
+			if err != nil {
+				return nil, err
+			}
+			fks = append(fks, declared...)
+		}
+	}
+	fks = append(fks, conf.ForeignKeys...)
+
+	dumped := map[string]bool{}
+	for dbName, tables := range conf.Tables {
+		for _, table := range tables {
+			if table.Type != TableTypeView {
+				dumped[qualifiedTableName(dbName, table.Name)] = true
+			}
+		}
+	}
+	// the own where of the tables, without the subset of the last dump
+	planConf := *conf
+	planConf.subset = nil
+	p := &subsetPlanner{
+		conf:       &planConf,
+		references: map[string][]ForeignKey{},
+		wheres:     map[string]string{},
+		visiting:   map[string]bool{},
+	}
+	for _, fk := range fks {
+		table := qualifiedTableName(fk.Database, fk.Table)
+		referenced := qualifiedTableName(fk.referencedDatabase(), fk.ReferencedTable)
+		if !dumped[table] || !dumped[referenced] || table == referenced {
+			continue
+		}
+		p.references[table] = append(p.references[table], fk)
+	}
+
+	subset := map[string]string{}
+	for dbName, tables := range conf.Tables {
+		for _, table := range tables {
+			if table.Type == TableTypeView {
+				continue
+			}
+			where, _ := p.where(dbName, table.Name)
+			if where != planConf.forTable(dbName, table.Name).Where {
+				subset[qualifiedTableName(dbName, table.Name)] = where
+				log.Info("dump the subset of table", zap.String("database", dbName), zap.String("table", table.Name),
+					zap.String("where", where))
+			}
+		}
+	}
+	return subset, nil
+}
+
+// where returns the where of the table, which is its own where and the
+// conditions that its foreign keys reference the dumped rows of the tables
+// having a where. It's not memorized if a foreign key in a cycle is ignored,
+// since it depends on where the cycle is entered, which is returned as
+// false.
+func (p *subsetPlanner) where(dbName, tableName string) (string, bool) {
+	name := qualifiedTableName(dbName, tableName)
+	if where, ok := p.wheres[name]; ok {
+		return where, true
+	}
+	memorized := true
+	own := p.conf.forTable(dbName, tableName).Where
+	var conditions []string
+	p.visiting[name] = true
+	d := p.conf.dialect()
+	for _, fk := range p.references[name] {
+		refDatabase := fk.referencedDatabase()
+		referenced := qualifiedTableName(refDatabase, fk.ReferencedTable)
+		if p.visiting[referenced] {
+			log.Warn("ignore the foreign key in a dependency cycle for subset",
+				zap.String("table", name), zap.String("referenced", referenced))
+			memorized = false
+			continue
+		}
+		refWhere, ok := p.where(refDatabase, fk.ReferencedTable)
+		memorized = memorized && ok
+		if refWhere == "" {
+			continue
+		}
+		conditions = append(conditions, fmt.Sprintf("%s IN (SELECT %s FROM %s WHERE %s)",
+			quoteColumnTuple(d, fk.Columns), quoteColumns(d, fk.ReferencedColumns),
+			qualifiedName(d, refDatabase, fk.ReferencedTable), refWhere))
+	}
+	delete(p.visiting, name)
+
+	where := own
+	if len(conditions) > 0 {
+		if own != "" {
+			conditions = append([]string{"(" + own + ")"}, conditions...)
+		}
+		where = strings.Join(conditions, " AND ")
+	}
+	if memorized {
+		p.wheres[name] = where
+	}
+	return where, memorized
+}
+
+func quoteColumns(d Dialect, columns []string) string {
+	quoted := make([]string, 0, len(columns))
+	for _, column := range columns {
+		quoted = append(quoted, d.QuoteIdentifier(column))
+	}
+	return strings.Join(quoted, ", ")
+}
+
+// quoteColumnTuple returns the columns as the left side of IN, which is a
+// row constructor if there are more than one columns.
+func quoteColumnTuple(d Dialect, columns []string) string {
+	if len(columns) == 1 {
+		return d.QuoteIdentifier(columns[0])
+	}
+	return "(" + quoteColumns(d, columns) + ")"
+}
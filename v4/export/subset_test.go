@@ -0,0 +1,127 @@
+package export
+
+import (
+	"regexp"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	. "github.com/pingcap/check"
+)
+
+var _ = Suite(&testSubsetSuite{})
+
+type testSubsetSuite struct{}
+
+const listForeignKeysQuery = "SELECT TABLE_NAME, CONSTRAINT_NAME, COLUMN_NAME, REFERENCED_TABLE_SCHEMA, REFERENCED_TABLE_NAME, REFERENCED_COLUMN_NAME " +
+	"FROM INFORMATION_SCHEMA.KEY_COLUMN_USAGE"
+
+var foreignKeysColumns = []string{"TABLE_NAME", "CONSTRAINT_NAME", "COLUMN_NAME", "REFERENCED_TABLE_SCHEMA", "REFERENCED_TABLE_NAME", "REFERENCED_COLUMN_NAME"}
+
+func (s *testSubsetSuite) TestListForeignKeys(c *C) {
+	db, mock, err := sqlmock.New()
+	c.Assert(err, IsNil)
+	defer db.Close()
+
+	mock.ExpectQuery(regexp.QuoteMeta(listForeignKeysQuery)).WithArgs("shop").
+		WillReturnRows(sqlmock.NewRows(foreignKeysColumns).
+			AddRow("items", "fk_order", "order_id", "shop", "orders", "id").
+			AddRow("items", "fk_product", "region", "catalog", "products", "region").
+			AddRow("items", "fk_product", "product_id", "catalog", "products", "id").
+			AddRow("orders", "fk_order", "customer_id", "shop", "customers", "id"))
+	fks, err := ListForeignKeys(db, "shop")
+	c.Assert(err, IsNil)
+	c.Assert(mock.ExpectationsWereMet(), IsNil)
+	c.Assert(fks, DeepEquals, []ForeignKey{
+		{Database: "shop", Table: "items", Columns: []string{"order_id"}, ReferencedDatabase: "shop", ReferencedTable: "orders", ReferencedColumns: []string{"id"}},
+		{Database: "shop", Table: "items", Columns: []string{"region", "product_id"}, ReferencedDatabase: "catalog", ReferencedTable: "products", ReferencedColumns: []string{"region", "id"}},
+		{Database: "shop", Table: "orders", Columns: []string{"customer_id"}, ReferencedDatabase: "shop", ReferencedTable: "customers", ReferencedColumns: []string{"id"}},
+	})
+}
+
+func (s *testSubsetSuite) TestPlanSubset(c *C) {
+	db, mock, err := sqlmock.New()
+	c.Assert(err, IsNil)
+	defer db.Close()
+
+	conf := DefaultConfig()
+	conf.Subset = true
+	conf.Tables = NewDatabaseTables().
+		AppendTables("shop", "customers", "orders", "items", "notes", "countries", "employees").
+		AppendViews("shop", "v")
+	conf.TableConfigs = []TableConfig{
+		{Database: "shop", Table: "customers", Where: "id % 100 = 0"},
+		{Database: "shop", Table: "items", Where: "qty > 0"},
+	}
+	// notes references the customers by a foreign key only known by the application
+	conf.ForeignKeys = []ForeignKey{
+		{Database: "shop", Table: "notes", Columns: []string{"owner"}, ReferencedTable: "customers", ReferencedColumns: []string{"id"}},
+	}
+	mock.ExpectQuery(regexp.QuoteMeta(listForeignKeysQuery)).WithArgs("shop").
+		WillReturnRows(sqlmock.NewRows(foreignKeysColumns).
+			AddRow("customers", "fk_country", "country", "shop", "countries", "code").
+			AddRow("employees", "fk_manager", "manager_id", "shop", "employees", "id").
+			AddRow("items", "fk_order", "order_id", "shop", "orders", "id").
+			AddRow("items", "fk_order", "customer_id", "shop", "orders", "customer_id").
+			AddRow("orders", "fk_customer", "customer_id", "shop", "customers", "id").
+			AddRow("orders", "fk_missing", "x", "other", "missing", "id"))
+	subset, err := planSubset(conf, db)
+	c.Assert(err, IsNil)
+	c.Assert(mock.ExpectationsWereMet(), IsNil)
+
+	customers := "SELECT `id` FROM `shop`.`customers` WHERE id % 100 = 0"
+	orders := "`customer_id` IN (" + customers + ")"
+	c.Assert(subset, DeepEquals, map[string]string{
+		"`shop`.`orders`": orders,
+		"`shop`.`items`":  "(qty > 0) AND (`order_id`, `customer_id`) IN (SELECT `id`, `customer_id` FROM `shop`.`orders` WHERE " + orders + ")",
+		"`shop`.`notes`":  "`owner` IN (" + customers + ")",
+	})
+
+	// the subset overrides the where of the tables
+	conf.subset = subset
+	c.Assert(conf.forTable("shop", "orders").Where, Equals, orders)
+	c.Assert(conf.forTable("shop", "customers").Where, Equals, "id % 100 = 0")
+	c.Assert(conf.forTable("shop", "countries"), Equals, conf)
+}
+
+func (s *testSubsetSuite) TestSubsetCycle(c *C) {
+	db, mock, err := sqlmock.New()
+	c.Assert(err, IsNil)
+	defer db.Close()
+
+	conf := DefaultConfig()
+	conf.Subset = true
+	conf.SourceDialect = DialectPostgres
+	conf.Tables = NewDatabaseTables().AppendTables("public", "a", "b", "c")
+	conf.TableConfigs = []TableConfig{{Database: "public", Table: "a", Where: "id < 10"}}
+	conf.ForeignKeys = []ForeignKey{
+		{Database: "public", Table: "b", Columns: []string{"a_id"}, ReferencedTable: "a", ReferencedColumns: []string{"id"}},
+		{Database: "public", Table: "c", Columns: []string{"b_id"}, ReferencedTable: "b", ReferencedColumns: []string{"id"}},
+		{Database: "public", Table: "a", Columns: []string{"c_id"}, ReferencedTable: "c", ReferencedColumns: []string{"id"}},
+	}
+	// the declared foreign keys of PostgreSQL aren't listed
+	subset, err := planSubset(conf, db)
+	c.Assert(err, IsNil)
+	c.Assert(mock.ExpectationsWereMet(), IsNil)
+	c.Assert(subset["`public`.`b`"], Equals, `"a_id" IN (SELECT "id" FROM "public"."a" WHERE id < 10)`)
+	c.Assert(subset["`public`.`c`"], Equals, `"b_id" IN (SELECT "id" FROM "public"."b" WHERE "a_id" IN (SELECT "id" FROM "public"."a" WHERE id < 10))`)
+	c.Assert(subset, HasLen, 2)
+}
+
+func (s *testSubsetSuite) TestValidate(c *C) {
+	conf := DefaultConfig()
+	conf.Subset = true
+	c.Assert(conf.Validate(), ErrorMatches, "(?s).*subset needs the where of some tables to select the rows it starts from.*")
+	conf.TableConfigs = []TableConfig{{Database: "shop", Table: "customers", Where: "id < 10"}}
+	c.Assert(conf.Validate(), IsNil)
+
+	conf.ForeignKeys = []ForeignKey{
+		{Database: "shop", Table: "orders", Columns: []string{"customer_id"}, ReferencedColumns: []string{"id"}},
+		{Database: "shop", Table: "items", Columns: []string{"order_id"}, ReferencedTable: "orders"},
+	}
+	c.Assert(conf.Validate(), ErrorMatches, "(?s).*the database, table and referenced table of the foreign key should be set, got `shop`.`orders` referencing `shop`.``"+
+		".*the columns and referenced columns of the foreign key of `shop`.`items` should be the same number, got 1 and 0.*")
+	conf.Subset = false
+	conf.ForeignKeys = conf.ForeignKeys[:0]
+	c.Assert(conf.Validate(), IsNil)
+	conf.ForeignKeys = []ForeignKey{{Database: "shop", Table: "items", Columns: []string{"order_id"}, ReferencedTable: "orders", ReferencedColumns: []string{"id"}}}
+	c.Assert(conf.Validate(), ErrorMatches, "(?s).*the foreign keys are only followed with subset.*")
+}
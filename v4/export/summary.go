@@ -0,0 +1,96 @@
+package export
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync/atomic"
+	"text/tabwriter"
+	"time"
+)
+
+// summaryPath is the name of the summary in the output directory.
+const summaryPath = "summary"
+
+// TableSummary is the rows, bytes and files dumped from a table, and how long
+// it took.
+type TableSummary struct {
+	Name     string        `json:"name"`
+	Rows     uint64        `json:"rows"`
+	Bytes    uint64        `json:"bytes"`
+	Files    uint64        `json:"files"`
+	Duration time.Duration `json:"duration"`
+}
+
+// DumpSummary is the summary of the tables of a dump, and their total.
+type DumpSummary struct {
+	Tables []TableSummary `json:"tables"`
+	Total  TableSummary   `json:"total"`
+}
+
+// Summary returns the summary of the tables sorted by their names. The total
+// duration is the elapsed time of the dump, and the unfinished tables count
+// the time until now.
+func (p *Progress) Summary() DumpSummary {
+	summary := DumpSummary{Total: TableSummary{Name: "TOTAL"}}
+	if p == nil {
+		return summary
+	}
+	now := time.Now()
+	p.mu.Lock()
+	for name, t := range p.tables {
+		table := TableSummary{
+			Name:  name,
+			Rows:  atomic.LoadUint64(&t.rows),
+			Bytes: atomic.LoadUint64(&t.bytes),
+			Files: atomic.LoadUint64(&t.files),
+		}
+		if startTime := atomic.LoadInt64(&t.startTime); startTime != 0 {
+			finish := now
+			if finishTime := atomic.LoadInt64(&t.finishTime); finishTime != 0 {
+				finish = time.Unix(0, finishTime)
+			}
+			table.Duration = finish.Sub(time.Unix(0, startTime))
+		}
+		summary.Tables = append(summary.Tables, table)
+		summary.Total.Rows += table.Rows
+		summary.Total.Bytes += table.Bytes
+		summary.Total.Files += table.Files
+	}
+	p.mu.Unlock()
+	sort.Slice(summary.Tables, func(i, j int) bool {
+		return summary.Tables[i].Name < summary.Tables[j].Name
+	})
+	summary.Total.Duration = p.Status().Elapsed
+	return summary
+}
+
+// String renders the summary as a table, the tables without any rows are
+// marked, since they're usually worth a look.
+func (s DumpSummary) String() string {
+	var b strings.Builder
+	w := tabwriter.NewWriter(&b, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "TABLE\tROWS\tBYTES\tFILES\tDURATION")
+	tables := append(append([]TableSummary(nil), s.Tables...), s.Total)
+	for _, table := range tables {
+		name := table.Name
+		if table.Rows == 0 && table.Name != s.Total.Name {
+			name += " (empty)"
+		}
+		fmt.Fprintf(w, "%s\t%d\t%s\t%d\t%s\n", name, table.Rows, formatBytes(table.Bytes), table.Files,
+			table.Duration.Round(time.Millisecond))
+	}
+	w.Flush()
+	return b.String()
+}
+
+// writeSummary writes the summary of the dump into the output directory.
+func writeSummary(conf *Config) error {
+	// write the summary even if the dump is canceled
+	fileWriter, err := conf.ExternalStorage.Create(context.Background(), summaryPath)
+	if err != nil {
+		return err
+	}
+	return closeFile(fileWriter, write(fileWriter, conf.Progress.Summary().String()))
+}
@@ -0,0 +1,69 @@
+package export
+
+import (
+	"context"
+	"database/sql/driver"
+	"io/ioutil"
+	"os"
+	"time"
+
+	. "github.com/pingcap/check"
+)
+
+var _ = Suite(&testSummarySuite{})
+
+type testSummarySuite struct{}
+
+func (s *testSummarySuite) TestSummary(c *C) {
+	dir, err := ioutil.TempDir("", "dumpling")
+	c.Assert(err, IsNil)
+	defer os.RemoveAll(dir)
+
+	conf := DefaultConfig()
+	conf.OutputDirPath = dir
+	conf.FileSize = 20
+	writer, err := NewSimpleWriter(conf)
+	c.Assert(err, IsNil)
+
+	p := NewProgress()
+	p.start(time.Now())
+	p.addTable("test", "employee")
+	p.addTable("test", "empty")
+	p.startTable("test", "employee")
+	data := [][]driver.Value{
+		{"1", "male", "bob@mail.com"},
+		{"2", "female", "sarah@mail.com"},
+		{"3", "male", "john@mail.com"},
+	}
+	tableIR := newMockTableIR("test", "employee", data, nil, []string{"INT", "SET", "VARCHAR"})
+	// the files are counted through the wrappers of the table
+	routed := &routedTableData{TableDataIR: withProgress(tableIR, p), conf: conf, database: "test", table: "employee"}
+	c.Assert(writer.WriteTableData(context.Background(), routed), IsNil)
+	p.finishTable("test", "employee")
+	p.finishTable("test", "empty")
+
+	summary := p.Summary()
+	c.Assert(summary.Tables, HasLen, 2)
+	employee := summary.Tables[0]
+	c.Assert(employee.Name, Equals, "`test`.`employee`")
+	c.Assert(employee.Rows, Equals, uint64(3))
+	c.Assert(employee.Bytes, Equals, uint64(len("1malebob@mail.com2femalesarah@mail.com3malejohn@mail.com")))
+	c.Assert(employee.Files, Equals, uint64(2))
+	c.Assert(summary.Tables[1], DeepEquals, TableSummary{Name: "`test`.`empty`"})
+	c.Assert(summary.Total.Rows, Equals, uint64(3))
+	c.Assert(summary.Total.Files, Equals, uint64(2))
+
+	c.Assert(summary.String(), Matches, "TABLE +ROWS +BYTES +FILES +DURATION\n"+
+		"`test`.`employee` +3 +56 B +2 +.*\n"+
+		"`test`.`empty` \\(empty\\) +0 +0 B +0 +0s\n"+
+		"TOTAL +3 +56 B +2 +.*\n")
+
+	storage := newMemStorage()
+	conf.ExternalStorage = storage
+	conf.Progress = p
+	c.Assert(writeSummary(conf), IsNil)
+	c.Assert(storage.files[summaryPath], Matches, "(?s)TABLE .*`test`.`empty` \\(empty\\) .*TOTAL .*")
+
+	var nilProgress *Progress
+	c.Assert(nilProgress.Summary().Tables, HasLen, 0)
+}
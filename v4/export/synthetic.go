@@ -0,0 +1,219 @@
+package export
+
+import (
+	"database/sql"
+	"fmt"
+	"math/rand"
+	"strconv"
+	"strings"
+)
+
+// syntheticPoolSize is the number of distinct rows generated in advance, the
+// rows of a SyntheticTable cycle through them so that generating is cheap
+// compared to the serialization being measured.
+const syntheticPoolSize = 1024
+
+// syntheticAlphabet has the quotes and the backslashes which must be escaped.
+const syntheticAlphabet = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789 ,'\"\\\n\t"
+
+// SyntheticTable configures the rows of NewSyntheticTableIR.
+type SyntheticTable struct {
+	Database string
+	Table    string
+	// ColumnTypes are the types of the columns, like INT, VARCHAR or BLOB.
+	ColumnTypes []string
+	// RowWidth is the approximate bytes of a row, it's shared by the string and
+	// binary columns.
+	RowWidth int
+	// Rows is the number of the rows.
+	Rows uint64
+	// Seed seeds the random values, the same seed generates the same rows.
+	Seed int64
+}
+
+// NewSyntheticTableIR creates a TableDataIR of the generated rows of table,
+// it measures the serialization and the writers without a database.
+func NewSyntheticTableIR(table SyntheticTable) (TableDataIR, error) {
+	if len(table.ColumnTypes) == 0 {
+		return nil, fmt.Errorf("synthetic table %s should have columns", table.Table)
+	}
+	colTypes := make([]string, len(table.ColumnTypes))
+	colNames := make([]string, len(table.ColumnTypes))
+	var numWidth, varColumns int
+	for i, colType := range table.ColumnTypes {
+		colTypes[i] = strings.ToUpper(strings.TrimSpace(colType))
+		colNames[i] = "c" + strconv.Itoa(i+1)
+		switch {
+		case isSyntheticNumber(colTypes[i]):
+			numWidth += 10
+		case isSyntheticTime(colTypes[i]):
+			numWidth += 19
+		default:
+			varColumns++
+		}
+	}
+	width := 0
+	if varColumns > 0 && table.RowWidth > numWidth {
+		width = (table.RowWidth - numWidth) / varColumns
+	}
+
+	rnd := rand.New(rand.NewSource(table.Seed))
+	pool := make([][]sql.RawBytes, syntheticPoolSize)
+	for i := range pool {
+		row := make([]sql.RawBytes, len(colTypes))
+		for j, colType := range colTypes {
+			row[j] = syntheticValue(rnd, colType, width)
+		}
+		pool[i] = row
+	}
+	return &syntheticTableIR{table: table, colTypes: colTypes, colNames: colNames, pool: pool}, nil
+}
+
+// syntheticReceiver is the receiver of colType, which tells how it's written.
+func syntheticReceiver(colType string) RowReceiverStringer {
+	if maker, ok := colTypeRowReceiverMap[colType]; ok {
+		return maker()
+	}
+	return SQLTypeStringMaker()
+}
+
+func isSyntheticNumber(colType string) bool {
+	_, ok := syntheticReceiver(colType).(*SQLTypeNumber)
+	return ok
+}
+
+func isSyntheticTime(colType string) bool {
+	switch colType {
+	case "DATE", "DATETIME", "TIMESTAMP":
+		return true
+	}
+	return false
+}
+
+func syntheticValue(rnd *rand.Rand, colType string, width int) sql.RawBytes {
+	switch {
+	case isSyntheticNumber(colType):
+		return sql.RawBytes(strconv.FormatInt(rnd.Int63n(1e10), 10))
+	case colType == "DATE":
+		return sql.RawBytes(fmt.Sprintf("20%02d-%02d-%02d", rnd.Intn(30), rnd.Intn(12)+1, rnd.Intn(28)+1))
+	case isSyntheticTime(colType):
+		return sql.RawBytes(fmt.Sprintf("20%02d-%02d-%02d %02d:%02d:%02d", rnd.Intn(30), rnd.Intn(12)+1,
+			rnd.Intn(28)+1, rnd.Intn(24), rnd.Intn(60), rnd.Intn(60)))
+	}
+	value := make(sql.RawBytes, width)
+	if _, binary := syntheticReceiver(colType).(*SQLTypeBytes); binary {
+		rnd.Read(value)
+		return value
+	}
+	for i := range value {
+		value[i] = syntheticAlphabet[rnd.Intn(len(syntheticAlphabet))]
+	}
+	return value
+}
+
+type syntheticTableIR struct {
+	table    SyntheticTable
+	colTypes []string
+	colNames []string
+	pool     [][]sql.RawBytes
+}
+
+func (s *syntheticTableIR) DatabaseName() string {
+	return s.table.Database
+}
+
+func (s *syntheticTableIR) TableName() string {
+	return s.table.Table
+}
+
+func (s *syntheticTableIR) ChunkIndex() int {
+	return 0
+}
+
+func (s *syntheticTableIR) ColumnCount() uint {
+	return uint(len(s.colTypes))
+}
+
+func (s *syntheticTableIR) ColumnTypes() []string {
+	return s.colTypes
+}
+
+func (s *syntheticTableIR) ColumnNames() []string {
+	return s.colNames
+}
+
+func (s *syntheticTableIR) SelectedField() string {
+	return ""
+}
+
+func (s *syntheticTableIR) EscapeBackSlash() bool {
+	return true
+}
+
+func (s *syntheticTableIR) QuoteBigIntegers() bool {
+	return false
+}
+
+func (s *syntheticTableIR) Output() OutputDialect {
+	return mysqlOutput{}
+}
+
+func (s *syntheticTableIR) SpecialComments() StringIter {
+	return newStringIter()
+}
+
+func (s *syntheticTableIR) SpecialFooters() StringIter {
+	return newStringIter()
+}
+
+func (s *syntheticTableIR) MysqldumpCompleted() bool {
+	return false
+}
+
+func (s *syntheticTableIR) Rows() SQLRowIter {
+	return &syntheticRowIter{
+		pool: s.pool,
+		rows: s.table.Rows,
+		args: make([]interface{}, len(s.colTypes)),
+	}
+}
+
+type syntheticRowIter struct {
+	pool [][]sql.RawBytes
+	rows uint64
+	idx  uint64
+	args []interface{}
+}
+
+func (iter *syntheticRowIter) Decode(row RowReceiver) error {
+	row.BindAddress(iter.args)
+	values := iter.pool[iter.idx%uint64(len(iter.pool))]
+	for i, arg := range iter.args {
+		*arg.(*sql.RawBytes) = values[i]
+	}
+	return nil
+}
+
+func (iter *syntheticRowIter) Next() {
+	iter.idx++
+}
+
+func (iter *syntheticRowIter) Error() error {
+	return nil
+}
+
+func (iter *syntheticRowIter) HasNext() bool {
+	return iter.idx < iter.rows
+}
+
+func (iter *syntheticRowIter) HasNextSQLRowIter() bool {
+	return iter.HasNext()
+}
+
+func (iter *syntheticRowIter) NextSQLRowIter() SQLRowIter {
+	return iter
+}
+
+func (iter *syntheticRowIter) Close() error {
+	return nil
+}
@@ -0,0 +1,93 @@
+package export
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"strings"
+	"testing"
+
+	. "github.com/pingcap/check"
+)
+
+var _ = Suite(&testSyntheticSuite{})
+
+type testSyntheticSuite struct{}
+
+func (s *testSyntheticSuite) TestSyntheticTable(c *C) {
+	_, err := NewSyntheticTableIR(SyntheticTable{Table: "t"})
+	c.Assert(err, ErrorMatches, "synthetic table t should have columns")
+
+	table := SyntheticTable{
+		Database:    "bench",
+		Table:       "t",
+		ColumnTypes: []string{"int", "VARCHAR", "DATETIME", "BLOB"},
+		RowWidth:    229,
+		Rows:        3000,
+		Seed:        1,
+	}
+	ir, err := NewSyntheticTableIR(table)
+	c.Assert(err, IsNil)
+	c.Assert(ir.ColumnTypes(), DeepEquals, []string{"INT", "VARCHAR", "DATETIME", "BLOB"})
+	c.Assert(ir.ColumnNames(), DeepEquals, []string{"c1", "c2", "c3", "c4"})
+
+	var csv bytes.Buffer
+	c.Assert(WriteInsertInCsv(context.Background(), ir, &csv, false, "\\N", nil), IsNil)
+	row := MakeRowReceiver(ir.ColumnTypes()).(RowReceiverArr)
+	iter := ir.Rows()
+	var rows int
+	for ; iter.HasNext(); iter.Next() {
+		c.Assert(iter.Decode(row), IsNil)
+		// the string and binary columns share the width left by the others
+		c.Assert(row[1].ReportSize(), Equals, uint64(100))
+		c.Assert(row[3].ReportSize(), Equals, uint64(100))
+		rows++
+	}
+	c.Assert(rows, Equals, 3000)
+
+	// the same seed generates the same rows
+	ir, err = NewSyntheticTableIR(table)
+	c.Assert(err, IsNil)
+	var again bytes.Buffer
+	c.Assert(WriteInsertInCsv(context.Background(), ir, &again, false, "\\N", nil), IsNil)
+	c.Assert(again.String(), Equals, csv.String())
+	c.Assert(strings.HasPrefix(csv.String(), `"c1","c2","c3","c4"`+"\n"), IsTrue)
+}
+
+func benchmarkSyntheticTable(b *testing.B, write func(TableDataIR) error) {
+	ir, err := NewSyntheticTableIR(SyntheticTable{
+		Database:    "bench",
+		Table:       "t",
+		ColumnTypes: []string{"BIGINT", "VARCHAR", "DATETIME", "DECIMAL", "TEXT", "BLOB"},
+		RowWidth:    256,
+		Rows:        10000,
+	})
+	if err != nil {
+		b.Fatal(err)
+	}
+	b.SetBytes(256 * 10000)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if err := write(ir); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkWriteInsert(b *testing.B) {
+	benchmarkSyntheticTable(b, func(ir TableDataIR) error {
+		return WriteInsert(context.Background(), ir, ioutil.Discard, UnspecifiedSize, nil)
+	})
+}
+
+func BenchmarkWriteInsertInCsv(b *testing.B) {
+	benchmarkSyntheticTable(b, func(ir TableDataIR) error {
+		return WriteInsertInCsv(context.Background(), ir, ioutil.Discard, false, "\\N", nil)
+	})
+}
+
+func BenchmarkWriteInsertInTsv(b *testing.B) {
+	benchmarkSyntheticTable(b, func(ir TableDataIR) error {
+		return WriteInsertInTsv(context.Background(), ir, ioutil.Discard, false, nil)
+	})
+}
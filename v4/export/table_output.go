@@ -0,0 +1,122 @@
+package export
+
+import (
+	"context"
+	"io"
+	"path"
+)
+
+// tableOutput is the destination of the files of a table given by TableConfig.
+type tableOutput struct {
+	database string
+	table    string
+	dir      string
+	storage  ExternalStorage
+}
+
+// routedStorage writes the files of the tables with their own outputs into
+// their storages, and the other files into the embedded ExternalStorage.
+type routedStorage struct {
+	ExternalStorage
+	outputs []tableOutput
+}
+
+// newRoutedStorage returns the storage routing the files of the tables with
+// Output or ExternalStorage in TableConfigs, or base itself if there isn't any.
+// The LocalStorage of Output is wrapped by the same WriterWrappers as base,
+// such as compression and encryption.
+func newRoutedStorage(conf *Config, base ExternalStorage) (ExternalStorage, error) {
+	var outputs []tableOutput
+	for _, tc := range conf.TableConfigs {
+		if tc.Output == "" && tc.ExternalStorage == nil {
+			continue
+		}
+		output := tableOutput{
+			database: tc.Database,
+			table:    tc.Table,
+			dir:      tc.Output,
+			storage:  tc.ExternalStorage,
+		}
+		if output.storage == nil {
+			storage, err := NewLocalStorage(tc.Output, conf.SyncFiles)
+			if err != nil {
+				return nil, err
+			}
+			output.storage = rewrapStorage(base, storage)
+		}
+		outputs = append(outputs, output)
+	}
+	if len(outputs) == 0 {
+		return base, nil
+	}
+	return &routedStorage{ExternalStorage: base, outputs: outputs}, nil
+}
+
+// rewrapStorage wraps s by the WriterWrappers of wrapped in the same order.
+func rewrapStorage(wrapped, s ExternalStorage) ExternalStorage {
+	var wraps []WriterWrapper
+	for {
+		w, ok := wrapped.(*wrappedStorage)
+		if !ok {
+			break
+		}
+		wraps = append(wraps, w.wrap)
+		wrapped = w.ExternalStorage
+	}
+	for i := len(wraps) - 1; i >= 0; i-- {
+		s = WrapStorage(s, wraps[i])
+	}
+	return s
+}
+
+// fileTableKey is the key of the fileTable in the contexts of creating files.
+type fileTableKey struct{}
+
+type fileTable struct {
+	database string
+	table    string
+}
+
+// withFileTable returns the context of creating the files of database.table,
+// which are written into the output of the table. The table set first is
+// kept, so that the routed tables are written into the outputs of their
+// source tables.
+func withFileTable(ctx context.Context, database, table string) context.Context {
+	if _, ok := ctx.Value(fileTableKey{}).(fileTable); ok {
+		return ctx
+	}
+	return context.WithValue(ctx, fileTableKey{}, fileTable{database: database, table: table})
+}
+
+// route returns the output of the table of the files created by ctx, or nil
+// if the files belong to the default output.
+func (s *routedStorage) route(ctx context.Context) *tableOutput {
+	t, ok := ctx.Value(fileTableKey{}).(fileTable)
+	if !ok {
+		return nil
+	}
+	for i := range s.outputs {
+		if output := &s.outputs[i]; output.database == t.database && output.table == t.table {
+			return output
+		}
+	}
+	return nil
+}
+
+func (s *routedStorage) Create(ctx context.Context, name string) (io.WriteCloser, error) {
+	if output := s.route(ctx); output != nil {
+		return output.storage.Create(ctx, name)
+	}
+	return s.ExternalStorage.Create(ctx, name)
+}
+
+// outputPath returns the path of the output file name created by ctx, which
+// is in the output of its table if the table has one.
+func (conf *Config) outputPath(ctx context.Context, name string) string {
+	if s, ok := conf.ExternalStorage.(*routedStorage); ok {
+		if output := s.route(ctx); output != nil && output.dir != "" {
+			return path.Join(output.dir, name)
+		}
+	}
+	return path.Join(conf.OutputDirPath, name)
+}
@@ -0,0 +1,128 @@
+package export
+
+import (
+	"context"
+	"io"
+	"io/ioutil"
+	"path/filepath"
+
+	. "github.com/pingcap/check"
+)
+
+var _ = Suite(&testTableOutputSuite{})
+
+type testTableOutputSuite struct{}
+
+func (s *testTableOutputSuite) TestRoutedStorage(c *C) {
+	conf := DefaultConfig()
+	conf.OutputDirPath = "/dump"
+	base, pii, nested := newMemStorage(), newMemStorage(), newMemStorage()
+	conf.TableConfigs = []TableConfig{
+		{Database: "app", Table: "users", ExternalStorage: pii, Output: "/secure"},
+		{Database: "app", Table: "users.v2", ExternalStorage: nested},
+		{Database: "app", Table: "orders", Where: "id > 0"},
+	}
+	storage, err := newRoutedStorage(conf, base)
+	c.Assert(err, IsNil)
+	conf.ExternalStorage = storage
+
+	// the files are routed by their tables rather than the prefixes of their names
+	ctx := context.Background()
+	users := withFileTable(ctx, "app", "users")
+	for _, f := range []struct {
+		ctx  context.Context
+		name string
+	}{
+		{users, "app.users.0.sql"},
+		{users, "app.users-schema.sql"},
+		{users, "app.users/part-0.csv"},
+		{withFileTable(users, "app", "orders"), "merged.users.0.sql"},
+		{withFileTable(ctx, "app", "users.v2"), "app.users.v2.0.sql"},
+		{withFileTable(ctx, "app.users", "v2"), "app.users.v2.1.sql"},
+		{withFileTable(ctx, "app", "users-archive"), "app.users-archive.0.sql"},
+		{withFileTable(ctx, "app", "orders"), "app.orders.0.sql"},
+		{ctx, "app.users.1.sql"},
+		{ctx, "metadata"},
+	} {
+		w, err := storage.Create(f.ctx, f.name)
+		c.Assert(err, IsNil)
+		_, err = w.Write([]byte(f.name))
+		c.Assert(err, IsNil)
+		c.Assert(w.Close(), IsNil)
+	}
+	c.Assert(pii.files, DeepEquals, map[string]string{
+		"app.users.0.sql":      "app.users.0.sql",
+		"app.users-schema.sql": "app.users-schema.sql",
+		"app.users/part-0.csv": "app.users/part-0.csv",
+		"merged.users.0.sql":   "merged.users.0.sql",
+	})
+	c.Assert(nested.files, DeepEquals, map[string]string{"app.users.v2.0.sql": "app.users.v2.0.sql"})
+	c.Assert(base.files, DeepEquals, map[string]string{
+		"app.users.v2.1.sql":      "app.users.v2.1.sql",
+		"app.users-archive.0.sql": "app.users-archive.0.sql",
+		"app.orders.0.sql":        "app.orders.0.sql",
+		"app.users.1.sql":         "app.users.1.sql",
+		"metadata":                "metadata",
+	})
+	c.Assert(baseStorage(storage), Equals, ExternalStorage(base))
+
+	c.Assert(conf.outputPath(users, "app.users.0.sql"), Equals, "/secure/app.users.0.sql")
+	c.Assert(conf.outputPath(withFileTable(ctx, "app", "users.v2"), "app.users.v2.0.sql"), Equals, "/dump/app.users.v2.0.sql")
+	c.Assert(conf.outputPath(ctx, "app.users.0.sql"), Equals, "/dump/app.users.0.sql")
+
+	// the storage isn't routed without any output
+	conf.TableConfigs = conf.TableConfigs[2:]
+	storage, err = newRoutedStorage(conf, base)
+	c.Assert(err, IsNil)
+	c.Assert(storage, Equals, ExternalStorage(base))
+}
+
+func (s *testTableOutputSuite) TestLocalOutput(c *C) {
+	conf := DefaultConfig()
+	conf.OutputDirPath = c.MkDir()
+	secure := filepath.Join(c.MkDir(), "secure")
+	conf.TableConfigs = []TableConfig{{Database: "app", Table: "users", Output: secure}}
+	base, err := newStorage(conf)
+	c.Assert(err, IsNil)
+	conf.ExternalStorage, err = newRoutedStorage(conf, base)
+	c.Assert(err, IsNil)
+	writer, err := NewSimpleWriter(conf)
+	c.Assert(err, IsNil)
+	c.Assert(writer.WriteTableMeta(context.Background(), "app", "users", "CREATE TABLE `users` (`id` INT)"), IsNil)
+	content, err := ioutil.ReadFile(filepath.Join(secure, "app.users-schema.sql"))
+	c.Assert(err, IsNil)
+	c.Assert(string(content), Equals, "CREATE TABLE `users` (`id` INT);\n")
+}
+
+func (s *testTableOutputSuite) TestWrappedLocalOutput(c *C) {
+	conf := DefaultConfig()
+	secure := c.MkDir()
+	conf.TableConfigs = []TableConfig{{Database: "app", Table: "users", Output: secure}}
+	var wrappedNames []string
+	// the files of the output are wrapped in the same order as the default ones
+	wrap := func(suffix string) WriterWrapper {
+		return func(name string, w io.WriteCloser) (io.WriteCloser, error) {
+			wrappedNames = append(wrappedNames, name+suffix)
+			return upperWriter{w}, nil
+		}
+	}
+	base := WrapStorage(WrapStorage(newMemStorage(), wrap(" inner")), wrap(" outer"))
+	storage, err := newRoutedStorage(conf, base)
+	c.Assert(err, IsNil)
+	conf.ExternalStorage = storage
+	writer, err := NewSimpleWriter(conf)
+	c.Assert(err, IsNil)
+	c.Assert(writer.WriteTableMeta(context.Background(), "app", "users", "create table users (id int)"), IsNil)
+	c.Assert(wrappedNames, DeepEquals, []string{"app.users-schema.sql inner", "app.users-schema.sql outer"})
+	content, err := ioutil.ReadFile(filepath.Join(secure, "app.users-schema.sql"))
+	c.Assert(err, IsNil)
+	c.Assert(string(content), Equals, "CREATE TABLE USERS (ID INT);\n")
+}
+
+func (s *testTableOutputSuite) TestValidate(c *C) {
+	conf := DefaultConfig()
+	conf.TableConfigs = []TableConfig{{Database: "app", Table: "users", Output: "/secure"}}
+	c.Assert(conf.Validate(), IsNil)
+	conf.FileType = "sqlite"
+	c.Assert(conf.Validate(), ErrorMatches, "the outputs of tables are only supported with filetype sql, csv and tsv")
+}
@@ -0,0 +1,222 @@
+package export
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+
+	"github.com/pingcap/errors"
+	"go.uber.org/zap"
+
+	"github.com/pingcap/dumpling/v4/log"
+)
+
+// execWriter executes the statements of the SQL files instead of writing them,
+// through the statementWriter of open for every meta, table or chunk.
+type execWriter struct {
+	cfg          *Config
+	bytesLimiter *throughputLimiter
+	rowsLimiter  *throughputLimiter
+	buffers      *BufferPool
+	// open returns a statementWriter which executes the statements in db.
+	open func(ctx context.Context, db string) (*statementWriter, error)
+}
+
+func newExecWriter(config *Config) execWriter {
+	return execWriter{
+		cfg:          config,
+		bytesLimiter: newThroughputLimiter(config.ThrottleBytesPerSec),
+		rowsLimiter:  newThroughputLimiter(config.ThrottleRowsPerSec),
+		buffers:      newConfigBufferPool(config),
+	}
+}
+
+func (f *execWriter) WriteDatabaseMeta(ctx context.Context, db, createSQL string) error {
+	return f.writeMeta(ctx, "", createSQL)
+}
+
+func (f *execWriter) WriteTableMeta(ctx context.Context, db, table, createSQL string) error {
+	return f.writeMeta(ctx, db, createSQL)
+}
+
+func (f *execWriter) writeMeta(ctx context.Context, db, createSQL string) error {
+	w, err := f.open(ctx, db)
+	if err != nil {
+		return err
+	}
+	err = WriteMeta(ctx, &metaData{target: db, metaSQL: createSQL}, w)
+	return closeFile(w, err)
+}
+
+func (f *execWriter) WriteTableData(ctx context.Context, ir TableDataIR) error {
+	log.Debug("start restoring table...", zap.String("table", ir.TableName()))
+
+	w, err := f.open(ctx, ir.DatabaseName())
+	if err != nil {
+		return err
+	}
+	chunksIter := buildChunksIter(withRowsThrottle(ctx, ir, f.rowsLimiter), UnspecifiedSize, f.cfg.StatementSize)
+	defer chunksIter.Rows().Close()
+	err = WriteInsert(ctx, chunksIter, newThrottledWriter(ctx, w, f.bytesLimiter), f.cfg.TransactionRows, f.buffers)
+	if err = closeFile(w, err); err != nil {
+		return err
+	}
+	log.Debug("restoring table successfully",
+		zap.String("table", ir.TableName()))
+	return nil
+}
+
+// TargetWriter replays the dump into the server of TargetDSN instead of writing
+// the SQL files. Every table or chunk is written through its own connection,
+// so the tables are restored as concurrently as they're dumped.
+type TargetWriter struct {
+	execWriter
+	db *sql.DB
+}
+
+func NewTargetWriter(config *Config) (Writer, error) {
+	db, err := sql.Open("mysql", config.TargetDSN)
+	if err != nil {
+		return nil, withStack(withKind(ErrorKindConnection, err))
+	}
+	return newTargetWriter(config, db), nil
+}
+
+func newTargetWriter(config *Config, db *sql.DB) *TargetWriter {
+	w := &TargetWriter{execWriter: newExecWriter(config), db: db}
+	w.open = w.openConn
+	return w
+}
+
+// Close closes the connections to the target.
+func (f *TargetWriter) Close() error {
+	return f.db.Close()
+}
+
+// openConn returns a statementWriter on a new connection using db.
+func (f *TargetWriter) openConn(ctx context.Context, db string) (*statementWriter, error) {
+	conn, err := f.db.Conn(ctx)
+	if err != nil {
+		return nil, withStack(withKind(ErrorKindConnection, err))
+	}
+	if db != "" {
+		query := "USE " + wrapBackTicks(db)
+		if _, err = conn.ExecContext(ctx, query); err != nil {
+			conn.Close()
+			return nil, withStack(withKind(ErrorKindWrite, errors.WithMessage(err, query)))
+		}
+	}
+	return newStatementWriter(ctx, conn, f.cfg.EscapeBackslash), nil
+}
+
+// statementWriter executes the statements written to it on conn. The
+// statements end with the delimiter and "\n" outside of the quoted strings,
+// identifiers and comments, the delimiter is ";" and changed by the DELIMITER
+// lines like the mysql client.
+type statementWriter struct {
+	ctx  context.Context
+	conn *sql.Conn
+
+	buf       []byte
+	delimiter []byte
+	scanner   sqlScanner
+	// scanned is how many bytes of buf are scanned
+	scanned int
+}
+
+func newStatementWriter(ctx context.Context, conn *sql.Conn, escapeBackslash bool) *statementWriter {
+	return &statementWriter{
+		ctx:       ctx,
+		conn:      conn,
+		delimiter: []byte(";"),
+		scanner:   sqlScanner{escapeBackslash: escapeBackslash},
+	}
+}
+
+func (w *statementWriter) Write(p []byte) (int, error) {
+	w.buf = append(w.buf, p...)
+	start, i := 0, w.scanned
+	for i < len(w.buf) {
+		// the delimiter at the end of a line comment doesn't end the statement
+		inComment := w.scanner.comment != 0
+		kind, n := w.scanner.scan(w.buf[i:], false)
+		if kind == sqlIncomplete {
+			break
+		}
+		if kind == sqlCode && w.buf[i] == '\n' && !inComment {
+			stmt := w.buf[start:i]
+			// the DELIMITER line may follow the comments, e.g. of mysqlbinlog
+			line := stmt[bytes.LastIndexByte(stmt, '\n')+1:]
+			if delimiter, ok := parseDelimiter(line); ok && isEmptyStatement(stmt[:len(stmt)-len(line)]) {
+				w.delimiter = delimiter
+				start = i + 1
+			} else if bytes.HasSuffix(stmt, w.delimiter) {
+				if err := w.exec(stmt[:len(stmt)-len(w.delimiter)]); err != nil {
+					return 0, err
+				}
+				start = i + 1
+			}
+		}
+		i += n
+	}
+	w.scanned = i - start
+	w.buf = append(w.buf[:0], w.buf[start:]...)
+	return len(p), nil
+}
+
+func (w *statementWriter) exec(stmt []byte) error {
+	if isEmptyStatement(stmt) {
+		return nil
+	}
+	query := string(stmt)
+	if _, err := w.conn.ExecContext(w.ctx, query); err != nil {
+		// query might be very long, only output the first 200 chars
+		if len(query) > 200 {
+			query = query[:200]
+		}
+		return withStack(withKind(ErrorKindWrite, errors.WithMessage(err, query)))
+	}
+	return nil
+}
+
+// parseDelimiter returns the delimiter of stmt if it's a DELIMITER line.
+func parseDelimiter(stmt []byte) ([]byte, bool) {
+	const command = "DELIMITER "
+	stmt = bytes.TrimSpace(stmt)
+	if len(stmt) <= len(command) || !bytes.EqualFold(stmt[:len(command)], []byte(command)) || bytes.IndexByte(stmt, '\n') >= 0 {
+		return nil, false
+	}
+	delimiter := bytes.TrimSpace(stmt[len(command):])
+	return append([]byte(nil), delimiter...), len(delimiter) > 0
+}
+
+// isEmptyStatement returns whether stmt has only blank and comment lines,
+// which are rejected by the servers as empty queries. The `#` comments are
+// written by mysqlbinlog.
+func isEmptyStatement(stmt []byte) bool {
+	for _, line := range bytes.Split(stmt, []byte("\n")) {
+		line = bytes.TrimSpace(line)
+		if len(line) > 0 && !bytes.HasPrefix(line, []byte("--")) && !bytes.HasPrefix(line, []byte("#")) {
+			return false
+		}
+	}
+	return true
+}
+
+// Close executes the statement left without the delimiter, and releases the
+// connection.
+func (w *statementWriter) Close() error {
+	var err error
+	if _, ok := parseDelimiter(w.buf); !ok {
+		err = w.exec(w.buf)
+	}
+	if closeErr := w.conn.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
+
+// Abort releases the connection without executing the incomplete statement.
+func (w *statementWriter) Abort() error {
+	return w.conn.Close()
+}
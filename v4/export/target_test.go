@@ -0,0 +1,129 @@
+package export
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	. "github.com/pingcap/check"
+)
+
+var _ = Suite(&testTargetSuite{})
+
+type testTargetSuite struct{}
+
+func newTestTargetWriter(c *C, conf *Config) (*TargetWriter, sqlmock.Sqlmock) {
+	db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+	c.Assert(err, IsNil)
+	return newTargetWriter(conf, db), mock
+}
+
+func (s *testTargetSuite) TestWriteMeta(c *C) {
+	writer, mock := newTestTargetWriter(c, DefaultConfig())
+	defer writer.Close()
+
+	mock.ExpectExec("CREATE DATABASE `test`").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec("USE `test`").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec("CREATE TABLE `t` (`a` int)").WillReturnResult(sqlmock.NewResult(0, 0))
+	ctx := context.Background()
+	c.Assert(writer.WriteDatabaseMeta(ctx, "test", "CREATE DATABASE `test`"), IsNil)
+	c.Assert(writer.WriteTableMeta(ctx, "test", "t", "CREATE TABLE `t` (`a` int)"), IsNil)
+	c.Assert(mock.ExpectationsWereMet(), IsNil)
+}
+
+func (s *testTargetSuite) TestWriteTableData(c *C) {
+	conf := DefaultConfig()
+	conf.TransactionRows = 2
+	writer, mock := newTestTargetWriter(c, conf)
+	defer writer.Close()
+
+	data := [][]driver.Value{
+		{"1", "a;\nb"},
+		{"2", "c"},
+		{"3", "d"},
+	}
+	specCmts := []string{"/*!40101 SET NAMES binary*/;"}
+	tableIR := newMockTableIR("test", "t", data, specCmts, []string{"INT", "VARCHAR"})
+	tableIR.(*mockTableIR).escapeBackSlash = true
+
+	result := sqlmock.NewResult(0, 0)
+	mock.ExpectExec("USE `test`").WillReturnResult(result)
+	mock.ExpectExec("/*!40101 SET NAMES binary*/").WillReturnResult(result)
+	mock.ExpectExec("BEGIN").WillReturnResult(result)
+	mock.ExpectExec("INSERT INTO `t` VALUES\n(1,'a;\\nb'),\n(2,'c')").WillReturnResult(result)
+	mock.ExpectExec("COMMIT").WillReturnResult(result)
+	mock.ExpectExec("BEGIN").WillReturnResult(result)
+	mock.ExpectExec("INSERT INTO `t` VALUES\n(3,'d')").WillReturnResult(result)
+	mock.ExpectExec("COMMIT").WillReturnResult(result)
+	c.Assert(writer.WriteTableData(context.Background(), tableIR), IsNil)
+	c.Assert(mock.ExpectationsWereMet(), IsNil)
+}
+
+func (s *testTargetSuite) TestWriteTableDataReturnsError(c *C) {
+	writer, mock := newTestTargetWriter(c, DefaultConfig())
+	defer writer.Close()
+
+	tableIR := newMockTableIR("test", "t", [][]driver.Value{{"1"}}, nil, []string{"INT"})
+	mock.ExpectExec("USE `test`").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec("INSERT INTO `t` VALUES\n(1)").WillReturnError(errors.New("table doesn't exist"))
+	err := writer.WriteTableData(context.Background(), tableIR)
+	c.Assert(err, ErrorMatches, "(?s).*table doesn't exist.*")
+	c.Assert(ErrorKindOf(err), Equals, ErrorKindWrite)
+	c.Assert(mock.ExpectationsWereMet(), IsNil)
+}
+
+func (s *testTargetSuite) TestStatementWriter(c *C) {
+	db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+	c.Assert(err, IsNil)
+	defer db.Close()
+	conn, err := db.Conn(context.Background())
+	c.Assert(err, IsNil)
+
+	mock.ExpectExec("-- comment\nSELECT 1").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec("SELECT 'a;\n''b', `c;\n`").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec("SELECT 'd\\';\n'").WillReturnResult(sqlmock.NewResult(0, 0))
+	w := newStatementWriter(context.Background(), conn, true)
+	for _, p := range []string{"-- comment\n", "SEL", "ECT 1;", "\n\n;\nSELECT 'a;\n", "''b', `c;\n`;\n", "SELECT 'd\\';\n';\n-- end"} {
+		n, err := w.Write([]byte(p))
+		c.Assert(err, IsNil)
+		c.Assert(n, Equals, len(p))
+	}
+	c.Assert(w.Close(), IsNil)
+	c.Assert(mock.ExpectationsWereMet(), IsNil)
+}
+
+func (s *testTargetSuite) TestStatementWriterComments(c *C) {
+	db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+	c.Assert(err, IsNil)
+	defer db.Close()
+	conn, err := db.Conn(context.Background())
+	c.Assert(err, IsNil)
+
+	// the quotation marks and the delimiters in the comments are ignored, even
+	// if the comments are split into the writes
+	mock.ExpectExec("-- Host: o'brien.local    Database: app\n# it's\nSELECT 1").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec("/* a;\n' */ SELECT 2").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec("/*!40101 SET @a = 1;\n*/").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec("-- the end;\nSELECT 3--1").WillReturnResult(sqlmock.NewResult(0, 0))
+	w := newStatementWriter(context.Background(), conn, true)
+	for _, p := range []string{"-", "- Host: o'brien.local    Database: app\n# it's\nSELECT 1;\n", "/", "* a;\n' *", "/ SELECT 2;\n",
+		"/*!40101 SET @a = 1;\n*/;\n", "-- the end;\n", "SELECT 3-", "-1;\n"} {
+		n, err := w.Write([]byte(p))
+		c.Assert(err, IsNil)
+		c.Assert(n, Equals, len(p))
+	}
+	c.Assert(w.Close(), IsNil)
+	c.Assert(mock.ExpectationsWereMet(), IsNil)
+}
+
+func (s *testTargetSuite) TestValidate(c *C) {
+	conf := DefaultConfig()
+	conf.TargetDSN = "root:@tcp(127.0.0.1:4000)/"
+	c.Assert(conf.Validate(), IsNil)
+	conf.FileType = "csv"
+	c.Assert(conf.Validate(), ErrorMatches, "target-dsn is only supported with filetype sql of the mysql and tidb target dialects")
+	conf.FileType = "sql"
+	conf.TargetDialect = TargetPostgres
+	c.Assert(conf.Validate(), ErrorMatches, "target-dsn is only supported with filetype sql of the mysql and tidb target dialects")
+}
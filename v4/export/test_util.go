@@ -1,9 +1,13 @@
 package export
 
 import (
+	"bytes"
+	"context"
 	"database/sql"
 	"database/sql/driver"
 	"fmt"
+	"io"
+	"sync"
 
 	"github.com/DATA-DOG/go-sqlmock"
 )
@@ -20,6 +24,10 @@ func (m *mockStringWriter) WriteString(s string) (int, error) {
 	return len(s), nil
 }
 
+func (m *mockStringWriter) Write(p []byte) (int, error) {
+	return m.WriteString(string(p))
+}
+
 type mockStringCollector struct {
 	buf string
 }
@@ -29,6 +37,37 @@ func (m *mockStringCollector) WriteString(s string) (int, error) {
 	return len(s), nil
 }
 
+func (m *mockStringCollector) Write(p []byte) (int, error) {
+	return m.WriteString(string(p))
+}
+
+// memStorage is an ExternalStorage keeping the closed files in memory.
+type memStorage struct {
+	mu    sync.Mutex
+	files map[string]string
+}
+
+func newMemStorage() *memStorage {
+	return &memStorage{files: map[string]string{}}
+}
+
+func (s *memStorage) Create(_ context.Context, name string) (io.WriteCloser, error) {
+	return &memFile{name: name, storage: s}, nil
+}
+
+type memFile struct {
+	bytes.Buffer
+	name    string
+	storage *memStorage
+}
+
+func (f *memFile) Close() error {
+	f.storage.mu.Lock()
+	f.storage.files[f.name] = f.String()
+	f.storage.mu.Unlock()
+	return nil
+}
+
 type mockSQLRowIterator struct {
 	idx  int
 	data [][]sql.NullString
@@ -75,16 +114,19 @@ func newMockMetaIR(targetName string, meta string, specialComments []string) Met
 }
 
 type mockTableIR struct {
-	dbName          string
-	tblName         string
-	chunIndex       int
-	data            [][]driver.Value
-	selectedField   string
-	specCmt         []string
-	colTypes        []string
-	colNames        []string
-	escapeBackSlash bool
-	rowErr          error
+	dbName           string
+	tblName          string
+	chunIndex        int
+	data             [][]driver.Value
+	selectedField    string
+	specCmt          []string
+	specFooter       []string
+	completed        bool
+	colTypes         []string
+	colNames         []string
+	escapeBackSlash  bool
+	quoteBigIntegers bool
+	rowErr           error
 }
 
 func (m *mockTableIR) DatabaseName() string {
@@ -122,6 +164,10 @@ func (m *mockTableIR) SpecialComments() StringIter {
 	return newStringIter(m.specCmt...)
 }
 
+func (m *mockTableIR) SpecialFooters() StringIter {
+	return newStringIter(m.specFooter...)
+}
+
 func (m *mockTableIR) Rows() SQLRowIter {
 	mockRows := sqlmock.NewRows(m.colTypes)
 	for _, datum := range m.data {
@@ -148,6 +194,18 @@ func (m *mockTableIR) EscapeBackSlash() bool {
 	return m.escapeBackSlash
 }
 
+func (m *mockTableIR) QuoteBigIntegers() bool {
+	return m.quoteBigIntegers
+}
+
+func (m *mockTableIR) MysqldumpCompleted() bool {
+	return m.completed
+}
+
+func (m *mockTableIR) Output() OutputDialect {
+	return mysqlOutput{}
+}
+
 func newMockTableIR(databaseName, tableName string, data [][]driver.Value, specialComments, colTypes []string) TableDataIR {
 	return &mockTableIR{
 		dbName:        databaseName,
@@ -0,0 +1,39 @@
+package export
+
+import (
+	"strings"
+)
+
+// sessionStatements returns the statements run on every connection to the
+// source besides the session variables, which bind the sessions to
+// ResourceGroup and set TiDBReplicaRead. It's empty until the source is
+// known to be TiDB.
+func (conf *Config) sessionStatements() []string {
+	caps := conf.ServerInfo.Capabilities()
+	var statements []string
+	if conf.ResourceGroup != "" && caps.ResourceGroups {
+		statements = append(statements, "SET RESOURCE GROUP "+wrapBackTicks(conf.ResourceGroup))
+	}
+	if conf.TiDBReplicaRead != "" && caps.ReplicaRead {
+		statements = append(statements, sessionVariable{name: "tidb_replica_read", value: conf.TiDBReplicaRead}.String())
+	}
+	return statements
+}
+
+// tableDataQuery returns the SELECT of table data with the priority and the
+// limit of the execution time of the chunks.
+func (conf *Config) tableDataQuery(query string) string {
+	return conf.limitExecutionTime(conf.lowPriority(query))
+}
+
+// lowPriority returns the SELECT query read with LOW_PRIORITY by the TiKV
+// coprocessors if LowPriority is set, so that the queries of the other
+// workloads are scheduled before it.
+func (conf *Config) lowPriority(query string) string {
+	const selectKeyword = "SELECT "
+	if !conf.LowPriority || !conf.ServerInfo.Capabilities().LowPriority || len(query) < len(selectKeyword) ||
+		!strings.EqualFold(query[:len(selectKeyword)], selectKeyword) {
+		return query
+	}
+	return query[:len(selectKeyword)] + "LOW_PRIORITY " + query[len(selectKeyword):]
+}
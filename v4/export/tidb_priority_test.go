@@ -0,0 +1,65 @@
+package export
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/coreos/go-semver/semver"
+	. "github.com/pingcap/check"
+)
+
+var _ = Suite(&testTiDBPrioritySuite{})
+
+type testTiDBPrioritySuite struct{}
+
+func (s *testTiDBPrioritySuite) TestValidate(c *C) {
+	conf := DefaultConfig()
+	conf.ResourceGroup = "rg`x"
+	c.Assert(conf.Validate(), ErrorMatches, "(?s).*invalid resource-group rg`x.*")
+
+	conf.ResourceGroup = "batch"
+	conf.ServerInfo = ServerInfo{ServerType: ServerTypeTiDB, ServerVersion: semver.New("6.5.0")}
+	c.Assert(conf.validateServer(), ErrorMatches, "resource-group is only supported by TiDB 7.1 and later, got TiDB")
+	conf.ServerInfo.ServerVersion = semver.New("7.1.0")
+	c.Assert(conf.validateServer(), IsNil)
+
+	conf = DefaultConfig()
+	conf.LowPriority = true
+	conf.ServerInfo = ServerInfo{ServerType: ServerTypeMySQL, ServerVersion: semver.New("8.0.18")}
+	c.Assert(conf.validateServer(), ErrorMatches, "low-priority is only supported by TiDB, got MySQL")
+}
+
+func (s *testTiDBPrioritySuite) TestTableDataQuery(c *C) {
+	conf := DefaultConfig()
+	conf.ServerInfo = ServerInfo{ServerType: ServerTypeTiDB, ServerVersion: semver.New("7.1.0")}
+	query := "SELECT * FROM `test`.`t`"
+	c.Assert(conf.tableDataQuery(query), Equals, query)
+	conf.LowPriority = true
+	c.Assert(conf.tableDataQuery(query), Equals, "SELECT LOW_PRIORITY * FROM `test`.`t`")
+	// the hints are still right after SELECT
+	conf.ChunkTimeout = 60
+	c.Assert(conf.tableDataQuery(query), Equals, "SELECT /*+ MAX_EXECUTION_TIME(60000) */ LOW_PRIORITY * FROM `test`.`t`")
+}
+
+func (s *testTiDBPrioritySuite) TestSessionStatements(c *C) {
+	conf := DefaultConfig()
+	conf.ResourceGroup = "batch"
+	// the source isn't detected yet
+	c.Assert(conf.sessionStatements(), HasLen, 0)
+	conf.ServerInfo = ServerInfo{ServerType: ServerTypeTiDB, ServerVersion: semver.New("7.5.0")}
+	c.Assert(conf.sessionStatements(), DeepEquals, []string{"SET RESOURCE GROUP `batch`"})
+
+	recorder := &recordingConnector{}
+	connector := &sessionConnector{Connector: recorder, maxIdle: 2, variables: []sessionVariable{{name: "wait_timeout", value: "28800"}}}
+	db := sql.OpenDB(connector)
+	defer db.Close()
+	ctx := context.Background()
+	_, err := db.ExecContext(ctx, "SELECT 1")
+	c.Assert(err, IsNil)
+	connector.keepStatements(db, conf.sessionStatements())
+	_, err = db.ExecContext(ctx, "SELECT 2")
+	c.Assert(err, IsNil)
+	c.Assert(recorder.conns, HasLen, 2)
+	c.Assert(recorder.conns[1].statements, DeepEquals, []string{
+		"SET SESSION wait_timeout = 28800", "SET RESOURCE GROUP `batch`", "SELECT 2"})
+}
@@ -0,0 +1,127 @@
+package export
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+
+	"github.com/pingcap/errors"
+
+	"github.com/pingcap/dumpling/v4/log"
+)
+
+// placementPoliciesPath is the file of the placement policies of TiDB, which
+// should be restored before the schemas of the tables referencing them.
+const placementPoliciesPath = "placement-policies.sql"
+
+// tidbReplicasConflicts returns the options conflicting with TiDBReplicas.
+func tidbReplicasConflicts(conf *Config) []string {
+	if strings.ToLower(conf.FileType) != "sql" || conf.TargetDSN != "" || conf.Sql != "" || conf.NoSchemas || len(conf.RouteRules) > 0 {
+		return []string{"tidb-replicas is only supported with filetype sql, and not with target-dsn, sql, no-schemas or route rules"}
+	}
+	return nil
+}
+
+// dumpTiDBReplicas writes the placement policies of the server into
+// placement-policies.sql, and the TiFlash replicas of the filtered tables of
+// a database as ALTER TABLE statements into `{db}-schema-tiflash.sql`, which
+// are run after the tables are restored.
+func dumpTiDBReplicas(ctx context.Context, conf *Config, db *sql.DB) error {
+	if conf.ServerInfo.Capabilities().PlacementPolicies {
+		policies, err := listPlacementPolicies(db)
+		if err != nil {
+			return withKind(ErrorKindSchema, err)
+		}
+		if len(policies) > 0 {
+			if err = writeSchemaFile(ctx, conf, placementPoliciesPath, strings.Join(policies, ";\n")+";\n"); err != nil {
+				return err
+			}
+		}
+	}
+	for dbName, tables := range conf.Tables {
+		statements, err := listTiFlashReplicas(db, dbName, tables)
+		if err != nil {
+			return withKind(ErrorKindSchema, err)
+		}
+		if len(statements) == 0 {
+			continue
+		}
+		fileName := fmt.Sprintf("%s-schema-tiflash.sql", dbName)
+		if err = writeSchemaFile(ctx, conf, fileName, strings.Join(statements, ";\n")+";\n"); err != nil {
+			return err
+		}
+	}
+	log.Info("dump tidb replicas finished")
+	return nil
+}
+
+// listPlacementPolicies returns the CREATE PLACEMENT POLICY statements of the
+// server, which are restored if they don't exist.
+func listPlacementPolicies(db *sql.DB) ([]string, error) {
+	var names oneStrColumnTable
+	const query = "SELECT POLICY_NAME FROM INFORMATION_SCHEMA.PLACEMENT_POLICIES ORDER BY POLICY_NAME"
+	if err := simpleQuery(db, query, names.handleOneRow); err != nil {
+		return nil, errors.WithMessage(err, query)
+	}
+	policies := make([]string, 0, len(names.data))
+	for _, name := range names.data {
+		var oneRow [2]string
+		handleOneRow := func(rows *sql.Rows) error {
+			return rows.Scan(&oneRow[0], &oneRow[1])
+		}
+		query := fmt.Sprintf("SHOW CREATE PLACEMENT POLICY %s", wrapBackTicks(name))
+		if err := simpleQuery(db, query, handleOneRow); err != nil {
+			return nil, errors.WithMessage(err, query)
+		}
+		policies = append(policies, strings.Replace(oneRow[1], "CREATE PLACEMENT POLICY ", "CREATE PLACEMENT POLICY IF NOT EXISTS ", 1))
+	}
+	return policies, nil
+}
+
+// listTiFlashReplicas returns the ALTER TABLE ... SET TIFLASH REPLICA
+// statements of the base tables of the database with TiFlash replicas.
+func listTiFlashReplicas(db *sql.DB, dbName string, tables []*TableInfo) ([]string, error) {
+	filtered := map[string]bool{}
+	for _, table := range tables {
+		if table.Type == TableTypeBase {
+			filtered[table.Name] = true
+		}
+	}
+	if len(filtered) == 0 {
+		return nil, nil
+	}
+	query := "SELECT DISTINCT TABLE_NAME, REPLICA_COUNT, LOCATION_LABELS FROM INFORMATION_SCHEMA.TIFLASH_REPLICA WHERE TABLE_SCHEMA = ? ORDER BY TABLE_NAME"
+	rows, err := db.Query(query, dbName)
+	if err != nil {
+		return nil, withStack(errors.WithMessage(err, query))
+	}
+	defer rows.Close()
+	var statements []string
+	for rows.Next() {
+		var (
+			table  string
+			count  uint64
+			labels sql.NullString
+		)
+		if err = rows.Scan(&table, &count, &labels); err != nil {
+			return nil, withStack(errors.WithMessage(err, query))
+		}
+		if !filtered[table] {
+			continue
+		}
+		statement := fmt.Sprintf("ALTER TABLE %s SET TIFLASH REPLICA %d", qualifiedTableName(dbName, table), count)
+		if labels.String != "" {
+			quoted := strings.Split(labels.String, ",")
+			for i, label := range quoted {
+				quoted[i] = fmt.Sprintf("'%s'", escapeSQLString(strings.TrimSpace(label)))
+			}
+			statement += " LOCATION LABELS " + strings.Join(quoted, ", ")
+		}
+		statements = append(statements, statement)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, withStack(errors.WithMessage(err, query))
+	}
+	return statements, nil
+}
@@ -0,0 +1,64 @@
+package export
+
+import (
+	"context"
+	"regexp"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/coreos/go-semver/semver"
+	. "github.com/pingcap/check"
+)
+
+var _ = Suite(&testTiDBReplicasSuite{})
+
+type testTiDBReplicasSuite struct{}
+
+func (s *testTiDBReplicasSuite) TestDumpTiDBReplicas(c *C) {
+	db, mock, err := sqlmock.New()
+	c.Assert(err, IsNil)
+	defer db.Close()
+
+	storage := newMemStorage()
+	conf := DefaultConfig()
+	conf.ExternalStorage = storage
+	conf.ServerInfo = ServerInfo{ServerType: ServerTypeTiDB, ServerVersion: semver.New("5.4.0")}
+	conf.Tables = NewDatabaseTables().AppendTables("test", "t1", "t2").AppendViews("test", "v")
+
+	mock.ExpectQuery("SELECT POLICY_NAME FROM INFORMATION_SCHEMA.PLACEMENT_POLICIES").
+		WillReturnRows(sqlmock.NewRows([]string{"POLICY_NAME"}).AddRow("p1"))
+	mock.ExpectQuery(regexp.QuoteMeta("SHOW CREATE PLACEMENT POLICY `p1`")).
+		WillReturnRows(sqlmock.NewRows([]string{"Policy", "Create Policy"}).
+			AddRow("p1", "CREATE PLACEMENT POLICY `p1` PRIMARY_REGION=\"us-east-1\" REGIONS=\"us-east-1,us-west-1\""))
+	mock.ExpectQuery("SELECT DISTINCT TABLE_NAME, REPLICA_COUNT, LOCATION_LABELS FROM INFORMATION_SCHEMA.TIFLASH_REPLICA").WithArgs("test").
+		WillReturnRows(sqlmock.NewRows([]string{"TABLE_NAME", "REPLICA_COUNT", "LOCATION_LABELS"}).
+			AddRow("t1", 2, "zone,host").AddRow("t2", 1, "").AddRow("ignored", 1, ""))
+	c.Assert(dumpTiDBReplicas(context.Background(), conf, db), IsNil)
+	c.Assert(mock.ExpectationsWereMet(), IsNil)
+	c.Assert(storage.files[placementPoliciesPath], Equals,
+		"CREATE PLACEMENT POLICY IF NOT EXISTS `p1` PRIMARY_REGION=\"us-east-1\" REGIONS=\"us-east-1,us-west-1\";\n")
+	c.Assert(storage.files["test-schema-tiflash.sql"], Equals,
+		"ALTER TABLE `test`.`t1` SET TIFLASH REPLICA 2 LOCATION LABELS 'zone', 'host';\n"+
+			"ALTER TABLE `test`.`t2` SET TIFLASH REPLICA 1;\n")
+
+	// the placement policies are skipped before TiDB 5.3
+	storage = newMemStorage()
+	conf.ExternalStorage = storage
+	conf.ServerInfo.ServerVersion = semver.New("4.0.0")
+	mock.ExpectQuery("SELECT DISTINCT TABLE_NAME, REPLICA_COUNT, LOCATION_LABELS FROM INFORMATION_SCHEMA.TIFLASH_REPLICA").WithArgs("test").
+		WillReturnRows(sqlmock.NewRows([]string{"TABLE_NAME", "REPLICA_COUNT", "LOCATION_LABELS"}))
+	c.Assert(dumpTiDBReplicas(context.Background(), conf, db), IsNil)
+	c.Assert(mock.ExpectationsWereMet(), IsNil)
+	c.Assert(storage.files, HasLen, 0)
+}
+
+func (s *testTiDBReplicasSuite) TestValidate(c *C) {
+	conf := DefaultConfig()
+	conf.TiDBReplicas = true
+	c.Assert(conf.Validate(), IsNil)
+	conf.NoSchemas = true
+	c.Assert(conf.Validate(), ErrorMatches, ".*tidb-replicas is only supported with filetype sql.*")
+
+	conf.NoSchemas = false
+	conf.ServerInfo = ServerInfo{ServerType: ServerTypeMySQL, ServerVersion: semver.New("8.0.18")}
+	c.Assert(conf.validateServer(), ErrorMatches, "dumping the replicas is only supported by TiDB 4.0 and later.*")
+}
@@ -0,0 +1,108 @@
+package export
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+	"go.uber.org/zap"
+
+	"github.com/pingcap/dumpling/v4/log"
+)
+
+const (
+	tracerName                 = "github.com/pingcap/dumpling"
+	defaultTracingFlushTimeout = 5 * time.Second
+)
+
+// newTracerProvider creates the provider exporting the spans to the OTLP HTTP
+// endpoint, like http://localhost:4318.
+func newTracerProvider(ctx context.Context, endpoint string) (*sdktrace.TracerProvider, error) {
+	u, err := parseTracingEndpoint(endpoint)
+	if err != nil {
+		return nil, err
+	}
+	opts := []otlptracehttp.Option{otlptracehttp.WithEndpoint(u.Host)}
+	if u.Scheme == "http" {
+		opts = append(opts, otlptracehttp.WithInsecure())
+	}
+	if u.Path != "" && u.Path != "/" {
+		opts = append(opts, otlptracehttp.WithURLPath(u.Path))
+	}
+	exporter, err := otlptracehttp.New(ctx, opts...)
+	if err != nil {
+		return nil, withStack(err)
+	}
+	return sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(resource.NewSchemaless(attribute.String("service.name", "dumpling"))),
+	), nil
+}
+
+func parseTracingEndpoint(endpoint string) (*url.URL, error) {
+	u, err := url.Parse(endpoint)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return nil, fmt.Errorf("invalid tracing endpoint %s, it should be like http://localhost:4318", endpoint)
+	}
+	return u, nil
+}
+
+// startDumpSpan starts the root span of the dump. The spans are exported to
+// TracingEndpoint if set, otherwise they're given to TracerProvider, or the
+// global provider of otel, which drops them unless it's set by the embedder.
+// The returned function ends the span and flushes the exporter.
+func startDumpSpan(ctx context.Context, conf *Config) (context.Context, func(error)) {
+	provider := conf.TracerProvider
+	var sdkProvider *sdktrace.TracerProvider
+	if conf.TracingEndpoint != "" {
+		var err error
+		if sdkProvider, err = newTracerProvider(ctx, conf.TracingEndpoint); err != nil {
+			log.Warn("create tracer provider failed, the dump isn't traced", zap.Error(err))
+		} else {
+			provider = sdkProvider
+		}
+	}
+	if provider == nil {
+		provider = otel.GetTracerProvider()
+	}
+	ctx, span := provider.Tracer(tracerName).Start(ctx, "dump", trace.WithAttributes(
+		attribute.String("output_dir", conf.OutputDirPath),
+		attribute.String("file_type", conf.FileType),
+		attribute.String("consistency", conf.Consistency),
+	))
+	return ctx, func(err error) {
+		endSpan(span, err)
+		if sdkProvider == nil {
+			return
+		}
+		flushCtx, cancel := context.WithTimeout(context.Background(), defaultTracingFlushTimeout)
+		defer cancel()
+		if err := sdkProvider.Shutdown(flushCtx); err != nil {
+			log.Warn("flush the spans failed", zap.String("endpoint", conf.TracingEndpoint), zap.Error(err))
+		}
+	}
+}
+
+// startSpan starts a child span of the span in ctx, it's a no-op if the dump
+// isn't traced.
+func startSpan(ctx context.Context, name string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	tracer := trace.SpanFromContext(ctx).TracerProvider().Tracer(tracerName)
+	return tracer.Start(ctx, name, trace.WithAttributes(attrs...))
+}
+
+// endSpan ends span, recording err if it's not nil.
+func endSpan(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, RootCause(err).Error())
+	}
+	span.End()
+}
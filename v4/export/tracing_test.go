@@ -0,0 +1,85 @@
+package export
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+
+	. "github.com/pingcap/check"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+var _ = Suite(&testTracingSuite{})
+
+type testTracingSuite struct{}
+
+func (s *testTracingSuite) TestSpans(c *C) {
+	recorder := tracetest.NewSpanRecorder()
+	conf := DefaultConfig()
+	conf.TracerProvider = sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	conf.Progress = NewProgress()
+
+	ctx, endDumpSpan := startDumpSpan(context.Background(), conf)
+	ir := newMockTableIR("app", "t", [][]driver.Value{{1}}, nil, []string{"INT"})
+	c.Assert(writeTableData(ctx, conf, newMockWriter(), ir), IsNil)
+	fileWriter, tearDown := buildInterceptFileWriter(ctx, newMemStorage(), "app.t.sql")
+	_, err := fileWriter.Write([]byte("INSERT INTO `t` VALUES (1);\n"))
+	c.Assert(err, IsNil)
+	c.Assert(tearDown(nil), IsNil)
+	endDumpSpan(errors.New("disk full"))
+
+	spans := recorder.Ended()
+	c.Assert(spans, HasLen, 3)
+	chunk, file, dump := spans[0], spans[1], spans[2]
+	c.Assert(chunk.Name(), Equals, "chunk")
+	c.Assert(chunk.Attributes(), DeepEquals, []attribute.KeyValue{
+		attribute.String("database", "app"),
+		attribute.String("table", "t"),
+		attribute.Int("chunk", 0),
+	})
+	c.Assert(file.Name(), Equals, "file")
+	c.Assert(dump.Name(), Equals, "dump")
+	c.Assert(chunk.Parent().SpanID(), Equals, dump.SpanContext().SpanID())
+	c.Assert(file.Parent().SpanID(), Equals, dump.SpanContext().SpanID())
+	c.Assert(chunk.Status().Code, Equals, codes.Unset)
+	c.Assert(dump.Status(), DeepEquals, sdktrace.Status{Code: codes.Error, Description: "disk full"})
+	c.Assert(dump.Events(), HasLen, 1)
+}
+
+func (s *testTracingSuite) TestExport(c *C) {
+	paths := make(chan string, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		paths <- r.URL.Path
+	}))
+	defer server.Close()
+
+	conf := DefaultConfig()
+	conf.TracingEndpoint = server.URL
+	ctx, endDumpSpan := startDumpSpan(context.Background(), conf)
+	_, span := startSpan(ctx, "consistency")
+	endSpan(span, nil)
+	// the spans are flushed at the end of dump
+	endDumpSpan(nil)
+	c.Assert(<-paths, Equals, "/v1/traces")
+}
+
+func (s *testTracingSuite) TestUntraced(c *C) {
+	// the spans are no-ops without a traced parent
+	ctx, span := startSpan(context.Background(), "table")
+	c.Assert(span.IsRecording(), IsFalse)
+	c.Assert(ctx, NotNil)
+	endSpan(span, errors.New("disk full"))
+}
+
+func (s *testTracingSuite) TestValidate(c *C) {
+	conf := DefaultConfig()
+	conf.TracingEndpoint = "localhost:4318"
+	c.Assert(conf.Validate(), ErrorMatches, "invalid tracing endpoint localhost:4318, .*")
+	conf.TracingEndpoint = "http://localhost:4318"
+	c.Assert(conf.Validate(), IsNil)
+}
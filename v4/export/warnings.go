@@ -0,0 +1,133 @@
+package export
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/pingcap/errors"
+	"go.uber.org/zap"
+
+	"github.com/pingcap/dumpling/v4/log"
+)
+
+const warningsPath = "warnings"
+
+// sqlWarning is a row of SHOW WARNINGS.
+type sqlWarning struct {
+	level   string
+	code    uint16
+	message string
+}
+
+func (w sqlWarning) String() string {
+	return fmt.Sprintf("%s %d %s", w.level, w.code, w.message)
+}
+
+// queryTableData runs the SELECT of table data in ctx, which is canceled after
+// the rows are read. If conf.CaptureWarnings is set, it runs on a dedicated
+// connection which is returned too, so that SHOW WARNINGS can be queried in the
+// same session after the rows are read.
+func queryTableData(ctx context.Context, conf *Config, db *sql.DB, query string) (*sql.Rows, *sql.Conn, error) {
+	query = conf.tableDataQuery(query)
+	if !conf.CaptureWarnings {
+		rows, err := db.QueryContext(ctx, query)
+		return rows, nil, err
+	}
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+	rows, err := conn.QueryContext(ctx, query)
+	if err != nil {
+		conn.Close()
+		return nil, nil, err
+	}
+	return rows, conn, nil
+}
+
+func showWarnings(ctx context.Context, conn *sql.Conn) ([]sqlWarning, error) {
+	rows, err := conn.QueryContext(ctx, "SHOW WARNINGS")
+	if err != nil {
+		return nil, withStack(errors.WithMessage(err, "SHOW WARNINGS"))
+	}
+	defer rows.Close()
+	var warnings []sqlWarning
+	for rows.Next() {
+		var w sqlWarning
+		if err := rows.Scan(&w.level, &w.code, &w.message); err != nil {
+			return nil, withStack(err)
+		}
+		warnings = append(warnings, w)
+	}
+	return warnings, withStack(rows.Err())
+}
+
+// warningSource is implemented by the TableDataIRs queried by queryTableData.
+type warningSource interface {
+	// takeWarnings closes the rows and returns the warnings of the query,
+	// the connection of the query is released after it's called.
+	takeWarnings(ctx context.Context) ([]sqlWarning, error)
+}
+
+// warningRecorder collects the warnings of the dumped chunks into a report,
+// it does nothing if it's nil.
+type warningRecorder struct {
+	strict bool
+
+	mu     sync.Mutex
+	report strings.Builder
+}
+
+func newWarningRecorder(strict bool) *warningRecorder {
+	return &warningRecorder{strict: strict}
+}
+
+// record takes the warnings of ir after it's written. In strict mode it
+// returns an error if there are any warnings.
+func (r *warningRecorder) record(ctx context.Context, ir TableDataIR) error {
+	source, ok := ir.(warningSource)
+	if !ok {
+		return nil
+	}
+	warnings, err := source.takeWarnings(ctx)
+	if err != nil || r == nil || len(warnings) == 0 {
+		return err
+	}
+
+	r.mu.Lock()
+	for _, w := range warnings {
+		fmt.Fprintf(&r.report, "`%s`.`%s` chunk %d: %s\n", ir.DatabaseName(), ir.TableName(), ir.ChunkIndex(), w)
+	}
+	r.mu.Unlock()
+	log.Warn("got warnings in dumping chunk",
+		zap.String("database", ir.DatabaseName()),
+		zap.String("table", ir.TableName()),
+		zap.Int("chunk", ir.ChunkIndex()),
+		zap.Int("count", len(warnings)),
+		zap.Stringer("first", warnings[0]))
+	if r.strict {
+		return errors.Errorf("got %d warnings in dumping `%s`.`%s` chunk %d, the first is: %s",
+			len(warnings), ir.DatabaseName(), ir.TableName(), ir.ChunkIndex(), warnings[0])
+	}
+	return nil
+}
+
+// writeReport writes the recorded warnings into warningsPath of storage,
+// the file is empty if there are no warnings.
+func (r *warningRecorder) writeReport(storage ExternalStorage) error {
+	if r == nil {
+		return nil
+	}
+	// write the report even if the dump is canceled
+	fileWriter, err := storage.Create(context.Background(), warningsPath)
+	if err != nil {
+		return err
+	}
+	r.mu.Lock()
+	report := r.report.String()
+	r.mu.Unlock()
+	return closeFile(fileWriter, write(fileWriter, report))
+}
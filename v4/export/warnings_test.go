@@ -0,0 +1,73 @@
+package export
+
+import (
+	"context"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	. "github.com/pingcap/check"
+)
+
+var _ = Suite(&testWarningsSuite{})
+
+type testWarningsSuite struct{}
+
+func (s *testWarningsSuite) queryChunk(c *C, warnings *sqlmock.Rows) TableDataIR {
+	db, mock, err := sqlmock.New()
+	c.Assert(err, IsNil)
+	mock.ExpectQuery("SELECT a FROM `test`.`t`").WillReturnRows(sqlmock.NewRows([]string{"a"}).AddRow("1"))
+	mock.ExpectQuery("SHOW WARNINGS").WillReturnRows(warnings)
+
+	conf := DefaultConfig()
+	conf.CaptureWarnings = true
+	rows, conn, err := queryTableData(context.Background(), conf, db, "SELECT a FROM `test`.`t`")
+	c.Assert(err, IsNil)
+	c.Assert(conn, NotNil)
+	return &tableData{database: "test", table: "t", chunkIndex: 1, rows: rows, conn: conn}
+}
+
+func (s *testWarningsSuite) TestRecordWarnings(c *C) {
+	ctx := context.Background()
+	recorder := newWarningRecorder(false)
+	ir := s.queryChunk(c, sqlmock.NewRows([]string{"Level", "Code", "Message"}).
+		AddRow("Warning", 1292, "Truncated incorrect DOUBLE value: 'a'"))
+	c.Assert(recorder.record(ctx, ir), IsNil)
+	ir = s.queryChunk(c, sqlmock.NewRows([]string{"Level", "Code", "Message"}))
+	c.Assert(recorder.record(ctx, ir), IsNil)
+
+	storage := newMemStorage()
+	c.Assert(recorder.writeReport(storage), IsNil)
+	c.Assert(storage.files[warningsPath], Equals,
+		"`test`.`t` chunk 1: Warning 1292 Truncated incorrect DOUBLE value: 'a'\n")
+
+	// the connection is released even if warnings are not recorded
+	var nilRecorder *warningRecorder
+	ir = s.queryChunk(c, sqlmock.NewRows([]string{"Level", "Code", "Message"}).AddRow("Warning", 1292, "x"))
+	c.Assert(nilRecorder.record(ctx, ir), IsNil)
+	c.Assert(ir.(*tableData).conn.PingContext(ctx), NotNil)
+	c.Assert(nilRecorder.writeReport(storage), IsNil)
+}
+
+func (s *testWarningsSuite) TestStrictWarnings(c *C) {
+	recorder := newWarningRecorder(true)
+	ir := s.queryChunk(c, sqlmock.NewRows([]string{"Level", "Code", "Message"}).
+		AddRow("Warning", 1366, "Incorrect string value").
+		AddRow("Warning", 1292, "Truncated"))
+	err := recorder.record(context.Background(), ir)
+	c.Assert(err, ErrorMatches, "got 2 warnings in dumping `test`.`t` chunk 1, the first is: Warning 1366 Incorrect string value")
+
+	ir = s.queryChunk(c, sqlmock.NewRows([]string{"Level", "Code", "Message"}))
+	c.Assert(recorder.record(context.Background(), ir), IsNil)
+}
+
+func (s *testWarningsSuite) TestQueryTableDataWithoutCapture(c *C) {
+	db, mock, err := sqlmock.New()
+	c.Assert(err, IsNil)
+	mock.ExpectQuery("SELECT a FROM `test`.`t`").WillReturnRows(sqlmock.NewRows([]string{"a"}).AddRow("1"))
+
+	rows, conn, err := queryTableData(context.Background(), DefaultConfig(), db, "SELECT a FROM `test`.`t`")
+	c.Assert(err, IsNil)
+	c.Assert(conn, IsNil)
+	c.Assert(rows.Close(), IsNil)
+	c.Assert(newWarningRecorder(true).record(context.Background(), &tableData{rows: rows}), IsNil)
+	c.Assert(mock.ExpectationsWereMet(), IsNil)
+}
@@ -0,0 +1,47 @@
+package export
+
+import (
+	"sync"
+)
+
+// writeFailure aborts the dump at the first error of writing a file, such as
+// a full disk or a broken pipe, so that all the workers stop reading their
+// chunks at the next row instead of after filling their current buffers.
+// All the methods are safe to be called concurrently, and on a nil writeFailure.
+type writeFailure struct {
+	mu    sync.Mutex
+	err   error
+	abort func()
+}
+
+func newWriteFailure(abort func()) *writeFailure {
+	return &writeFailure{abort: abort}
+}
+
+// fail records err and aborts the dump if it's the first write error.
+func (f *writeFailure) fail(err error) {
+	if f == nil || err == nil {
+		return
+	}
+	f.mu.Lock()
+	first := f.err == nil
+	if first {
+		f.err = err
+	}
+	f.mu.Unlock()
+	if first {
+		f.abort()
+	}
+}
+
+// Err returns the first write error, or nil if nothing failed to be written.
+// The workers aborted by it return the errors of the canceled context, which
+// should be replaced by it.
+func (f *writeFailure) Err() error {
+	if f == nil {
+		return nil
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.err
+}
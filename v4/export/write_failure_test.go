@@ -0,0 +1,61 @@
+package export
+
+import (
+	"bytes"
+	"context"
+	"database/sql/driver"
+	"errors"
+
+	. "github.com/pingcap/check"
+)
+
+var _ = Suite(&testWriteFailureSuite{})
+
+type testWriteFailureSuite struct{}
+
+func (s *testWriteFailureSuite) TestFail(c *C) {
+	var nilFailure *writeFailure
+	nilFailure.fail(errors.New("ignored"))
+	c.Assert(nilFailure.Err(), IsNil)
+
+	aborts := 0
+	f := newWriteFailure(func() { aborts++ })
+	f.fail(nil)
+	c.Assert(f.Err(), IsNil)
+	c.Assert(aborts, Equals, 0)
+
+	// only the first error is kept, and the dump is aborted once
+	first := errors.New("disk full")
+	f.fail(first)
+	f.fail(errors.New("broken pipe"))
+	c.Assert(f.Err(), Equals, first)
+	c.Assert(aborts, Equals, 1)
+}
+
+func (s *testWriteFailureSuite) TestAbortOtherFiles(c *C) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	buffers := NewBufferPool(UnspecifiedSize, UnspecifiedSize)
+	buffers.failure = newWriteFailure(cancel)
+
+	data := [][]driver.Value{{"1", "male"}, {"2", "female"}}
+	tableIR := newMockTableIR("test", "employee", data, nil, []string{"INT", "SET"})
+	writeErr := errors.New("mock write error")
+	err := WriteInsert(ctx, tableIR, &failingWriter{err: writeErr}, UnspecifiedSize, buffers)
+	c.Assert(errors.Is(err, writeErr), IsTrue)
+	c.Assert(errors.Is(buffers.failure.Err(), writeErr), IsTrue)
+	c.Assert(ctx.Err(), Equals, context.Canceled)
+
+	// the other files stop at the first row, long before their buffers are full
+	wp, pipeCtx, stop := startWriterPipe(ctx, &bytes.Buffer{}, buffers)
+	defer stop()
+	wp.Buffer().WriteString("(1,'male')")
+	_, err = wp.swap(pipeCtx)
+	c.Assert(err, Equals, context.Canceled)
+
+	w := &bytes.Buffer{}
+	tableIR = newMockTableIR("test", "employee", data, nil, []string{"INT", "SET"})
+	err = WriteInsert(ctx, tableIR, w, UnspecifiedSize, buffers)
+	c.Assert(err, Equals, context.Canceled)
+	c.Assert(w.Len(), Equals, 0)
+}
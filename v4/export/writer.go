@@ -1,9 +1,9 @@
 package export
 
 import (
+	"bytes"
 	"context"
 	"fmt"
-	"os"
 	"path"
 
 	"github.com/pingcap/errors"
@@ -20,35 +20,47 @@ type Writer interface {
 }
 
 type SimpleWriter struct {
-	cfg *Config
+	cfg          *Config
+	bytesLimiter *throughputLimiter
+	rowsLimiter  *throughputLimiter
+	buffers      *BufferPool
+	storage      ExternalStorage
 }
 
 func NewSimpleWriter(config *Config) (Writer, error) {
 	if config.Sql != "" {
 		log.Error("unsupported dump data in sql format", zap.String("sql", config.Sql))
-		return nil, errors.New("unsupported dump data in sql format when specific sql")
+		return nil, withKind(ErrorKindConfig, errors.New("unsupported dump data in sql format when specific sql"))
 	}
-	sw := &SimpleWriter{cfg: config}
-	return sw, os.MkdirAll(config.OutputDirPath, 0755)
+	storage, err := newStorage(config)
+	if err != nil {
+		return nil, err
+	}
+	sw := &SimpleWriter{
+		cfg:          config,
+		bytesLimiter: newThroughputLimiter(config.ThrottleBytesPerSec),
+		rowsLimiter:  newThroughputLimiter(config.ThrottleRowsPerSec),
+		buffers:      newConfigBufferPool(config),
+		storage:      storage,
+	}
+	return sw, nil
 }
 
 func (f *SimpleWriter) WriteDatabaseMeta(ctx context.Context, db, createSQL string) error {
 	fileName := fmt.Sprintf("%s-schema-create.sql", db)
-	filePath := path.Join(f.cfg.OutputDirPath, fileName)
-	return writeMetaToFile(db, createSQL, filePath)
+	return writeMetaToFile(ctx, f.cfg, f.storage, db, createSQL, fileName)
 }
 
 func (f *SimpleWriter) WriteTableMeta(ctx context.Context, db, table, createSQL string) error {
-	fileName := fmt.Sprintf("%s.%s-schema.sql", db, table)
-	filePath := path.Join(f.cfg.OutputDirPath, fileName)
-	return writeMetaToFile(db, createSQL, filePath)
+	return writeTableMetaToFile(ctx, f.cfg, f.storage, db, table, createSQL)
 }
 
 func (f *SimpleWriter) WriteTableData(ctx context.Context, ir TableDataIR) error {
 	log.Debug("start dumping table...", zap.String("table", ir.TableName()))
+	ctx = withFileTable(ctx, ir.DatabaseName(), ir.TableName())
 
-	chunkIndex := ir.ChunkIndex()
-	fileName := fmt.Sprintf("%s.%s.%d.sql", ir.DatabaseName(), ir.TableName(), ir.ChunkIndex())
+	namer := newOutputFileNamer(f.cfg, ir)
+	fileName := fmt.Sprintf("%s.sql", namer.NextName())
 	// just let `database.table.sql` be `database.table.0.sql`
 	/*if fileName == "" {
 		// set initial file name
@@ -57,78 +69,136 @@ func (f *SimpleWriter) WriteTableData(ctx context.Context, ir TableDataIR) error
 			fileName = fmt.Sprintf("%s.%s.%d.sql", ir.DatabaseName(), ir.TableName(), 0)
 		}
 	}*/
-	chunksIter := buildChunksIter(ir, f.cfg.FileSize, f.cfg.StatementSize)
+	chunksIter := buildChunksIter(withRowsThrottle(ctx, ir, f.rowsLimiter), f.cfg.FileSize, f.cfg.StatementSize)
 	defer chunksIter.Rows().Close()
 
+	first := true
 	for {
-		filePath := path.Join(f.cfg.OutputDirPath, fileName)
-		fileWriter, tearDown := buildInterceptFileWriter(filePath)
-		err := WriteInsert(chunksIter, fileWriter)
-		tearDown()
-		if err != nil {
+		filePath := f.cfg.outputPath(ctx, fileName)
+		fileWriter, tearDown := buildInterceptFileWriter(ctx, f.storage, fileName)
+		err := WriteInsert(ctx, chunksIter, newThrottledWriter(ctx, fileWriter, f.bytesLimiter), f.cfg.TransactionRows, f.buffers)
+		written := fileWriter.SomethingIsWritten
+		if err == nil && !written && first {
+			written, err = writeEmptyTable(f.cfg, ir, fileWriter, func(bf *bytes.Buffer) {
+				writeSQLHeader(bf, ir)
+				writeSQLFooter(bf, ir)
+			})
+		}
+		if err = tearDown(err); err != nil {
 			return err
 		}
+		first = false
 
-		if w, ok := fileWriter.(*InterceptFileWriter); ok && !w.SomethingIsWritten {
+		if !written {
 			break
 		}
+		f.cfg.hooks().OnFileClosed(filePath)
+		tableProgressOf(ir).addFile()
 
 		if f.cfg.FileSize == UnspecifiedSize {
 			break
 		}
-		chunkIndex += 1
-		fileName = fmt.Sprintf("%s.%s.%d.sql", ir.DatabaseName(), ir.TableName(), chunkIndex)
+		fileName = fmt.Sprintf("%s.sql", namer.NextName())
 	}
 	log.Debug("dumping table successfully",
 		zap.String("table", ir.TableName()))
 	return nil
 }
 
-func writeMetaToFile(target, metaSQL, path string) error {
-	fileWriter, tearDown, err := buildFileWriter(path)
+func writeMetaToFile(ctx context.Context, conf *Config, storage ExternalStorage, target, metaSQL, fileName string) error {
+	fileWriter, err := storage.Create(ctx, fileName)
 	if err != nil {
 		return err
 	}
-	defer tearDown()
 
-	return WriteMeta(&metaData{
+	err = WriteMeta(ctx, &metaData{
 		target:  target,
 		metaSQL: metaSQL,
 	}, fileWriter)
+	if err = closeFile(fileWriter, err); err != nil {
+		return err
+	}
+	conf.hooks().OnFileClosed(conf.outputPath(ctx, fileName))
+	return nil
+}
+
+// writeTableMetaToFile writes the schema file of db.table, unless it's
+// deduplicated by DedupSchemas.
+func writeTableMetaToFile(ctx context.Context, conf *Config, storage ExternalStorage, db, table, createSQL string) error {
+	fileName := fmt.Sprintf("%s.%s-schema.sql", db, table)
+	if conf.schemaDedup.dedup(conf, db, table, fileName, createSQL) {
+		return nil
+	}
+	return writeMetaToFile(withFileTable(ctx, db, table), conf, storage, db, createSQL, fileName)
+}
+
+// newStorage returns the ExternalStorage of conf, or a LocalStorage of the output directory if it's not set.
+func newStorage(conf *Config) (ExternalStorage, error) {
+	if conf.ExternalStorage != nil {
+		return conf.ExternalStorage, nil
+	}
+	return NewLocalStorage(conf.OutputDirPath, conf.SyncFiles)
 }
 
 type CsvWriter struct {
-	cfg *Config
+	cfg          *Config
+	bytesLimiter *throughputLimiter
+	rowsLimiter  *throughputLimiter
+	buffers      *BufferPool
+	storage      ExternalStorage
+	// loadScripts is nil unless LoadDataScripts
+	loadScripts *loadDataScripts
 }
 
 func NewCsvWriter(config *Config) (Writer, error) {
-	sw := &CsvWriter{cfg: config}
-	return sw, os.MkdirAll(config.OutputDirPath, 0755)
+	storage, err := newStorage(config)
+	if err != nil {
+		return nil, err
+	}
+	sw := &CsvWriter{
+		cfg:          config,
+		bytesLimiter: newThroughputLimiter(config.ThrottleBytesPerSec),
+		rowsLimiter:  newThroughputLimiter(config.ThrottleRowsPerSec),
+		buffers:      newConfigBufferPool(config),
+		storage:      storage,
+	}
+	if config.LoadDataScripts {
+		sw.loadScripts = newLoadDataScripts()
+	}
+	return sw, nil
+}
+
+// Close writes the LOAD DATA scripts of the written CSV files.
+func (f *CsvWriter) Close() error {
+	return f.loadScripts.write(context.Background(), f.cfg, f.storage)
 }
 
 func (f *CsvWriter) WriteDatabaseMeta(ctx context.Context, db, createSQL string) error {
 	fileName := fmt.Sprintf("%s-schema-create.sql", db)
-	filePath := path.Join(f.cfg.OutputDirPath, fileName)
-	return writeMetaToFile(db, createSQL, filePath)
+	return writeMetaToFile(ctx, f.cfg, f.storage, db, createSQL, fileName)
 }
 
 func (f *CsvWriter) WriteTableMeta(ctx context.Context, db, table, createSQL string) error {
-	fileName := fmt.Sprintf("%s.%s-schema.sql", db, table)
-	filePath := path.Join(f.cfg.OutputDirPath, fileName)
-	return writeMetaToFile(db, createSQL, filePath)
+	return writeTableMetaToFile(ctx, f.cfg, f.storage, db, table, createSQL)
 }
 
 type outputFileNamer struct {
 	chunkIndex int
 	dbName     string
 	tableName  string
+	// delta is the number of the incremental run, the files are named
+	// <db>.<table>.delta<n>.<chunk> unless it's 0
+	delta int
+	// dir is the directory of the files in the storage, it's the root if empty
+	dir string
 }
 
-func newOutputFileNamer(ir TableDataIR) *outputFileNamer {
+func newOutputFileNamer(conf *Config, ir TableDataIR) *outputFileNamer {
 	return &outputFileNamer{
 		chunkIndex: ir.ChunkIndex(),
 		dbName:     ir.DatabaseName(),
 		tableName:  ir.TableName(),
+		delta:      conf.incremental.delta(),
 	}
 }
 
@@ -137,29 +207,48 @@ func (namer *outputFileNamer) NextName() string {
 	if namer.dbName == "" || namer.tableName == "" {
 		return fmt.Sprintf("result.%d", namer.chunkIndex)
 	}
-	return fmt.Sprintf("%s.%s.%d", namer.dbName, namer.tableName, namer.chunkIndex)
+	if namer.delta > 0 {
+		return path.Join(namer.dir, fmt.Sprintf("%s.%s.delta%d.%d", namer.dbName, namer.tableName, namer.delta, namer.chunkIndex))
+	}
+	return path.Join(namer.dir, fmt.Sprintf("%s.%s.%d", namer.dbName, namer.tableName, namer.chunkIndex))
 }
 
 func (f *CsvWriter) WriteTableData(ctx context.Context, ir TableDataIR) error {
 	log.Debug("start dumping table in csv format...", zap.String("table", ir.TableName()))
+	ctx = withFileTable(ctx, ir.DatabaseName(), ir.TableName())
 
-	namer := newOutputFileNamer(ir)
+	namer := newOutputFileNamer(f.cfg, ir)
+	if f.cfg.HiveLocation != "" {
+		namer.dir = hiveTableDir(ir.DatabaseName(), ir.TableName())
+	}
 	fileName := fmt.Sprintf("%s.csv", namer.NextName())
-	chunksIter := buildChunksIter(ir, f.cfg.FileSize, f.cfg.StatementSize)
+	chunksIter := buildChunksIter(withRowsThrottle(ctx, ir, f.rowsLimiter), f.cfg.FileSize, f.cfg.StatementSize)
 	defer chunksIter.Rows().Close()
 
+	first := true
 	for {
-		filePath := path.Join(f.cfg.OutputDirPath, fileName)
-		fileWriter, tearDown := buildInterceptFileWriter(filePath)
-		err := WriteInsertInCsv(chunksIter, fileWriter, f.cfg.NoHeader, f.cfg.CsvNullValue)
-		tearDown()
-		if err != nil {
+		filePath := f.cfg.outputPath(ctx, fileName)
+		fileWriter, tearDown := buildInterceptFileWriter(ctx, f.storage, fileName)
+		err := WriteInsertInCsv(ctx, chunksIter, newThrottledWriter(ctx, fileWriter, f.bytesLimiter), f.cfg.NoHeader, f.cfg.CsvNullValue, f.buffers)
+		written := fileWriter.SomethingIsWritten
+		if err == nil && !written && first {
+			written, err = writeEmptyTable(f.cfg, ir, fileWriter, func(bf *bytes.Buffer) {
+				if !f.cfg.NoHeader {
+					writeCsvHeader(bf, ir, ir.EscapeBackSlash())
+				}
+			})
+		}
+		if err = tearDown(err); err != nil {
 			return err
 		}
+		first = false
 
-		if w, ok := fileWriter.(*InterceptFileWriter); ok && !w.SomethingIsWritten {
+		if !written {
 			break
 		}
+		f.cfg.hooks().OnFileClosed(filePath)
+		tableProgressOf(ir).addFile()
+		f.loadScripts.record(ir, fileName)
 
 		if f.cfg.FileSize == UnspecifiedSize {
 			break
@@ -170,3 +259,61 @@ func (f *CsvWriter) WriteTableData(ctx context.Context, ir TableDataIR) error {
 		zap.String("table", ir.TableName()))
 	return nil
 }
+
+// TsvWriter writes the data files in the TabSeparated format of ClickHouse,
+// which are loadable by INSERT ... FORMAT TSV. It writes the meta files in the
+// same way as CsvWriter.
+type TsvWriter struct {
+	*CsvWriter
+}
+
+func NewTsvWriter(config *Config) (Writer, error) {
+	w, err := NewCsvWriter(config)
+	if err != nil {
+		return nil, err
+	}
+	return TsvWriter{w.(*CsvWriter)}, nil
+}
+
+func (f TsvWriter) WriteTableData(ctx context.Context, ir TableDataIR) error {
+	log.Debug("start dumping table in tsv format...", zap.String("table", ir.TableName()))
+	ctx = withFileTable(ctx, ir.DatabaseName(), ir.TableName())
+
+	namer := newOutputFileNamer(f.cfg, ir)
+	fileName := fmt.Sprintf("%s.tsv", namer.NextName())
+	chunksIter := buildChunksIter(withRowsThrottle(ctx, ir, f.rowsLimiter), f.cfg.FileSize, f.cfg.StatementSize)
+	defer chunksIter.Rows().Close()
+
+	first := true
+	for {
+		filePath := f.cfg.outputPath(ctx, fileName)
+		fileWriter, tearDown := buildInterceptFileWriter(ctx, f.storage, fileName)
+		err := WriteInsertInTsv(ctx, chunksIter, newThrottledWriter(ctx, fileWriter, f.bytesLimiter), f.cfg.NoHeader, f.buffers)
+		written := fileWriter.SomethingIsWritten
+		if err == nil && !written && first {
+			written, err = writeEmptyTable(f.cfg, ir, fileWriter, func(bf *bytes.Buffer) {
+				if !f.cfg.NoHeader {
+					writeTsvHeader(bf, ir)
+				}
+			})
+		}
+		if err = tearDown(err); err != nil {
+			return err
+		}
+		first = false
+
+		if !written {
+			break
+		}
+		f.cfg.hooks().OnFileClosed(filePath)
+		tableProgressOf(ir).addFile()
+
+		if f.cfg.FileSize == UnspecifiedSize {
+			break
+		}
+		fileName = fmt.Sprintf("%s.tsv", namer.NextName())
+	}
+	log.Debug("dumping table in tsv format successfully",
+		zap.String("table", ir.TableName()))
+	return nil
+}
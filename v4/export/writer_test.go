@@ -207,12 +207,17 @@ func (s *testDumpSuite) TestWriteTableDataWithStatementSize(c *C) {
 	config.OutputDirPath, err = ioutil.TempDir("", "dumpling")
 	fmt.Println(config.OutputDirPath)
 	c.Assert(err, IsNil)
+	// the writer writes to the output directory when it's created
+	writer, err = NewSimpleWriter(config)
+	c.Assert(err, IsNil)
 
+	// the rows larger than a statement are written in statements of their own
 	cases = map[string]string{
 		"test.employee.0.sql": "/*!40101 SET NAMES binary*/;\n" +
 			"/*!40014 SET FOREIGN_KEY_CHECKS=0*/;\n" +
 			"INSERT INTO `employee` VALUES\n" +
-			"(1,'male','bob@mail.com','020-1234',NULL),\n" +
+			"(1,'male','bob@mail.com','020-1234',NULL);\n" +
+			"INSERT INTO `employee` VALUES\n" +
 			"(2,'female','sarah@mail.com','020-1253','healthy');\n" +
 			"INSERT INTO `employee` VALUES\n" +
 			"(3,'male','john@mail.com','020-1256','healthy');\n",
@@ -234,3 +239,30 @@ func (s *testDumpSuite) TestWriteTableDataWithStatementSize(c *C) {
 		c.Assert(string(bytes), Equals, expected)
 	}
 }
+
+func (s *testDumpSuite) TestWriteTableDataInTsv(c *C) {
+	dir, err := ioutil.TempDir("", "dumpling")
+	c.Assert(err, IsNil)
+	defer os.RemoveAll(dir)
+
+	config := DefaultConfig()
+	config.OutputDirPath = dir
+	config.NoHeader = true
+	ctx := context.Background()
+
+	writer, err := NewTsvWriter(config)
+	c.Assert(err, IsNil)
+
+	data := [][]driver.Value{
+		{"1", "male", nil},
+		{"2", "female", "healthy"},
+	}
+	colTypes := []string{"INT", "SET", "TEXT"}
+	tableIR := newMockTableIR("test", "employee", data, nil, colTypes)
+	err = writer.WriteTableData(ctx, tableIR)
+	c.Assert(err, IsNil)
+
+	bytes, err := ioutil.ReadFile(path.Join(dir, "test.employee.0.tsv"))
+	c.Assert(err, IsNil)
+	c.Assert(string(bytes), Equals, "1\tmale\t\\N\n2\tfemale\thealthy\n")
+}
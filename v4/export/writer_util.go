@@ -1,60 +1,156 @@
 package export
 
 import (
-	"bufio"
 	"bytes"
 	"context"
 	"fmt"
 	"io"
-	"os"
 	"strings"
 	"sync"
+	"time"
 
 	"github.com/pingcap/dumpling/v4/log"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 	"go.uber.org/zap"
 )
 
 const lengthLimit = 1048576
 
-var pool = sync.Pool{New: func() interface{} {
-	return &bytes.Buffer{}
-}}
+// writerPipeDepth is the default number of filled buffers that can be in flight
+// in a writerPipe, so that the serialization keeps going while the output is slow.
+const writerPipeDepth = 8
 
+// writerPipe overlaps the serialization and the writing of a file. The producer
+// serializes rows into the current buffer, and swaps in an empty one when it's
+// full, while the filled buffers are written to w in the background by Run.
 type writerPipe struct {
 	input  chan *bytes.Buffer
 	closed chan struct{}
-	errCh  chan error
 
-	w io.Writer
+	errMu sync.Mutex
+	err   error
+
+	w       io.Writer
+	buffers *BufferPool
+	bf      *bytes.Buffer
 }
 
-func newWriterPipe(w io.Writer) *writerPipe {
+func newWriterPipe(w io.Writer, buffers *BufferPool) *writerPipe {
+	if buffers == nil {
+		buffers = defaultBufferPool
+	}
 	return &writerPipe{
-		input:  make(chan *bytes.Buffer, 8),
-		closed: make(chan struct{}),
-		errCh:  make(chan error, 1),
-		w:      w,
+		input:   make(chan *bytes.Buffer, buffers.pipeDepth()),
+		closed:  make(chan struct{}),
+		w:       w,
+		buffers: buffers,
+		bf:      buffers.get(),
+	}
+}
+
+// Buffer returns the buffer to serialize rows into.
+func (b *writerPipe) Buffer() *bytes.Buffer {
+	return b.bf
+}
+
+// swap passes the current buffer to the writing goroutine if it's full, and
+// returns the buffer to serialize the following rows into. It blocks while the
+// buffered bytes exceed the memory budget, or returns the error occurred in writing.
+// It's called after every row, so the dump aborted by a write error of another
+// file stops at the next row.
+func (b *writerPipe) swap(ctx context.Context) (*bytes.Buffer, error) {
+	if err := b.Error(); err != nil {
+		return nil, err
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if b.bf.Len() < b.buffers.Size() {
+		return b.bf, nil
+	}
+	if err := b.send(ctx, b.bf); err != nil {
+		return nil, err
+	}
+	b.bf = b.buffers.get()
+	return b.bf, nil
+}
+
+// Close passes the rest bytes to the writing goroutine, and waits for all the
+// buffers to be written.
+func (b *writerPipe) Close(ctx context.Context) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if b.bf.Len() > 0 {
+		if err := b.send(ctx, b.bf); err != nil {
+			return err
+		}
+	} else {
+		b.buffers.put(b.bf)
+	}
+	b.bf = nil
+	close(b.input)
+	<-b.closed
+	return b.Error()
+}
+
+// send passes s to the pipe, it blocks while the buffered bytes exceed the
+// memory budget or the pipe is full, until ctx is done.
+func (b *writerPipe) send(ctx context.Context, s *bytes.Buffer) error {
+	if err := b.buffers.budget.acquire(ctx, uint64(s.Len())); err != nil {
+		return err
+	}
+	select {
+	case b.input <- s:
+		return nil
+	case <-ctx.Done():
+		// s isn't passed, so it's still the buffer of the producer
+		b.buffers.budget.release(uint64(s.Len()))
+		return ctx.Err()
 	}
 }
 
+// drain releases the buffers left in the pipe after Run returns.
+func (b *writerPipe) drain() {
+	for {
+		select {
+		case s, ok := <-b.input:
+			if !ok {
+				return
+			}
+			b.release(s)
+		default:
+			return
+		}
+	}
+}
+
+func (b *writerPipe) release(s *bytes.Buffer) {
+	b.buffers.budget.release(uint64(s.Len()))
+	b.buffers.put(s)
+}
+
 func (b *writerPipe) Run(ctx context.Context) {
 	defer close(b.closed)
-	var errOccurs bool
 	for {
 		select {
 		case s, ok := <-b.input:
 			if !ok {
 				return
 			}
-			if errOccurs {
+			if b.Error() != nil {
+				b.release(s)
 				continue
 			}
 			err := writeBytes(b.w, s.Bytes())
-			s.Reset()
-			pool.Put(s)
+			b.release(s)
 			if err != nil {
-				errOccurs = true
-				b.errCh <- err
+				b.errMu.Lock()
+				b.err = err
+				b.errMu.Unlock()
+				// the other files stop at once instead of at their next full buffer
+				b.buffers.failure.fail(err)
 			}
 		case <-ctx.Done():
 			return
@@ -62,16 +158,30 @@ func (b *writerPipe) Run(ctx context.Context) {
 	}
 }
 
+// Error returns the first error occurred in writing.
 func (b *writerPipe) Error() error {
-	select {
-	case err := <-b.errCh:
-		return err
-	default:
-		return nil
+	b.errMu.Lock()
+	defer b.errMu.Unlock()
+	return b.err
+}
+
+// startWriterPipe starts a writerPipe writing to w until ctx is done, the returned
+// function stops the pipe and releases the buffers not written.
+func startWriterPipe(ctx context.Context, w io.Writer, buffers *BufferPool) (*writerPipe, context.Context, func()) {
+	wp := newWriterPipe(w, buffers)
+	ctx, cancel := context.WithCancel(ctx)
+	go wp.Run(ctx)
+	return wp, ctx, func() {
+		cancel()
+		<-wp.closed
+		wp.drain()
 	}
 }
 
-func WriteMeta(meta MetaIR, w io.StringWriter) error {
+func WriteMeta(ctx context.Context, meta MetaIR, w io.Writer) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
 	log.Debug("start dumping meta data", zap.String("target", meta.TargetName()))
 
 	specCmtIter := meta.SpecialComments()
@@ -89,42 +199,26 @@ func WriteMeta(meta MetaIR, w io.StringWriter) error {
 	return nil
 }
 
-func WriteInsert(tblIR TableDataIR, w io.Writer) error {
+// WriteInsert writes the rows of tblIR as INSERT statements. If txnRows is not
+// UnspecifiedSize, every txnRows rows are wrapped with BEGIN and COMMIT.
+// The serialization buffers are taken from buffers, or a default pool if it's nil.
+// It stops and returns the error of ctx once ctx is done.
+func WriteInsert(ctx context.Context, tblIR TableDataIR, w io.Writer, txnRows uint64, buffers *BufferPool) error {
 	fileRowIter := tblIR.Rows()
 	if !fileRowIter.HasNext() {
 		return nil
 	}
 
-	bf := pool.Get().(*bytes.Buffer)
-	if bfCap := bf.Cap(); bfCap < lengthLimit {
-		bf.Grow(lengthLimit - bfCap)
-	}
-
-	wp := newWriterPipe(w)
-
-	ctx, cancel := context.WithCancel(context.Background())
-	var wg sync.WaitGroup
-	wg.Add(1)
-	go func() {
-		wp.Run(ctx)
-		wg.Done()
-	}()
-	defer func() {
-		cancel()
-		wg.Wait()
-	}()
-
-	specCmtIter := tblIR.SpecialComments()
-	for specCmtIter.HasNext() {
-		bf.WriteString(specCmtIter.Next())
-		bf.WriteByte('\n')
-	}
+	wp, ctx, stop := startWriterPipe(ctx, w, buffers)
+	defer stop()
+	bf := wp.Buffer()
+	writeSQLHeader(bf, tblIR)
 
 	var (
 		insertStatementPrefix string
-		row                   = MakeRowReceiver(tblIR.ColumnTypes())
+		row                   = makeRowReceiver(tblIR.ColumnTypes(), tblIR.Output(), tblIR.QuoteBigIntegers()).(RowReceiverArr)
 		counter               = 0
-		escapeBackSlash       = tblIR.EscapeBackSlash()
+		writer                = rowWriter{format: sqlRowFormat, escapeBackslash: tblIR.EscapeBackSlash()}
 		err                   error
 	)
 
@@ -132,102 +226,213 @@ func WriteInsert(tblIR TableDataIR, w io.Writer) error {
 	// if has generated column
 	if selectedField != "" {
 		insertStatementPrefix = fmt.Sprintf("INSERT INTO %s %s VALUES\n",
-			wrapBackTicks(tblIR.TableName()), selectedField)
+			tblIR.Output().QuoteIdentifier(tblIR.TableName()), selectedField)
 	} else {
 		insertStatementPrefix = fmt.Sprintf("INSERT INTO %s VALUES\n",
-			wrapBackTicks(tblIR.TableName()))
+			tblIR.Output().QuoteIdentifier(tblIR.TableName()))
 	}
 
+	if txnRows != UnspecifiedSize {
+		bf.WriteString("BEGIN;\n")
+	}
 	for fileRowIter.HasNextSQLRowIter() {
 		bf.WriteString(insertStatementPrefix)
 
 		fileRowIter = fileRowIter.NextSQLRowIter()
+		// continued is whether the statement is continued by the next row
+		continued := false
 		for fileRowIter.HasNext() {
 			if err = fileRowIter.Decode(row); err != nil {
 				log.Error("scanning from sql.Row failed", zap.Error(err))
 				return err
 			}
 
-			row.WriteToBuffer(bf, escapeBackSlash)
+			if continued {
+				if startsStatement(fileRowIter) {
+					// the row larger than a statement is written in one of its own
+					bf.WriteString(";\n")
+					bf.WriteString(insertStatementPrefix)
+				} else {
+					bf.WriteString(",\n")
+				}
+				continued = false
+			}
+			if bf, err = writer.writeRow(ctx, wp, bf, row); err != nil {
+				return err
+			}
 			counter += 1
 
-			if bf.Len() >= lengthLimit {
-				wp.input <- bf
-				bf = pool.Get().(*bytes.Buffer)
-				if bfCap := bf.Cap(); bfCap < lengthLimit {
-					bf.Grow(lengthLimit - bfCap)
-				}
+			if bf, err = wp.swap(ctx); err != nil {
+				return err
 			}
 
 			fileRowIter.Next()
+			txnFull := txnRows != UnspecifiedSize && uint64(counter)%txnRows == 0
 			if fileRowIter.HasNext() {
-				bf.WriteString(",\n")
+				if txnFull {
+					// end current statement to start a new transaction
+					bf.WriteString(";\nCOMMIT;\nBEGIN;\n")
+					bf.WriteString(insertStatementPrefix)
+				} else {
+					continued = true
+				}
 			} else {
 				bf.WriteString(";\n")
-			}
-
-			if err = wp.Error(); err != nil {
-				return err
+				if txnFull && fileRowIter.HasNextSQLRowIter() {
+					bf.WriteString("COMMIT;\nBEGIN;\n")
+				}
 			}
 		}
 	}
+	if txnRows != UnspecifiedSize {
+		bf.WriteString("COMMIT;\n")
+	}
+	writeSQLFooter(bf, tblIR)
 	log.Debug("dumping table",
 		zap.String("table", tblIR.TableName()),
 		zap.Int("record counts", counter))
-	if bf.Len() > 0 {
-		wp.input <- bf
-	}
-	close(wp.input)
-	<-wp.closed
-	if err = fileRowIter.Error(); err != nil {
+	if err = wp.Close(ctx); err != nil {
 		return err
 	}
-	return wp.Error()
+	return fileRowIter.Error()
 }
 
-func WriteInsertInCsv(tblIR TableDataIR, w io.Writer, noHeader bool, csvNullValue string) error {
+func WriteInsertInCsv(ctx context.Context, tblIR TableDataIR, w io.Writer, noHeader bool, csvNullValue string, buffers *BufferPool) error {
 	fileRowIter := tblIR.Rows()
 	if !fileRowIter.HasNext() {
 		return nil
 	}
 
-	bf := pool.Get().(*bytes.Buffer)
-	if bfCap := bf.Cap(); bfCap < lengthLimit {
-		bf.Grow(lengthLimit - bfCap)
-	}
-
-	wp := newWriterPipe(w)
-
-	ctx, cancel := context.WithCancel(context.Background())
-	var wg sync.WaitGroup
-	wg.Add(1)
-	go func() {
-		wp.Run(ctx)
-		wg.Done()
-	}()
-	defer func() {
-		cancel()
-		wg.Wait()
-	}()
+	wp, ctx, stop := startWriterPipe(ctx, w, buffers)
+	defer stop()
+	bf := wp.Buffer()
 
 	var (
-		row             = MakeRowReceiver(tblIR.ColumnTypes())
+		row             = makeRowReceiver(tblIR.ColumnTypes(), tblIR.Output(), tblIR.QuoteBigIntegers()).(RowReceiverArr)
 		counter         = 0
 		escapeBackSlash = tblIR.EscapeBackSlash()
+		writer          = rowWriter{format: csvRowFormat, escapeBackslash: escapeBackSlash, csvNullValue: csvNullValue}
 		err             error
 	)
 
-	if !noHeader && len(tblIR.ColumnNames()) != 0 {
-		for i, col := range tblIR.ColumnNames() {
-			bf.WriteByte(doubleQuotationMark)
-			escape([]byte(col), bf, escapeBackSlash)
-			bf.WriteByte(doubleQuotationMark)
-			if i != len(tblIR.ColumnTypes())-1 {
-				bf.WriteByte(',')
+	if !noHeader {
+		writeCsvHeader(bf, tblIR, escapeBackSlash)
+	}
+
+	for fileRowIter.HasNextSQLRowIter() {
+		fileRowIter = fileRowIter.NextSQLRowIter()
+		for fileRowIter.HasNext() {
+			if err = fileRowIter.Decode(row); err != nil {
+				log.Error("scanning from sql.Row failed", zap.Error(err))
+				return err
+			}
+
+			if bf, err = writer.writeRow(ctx, wp, bf, row); err != nil {
+				return err
+			}
+			counter += 1
+
+			if bf, err = wp.swap(ctx); err != nil {
+				return err
 			}
+
+			fileRowIter.Next()
+			bf.WriteByte('\n')
 		}
+	}
+
+	log.Debug("dumping table",
+		zap.String("table", tblIR.TableName()),
+		zap.Int("record counts", counter))
+	if err = wp.Close(ctx); err != nil {
+		return err
+	}
+	return fileRowIter.Error()
+}
+
+// startsStatement returns whether the row decoded last by iter is larger than
+// the statement size, so that it's written in a statement of its own.
+func startsStatement(iter SQLRowIter) bool {
+	if iter, ok := iter.(interface{ startsStatement() bool }); ok {
+		return iter.startsStatement()
+	}
+	return false
+}
+
+// writeSQLHeader writes the special comments of tblIR, one per line.
+func writeSQLHeader(bf *bytes.Buffer, tblIR TableDataIR) {
+	specCmtIter := tblIR.SpecialComments()
+	for specCmtIter.HasNext() {
+		bf.WriteString(specCmtIter.Next())
 		bf.WriteByte('\n')
 	}
+}
+
+// writeSQLFooter writes the special footers of tblIR, one per line, and the
+// mysqldump line of the time the file is completed if it's written.
+func writeSQLFooter(bf *bytes.Buffer, tblIR TableDataIR) {
+	specFooterIter := tblIR.SpecialFooters()
+	for specFooterIter.HasNext() {
+		bf.WriteString(specFooterIter.Next())
+		bf.WriteByte('\n')
+	}
+	if tblIR.MysqldumpCompleted() {
+		bf.WriteString("\n" + mysqldumpCompletedFooter + time.Now().Format(metadataTimeLayout) + "\n")
+	}
+}
+
+// writeCsvHeader writes the line of the quoted column names of tblIR.
+func writeCsvHeader(bf *bytes.Buffer, tblIR TableDataIR, escapeBackSlash bool) {
+	if len(tblIR.ColumnNames()) == 0 {
+		return
+	}
+	for i, col := range tblIR.ColumnNames() {
+		bf.WriteByte(doubleQuotationMark)
+		csvEscaper(escapeBackSlash).Escape(bf, []byte(col))
+		bf.WriteByte(doubleQuotationMark)
+		if i != len(tblIR.ColumnTypes())-1 {
+			bf.WriteByte(',')
+		}
+	}
+	bf.WriteByte('\n')
+}
+
+// writeTsvHeader writes the line of the column names of tblIR.
+func writeTsvHeader(bf *bytes.Buffer, tblIR TableDataIR) {
+	if len(tblIR.ColumnNames()) == 0 {
+		return
+	}
+	for i, col := range tblIR.ColumnNames() {
+		TSVEscaper.Escape(bf, []byte(col))
+		if i != len(tblIR.ColumnNames())-1 {
+			bf.WriteByte('\t')
+		}
+	}
+	bf.WriteByte('\n')
+}
+
+// WriteInsertInTsv writes the rows in the TabSeparated format of ClickHouse,
+// the column names are written in the first line unless noHeader.
+func WriteInsertInTsv(ctx context.Context, tblIR TableDataIR, w io.Writer, noHeader bool, buffers *BufferPool) error {
+	fileRowIter := tblIR.Rows()
+	if !fileRowIter.HasNext() {
+		return nil
+	}
+
+	wp, ctx, stop := startWriterPipe(ctx, w, buffers)
+	defer stop()
+	bf := wp.Buffer()
+
+	var (
+		row     = makeRowReceiver(tblIR.ColumnTypes(), tblIR.Output(), tblIR.QuoteBigIntegers()).(RowReceiverArr)
+		counter = 0
+		writer  = rowWriter{format: tsvRowFormat}
+		err     error
+	)
+
+	if !noHeader {
+		writeTsvHeader(bf, tblIR)
+	}
 
 	for fileRowIter.HasNextSQLRowIter() {
 		fileRowIter = fileRowIter.NextSQLRowIter()
@@ -237,41 +442,31 @@ func WriteInsertInCsv(tblIR TableDataIR, w io.Writer, noHeader bool, csvNullValu
 				return err
 			}
 
-			row.WriteToBufferInCsv(bf, escapeBackSlash, csvNullValue)
+			if bf, err = writer.writeRow(ctx, wp, bf, row); err != nil {
+				return err
+			}
 			counter += 1
 
-			if bf.Len() >= lengthLimit {
-				wp.input <- bf
-				bf = pool.Get().(*bytes.Buffer)
-				if bfCap := bf.Cap(); bfCap < lengthLimit {
-					bf.Grow(lengthLimit - bfCap)
-				}
+			if bf, err = wp.swap(ctx); err != nil {
+				return err
 			}
 
 			fileRowIter.Next()
 			bf.WriteByte('\n')
-			if err = wp.Error(); err != nil {
-				return err
-			}
 		}
 	}
 
 	log.Debug("dumping table",
 		zap.String("table", tblIR.TableName()),
 		zap.Int("record counts", counter))
-	if bf.Len() > 0 {
-		wp.input <- bf
-	}
-	close(wp.input)
-	<-wp.closed
-	if err = fileRowIter.Error(); err != nil {
+	if err = wp.Close(ctx); err != nil {
 		return err
 	}
-	return wp.Error()
+	return fileRowIter.Error()
 }
 
-func write(writer io.StringWriter, str string) error {
-	_, err := writer.WriteString(str)
+func write(writer io.Writer, str string) error {
+	_, err := io.WriteString(writer, str)
 	if err != nil {
 		// str might be very long, only output the first 200 chars
 		outputLength := len(str)
@@ -282,7 +477,7 @@ func write(writer io.StringWriter, str string) error {
 			zap.String("string", str[:outputLength]),
 			zap.Error(err))
 	}
-	return err
+	return withKind(ErrorKindWrite, err)
 }
 
 func writeBytes(writer io.Writer, p []byte) error {
@@ -297,80 +492,44 @@ func writeBytes(writer io.Writer, p []byte) error {
 			zap.ByteString("string", p[:outputLength]),
 			zap.Error(err))
 	}
-	return err
+	return withKind(ErrorKindWrite, err)
 }
 
-func buildFileWriter(path string) (io.StringWriter, func(), error) {
-	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0755)
-	if err != nil {
-		log.Error("open file failed",
-			zap.String("path", path),
-			zap.Error(err))
-		return nil, nil, err
-	}
-	log.Debug("opened file", zap.String("path", path))
-	buf := bufio.NewWriter(file)
-	tearDownRoutine := func() {
-		_ = buf.Flush()
-		err := file.Close()
-		if err == nil {
-			return
-		}
-		log.Error("close file failed",
-			zap.String("path", path),
-			zap.Error(err))
-	}
-	return buf, tearDownRoutine, nil
-}
-
-func buildInterceptFileWriter(path string) (io.Writer, func()) {
-	var file *os.File
+func buildInterceptFileWriter(ctx context.Context, storage ExternalStorage, name string) (*InterceptFileWriter, func(error) error) {
+	var (
+		file io.WriteCloser
+		span trace.Span
+	)
 	fileWriter := &InterceptFileWriter{}
 	initRoutine := func() error {
-		f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0755)
-		file = f
+		// the span of the file lasts from creating to finalizing, including the upload
+		_, span = startSpan(ctx, "file", attribute.String("file", name))
+		w, err := storage.Create(ctx, name)
 		if err != nil {
-			log.Error("open file failed",
-				zap.String("path", path),
-				zap.Error(err))
+			endSpan(span, err)
+			return err
 		}
-		log.Debug("opened file", zap.String("path", path))
-		fileWriter.Writer = file
-		return err
+		file = w
+		fileWriter.Writer = w
+		return nil
 	}
 	fileWriter.initRoutine = initRoutine
 
-	tearDownRoutine := func() {
+	// tearDownRoutine finalizes the file if err is nil, and returns err or the error of finalizing
+	tearDownRoutine := func(err error) error {
 		if file == nil {
-			return
+			return err
 		}
 		log.Debug("tear down lazy file writer...")
-		err := file.Close()
-		if err == nil {
-			return
-		}
-		log.Error("close file failed", zap.String("path", path))
+		err = closeFile(file, err)
+		endSpan(span, err)
+		return err
 	}
 	return fileWriter, tearDownRoutine
 }
 
-type LazyStringWriter struct {
-	initRoutine func() error
-	sync.Once
-	io.StringWriter
-	err error
-}
-
-func (l *LazyStringWriter) WriteString(str string) (int, error) {
-	l.Do(func() { l.err = l.initRoutine() })
-	if l.err != nil {
-		return 0, fmt.Errorf("open file error: %s", l.err.Error())
-	}
-	return l.StringWriter.WriteString(str)
-}
-
-// InterceptFileWriter is an interceptor of os.File,
-// tracking whether a StringWriter has written something.
+// InterceptFileWriter is an interceptor of the files created by ExternalStorage,
+// it creates the file lazily and tracks whether something has been written.
 type InterceptFileWriter struct {
 	io.Writer
 	sync.Once
@@ -386,7 +545,7 @@ func (w *InterceptFileWriter) Write(p []byte) (int, error) {
 		w.SomethingIsWritten = true
 	}
 	if w.err != nil {
-		return 0, fmt.Errorf("open file error: %s", w.err.Error())
+		return 0, fmt.Errorf("open file error: %w", w.err)
 	}
 	return w.Writer.Write(p)
 }
@@ -2,10 +2,12 @@ package export
 
 import (
 	"bytes"
+	"context"
 	"errors"
 	"fmt"
 	"strings"
 	"testing"
+	"time"
 
 	"database/sql/driver"
 
@@ -36,7 +38,7 @@ func (s *testUtilSuite) TestWriteMeta(c *C) {
 	meta := newMockMetaIR("t1", createTableStmt, specCmts)
 	strCollector := &mockStringCollector{}
 
-	err := WriteMeta(meta, strCollector)
+	err := WriteMeta(context.Background(), meta, strCollector)
 	c.Assert(err, IsNil)
 	expected := "/*!40103 SET TIME_ZONE='+00:00' */;\n" +
 		"CREATE TABLE `t1` (\n" +
@@ -60,7 +62,7 @@ func (s *testUtilSuite) TestWriteInsert(c *C) {
 	tableIR := newMockTableIR("test", "employee", data, specCmts, colTypes)
 	bf := &bytes.Buffer{}
 
-	err := WriteInsert(tableIR, bf)
+	err := WriteInsert(context.Background(), tableIR, bf, UnspecifiedSize, nil)
 	c.Assert(err, IsNil)
 	expected := "/*!40101 SET NAMES binary*/;\n" +
 		"/*!40014 SET FOREIGN_KEY_CHECKS=0*/;\n" +
@@ -72,6 +74,88 @@ func (s *testUtilSuite) TestWriteInsert(c *C) {
 	c.Assert(bf.String(), Equals, expected)
 }
 
+func (s *testUtilSuite) TestWriteInsertWithFooters(c *C) {
+	data := [][]driver.Value{
+		{"1", "male"},
+		{"2", "female"},
+	}
+	colTypes := []string{"INT", "SET"}
+	conf := DefaultConfig()
+	conf.DisableForeignKeyChecks = true
+	conf.DisableUniqueChecks = true
+	conf.NoAutocommit = true
+	conf.DisableKeys = true
+	tableIR := newMockTableIR("test", "employee", data, buildSpecialComments(conf, "test", "employee"), colTypes)
+	tableIR.(*mockTableIR).specFooter = buildSpecialFooters(conf, "employee")
+	bf := &bytes.Buffer{}
+
+	err := WriteInsert(context.Background(), tableIR, bf, UnspecifiedSize, nil)
+	c.Assert(err, IsNil)
+	expected := "/*!40101 SET NAMES binary*/;\n" +
+		"/*!40014 SET @OLD_FOREIGN_KEY_CHECKS=@@FOREIGN_KEY_CHECKS, FOREIGN_KEY_CHECKS=0*/;\n" +
+		"/*!40014 SET @OLD_UNIQUE_CHECKS=@@UNIQUE_CHECKS, UNIQUE_CHECKS=0*/;\n" +
+		"/*!40000 ALTER TABLE `employee` DISABLE KEYS*/;\n" +
+		"SET AUTOCOMMIT=0;\n" +
+		"INSERT INTO `employee` VALUES\n" +
+		"(1,'male'),\n" +
+		"(2,'female');\n" +
+		"COMMIT;\n" +
+		"/*!40000 ALTER TABLE `employee` ENABLE KEYS*/;\n" +
+		"/*!40014 SET UNIQUE_CHECKS=@OLD_UNIQUE_CHECKS*/;\n" +
+		"/*!40014 SET FOREIGN_KEY_CHECKS=@OLD_FOREIGN_KEY_CHECKS*/;\n"
+	c.Assert(bf.String(), Equals, expected)
+}
+
+func (s *testUtilSuite) TestWriteMysqldumpCompletedTime(c *C) {
+	conf := DefaultConfig()
+	conf.MysqldumpCompatible = true
+	tableIR := newMockTableIR("test", "employee", [][]driver.Value{{"1"}}, nil, []string{"INT"})
+	// the time isn't taken when the footers are built, but when they're written
+	tableIR.(*mockTableIR).specFooter = buildSpecialFooters(conf, "employee")
+	tableIR.(*mockTableIR).completed = writesMysqldumpCompleted(conf)
+	bf := &bytes.Buffer{}
+	start := time.Now().Truncate(time.Second)
+	c.Assert(WriteInsert(context.Background(), tableIR, bf, UnspecifiedSize, nil), IsNil)
+	lines := strings.Split(strings.TrimSuffix(bf.String(), "\n"), "\n")
+	completed := strings.TrimPrefix(lines[len(lines)-1], mysqldumpCompletedFooter)
+	completedAt, err := time.ParseInLocation(metadataTimeLayout, completed, time.Local)
+	c.Assert(err, IsNil)
+	c.Assert(completedAt.Before(start), IsFalse)
+}
+
+func (s *testUtilSuite) TestWriteInsertWithTransactionRows(c *C) {
+	data := [][]driver.Value{
+		{"1", "male"},
+		{"2", "female"},
+		{"3", "male"},
+		{"4", "female"},
+		{"5", "male"},
+	}
+	colTypes := []string{"INT", "SET"}
+	specCmts := []string{"/*!40101 SET NAMES binary*/;"}
+	tableIR := newMockTableIR("test", "employee", data, specCmts, colTypes)
+	bf := &bytes.Buffer{}
+
+	err := WriteInsert(context.Background(), tableIR, bf, 2, nil)
+	c.Assert(err, IsNil)
+	expected := "/*!40101 SET NAMES binary*/;\n" +
+		"BEGIN;\n" +
+		"INSERT INTO `employee` VALUES\n" +
+		"(1,'male'),\n" +
+		"(2,'female');\n" +
+		"COMMIT;\n" +
+		"BEGIN;\n" +
+		"INSERT INTO `employee` VALUES\n" +
+		"(3,'male'),\n" +
+		"(4,'female');\n" +
+		"COMMIT;\n" +
+		"BEGIN;\n" +
+		"INSERT INTO `employee` VALUES\n" +
+		"(5,'male');\n" +
+		"COMMIT;\n"
+	c.Assert(bf.String(), Equals, expected)
+}
+
 func (s *testUtilSuite) TestWriteInsertReturnsError(c *C) {
 	data := [][]driver.Value{
 		{"1", "male", "bob@mail.com", "020-1234", nil},
@@ -89,7 +173,7 @@ func (s *testUtilSuite) TestWriteInsertReturnsError(c *C) {
 	tableIR := newMockTableIRWithError("test", "employee", data, specCmts, colTypes, rowErr)
 	bf := &bytes.Buffer{}
 
-	err := WriteInsert(tableIR, bf)
+	err := WriteInsert(context.Background(), tableIR, bf, UnspecifiedSize, nil)
 	c.Assert(err, Equals, rowErr)
 	expected := "/*!40101 SET NAMES binary*/;\n" +
 		"/*!40014 SET FOREIGN_KEY_CHECKS=0*/;\n" +
@@ -111,7 +195,7 @@ func (s *testUtilSuite) TestWriteInsertInCsv(c *C) {
 	tableIR := newMockTableIR("test", "employee", data, nil, colTypes)
 	bf := &bytes.Buffer{}
 
-	err := WriteInsertInCsv(tableIR, bf, true, "\\N")
+	err := WriteInsertInCsv(context.Background(), tableIR, bf, true, "\\N", nil)
 	c.Assert(err, IsNil)
 	expected := "1,\"male\",\"bob@mail.com\",\"020-1234\",\\N\n" +
 		"2,\"female\",\"sarah@mail.com\",\"020-1253\",\"healthy\"\n" +
@@ -120,6 +204,22 @@ func (s *testUtilSuite) TestWriteInsertInCsv(c *C) {
 	c.Assert(bf.String(), Equals, expected)
 }
 
+func (s *testUtilSuite) TestWriteInsertInTsv(c *C) {
+	data := [][]driver.Value{
+		{"1", "tab\there", []byte{0x00, 0x5c}, nil},
+		{"2", "line\nbreak", []byte("bin"), "back\\slash"},
+	}
+	colTypes := []string{"INT", "VARCHAR", "BLOB", "TEXT"}
+	tableIR := newMockTableIR("test", "t", data, nil, colTypes)
+	bf := &bytes.Buffer{}
+
+	err := WriteInsertInTsv(context.Background(), tableIR, bf, true, nil)
+	c.Assert(err, IsNil)
+	expected := "1\ttab\\there\t\\0\\\\\t\\N\n" +
+		"2\tline\\nbreak\tbin\tback\\\\slash\n"
+	c.Assert(bf.String(), Equals, expected)
+}
+
 func (s *testUtilSuite) TestSQLDataTypes(c *C) {
 	data := [][]driver.Value{
 		{"CHAR", "char1", `'char1'`},
@@ -135,7 +235,7 @@ func (s *testUtilSuite) TestSQLDataTypes(c *C) {
 		tableIR := newMockTableIR("test", "t", tableData, nil, colType)
 		bf := &bytes.Buffer{}
 
-		err := WriteInsert(tableIR, bf)
+		err := WriteInsert(context.Background(), tableIR, bf, UnspecifiedSize, nil)
 		c.Assert(err, IsNil)
 		lines := strings.Split(bf.String(), "\n")
 		c.Assert(len(lines), Equals, 3)
@@ -160,3 +260,140 @@ func (s *testUtilSuite) TestWrite(c *C) {
 	err := write(mocksw, "test")
 	c.Assert(err, IsNil)
 }
+
+type failingWriter struct {
+	writes int
+	err    error
+}
+
+func (w *failingWriter) Write(p []byte) (int, error) {
+	w.writes++
+	return 0, w.err
+}
+
+func (s *testUtilSuite) TestWriteInsertWithOversizedRows(c *C) {
+	oversized := strings.Repeat("b", 100)
+	data := [][]driver.Value{
+		{"1", "a"},
+		{"2", "a"},
+		{"3", oversized},
+		{"4", "a"},
+		{"5", oversized},
+		{"6", oversized},
+		{"7", "a"},
+	}
+	tableIR := newMockTableIR("test", "t", data, nil, []string{"INT", "VARCHAR"})
+	bf := &bytes.Buffer{}
+
+	// the rows larger than a statement are written in statements of their own
+	err := WriteInsert(context.Background(), buildChunksIter(tableIR, UnspecifiedSize, 50), bf, UnspecifiedSize, nil)
+	c.Assert(err, IsNil)
+	expected := "INSERT INTO `t` VALUES\n(1,'a'),\n(2,'a');\n" +
+		"INSERT INTO `t` VALUES\n(3,'" + oversized + "');\n" +
+		"INSERT INTO `t` VALUES\n(4,'a');\n" +
+		"INSERT INTO `t` VALUES\n(5,'" + oversized + "');\n" +
+		"INSERT INTO `t` VALUES\n(6,'" + oversized + "');\n" +
+		"INSERT INTO `t` VALUES\n(7,'a');\n"
+	c.Assert(bf.String(), Equals, expected)
+
+	bf.Reset()
+	err = WriteInsert(context.Background(), buildChunksIter(tableIR, UnspecifiedSize, 50), bf, 2, nil)
+	c.Assert(err, IsNil)
+	expected = "BEGIN;\n" +
+		"INSERT INTO `t` VALUES\n(1,'a'),\n(2,'a');\n" +
+		"COMMIT;\nBEGIN;\n" +
+		"INSERT INTO `t` VALUES\n(3,'" + oversized + "');\n" +
+		"INSERT INTO `t` VALUES\n(4,'a');\n" +
+		"COMMIT;\nBEGIN;\n" +
+		"INSERT INTO `t` VALUES\n(5,'" + oversized + "');\n" +
+		"INSERT INTO `t` VALUES\n(6,'" + oversized + "');\n" +
+		"COMMIT;\nBEGIN;\n" +
+		"INSERT INTO `t` VALUES\n(7,'a');\n" +
+		"COMMIT;\n"
+	c.Assert(bf.String(), Equals, expected)
+}
+
+func (s *testUtilSuite) TestWriteInsertRowLargerThanBuffers(c *C) {
+	// the row is flushed across many buffers without being cut into statements
+	huge := strings.Repeat("it's ", lengthLimit)
+	data := [][]driver.Value{
+		{"1", "a"},
+		{"2", huge},
+		{"3", "a"},
+	}
+	tableIR := newMockTableIR("test", "t", data, nil, []string{"INT", "TEXT"})
+	w := &maxWriteRecorder{}
+	err := WriteInsert(context.Background(), buildChunksIter(tableIR, UnspecifiedSize, minBufferSize), w, UnspecifiedSize, NewBufferPool(minBufferSize, UnspecifiedSize))
+	c.Assert(err, IsNil)
+	expected := "INSERT INTO `t` VALUES\n(1,'a');\n" +
+		"INSERT INTO `t` VALUES\n(2,'" + strings.Replace(huge, "'", "''", -1) + "');\n" +
+		"INSERT INTO `t` VALUES\n(3,'a');\n"
+	c.Assert(w.String() == expected, IsTrue)
+	c.Assert(w.maxWrite <= minBufferSize+2*valueSegmentSize, IsTrue, Commentf("max write %d", w.maxWrite))
+}
+
+func (s *testUtilSuite) TestWriteInsertWithMultipleBuffers(c *C) {
+	value := strings.Repeat("a", 1024)
+	data := make([][]driver.Value, 0, 3000)
+	for i := 0; i < 3000; i++ {
+		data = append(data, []driver.Value{value})
+	}
+	colTypes := []string{"VARCHAR"}
+
+	bf := &bytes.Buffer{}
+	err := WriteInsert(context.Background(), newMockTableIR("test", "t", data, nil, colTypes), bf, UnspecifiedSize, nil)
+	c.Assert(err, IsNil)
+	c.Assert(bf.Len() > 2*lengthLimit, IsTrue)
+	c.Assert(strings.HasPrefix(bf.String(), "INSERT INTO `t` VALUES\n('"+value+"'),\n"), IsTrue)
+	c.Assert(strings.HasSuffix(bf.String(), "('"+value+"');\n"), IsTrue)
+	c.Assert(strings.Count(bf.String(), "\n"), Equals, 3001)
+
+	// the first writing error stops the serialization
+	writeErr := errors.New("mock write error")
+	w := &failingWriter{err: writeErr}
+	err = WriteInsert(context.Background(), newMockTableIR("test", "t", data, nil, colTypes), w, UnspecifiedSize, nil)
+	c.Assert(errors.Is(err, writeErr), IsTrue)
+	c.Assert(ErrorKindOf(err), Equals, ErrorKindWrite)
+	c.Assert(w.writes, Equals, 1)
+}
+
+func (s *testUtilSuite) TestWriteWithCanceledContext(c *C) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	strCollector := &mockStringCollector{}
+	meta := newMockMetaIR("test", "CREATE DATABASE `test`", nil)
+	c.Assert(WriteMeta(ctx, meta, strCollector), Equals, context.Canceled)
+	c.Assert(strCollector.buf, Equals, "")
+
+	data := [][]driver.Value{
+		{"1", "male"},
+		{"2", "female"},
+	}
+	colTypes := []string{"INT", "SET"}
+	bf := &bytes.Buffer{}
+	err := WriteInsert(ctx, newMockTableIR("test", "employee", data, nil, colTypes), bf, UnspecifiedSize, nil)
+	c.Assert(err, Equals, context.Canceled)
+	c.Assert(bf.Len(), Equals, 0)
+	err = WriteInsertInCsv(ctx, newMockTableIR("test", "employee", data, nil, colTypes), bf, true, "\\N", nil)
+	c.Assert(err, Equals, context.Canceled)
+	c.Assert(bf.Len(), Equals, 0)
+}
+
+func (s *testUtilSuite) TestSendToFullPipe(c *C) {
+	buffers := NewBufferPool(UnspecifiedSize, 1<<20)
+	buffers.depth = 1
+	// the pipe isn't run, so it's full after the first buffer
+	wp := newWriterPipe(&bytes.Buffer{}, buffers)
+	ctx, cancel := context.WithCancel(context.Background())
+	c.Assert(wp.send(ctx, bytes.NewBufferString("first")), IsNil)
+
+	done := make(chan error, 1)
+	go func() {
+		done <- wp.send(ctx, bytes.NewBufferString("second"))
+	}()
+	cancel()
+	c.Assert(<-done, Equals, context.Canceled)
+	// only the bytes in the pipe are taken from the budget
+	c.Assert(buffers.budget.Used(), Equals, uint64(len("first")))
+}
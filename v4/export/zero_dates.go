@@ -0,0 +1,114 @@
+package export
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/pingcap/errors"
+)
+
+const (
+	// ZeroDatesKeep writes the zero dates and the other invalid temporal
+	// values as they're read.
+	ZeroDatesKeep = "keep"
+	// ZeroDatesNull writes the invalid temporal values as NULL.
+	ZeroDatesNull = "null"
+	// ZeroDatesError fails the dump at the first invalid temporal value.
+	ZeroDatesError = "error"
+)
+
+// temporalTypes are the types of the columns whose values may be zero dates.
+var temporalTypes = map[string]bool{
+	"DATE":      true,
+	"DATETIME":  true,
+	"TIMESTAMP": true,
+}
+
+// zeroDatesConflicts returns the options conflicting with ZeroDates.
+func zeroDatesConflicts(conf *Config) []string {
+	switch conf.ZeroDates {
+	case "", ZeroDatesKeep:
+		return nil
+	case ZeroDatesNull, ZeroDatesError:
+	default:
+		return []string{fmt.Sprintf("invalid zero-dates %s, should be keep, null or error", conf.ZeroDates)}
+	}
+	// the files of the server are written without reading the rows
+	if conf.ServerOutfileDir != "" {
+		return []string{"zero-dates " + conf.ZeroDates + " is not supported with server-outfile-dir"}
+	}
+	return nil
+}
+
+// isInvalidTemporal returns whether the value of a DATE, DATETIME or
+// TIMESTAMP column is rejected by the strict sql_mode, i.e. it's 0000-00-00,
+// its month or day is zero, or the day is after the end of the month. They're
+// stored when the sql_mode of the source is without NO_ZERO_DATE or
+// NO_ZERO_IN_DATE, or with ALLOW_INVALID_DATES.
+func isInvalidTemporal(value []byte) bool {
+	if len(value) < 10 || value[4] != '-' || value[7] != '-' {
+		return false
+	}
+	year, ok1 := parseDigits(value[:4])
+	month, ok2 := parseDigits(value[5:7])
+	day, ok3 := parseDigits(value[8:10])
+	if !ok1 || !ok2 || !ok3 {
+		return false
+	}
+	if month == 0 || month > 12 || day == 0 {
+		return true
+	}
+	// the day 0 of the next month is the last day of the month
+	return day > time.Date(year, time.Month(month)+1, 0, 0, 0, 0, 0, time.UTC).Day()
+}
+
+func parseDigits(b []byte) (int, bool) {
+	n := 0
+	for _, c := range b {
+		if c < '0' || c > '9' {
+			return 0, false
+		}
+		n = n*10 + int(c-'0')
+	}
+	return n, true
+}
+
+// withZeroDates returns ir with the invalid temporal values of its DATE,
+// DATETIME and TIMESTAMP columns handled by ZeroDates, or ir itself if
+// they're kept.
+func withZeroDates(conf *Config, ir TableDataIR) TableDataIR {
+	if conf.ZeroDates == "" || conf.ZeroDates == ZeroDatesKeep {
+		return ir
+	}
+	var rewriters []columnRewriter
+	columns := ir.ColumnNames()
+	for i, colType := range ir.ColumnTypes() {
+		if !temporalTypes[colType] {
+			continue
+		}
+		if rewriters == nil {
+			rewriters = make([]columnRewriter, len(columns))
+		}
+		if conf.ZeroDates == ZeroDatesNull {
+			rewriters[i] = func(value []byte) ([]byte, error) {
+				if isInvalidTemporal(value) {
+					return nil, nil
+				}
+				return value, nil
+			}
+			continue
+		}
+		column := columns[i]
+		rewriters[i] = func(value []byte) ([]byte, error) {
+			if isInvalidTemporal(value) {
+				return nil, errors.Errorf("the column %s of %s has the invalid temporal value %s, which is rejected by zero-dates error",
+					column, qualifiedTableName(ir.DatabaseName(), ir.TableName()), value)
+			}
+			return value, nil
+		}
+	}
+	if rewriters == nil {
+		return ir
+	}
+	return &rewrittenTableData{TableDataIR: ir, rewriters: rewriters}
+}
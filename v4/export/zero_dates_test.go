@@ -0,0 +1,78 @@
+package export
+
+import (
+	"bytes"
+	"context"
+	"database/sql/driver"
+
+	. "github.com/pingcap/check"
+)
+
+var _ = Suite(&testZeroDatesSuite{})
+
+type testZeroDatesSuite struct{}
+
+func (s *testZeroDatesSuite) TestIsInvalidTemporal(c *C) {
+	for _, t := range []struct {
+		value   string
+		invalid bool
+	}{
+		{"2021-03-04", false},
+		{"2021-03-04 05:06:07.123456", false},
+		{"2020-02-29", false},
+		{"0000-01-01", false},
+		{"0000-00-00", true},
+		{"0000-00-00 00:00:00", true},
+		{"2021-00-15", true},
+		{"2021-03-00 00:00:00", true},
+		{"2021-02-29", true},
+		{"2021-04-31", true},
+		{"2021-13-01", true},
+		// TIME and the values of the other sources
+		{"12:00:00", false},
+		{"infinity", false},
+	} {
+		c.Assert(isInvalidTemporal([]byte(t.value)), Equals, t.invalid, Commentf("value %s", t.value))
+	}
+}
+
+func zeroDatesTableIR() TableDataIR {
+	data := [][]driver.Value{
+		{"1", "0000-00-00", "2021-03-04 05:06:07", "0000-00-00"},
+		{"2", "2021-02-30", "0000-00-00 00:00:00", nil},
+	}
+	tableIR := newMockTableIR("test", "t", data, nil, []string{"INT", "DATE", "DATETIME", "VARCHAR"})
+	tableIR.(*mockTableIR).colNames = []string{"id", "d", "dt", "note"}
+	return tableIR
+}
+
+func (s *testZeroDatesSuite) TestZeroDates(c *C) {
+	conf := DefaultConfig()
+	ir := zeroDatesTableIR()
+	c.Assert(withZeroDates(conf, ir), Equals, ir)
+
+	conf.ZeroDates = ZeroDatesNull
+	bf := &bytes.Buffer{}
+	c.Assert(WriteInsert(context.Background(), withZeroDates(conf, zeroDatesTableIR()), bf, UnspecifiedSize, nil), IsNil)
+	// the strings aren't temporal values
+	c.Assert(bf.String(), Equals, "INSERT INTO `t` VALUES\n"+
+		"(1,NULL,'2021-03-04 05:06:07','0000-00-00'),\n"+
+		"(2,NULL,NULL,NULL);\n")
+
+	conf.ZeroDates = ZeroDatesError
+	bf.Reset()
+	err := WriteInsert(context.Background(), withZeroDates(conf, zeroDatesTableIR()), bf, UnspecifiedSize, nil)
+	c.Assert(err, ErrorMatches, "the column d of `test`.`t` has the invalid temporal value 0000-00-00, which is rejected by zero-dates error")
+}
+
+func (s *testZeroDatesSuite) TestValidate(c *C) {
+	conf := DefaultConfig()
+	c.Assert(conf.Validate(), IsNil)
+	conf.ZeroDates = ZeroDatesError
+	c.Assert(conf.Validate(), IsNil)
+	conf.ZeroDates = "zero"
+	c.Assert(conf.Validate(), ErrorMatches, "(?s).*invalid zero-dates zero, should be keep, null or error.*")
+	conf.ZeroDates = ZeroDatesNull
+	conf.ServerOutfileDir = "/tmp/outfile"
+	c.Assert(conf.Validate(), ErrorMatches, "(?s).*zero-dates null is not supported with server-outfile-dir.*")
+}
@@ -1,6 +1,8 @@
 package log
 
 import (
+	"os"
+
 	"github.com/pingcap/errors"
 	pclog "github.com/pingcap/log"
 	"go.uber.org/zap"
@@ -38,8 +40,11 @@ type Config struct {
 	Format string `toml:"format" json:"format"`
 }
 
+// InitAppLogger initializes the global logger. The log is written to stderr
+// if cfg.File is empty, so that it's never mixed with the data on stdout,
+// otherwise to cfg.File which is rotated by size.
 func InitAppLogger(cfg *Config) error {
-	logger, props, err := pclog.InitLogger(&pclog.Config{
+	pcfg := &pclog.Config{
 		Level: cfg.Level,
 		File: pclog.FileLogConfig{
 			Filename:   cfg.File,
@@ -47,8 +52,26 @@ func InitAppLogger(cfg *Config) error {
 			MaxDays:    cfg.FileMaxDays,
 			MaxBackups: cfg.FileMaxBackups,
 		},
-		Format: cfg.Format,
-	})
+	}
+	switch cfg.Format {
+	case "", "text", "console":
+		pcfg.Format = "text"
+	case "json":
+		pcfg.Format = "json"
+	default:
+		return errors.Errorf("unsupported log format %s, expect text, console or json", cfg.Format)
+	}
+
+	var (
+		logger *zap.Logger
+		props  *pclog.ZapProperties
+		err    error
+	)
+	if cfg.File == "" {
+		logger, props, err = pclog.InitLoggerWithWriteSyncer(pcfg, zapcore.Lock(os.Stderr))
+	} else {
+		logger, props, err = pclog.InitLogger(pcfg)
+	}
 	if err != nil {
 		return errors.WithStack(err)
 	}